@@ -0,0 +1,51 @@
+package acquirer
+
+import (
+	"context"
+)
+
+//Acquirer is the connector to the acquirer deciding whether a card can be used for an operation
+type Acquirer interface {
+	IsDeclined(ctx context.Context, operationName string, cardNumber string) (bool, error)
+	VerifyAddress(ctx context.Context, cardNumber string) (addressMatch bool, postcodeMatch bool, err error)
+	Check(ctx context.Context) error
+}
+
+//RejectStore is the persistence of the cards the simulator declines
+type RejectStore interface {
+	CheckRejectByCardNumber(context.Context, string, string) (bool, error)
+}
+
+type simulator struct {
+	store RejectStore
+}
+
+//NewSimulator creates an acquirer declining the operations listed in the rejects table
+func NewSimulator(store RejectStore) Acquirer {
+	return &simulator{store: store}
+}
+
+//IsDeclined checks whether the operation with the passed card number is present in the rejects table
+func (s *simulator) IsDeclined(ctx context.Context, operationName string, cardNumber string) (bool, error) {
+	return s.store.CheckRejectByCardNumber(ctx, operationName, cardNumber)
+}
+
+//VerifyAddress matches the billing address of the card unless the address line or the postcode is listed in the rejects table,
+//as the avs_address and avs_postcode operations
+func (s *simulator) VerifyAddress(ctx context.Context, cardNumber string) (addressMatch bool, postcodeMatch bool, err error) {
+	addressMismatch, err := s.store.CheckRejectByCardNumber(ctx, "avs_address", cardNumber)
+	if err != nil {
+		return false, false, err
+	}
+	postcodeMismatch, err := s.store.CheckRejectByCardNumber(ctx, "avs_postcode", cardNumber)
+	if err != nil {
+		return false, false, err
+	}
+	return !addressMismatch, !postcodeMismatch, nil
+}
+
+//Check reports whether the simulator can answer, it has no connection of its own and only needs the rejects table to be readable
+func (s *simulator) Check(ctx context.Context) error {
+	_, err := s.store.CheckRejectByCardNumber(ctx, "authorisation", "")
+	return err
+}
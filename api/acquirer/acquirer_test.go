@@ -0,0 +1,89 @@
+package acquirer
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type rejectStoreMock struct {
+	checkRejectByCardNumber func(string, string) (bool, error)
+}
+
+func (r rejectStoreMock) CheckRejectByCardNumber(ctx context.Context, opName string, cardNumber string) (bool, error) {
+	return r.checkRejectByCardNumber(opName, cardNumber)
+}
+
+func TestSimulator_IsDeclined(t *testing.T) {
+	t.Parallel()
+	simulator := NewSimulator(rejectStoreMock{
+		checkRejectByCardNumber: func(opName string, cardNumber string) (bool, error) {
+			return opName == "capture" && cardNumber == "4000000000000259", nil
+		},
+	})
+
+	isDeclined, err := simulator.IsDeclined(context.Background(), "capture", "4000000000000259")
+	assert.Nil(t, err)
+	assert.EqualValues(t, true, isDeclined)
+
+	isDeclined, err = simulator.IsDeclined(context.Background(), "authorisation", "4000000000000259")
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, isDeclined)
+}
+
+func TestSimulator_IsDeclined_StoreError(t *testing.T) {
+	t.Parallel()
+	simulator := NewSimulator(rejectStoreMock{
+		checkRejectByCardNumber: func(string, string) (bool, error) {
+			return false, errors.New("error")
+		},
+	})
+
+	_, err := simulator.IsDeclined(context.Background(), "capture", "4000000000000259")
+	assert.EqualValues(t, "error", err.Error())
+}
+
+func TestSimulator_Check(t *testing.T) {
+	t.Parallel()
+	healthy := NewSimulator(rejectStoreMock{
+		checkRejectByCardNumber: func(string, string) (bool, error) {
+			return false, nil
+		},
+	})
+	assert.Nil(t, healthy.Check(context.Background()))
+
+	unhealthy := NewSimulator(rejectStoreMock{
+		checkRejectByCardNumber: func(string, string) (bool, error) {
+			return false, errors.New("database is locked")
+		},
+	})
+	assert.NotNil(t, unhealthy.Check(context.Background()))
+}
+
+func TestSimulator_VerifyAddress(t *testing.T) {
+	t.Parallel()
+	simulator := NewSimulator(rejectStoreMock{
+		checkRejectByCardNumber: func(opName string, cardNumber string) (bool, error) {
+			return opName == "avs_postcode" && cardNumber == "4000000000000259", nil
+		},
+	})
+
+	addressMatch, postcodeMatch, err := simulator.VerifyAddress(context.Background(), "4000000000000259")
+	assert.Nil(t, err)
+	assert.EqualValues(t, true, addressMatch)
+	assert.EqualValues(t, false, postcodeMatch)
+
+	addressMatch, postcodeMatch, err = simulator.VerifyAddress(context.Background(), "4000000000000077")
+	assert.Nil(t, err)
+	assert.EqualValues(t, true, addressMatch)
+	assert.EqualValues(t, true, postcodeMatch)
+
+	failing := NewSimulator(rejectStoreMock{
+		checkRejectByCardNumber: func(string, string) (bool, error) {
+			return false, errors.New("error")
+		},
+	})
+	_, _, err = failing.VerifyAddress(context.Background(), "4000000000000259")
+	assert.EqualValues(t, "error", err.Error())
+}
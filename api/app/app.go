@@ -1,20 +1,210 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/data_access"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"syscall"
+	"time"
 )
 
-var (
-	router = gin.Default()
-)
+//App is a gateway instance built from its configuration
+type App struct {
+	cfg       *config.Config
+	logger    *logger.Logger
+	store     *data_access.Database
+	container *container
+}
+
+//New opens the database and wires the components of a gateway instance
+func New(cfg *config.Config, log *logger.Logger) (*App, error) {
+	clk := clock.New()
+	m := metrics.New()
+	store, err := data_access.New(cfg.Database, clk, log, m)
+	if err != nil {
+		return nil, err
+	}
+	m.WatchHeldAmounts(store)
 
-//RunApp will run constantly until the application is shutdown
-func RunApp() {
-	routes()
+	return &App{
+		cfg:       cfg,
+		logger:    log,
+		store:     store,
+		container: newContainer(cfg, store, clk, log, m),
+	}, nil
+}
 
-	err := router.Run(":8080")
+//Run will run constantly until the application receives SIGINT or SIGTERM,
+//the in-flight requests are then drained before it returns, the gRPC calls included when their server is enabled
+func (a *App) Run() error {
+	if a.cfg.Logging.Level == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	server := newServer(a.cfg.Server, a.container.router())
+	listener, err := net.Listen("tcp", server.Addr)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if a.cfg.GRPC.ListenAddress != "" {
+		stop, err := a.serveGRPC()
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		defer stop()
+	}
+
+	if a.cfg.Features.Subscriptions {
+		a.container.scheduler.Start()
+		defer a.container.scheduler.Stop()
+	}
+	a.container.sweeper.Start()
+	defer a.container.sweeper.Stop()
+	a.container.disputer.Start()
+	defer a.container.disputer.Stop()
+	a.container.settler.Start()
+	defer a.container.settler.Stop()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	return serve(server, listener, a.cfg.Server, quit, a.container.health.Drain, a.logger)
+}
+
+//serveGRPC serves the payment calls over gRPC on the configured address, with the TLS files of the http server when
+//they are set, and returns the function stopping the server once the in-flight calls have completed or the shutdown
+//timeout has passed
+func (a *App) serveGRPC() (func(), error) {
+	listener, err := net.Listen("tcp", a.cfg.GRPC.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []grpc.ServerOption
+	if a.cfg.Server.TLSCertFile != "" && a.cfg.Server.TLSKeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(a.cfg.Server.TLSCertFile, a.cfg.Server.TLSKeyFile)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	server := a.container.grpcServer(opts...)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			a.logger.Error("grpc server stopped", logger.Err(err))
+		}
+	}()
+	return func() {
+		gracefulStop(server, a.cfg.Server.ShutdownTimeout.Duration, a.logger)
+	}, nil
+}
+
+//grpcStopper is the part of the gRPC server shutting it down
+type grpcStopper interface {
+	GracefulStop()
+	Stop()
+}
+
+//gracefulStop stops the server from accepting new calls and waits up to the timeout for the in-flight calls to
+//complete, the calls still running then are cancelled
+func gracefulStop(server grpcStopper, timeout time.Duration, log *logger.Logger) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Error("unable to drain in-flight grpc calls", logger.Duration("timeout", timeout))
+		//stopping the server closes its connections and releases the graceful stop
+		server.Stop()
+		<-done
 	}
 }
+
+//Export writes the export of the transactions described by the request to out, for the export subcommand
+func (a *App) Export(ctx context.Context, request transaction_domain.ExportRequest, out io.Writer) error {
+	file, errInf := a.container.transactions.ExportTransactions(ctx, request)
+	if errInf != nil {
+		return errors.New(errInf.ErrorMessage())
+	}
+	return file.Write(out)
+}
+
+//Close closes the database, it is only called once Run has drained the in-flight requests
+func (a *App) Close() error {
+	return a.store.Close()
+}
+
+//newServer creates the http server with the configured address, timeouts and header size
+func newServer(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           cfg.ListenAddress,
+		Handler:        handler,
+		ReadTimeout:    cfg.ReadTimeout.Duration,
+		WriteTimeout:   cfg.WriteTimeout.Duration,
+		IdleTimeout:    cfg.IdleTimeout.Duration,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+	}
+}
+
+//serve accepts connections on the listener until a signal is received on quit, then it drains the gateway so
+//that readiness fails, keeps serving for the shutdown delay while load balancers take it out of rotation, stops
+//accepting new connections and waits up to the shutdown timeout for the in-flight requests to complete
+func serve(server *http.Server, listener net.Listener, cfg config.ServerConfig, quit <-chan os.Signal, drain func(), log *logger.Logger) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+			err = server.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != http.ErrServerClosed {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case sig := <-quit:
+		log.Info("draining in-flight requests", logger.String("signal", sig.String()))
+	}
+
+	drain()
+	time.Sleep(cfg.ShutdownDelay.Duration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("unable to drain in-flight requests", logger.Err(err))
+		return err
+	}
+
+	return <-serverErr
+}
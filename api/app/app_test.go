@@ -0,0 +1,1149 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/reject"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/capture_domain"
+	"payment-gateway-api/api/domain/dispute_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/health_domain"
+	"payment-gateway-api/api/domain/ledger_domain"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"payment-gateway-api/api/domain/report_domain"
+	"payment-gateway-api/api/domain/settlement_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/middleware"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewServer(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default().Server
+	server := newServer(cfg, http.NotFoundHandler())
+	assert.EqualValues(t, cfg.ListenAddress, server.Addr)
+	assert.EqualValues(t, cfg.ReadTimeout.Duration, server.ReadTimeout)
+	assert.EqualValues(t, cfg.WriteTimeout.Duration, server.WriteTimeout)
+	assert.EqualValues(t, cfg.IdleTimeout.Duration, server.IdleTimeout)
+	assert.EqualValues(t, cfg.MaxHeaderBytes, server.MaxHeaderBytes)
+}
+
+func TestServe_DrainsInFlightRequestsOnSignal(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default().Server
+	requestStarted := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requestStarted)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("captured"))
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	quit := make(chan os.Signal, 1)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(newServer(cfg, handler), listener, cfg, quit, func() {}, logger.Discard())
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		responses <- result{status: resp.StatusCode, body: string(body)}
+	}()
+
+	<-requestStarted
+	quit <- syscall.SIGTERM
+
+	response := <-responses
+	assert.Nil(t, response.err)
+	assert.EqualValues(t, http.StatusOK, response.status)
+	assert.EqualValues(t, "captured", response.body)
+	assert.Nil(t, <-serveErr)
+
+	//no new connections are accepted once the server has shut down
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.NotNil(t, err)
+}
+
+func TestServe_ShutdownDeadlineExceeded(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = config.Duration{Duration: 50 * time.Millisecond}
+
+	requestStarted := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requestStarted)
+		<-release
+	})
+	defer close(release)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	quit := make(chan os.Signal, 1)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(newServer(cfg, handler), listener, cfg, quit, func() {}, logger.Discard())
+	}()
+
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-requestStarted
+	quit <- syscall.SIGINT
+	assert.NotNil(t, <-serveErr)
+}
+
+func newTestApp(t *testing.T, dsn string) *App {
+	cfg := config.Default()
+	cfg.Database.DSN = dsn
+	return newTestAppWithConfig(t, cfg)
+}
+
+func newTestAppWithConfig(t *testing.T, cfg *config.Config) *App {
+	gateway, err := New(cfg, logger.Discard())
+	assert.Nil(t, err)
+	return gateway
+}
+
+func TestNew_IndependentInstances(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first := newTestApp(t, filepath.Join(dir, "first.db"))
+	defer first.Close()
+	second := newTestApp(t, filepath.Join(dir, "second.db"))
+	defer second.Close()
+
+	body, err := json.Marshal(auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2099",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "GBP",
+	})
+	assert.Nil(t, err)
+
+	response := httptest.NewRecorder()
+	first.container.router().ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/authorize", bytes.NewBuffer(body)))
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+
+	//the authorisation only exists in the store of the instance that created it
+	voidBody := []byte(`{"id": "` + authResponse.AuthID + `"}`)
+	response = httptest.NewRecorder()
+	second.container.router().ServeHTTP(response, httptest.NewRequest(http.MethodPatch, "/void", bytes.NewBuffer(voidBody)))
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+
+	response = httptest.NewRecorder()
+	first.container.router().ServeHTTP(response, httptest.NewRequest(http.MethodPatch, "/void", bytes.NewBuffer(voidBody)))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+}
+
+func TestRouter_Metrics(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gateway := newTestApp(t, filepath.Join(dir, "gateway.db"))
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	body, err := json.Marshal(auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2099",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "GBP",
+	})
+	assert.Nil(t, err)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/authorize", bytes.NewBuffer(body)))
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+
+	metrics := response.Body.String()
+	assert.Contains(t, metrics, `gateway_operations_total{code="none",operation="authorisation",outcome="approved"} 1`)
+	assert.Contains(t, metrics, `gateway_http_request_duration_seconds_count{method="POST",route="/authorize",status="201"} 1`)
+	assert.Contains(t, metrics, `gateway_db_call_duration_seconds_count{call="InsertAuthRecord",outcome="ok"} 1`)
+	assert.Contains(t, metrics, `gateway_open_authorisations{currency="GBP"} 1`)
+	assert.Contains(t, metrics, `gateway_held_amount{currency="GBP"} 10`)
+}
+
+func TestRouter_CancelledCaptureLeavesNoWrites(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gateway := newTestApp(t, filepath.Join(dir, "gateway.db"))
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	body, err := json.Marshal(auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2099",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "GBP",
+	})
+	assert.Nil(t, err)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/authorize", bytes.NewBuffer(body)))
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+
+	//the client has gone away by the time the capture reaches the gateway
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	captureBody := []byte(`{"id": "` + authResponse.AuthID + `", "amount": 4}`)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPatch, "/capture", bytes.NewBuffer(captureBody)).WithContext(ctx))
+	assert.EqualValues(t, error_domain.StatusClientClosedRequest, response.Code)
+
+	_, record, err := gateway.store.GetAuthRecordByID(context.Background(), authResponse.AuthID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10, record.AvailableAmount)
+	isPresent, _, err := gateway.store.GetOperationByAuthIDAndOperationName(context.Background(), authResponse.AuthID, "capture")
+	assert.Nil(t, err)
+	assert.False(t, isPresent)
+}
+
+func TestServe_ReadinessFailsWhileShuttingDown(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gateway := newTestApp(t, filepath.Join(dir, "gateway.db"))
+	defer gateway.Close()
+
+	cfg := config.Default().Server
+	cfg.ShutdownDelay = config.Duration{Duration: 500 * time.Millisecond}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	readyz := "http://" + listener.Addr().String() + "/readyz"
+
+	quit := make(chan os.Signal, 1)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(newServer(cfg, gateway.container.router()), listener, cfg, quit, gateway.container.health.Drain, logger.Discard())
+	}()
+
+	resp, err := http.Get(readyz)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.EqualValues(t, http.StatusOK, resp.StatusCode)
+
+	//requests are still served during the shutdown delay but the gateway is no longer ready
+	quit <- syscall.SIGTERM
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(readyz)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		var readiness health_domain.ReadinessResponse
+		return json.NewDecoder(resp.Body).Decode(&readiness) == nil && resp.StatusCode == http.StatusServiceUnavailable &&
+			readiness.Checks["shutdown"] == "the gateway is shutting down"
+	}, 400*time.Millisecond, 20*time.Millisecond)
+
+	assert.Nil(t, <-serveErr)
+}
+
+func TestRouter_HealthEndpoints(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gateway := newTestApp(t, filepath.Join(dir, "gateway.db"))
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var readiness health_domain.ReadinessResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &readiness))
+	assert.EqualValues(t, map[string]string{"shutdown": "ok", "database": "ok", "migrations": "ok", "acquirer": "ok"}, readiness.Checks)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var version health_domain.VersionResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &version))
+	assert.EqualValues(t, data_access.SchemaVersion, version.SchemaVersion)
+	assert.EqualValues(t, "unknown", version.GitSHA)
+
+	//the db no longer being reachable makes the gateway not ready while it stays alive
+	assert.Nil(t, gateway.store.Close())
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.Code)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+}
+
+func TestRouter_FraudRules(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	//the admin endpoints are not served without a token
+	gateway := newTestApp(t, filepath.Join(dir, "disabled.db"))
+	defer gateway.Close()
+	response := httptest.NewRecorder()
+	gateway.container.router().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/admin/fraud/rules", nil))
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway = newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	rule := `{"name": "high amount", "type": "amount_threshold", "action": "deny", "threshold": 100, "currency": "GBP"}`
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/admin/fraud/rules", bytes.NewBufferString(rule)))
+	assert.EqualValues(t, http.StatusUnauthorized, response.Code)
+
+	request := httptest.NewRequest(http.MethodPost, "/admin/fraud/rules", bytes.NewBufferString(rule))
+	request.Header.Set("Authorization", "Bearer s3cret")
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+
+	authorise := func(amount float32) int {
+		body, err := json.Marshal(auth_domain.AuthRequest{
+			CardDetails: auth_domain.CardDetails{
+				Number:     "4929907390318794",
+				ExpiryDate: "12-2099",
+				Cvv:        "123",
+			},
+			Amount:   amount,
+			Currency: "GBP",
+		})
+		assert.Nil(t, err)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/authorize", bytes.NewBuffer(body)))
+		return response.Code
+	}
+	assert.EqualValues(t, http.StatusUnauthorized, authorise(150))
+	assert.EqualValues(t, http.StatusCreated, authorise(50))
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, response.Body.String(), `gateway_fraud_decisions_total{decision="deny"} 1`)
+	assert.Contains(t, response.Body.String(), `gateway_fraud_rules_triggered_total{action="deny",dry_run="false",rule="high amount"} 1`)
+}
+
+func TestRouter_Reviews(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	response := serve(http.MethodPost, "/admin/fraud/rules", `{"name": "high amount", "type": "amount_threshold", "action": "review", "threshold": 100, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+
+	response = serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 150, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusAccepted, response.Code)
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	assert.EqualValues(t, auth_domain.StatusPendingReview, authResponse.Status)
+
+	//the authorisation cannot be captured while it is held
+	capture := fmt.Sprintf(`{"id": "%s", "amount": 150}`, authResponse.AuthID)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPatch, "/capture", capture).Code)
+
+	response = serve(http.MethodGet, "/admin/reviews?state=pending", "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"triggered_rules":["high amount"]`)
+
+	approve := fmt.Sprintf(`{"id": "%s", "reviewer": "analyst@example.com", "reason": "customer confirmed the purchase"}`, authResponse.AuthID)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/admin/reviews/approve", approve).Code)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPatch, "/admin/reviews/decline", approve).Code)
+
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", capture).Code)
+
+	response = serve(http.MethodGet, "/metrics", "")
+	assert.Contains(t, response.Body.String(), `gateway_reviews_total{outcome="approved"} 1`)
+}
+
+func TestRouter_ThreeDSChallenge(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	assert.EqualValues(t, http.StatusNoContent, serve(http.MethodPut, "/admin/fraud/bins", `{"bins": [{"bin": "492990", "country": "FR"}]}`).Code)
+
+	response := serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 25, "currency": "EUR"}`)
+	assert.EqualValues(t, http.StatusAccepted, response.Code)
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	assert.EqualValues(t, auth_domain.StatusRequiresAction, authResponse.Status)
+	assert.EqualValues(t, "/acs/challenges/"+authResponse.AuthID, authResponse.ChallengeURL)
+
+	//the authorisation is only completed once the cardholder has answered the challenge
+	complete := "/authorize/" + authResponse.AuthID + "/complete"
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, complete, "").Code)
+
+	response = serve(http.MethodGet, authResponse.ChallengeURL, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"card_number":"****8794"`)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPost, authResponse.ChallengeURL, `{"code": "123456"}`).Code)
+
+	response = serve(http.MethodPost, complete, "")
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	assert.EqualValues(t, true, authResponse.LiabilityShift)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, complete, "").Code)
+
+	capture := fmt.Sprintf(`{"id": "%s", "amount": 25}`, authResponse.AuthID)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", capture).Code)
+
+	response = serve(http.MethodGet, "/metrics", "")
+	assert.Contains(t, response.Body.String(), `gateway_challenges_total{outcome="authenticated"} 1`)
+}
+
+func TestRouter_AddressVerification(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	//the issuer of the card has another postcode on file
+	assert.Nil(t, gateway.store.Db.Create(&reject.Reject{CardNumber: "4929907390318794", Operation: "avs_postcode"}).Error)
+	response := serve(http.MethodPost, "/admin/fraud/rules", `{"name": "avs mismatch", "type": "avs_mismatch", "action": "review"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+
+	authorise := `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123", "cardholder_name": "Jane Doe",
+		"billing_address": {"line1": "10 Downing Street", "city": "London", "postcode": "sw1a 2aa", "country": "GB"}}, "amount": 25, "currency": "GBP"}`
+	response = serve(http.MethodPost, "/authorize", authorise)
+	assert.EqualValues(t, http.StatusAccepted, response.Code)
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	assert.EqualValues(t, auth_domain.StatusPendingReview, authResponse.Status)
+	assert.EqualValues(t, &auth_domain.AVSResult{Address: auth_domain.AVSMatch, Postcode: auth_domain.AVSNoMatch}, authResponse.AVS)
+
+	var stored auth.Auth
+	assert.Nil(t, gateway.store.Db.Where("id = ?", authResponse.AuthID).First(&stored).Error)
+	assert.EqualValues(t, "Jane Doe", stored.CardholderName)
+	assert.EqualValues(t, "SW1A 2AA", stored.BillingPostcode)
+	assert.EqualValues(t, auth_domain.AVSNoMatch, stored.AVSPostcode)
+
+	response = serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123",
+		"billing_address": {"line1": "1 Main Street", "postcode": "ABC", "country": "US"}}, "amount": 25, "currency": "USD"}`)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), error_constant.InvalidPostcode)
+}
+
+func TestRouter_Settlement(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer s3cret")
+		request.Header.Set("X-Merchant-ID", "acme")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	response := serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 100, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	//acme is charged 1.5% plus 0.20 on the visa captures, the percentage is returned on refunds
+	response = serve(http.MethodPut, "/admin/fees/acme", `{"refund_policy": "return", "rates": [{"brand": "visa", "percentage": 1.5, "fixed": 0.2}]}`)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodGet, "/admin/fees/acme", "").Code)
+	response = serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 60}`, authResponse.AuthID))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var captureResponse capture_domain.CaptureResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &captureResponse))
+	assert.EqualValues(t, float32(1.1), captureResponse.Fee)
+	assert.EqualValues(t, float32(58.9), captureResponse.NetAmount)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/refund", fmt.Sprintf(`{"id": "%s", "amount": 10}`, authResponse.AuthID)).Code)
+	//the operations are moved before the latest cut-off
+	assert.Nil(t, gateway.store.Db.Model(&operation.Operation{}).Where("auth_id = ?", authResponse.AuthID).
+		UpdateColumn("created_at", time.Now().Add(-48*time.Hour)).Error)
+
+	response = serve(http.MethodPost, "/admin/settlements", "")
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var batch settlement_domain.BatchResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &batch))
+	assert.EqualValues(t, 2, batch.Operations)
+	assert.EqualValues(t, []settlement_domain.TotalResponse{
+		{MerchantID: "acme", Currency: "GBP", Captures: 1, CapturedAmount: 60, Refunds: 1, RefundedAmount: 10, Fees: 0.95, NetAmount: 49.05},
+	}, batch.Totals)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/admin/settlements", "").Code)
+
+	response = serve(http.MethodGet, "/admin/settlements/"+batch.ID+"/file?format=csv", "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), ",acme,GBP,1,60.00,1,10.00,0.95,49.05\n")
+
+	//the acquirer paid out the capture but not the refund
+	report := fmt.Sprintf("reference,type,amount,currency\n%s,capture,60.00,GBP\n", authResponse.AuthID)
+	response = serve(http.MethodPost, "/admin/settlements/"+batch.ID+"/reconciliations", report)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var reconciliation reconciliation_domain.ReconciliationResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &reconciliation))
+	assert.EqualValues(t, 1, reconciliation.Matched)
+	assert.EqualValues(t, 1, reconciliation.Missing)
+	response = serve(http.MethodGet, "/admin/reconciliations/"+reconciliation.ID, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"status":"missing"`)
+
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPost, "/admin/settlements/"+batch.ID+"/submit", "").Code)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/admin/settlements/"+batch.ID+"/reopen", "").Code)
+}
+
+func TestRouter_ReconciliationReportSize(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	cfg.Reconciliation.MaxReportSize = 256 << 10
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer s3cret")
+		request.Header.Set("X-Merchant-ID", "acme")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	response := serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 100, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 60}`, authResponse.AuthID)).Code)
+	assert.Nil(t, gateway.store.Db.Model(&operation.Operation{}).Where("auth_id = ?", authResponse.AuthID).
+		UpdateColumn("created_at", time.Now().Add(-48*time.Hour)).Error)
+	response = serve(http.MethodPost, "/admin/settlements", "")
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var batch settlement_domain.BatchResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &batch))
+
+	//the report is larger than the bodies of the other routes, it pays out the capture and lines of other merchants
+	var report strings.Builder
+	report.WriteString(fmt.Sprintf("reference,type,amount,currency\n%s,capture,60.00,GBP\n", authResponse.AuthID))
+	for i := 0; report.Len() <= 96<<10; i++ {
+		report.WriteString(fmt.Sprintf("00000000-0000-4000-8000-%012d,capture,%d.00,GBP\n", i, i+1))
+	}
+	assert.True(t, int64(report.Len()) > cfg.Server.MaxBodyBytes)
+	response = serve(http.MethodPost, "/admin/settlements/"+batch.ID+"/reconciliations", report.String())
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var reconciliation reconciliation_domain.ReconciliationResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &reconciliation))
+	assert.EqualValues(t, 1, reconciliation.Matched)
+	assert.True(t, reconciliation.Unexpected > 0)
+
+	response = serve(http.MethodPost, "/admin/settlements/"+batch.ID+"/reconciliations", report.String()+strings.Repeat(report.String()[32:], 2))
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, response.Code)
+}
+
+func TestRouter_Ledger(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer s3cret")
+		request.Header.Set("X-Merchant-ID", "acme")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+	authorise := func() string {
+		response := serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 100, "currency": "GBP"}`)
+		assert.EqualValues(t, http.StatusCreated, response.Code)
+		var authResponse auth_domain.AuthResponse
+		assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+		return authResponse.AuthID
+	}
+
+	captured, voided := authorise(), authorise()
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 60}`, captured)).Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/refund", fmt.Sprintf(`{"id": "%s", "amount": 10}`, captured)).Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/void", fmt.Sprintf(`{"id": "%s"}`, voided)).Code)
+
+	response := serve(http.MethodGet, "/admin/ledger/balances?merchant_id=acme", "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var balances []ledger_domain.BalanceResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &balances))
+	assert.EqualValues(t, []ledger_domain.BalanceResponse{
+		{Account: "cardholder_funds", Currency: "GBP", Balance: -100},
+		{Account: "cardholder_hold", Currency: "GBP", Balance: 50},
+		{Account: "merchant_receivable", Currency: "GBP", Balance: 60},
+		{Account: "refunds_payable", Currency: "GBP", Balance: -10},
+	}, balances)
+
+	response = serve(http.MethodGet, "/admin/ledger/entries?auth_id="+voided, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var entries []ledger_domain.EntryResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &entries))
+	assert.EqualValues(t, 2, len(entries))
+	assert.EqualValues(t, "void", entries[1].Name)
+
+	response = serve(http.MethodGet, "/admin/ledger/check", "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var check ledger_domain.CheckResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &check))
+	assert.EqualValues(t, 2, check.Authorisations)
+	assert.Empty(t, check.Violations)
+}
+
+func TestRouter_Disputes(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	cfg.Disputes.EvidenceDir = filepath.Join(dir, "evidence")
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	send := func(request *http.Request) *httptest.ResponseRecorder {
+		request.Header.Set("Authorization", "Bearer s3cret")
+		request.Header.Set("X-Merchant-ID", "acme")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		return send(httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	}
+
+	response := serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 100, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	open := fmt.Sprintf(`{"auth_id": "%s", "amount": 50, "reason": "fraud"}`, authResponse.AuthID)
+	//only captured transactions can be disputed
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/admin/disputes", open).Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 60}`, authResponse.AuthID)).Code)
+
+	response = serve(http.MethodPost, "/admin/disputes", open)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var opened dispute_domain.DisputeResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &opened))
+	assert.EqualValues(t, dispute_domain.StateOpened, opened.State)
+	assert.EqualValues(t, "acme", opened.MerchantID)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/admin/disputes", open).Code)
+
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/admin/disputes/"+opened.ID, `{"state": "evidence_required"}`).Code)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/admin/disputes/"+opened.ID+"/submit", "").Code)
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "delivery.txt")
+	part.Write([]byte("signed for by the cardholder"))
+	form.Close()
+	request := httptest.NewRequest(http.MethodPost, "/admin/disputes/"+opened.ID+"/evidence", body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	response = send(request)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var evidence dispute_domain.EvidenceResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &evidence))
+	assert.EqualValues(t, "text/plain", evidence.ContentType)
+
+	response = serve(http.MethodGet, fmt.Sprintf("/admin/disputes/%s/evidence/%d", opened.ID, evidence.ID), "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "signed for by the cardholder", response.Body.String())
+
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPost, "/admin/disputes/"+opened.ID+"/submit", "").Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/admin/disputes/"+opened.ID, `{"state": "lost", "detail": "evidence rejected"}`).Code)
+
+	response = serve(http.MethodGet, "/admin/disputes/"+opened.ID, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var lost dispute_domain.DisputeResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &lost))
+	assert.EqualValues(t, dispute_domain.StateLost, lost.State)
+	assert.EqualValues(t, 1, len(lost.Evidence))
+	assert.EqualValues(t, 5, len(lost.Events))
+
+	//the amount charged back can no longer be refunded
+	assert.EqualValues(t, http.StatusUnauthorized, serve(http.MethodPatch, "/refund", fmt.Sprintf(`{"id": "%s", "amount": 20}`, authResponse.AuthID)).Code)
+	response = serve(http.MethodGet, "/admin/ledger/balances?auth_id="+authResponse.AuthID, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `{"account":"chargebacks","currency":"GBP","balance":50}`)
+	assert.Contains(t, response.Body.String(), `{"account":"merchant_receivable","currency":"GBP","balance":10}`)
+
+	response = serve(http.MethodGet, "/metrics", "")
+	assert.Contains(t, response.Body.String(), `gateway_dispute_events_total{event="lost"} 1`)
+}
+
+func TestRouter_Transactions(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serveAs := func(merchantID string, method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("X-Merchant-ID", merchantID)
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		return serveAs("acme", method, path, body)
+	}
+
+	var ids []string
+	for _, amount := range []int{100, 200, 300} {
+		response := serve(http.MethodPost, "/authorize", fmt.Sprintf(`{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": %d, "currency": "GBP"}`, amount))
+		assert.EqualValues(t, http.StatusCreated, response.Code)
+		var authResponse auth_domain.AuthResponse
+		assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+		ids = append(ids, authResponse.AuthID)
+	}
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 150}`, ids[1])).Code)
+	response := serveAs("globex", http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 400, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+
+	//the merchants only search their own transactions, whatever the merchant filter
+	response = serve(http.MethodGet, "/transactions?merchant_id=globex&last4=8794&sort=amount&limit=2", "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var page transaction_domain.SearchResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &page))
+	assert.EqualValues(t, 2, len(page.Transactions))
+	assert.EqualValues(t, ids[2], page.Transactions[0].ID)
+	assert.EqualValues(t, ids[1], page.Transactions[1].ID)
+	assert.EqualValues(t, transaction_domain.StateCaptured, page.Transactions[1].State)
+	assert.NotEmpty(t, page.NextCursor)
+
+	response = serve(http.MethodGet, "/transactions?merchant_id=globex&last4=8794&sort=amount&limit=2&cursor="+page.NextCursor, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	page = transaction_domain.SearchResponse{}
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &page))
+	assert.EqualValues(t, 1, len(page.Transactions))
+	assert.EqualValues(t, ids[0], page.Transactions[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodGet, "/transactions?state=settled", "").Code)
+
+	//the transactions of every merchant are only searched by the admins
+	response = serve(http.MethodGet, "/admin/transactions?sort=amount", "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	page = transaction_domain.SearchResponse{}
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &page))
+	assert.EqualValues(t, 4, len(page.Transactions))
+	assert.EqualValues(t, "globex", page.Transactions[0].MerchantID)
+	unauthenticated := httptest.NewRecorder()
+	router.ServeHTTP(unauthenticated, httptest.NewRequest(http.MethodGet, "/admin/transactions", nil))
+	assert.EqualValues(t, http.StatusUnauthorized, unauthenticated.Code)
+
+	response = serve(http.MethodGet, "/transactions/"+ids[1], "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var captured transaction_domain.TransactionResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &captured))
+	assert.EqualValues(t, 150, captured.CapturedAmount)
+	assert.EqualValues(t, []string{"authorisation", "capture"}, []string{captured.Operations[0].Name, captured.Operations[1].Name})
+	assert.NotContains(t, response.Body.String(), "4929907390318794")
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodGet, "/v1/authorisations/"+ids[1], "").Code)
+
+	//the transactions of another merchant are not found
+	assert.EqualValues(t, http.StatusNotFound, serveAs("globex", http.MethodGet, "/transactions/"+ids[1], "").Code)
+	assert.EqualValues(t, http.StatusNotFound, serveAs("globex", http.MethodGet, "/v1/authorisations/"+ids[1], "").Code)
+}
+
+func TestRouter_Reports(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serveAs := func(merchantID string, method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("X-Merchant-ID", merchantID)
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		return serveAs("acme", method, path, body)
+	}
+	authorise := func(number string, amount int) *httptest.ResponseRecorder {
+		return serve(http.MethodPost, "/authorize", fmt.Sprintf(`{"card_details": {"card_number": "%s", "expiry_date": "12-2099", "cvv": "123"}, "amount": %d, "currency": "GBP"}`, number, amount))
+	}
+
+	var ids []string
+	for _, amount := range []int{100, 50} {
+		response := authorise("4929907390318794", amount)
+		assert.EqualValues(t, http.StatusCreated, response.Code)
+		var authResponse auth_domain.AuthResponse
+		assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+		ids = append(ids, authResponse.AuthID)
+	}
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 100}`, ids[0])).Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/refund", fmt.Sprintf(`{"id": "%s", "amount": 20}`, ids[0])).Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/void", fmt.Sprintf(`{"id": "%s"}`, ids[1])).Code)
+	//the acquirer declines the second card
+	assert.Nil(t, gateway.store.Db.Create(&reject.Reject{CardNumber: "4000000000000002", Operation: "authorisation"}).Error)
+	assert.EqualValues(t, http.StatusUnauthorized, authorise("4000000000000002", 75).Code)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today.AddDate(0, 0, -1).Format(time.RFC3339), today.AddDate(0, 0, 1).Format(time.RFC3339)
+	//the merchants only report on their own activity, whatever the merchant filter
+	response := serve(http.MethodGet, "/reports/summary?merchant_id=globex&currency=gbp&from="+from+"&to="+to, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var summary report_domain.SummaryResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &summary))
+	assert.EqualValues(t, report_domain.BucketDay, summary.Bucket)
+	assert.EqualValues(t, "acme", summary.MerchantID)
+	assert.EqualValues(t, 2, len(summary.Buckets))
+	assert.EqualValues(t, 0, summary.Buckets[0].Authorisations.Count)
+	assert.EqualValues(t, today.Format(report_domain.DateLayout), summary.Buckets[1].Start)
+
+	totals := summary.Totals
+	assert.EqualValues(t, 2, totals.Authorisations.Count)
+	assert.EqualValues(t, 150, totals.Authorisations.Amounts["GBP"])
+	assert.EqualValues(t, 1, totals.Captures.Count)
+	assert.EqualValues(t, 20, totals.Refunds.Amounts["GBP"])
+	assert.EqualValues(t, 1, totals.Voids.Count)
+	assert.EqualValues(t, 1, totals.DeclinesByReason["acquirer"].Count)
+	assert.EqualValues(t, 0.6667, totals.ApprovalRate)
+	assert.EqualValues(t, 0.3333, totals.DeclineRates["acquirer"])
+	assert.EqualValues(t, 1, totals.RefundRatio)
+
+	//another merchant has no activity
+	response = serveAs("globex", http.MethodGet, "/reports/summary?merchant_id=acme&from="+from+"&to="+to, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"approval_rate":0`)
+
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodGet, "/reports/summary?from="+from, "").Code)
+
+	//the activity of every merchant is only reported to the admins
+	assert.EqualValues(t, http.StatusOK, serveAs("globex", http.MethodGet, "/admin/reports/summary?merchant_id=acme&from="+from+"&to="+to, "").Code)
+	response = serve(http.MethodGet, "/admin/reports/summary?from="+from+"&to="+to, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	summary = report_domain.SummaryResponse{}
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &summary))
+	assert.EqualValues(t, 2, summary.Totals.Authorisations.Count)
+	unauthenticated := httptest.NewRecorder()
+	router.ServeHTTP(unauthenticated, httptest.NewRequest(http.MethodGet, "/admin/reports/summary?from="+from+"&to="+to, nil))
+	assert.EqualValues(t, http.StatusUnauthorized, unauthenticated.Code)
+}
+
+func TestRouter_V1(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gateway := newTestApp(t, filepath.Join(dir, "gateway.db"))
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("X-Merchant-ID", "acme")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+	authorise := `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 100, "currency": "GBP"}`
+
+	response := serve(http.MethodPost, "/v1/authorisations", authorise)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.Empty(t, response.Header().Get(middleware.DeprecationHeader))
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	location := response.Header().Get("Location")
+	assert.EqualValues(t, "/v1/authorisations/"+authResponse.AuthID, location)
+
+	response = serve(http.MethodPost, location+"/captures", `{"amount": 60}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, location, response.Header().Get("Location"))
+	response = serve(http.MethodPost, location+"/refunds", `{"amount": 10}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, location, response.Header().Get("Location"))
+	assert.EqualValues(t, http.StatusUnprocessableEntity, serve(http.MethodPost, location+"/captures", `{"amount": -1}`).Code)
+
+	//the authorisation lists the operations created under it
+	response = serve(http.MethodGet, location, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var transaction transaction_domain.TransactionResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &transaction))
+	assert.EqualValues(t, 60, transaction.CapturedAmount)
+	assert.EqualValues(t, 10, transaction.RefundedAmount)
+
+	response = serve(http.MethodPost, "/v1/authorisations", authorise)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	voided := response.Header().Get("Location")
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPost, voided+"/void", "").Code)
+	assert.EqualValues(t, http.StatusNotFound, serve(http.MethodPost, "/v1/authorisations/6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12/void", "").Code)
+
+	//the legacy routes still work and are flagged as deprecated
+	response = serve(http.MethodPost, "/authorize", authorise)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, "true", response.Header().Get(middleware.DeprecationHeader))
+	assert.EqualValues(t, `</v1/authorisations>; rel="successor-version"`, response.Header().Get(middleware.LinkHeader))
+	assert.Empty(t, response.Header().Get("Location"))
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	response = serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 10}`, authResponse.AuthID))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "true", response.Header().Get(middleware.DeprecationHeader))
+}
+
+func TestApp_Export(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	response := serve(http.MethodPost, "/authorize", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 100, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	var authResponse auth_domain.AuthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &authResponse))
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPatch, "/capture", fmt.Sprintf(`{"id": "%s", "amount": 60}`, authResponse.AuthID)).Code)
+
+	from := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	to := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	request := transaction_domain.ExportRequest{From: from, To: to, Columns: "transaction_id,card_number,operation,amount"}
+	var buffer bytes.Buffer
+	assert.Nil(t, gateway.Export(context.Background(), request, &buffer))
+	lines := strings.Split(buffer.String(), "\n")
+	//the header, the two operations, an empty line, the totals header and line and the final line break
+	require.Len(t, lines, 7)
+	assert.EqualValues(t, "transaction_id,card_number,operation,amount", lines[0])
+	assert.EqualValues(t, authResponse.AuthID+",492990******8794,authorisation,100.00", lines[1])
+	assert.EqualValues(t, authResponse.AuthID+",492990******8794,capture,60.00", lines[2])
+	assert.EqualValues(t, "GBP,2,100.00,60.00,0.00,0.00,0.00,0.00,60.00", lines[5])
+
+	err = gateway.Export(context.Background(), transaction_domain.ExportRequest{From: from}, &bytes.Buffer{})
+	assert.EqualValues(t, "["+error_constant.ExportRangeRequired+"]", err.Error())
+
+	//the same export is served to the admins
+	response = serve(http.MethodGet, "/admin/transactions/export?format=jsonl&from="+from+"&to="+to, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "application/x-ndjson", response.Header().Get("Content-Type"))
+	assert.EqualValues(t, 3, strings.Count(response.Body.String(), "\n"))
+	assert.NotContains(t, response.Body.String(), "4929907390318794")
+}
+
+//the timestamps are stored in UTC whatever the zone of the host, the range queries compare them with UTC bounds
+func TestApp_RangeQueriesOutsideUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC-5", -5*60*60)
+	defer func() { time.Local = local }()
+
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("X-Merchant-ID", "acme")
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	response := serve(http.MethodPost, "/v1/authorisations", `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 100, "currency": "GBP"}`)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, http.StatusCreated, serve(http.MethodPost, response.Header().Get("Location")+"/captures", `{"amount": 60}`).Code)
+
+	//the bounds are written in the zone of the host, an hour either side of now
+	from := url.QueryEscape(time.Now().Add(-time.Hour).Format(time.RFC3339))
+	to := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+
+	response = serve(http.MethodGet, "/admin/transactions?from="+from+"&to="+to, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var page transaction_domain.SearchResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &page))
+	assert.EqualValues(t, 1, len(page.Transactions))
+
+	response = serve(http.MethodGet, "/admin/transactions/export?format=jsonl&from="+from+"&to="+to, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, 2, strings.Count(response.Body.String(), `"operation"`))
+
+	response = serve(http.MethodGet, "/admin/reports/summary?from="+from+"&to="+to, "")
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var summary report_domain.SummaryResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &summary))
+	assert.EqualValues(t, 1, summary.Totals.Authorisations.Count)
+	assert.EqualValues(t, 1, summary.Totals.Captures.Count)
+}
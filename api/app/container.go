@@ -0,0 +1,277 @@
+package app
+
+import (
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"payment-gateway-api/api/acquirer"
+	"payment-gateway-api/api/build"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/controllers/acs_controller"
+	"payment-gateway-api/api/controllers/authorisation_controller"
+	"payment-gateway-api/api/controllers/capture_controller"
+	"payment-gateway-api/api/controllers/dispute_controller"
+	"payment-gateway-api/api/controllers/docs_controller"
+	"payment-gateway-api/api/controllers/fee_controller"
+	"payment-gateway-api/api/controllers/fraud_controller"
+	"payment-gateway-api/api/controllers/health_controller"
+	"payment-gateway-api/api/controllers/ledger_controller"
+	"payment-gateway-api/api/controllers/reconciliation_controller"
+	"payment-gateway-api/api/controllers/refund_controller"
+	"payment-gateway-api/api/controllers/report_controller"
+	"payment-gateway-api/api/controllers/review_controller"
+	"payment-gateway-api/api/controllers/settlement_controller"
+	"payment-gateway-api/api/controllers/subscription_controller"
+	"payment-gateway-api/api/controllers/transaction_controller"
+	"payment-gateway-api/api/controllers/void_controller"
+	"payment-gateway-api/api/data_access"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"payment-gateway-api/api/grpc_server"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"payment-gateway-api/api/middleware"
+	"payment-gateway-api/api/ratelimit"
+	"payment-gateway-api/api/services/acs_service"
+	"payment-gateway-api/api/services/authorisation_service"
+	"payment-gateway-api/api/services/capture_service"
+	"payment-gateway-api/api/services/common_service"
+	"payment-gateway-api/api/services/dispute_service"
+	"payment-gateway-api/api/services/fee_service"
+	"payment-gateway-api/api/services/fraud_service"
+	"payment-gateway-api/api/services/health_service"
+	"payment-gateway-api/api/services/ledger_service"
+	"payment-gateway-api/api/services/reconciliation_service"
+	"payment-gateway-api/api/services/refund_service"
+	"payment-gateway-api/api/services/report_service"
+	"payment-gateway-api/api/services/review_service"
+	"payment-gateway-api/api/services/settlement_service"
+	"payment-gateway-api/api/services/subscription_service"
+	"payment-gateway-api/api/services/transaction_service"
+	"payment-gateway-api/api/services/void_service"
+)
+
+//container holds the components of one gateway instance wired together
+type container struct {
+	logger       *logger.Logger
+	metrics      *metrics.Metrics
+	features     config.FeaturesConfig
+	timeouts     config.TimeoutsConfig
+	server       config.ServerConfig
+	limits       config.RateLimitsConfig
+	limiter      *ratelimit.Limiter
+	admin        config.AdminConfig
+	uploads      map[string]int64
+	scheduler    *subscription_service.Scheduler
+	sweeper      *review_service.Sweeper
+	disputer     *dispute_service.Sweeper
+	settler      *settlement_service.Scheduler
+	health       health_service.Service
+	transactions transaction_service.Service
+	payments     grpc_server.Dependencies
+
+	authorisationHandler  *authorisation_controller.Handler
+	captureHandler        *capture_controller.Handler
+	refundHandler         *refund_controller.Handler
+	voidHandler           *void_controller.Handler
+	subscriptionHandler   *subscription_controller.Handler
+	healthHandler         *health_controller.Handler
+	fraudHandler          *fraud_controller.Handler
+	reviewHandler         *review_controller.Handler
+	acsHandler            *acs_controller.Handler
+	settlementHandler     *settlement_controller.Handler
+	reconciliationHandler *reconciliation_controller.Handler
+	ledgerHandler         *ledger_controller.Handler
+	feeHandler            *fee_controller.Handler
+	disputeHandler        *dispute_controller.Handler
+	transactionHandler    *transaction_controller.Handler
+	reportHandler         *report_controller.Handler
+	docsHandler           *docs_controller.Handler
+}
+
+//newContainer builds the services and handlers of the gateway on top of the given store
+func newContainer(cfg *config.Config, store *data_access.Database, clk clock.Clock, log *logger.Logger, m *metrics.Metrics) *container {
+	simulator := acquirer.NewSimulator(store)
+	commonService := common_service.New(common_service.Dependencies{
+		Store:  store,
+		Logger: log,
+	})
+	fraudService := fraud_service.New(fraud_service.Dependencies{
+		Store:   store,
+		Clock:   clk,
+		Logger:  log,
+		Metrics: m,
+	})
+	authorisationService := authorisation_service.New(authorisation_service.Dependencies{
+		Store:        store,
+		Acquirer:     simulator,
+		FraudService: fraudService,
+		Clock:        clk,
+		Logger:       log,
+		Metrics:      m,
+		Limits:       cfg.Limits,
+		ReviewSLA:    cfg.Reviews.SLA.Duration,
+		ThreeDS:      cfg.ThreeDS,
+	})
+	acsService := acs_service.New(acs_service.Dependencies{
+		Store:   store,
+		Clock:   clk,
+		Logger:  log,
+		Metrics: m,
+	})
+	reviewService := review_service.New(review_service.Dependencies{
+		Store:   store,
+		Clock:   clk,
+		Logger:  log,
+		Metrics: m,
+	})
+	settlementService := settlement_service.New(settlement_service.Dependencies{
+		Store:  store,
+		Clock:  clk,
+		Logger: log,
+		CutOff: cfg.Settlement.CutOffOfDay(),
+	})
+	reconciliationService := reconciliation_service.New(reconciliation_service.Dependencies{
+		Store:  store,
+		Clock:  clk,
+		Logger: log,
+		Columns: reconciliation_domain.Columns{
+			Reference: cfg.Reconciliation.Columns.Reference,
+			Type:      cfg.Reconciliation.Columns.Type,
+			Amount:    cfg.Reconciliation.Columns.Amount,
+			Currency:  cfg.Reconciliation.Columns.Currency,
+		},
+	})
+	ledgerService := ledger_service.New(ledger_service.Dependencies{
+		Store:  store,
+		Clock:  clk,
+		Logger: log,
+	})
+	feeService := fee_service.New(fee_service.Dependencies{
+		Store:  store,
+		Logger: log,
+	})
+	captureService := capture_service.New(capture_service.Dependencies{
+		Store:         store,
+		CommonService: commonService,
+		FeeService:    feeService,
+		Acquirer:      simulator,
+		Clock:         clk,
+		Logger:        log,
+		Metrics:       m,
+	})
+	refundService := refund_service.New(refund_service.Dependencies{
+		Store:         store,
+		CommonService: commonService,
+		FeeService:    feeService,
+		Acquirer:      simulator,
+		Clock:         clk,
+		Logger:        log,
+		Metrics:       m,
+	})
+	voidService := void_service.New(void_service.Dependencies{
+		Store:         store,
+		CommonService: commonService,
+		Logger:        log,
+		Metrics:       m,
+	})
+	healthService := health_service.New(health_service.Dependencies{
+		Store:         store,
+		Acquirer:      simulator,
+		Logger:        log,
+		SchemaVersion: data_access.SchemaVersion,
+		GitSHA:        build.GitSHA,
+		BuildTime:     build.Time,
+	})
+	disputeService := dispute_service.New(dispute_service.Dependencies{
+		Store:           store,
+		Evidence:        dispute_service.NewDiskStore(cfg.Disputes.EvidenceDir),
+		Clock:           clk,
+		Logger:          log,
+		Metrics:         m,
+		ResponseWindow:  cfg.Disputes.ResponseWindow.Duration,
+		MaxEvidenceSize: cfg.Disputes.MaxEvidenceSize,
+	})
+	transactionService := transaction_service.New(transaction_service.Dependencies{
+		Store:  store,
+		Logger: log,
+	})
+	reportService := report_service.New(report_service.Dependencies{
+		Store:  store,
+		Logger: log,
+	})
+	subscriptionService := subscription_service.New(subscription_service.Dependencies{
+		Store:                store,
+		AuthorisationService: authorisationService,
+		CaptureService:       captureService,
+		VoidService:          voidService,
+		Clock:                clk,
+		Logger:               log,
+		RetryIntervals:       config.Durations(cfg.Subscriptions.RetryIntervals),
+	})
+	payments := grpc_server.Dependencies{
+		AuthorisationService: authorisationService,
+		CaptureService:       captureService,
+		RefundService:        refundService,
+		VoidService:          voidService,
+		TransactionService:   transactionService,
+		Logger:               log,
+		Timeouts:             cfg.Timeouts,
+	}
+	//the evidence uploads are allowed the maximum evidence size on top of the rest of the form, the acquirer reports
+	//are sent as the whole body
+	uploads := map[string]int64{
+		"/admin/disputes/:id/evidence":           cfg.Disputes.MaxEvidenceSize + cfg.Server.MaxBodyBytes,
+		"/admin/settlements/:id/reconciliations": cfg.Reconciliation.MaxReportSize,
+	}
+
+	return &container{
+		logger:                log,
+		metrics:               m,
+		features:              cfg.Features,
+		timeouts:              cfg.Timeouts,
+		server:                cfg.Server,
+		limits:                cfg.RateLimits,
+		limiter:               ratelimit.New(clk),
+		admin:                 cfg.Admin,
+		uploads:               uploads,
+		scheduler:             subscription_service.NewScheduler(subscriptionService, cfg.Subscriptions.SchedulerInterval.Duration, log),
+		sweeper:               review_service.NewSweeper(reviewService, cfg.Reviews.SweepInterval.Duration, log),
+		disputer:              dispute_service.NewSweeper(disputeService, cfg.Disputes.SweepInterval.Duration, log),
+		settler:               settlement_service.NewScheduler(settlementService, cfg.Settlement.CloseInterval.Duration, log),
+		health:                healthService,
+		transactions:          transactionService,
+		payments:              payments,
+		authorisationHandler:  authorisation_controller.New(authorisationService, log),
+		captureHandler:        capture_controller.New(captureService, log),
+		refundHandler:         refund_controller.New(refundService, log),
+		voidHandler:           void_controller.New(voidService, log),
+		subscriptionHandler:   subscription_controller.New(subscriptionService, log),
+		healthHandler:         health_controller.New(healthService, log),
+		fraudHandler:          fraud_controller.New(fraudService, log),
+		reviewHandler:         review_controller.New(reviewService, log),
+		acsHandler:            acs_controller.New(acsService, log),
+		settlementHandler:     settlement_controller.New(settlementService, log),
+		reconciliationHandler: reconciliation_controller.New(reconciliationService, log),
+		ledgerHandler:         ledger_controller.New(ledgerService, log),
+		feeHandler:            fee_controller.New(feeService, log),
+		disputeHandler:        dispute_controller.New(disputeService, log),
+		transactionHandler:    transaction_controller.New(transactionService, log),
+		reportHandler:         report_controller.New(reportService, log),
+		docsHandler:           docs_controller.New(specification(), log),
+	}
+}
+
+//router creates the gin engine serving the routes of the container, every request is tagged with a
+//request ID used by all the log lines it produces, its latency is recorded, its context has a deadline and its body is capped
+func (c *container) router() *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.RequestLogger(c.logger), middleware.Metrics(c.metrics), middleware.Deadline(c.timeouts),
+		middleware.BodyLimit(c.server.MaxBodyBytes, c.uploads))
+	routes(router, c)
+	return router
+}
+
+//grpcServer creates the gRPC server of the payment calls, served with the same services as the routes
+func (c *container) grpcServer(opts ...grpc.ServerOption) *grpc.Server {
+	return grpc_server.New(c.payments, opts...)
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/grpc_server"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/paymentpb"
+	"testing"
+	"time"
+)
+
+func TestGRPC_PaymentCalls(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gateway := newTestApp(t, filepath.Join(dir, "gateway.db"))
+	defer gateway.Close()
+
+	listener := bufconn.Listen(1 << 20)
+	server := gateway.container.grpcServer()
+	go server.Serve(listener)
+	defer server.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	assert.Nil(t, err)
+	defer conn.Close()
+	client := paymentpb.NewPaymentsClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpc_server.MerchantIDKey, "acme")
+	authorise := &paymentpb.AuthoriseRequest{
+		CardDetails: &paymentpb.CardDetails{CardNumber: "4929907390318794", ExpiryDate: "12-2099", Cvv: "123"},
+		Amount:      100,
+		Currency:    "GBP",
+	}
+
+	authorisation, err := client.Authorise(ctx, authorise)
+	assert.Nil(t, err)
+	assert.True(t, authorisation.GetSuccess())
+	_, err = client.Capture(ctx, &paymentpb.CaptureRequest{Id: authorisation.GetId(), Amount: 60})
+	assert.Nil(t, err)
+	_, err = client.Refund(ctx, &paymentpb.RefundRequest{Id: authorisation.GetId(), Amount: 10})
+	assert.Nil(t, err)
+	//a refunded transaction can no longer be captured
+	_, err = client.Capture(ctx, &paymentpb.CaptureRequest{Id: authorisation.GetId(), Amount: 10})
+	assert.EqualValues(t, codes.FailedPrecondition, status.Code(err))
+	assert.EqualValues(t, "["+error_constant.TransactionStateInvalid+"]", status.Convert(err).Message())
+	_, err = client.Capture(ctx, &paymentpb.CaptureRequest{Id: authorisation.GetId(), Amount: -1})
+	assert.EqualValues(t, codes.InvalidArgument, status.Code(err))
+
+	transaction, err := client.GetTransaction(ctx, &paymentpb.GetTransactionRequest{Id: authorisation.GetId()})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "acme", transaction.GetMerchantId())
+	assert.EqualValues(t, 60, transaction.GetCapturedAmount())
+	assert.EqualValues(t, 10, transaction.GetRefundedAmount())
+	other := metadata.AppendToOutgoingContext(context.Background(), grpc_server.MerchantIDKey, "globex")
+	_, err = client.GetTransaction(other, &paymentpb.GetTransactionRequest{Id: authorisation.GetId()})
+	assert.EqualValues(t, codes.NotFound, status.Code(err))
+
+	authorisation, err = client.Authorise(ctx, authorise)
+	assert.Nil(t, err)
+	voided, err := client.Void(ctx, &paymentpb.VoidRequest{Id: authorisation.GetId()})
+	assert.Nil(t, err)
+	assert.True(t, voided.GetSuccess())
+	_, err = client.Void(ctx, &paymentpb.VoidRequest{Id: "6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12"})
+	assert.EqualValues(t, codes.NotFound, status.Code(err))
+}
+
+//stopperMock is a gRPC server whose graceful stop waits for the in-flight calls until they complete or it is stopped
+type stopperMock struct {
+	calls   chan struct{}
+	stopped chan struct{}
+}
+
+func (s *stopperMock) GracefulStop() {
+	select {
+	case <-s.calls:
+	case <-s.stopped:
+	}
+}
+
+func (s *stopperMock) Stop() {
+	close(s.stopped)
+}
+
+func TestGracefulStop(t *testing.T) {
+	t.Parallel()
+
+	//the in-flight calls complete before the timeout
+	server := &stopperMock{calls: make(chan struct{}), stopped: make(chan struct{})}
+	close(server.calls)
+	gracefulStop(server, time.Minute, logger.Discard())
+	select {
+	case <-server.stopped:
+		t.Fatal("the server has been stopped although its calls completed")
+	default:
+	}
+
+	//the in-flight calls are still running after the timeout
+	server = &stopperMock{calls: make(chan struct{}), stopped: make(chan struct{})}
+	start := time.Now()
+	gracefulStop(server, 50*time.Millisecond, logger.Discard())
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	select {
+	case <-server.stopped:
+	default:
+		t.Fatal("the server has not been stopped after the timeout")
+	}
+}
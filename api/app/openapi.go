@@ -0,0 +1,322 @@
+package app
+
+import (
+	"net/http"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/capture_domain"
+	"payment-gateway-api/api/domain/dispute_domain"
+	"payment-gateway-api/api/domain/fee_domain"
+	"payment-gateway-api/api/domain/fraud_domain"
+	"payment-gateway-api/api/domain/health_domain"
+	"payment-gateway-api/api/domain/ledger_domain"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"payment-gateway-api/api/domain/refund_domain"
+	"payment-gateway-api/api/domain/report_domain"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/domain/settlement_domain"
+	"payment-gateway-api/api/domain/subscription_domain"
+	"payment-gateway-api/api/domain/threeds_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/domain/void_domain"
+	"payment-gateway-api/api/middleware"
+	"payment-gateway-api/api/openapi"
+)
+
+//apiVersion is the version of the contract described by the OpenAPI document
+const apiVersion = "1.0.0"
+
+var (
+	text   = &openapi.Schema{Type: "string"}
+	binary = &openapi.Schema{Type: "string", Format: "binary"}
+	upload = &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{"file": binary}}
+
+	location = map[string]string{"Location": "the URL of the authorisation in the versioned API"}
+)
+
+//specification describes every route of routes() along with the domain structs they bind and write, the admin
+//endpoints included whether or not they are served
+func specification() *openapi.Document {
+	d := openapi.New("Payment Gateway API",
+		"Authorises, captures, refunds and voids card payments. The admin endpoints are only served when an admin token has been configured.",
+		apiVersion)
+
+	d.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/healthz", ID: "getHealth", Tag: "health", Summary: "Tells whether the process is alive",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: health_domain.HealthResponse{}}}})
+	d.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/readyz", ID: "getReadiness", Tag: "health", Summary: "Tells whether the gateway can serve payments",
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Body: health_domain.ReadinessResponse{}},
+			{Status: http.StatusServiceUnavailable, Body: health_domain.ReadinessResponse{}},
+		}})
+	d.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/version", ID: "getVersion", Tag: "health", Summary: "Returns the build and the schema version",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: health_domain.VersionResponse{}}}})
+	d.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/metrics", ID: "getMetrics", Tag: "health", Summary: "Returns the Prometheus metrics",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: text, ContentType: "text/plain"}}})
+	d.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/openapi.json", ID: "getOpenAPI", Tag: "docs", Summary: "Returns this document",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: &openapi.Schema{Type: "object"}}}})
+	d.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/docs", ID: "getSwaggerUI", Tag: "docs", Summary: "Browses this document with Swagger UI",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: text, ContentType: "text/html"}}})
+
+	d.Add(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/v1/authorisations", ID: "createAuthorisation", Tag: "payments", Summary: "Authorises an amount on a card",
+		Body: auth_domain.AuthRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusCreated, Description: "The authorisation has been approved", Body: auth_domain.AuthResponse{}, Headers: location},
+			{Status: http.StatusAccepted, Description: "The authorisation is held for review or waits for the cardholder to authenticate", Body: auth_domain.AuthResponse{}, Headers: location},
+		},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodGet, Path: "/v1/authorisations/:id", ID: "getAuthorisation", Tag: "payments", Summary: "Returns an authorisation with its captures, refunds and void",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: transaction_domain.TransactionResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/v1/authorisations/:id/complete", ID: "completeAuthorisation", Tag: "payments", Summary: "Completes an authorisation once the 3-D Secure challenge has been answered",
+		Replies: []openapi.Reply{
+			{Status: http.StatusCreated, Description: "The authorisation has been approved", Body: auth_domain.AuthResponse{}},
+			{Status: http.StatusAccepted, Description: "The authorisation is held for review", Body: auth_domain.AuthResponse{}},
+		},
+		Errors: []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/v1/authorisations/:id/captures", ID: "createCapture", Tag: "payments", Summary: "Captures some or all of the authorised amount",
+		Body:    capture_domain.AmountRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: capture_domain.CaptureResponse{}, Headers: location}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/v1/authorisations/:id/refunds", ID: "createRefund", Tag: "payments", Summary: "Refunds some or all of the captured amount",
+		Body:    refund_domain.AmountRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: refund_domain.RefundResponse{}, Headers: location}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/v1/authorisations/:id/void", ID: "voidAuthorisation", Tag: "payments", Summary: "Voids an authorisation",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: void_domain.VoidResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+
+	//the legacy routes, superseded by /v1
+	d.Add(deprecated(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/authorize", ID: "legacyAuthorise", Tag: "payments", Summary: "Authorises an amount on a card",
+		Body: auth_domain.AuthRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusCreated, Description: "The authorisation has been approved", Body: auth_domain.AuthResponse{}},
+			{Status: http.StatusAccepted, Description: "The authorisation is held for review or waits for the cardholder to authenticate", Body: auth_domain.AuthResponse{}},
+		},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity, http.StatusInternalServerError}})))
+	d.Add(deprecated(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/authorize/:id/complete", ID: "legacyCompleteAuthorisation", Tag: "payments", Summary: "Completes an authorisation once the 3-D Secure challenge has been answered",
+		Replies: []openapi.Reply{
+			{Status: http.StatusCreated, Description: "The authorisation has been approved", Body: auth_domain.AuthResponse{}},
+			{Status: http.StatusAccepted, Description: "The authorisation is held for review", Body: auth_domain.AuthResponse{}},
+		},
+		Errors: []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}})))
+	d.Add(deprecated(payment(openapi.Endpoint{Method: http.MethodPatch, Path: "/void", ID: "legacyVoid", Tag: "payments", Summary: "Voids an authorisation",
+		Body:    void_domain.VoidRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: void_domain.VoidResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}})))
+	d.Add(deprecated(payment(openapi.Endpoint{Method: http.MethodPatch, Path: "/capture", ID: "legacyCapture", Tag: "payments", Summary: "Captures some or all of the authorised amount",
+		Body:    capture_domain.CaptureRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: capture_domain.CaptureResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError}})))
+	d.Add(deprecated(payment(openapi.Endpoint{Method: http.MethodPatch, Path: "/refund", ID: "legacyRefund", Tag: "payments", Summary: "Refunds some or all of the captured amount",
+		Body:    refund_domain.RefundRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: refund_domain.RefundResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError}})))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodGet, Path: "/transactions", ID: "searchTransactions", Tag: "transactions", Summary: "Searches the transactions of the merchant, a page at a time",
+		Query:   transaction_domain.SearchRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: transaction_domain.SearchResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodGet, Path: "/transactions/:id", ID: "getTransaction", Tag: "transactions", Summary: "Returns a transaction with its operations",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: transaction_domain.TransactionResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(payment(openapi.Endpoint{Method: http.MethodGet, Path: "/reports/summary", ID: "getSummaryReport", Tag: "reports", Summary: "Sums up the activity of the merchant over a period by bucket",
+		Query:   report_domain.SummaryRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: report_domain.SummaryResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+
+	d.Add(payment(openapi.Endpoint{Method: http.MethodPost, Path: "/subscription", ID: "createSubscription", Tag: "subscriptions", Summary: "Schedules recurring charges against a stored card",
+		Body:    subscription_domain.SubscriptionRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: subscription_domain.SubscriptionResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	for _, change := range []struct{ path, id, summary string }{
+		{"/subscription/pause", "pauseSubscription", "Pauses a subscription"},
+		{"/subscription/resume", "resumeSubscription", "Resumes a paused subscription"},
+		{"/subscription/cancel", "cancelSubscription", "Cancels a subscription"},
+	} {
+		d.Add(payment(openapi.Endpoint{Method: http.MethodPatch, Path: change.path, ID: change.id, Tag: "subscriptions", Summary: change.summary,
+			Body:    subscription_domain.SubscriptionStateRequest{},
+			Replies: []openapi.Reply{{Status: http.StatusOK, Body: subscription_domain.SubscriptionResponse{}}},
+			Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	}
+
+	d.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/acs/challenges/:id", ID: "getChallenge", Tag: "3-D Secure", Summary: "Shows a 3-D Secure challenge to the cardholder",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: threeds_domain.ChallengeResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}})
+	d.Add(openapi.Endpoint{Method: http.MethodPost, Path: "/acs/challenges/:id", ID: "answerChallenge", Tag: "3-D Secure", Summary: "Answers a 3-D Secure challenge",
+		Body:    threeds_domain.ChallengeRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: threeds_domain.ChallengeResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}})
+
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/fraud/rules", ID: "listFraudRules", Tag: "fraud", Summary: "Lists the fraud rules",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: []fraud_domain.Rule{}}},
+		Errors:  []int{http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/fraud/rules", ID: "createFraudRule", Tag: "fraud", Summary: "Creates a fraud rule",
+		Body:    fraud_domain.RuleRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: fraud_domain.Rule{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPut, Path: "/admin/fraud/rules/:id", ID: "updateFraudRule", Tag: "fraud", Summary: "Replaces a fraud rule",
+		Body:    fraud_domain.RuleRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: fraud_domain.Rule{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodDelete, Path: "/admin/fraud/rules/:id", ID: "deleteFraudRule", Tag: "fraud", Summary: "Deletes a fraud rule",
+		Replies: []openapi.Reply{{Status: http.StatusNoContent}},
+		Errors:  []int{http.StatusNotFound, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPut, Path: "/admin/fraud/bins", ID: "saveBinCountries", Tag: "fraud", Summary: "Records the issuing country of card bins",
+		Body:    fraud_domain.BinCountriesRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusNoContent}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/fraud/dry-run", ID: "dryRunFraudRules", Tag: "fraud", Summary: "Evaluates the fraud rules against a transaction without recording it",
+		Body:    fraud_domain.Transaction{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: fraud_domain.Assessment{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/reviews", ID: "listReviews", Tag: "reviews", Summary: "Lists the authorisations held for review",
+		Params:  query("state"),
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: []review_domain.ReviewResponse{}}},
+		Errors:  []int{http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	for _, decision := range []struct{ path, id, summary string }{
+		{"/admin/reviews/approve", "approveReview", "Approves an authorisation held for review"},
+		{"/admin/reviews/decline", "declineReview", "Declines an authorisation held for review"},
+	} {
+		d.Add(admin(openapi.Endpoint{Method: http.MethodPatch, Path: decision.path, ID: decision.id, Tag: "reviews", Summary: decision.summary,
+			Body:    review_domain.ReviewDecisionRequest{},
+			Replies: []openapi.Reply{{Status: http.StatusOK, Body: review_domain.ReviewResponse{}}},
+			Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	}
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/settlements", ID: "listSettlementBatches", Tag: "settlements", Summary: "Lists the settlement batches",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: []settlement_domain.BatchResponse{}}},
+		Errors:  []int{http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/settlements", ID: "closeSettlementBatch", Tag: "settlements", Summary: "Closes the batch of the last cut-off",
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: settlement_domain.BatchResponse{}}},
+		Errors:  []int{http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/settlements/:id", ID: "getSettlementBatch", Tag: "settlements", Summary: "Returns a settlement batch",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: settlement_domain.BatchResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/settlements/:id/file", ID: "getSettlementFile", Tag: "settlements", Summary: "Downloads the settlement file of a batch",
+		Params: query("format"),
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Body: text, ContentType: "text/csv"},
+			{Status: http.StatusOK, Body: text, ContentType: "text/plain"},
+		},
+		Errors: []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/settlements/:id/submit", ID: "submitSettlementBatch", Tag: "settlements", Summary: "Marks a batch as submitted to the acquirer",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: settlement_domain.BatchResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/settlements/:id/reopen", ID: "reopenSettlementBatch", Tag: "settlements", Summary: "Reopens a closed batch",
+		Replies: []openapi.Reply{{Status: http.StatusNoContent}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/settlements/:id/reconciliations", ID: "importReconciliationReport", Tag: "reconciliations", Summary: "Reconciles a batch with the report of the acquirer",
+		Params: query("reference_column", "type_column", "amount_column", "currency_column"),
+		Body:   text, BodyType: "text/csv",
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: reconciliation_domain.ReconciliationResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/reconciliations", ID: "listReconciliations", Tag: "reconciliations", Summary: "Lists the reconciliations",
+		Params:  query("batch_id"),
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: []reconciliation_domain.ReconciliationResponse{}}},
+		Errors:  []int{http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/reconciliations/:id", ID: "getReconciliation", Tag: "reconciliations", Summary: "Returns a reconciliation with its items",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: reconciliation_domain.ReconciliationResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/ledger/balances", ID: "getLedgerBalances", Tag: "ledger", Summary: "Returns the balances of the ledger accounts",
+		Params:  query("auth_id", "merchant_id"),
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: []ledger_domain.BalanceResponse{}}},
+		Errors:  []int{http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/ledger/entries", ID: "listLedgerEntries", Tag: "ledger", Summary: "Lists the ledger entries of an authorisation",
+		Params:  query("auth_id"),
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: []ledger_domain.EntryResponse{}}},
+		Errors:  []int{http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/ledger/check", ID: "checkLedger", Tag: "ledger", Summary: "Checks the invariants of the ledger",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: ledger_domain.CheckResponse{}}},
+		Errors:  []int{http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/fees/:merchant_id", ID: "getFeeSchedule", Tag: "fees", Summary: "Returns the fee schedule of a merchant",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: fee_domain.ScheduleResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPut, Path: "/admin/fees/:merchant_id", ID: "saveFeeSchedule", Tag: "fees", Summary: "Saves the fee schedule of a merchant",
+		Body:    fee_domain.ScheduleRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: fee_domain.ScheduleResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodDelete, Path: "/admin/fees/:merchant_id", ID: "deleteFeeSchedule", Tag: "fees", Summary: "Deletes the fee schedule of a merchant",
+		Replies: []openapi.Reply{{Status: http.StatusNoContent}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/disputes", ID: "listDisputes", Tag: "disputes", Summary: "Lists the disputes",
+		Params:  query("state", "auth_id"),
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: []dispute_domain.DisputeResponse{}}},
+		Errors:  []int{http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/disputes", ID: "openDispute", Tag: "disputes", Summary: "Opens a dispute on a captured transaction",
+		Body:    dispute_domain.DisputeRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: dispute_domain.DisputeResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/disputes/:id", ID: "getDispute", Tag: "disputes", Summary: "Returns a dispute with its evidence and events",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: dispute_domain.DisputeResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPatch, Path: "/admin/disputes/:id", ID: "updateDisputeState", Tag: "disputes", Summary: "Moves a dispute to another state",
+		Body:    dispute_domain.StateRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: dispute_domain.DisputeResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/disputes/:id/evidence", ID: "addDisputeEvidence", Tag: "disputes", Summary: "Uploads a piece of evidence for a dispute",
+		Body: upload, BodyType: "multipart/form-data",
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: dispute_domain.EvidenceResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/disputes/:id/evidence/:evidence_id", ID: "getDisputeEvidence", Tag: "disputes", Summary: "Downloads a piece of evidence",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: binary, ContentType: "application/octet-stream"}},
+		Errors:  []int{http.StatusNotFound, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodPost, Path: "/admin/disputes/:id/submit", ID: "submitDispute", Tag: "disputes", Summary: "Submits the evidence of a dispute to the acquirer",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: dispute_domain.DisputeResponse{}}},
+		Errors:  []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/transactions", ID: "searchAllTransactions", Tag: "transactions", Summary: "Searches the transactions of every merchant, a page at a time",
+		Query:   transaction_domain.SearchRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: transaction_domain.SearchResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/transactions/export", ID: "exportTransactions", Tag: "transactions", Summary: "Exports the operations made over a period",
+		Query: transaction_domain.ExportRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Body: text, ContentType: "text/csv"},
+			{Status: http.StatusOK, Body: text, ContentType: "application/x-ndjson"},
+		},
+		Errors: []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	d.Add(admin(openapi.Endpoint{Method: http.MethodGet, Path: "/admin/reports/summary", ID: "getOverallSummaryReport", Tag: "reports", Summary: "Sums up the activity of every merchant over a period by bucket",
+		Query:   report_domain.SummaryRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: report_domain.SummaryResponse{}}},
+		Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}}))
+	return d
+}
+
+//payment describes a payment endpoint, those are rate limited by merchant
+func payment(endpoint openapi.Endpoint) openapi.Endpoint {
+	endpoint.Headers = append(endpoint.Headers, openapi.Parameter{
+		Name:        middleware.MerchantIDHeader,
+		In:          "header",
+		Description: "the merchant making the request, the rate limits are applied by merchant and the transactions and reports are only read by their merchant",
+		Schema:      &openapi.Schema{Type: "string"},
+	})
+	endpoint.Errors = append(endpoint.Errors, http.StatusTooManyRequests)
+	return endpoint
+}
+
+//deprecated describes a legacy endpoint, its successful responses carry the deprecation headers
+func deprecated(endpoint openapi.Endpoint) openapi.Endpoint {
+	endpoint.Deprecated = true
+	replies := make([]openapi.Reply, 0, len(endpoint.Replies))
+	for _, reply := range endpoint.Replies {
+		reply.Headers = map[string]string{
+			middleware.DeprecationHeader: "always true, the route is superseded by the versioned API",
+			middleware.LinkHeader:        "the successor of the route in the versioned API",
+		}
+		replies = append(replies, reply)
+	}
+	endpoint.Replies = replies
+	return endpoint
+}
+
+//admin describes an admin endpoint, those require the admin token
+func admin(endpoint openapi.Endpoint) openapi.Endpoint {
+	endpoint.Admin = true
+	endpoint.Errors = append(endpoint.Errors, http.StatusUnauthorized)
+	return endpoint
+}
+
+//query describes the query parameters read one by one by the handlers
+func query(names ...string) []openapi.Parameter {
+	parameters := make([]openapi.Parameter, 0, len(names))
+	for _, name := range names {
+		parameters = append(parameters, openapi.Parameter{Name: name, In: "query", Schema: &openapi.Schema{Type: "string"}})
+	}
+	return parameters
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/openapi"
+	"sort"
+	"testing"
+)
+
+//update rewrites the published document from the endpoints and the domain structs: go test ./api/app -run TestSpecification -update
+var update = flag.Bool("update", false, "rewrite testdata/openapi.json from the specification")
+
+const specFile = "testdata/openapi.json"
+
+func TestSpecification_MatchesRoutes(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	//every optional route is served so that all of them are compared
+	cfg := config.Default()
+	cfg.Database.DSN = filepath.Join(dir, "gateway.db")
+	cfg.Admin.Token = "s3cret"
+	cfg.Features.Subscriptions = true
+	gateway := newTestAppWithConfig(t, cfg)
+	defer gateway.Close()
+
+	var routes []string
+	for _, route := range gateway.container.router().Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	sort.Strings(routes)
+	assert.EqualValues(t, routes, specification().Operations(), "routes() and specification() describe different endpoints")
+}
+
+func TestSpecification_MatchesPublishedDocument(t *testing.T) {
+	t.Parallel()
+	generated, err := json.MarshalIndent(specification(), "", "  ")
+	assert.Nil(t, err)
+	generated = append(generated, '\n')
+	if *update {
+		assert.Nil(t, ioutil.WriteFile(specFile, generated, 0644))
+	}
+
+	published, err := ioutil.ReadFile(specFile)
+	assert.Nil(t, err)
+	//a difference is a change of the contract, it is reviewed by running the test with -update and diffing the file
+	assert.True(t, bytes.Equal(published, generated), "the endpoints or the domain structs have drifted from "+specFile+", run the test with -update")
+}
+
+func TestRouter_OpenAPI(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gateway")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gateway := newTestApp(t, filepath.Join(dir, "gateway.db"))
+	defer gateway.Close()
+	router := gateway.container.router()
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	var document openapi.Document
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &document))
+	assert.EqualValues(t, openapi.Version, document.OpenAPI)
+	capture := document.Paths["/capture"]["patch"]
+	assert.Contains(t, capture.Responses, "422")
+	assert.EqualValues(t, "#/components/schemas/capture_domain.CaptureRequest", capture.RequestBody.Content[openapi.ContentJSON].Schema.Ref)
+	assert.Contains(t, document.Components.Schemas["auth_domain.AuthResponse"].Properties, "liability_shift")
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, response.Body.String(), `url: "/openapi.json"`)
+}
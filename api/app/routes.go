@@ -1,15 +1,85 @@
 package app
 
 import (
-	"payment-gateway-api/api/controllers/authorisation_controller"
-	"payment-gateway-api/api/controllers/capture_controller"
-	"payment-gateway-api/api/controllers/refund_controller"
-	"payment-gateway-api/api/controllers/void_controller"
+	"github.com/gin-gonic/gin"
+	"payment-gateway-api/api/middleware"
 )
 
-func routes() {
-	router.POST("/authorize", authorisation_controller.HandleAuthorisationRequest)
-	router.PATCH("/void", void_controller.HandleVoidRequest)
-	router.PATCH("/capture", capture_controller.HandleCaptureRequest)
-	router.PATCH("/refund", refund_controller.HandleRefundRequest)
+func routes(router *gin.Engine, c *container) {
+	router.GET("/healthz", c.healthHandler.HandleHealthRequest)
+	router.GET("/readyz", c.healthHandler.HandleReadinessRequest)
+	router.GET("/version", c.healthHandler.HandleVersionRequest)
+	router.GET("/metrics", gin.WrapH(c.metrics.Handler()))
+	router.GET("/openapi.json", c.docsHandler.HandleSpecRequest)
+	router.GET("/docs", c.docsHandler.HandleSwaggerUIRequest)
+
+	//probes and scrapes are never throttled, only the payment routes are
+	payments := router.Group("", middleware.RateLimit(c.limiter, c.limits, c.metrics))
+	v1 := payments.Group("/v1")
+	v1.POST("/authorisations", c.authorisationHandler.HandleCreateAuthorisationRequest)
+	v1.GET("/authorisations/:id", c.transactionHandler.HandleGetTransactionRequest)
+	v1.POST("/authorisations/:id/complete", c.authorisationHandler.HandleCompleteAuthorisationRequest)
+	v1.POST("/authorisations/:id/captures", c.captureHandler.HandleCreateCaptureRequest)
+	v1.POST("/authorisations/:id/refunds", c.refundHandler.HandleCreateRefundRequest)
+	v1.POST("/authorisations/:id/void", c.voidHandler.HandleVoidAuthorisationRequest)
+
+	//the verb routes taking the authorisation ID in the body stay available until the clients have moved to /v1
+	payments.POST("/authorize", middleware.Deprecated("/v1/authorisations"), c.authorisationHandler.HandleAuthorisationRequest)
+	payments.POST("/authorize/:id/complete", middleware.Deprecated("/v1/authorisations/{id}/complete"), c.authorisationHandler.HandleCompleteAuthorisationRequest)
+	payments.PATCH("/void", middleware.Deprecated("/v1/authorisations/{id}/void"), c.voidHandler.HandleVoidRequest)
+	payments.PATCH("/capture", middleware.Deprecated("/v1/authorisations/{id}/captures"), c.captureHandler.HandleCaptureRequest)
+	payments.PATCH("/refund", middleware.Deprecated("/v1/authorisations/{id}/refunds"), c.refundHandler.HandleRefundRequest)
+	payments.GET("/transactions", c.transactionHandler.HandleSearchTransactionsRequest)
+	payments.GET("/transactions/:id", c.transactionHandler.HandleGetTransactionRequest)
+	payments.GET("/reports/summary", c.reportHandler.HandleSummaryRequest)
+
+	if c.features.Subscriptions {
+		payments.POST("/subscription", c.subscriptionHandler.HandleCreateSubscriptionRequest)
+		payments.PATCH("/subscription/pause", c.subscriptionHandler.HandlePauseSubscriptionRequest)
+		payments.PATCH("/subscription/resume", c.subscriptionHandler.HandleResumeSubscriptionRequest)
+		payments.PATCH("/subscription/cancel", c.subscriptionHandler.HandleCancelSubscriptionRequest)
+	}
+
+	//the simulated access control server stands for the issuers, the challenge urls point to it by default
+	router.GET("/acs/challenges/:id", c.acsHandler.HandleGetChallengeRequest)
+	router.POST("/acs/challenges/:id", c.acsHandler.HandleAnswerChallengeRequest)
+
+	//the admin endpoints are only served when a token has been configured
+	if c.admin.Token != "" {
+		admin := router.Group("/admin", middleware.AdminAuth(c.admin.Token))
+		admin.GET("/fraud/rules", c.fraudHandler.HandleListRulesRequest)
+		admin.POST("/fraud/rules", c.fraudHandler.HandleCreateRuleRequest)
+		admin.PUT("/fraud/rules/:id", c.fraudHandler.HandleUpdateRuleRequest)
+		admin.DELETE("/fraud/rules/:id", c.fraudHandler.HandleDeleteRuleRequest)
+		admin.PUT("/fraud/bins", c.fraudHandler.HandleBinCountriesRequest)
+		admin.POST("/fraud/dry-run", c.fraudHandler.HandleDryRunRequest)
+		admin.GET("/reviews", c.reviewHandler.HandleListReviewsRequest)
+		admin.PATCH("/reviews/approve", c.reviewHandler.HandleApproveReviewRequest)
+		admin.PATCH("/reviews/decline", c.reviewHandler.HandleDeclineReviewRequest)
+		admin.GET("/settlements", c.settlementHandler.HandleListBatchesRequest)
+		admin.POST("/settlements", c.settlementHandler.HandleCloseBatchRequest)
+		admin.GET("/settlements/:id", c.settlementHandler.HandleGetBatchRequest)
+		admin.GET("/settlements/:id/file", c.settlementHandler.HandleSettlementFileRequest)
+		admin.POST("/settlements/:id/submit", c.settlementHandler.HandleSubmitBatchRequest)
+		admin.POST("/settlements/:id/reopen", c.settlementHandler.HandleReopenBatchRequest)
+		admin.POST("/settlements/:id/reconciliations", c.reconciliationHandler.HandleImportReportRequest)
+		admin.GET("/reconciliations", c.reconciliationHandler.HandleListReconciliationsRequest)
+		admin.GET("/reconciliations/:id", c.reconciliationHandler.HandleGetReconciliationRequest)
+		admin.GET("/ledger/balances", c.ledgerHandler.HandleBalancesRequest)
+		admin.GET("/ledger/entries", c.ledgerHandler.HandleEntriesRequest)
+		admin.GET("/ledger/check", c.ledgerHandler.HandleCheckRequest)
+		admin.GET("/fees/:merchant_id", c.feeHandler.HandleGetScheduleRequest)
+		admin.PUT("/fees/:merchant_id", c.feeHandler.HandleSaveScheduleRequest)
+		admin.DELETE("/fees/:merchant_id", c.feeHandler.HandleDeleteScheduleRequest)
+		admin.GET("/disputes", c.disputeHandler.HandleListDisputesRequest)
+		admin.POST("/disputes", c.disputeHandler.HandleOpenDisputeRequest)
+		admin.GET("/disputes/:id", c.disputeHandler.HandleGetDisputeRequest)
+		admin.PATCH("/disputes/:id", c.disputeHandler.HandleUpdateStateRequest)
+		admin.POST("/disputes/:id/evidence", c.disputeHandler.HandleAddEvidenceRequest)
+		admin.GET("/disputes/:id/evidence/:evidence_id", c.disputeHandler.HandleGetEvidenceRequest)
+		admin.POST("/disputes/:id/submit", c.disputeHandler.HandleSubmitDisputeRequest)
+		admin.GET("/transactions", c.transactionHandler.HandleSearchAllTransactionsRequest)
+		admin.GET("/transactions/export", c.transactionHandler.HandleExportRequest)
+		admin.GET("/reports/summary", c.reportHandler.HandleOverallSummaryRequest)
+	}
 }
@@ -0,0 +1,10 @@
+package build
+
+//the build information is set when the binary is built, e.g.
+//go build -ldflags "-X payment-gateway-api/api/build.GitSHA=$(git rev-parse HEAD) -X payment-gateway-api/api/build.Time=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	//GitSHA is the commit the binary has been built from
+	GitSHA = "unknown"
+	//Time is the time the binary has been built at
+	Time = "unknown"
+)
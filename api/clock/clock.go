@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+//Clock provides the current time so that time dependent logic can be tested
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+//New returns a clock backed by the system time
+func New() Clock {
+	return &realClock{}
+}
+
+//Now returns the current system time in UTC, the timestamps are stored and compared in UTC whatever the zone of the host
+func (c *realClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+//FakeClock is a clock whose time is set manually, it is meant to be used in tests
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+//NewFake returns a fake clock frozen at the given time
+func NewFake(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+//Now returns the time the fake clock is currently set to
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+//Set moves the fake clock to the given time
+func (f *FakeClock) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+//Advance moves the fake clock forward by the given duration
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
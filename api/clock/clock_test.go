@@ -0,0 +1,29 @@
+package clock
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, time.January, 31, 10, 0, 0, 0, time.UTC)
+	fake := NewFake(now)
+	assert.EqualValues(t, now, fake.Now())
+
+	fake.Advance(24 * time.Hour)
+	assert.EqualValues(t, now.Add(24*time.Hour), fake.Now())
+
+	later := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	fake.Set(later)
+	assert.EqualValues(t, later, fake.Now())
+}
+
+func TestRealClock(t *testing.T) {
+	t.Parallel()
+	before := time.Now()
+	actual := New().Now()
+	assert.False(t, actual.Before(before))
+	assert.EqualValues(t, time.UTC, actual.Location())
+}
@@ -1,9 +1,649 @@
 package config
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Config is the configuration of the gateway, it is loaded once at startup and passed to the components needing it
+type Config struct {
+	Database       DatabaseConfig       `yaml:"database" json:"database"`
+	Server         ServerConfig         `yaml:"server" json:"server"`
+	GRPC           GRPCConfig           `yaml:"grpc" json:"grpc"`
+	Logging        LoggingConfig        `yaml:"logging" json:"logging"`
+	Features       FeaturesConfig       `yaml:"features" json:"features"`
+	Limits         LimitsConfig         `yaml:"limits" json:"limits"`
+	Subscriptions  SubscriptionsConfig  `yaml:"subscriptions" json:"subscriptions"`
+	Timeouts       TimeoutsConfig       `yaml:"timeouts" json:"timeouts"`
+	RateLimits     RateLimitsConfig     `yaml:"rate_limits" json:"rate_limits"`
+	Admin          AdminConfig          `yaml:"admin" json:"admin"`
+	Reviews        ReviewsConfig        `yaml:"reviews" json:"reviews"`
+	ThreeDS        ThreeDSConfig        `yaml:"three_ds" json:"three_ds"`
+	Settlement     SettlementConfig     `yaml:"settlement" json:"settlement"`
+	Reconciliation ReconciliationConfig `yaml:"reconciliation" json:"reconciliation"`
+	Disputes       DisputesConfig       `yaml:"disputes" json:"disputes"`
+}
+
+//DatabaseConfig defines the database the gateway stores its records in
+type DatabaseConfig struct {
+	Driver string `yaml:"driver" json:"driver"`
+	DSN    string `yaml:"dsn" json:"dsn"`
+}
+
+//ServerConfig defines the http server, TLS is enabled when both the cert and key files are set
+type ServerConfig struct {
+	ListenAddress   string   `yaml:"listen_address" json:"listen_address"`
+	ReadTimeout     Duration `yaml:"read_timeout" json:"read_timeout"`
+	WriteTimeout    Duration `yaml:"write_timeout" json:"write_timeout"`
+	IdleTimeout     Duration `yaml:"idle_timeout" json:"idle_timeout"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+	ShutdownDelay   Duration `yaml:"shutdown_delay" json:"shutdown_delay"`
+	MaxHeaderBytes  int      `yaml:"max_header_bytes" json:"max_header_bytes"`
+	MaxBodyBytes    int64    `yaml:"max_body_bytes" json:"max_body_bytes"`
+	TLSCertFile     string   `yaml:"tls_cert_file" json:"tls_cert_file"`
+	TLSKeyFile      string   `yaml:"tls_key_file" json:"tls_key_file"`
+}
+
+//GRPCConfig defines the gRPC server of the payment calls, it is only served when an address is set and uses the
+//TLS cert and key files of the http server when they are set
+type GRPCConfig struct {
+	ListenAddress string `yaml:"listen_address" json:"listen_address"`
+}
+
+//LoggingConfig defines how verbose the gateway is
+type LoggingConfig struct {
+	Level string `yaml:"level" json:"level"`
+}
+
+//FeaturesConfig toggles the optional parts of the gateway
+type FeaturesConfig struct {
+	Subscriptions bool `yaml:"subscriptions" json:"subscriptions"`
+}
+
+//LimitsConfig defines the business limits enforced by the services
+type LimitsConfig struct {
+	MaxAuthorisationAmount float32 `yaml:"max_authorisation_amount" json:"max_authorisation_amount"`
+}
+
+//SubscriptionsConfig defines the recurring billing scheduler and its retries of declined charges
+type SubscriptionsConfig struct {
+	SchedulerInterval Duration   `yaml:"scheduler_interval" json:"scheduler_interval"`
+	RetryIntervals    []Duration `yaml:"retry_intervals" json:"retry_intervals"`
+}
+
+//TimeoutsConfig defines how long a request can run before its context expires, endpoints are keyed by route
+//and fall back on the default
+type TimeoutsConfig struct {
+	Default   Duration            `yaml:"default" json:"default"`
+	Endpoints map[string]Duration `yaml:"endpoints" json:"endpoints"`
+}
+
+//For returns the timeout of the given route
+func (t TimeoutsConfig) For(route string) time.Duration {
+	if timeout, ok := t.Endpoints[route]; ok {
+		return timeout.Duration
+	}
+	return t.Default.Duration
+}
+
+//RateLimitsConfig defines the token buckets the payment requests are taken from, a request needs a token from
+//the bucket of its merchant and from the bucket of its client ip, endpoints are keyed by route and fall back on the default
+type RateLimitsConfig struct {
+	Enabled   bool                         `yaml:"enabled" json:"enabled"`
+	Default   EndpointRateLimit            `yaml:"default" json:"default"`
+	Endpoints map[string]EndpointRateLimit `yaml:"endpoints" json:"endpoints"`
+}
+
+//EndpointRateLimit is the limit of the merchant buckets and of the client ip buckets of an endpoint
+type EndpointRateLimit struct {
+	Merchant RateLimit `yaml:"merchant" json:"merchant"`
+	ClientIP RateLimit `yaml:"client_ip" json:"client_ip"`
+}
+
+//RateLimit is the number of requests per second a bucket is refilled with and the number of requests it holds
+type RateLimit struct {
+	PerSecond float64 `yaml:"per_second" json:"per_second"`
+	Burst     int     `yaml:"burst" json:"burst"`
+}
+
+//For returns the rate limit of the given route
+func (r RateLimitsConfig) For(route string) EndpointRateLimit {
+	if limit, ok := r.Endpoints[route]; ok {
+		return limit
+	}
+	return r.Default
+}
+
+//AdminConfig defines the access to the admin endpoints, they are only served when a token is set and
+//every request to them must carry it as a bearer token
+type AdminConfig struct {
+	Token string `yaml:"token" json:"token"`
+}
+
+//ReviewsConfig defines how long the authorisations held for review wait for an analyst before they are
+//declined, and how often the expired ones are looked for
+type ReviewsConfig struct {
+	SLA           Duration `yaml:"sla" json:"sla"`
+	SweepInterval Duration `yaml:"sweep_interval" json:"sweep_interval"`
+}
+
+//ThreeDSConfig defines the cards the cardholder must authenticate, those issued in one of the countries, how long
+//the cardholder has to complete the challenge and the address of the access control server serving the challenges
+type ThreeDSConfig struct {
+	Countries    []string `yaml:"countries" json:"countries"`
+	ChallengeTTL Duration `yaml:"challenge_ttl" json:"challenge_ttl"`
+	ACSURL       string   `yaml:"acs_url" json:"acs_url"`
+}
+
+//SettlementConfig defines the time of the day, in UTC and in the HH:MM format, the settlement batch is closed at
+//and how often the scheduler looks for a batch to close
+type SettlementConfig struct {
+	CutOff        string   `yaml:"cut_off" json:"cut_off"`
+	CloseInterval Duration `yaml:"close_interval" json:"close_interval"`
+}
+
+//CutOffOfDay returns the time elapsed since midnight UTC at the cut-off, the cut-off is validated when the configuration is loaded
+func (s SettlementConfig) CutOffOfDay() time.Duration {
+	cutOff, _ := time.Parse("15:04", s.CutOff)
+	return time.Duration(cutOff.Hour())*time.Hour + time.Duration(cutOff.Minute())*time.Minute
+}
+
+//ReconciliationConfig defines the headers of the columns of the acquirer settlement reports holding the
+//authorisation id, the type of operation, the amount and the currency paid out, the type and currency are optional,
+//and the maximum size in bytes of a report
+type ReconciliationConfig struct {
+	Columns       ReconciliationColumns `yaml:"columns" json:"columns"`
+	MaxReportSize int64                 `yaml:"max_report_size" json:"max_report_size"`
+}
+
+//ReconciliationColumns maps the values read from the acquirer settlement reports to the headers of their columns
+type ReconciliationColumns struct {
+	Reference string `yaml:"reference" json:"reference"`
+	Type      string `yaml:"type" json:"type"`
+	Amount    string `yaml:"amount" json:"amount"`
+	Currency  string `yaml:"currency" json:"currency"`
+}
+
+//DisputesConfig defines the directory the dispute evidence is stored in, the maximum size in bytes of an evidence
+//document, the time the merchant has to answer a dispute and how often the overdue disputes are looked for
+type DisputesConfig struct {
+	EvidenceDir     string   `yaml:"evidence_dir" json:"evidence_dir"`
+	MaxEvidenceSize int64    `yaml:"max_evidence_size" json:"max_evidence_size"`
+	ResponseWindow  Duration `yaml:"response_window" json:"response_window"`
+	SweepInterval   Duration `yaml:"sweep_interval" json:"sweep_interval"`
+}
+
+//Duration is a time.Duration written as a string such as "30s" in the configuration file
+type Duration struct {
+	time.Duration
+}
+
+//UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return d.parse(value)
+}
+
+//MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+//UnmarshalYAML parses a duration string
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return d.parse(value)
+}
+
+func (d *Duration) parse(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+//Durations converts the configured durations into time.Duration values
+func Durations(durations []Duration) []time.Duration {
+	result := make([]time.Duration, 0, len(durations))
+	for _, d := range durations {
+		result = append(result, d.Duration)
+	}
+	return result
+}
+
 var (
-	DbStoreFilePath      = "./api/data_access/db_store/gateway.db"
-	ExpirationDateLayout = "01-2006"
-	UUIDCodeLayout       = "^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$"
-	CvvFormatLayout      = "^[0-9]{3,4}$"
-	CurrencyCodeLayout   = "^[A-Z]{3}$"
+	supportedDrivers  = []string{"sqlite3"}
+	supportedLogLevel = []string{"debug", "info", "warn", "error"}
+	countryPattern    = regexp.MustCompile("^[A-Z]{2}$")
 )
+
+//Default returns the configuration used when nothing overrides it
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Driver: "sqlite3",
+			DSN:    "./api/data_access/db_store/gateway.db",
+		},
+		Server: ServerConfig{
+			ListenAddress:   ":8080",
+			ReadTimeout:     Duration{10 * time.Second},
+			WriteTimeout:    Duration{30 * time.Second},
+			IdleTimeout:     Duration{120 * time.Second},
+			ShutdownTimeout: Duration{30 * time.Second},
+			MaxHeaderBytes:  1 << 20,
+			MaxBodyBytes:    64 << 10,
+		},
+		GRPC: GRPCConfig{
+			ListenAddress: "",
+		},
+		Logging: LoggingConfig{
+			Level: "info",
+		},
+		Features: FeaturesConfig{
+			Subscriptions: true,
+		},
+		Limits: LimitsConfig{
+			MaxAuthorisationAmount: 100000,
+		},
+		Subscriptions: SubscriptionsConfig{
+			SchedulerInterval: Duration{time.Minute},
+			RetryIntervals:    []Duration{{24 * time.Hour}, {72 * time.Hour}, {168 * time.Hour}},
+		},
+		Timeouts: TimeoutsConfig{
+			Default:   Duration{10 * time.Second},
+			Endpoints: map[string]Duration{},
+		},
+		RateLimits: RateLimitsConfig{
+			Enabled: true,
+			Default: EndpointRateLimit{
+				Merchant: RateLimit{PerSecond: 50, Burst: 100},
+				ClientIP: RateLimit{PerSecond: 100, Burst: 200},
+			},
+			Endpoints: map[string]EndpointRateLimit{},
+		},
+		Admin: AdminConfig{
+			Token: "",
+		},
+		Reviews: ReviewsConfig{
+			SLA:           Duration{24 * time.Hour},
+			SweepInterval: Duration{time.Minute},
+		},
+		ThreeDS: ThreeDSConfig{
+			//the member states of the European Union
+			Countries: []string{"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU", "IE", "IT",
+				"LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK"},
+			ChallengeTTL: Duration{10 * time.Minute},
+			ACSURL:       "/acs/challenges",
+		},
+		Settlement: SettlementConfig{
+			CutOff:        "22:00",
+			CloseInterval: Duration{time.Minute},
+		},
+		Reconciliation: ReconciliationConfig{
+			Columns: ReconciliationColumns{
+				Reference: "reference",
+				Type:      "type",
+				Amount:    "amount",
+				Currency:  "currency",
+			},
+			MaxReportSize: 10 << 20,
+		},
+		Disputes: DisputesConfig{
+			EvidenceDir:     "evidence",
+			MaxEvidenceSize: 10 << 20,
+			ResponseWindow:  Duration{7 * 24 * time.Hour},
+			SweepInterval:   Duration{time.Minute},
+		},
+	}
+}
+
+//setting is a configuration value that can be overridden by an environment variable and a command-line flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(*Config, string) error
+}
+
+var settings = []setting{
+	{"db-driver", "GATEWAY_DB_DRIVER", "database driver", func(c *Config, v string) error {
+		c.Database.Driver = v
+		return nil
+	}},
+	{"db-dsn", "GATEWAY_DB_DSN", "database data source name", func(c *Config, v string) error {
+		c.Database.DSN = v
+		return nil
+	}},
+	{"listen-address", "GATEWAY_LISTEN_ADDRESS", "address the http server listens on", func(c *Config, v string) error {
+		c.Server.ListenAddress = v
+		return nil
+	}},
+	{"read-timeout", "GATEWAY_READ_TIMEOUT", "http server read timeout", func(c *Config, v string) error {
+		return c.Server.ReadTimeout.parse(v)
+	}},
+	{"write-timeout", "GATEWAY_WRITE_TIMEOUT", "http server write timeout", func(c *Config, v string) error {
+		return c.Server.WriteTimeout.parse(v)
+	}},
+	{"idle-timeout", "GATEWAY_IDLE_TIMEOUT", "http server idle timeout", func(c *Config, v string) error {
+		return c.Server.IdleTimeout.parse(v)
+	}},
+	{"shutdown-timeout", "GATEWAY_SHUTDOWN_TIMEOUT", "time given to in-flight requests on shutdown", func(c *Config, v string) error {
+		return c.Server.ShutdownTimeout.parse(v)
+	}},
+	{"shutdown-delay", "GATEWAY_SHUTDOWN_DELAY", "time readiness fails before the server stops accepting connections", func(c *Config, v string) error {
+		return c.Server.ShutdownDelay.parse(v)
+	}},
+	{"max-header-bytes", "GATEWAY_MAX_HEADER_BYTES", "maximum size of the request headers", func(c *Config, v string) error {
+		parsed, err := strconv.Atoi(v)
+		c.Server.MaxHeaderBytes = parsed
+		return err
+	}},
+	{"max-body-bytes", "GATEWAY_MAX_BODY_BYTES", "maximum size of the request bodies", func(c *Config, v string) error {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		c.Server.MaxBodyBytes = parsed
+		return err
+	}},
+	{"tls-cert-file", "GATEWAY_TLS_CERT_FILE", "certificate file enabling https", func(c *Config, v string) error {
+		c.Server.TLSCertFile = v
+		return nil
+	}},
+	{"tls-key-file", "GATEWAY_TLS_KEY_FILE", "private key file enabling https", func(c *Config, v string) error {
+		c.Server.TLSKeyFile = v
+		return nil
+	}},
+	{"grpc-listen-address", "GATEWAY_GRPC_LISTEN_ADDRESS", "address of the grpc server, it is disabled when empty", func(c *Config, v string) error {
+		c.GRPC.ListenAddress = v
+		return nil
+	}},
+	{"log-level", "GATEWAY_LOG_LEVEL", "logging level among debug, info, warn and error", func(c *Config, v string) error {
+		c.Logging.Level = v
+		return nil
+	}},
+	{"feature-subscriptions", "GATEWAY_FEATURE_SUBSCRIPTIONS", "enables recurring billing subscriptions", func(c *Config, v string) error {
+		parsed, err := strconv.ParseBool(v)
+		c.Features.Subscriptions = parsed
+		return err
+	}},
+	{"max-authorisation-amount", "GATEWAY_MAX_AUTHORISATION_AMOUNT", "maximum amount of a single authorisation", func(c *Config, v string) error {
+		parsed, err := strconv.ParseFloat(v, 32)
+		c.Limits.MaxAuthorisationAmount = float32(parsed)
+		return err
+	}},
+	{"subscription-scheduler-interval", "GATEWAY_SUBSCRIPTION_SCHEDULER_INTERVAL", "how often due subscriptions are charged", func(c *Config, v string) error {
+		return c.Subscriptions.SchedulerInterval.parse(v)
+	}},
+	{"rate-limits", "GATEWAY_RATE_LIMITS", "enables the rate limits of the payment endpoints", func(c *Config, v string) error {
+		parsed, err := strconv.ParseBool(v)
+		c.RateLimits.Enabled = parsed
+		return err
+	}},
+	{"admin-token", "GATEWAY_ADMIN_TOKEN", "bearer token of the admin endpoints, they are disabled when empty", func(c *Config, v string) error {
+		c.Admin.Token = v
+		return nil
+	}},
+	{"review-sla", "GATEWAY_REVIEW_SLA", "time after which the authorisations not reviewed are declined", func(c *Config, v string) error {
+		return c.Reviews.SLA.parse(v)
+	}},
+	{"three-ds-countries", "GATEWAY_THREE_DS_COUNTRIES", "comma separated issuing countries of the cards to authenticate", func(c *Config, v string) error {
+		c.ThreeDS.Countries = []string{}
+		for _, country := range strings.Split(v, ",") {
+			if country = strings.TrimSpace(country); country != "" {
+				c.ThreeDS.Countries = append(c.ThreeDS.Countries, country)
+			}
+		}
+		return nil
+	}},
+	{"acs-url", "GATEWAY_ACS_URL", "address of the access control server serving the 3-D Secure challenges", func(c *Config, v string) error {
+		c.ThreeDS.ACSURL = v
+		return nil
+	}},
+	{"settlement-cut-off", "GATEWAY_SETTLEMENT_CUT_OFF", "time of the day in UTC, as HH:MM, the settlement batch is closed at", func(c *Config, v string) error {
+		c.Settlement.CutOff = v
+		return nil
+	}},
+	{"dispute-evidence-dir", "GATEWAY_DISPUTE_EVIDENCE_DIR", "directory the dispute evidence documents are stored in", func(c *Config, v string) error {
+		c.Disputes.EvidenceDir = v
+		return nil
+	}},
+	{"dispute-response-window", "GATEWAY_DISPUTE_RESPONSE_WINDOW", "time the merchant has to answer a dispute before it is lost", func(c *Config, v string) error {
+		return c.Disputes.ResponseWindow.parse(v)
+	}},
+	{"request-timeout", "GATEWAY_REQUEST_TIMEOUT", "default time a request can run before it is cancelled", func(c *Config, v string) error {
+		return c.Timeouts.Default.parse(v)
+	}},
+}
+
+//Load builds the configuration from the defaults, then the configuration file, then the environment
+//variables and finally the command-line flags, each overriding the previous one, and validates the result
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("payment-gateway-api", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("GATEWAY_CONFIG_FILE"), "path to a yaml or json configuration file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.flag] = fs.String(s.flag, "", s.usage+" (env "+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(cfg, value); err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %v", value, s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := s.set(cfg, *flagValues[s.flag]); err != nil {
+					flagErr = fmt.Errorf("invalid value %q for -%s: %v", *flagValues[s.flag], s.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+//loadFile overrides the configuration with the content of a yaml or json file
+func loadFile(cfg *Config, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read configuration file: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(content, cfg)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("configuration file %s must have a .yaml, .yml or .json extension", path)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse configuration file %s: %v", path, err)
+	}
+	return nil
+}
+
+//Validate checks the configuration values and reports every invalid one
+func (c *Config) Validate() error {
+	var errs []string
+
+	if !contains(supportedDrivers, c.Database.Driver) {
+		errs = append(errs, fmt.Sprintf("database driver %q is not supported, use one of %v", c.Database.Driver, supportedDrivers))
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, "database dsn cannot be empty")
+	}
+	if _, _, err := net.SplitHostPort(c.Server.ListenAddress); err != nil {
+		errs = append(errs, fmt.Sprintf("listen address %q is not valid: %v", c.Server.ListenAddress, err))
+	}
+	for name, d := range map[string]Duration{
+		"read timeout":     c.Server.ReadTimeout,
+		"write timeout":    c.Server.WriteTimeout,
+		"idle timeout":     c.Server.IdleTimeout,
+		"shutdown timeout": c.Server.ShutdownTimeout,
+	} {
+		if d.Duration <= 0 {
+			errs = append(errs, name+" must be positive")
+		}
+	}
+	if c.Server.ShutdownDelay.Duration < 0 {
+		errs = append(errs, "shutdown delay cannot be negative")
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		errs = append(errs, "max header bytes must be positive")
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		errs = append(errs, "max body bytes must be positive")
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, "tls cert file and tls key file must be set together")
+	}
+	if c.GRPC.ListenAddress != "" {
+		if _, _, err := net.SplitHostPort(c.GRPC.ListenAddress); err != nil {
+			errs = append(errs, fmt.Sprintf("grpc listen address %q is not valid: %v", c.GRPC.ListenAddress, err))
+		}
+	}
+	if !contains(supportedLogLevel, c.Logging.Level) {
+		errs = append(errs, fmt.Sprintf("log level %q is not valid, use one of %v", c.Logging.Level, supportedLogLevel))
+	}
+	if c.Limits.MaxAuthorisationAmount <= 0 {
+		errs = append(errs, "max authorisation amount must be positive")
+	}
+	if c.Subscriptions.SchedulerInterval.Duration <= 0 {
+		errs = append(errs, "subscription scheduler interval must be positive")
+	}
+	for _, d := range c.Subscriptions.RetryIntervals {
+		if d.Duration <= 0 {
+			errs = append(errs, "subscription retry intervals must be positive")
+			break
+		}
+	}
+	if c.Reviews.SLA.Duration <= 0 {
+		errs = append(errs, "review sla must be positive")
+	}
+	if c.Reviews.SweepInterval.Duration <= 0 {
+		errs = append(errs, "review sweep interval must be positive")
+	}
+	for _, country := range c.ThreeDS.Countries {
+		if !countryPattern.MatchString(country) {
+			errs = append(errs, fmt.Sprintf("3-D Secure country %q must be an ISO 3166 alpha-2 code", country))
+		}
+	}
+	if c.ThreeDS.ChallengeTTL.Duration <= 0 {
+		errs = append(errs, "3-D Secure challenge ttl must be positive")
+	}
+	if c.ThreeDS.ACSURL == "" {
+		errs = append(errs, "acs url cannot be empty")
+	}
+	if _, err := time.Parse("15:04", c.Settlement.CutOff); err != nil {
+		errs = append(errs, fmt.Sprintf("settlement cut-off %q must be a time of the day as HH:MM", c.Settlement.CutOff))
+	}
+	if c.Settlement.CloseInterval.Duration <= 0 {
+		errs = append(errs, "settlement close interval must be positive")
+	}
+	if c.Reconciliation.Columns.Reference == "" || c.Reconciliation.Columns.Amount == "" {
+		errs = append(errs, "reconciliation reference and amount columns cannot be empty")
+	}
+	if c.Reconciliation.MaxReportSize <= 0 {
+		errs = append(errs, "reconciliation max report size must be positive")
+	}
+	if c.Disputes.EvidenceDir == "" {
+		errs = append(errs, "dispute evidence dir cannot be empty")
+	}
+	if c.Disputes.MaxEvidenceSize <= 0 {
+		errs = append(errs, "dispute max evidence size must be positive")
+	}
+	if c.Disputes.ResponseWindow.Duration <= 0 {
+		errs = append(errs, "dispute response window must be positive")
+	}
+	if c.Disputes.SweepInterval.Duration <= 0 {
+		errs = append(errs, "dispute sweep interval must be positive")
+	}
+	if c.Timeouts.Default.Duration <= 0 {
+		errs = append(errs, "default request timeout must be positive")
+	}
+	for route, d := range c.Timeouts.Endpoints {
+		if !strings.HasPrefix(route, "/") {
+			errs = append(errs, fmt.Sprintf("request timeout route %q must start with /", route))
+		}
+		if d.Duration <= 0 {
+			errs = append(errs, fmt.Sprintf("request timeout of %s must be positive", route))
+		}
+	}
+	errs = append(errs, validateRateLimit("default", c.RateLimits.Default)...)
+	for route, limit := range c.RateLimits.Endpoints {
+		if !strings.HasPrefix(route, "/") {
+			errs = append(errs, fmt.Sprintf("rate limit route %q must start with /", route))
+		}
+		errs = append(errs, validateRateLimit(route, limit)...)
+	}
+
+	if len(errs) > 0 {
+		//map iteration order is random, keep the report stable
+		sort.Strings(errs)
+		return errors.New("invalid configuration: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validateRateLimit(name string, limit EndpointRateLimit) []string {
+	var errs []string
+	for scope, l := range map[string]RateLimit{"merchant": limit.Merchant, "client ip": limit.ClientIP} {
+		if l.PerSecond <= 0 || l.Burst < 1 {
+			errs = append(errs, fmt.Sprintf("%s rate limit of %s must have a positive rate and a burst of at least 1", scope, name))
+		}
+	}
+	return errs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	dir, err := ioutil.TempDir("", "gateway-config")
+	assert.Nil(t, err)
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestDefault_IsValid(t *testing.T) {
+	assert.Nil(t, Default().Validate())
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load([]string{})
+	assert.Nil(t, err)
+	assert.EqualValues(t, Default(), cfg)
+}
+
+func TestLoad_YamlFile(t *testing.T) {
+	path := writeConfigFile(t, "gateway.yaml", `
+database:
+  dsn: /var/lib/gateway/gateway.db
+server:
+  listen_address: 127.0.0.1:9090
+  read_timeout: 5s
+features:
+  subscriptions: false
+subscriptions:
+  retry_intervals: [1h, 2h]
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	cfg, err := Load([]string{"-config", path})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "sqlite3", cfg.Database.Driver)
+	assert.EqualValues(t, "/var/lib/gateway/gateway.db", cfg.Database.DSN)
+	assert.EqualValues(t, "127.0.0.1:9090", cfg.Server.ListenAddress)
+	assert.EqualValues(t, 5*time.Second, cfg.Server.ReadTimeout.Duration)
+	assert.EqualValues(t, 30*time.Second, cfg.Server.WriteTimeout.Duration)
+	assert.EqualValues(t, false, cfg.Features.Subscriptions)
+	assert.EqualValues(t, []time.Duration{time.Hour, 2 * time.Hour}, Durations(cfg.Subscriptions.RetryIntervals))
+}
+
+func TestLoad_JsonFile(t *testing.T) {
+	path := writeConfigFile(t, "gateway.json", `{"logging": {"level": "debug"}, "limits": {"max_authorisation_amount": 500}}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	cfg, err := Load([]string{"-config", path})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "debug", cfg.Logging.Level)
+	assert.EqualValues(t, 500, cfg.Limits.MaxAuthorisationAmount)
+}
+
+func TestLoad_UnknownFieldInFile(t *testing.T) {
+	path := writeConfigFile(t, "gateway.yaml", "server:\n  listen_adress: :9090\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := Load([]string{"-config", path})
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "listen_adress"))
+}
+
+func TestLoad_EnvironmentOverridesFileAndFlagsOverrideEnvironment(t *testing.T) {
+	path := writeConfigFile(t, "gateway.yaml", "server:\n  listen_address: :7000\n  idle_timeout: 1m\nlogging:\n  level: warn\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Setenv("GATEWAY_CONFIG_FILE", path)
+	os.Setenv("GATEWAY_LISTEN_ADDRESS", ":7001")
+	os.Setenv("GATEWAY_LOG_LEVEL", "error")
+	defer os.Unsetenv("GATEWAY_CONFIG_FILE")
+	defer os.Unsetenv("GATEWAY_LISTEN_ADDRESS")
+	defer os.Unsetenv("GATEWAY_LOG_LEVEL")
+
+	cfg, err := Load([]string{"-listen-address", ":7002"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, ":7002", cfg.Server.ListenAddress)
+	assert.EqualValues(t, "error", cfg.Logging.Level)
+	assert.EqualValues(t, time.Minute, cfg.Server.IdleTimeout.Duration)
+}
+
+func TestLoad_InvalidEnvironmentValue(t *testing.T) {
+	os.Setenv("GATEWAY_READ_TIMEOUT", "ten seconds")
+	defer os.Unsetenv("GATEWAY_READ_TIMEOUT")
+
+	_, err := Load([]string{})
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "GATEWAY_READ_TIMEOUT"))
+}
+
+func TestLoad_ThreeDSCountries(t *testing.T) {
+	os.Setenv("GATEWAY_THREE_DS_COUNTRIES", "FR, DE,")
+	defer os.Unsetenv("GATEWAY_THREE_DS_COUNTRIES")
+
+	cfg, err := Load([]string{})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"FR", "DE"}, cfg.ThreeDS.Countries)
+
+	//no country disables the challenges
+	cfg, err = Load([]string{"-three-ds-countries", ""})
+	assert.Nil(t, err)
+	assert.Empty(t, cfg.ThreeDS.Countries)
+}
+
+func TestSettlementConfig_CutOffOfDay(t *testing.T) {
+	t.Parallel()
+	assert.EqualValues(t, 22*time.Hour, Default().Settlement.CutOffOfDay())
+	assert.EqualValues(t, 17*time.Hour+30*time.Minute, SettlementConfig{CutOff: "17:30"}.CutOffOfDay())
+}
+
+func TestValidate_ReportsEveryInvalidValue(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Driver = "oracle"
+	cfg.Server.ListenAddress = "8080"
+	cfg.Server.WriteTimeout = Duration{}
+	cfg.Server.TLSCertFile = "cert.pem"
+	cfg.GRPC.ListenAddress = "9090"
+	cfg.Logging.Level = "verbose"
+	cfg.Limits.MaxAuthorisationAmount = 0
+	cfg.Reviews.SLA = Duration{}
+	cfg.ThreeDS.Countries = []string{"FRA"}
+	cfg.Settlement.CutOff = "25:00"
+	cfg.Reconciliation.Columns.Amount = ""
+	cfg.Reconciliation.MaxReportSize = 0
+	cfg.Disputes.ResponseWindow = Duration{}
+
+	err := cfg.Validate()
+	assert.NotNil(t, err)
+	for _, expected := range []string{"oracle", "listen address", "write timeout", "tls", "grpc listen address", "verbose", "max authorisation amount", "review sla", "FRA", "25:00", "reconciliation", "max report size", "dispute response window"} {
+		assert.True(t, strings.Contains(err.Error(), expected), expected)
+	}
+}
+
+func TestTimeoutsConfig_For(t *testing.T) {
+	path := writeConfigFile(t, "gateway.yaml", "timeouts:\n  default: 3s\n  endpoints:\n    /capture: 500ms\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	cfg, err := Load([]string{"-config", path})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 500*time.Millisecond, cfg.Timeouts.For("/capture"))
+	assert.EqualValues(t, 3*time.Second, cfg.Timeouts.For("/refund"))
+
+	cfg.Timeouts.Endpoints["refund"] = Duration{}
+	err = cfg.Validate()
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), `route "refund" must start with /`))
+	assert.True(t, strings.Contains(err.Error(), "request timeout of refund must be positive"))
+}
+
+func TestRateLimitsConfig(t *testing.T) {
+	cfg := Default()
+	cfg.RateLimits.Endpoints["/authorize"] = EndpointRateLimit{Merchant: RateLimit{PerSecond: 20, Burst: 40}, ClientIP: RateLimit{PerSecond: 50, Burst: 100}}
+	assert.EqualValues(t, RateLimit{PerSecond: 20, Burst: 40}, cfg.RateLimits.For("/authorize").Merchant)
+	assert.EqualValues(t, cfg.RateLimits.Default, cfg.RateLimits.For("/capture"))
+	assert.Nil(t, cfg.Validate())
+
+	cfg.RateLimits.Endpoints["capture"] = EndpointRateLimit{Merchant: RateLimit{PerSecond: 1, Burst: 0}, ClientIP: RateLimit{PerSecond: 1, Burst: 1}}
+	cfg.Server.MaxBodyBytes = 0
+	err := cfg.Validate()
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), `rate limit route "capture" must start with /`))
+	assert.True(t, strings.Contains(err.Error(), "merchant rate limit of capture must have a positive rate and a burst of at least 1"))
+	assert.False(t, strings.Contains(err.Error(), "client ip rate limit of capture"))
+	assert.True(t, strings.Contains(err.Error(), "max body bytes must be positive"))
+}
+
+func TestLoad_ExampleFile(t *testing.T) {
+	cfg, err := Load([]string{"-config", "../../config.example.yaml"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, Default(), cfg)
+}
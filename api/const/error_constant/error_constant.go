@@ -3,6 +3,7 @@ package error_constant
 var (
 	InvalidAuthIdField           = "authorisation id field is not valid"
 	InvalidAmount                = "amount cannot be negative"
+	AmountAboveLimit             = "amount is above the maximum allowed"
 	InvalidCardExpiryDate        = "expiry date is not valid"
 	InvalidCardNumber            = "card number is not valid"
 	InvalidCvv                   = "cvv number is not valid"
@@ -14,6 +15,7 @@ var (
 	TransactionRetrievalFailure  = "unable to retrieve authorisation transaction"
 	RejectRetrievalFailure       = "unable to retrieve rejects"
 	UpdateAvailableAmountFailure = "unable to update available amount"
+	ConcurrentTransactionUpdate  = "the transaction has been changed by another operation, retry the request"
 	TransactionNotFound          = "authorisation transaction not found"
 	ExpiredCard                  = "card is expired"
 	RequestedAmountNotValid      = "the requested amount cannot be processed"
@@ -21,4 +23,117 @@ var (
 	OperationNameInvalid         = "passed operation name is invalid"
 	UnableToCheckForInvalidState = "unable to check for invalid state"
 	UnableToVoidTransaction      = "unable to void transaction"
+	InvalidSubscriptionIdField   = "subscription id field is not valid"
+	InvalidInterval              = "interval must be one of daily, weekly, monthly or yearly"
+	InvalidAnchorDate            = "anchor date is not valid"
+	InvalidMaxCycles             = "max cycles cannot be negative"
+	SubscriptionCreationFailure  = "unable to create subscription"
+	SubscriptionRetrievalFailure = "unable to retrieve subscription"
+	SubscriptionUpdateFailure    = "unable to update subscription"
+	SubscriptionNotFound         = "subscription not found"
+	SubscriptionStateInvalid     = "subscription is not in a state that allows this operation"
+	SubscriptionChargeNotClaimed = "unable to record the charge attempt of the subscription"
+	SubscriptionChargeNotSaved   = "unable to record the outcome of the charge attempt"
+	SubscriptionChargeUnresolved = "charge attempt has no recorded outcome, left for reconciliation"
+	RequestTimedOut              = "the request did not complete in time"
+	RequestCancelled             = "the request has been cancelled"
+	GatewayShuttingDown          = "the gateway is shutting down"
+	TooManyRequests              = "too many requests, retry later"
+	RequestBodyTooLarge          = "the request body is too large"
+	AdminUnauthorised            = "a valid admin token is required"
+	FraudCheckFailure            = "unable to assess the risk of the transaction"
+	InvalidRuleIdField           = "rule id field is not valid"
+	InvalidRuleName              = "rule name cannot be empty"
+	InvalidRuleType              = "rule type must be one of velocity_count, velocity_amount, amount_threshold, repeated_declines, bin_country or avs_mismatch"
+	InvalidRuleAction            = "rule action must be review or deny"
+	InvalidRuleThreshold         = "rule threshold must be positive"
+	InvalidRuleWindow            = "rule window must be a positive duration"
+	InvalidRuleCountries         = "rule countries must be ISO 3166 alpha-2 codes"
+	InvalidBin                   = "bin must be made of 6 to 8 digits"
+	InvalidCountryCode           = "country must be an ISO 3166 alpha-2 code"
+	RuleNotFound                 = "fraud rule not found"
+	RuleRetrievalFailure         = "unable to retrieve fraud rules"
+	RuleUpdateFailure            = "unable to update fraud rules"
+	InvalidReviewer              = "reviewer cannot be empty"
+	InvalidReviewReason          = "reason cannot be empty"
+	InvalidReviewState           = "state must be one of pending, approved or declined"
+	ReviewNotFound               = "review not found"
+	ReviewStateInvalid           = "review has already been completed"
+	ReviewRetrievalFailure       = "unable to retrieve reviews"
+	ReviewUpdateFailure          = "unable to update review"
+	AuthorisationPendingReview   = "authorisation is pending review"
+	AuthorisationReviewDeclined  = "authorisation has been declined in review"
+	AuthenticationCheckFailure   = "unable to check whether the card must be authenticated"
+	InvalidChallengeCode         = "challenge code cannot be empty"
+	ChallengeNotFound            = "challenge not found"
+	ChallengeNotCompleted        = "the cardholder has not completed the challenge"
+	ChallengeExpired             = "the challenge has expired"
+	ChallengeStateInvalid        = "the challenge has already been completed"
+	ChallengeRetrievalFailure    = "unable to retrieve challenge"
+	ChallengeUpdateFailure       = "unable to update challenge"
+	AuthenticationFailure        = "cardholder authentication failed"
+	InvalidCardholderName        = "cardholder name is not valid"
+	InvalidBillingAddressLine    = "billing address line1 cannot be empty"
+	InvalidBillingCountry        = "billing address country must be an ISO 3166 alpha-2 code"
+	InvalidPostcode              = "billing address postcode is not valid for its country"
+	AddressVerificationFailure   = "unable to verify the billing address"
+	InvalidBatchIdField          = "settlement batch id field is not valid"
+	InvalidSettlementFormat      = "settlement file format must be one of csv or fixed"
+	BatchNotFound                = "settlement batch not found"
+	BatchAlreadyClosed           = "the settlement batch of the cut-off has already been closed"
+	BatchStateInvalid            = "the settlement batch has already been submitted"
+	BatchRetrievalFailure        = "unable to retrieve settlement batches"
+	BatchUpdateFailure           = "unable to update settlement batch"
+	InvalidReconciliationIdField = "reconciliation id field is not valid"
+	InvalidReportFile            = "settlement report must be a csv file with a header line"
+	ReportColumnMissing          = "settlement report is missing a mapped column"
+	InvalidReportLine            = "settlement report line is not valid"
+	ReconciliationNotFound       = "reconciliation not found"
+	ReportRetrievalFailure       = "unable to retrieve reconciliation reports"
+	ReportCreationFailure        = "unable to store reconciliation report"
+	LedgerRetrievalFailure       = "unable to retrieve the ledger"
+	InvalidMerchantIdField       = "merchant id field is not valid"
+	InvalidRefundPolicy          = "refund policy must be one of retain, return or charge"
+	InvalidFeeRates              = "fee schedule must have at least one rate"
+	InvalidFeeBrand              = "fee brand must be one of visa, mastercard, amex, discover or unknown"
+	InvalidFeePercentage         = "fee percentage must be between 0 and 100"
+	InvalidFeeAmount             = "fee fixed amounts cannot be negative"
+	DuplicateFeeRate             = "fee rates must have distinct brand and currency pairs"
+	FeeScheduleNotFound          = "fee schedule not found"
+	FeeRetrievalFailure          = "unable to retrieve fee schedule"
+	FeeUpdateFailure             = "unable to update fee schedule"
+	FeeCalculationFailure        = "unable to calculate the fee of the operation"
+	InvalidDisputeIdField        = "dispute id field is not valid"
+	InvalidDisputeReason         = "dispute reason cannot be empty"
+	InvalidDisputeState          = "state must be one of evidence_required, won or lost"
+	InvalidDisputeFilter         = "state must be one of opened, evidence_required, submitted, won or lost"
+	InvalidEvidenceIdField       = "evidence id field is not valid"
+	InvalidEvidenceFile          = "evidence must be a pdf, png, jpeg or plain text file"
+	EvidenceTooLarge             = "evidence is larger than the maximum evidence size"
+	DisputeNotFound              = "dispute not found"
+	EvidenceNotFound             = "evidence not found"
+	DisputeStateInvalid          = "the dispute is not in a state that allows this operation"
+	DisputeAlreadyOpen           = "the authorisation already has a dispute in progress"
+	DisputeEvidenceMissing       = "evidence must be attached before the dispute is submitted"
+	DisputeDeadlinePassed        = "the response deadline of the dispute has passed"
+	DisputeRetrievalFailure      = "unable to retrieve disputes"
+	DisputeUpdateFailure         = "unable to update dispute"
+	InvalidTimestampFilter       = "from and to must be RFC 3339 timestamps"
+	InvalidDateRange             = "from must be before to"
+	InvalidTransactionState      = "state must be one of authorised, pending_review, captured, refunded, voided or charged_back"
+	InvalidOperationType         = "operation must be one of authorisation, capture, refund, void or chargeback"
+	InvalidAmountRange           = "min_amount cannot be greater than max_amount"
+	InvalidCardLast4             = "last4 must be made of 4 digits"
+	InvalidSortField             = "sort must be one of created_at or amount"
+	InvalidSortOrder             = "order must be one of asc or desc"
+	InvalidPageLimit             = "limit must be between 1 and 200"
+	InvalidCursor                = "cursor is not valid for this search"
+	ExportRangeRequired          = "from and to are required to export transactions"
+	InvalidExportFormat          = "format must be one of csv or jsonl"
+	InvalidExportColumns         = "columns must be a comma separated list of export columns"
+	ExportFailure                = "unable to export transactions"
+	ReportRangeRequired          = "from and to are required to report on transactions"
+	InvalidReportBucket          = "bucket must be one of day, week or month"
+	ReportRangeTooLong           = "the report cannot hold more than 366 buckets"
+	ReportFailure                = "unable to build the report"
 )
@@ -0,0 +1,23 @@
+package format_constant
+
+const (
+	ExpirationDateLayout = "01-2006"
+	AnchorDateLayout     = "2006-01-02"
+	TimestampLayout      = "2006-01-02T15:04:05Z07:00"
+	UUIDCodeLayout       = "^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$"
+	CvvFormatLayout      = "^[0-9]{3,4}$"
+	CurrencyCodeLayout   = "^[A-Z]{3}$"
+	CountryCodeLayout    = "^[A-Z]{2}$"
+	BinLayout            = "^[0-9]{6,8}$"
+	MerchantIdLayout     = "^[A-Za-z0-9._:-]{1,128}$"
+	CardholderNameLayout = "^[\\p{L}][\\p{L} '.,-]{0,69}$"
+	GBPostcodeLayout     = "^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$"
+	USPostcodeLayout     = "^[0-9]{5}(-[0-9]{4})?$"
+	CAPostcodeLayout     = "^[A-Z][0-9][A-Z] ?[0-9][A-Z][0-9]$"
+	IEPostcodeLayout     = "^[A-Z][0-9][0-9W] ?[A-Z0-9]{4}$"
+	NLPostcodeLayout     = "^[0-9]{4} ?[A-Z]{2}$"
+	JPPostcodeLayout     = "^[0-9]{3}-?[0-9]{4}$"
+	FourDigitsPostcode   = "^[0-9]{4}$"
+	FiveDigitsPostcode   = "^[0-9]{5}$"
+	OtherPostcodeLayout  = "^[A-Z0-9][A-Z0-9 -]{1,9}$"
+)
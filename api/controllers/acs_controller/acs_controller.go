@@ -0,0 +1,56 @@
+package acs_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/threeds_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/acs_service"
+)
+
+//Handler serves the endpoints of the simulated access control server
+type Handler struct {
+	service acs_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the simulated access control server
+func New(service acs_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleGetChallengeRequest handles request for the challenge shown to the cardholder
+func (h *Handler) HandleGetChallengeRequest(c *gin.Context) {
+	result, apiError := h.service.GetChallenge(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleAnswerChallengeRequest handles request for the answer of the cardholder to the challenge
+func (h *Handler) HandleAnswerChallengeRequest(c *gin.Context) {
+	request := threeds_domain.ChallengeRequest{}
+
+	err := c.BindJSON(&request)
+	if err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return
+	}
+
+	result, apiError := h.service.AnswerChallenge(c.Request.Context(), c.Param("id"), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,85 @@
+package acs_controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/threeds_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+)
+
+type acsServiceMock struct {
+	getChallenge    func(string) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface)
+	answerChallenge func(string, threeds_domain.ChallengeRequest) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface)
+}
+
+func (a *acsServiceMock) GetChallenge(ctx context.Context, id string) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface) {
+	return a.getChallenge(id)
+}
+
+func (a *acsServiceMock) AnswerChallenge(ctx context.Context, id string, request threeds_domain.ChallengeRequest) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface) {
+	return a.answerChallenge(id, request)
+}
+
+func newHandler(service *acsServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleGetChallengeRequest(t *testing.T) {
+	t.Parallel()
+	service := &acsServiceMock{}
+	service.getChallenge = func(id string) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface) {
+		if id == "unknown" {
+			return nil, &error_domain.GatewayError{Code: http.StatusNotFound, Error: "challenge not found"}
+		}
+		return &threeds_domain.ChallengeResponse{AuthID: id, State: threeds_domain.StatePending}, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "valid_auth_id"}}
+	c.Request, _ = http.NewRequest(http.MethodGet, "", nil)
+	newHandler(service).HandleGetChallengeRequest(c)
+	var actualResponse threeds_domain.ChallengeResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, threeds_domain.ChallengeResponse{AuthID: "valid_auth_id", State: threeds_domain.StatePending}, actualResponse)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "unknown"}}
+	c.Request, _ = http.NewRequest(http.MethodGet, "", nil)
+	newHandler(service).HandleGetChallengeRequest(c)
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleAnswerChallengeRequest(t *testing.T) {
+	t.Parallel()
+	service := &acsServiceMock{}
+	service.answerChallenge = func(id string, request threeds_domain.ChallengeRequest) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "123456", request.Code)
+		return &threeds_domain.ChallengeResponse{AuthID: id, State: threeds_domain.StateAuthenticated}, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "valid_auth_id"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "", ioutil.NopCloser(strings.NewReader(`{"code": "123456"}`)))
+	newHandler(service).HandleAnswerChallengeRequest(c)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"state":"authenticated"`)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "", ioutil.NopCloser(strings.NewReader(`{}`)))
+	newHandler(service).HandleAnswerChallengeRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
@@ -2,30 +2,81 @@ package authorisation_controller
 
 import (
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
 	"payment-gateway-api/api/domain/auth_domain"
 	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/logger"
 	"payment-gateway-api/api/services/authorisation_service"
 )
 
+//Handler serves the authorisation endpoint with the authorisation service
+type Handler struct {
+	service authorisation_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the authorisation endpoint
+func New(service authorisation_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
 //HandleAuthorisationRequest handles request for the authorisation endpoint
-func HandleAuthorisationRequest(c *gin.Context) {
+func (h *Handler) HandleAuthorisationRequest(c *gin.Context) {
+	if result := h.authorise(c); result != nil {
+		respond(c, result)
+	}
+}
+
+//HandleCreateAuthorisationRequest handles request for the authorisations endpoint of the versioned API, the
+//authorisation is located with the Location header
+func (h *Handler) HandleCreateAuthorisationRequest(c *gin.Context) {
+	if result := h.authorise(c); result != nil {
+		c.Header("Location", auth_domain.Location(result.AuthID))
+		respond(c, result)
+	}
+}
+
+//HandleCompleteAuthorisationRequest handles request for the endpoint finalising an authorisation once the cardholder
+//has answered the 3-D Secure challenge
+func (h *Handler) HandleCompleteAuthorisationRequest(c *gin.Context) {
+	result, apiError := h.service.CompleteAuthorisation(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	respond(c, result)
+}
+
+//authorise binds the request and authorises it, the response has already been written when nil is returned
+func (h *Handler) authorise(c *gin.Context) *auth_domain.AuthResponse {
 	request := auth_domain.AuthRequest{}
 
 	err := c.BindJSON(&request)
 	if err != nil {
-		log.Println(err.Error())
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
 		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
 			Code:  http.StatusBadRequest,
 			Error: "request body is invalid",
 		})
-		return
+		return nil
 	}
 
-	result, apiError := authorisation_service.AuthorisationService.AuthoriseTransaction(request)
+	result, apiError := h.service.AuthoriseTransaction(c.Request.Context(), request)
 	if apiError != nil {
 		c.JSON(apiError.Status(), apiError)
+		return nil
+	}
+	return result
+}
+
+//respond writes the authorisation, those held for review or waiting for the cardholder to authenticate are accepted
+//but cannot be captured yet
+func respond(c *gin.Context, result *auth_domain.AuthResponse) {
+	if result.Status == auth_domain.StatusPendingReview || result.Status == auth_domain.StatusRequiresAction {
+		c.JSON(http.StatusAccepted, result)
 		return
 	}
 	c.JSON(http.StatusCreated, result)
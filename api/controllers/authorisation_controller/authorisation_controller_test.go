@@ -2,6 +2,7 @@ package authorisation_controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -10,26 +11,39 @@ import (
 	"net/http/httptest"
 	"payment-gateway-api/api/domain/auth_domain"
 	"payment-gateway-api/api/domain/error_domain"
-	"payment-gateway-api/api/services/authorisation_service"
+	"payment-gateway-api/api/logger"
 	"strings"
 	"testing"
 )
 
-var (
-	authoriseTransactionFunc func(auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
-)
-
-type authoriseServiceMock struct{}
+type authoriseServiceMock struct {
+	authoriseTransactionFunc  func(auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
+	completeAuthorisationFunc func(string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
+}
 
 func (a *authoriseServiceMock) GetAllRecords() (string, error_domain.GatewayErrorInterface) {
 	return "", nil
 }
 
-func (a *authoriseServiceMock) AuthoriseTransaction(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
-	return authoriseTransactionFunc(request)
+func (a *authoriseServiceMock) AuthoriseTransaction(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return a.authoriseTransactionFunc(request)
+}
+
+func (a *authoriseServiceMock) AuthoriseStoredCardTransaction(context.Context, auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (a *authoriseServiceMock) CompleteAuthorisation(ctx context.Context, id string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return a.completeAuthorisationFunc(id)
+}
+
+func newHandler(service *authoriseServiceMock) *Handler {
+	return New(service, logger.Discard())
 }
 
 func TestHandleAuthorisationRequestSuccess(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
 	expectedResponse := auth_domain.AuthResponse{
 		AuthID:    "valid_auth_id",
 		IsSuccess: true,
@@ -37,18 +51,16 @@ func TestHandleAuthorisationRequestSuccess(t *testing.T) {
 		Currency:  "GBP",
 	}
 
-	authoriseTransactionFunc = func(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	service.authoriseTransactionFunc = func(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
 		return &expectedResponse, nil
 	}
 
-	authorisation_service.AuthorisationService = &authoriseServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
 	cardDetails := auth_domain.CardDetails{
 		Number:     "4929907390318794",
-		ExpiryDate: "12-3500",
+		ExpiryDate: "12-2021",
 		Cvv:        "123",
 	}
 	request := auth_domain.AuthRequest{
@@ -67,7 +79,7 @@ func TestHandleAuthorisationRequestSuccess(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleAuthorisationRequest(c)
+	newHandler(service).HandleAuthorisationRequest(c)
 	var actualResponse auth_domain.AuthResponse
 	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
 	assert.Nil(t, err)
@@ -75,23 +87,23 @@ func TestHandleAuthorisationRequestSuccess(t *testing.T) {
 }
 
 func TestHandleAuthorisationRequestErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusUnprocessableEntity,
 		Error: "error_from_service",
 	}
 
-	authoriseTransactionFunc = func(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	service.authoriseTransactionFunc = func(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
 		return nil, &expectedError
 	}
 
-	authorisation_service.AuthorisationService = &authoriseServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
 	cardDetails := auth_domain.CardDetails{
 		Number:     "4929907390318794",
-		ExpiryDate: "12-3500",
+		ExpiryDate: "12-2021",
 		Cvv:        "123",
 	}
 	request := auth_domain.AuthRequest{
@@ -110,7 +122,7 @@ func TestHandleAuthorisationRequestErrorFromService(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleAuthorisationRequest(c)
+	newHandler(service).HandleAuthorisationRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
@@ -118,6 +130,8 @@ func TestHandleAuthorisationRequestErrorFromService(t *testing.T) {
 }
 
 func TestHandleAuthorisationRequestInvalidBody(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
 	var err error
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusBadRequest,
@@ -141,9 +155,118 @@ func TestHandleAuthorisationRequestInvalidBody(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleAuthorisationRequest(c)
+	newHandler(service).HandleAuthorisationRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedError.ErrorMessage(), actualError.ErrorMessage())
 }
+
+func TestHandleAuthorisationRequestPendingReview(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	expectedResponse := auth_domain.AuthResponse{
+		AuthID:   "valid_auth_id",
+		Status:   auth_domain.StatusPendingReview,
+		Amount:   10,
+		Currency: "GBP",
+	}
+
+	service.authoriseTransactionFunc = func(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	b, err := json.Marshal(&auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{Number: "4929907390318794", ExpiryDate: "12-2021", Cvv: "123"},
+		Amount:      10,
+		Currency:    "GBP",
+	})
+	assert.Nil(t, err)
+	c.Request, err = http.NewRequest(http.MethodPost, "", bytes.NewBuffer(b))
+	assert.Nil(t, err)
+
+	newHandler(service).HandleAuthorisationRequest(c)
+	var actualResponse auth_domain.AuthResponse
+	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusAccepted, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleCompleteAuthorisationRequest(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	expectedResponse := auth_domain.AuthResponse{
+		AuthID:         "valid_auth_id",
+		IsSuccess:      true,
+		Status:         auth_domain.StatusApproved,
+		Amount:         10,
+		Currency:       "EUR",
+		LiabilityShift: true,
+	}
+	service.completeAuthorisationFunc = func(id string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		if id != "valid_auth_id" {
+			return nil, &error_domain.GatewayError{Code: http.StatusUnprocessableEntity, Error: "the cardholder has not completed the challenge"}
+		}
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "valid_auth_id"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "", nil)
+	newHandler(service).HandleCompleteAuthorisationRequest(c)
+	var actualResponse auth_domain.AuthResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "pending_auth_id"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "", nil)
+	newHandler(service).HandleCompleteAuthorisationRequest(c)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, response.Code)
+}
+
+func TestHandleCreateAuthorisationRequest(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	expectedResponse := auth_domain.AuthResponse{
+		AuthID:    "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01",
+		IsSuccess: true,
+		Status:    auth_domain.StatusApproved,
+		Amount:    10,
+		Currency:  "GBP",
+	}
+	service.authoriseTransactionFunc = func(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	body := `{"card_details": {"card_number": "4929907390318794", "expiry_date": "12-2099", "cvv": "123"}, "amount": 10, "currency": "GBP"}`
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations", strings.NewReader(body))
+
+	newHandler(service).HandleCreateAuthorisationRequest(c)
+	var actualResponse auth_domain.AuthResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", response.Header().Get("Location"))
+	assert.EqualValues(t, expectedResponse, actualResponse)
+
+	//nothing is created when the authorisation is refused
+	service.authoriseTransactionFunc = func(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return nil, &error_domain.GatewayError{Code: http.StatusUnauthorized, Error: "authorisation failed"}
+	}
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations", strings.NewReader(body))
+	newHandler(service).HandleCreateAuthorisationRequest(c)
+	assert.EqualValues(t, http.StatusUnauthorized, response.Code)
+	assert.Empty(t, response.Header().Get("Location"))
+}
@@ -2,20 +2,35 @@ package capture_controller
 
 import (
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
+	"payment-gateway-api/api/domain/auth_domain"
 	"payment-gateway-api/api/domain/capture_domain"
 	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/logger"
 	"payment-gateway-api/api/services/capture_service"
 )
 
+//Handler serves the capture endpoint with the capture service
+type Handler struct {
+	service capture_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the capture endpoint
+func New(service capture_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
 //HandleCaptureRequest handles request for the capture endpoint
-func HandleCaptureRequest(c *gin.Context) {
+func (h *Handler) HandleCaptureRequest(c *gin.Context) {
 	request := capture_domain.CaptureRequest{}
 
 	err := c.BindJSON(&request)
 	if err != nil {
-		log.Println(err.Error())
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
 		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
 			Code:  http.StatusBadRequest,
 			Error: "request body is invalid",
@@ -23,10 +38,35 @@ func HandleCaptureRequest(c *gin.Context) {
 		return
 	}
 
-	result, apiError := capture_service.CaptureService.CaptureTransactionAmount(request)
+	result, apiError := h.service.CaptureTransactionAmount(c.Request.Context(), request)
 	if apiError != nil {
 		c.JSON(apiError.Status(), apiError)
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
+
+//HandleCreateCaptureRequest handles request for the captures endpoint of an authorisation in the versioned API, the
+//authorisation ID is taken from the path and the authorisation listing the capture is located with the Location header
+func (h *Handler) HandleCreateCaptureRequest(c *gin.Context) {
+	body := capture_domain.AmountRequest{}
+
+	err := c.BindJSON(&body)
+	if err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return
+	}
+
+	request := capture_domain.CaptureRequest{AuthId: c.Param("id"), Amount: body.Amount}
+	result, apiError := h.service.CaptureTransactionAmount(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Header("Location", auth_domain.Location(request.AuthId))
+	c.JSON(http.StatusCreated, result)
+}
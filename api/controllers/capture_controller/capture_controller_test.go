@@ -2,6 +2,7 @@ package capture_controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -10,34 +11,36 @@ import (
 	"net/http/httptest"
 	"payment-gateway-api/api/domain/capture_domain"
 	"payment-gateway-api/api/domain/error_domain"
-	"payment-gateway-api/api/services/capture_service"
+	"payment-gateway-api/api/logger"
 	"strings"
 	"testing"
 )
 
-var (
+type captureServiceMock struct {
 	captureTransactionAmount func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface)
-)
+}
 
-type captureServiceMock struct{}
+func (v *captureServiceMock) CaptureTransactionAmount(ctx context.Context, request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+	return v.captureTransactionAmount(request)
+}
 
-func (v captureServiceMock) CaptureTransactionAmount(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
-	return captureTransactionAmount(request)
+func newHandler(service *captureServiceMock) *Handler {
+	return New(service, logger.Discard())
 }
 
 func TestHandleCaptureRequest(t *testing.T) {
+	t.Parallel()
+	service := &captureServiceMock{}
 	expectedResponse := capture_domain.CaptureResponse{
 		IsSuccess: true,
 		Amount:    10,
 		Currency:  "LKR",
 	}
 
-	captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+	service.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
 		return &expectedResponse, nil
 	}
 
-	capture_service.CaptureService = &captureServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
@@ -56,7 +59,7 @@ func TestHandleCaptureRequest(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleCaptureRequest(c)
+	newHandler(service).HandleCaptureRequest(c)
 	var actualResponse capture_domain.CaptureResponse
 	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
 	assert.Nil(t, err)
@@ -64,17 +67,17 @@ func TestHandleCaptureRequest(t *testing.T) {
 }
 
 func TestHandleCaptureRequest_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &captureServiceMock{}
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusUnprocessableEntity,
 		Error: "error_from_service",
 	}
 
-	captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+	service.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
 		return nil, &expectedError
 	}
 
-	capture_service.CaptureService = &captureServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
@@ -92,7 +95,7 @@ func TestHandleCaptureRequest_ErrorFromService(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleCaptureRequest(c)
+	newHandler(service).HandleCaptureRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
@@ -100,6 +103,8 @@ func TestHandleCaptureRequest_ErrorFromService(t *testing.T) {
 }
 
 func TestHandleCaptureRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	service := &captureServiceMock{}
 	var err error
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusBadRequest,
@@ -123,9 +128,48 @@ func TestHandleCaptureRequest_InvalidBody(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleCaptureRequest(c)
+	newHandler(service).HandleCaptureRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedError.ErrorMessage(), actualError.ErrorMessage())
 }
+
+func TestHandleCreateCaptureRequest(t *testing.T) {
+	t.Parallel()
+	service := &captureServiceMock{}
+	expectedResponse := capture_domain.CaptureResponse{
+		IsSuccess: true,
+		Amount:    5,
+		Currency:  "GBP",
+	}
+	service.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, capture_domain.CaptureRequest{AuthId: "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", Amount: 5}, request)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01/captures", strings.NewReader(`{"amount": 5}`))
+
+	newHandler(service).HandleCreateCaptureRequest(c)
+	var actualResponse capture_domain.CaptureResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", response.Header().Get("Location"))
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleCreateCaptureRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01/captures", strings.NewReader(`{"amount": "five"}`))
+
+	newHandler(&captureServiceMock{}).HandleCreateCaptureRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.Empty(t, response.Header().Get("Location"))
+}
@@ -0,0 +1,143 @@
+package dispute_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"mime"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/dispute_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/dispute_service"
+)
+
+//Handler serves the dispute admin endpoints with the dispute service
+type Handler struct {
+	service dispute_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the dispute admin endpoints
+func New(service dispute_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleOpenDisputeRequest handles request for the dispute creation endpoint, called when the acquirer notifies a chargeback
+func (h *Handler) HandleOpenDisputeRequest(c *gin.Context) {
+	request := dispute_domain.DisputeRequest{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.OpenDispute(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+//HandleListDisputesRequest handles request for the dispute list endpoint, the list can be filtered on the state
+//and auth_id query parameters
+func (h *Handler) HandleListDisputesRequest(c *gin.Context) {
+	result, apiError := h.service.ListDisputes(c.Request.Context(), c.Query("state"), c.Query("auth_id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleGetDisputeRequest handles request for the endpoint returning a dispute with its evidence and events
+func (h *Handler) HandleGetDisputeRequest(c *gin.Context) {
+	result, apiError := h.service.GetDispute(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleUpdateStateRequest handles request for the dispute update endpoint, called when the acquirer asks for evidence
+//or decides the dispute
+func (h *Handler) HandleUpdateStateRequest(c *gin.Context) {
+	request := dispute_domain.StateRequest{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.UpdateState(c.Request.Context(), c.Param("id"), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleAddEvidenceRequest handles request for the evidence upload endpoint, the document is the file field of a multipart form
+func (h *Handler) HandleAddEvidenceRequest(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn(error_constant.InvalidEvidenceFile, logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		h.logger.Ctx(c.Request.Context()).Error(error_constant.DisputeUpdateFailure, logger.Err(err))
+		c.JSON(http.StatusInternalServerError, error_domain.GatewayError{
+			Code:  http.StatusInternalServerError,
+			Error: error_constant.DisputeUpdateFailure,
+		})
+		return
+	}
+	defer file.Close()
+
+	result, apiError := h.service.AddEvidence(c.Request.Context(), c.Param("id"), header.Filename, file)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+//HandleGetEvidenceRequest handles request for the evidence download endpoint
+func (h *Handler) HandleGetEvidenceRequest(c *gin.Context) {
+	result, apiError := h.service.GetEvidence(c.Request.Context(), c.Param("id"), c.Param("evidence_id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	defer result.Content.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": result.Name})
+	c.DataFromReader(http.StatusOK, result.Size, result.ContentType, result.Content, map[string]string{"Content-Disposition": disposition})
+}
+
+//HandleSubmitDisputeRequest handles request for the endpoint submitting the evidence of a dispute to the acquirer
+func (h *Handler) HandleSubmitDisputeRequest(c *gin.Context) {
+	result, apiError := h.service.SubmitDispute(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) bind(c *gin.Context, request interface{}) bool {
+	if err := c.BindJSON(request); err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,200 @@
+package dispute_controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/dispute_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+)
+
+var disputeID = "7d5a3f4c-0e6b-4a4d-9c3f-5b8e9d0a1f23"
+
+type disputeServiceMock struct {
+	openDispute   func(dispute_domain.DisputeRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	listDisputes  func(string, string) ([]dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	getDispute    func(string) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	updateState   func(string, dispute_domain.StateRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	addEvidence   func(string, string, io.Reader) (*dispute_domain.EvidenceResponse, error_domain.GatewayErrorInterface)
+	getEvidence   func(string, string) (*dispute_domain.EvidenceFile, error_domain.GatewayErrorInterface)
+	submitDispute func(string) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+}
+
+func (d *disputeServiceMock) OpenDispute(ctx context.Context, request dispute_domain.DisputeRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface) {
+	return d.openDispute(request)
+}
+
+func (d *disputeServiceMock) ListDisputes(ctx context.Context, state string, authID string) ([]dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface) {
+	return d.listDisputes(state, authID)
+}
+
+func (d *disputeServiceMock) GetDispute(ctx context.Context, id string) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface) {
+	return d.getDispute(id)
+}
+
+func (d *disputeServiceMock) UpdateState(ctx context.Context, id string, request dispute_domain.StateRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface) {
+	return d.updateState(id, request)
+}
+
+func (d *disputeServiceMock) AddEvidence(ctx context.Context, id string, name string, content io.Reader) (*dispute_domain.EvidenceResponse, error_domain.GatewayErrorInterface) {
+	return d.addEvidence(id, name, content)
+}
+
+func (d *disputeServiceMock) GetEvidence(ctx context.Context, id string, evidenceID string) (*dispute_domain.EvidenceFile, error_domain.GatewayErrorInterface) {
+	return d.getEvidence(id, evidenceID)
+}
+
+func (d *disputeServiceMock) SubmitDispute(ctx context.Context, id string) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface) {
+	return d.submitDispute(id)
+}
+
+func (d *disputeServiceMock) ExpireOverdueDisputes(ctx context.Context) error {
+	return nil
+}
+
+func newHandler(service *disputeServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleOpenDisputeRequest(t *testing.T) {
+	t.Parallel()
+	service := &disputeServiceMock{}
+	expectedResponse := dispute_domain.DisputeResponse{
+		ID:        disputeID,
+		AuthID:    "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01",
+		State:     dispute_domain.StateOpened,
+		Reason:    "fraud",
+		Amount:    60,
+		Currency:  "GBP",
+		DueAt:     "2020-06-22T12:00:00Z",
+		CreatedAt: "2020-06-15T12:00:00Z",
+	}
+	service.openDispute = func(request dispute_domain.DisputeRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, dispute_domain.DisputeRequest{AuthId: expectedResponse.AuthID, Amount: 60, Reason: "fraud"}, request)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	body := `{"auth_id":"5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01","amount":60,"reason":"fraud"}`
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/disputes", strings.NewReader(body))
+
+	newHandler(service).HandleOpenDisputeRequest(c)
+	var actualResponse dispute_domain.DisputeResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleOpenDisputeRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/disputes", strings.NewReader(`{"auth_id":`))
+
+	newHandler(&disputeServiceMock{}).HandleOpenDisputeRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "request body is invalid")
+}
+
+func TestHandleUpdateStateRequest_Error(t *testing.T) {
+	t.Parallel()
+	service := &disputeServiceMock{}
+	service.updateState = func(id string, request dispute_domain.StateRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, disputeID, id)
+		assert.EqualValues(t, dispute_domain.StateWon, request.State)
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeStateInvalid))
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/admin/disputes/"+disputeID, strings.NewReader(`{"state":"won"}`))
+	c.Params = gin.Params{{Key: "id", Value: disputeID}}
+
+	newHandler(service).HandleUpdateStateRequest(c)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Contains(t, response.Body.String(), error_constant.DisputeStateInvalid)
+}
+
+func TestHandleAddEvidenceRequest(t *testing.T) {
+	t.Parallel()
+	service := &disputeServiceMock{}
+	expectedResponse := dispute_domain.EvidenceResponse{ID: 1, Name: "receipt.pdf", ContentType: "application/pdf", Size: 9, SHA256: "digest", CreatedAt: "2020-06-15T12:00:00Z"}
+	service.addEvidence = func(id string, name string, content io.Reader) (*dispute_domain.EvidenceResponse, error_domain.GatewayErrorInterface) {
+		data, _ := ioutil.ReadAll(content)
+		assert.EqualValues(t, disputeID, id)
+		assert.EqualValues(t, "receipt.pdf", name)
+		assert.EqualValues(t, "%PDF-1.4\n", string(data))
+		return &expectedResponse, nil
+	}
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "receipt.pdf")
+	part.Write([]byte("%PDF-1.4\n"))
+	form.Close()
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/disputes/"+disputeID+"/evidence", body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+	c.Params = gin.Params{{Key: "id", Value: disputeID}}
+
+	newHandler(service).HandleAddEvidenceRequest(c)
+	var actualResponse dispute_domain.EvidenceResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleAddEvidenceRequest_MissingFile(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/disputes/"+disputeID+"/evidence", strings.NewReader(`{}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	newHandler(&disputeServiceMock{}).HandleAddEvidenceRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "request body is invalid")
+}
+
+func TestHandleGetEvidenceRequest(t *testing.T) {
+	t.Parallel()
+	service := &disputeServiceMock{}
+	service.getEvidence = func(id string, evidenceID string) (*dispute_domain.EvidenceFile, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, disputeID, id)
+		assert.EqualValues(t, "1", evidenceID)
+		return &dispute_domain.EvidenceFile{
+			Name:        "delivery note.txt",
+			ContentType: "text/plain",
+			Size:        9,
+			Content:     ioutil.NopCloser(strings.NewReader("delivered")),
+		}, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/disputes/"+disputeID+"/evidence/1", nil)
+	c.Params = gin.Params{{Key: "id", Value: disputeID}, {Key: "evidence_id", Value: "1"}}
+
+	newHandler(service).HandleGetEvidenceRequest(c)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "text/plain", response.Header().Get("Content-Type"))
+	assert.EqualValues(t, `attachment; filename="delivery note.txt"`, response.Header().Get("Content-Disposition"))
+	assert.EqualValues(t, "delivered", response.Body.String())
+}
@@ -0,0 +1,55 @@
+package docs_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/openapi"
+)
+
+//swaggerPage is the Swagger UI page browsing the document served at /openapi.json, the UI is loaded from its CDN at a
+//pinned version and the browser refuses the assets unless they match their integrity hashes
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Payment Gateway API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@4.15.5/swagger-ui.css"
+        integrity="sha384-2/StnWvcTFa+ulN5XGsmRCRCHlS3w55zYM2opgTX9cGDkOHlC2PJMND08SWG4Bag" crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@4.15.5/swagger-ui-bundle.js"
+          integrity="sha384-GJoyyEnbeIyINXWDkEzUHpPPCZPcP2KrAg83c6DGAkTPr2tDHQ59DuqMRwAwsJwV" crossorigin="anonymous"></script>
+  <script>
+    window.onload = function () {
+      SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
+
+//Handler serves the OpenAPI document of the gateway and the page browsing it
+type Handler struct {
+	document *openapi.Document
+	logger   *logger.Logger
+}
+
+//New creates the handler of the documentation endpoints
+func New(document *openapi.Document, log *logger.Logger) *Handler {
+	return &Handler{
+		document: document,
+		logger:   log,
+	}
+}
+
+//HandleSpecRequest handles request for the OpenAPI document
+func (h *Handler) HandleSpecRequest(c *gin.Context) {
+	c.JSON(http.StatusOK, h.document)
+}
+
+//HandleSwaggerUIRequest handles request for the Swagger UI page
+func (h *Handler) HandleSwaggerUIRequest(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerPage))
+}
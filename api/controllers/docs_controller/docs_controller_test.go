@@ -0,0 +1,58 @@
+package docs_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/openapi"
+	"regexp"
+	"testing"
+)
+
+func newHandler() *Handler {
+	document := openapi.New("Payment Gateway API", "", "1.0.0")
+	document.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/docs", ID: "getSwaggerUI", Tag: "docs", Summary: "Browses this document with Swagger UI"})
+	return New(document, logger.Discard())
+}
+
+func TestHandleSpecRequest(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request = httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+
+	newHandler().HandleSpecRequest(c)
+
+	var document openapi.Document
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Header().Get("Content-Type"), "application/json")
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &document))
+	assert.EqualValues(t, openapi.Version, document.OpenAPI)
+	assert.EqualValues(t, "Payment Gateway API", document.Info.Title)
+	assert.EqualValues(t, "getSwaggerUI", document.Paths["/docs"]["get"].OperationID)
+}
+
+func TestHandleSwaggerUIRequest(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request = httptest.NewRequest(http.MethodGet, "/docs", nil)
+
+	newHandler().HandleSwaggerUIRequest(c)
+
+	body := response.Body.String()
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "text/html; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Contains(t, body, `url: "/openapi.json"`)
+
+	//every asset of the CDN is pinned to an exact version and checked against its integrity hash
+	assets := regexp.MustCompile(`(?:href|src)="(https://[^"]+)"\s+integrity="sha384-[A-Za-z0-9+/]{64}" crossorigin="anonymous"`).FindAllStringSubmatch(body, -1)
+	assert.EqualValues(t, 2, len(assets))
+	assert.EqualValues(t, 2, len(regexp.MustCompile(`https://`).FindAllString(body, -1)))
+	for _, asset := range assets {
+		assert.Regexp(t, `^https://unpkg\.com/swagger-ui-dist@\d+\.\d+\.\d+/`, asset[1])
+	}
+}
@@ -0,0 +1,64 @@
+package fee_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fee_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/fee_service"
+)
+
+//Handler serves the fee schedule admin endpoints with the fee service
+type Handler struct {
+	service fee_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the fee schedule admin endpoints
+func New(service fee_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleGetScheduleRequest handles request for the fee schedule retrieval endpoint
+func (h *Handler) HandleGetScheduleRequest(c *gin.Context) {
+	result, apiError := h.service.GetSchedule(c.Request.Context(), c.Param("merchant_id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleSaveScheduleRequest handles request for the fee schedule update endpoint
+func (h *Handler) HandleSaveScheduleRequest(c *gin.Context) {
+	request := fee_domain.ScheduleRequest{}
+	if err := c.BindJSON(&request); err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return
+	}
+
+	result, apiError := h.service.SaveSchedule(c.Request.Context(), c.Param("merchant_id"), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleDeleteScheduleRequest handles request for the fee schedule deletion endpoint
+func (h *Handler) HandleDeleteScheduleRequest(c *gin.Context) {
+	apiError := h.service.DeleteSchedule(c.Request.Context(), c.Param("merchant_id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
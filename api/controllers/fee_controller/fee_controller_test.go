@@ -0,0 +1,106 @@
+package fee_controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fee_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+)
+
+type feeServiceMock struct {
+	getSchedule    func(string) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface)
+	saveSchedule   func(string, fee_domain.ScheduleRequest) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface)
+	deleteSchedule func(string) error_domain.GatewayErrorInterface
+}
+
+func (f *feeServiceMock) CalculateFee(ctx context.Context, record *auth.Auth, operationName string, amount float32) (float32, error_domain.GatewayErrorInterface) {
+	return 0, nil
+}
+
+func (f *feeServiceMock) GetSchedule(ctx context.Context, merchantID string) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface) {
+	return f.getSchedule(merchantID)
+}
+
+func (f *feeServiceMock) SaveSchedule(ctx context.Context, merchantID string, request fee_domain.ScheduleRequest) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface) {
+	return f.saveSchedule(merchantID, request)
+}
+
+func (f *feeServiceMock) DeleteSchedule(ctx context.Context, merchantID string) error_domain.GatewayErrorInterface {
+	return f.deleteSchedule(merchantID)
+}
+
+func newHandler(service *feeServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleSaveScheduleRequest(t *testing.T) {
+	t.Parallel()
+	expectedResponse := fee_domain.ScheduleResponse{
+		MerchantID:   "acme",
+		RefundPolicy: fee_domain.RefundReturn,
+		Rates:        []fee_domain.Rate{{Brand: "visa", Percentage: 1.4, Fixed: 0.2}},
+		UpdatedAt:    "2020-06-15T12:00:00Z",
+	}
+	service := &feeServiceMock{}
+	service.saveSchedule = func(merchantID string, request fee_domain.ScheduleRequest) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "acme", merchantID)
+		assert.EqualValues(t, fee_domain.RefundReturn, request.RefundPolicy)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "merchant_id", Value: "acme"}}
+	var err error
+	c.Request, err = http.NewRequest(http.MethodPut, "", strings.NewReader(`{"refund_policy": "return", "rates": [{"brand": "visa", "percentage": 1.4, "fixed": 0.2}]}`))
+	assert.Nil(t, err)
+
+	newHandler(service).HandleSaveScheduleRequest(c)
+	var actualResponse fee_domain.ScheduleResponse
+	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPut, "", strings.NewReader(`{"rates": "visa"}`))
+	newHandler(service).HandleSaveScheduleRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+}
+
+func TestHandleGetAndDeleteScheduleRequest(t *testing.T) {
+	t.Parallel()
+	notFound := &error_domain.GatewayError{Code: http.StatusNotFound, Error: "fee schedule not found"}
+	service := &feeServiceMock{
+		getSchedule: func(merchantID string) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface) {
+			return nil, notFound
+		},
+		deleteSchedule: func(merchantID string) error_domain.GatewayErrorInterface {
+			return nil
+		},
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "merchant_id", Value: "globex"}}
+	c.Request, _ = http.NewRequest(http.MethodGet, "", nil)
+	newHandler(service).HandleGetScheduleRequest(c)
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "merchant_id", Value: "acme"}}
+	c.Request, _ = http.NewRequest(http.MethodDelete, "", nil)
+	newHandler(service).HandleDeleteScheduleRequest(c)
+	c.Writer.WriteHeaderNow()
+	assert.EqualValues(t, http.StatusNoContent, response.Code)
+}
@@ -0,0 +1,116 @@
+package fraud_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fraud_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/fraud_service"
+)
+
+//Handler serves the fraud admin endpoints with the fraud service
+type Handler struct {
+	service fraud_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the fraud admin endpoints
+func New(service fraud_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleListRulesRequest handles request for the fraud rules listing endpoint
+func (h *Handler) HandleListRulesRequest(c *gin.Context) {
+	result, apiError := h.service.ListRules(c.Request.Context())
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleCreateRuleRequest handles request for the fraud rule creation endpoint
+func (h *Handler) HandleCreateRuleRequest(c *gin.Context) {
+	request := fraud_domain.RuleRequest{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.CreateRule(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+//HandleUpdateRuleRequest handles request for the fraud rule update endpoint
+func (h *Handler) HandleUpdateRuleRequest(c *gin.Context) {
+	request := fraud_domain.RuleRequest{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.UpdateRule(c.Request.Context(), c.Param("id"), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleDeleteRuleRequest handles request for the fraud rule deletion endpoint
+func (h *Handler) HandleDeleteRuleRequest(c *gin.Context) {
+	apiError := h.service.DeleteRule(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+//HandleBinCountriesRequest handles request for the bin countries endpoint
+func (h *Handler) HandleBinCountriesRequest(c *gin.Context) {
+	request := fraud_domain.BinCountriesRequest{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	apiError := h.service.SaveBinCountries(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+//HandleDryRunRequest handles request for the fraud dry run endpoint
+func (h *Handler) HandleDryRunRequest(c *gin.Context) {
+	request := fraud_domain.Transaction{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.DryRun(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) bind(c *gin.Context, request interface{}) bool {
+	if err := c.BindJSON(request); err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return false
+	}
+	return true
+}
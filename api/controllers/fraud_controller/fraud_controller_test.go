@@ -0,0 +1,167 @@
+package fraud_controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fraud_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+)
+
+type fraudServiceMock struct {
+	createRule func(fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface)
+	updateRule func(string, fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface)
+	deleteRule func(string) error_domain.GatewayErrorInterface
+	dryRun     func(fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface)
+}
+
+func (f *fraudServiceMock) Evaluate(ctx context.Context, transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+	return f.dryRun(transaction)
+}
+
+func (f *fraudServiceMock) DryRun(ctx context.Context, transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+	return f.dryRun(transaction)
+}
+
+func (f *fraudServiceMock) ListRules(ctx context.Context) ([]fraud_domain.Rule, error_domain.GatewayErrorInterface) {
+	return []fraud_domain.Rule{}, nil
+}
+
+func (f *fraudServiceMock) CreateRule(ctx context.Context, request fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface) {
+	return f.createRule(request)
+}
+
+func (f *fraudServiceMock) UpdateRule(ctx context.Context, id string, request fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface) {
+	return f.updateRule(id, request)
+}
+
+func (f *fraudServiceMock) DeleteRule(ctx context.Context, id string) error_domain.GatewayErrorInterface {
+	return f.deleteRule(id)
+}
+
+func (f *fraudServiceMock) SaveBinCountries(ctx context.Context, request fraud_domain.BinCountriesRequest) error_domain.GatewayErrorInterface {
+	return nil
+}
+
+func newHandler(service *fraudServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleCreateRuleRequest(t *testing.T) {
+	t.Parallel()
+	service := &fraudServiceMock{}
+	expectedResponse := fraud_domain.Rule{
+		ID:        1,
+		Name:      "card velocity",
+		Type:      fraud_domain.RuleVelocityCount,
+		Action:    fraud_domain.DecisionDeny,
+		Threshold: 5,
+		Window:    "1h0m0s",
+		Enabled:   true,
+	}
+
+	service.createRule = func(request fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "card velocity", request.Name)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+
+	b, err := json.Marshal(&fraud_domain.RuleRequest{
+		Name:      "card velocity",
+		Type:      fraud_domain.RuleVelocityCount,
+		Action:    fraud_domain.DecisionDeny,
+		Threshold: 5,
+		Window:    "1h",
+	})
+	assert.Nil(t, err)
+	c.Request, err = http.NewRequest(http.MethodPost, "", bytes.NewBuffer(b))
+	assert.Nil(t, err)
+
+	newHandler(service).HandleCreateRuleRequest(c)
+	var actualResponse fraud_domain.Rule
+	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleUpdateRuleRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	service := &fraudServiceMock{}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	var err error
+	c.Request, err = http.NewRequest(http.MethodPut, "", ioutil.NopCloser(strings.NewReader(`{"name": 5}`)))
+	assert.Nil(t, err)
+
+	newHandler(service).HandleUpdateRuleRequest(c)
+	var actualError error_domain.GatewayError
+	err = json.Unmarshal(response.Body.Bytes(), &actualError)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.EqualValues(t, "request body is invalid", actualError.ErrorMessage())
+}
+
+func TestHandleDeleteRuleRequest(t *testing.T) {
+	t.Parallel()
+	service := &fraudServiceMock{}
+	service.deleteRule = func(id string) error_domain.GatewayErrorInterface {
+		if id == "1" {
+			return nil
+		}
+		return &error_domain.GatewayError{Code: http.StatusNotFound, Error: "fraud rule not found"}
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	c.Request, _ = http.NewRequest(http.MethodDelete, "", nil)
+	newHandler(service).HandleDeleteRuleRequest(c)
+	c.Writer.WriteHeaderNow()
+	assert.EqualValues(t, http.StatusNoContent, response.Code)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "2"}}
+	c.Request, _ = http.NewRequest(http.MethodDelete, "", nil)
+	newHandler(service).HandleDeleteRuleRequest(c)
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleDryRunRequest(t *testing.T) {
+	t.Parallel()
+	service := &fraudServiceMock{}
+	expectedResponse := fraud_domain.Assessment{
+		Decision: fraud_domain.DecisionReview,
+		TriggeredRules: []fraud_domain.TriggeredRule{
+			{ID: 2, Name: "high amount", Type: fraud_domain.RuleAmountThreshold, Action: fraud_domain.DecisionReview, Reason: "amount above 500.00 GBP"},
+		},
+	}
+	service.dryRun = func(transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	var err error
+	c.Request, err = http.NewRequest(http.MethodPost, "", strings.NewReader(`{"card_number": "4929907390318794", "amount": 600, "currency": "GBP"}`))
+	assert.Nil(t, err)
+
+	newHandler(service).HandleDryRunRequest(c)
+	var actualResponse fraud_domain.Assessment
+	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
@@ -0,0 +1,43 @@
+package health_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/health_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/health_service"
+)
+
+//Handler serves the health, readiness and version endpoints with the health service
+type Handler struct {
+	service health_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the health endpoints
+func New(service health_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleHealthRequest handles request for the liveness endpoint, answering is enough to show the process is alive
+func (h *Handler) HandleHealthRequest(c *gin.Context) {
+	c.JSON(http.StatusOK, health_domain.HealthResponse{Status: health_domain.StatusOK})
+}
+
+//HandleReadinessRequest handles request for the readiness endpoint
+func (h *Handler) HandleReadinessRequest(c *gin.Context) {
+	result := h.service.Readiness(c.Request.Context())
+	if !result.IsReady() {
+		c.JSON(http.StatusServiceUnavailable, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleVersionRequest handles request for the version endpoint
+func (h *Handler) HandleVersionRequest(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Version())
+}
@@ -0,0 +1,88 @@
+package health_controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/domain/health_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+type healthServiceMock struct {
+	readiness func() *health_domain.ReadinessResponse
+	version   func() *health_domain.VersionResponse
+}
+
+func (h *healthServiceMock) Readiness(ctx context.Context) *health_domain.ReadinessResponse {
+	return h.readiness()
+}
+
+func (h *healthServiceMock) Version() *health_domain.VersionResponse {
+	return h.version()
+}
+
+func (h *healthServiceMock) Drain() {}
+
+func newHandler(service *healthServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleHealthRequest(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	newHandler(&healthServiceMock{}).HandleHealthRequest(c)
+
+	var actualResponse health_domain.HealthResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &actualResponse))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, health_domain.StatusOK, actualResponse.Status)
+}
+
+func TestHandleReadinessRequest(t *testing.T) {
+	t.Parallel()
+	ready := &health_domain.ReadinessResponse{Status: health_domain.StatusReady, Checks: map[string]string{"database": "ok"}}
+	notReady := &health_domain.ReadinessResponse{Status: health_domain.StatusNotReady, Checks: map[string]string{"database": "unable to open database file"}}
+
+	for expectedCode, result := range map[int]*health_domain.ReadinessResponse{
+		http.StatusOK:                 ready,
+		http.StatusServiceUnavailable: notReady,
+	} {
+		result := result
+		response := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(response)
+		c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+		newHandler(&healthServiceMock{readiness: func() *health_domain.ReadinessResponse {
+			return result
+		}}).HandleReadinessRequest(c)
+
+		var actualResponse health_domain.ReadinessResponse
+		assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &actualResponse))
+		assert.EqualValues(t, expectedCode, response.Code)
+		assert.EqualValues(t, *result, actualResponse)
+	}
+}
+
+func TestHandleVersionRequest(t *testing.T) {
+	t.Parallel()
+	expectedResponse := health_domain.VersionResponse{GitSHA: "4f1c2d9", BuildTime: "2020-07-01T10:00:00Z", SchemaVersion: 1}
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request = httptest.NewRequest(http.MethodGet, "/version", nil)
+
+	newHandler(&healthServiceMock{version: func() *health_domain.VersionResponse {
+		return &expectedResponse
+	}}).HandleVersionRequest(c)
+
+	var actualResponse health_domain.VersionResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &actualResponse))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
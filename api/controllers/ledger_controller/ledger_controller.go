@@ -0,0 +1,53 @@
+package ledger_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/ledger_service"
+)
+
+//Handler serves the ledger admin endpoints with the ledger service
+type Handler struct {
+	service ledger_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the ledger admin endpoints
+func New(service ledger_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleBalancesRequest handles request for the ledger balances endpoint, the optional auth_id and merchant_id
+//query parameters keep the postings of an authorisation and of a merchant
+func (h *Handler) HandleBalancesRequest(c *gin.Context) {
+	result, apiError := h.service.GetBalances(c.Request.Context(), c.Query("auth_id"), c.Query("merchant_id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleEntriesRequest handles request for the endpoint returning the journal entries of the authorisation given by the auth_id query parameter
+func (h *Handler) HandleEntriesRequest(c *gin.Context) {
+	result, apiError := h.service.ListEntries(c.Request.Context(), c.Query("auth_id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleCheckRequest handles request for the endpoint checking the invariants of the ledger
+func (h *Handler) HandleCheckRequest(c *gin.Context) {
+	result, apiError := h.service.CheckInvariants(c.Request.Context())
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
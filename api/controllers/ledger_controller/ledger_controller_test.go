@@ -0,0 +1,50 @@
+package ledger_controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/ledger_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+type ledgerServiceMock struct {
+	getBalances func(string, string) ([]ledger_domain.BalanceResponse, error_domain.GatewayErrorInterface)
+}
+
+func (s *ledgerServiceMock) GetBalances(ctx context.Context, authID string, merchantID string) ([]ledger_domain.BalanceResponse, error_domain.GatewayErrorInterface) {
+	return s.getBalances(authID, merchantID)
+}
+
+func (s *ledgerServiceMock) ListEntries(ctx context.Context, authID string) ([]ledger_domain.EntryResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (s *ledgerServiceMock) CheckInvariants(ctx context.Context) (*ledger_domain.CheckResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func TestHandleBalancesRequest(t *testing.T) {
+	t.Parallel()
+	expectedResponse := []ledger_domain.BalanceResponse{{Account: "merchant_receivable", Currency: "GBP", Balance: 60}}
+	service := &ledgerServiceMock{getBalances: func(authID string, merchantID string) ([]ledger_domain.BalanceResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "", authID)
+		assert.EqualValues(t, "acme", merchantID)
+		return expectedResponse, nil
+	}}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/ledger/balances?merchant_id=acme", nil)
+
+	New(service, logger.Discard()).HandleBalancesRequest(c)
+	var actualResponse []ledger_domain.BalanceResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &actualResponse))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
@@ -0,0 +1,61 @@
+package reconciliation_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/reconciliation_service"
+)
+
+//Handler serves the reconciliation admin endpoints with the reconciliation service
+type Handler struct {
+	service reconciliation_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the reconciliation admin endpoints
+func New(service reconciliation_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleImportReportRequest handles request for the endpoint reconciling a settlement batch with the acquirer report sent
+//as the request body, the query parameters override the configured columns of the report
+func (h *Handler) HandleImportReportRequest(c *gin.Context) {
+	columns := reconciliation_domain.Columns{
+		Reference: c.Query("reference_column"),
+		Type:      c.Query("type_column"),
+		Amount:    c.Query("amount_column"),
+		Currency:  c.Query("currency_column"),
+	}
+	result, apiError := h.service.ImportReport(c.Request.Context(), c.Param("id"), c.Request.Body, columns)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+//HandleListReconciliationsRequest handles request for the reconciliations endpoint, the optional batch_id query
+//parameter keeps the reconciliations of a settlement batch
+func (h *Handler) HandleListReconciliationsRequest(c *gin.Context) {
+	result, apiError := h.service.ListReconciliations(c.Request.Context(), c.Query("batch_id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleGetReconciliationRequest handles request for the endpoint returning a reconciliation with its items
+func (h *Handler) HandleGetReconciliationRequest(c *gin.Context) {
+	result, apiError := h.service.GetReconciliation(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
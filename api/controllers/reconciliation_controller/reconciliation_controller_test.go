@@ -0,0 +1,83 @@
+package reconciliation_controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+var batchID = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+
+type reconciliationServiceMock struct {
+	importReport        func(string, io.Reader, reconciliation_domain.Columns) (*reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface)
+	listReconciliations func(string) ([]reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface)
+}
+
+func (s *reconciliationServiceMock) ImportReport(ctx context.Context, batchID string, report io.Reader, columns reconciliation_domain.Columns) (*reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface) {
+	return s.importReport(batchID, report, columns)
+}
+
+func (s *reconciliationServiceMock) ListReconciliations(ctx context.Context, batchID string) ([]reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface) {
+	return s.listReconciliations(batchID)
+}
+
+func (s *reconciliationServiceMock) GetReconciliation(ctx context.Context, id string) (*reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func newHandler(service *reconciliationServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleImportReportRequest(t *testing.T) {
+	t.Parallel()
+	expectedResponse := reconciliation_domain.ReconciliationResponse{ID: "1a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", BatchID: batchID, Matched: 1}
+	service := &reconciliationServiceMock{importReport: func(id string, report io.Reader, columns reconciliation_domain.Columns) (*reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, batchID, id)
+		assert.EqualValues(t, reconciliation_domain.Columns{Reference: "txn_ref", Amount: "paid"}, columns)
+		body, err := ioutil.ReadAll(report)
+		assert.Nil(t, err)
+		assert.EqualValues(t, "txn_ref,paid\n", string(body))
+		return &expectedResponse, nil
+	}}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/settlements/"+batchID+"/reconciliations?reference_column=txn_ref&amount_column=paid",
+		bytes.NewBufferString("txn_ref,paid\n"))
+	c.Params = gin.Params{{Key: "id", Value: batchID}}
+
+	newHandler(service).HandleImportReportRequest(c)
+	var actualResponse reconciliation_domain.ReconciliationResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &actualResponse))
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleListReconciliationsRequest(t *testing.T) {
+	t.Parallel()
+	service := &reconciliationServiceMock{listReconciliations: func(id string) ([]reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "not-a-uuid", id)
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidBatchIdField))
+	}}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/reconciliations?batch_id=not-a-uuid", nil)
+
+	newHandler(service).HandleListReconciliationsRequest(c)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Contains(t, response.Body.String(), error_constant.InvalidBatchIdField)
+}
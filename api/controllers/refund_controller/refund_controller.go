@@ -2,20 +2,35 @@ package refund_controller
 
 import (
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
+	"payment-gateway-api/api/domain/auth_domain"
 	"payment-gateway-api/api/domain/error_domain"
 	"payment-gateway-api/api/domain/refund_domain"
+	"payment-gateway-api/api/logger"
 	"payment-gateway-api/api/services/refund_service"
 )
 
+//Handler serves the refund endpoint with the refund service
+type Handler struct {
+	service refund_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the refund endpoint
+func New(service refund_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
 //HandleRefundRequest handles request for the refund endpoint
-func HandleRefundRequest(c *gin.Context) {
+func (h *Handler) HandleRefundRequest(c *gin.Context) {
 	request := refund_domain.RefundRequest{}
 
 	err := c.BindJSON(&request)
 	if err != nil {
-		log.Println(err.Error())
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
 		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
 			Code:  http.StatusBadRequest,
 			Error: "request body is invalid",
@@ -23,10 +38,35 @@ func HandleRefundRequest(c *gin.Context) {
 		return
 	}
 
-	result, apiError := refund_service.RefundService.RefundTransactionAmount(request)
+	result, apiError := h.service.RefundTransactionAmount(c.Request.Context(), request)
 	if apiError != nil {
 		c.JSON(apiError.Status(), apiError)
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
+
+//HandleCreateRefundRequest handles request for the refunds endpoint of an authorisation in the versioned API, the
+//authorisation ID is taken from the path and the authorisation listing the refund is located with the Location header
+func (h *Handler) HandleCreateRefundRequest(c *gin.Context) {
+	body := refund_domain.AmountRequest{}
+
+	err := c.BindJSON(&body)
+	if err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return
+	}
+
+	request := refund_domain.RefundRequest{AuthId: c.Param("id"), Amount: body.Amount}
+	result, apiError := h.service.RefundTransactionAmount(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Header("Location", auth_domain.Location(request.AuthId))
+	c.JSON(http.StatusCreated, result)
+}
@@ -2,6 +2,7 @@ package refund_controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -10,34 +11,36 @@ import (
 	"net/http/httptest"
 	"payment-gateway-api/api/domain/error_domain"
 	"payment-gateway-api/api/domain/refund_domain"
-	"payment-gateway-api/api/services/refund_service"
+	"payment-gateway-api/api/logger"
 	"strings"
 	"testing"
 )
 
-var (
+type refundServiceMock struct {
 	refundTransactionAmount func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface)
-)
+}
 
-type refundServiceMock struct{}
+func (v *refundServiceMock) RefundTransactionAmount(ctx context.Context, request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+	return v.refundTransactionAmount(request)
+}
 
-func (v refundServiceMock) RefundTransactionAmount(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
-	return refundTransactionAmount(request)
+func newHandler(service *refundServiceMock) *Handler {
+	return New(service, logger.Discard())
 }
 
 func TestHandleRefundRequest(t *testing.T) {
+	t.Parallel()
+	service := &refundServiceMock{}
 	expectedResponse := refund_domain.RefundResponse{
 		IsSuccess: true,
 		Amount:    10,
 		Currency:  "LKR",
 	}
 
-	refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+	service.refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
 		return &expectedResponse, nil
 	}
 
-	refund_service.RefundService = &refundServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
@@ -56,7 +59,7 @@ func TestHandleRefundRequest(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleRefundRequest(c)
+	newHandler(service).HandleRefundRequest(c)
 	var actualResponse refund_domain.RefundResponse
 	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
 	assert.Nil(t, err)
@@ -64,17 +67,17 @@ func TestHandleRefundRequest(t *testing.T) {
 }
 
 func TestHandleRefundRequest_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &refundServiceMock{}
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusUnprocessableEntity,
 		Error: "error_from_service",
 	}
 
-	refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+	service.refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
 		return nil, &expectedError
 	}
 
-	refund_service.RefundService = &refundServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
@@ -92,7 +95,7 @@ func TestHandleRefundRequest_ErrorFromService(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleRefundRequest(c)
+	newHandler(service).HandleRefundRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
@@ -100,6 +103,8 @@ func TestHandleRefundRequest_ErrorFromService(t *testing.T) {
 }
 
 func TestHandleRefundRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	service := &refundServiceMock{}
 	var err error
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusBadRequest,
@@ -123,9 +128,48 @@ func TestHandleRefundRequest_InvalidBody(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleRefundRequest(c)
+	newHandler(service).HandleRefundRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedError.ErrorMessage(), actualError.ErrorMessage())
 }
+
+func TestHandleCreateRefundRequest(t *testing.T) {
+	t.Parallel()
+	service := &refundServiceMock{}
+	expectedResponse := refund_domain.RefundResponse{
+		IsSuccess: true,
+		Amount:    5,
+		Currency:  "GBP",
+	}
+	service.refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, refund_domain.RefundRequest{AuthId: "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", Amount: 5}, request)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01/refunds", strings.NewReader(`{"amount": 5}`))
+
+	newHandler(service).HandleCreateRefundRequest(c)
+	var actualResponse refund_domain.RefundResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", response.Header().Get("Location"))
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleCreateRefundRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01/refunds", strings.NewReader(`{"amount": "five"}`))
+
+	newHandler(&refundServiceMock{}).HandleCreateRefundRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.Empty(t, response.Header().Get("Location"))
+}
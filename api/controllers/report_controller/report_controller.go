@@ -0,0 +1,55 @@
+package report_controller
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/report_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/report_service"
+)
+
+//Handler serves the report endpoints with the report service
+type Handler struct {
+	service report_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the report endpoints
+func New(service report_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleSummaryRequest handles request for the endpoint reporting on the activity of the calling merchant, the period,
+//the filters and the bucket are query parameters
+func (h *Handler) HandleSummaryRequest(c *gin.Context) {
+	h.summarise(c, h.service.GetSummary)
+}
+
+//HandleOverallSummaryRequest handles request for the admin endpoint reporting on the activity of every merchant
+func (h *Handler) HandleOverallSummaryRequest(c *gin.Context) {
+	h.summarise(c, h.service.GetOverallSummary)
+}
+
+func (h *Handler) summarise(c *gin.Context, summarise func(context.Context, report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface)) {
+	request := report_domain.SummaryRequest{}
+	if err := c.ShouldBindQuery(&request); err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request query is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request query is invalid",
+		})
+		return
+	}
+
+	result, apiError := summarise(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
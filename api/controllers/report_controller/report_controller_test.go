@@ -0,0 +1,95 @@
+package report_controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/report_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+type reportServiceMock struct {
+	getSummary        func(report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface)
+	getOverallSummary func(report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface)
+}
+
+func (r *reportServiceMock) GetSummary(ctx context.Context, request report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface) {
+	return r.getSummary(request)
+}
+
+func (r *reportServiceMock) GetOverallSummary(ctx context.Context, request report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface) {
+	return r.getOverallSummary(request)
+}
+
+func newHandler(service *reportServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleSummaryRequest(t *testing.T) {
+	t.Parallel()
+	service := &reportServiceMock{}
+	expectedResponse := report_domain.SummaryResponse{
+		From:    "2020-06-01T00:00:00Z",
+		To:      "2020-06-08T00:00:00Z",
+		Bucket:  report_domain.BucketWeek,
+		Totals:  report_domain.NewSummary(),
+		Buckets: []report_domain.BucketSummary{{Start: "2020-06-01", Summary: report_domain.NewSummary()}},
+	}
+	expectedResponse.Totals.ApprovalRate = 0.75
+	service.getSummary = func(request report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface) {
+		expectedRequest := report_domain.SummaryRequest{From: "2020-06-01T00:00:00Z", To: "2020-06-08T00:00:00Z", MerchantID: "acme", Currency: "GBP", Bucket: "week"}
+		assert.EqualValues(t, expectedRequest, request)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/payments/reports/summary?from=2020-06-01T00:00:00Z&to=2020-06-08T00:00:00Z&merchant_id=acme&currency=GBP&bucket=week", nil)
+
+	newHandler(service).HandleSummaryRequest(c)
+	var actualResponse report_domain.SummaryResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleOverallSummaryRequest(t *testing.T) {
+	t.Parallel()
+	service := &reportServiceMock{}
+	service.getOverallSummary = func(request report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, report_domain.SummaryRequest{From: "2020-06-01T00:00:00Z", To: "2020-06-08T00:00:00Z"}, request)
+		return &report_domain.SummaryResponse{From: request.From, To: request.To, Totals: report_domain.NewSummary()}, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/reports/summary?from=2020-06-01T00:00:00Z&to=2020-06-08T00:00:00Z", nil)
+
+	newHandler(service).HandleOverallSummaryRequest(c)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"from":"2020-06-01T00:00:00Z"`)
+}
+
+func TestHandleSummaryRequest_Error(t *testing.T) {
+	t.Parallel()
+	service := &reportServiceMock{}
+	service.getSummary = func(request report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ReportRangeRequired))
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/payments/reports/summary", nil)
+
+	newHandler(service).HandleSummaryRequest(c)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Contains(t, response.Body.String(), error_constant.ReportRangeRequired)
+}
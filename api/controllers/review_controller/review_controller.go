@@ -0,0 +1,76 @@
+package review_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/review_service"
+)
+
+//Handler serves the review admin endpoints with the review service
+type Handler struct {
+	service review_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the review admin endpoints
+func New(service review_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleListReviewsRequest handles request for the review queue endpoint, the queue can be filtered on the state query parameter
+func (h *Handler) HandleListReviewsRequest(c *gin.Context) {
+	result, apiError := h.service.ListReviews(c.Request.Context(), c.Query("state"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleApproveReviewRequest handles request for the review approval endpoint
+func (h *Handler) HandleApproveReviewRequest(c *gin.Context) {
+	request := review_domain.ReviewDecisionRequest{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.ApproveReview(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleDeclineReviewRequest handles request for the review decline endpoint
+func (h *Handler) HandleDeclineReviewRequest(c *gin.Context) {
+	request := review_domain.ReviewDecisionRequest{}
+	if !h.bind(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.DeclineReview(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) bind(c *gin.Context, request interface{}) bool {
+	if err := c.BindJSON(request); err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return false
+	}
+	return true
+}
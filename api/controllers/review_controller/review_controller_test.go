@@ -0,0 +1,133 @@
+package review_controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+)
+
+type reviewServiceMock struct {
+	listReviews   func(string) ([]review_domain.ReviewResponse, error_domain.GatewayErrorInterface)
+	approveReview func(review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface)
+	declineReview func(review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface)
+}
+
+func (r *reviewServiceMock) ListReviews(ctx context.Context, state string) ([]review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+	return r.listReviews(state)
+}
+
+func (r *reviewServiceMock) ApproveReview(ctx context.Context, request review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+	return r.approveReview(request)
+}
+
+func (r *reviewServiceMock) DeclineReview(ctx context.Context, request review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+	return r.declineReview(request)
+}
+
+func (r *reviewServiceMock) DeclineExpiredReviews(ctx context.Context) error {
+	return nil
+}
+
+func newHandler(service *reviewServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleListReviewsRequest(t *testing.T) {
+	t.Parallel()
+	service := &reviewServiceMock{}
+	expectedResponse := []review_domain.ReviewResponse{{
+		AuthID:         "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01",
+		State:          review_domain.StatePending,
+		TriggeredRules: []string{"daily amount"},
+		Amount:         600,
+		Currency:       "GBP",
+		DueAt:          "2020-06-15T13:00:00Z",
+	}}
+	service.listReviews = func(state string) ([]review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, review_domain.StatePending, state)
+		return expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/reviews?state=pending", nil)
+
+	newHandler(service).HandleListReviewsRequest(c)
+	var actualResponse []review_domain.ReviewResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleApproveReviewRequest(t *testing.T) {
+	t.Parallel()
+	service := &reviewServiceMock{}
+	expectedResponse := review_domain.ReviewResponse{
+		AuthID:     "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01",
+		State:      review_domain.StateApproved,
+		Amount:     600,
+		Currency:   "GBP",
+		DueAt:      "2020-06-15T13:00:00Z",
+		Reviewer:   "analyst@example.com",
+		Reason:     "customer confirmed the purchase",
+		ReviewedAt: "2020-06-15T12:30:00Z",
+	}
+	service.approveReview = func(request review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "analyst@example.com", request.Reviewer)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	b, err := json.Marshal(&review_domain.ReviewDecisionRequest{
+		AuthId:   "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01",
+		Reviewer: "analyst@example.com",
+		Reason:   "customer confirmed the purchase",
+	})
+	assert.Nil(t, err)
+	c.Request, err = http.NewRequest(http.MethodPatch, "", bytes.NewBuffer(b))
+	assert.Nil(t, err)
+
+	newHandler(service).HandleApproveReviewRequest(c)
+	var actualResponse review_domain.ReviewResponse
+	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleDeclineReviewRequest_Errors(t *testing.T) {
+	t.Parallel()
+	service := &reviewServiceMock{}
+	service.declineReview = func(request review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+		return nil, &error_domain.GatewayError{Code: http.StatusUnprocessableEntity, Error: "review is not pending"}
+	}
+
+	//the reason of the decision is required
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "", ioutil.NopCloser(strings.NewReader(`{"id": "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", "reviewer": "analyst@example.com"}`)))
+	newHandler(service).HandleDeclineReviewRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "", ioutil.NopCloser(strings.NewReader(`{"id": "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", "reviewer": "analyst@example.com", "reason": "stolen card"}`)))
+	newHandler(service).HandleDeclineReviewRequest(c)
+	var actualError error_domain.GatewayError
+	err := json.Unmarshal(response.Body.Bytes(), &actualError)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, response.Code)
+	assert.EqualValues(t, "review is not pending", actualError.ErrorMessage())
+}
@@ -0,0 +1,82 @@
+package settlement_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/settlement_service"
+)
+
+//Handler serves the settlement admin endpoints with the settlement service
+type Handler struct {
+	service settlement_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the settlement admin endpoints
+func New(service settlement_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleCloseBatchRequest handles request for the endpoint closing the settlement batch of the latest cut-off
+func (h *Handler) HandleCloseBatchRequest(c *gin.Context) {
+	result, apiError := h.service.CloseBatch(c.Request.Context())
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+//HandleListBatchesRequest handles request for the settlement batches endpoint
+func (h *Handler) HandleListBatchesRequest(c *gin.Context) {
+	result, apiError := h.service.ListBatches(c.Request.Context())
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleGetBatchRequest handles request for the endpoint returning a settlement batch with its totals
+func (h *Handler) HandleGetBatchRequest(c *gin.Context) {
+	result, apiError := h.service.GetBatch(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleSettlementFileRequest handles request for the settlement file endpoint, the format is set by the format query parameter
+func (h *Handler) HandleSettlementFileRequest(c *gin.Context) {
+	result, apiError := h.service.GetSettlementFile(c.Request.Context(), c.Param("id"), c.Query("format"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+result.Name+`"`)
+	c.Data(http.StatusOK, result.ContentType, result.Content)
+}
+
+//HandleSubmitBatchRequest handles request for the endpoint recording the submission of a settlement batch
+func (h *Handler) HandleSubmitBatchRequest(c *gin.Context) {
+	result, apiError := h.service.SubmitBatch(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleReopenBatchRequest handles request for the endpoint reopening a settlement batch that has not been submitted
+func (h *Handler) HandleReopenBatchRequest(c *gin.Context) {
+	if apiError := h.service.ReopenBatch(c.Request.Context(), c.Param("id")); apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package settlement_controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/settlement_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+var batchID = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+
+type settlementServiceMock struct {
+	closeBatch        func() (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface)
+	getSettlementFile func(string, string) (*settlement_domain.File, error_domain.GatewayErrorInterface)
+	reopenBatch       func(string) error_domain.GatewayErrorInterface
+}
+
+func (s *settlementServiceMock) CloseBatch(ctx context.Context) (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface) {
+	return s.closeBatch()
+}
+
+func (s *settlementServiceMock) ListBatches(ctx context.Context) ([]settlement_domain.BatchResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (s *settlementServiceMock) GetBatch(ctx context.Context, id string) (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (s *settlementServiceMock) GetSettlementFile(ctx context.Context, id string, format string) (*settlement_domain.File, error_domain.GatewayErrorInterface) {
+	return s.getSettlementFile(id, format)
+}
+
+func (s *settlementServiceMock) SubmitBatch(ctx context.Context, id string) (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (s *settlementServiceMock) ReopenBatch(ctx context.Context, id string) error_domain.GatewayErrorInterface {
+	return s.reopenBatch(id)
+}
+
+func (s *settlementServiceMock) CloseDueBatch(ctx context.Context) error {
+	return nil
+}
+
+func newHandler(service *settlementServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleCloseBatchRequest(t *testing.T) {
+	t.Parallel()
+	expectedResponse := settlement_domain.BatchResponse{ID: batchID, CutOff: "2020-06-14T22:00:00Z", State: settlement_domain.StateClosed}
+	service := &settlementServiceMock{closeBatch: func() (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface) {
+		return &expectedResponse, nil
+	}}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/settlements", nil)
+
+	newHandler(service).HandleCloseBatchRequest(c)
+	var actualResponse settlement_domain.BatchResponse
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &actualResponse))
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleSettlementFileRequest(t *testing.T) {
+	t.Parallel()
+	service := &settlementServiceMock{getSettlementFile: func(id string, format string) (*settlement_domain.File, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, batchID, id)
+		assert.EqualValues(t, settlement_domain.FormatFixedWidth, format)
+		return &settlement_domain.File{Name: "settlement.txt", ContentType: "text/plain", Content: []byte("H\n")}, nil
+	}}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/settlements/"+batchID+"/file?format=fixed", nil)
+	c.Params = gin.Params{{Key: "id", Value: batchID}}
+
+	newHandler(service).HandleSettlementFileRequest(c)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "text/plain", response.Header().Get("Content-Type"))
+	assert.EqualValues(t, `attachment; filename="settlement.txt"`, response.Header().Get("Content-Disposition"))
+	assert.EqualValues(t, "H\n", response.Body.String())
+}
+
+func TestHandleReopenBatchRequest(t *testing.T) {
+	t.Parallel()
+	service := &settlementServiceMock{reopenBatch: func(id string) error_domain.GatewayErrorInterface {
+		return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.BatchStateInvalid))
+	}}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/admin/settlements/"+batchID+"/reopen", nil)
+	c.Params = gin.Params{{Key: "id", Value: batchID}}
+
+	newHandler(service).HandleReopenBatchRequest(c)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Contains(t, response.Body.String(), error_constant.BatchStateInvalid)
+}
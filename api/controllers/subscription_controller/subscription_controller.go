@@ -0,0 +1,83 @@
+package subscription_controller
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/subscription_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/subscription_service"
+)
+
+//Handler serves the subscription endpoints with the subscription service
+type Handler struct {
+	service subscription_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the subscription endpoints
+func New(service subscription_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleCreateSubscriptionRequest handles request for the subscription creation endpoint
+func (h *Handler) HandleCreateSubscriptionRequest(c *gin.Context) {
+	request := subscription_domain.SubscriptionRequest{}
+
+	err := c.BindJSON(&request)
+	if err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return
+	}
+
+	result, apiError := h.service.CreateSubscription(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+//HandlePauseSubscriptionRequest handles request for the subscription pause endpoint
+func (h *Handler) HandlePauseSubscriptionRequest(c *gin.Context) {
+	h.handleStateRequest(c, h.service.PauseSubscription)
+}
+
+//HandleResumeSubscriptionRequest handles request for the subscription resume endpoint
+func (h *Handler) HandleResumeSubscriptionRequest(c *gin.Context) {
+	h.handleStateRequest(c, h.service.ResumeSubscription)
+}
+
+//HandleCancelSubscriptionRequest handles request for the subscription cancel endpoint
+func (h *Handler) HandleCancelSubscriptionRequest(c *gin.Context) {
+	h.handleStateRequest(c, h.service.CancelSubscription)
+}
+
+func (h *Handler) handleStateRequest(c *gin.Context, changeState func(context.Context, subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface)) {
+	request := subscription_domain.SubscriptionStateRequest{}
+
+	err := c.BindJSON(&request)
+	if err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request body is invalid",
+		})
+		return
+	}
+
+	result, apiError := changeState(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,151 @@
+package subscription_controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/subscription_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+)
+
+type subscriptionServiceMock struct {
+	createSubscription func(subscription_domain.SubscriptionRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface)
+	changeState        func(subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface)
+}
+
+func (s *subscriptionServiceMock) CreateSubscription(ctx context.Context, request subscription_domain.SubscriptionRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+	return s.createSubscription(request)
+}
+
+func (s *subscriptionServiceMock) PauseSubscription(ctx context.Context, request subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+	return s.changeState(request)
+}
+
+func (s *subscriptionServiceMock) ResumeSubscription(ctx context.Context, request subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+	return s.changeState(request)
+}
+
+func (s *subscriptionServiceMock) CancelSubscription(ctx context.Context, request subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+	return s.changeState(request)
+}
+
+func (s *subscriptionServiceMock) ChargeDueSubscriptions(ctx context.Context) error {
+	return nil
+}
+
+func newHandler(service *subscriptionServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleCreateSubscriptionRequest(t *testing.T) {
+	t.Parallel()
+	service := &subscriptionServiceMock{}
+	expectedResponse := subscription_domain.SubscriptionResponse{
+		SubscriptionID: "valid_id",
+		IsSuccess:      true,
+		State:          subscription_domain.StateActive,
+		Amount:         10,
+		Currency:       "GBP",
+		Interval:       subscription_domain.IntervalMonthly,
+		NextChargeDate: "2020-01-31",
+	}
+
+	service.createSubscription = func(request subscription_domain.SubscriptionRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+
+	request := subscription_domain.SubscriptionRequest{
+		AuthId:     "valid_string",
+		Amount:     10,
+		Currency:   "GBP",
+		Interval:   subscription_domain.IntervalMonthly,
+		AnchorDate: "2020-01-31",
+	}
+
+	b, err := json.Marshal(&request)
+	if err != nil {
+		t.Fail()
+	}
+
+	c.Request, err = http.NewRequest(http.MethodPost, "", bytes.NewBuffer(b))
+	if err != nil {
+		t.Fail()
+	}
+
+	newHandler(service).HandleCreateSubscriptionRequest(c)
+	var actualResponse subscription_domain.SubscriptionResponse
+	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusCreated, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandlePauseSubscriptionRequest_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &subscriptionServiceMock{}
+	expectedError := error_domain.GatewayError{
+		Code:  http.StatusUnprocessableEntity,
+		Error: "error_from_service",
+	}
+
+	service.changeState = func(request subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+		return nil, &expectedError
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+
+	b, err := json.Marshal(&subscription_domain.SubscriptionStateRequest{SubscriptionId: "valid_string"})
+	if err != nil {
+		t.Fail()
+	}
+
+	c.Request, err = http.NewRequest(http.MethodPatch, "", bytes.NewBuffer(b))
+	if err != nil {
+		t.Fail()
+	}
+
+	newHandler(service).HandlePauseSubscriptionRequest(c)
+	var actualError error_domain.GatewayError
+	err = json.Unmarshal(response.Body.Bytes(), &actualError)
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedError.Code, response.Code)
+	assert.EqualValues(t, expectedError.ErrorMessage(), actualError.ErrorMessage())
+}
+
+func TestHandleCancelSubscriptionRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	service := &subscriptionServiceMock{}
+	var err error
+	expectedError := error_domain.GatewayError{
+		Code:  http.StatusBadRequest,
+		Error: "request body is invalid",
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+
+	body := ioutil.NopCloser(strings.NewReader(`{"subscription": "THIS IS SO WRONG"}`))
+
+	c.Request, err = http.NewRequest(http.MethodPatch, "", body)
+	if err != nil {
+		t.Fail()
+	}
+
+	newHandler(service).HandleCancelSubscriptionRequest(c)
+	var actualError error_domain.GatewayError
+	err = json.Unmarshal(response.Body.Bytes(), &actualError)
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedError.ErrorMessage(), actualError.ErrorMessage())
+}
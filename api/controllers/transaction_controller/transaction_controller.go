@@ -0,0 +1,94 @@
+package transaction_controller
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/transaction_service"
+)
+
+//Handler serves the transaction endpoints with the transaction service
+type Handler struct {
+	service transaction_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the transaction endpoints
+func New(service transaction_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
+//HandleSearchTransactionsRequest handles request for the endpoint searching the transactions of the calling merchant,
+//the filters, the order and the cursor of the page are query parameters
+func (h *Handler) HandleSearchTransactionsRequest(c *gin.Context) {
+	h.search(c, h.service.SearchTransactions)
+}
+
+//HandleSearchAllTransactionsRequest handles request for the admin endpoint searching the transactions of every merchant
+func (h *Handler) HandleSearchAllTransactionsRequest(c *gin.Context) {
+	h.search(c, h.service.SearchAllTransactions)
+}
+
+func (h *Handler) search(c *gin.Context, search func(context.Context, transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface)) {
+	request := transaction_domain.SearchRequest{}
+	if !h.bindQuery(c, &request) {
+		return
+	}
+
+	result, apiError := search(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleGetTransactionRequest handles request for the endpoint returning a transaction with its operations
+func (h *Handler) HandleGetTransactionRequest(c *gin.Context) {
+	result, apiError := h.service.GetTransaction(c.Request.Context(), c.Param("id"))
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleExportRequest handles request for the transaction export endpoint, the export is streamed as it is read. Once
+//the first bytes have been sent a failure can no longer change the status, the export is then cut short and the
+//missing totals tell it apart from a complete one
+func (h *Handler) HandleExportRequest(c *gin.Context) {
+	request := transaction_domain.ExportRequest{}
+	if !h.bindQuery(c, &request) {
+		return
+	}
+
+	result, apiError := h.service.ExportTransactions(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+result.Name+`"`)
+	c.Header("Content-Type", result.ContentType)
+	c.Status(http.StatusOK)
+	if err := result.Write(c.Writer); err != nil {
+		c.Abort()
+	}
+}
+
+func (h *Handler) bindQuery(c *gin.Context, request interface{}) bool {
+	if err := c.ShouldBindQuery(request); err != nil {
+		h.logger.Ctx(c.Request.Context()).Warn("request query is invalid", logger.Err(err))
+		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
+			Code:  http.StatusBadRequest,
+			Error: "request query is invalid",
+		})
+		return false
+	}
+	return true
+}
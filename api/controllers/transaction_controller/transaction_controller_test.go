@@ -0,0 +1,159 @@
+package transaction_controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+var authID = "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+
+type transactionServiceMock struct {
+	searchTransactions    func(transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface)
+	searchAllTransactions func(transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface)
+	getTransaction        func(string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface)
+	exportTransactions    func(transaction_domain.ExportRequest) (*transaction_domain.ExportFile, error_domain.GatewayErrorInterface)
+}
+
+func (t *transactionServiceMock) SearchTransactions(ctx context.Context, request transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface) {
+	return t.searchTransactions(request)
+}
+
+func (t *transactionServiceMock) SearchAllTransactions(ctx context.Context, request transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface) {
+	return t.searchAllTransactions(request)
+}
+
+func (t *transactionServiceMock) GetTransaction(ctx context.Context, id string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface) {
+	return t.getTransaction(id)
+}
+
+func (t *transactionServiceMock) ExportTransactions(ctx context.Context, request transaction_domain.ExportRequest) (*transaction_domain.ExportFile, error_domain.GatewayErrorInterface) {
+	return t.exportTransactions(request)
+}
+
+func newHandler(service *transactionServiceMock) *Handler {
+	return New(service, logger.Discard())
+}
+
+func TestHandleSearchTransactionsRequest(t *testing.T) {
+	t.Parallel()
+	service := &transactionServiceMock{}
+	expectedResponse := transaction_domain.SearchResponse{
+		Transactions: []transaction_domain.TransactionResponse{{ID: authID, State: transaction_domain.StateCaptured, Amount: 100, Currency: "GBP"}},
+		NextCursor:   "eyJpZCI6IjEifQ",
+	}
+	service.searchTransactions = func(request transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface) {
+		expectedRequest := transaction_domain.SearchRequest{State: "captured", MinAmount: 10.5, Last4: "0002", Sort: "amount", Limit: 20, Cursor: "abc"}
+		assert.EqualValues(t, expectedRequest, request)
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/payments/transactions?state=captured&min_amount=10.5&last4=0002&sort=amount&limit=20&cursor=abc", nil)
+
+	newHandler(service).HandleSearchTransactionsRequest(c)
+	var actualResponse transaction_domain.SearchResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestHandleSearchAllTransactionsRequest(t *testing.T) {
+	t.Parallel()
+	service := &transactionServiceMock{}
+	service.searchAllTransactions = func(request transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, transaction_domain.SearchRequest{MerchantID: "globex"}, request)
+		return &transaction_domain.SearchResponse{Transactions: []transaction_domain.TransactionResponse{}}, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/transactions?merchant_id=globex", nil)
+
+	newHandler(service).HandleSearchAllTransactionsRequest(c)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, `{"transactions":[]}`, response.Body.String())
+}
+
+func TestHandleSearchTransactionsRequest_InvalidQuery(t *testing.T) {
+	t.Parallel()
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/payments/transactions?limit=ten", nil)
+
+	newHandler(&transactionServiceMock{}).HandleSearchTransactionsRequest(c)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "request query is invalid")
+}
+
+func TestHandleGetTransactionRequest_Error(t *testing.T) {
+	t.Parallel()
+	service := &transactionServiceMock{}
+	service.getTransaction = func(id string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, authID, id)
+		return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/payments/transactions/"+authID, nil)
+	c.Params = gin.Params{{Key: "id", Value: authID}}
+
+	newHandler(service).HandleGetTransactionRequest(c)
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+	assert.Contains(t, response.Body.String(), error_constant.TransactionNotFound)
+}
+
+func TestHandleExportRequest(t *testing.T) {
+	t.Parallel()
+	service := &transactionServiceMock{}
+	service.exportTransactions = func(request transaction_domain.ExportRequest) (*transaction_domain.ExportFile, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z", Columns: "operation,amount"}, request)
+		return &transaction_domain.ExportFile{
+			Name:        "transactions-20200601-20200701.csv",
+			ContentType: "text/csv",
+			Write: func(w io.Writer) error {
+				_, err := io.WriteString(w, "operation,amount\ncapture,60.00\n")
+				return err
+			},
+		}, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/transactions/export?from=2020-06-01T00:00:00Z&to=2020-07-01T00:00:00Z&columns=operation,amount", nil)
+
+	newHandler(service).HandleExportRequest(c)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "text/csv", response.Header().Get("Content-Type"))
+	assert.EqualValues(t, `attachment; filename="transactions-20200601-20200701.csv"`, response.Header().Get("Content-Disposition"))
+	assert.EqualValues(t, "operation,amount\ncapture,60.00\n", response.Body.String())
+}
+
+func TestHandleExportRequest_Error(t *testing.T) {
+	t.Parallel()
+	service := &transactionServiceMock{}
+	service.exportTransactions = func(request transaction_domain.ExportRequest) (*transaction_domain.ExportFile, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ExportRangeRequired))
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/admin/transactions/export", nil)
+
+	newHandler(service).HandleExportRequest(c)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Contains(t, response.Body.String(), error_constant.ExportRangeRequired)
+}
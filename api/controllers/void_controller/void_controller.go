@@ -2,20 +2,34 @@ package void_controller
 
 import (
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
 	"payment-gateway-api/api/domain/error_domain"
 	"payment-gateway-api/api/domain/void_domain"
+	"payment-gateway-api/api/logger"
 	"payment-gateway-api/api/services/void_service"
 )
 
+//Handler serves the void endpoint with the void service
+type Handler struct {
+	service void_service.Service
+	logger  *logger.Logger
+}
+
+//New creates the handler of the void endpoint
+func New(service void_service.Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  log,
+	}
+}
+
 //HandleVoidRequest handles request for the void endpoint
-func HandleVoidRequest(c *gin.Context) {
+func (h *Handler) HandleVoidRequest(c *gin.Context) {
 	request := void_domain.VoidRequest{}
 
 	err := c.BindJSON(&request)
 	if err != nil {
-		log.Println(err.Error())
+		h.logger.Ctx(c.Request.Context()).Warn("request body is invalid", logger.Err(err))
 		c.JSON(http.StatusBadRequest, error_domain.GatewayError{
 			Code:  http.StatusBadRequest,
 			Error: "request body is invalid",
@@ -23,7 +37,18 @@ func HandleVoidRequest(c *gin.Context) {
 		return
 	}
 
-	result, apiError := void_service.VoidService.VoidTransaction(request)
+	result, apiError := h.service.VoidTransaction(c.Request.Context(), request)
+	if apiError != nil {
+		c.JSON(apiError.Status(), apiError)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+//HandleVoidAuthorisationRequest handles request for the void endpoint of an authorisation in the versioned API, the
+//authorisation ID is taken from the path. Nothing is created, the voided authorisation is returned with 200
+func (h *Handler) HandleVoidAuthorisationRequest(c *gin.Context) {
+	result, apiError := h.service.VoidTransaction(c.Request.Context(), void_domain.VoidRequest{AuthId: c.Param("id")})
 	if apiError != nil {
 		c.JSON(apiError.Status(), apiError)
 		return
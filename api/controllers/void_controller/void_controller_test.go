@@ -2,6 +2,7 @@ package void_controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -10,34 +11,36 @@ import (
 	"net/http/httptest"
 	"payment-gateway-api/api/domain/error_domain"
 	"payment-gateway-api/api/domain/void_domain"
-	"payment-gateway-api/api/services/void_service"
+	"payment-gateway-api/api/logger"
 	"strings"
 	"testing"
 )
 
-var (
+type voidServiceMock struct {
 	voidTransaction func(void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface)
-)
+}
 
-type voidServiceMock struct{}
+func (v *voidServiceMock) VoidTransaction(ctx context.Context, request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+	return v.voidTransaction(request)
+}
 
-func (v voidServiceMock) VoidTransaction(request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
-	return voidTransaction(request)
+func newHandler(service *voidServiceMock) *Handler {
+	return New(service, logger.Discard())
 }
 
 func TestHandleVoidRequest(t *testing.T) {
+	t.Parallel()
+	service := &voidServiceMock{}
 	expectedResponse := void_domain.VoidResponse{
 		IsSuccess: true,
 		Amount:    10,
 		Currency:  "LKR",
 	}
 
-	voidTransaction = func(request void_domain.VoidRequest) (response *void_domain.VoidResponse, errorInterface error_domain.GatewayErrorInterface) {
+	service.voidTransaction = func(request void_domain.VoidRequest) (response *void_domain.VoidResponse, errorInterface error_domain.GatewayErrorInterface) {
 		return &expectedResponse, nil
 	}
 
-	void_service.VoidService = &voidServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
@@ -53,7 +56,7 @@ func TestHandleVoidRequest(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleVoidRequest(c)
+	newHandler(service).HandleVoidRequest(c)
 	var actualResponse void_domain.VoidResponse
 	err = json.Unmarshal(response.Body.Bytes(), &actualResponse)
 	assert.Nil(t, err)
@@ -61,17 +64,17 @@ func TestHandleVoidRequest(t *testing.T) {
 }
 
 func TestHandleVoidRequest_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &voidServiceMock{}
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusUnprocessableEntity,
 		Error: "error_from_service",
 	}
 
-	voidTransaction = func(request void_domain.VoidRequest) (response *void_domain.VoidResponse, errorInterface error_domain.GatewayErrorInterface) {
+	service.voidTransaction = func(request void_domain.VoidRequest) (response *void_domain.VoidResponse, errorInterface error_domain.GatewayErrorInterface) {
 		return nil, &expectedError
 	}
 
-	void_service.VoidService = &voidServiceMock{}
-
 	response := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(response)
 
@@ -87,7 +90,7 @@ func TestHandleVoidRequest_ErrorFromService(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleVoidRequest(c)
+	newHandler(service).HandleVoidRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
@@ -95,6 +98,8 @@ func TestHandleVoidRequest_ErrorFromService(t *testing.T) {
 }
 
 func TestHandleVoidRequest_InvalidBody(t *testing.T) {
+	t.Parallel()
+	service := &voidServiceMock{}
 	var err error
 	expectedError := error_domain.GatewayError{
 		Code:  http.StatusBadRequest,
@@ -118,9 +123,44 @@ func TestHandleVoidRequest_InvalidBody(t *testing.T) {
 		t.Fail()
 	}
 
-	HandleVoidRequest(c)
+	newHandler(service).HandleVoidRequest(c)
 	var actualError error_domain.GatewayError
 	err = json.Unmarshal(response.Body.Bytes(), &actualError)
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedError.ErrorMessage(), actualError.ErrorMessage())
 }
+
+func TestHandleVoidAuthorisationRequest(t *testing.T) {
+	t.Parallel()
+	service := &voidServiceMock{}
+	expectedResponse := void_domain.VoidResponse{
+		IsSuccess: true,
+		Amount:    10,
+		Currency:  "GBP",
+	}
+	service.voidTransaction = func(request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+		if request.AuthId != "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01" {
+			return nil, &error_domain.GatewayError{Code: http.StatusNotFound, Error: "transaction not found"}
+		}
+		return &expectedResponse, nil
+	}
+
+	response := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations/5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01/void", nil)
+
+	newHandler(service).HandleVoidAuthorisationRequest(c)
+	var actualResponse void_domain.VoidResponse
+	err := json.Unmarshal(response.Body.Bytes(), &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+
+	response = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(response)
+	c.Params = gin.Params{{Key: "id", Value: "6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12"}}
+	c.Request, _ = http.NewRequest(http.MethodPost, "/v1/authorisations/6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12/void", nil)
+	newHandler(service).HandleVoidAuthorisationRequest(c)
+	assert.EqualValues(t, http.StatusNotFound, response.Code)
+}
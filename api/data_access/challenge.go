@@ -0,0 +1,159 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/challenge"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//InsertChallengeRecord inserts the challenge the cardholder must complete before the authorisation is sent to the acquirer
+func (db *Database) InsertChallengeRecord(ctx context.Context, data *challenge.Challenge) (err error) {
+	defer db.observe("InsertChallengeRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertChallengeRecord"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertChallengeRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//GetChallengeRecordByAuthID fetches the challenge of an authorisation
+func (db *Database) GetChallengeRecordByAuthID(ctx context.Context, id string) (_ *challenge.Challenge, err error) {
+	defer db.observe("GetChallengeRecordByAuthID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetChallengeRecordByAuthID"), logger.Err(err))
+		return nil, err
+	}
+
+	var record challenge.Challenge
+	if err := tx.Where("auth_id = ?", id).First(&record).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetChallengeRecordByAuthID"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &record, tx.Commit().Error
+}
+
+//UpdateChallengeRecordState moves the challenge from one state to another, gorm.ErrRecordNotFound is returned
+//when the challenge is no longer in the expected state
+func (db *Database) UpdateChallengeRecordState(ctx context.Context, data *challenge.Challenge, from string) (err error) {
+	defer db.observe("UpdateChallengeRecordState", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateChallengeRecordState"), logger.Err(err))
+		return err
+	}
+
+	if err := db.updateChallengeState(data, from, tx); err != nil {
+		if err != gorm.ErrRecordNotFound {
+			db.logger.Error("database call failed", logger.String("call", "UpdateChallengeRecordState"), logger.Err(err))
+		}
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//FinaliseChallengeRecord completes the challenge together with the authorisation it led to, if any, and with its entry
+//in the review queue when the fraud rules held it, gorm.ErrRecordNotFound is returned when the challenge is no longer
+//in the expected state so that a challenge is never finalised twice
+func (db *Database) FinaliseChallengeRecord(ctx context.Context, data *challenge.Challenge, from string, authorised *auth.Auth, pending *review.Review) (err error) {
+	defer db.observe("FinaliseChallengeRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "FinaliseChallengeRecord"), logger.Err(err))
+		return err
+	}
+
+	if err := db.updateChallengeState(data, from, tx); err != nil {
+		if err != gorm.ErrRecordNotFound {
+			db.logger.Error("database call failed", logger.String("call", "FinaliseChallengeRecord"), logger.Err(err))
+		}
+		tx.Rollback()
+		return err
+	}
+
+	if authorised == nil {
+		return tx.Commit().Error
+	}
+
+	if err := tx.Create(authorised).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "FinaliseChallengeRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := db.insertOperation("authorisation", authorised, authorised.AuthorisedAmount, 0, tx); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "FinaliseChallengeRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if pending != nil {
+		if err := db.insertReview(pending, authorised, tx); err != nil {
+			db.logger.Error("database call failed", logger.String("call", "FinaliseChallengeRecord"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+//updateChallengeState checks the current state in the update itself so that two concurrent calls cannot both move the challenge
+func (db *Database) updateChallengeState(data *challenge.Challenge, from string, tx *gorm.DB) error {
+	result := tx.Model(&challenge.Challenge{}).Where("auth_id = ? AND state = ?", data.AuthID, from).Updates(map[string]interface{}{
+		"state":        data.State,
+		"completed_at": data.CompletedAt,
+		"updated_at":   data.UpdatedAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package data_access
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/challenge"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"testing"
+	"time"
+)
+
+func TestDatabase_Challenges_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	id := "a1c3e5f7-2b4d-4f6a-8c0e-1d3f5a7b9c02"
+	record := challenge.Challenge{
+		AuthID:     id,
+		Number:     "4929907390318794",
+		ExpiryDate: "12-2099",
+		Amount:     10,
+		Currency:   "EUR",
+		State:      "pending",
+		Rules:      "high amount",
+		ExpiresAt:  now.Add(10 * time.Minute),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	assert.Nil(t, db.InsertChallengeRecord(context.Background(), &record))
+
+	record.State = "authenticated"
+	assert.Nil(t, db.UpdateChallengeRecordState(context.Background(), &record, "pending"))
+	//a challenge can only be answered once
+	assert.EqualValues(t, "record not found", db.UpdateChallengeRecordState(context.Background(), &record, "pending").Error())
+
+	stored, err := db.GetChallengeRecordByAuthID(context.Background(), id)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "authenticated", stored.State)
+	assert.EqualValues(t, now.Add(10*time.Minute), stored.ExpiresAt.UTC())
+
+	authorised := auth.Auth{
+		ID:               id,
+		Number:           record.Number,
+		ExpiryDate:       record.ExpiryDate,
+		AuthorisedAmount: 10,
+		AvailableAmount:  10,
+		Currency:         "EUR",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		ThreeDSStatus:    "authenticated",
+		LiabilityShift:   true,
+	}
+	pending := review.Review{AuthID: id, State: "pending", Rules: record.Rules, Amount: 10, Currency: "EUR", DueAt: now.Add(time.Hour)}
+	stored.State = "completed"
+	stored.CompletedAt = now
+	assert.Nil(t, db.FinaliseChallengeRecord(context.Background(), stored, "authenticated", &authorised, &pending))
+	//and finalised once
+	assert.EqualValues(t, "record not found", db.FinaliseChallengeRecord(context.Background(), stored, "authenticated", nil, nil).Error())
+
+	_, found, err := db.GetAuthRecordByID(context.Background(), id)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "authenticated", found.ThreeDSStatus)
+	assert.EqualValues(t, true, found.LiabilityShift)
+
+	held, err := db.GetReviewRecordByAuthID(context.Background(), id)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "high amount", held.Rules)
+}
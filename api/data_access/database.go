@@ -1,58 +1,169 @@
 package data_access
 
 import (
+	"context"
 	"github.com/jinzhu/gorm"
 	_ "github.com/mattn/go-sqlite3"
-	"log"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/config"
 	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/challenge"
+	"payment-gateway-api/api/data_access/database_model/decline"
+	"payment-gateway-api/api/data_access/database_model/dispute"
+	"payment-gateway-api/api/data_access/database_model/fee"
+	"payment-gateway-api/api/data_access/database_model/fraud"
+	"payment-gateway-api/api/data_access/database_model/journal"
+	"payment-gateway-api/api/data_access/database_model/migration"
 	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/reconciliation"
 	"payment-gateway-api/api/data_access/database_model/reject"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"payment-gateway-api/api/data_access/database_model/subscription"
+	"payment-gateway-api/api/ledger"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
 	"strings"
 	"time"
 )
 
-type database struct {
-	Db *gorm.DB
+//SchemaVersion is the version of the schema this build migrates the db to, it is bumped whenever a table changes
+const SchemaVersion = 15
+
+//Database is the gorm backed store of the gateway, every call runs in a transaction bound to the context
+//of the caller that database/sql rolls back as soon as the context is done, so that a cancelled call never
+//leaves partial writes behind
+type Database struct {
+	Db      *gorm.DB
+	clock   clock.Clock
+	logger  *logger.Logger
+	metrics *metrics.Metrics
 }
 
-type databaseInterface interface {
-	Setup(string) error
-	InsertAuthRecord(*auth.Auth) error
-	GetAuthRecordByID(string) (bool, *auth.Auth, error)
-	Close() error
-	SoftDeleteAuthRecordByID(string) error
-	HardDeleteAuthRecordByID(string) error
-	GetOperationByAuthIDAndOperationName(string, string) (bool, operation.Operation, error)
-	DeleteOperationRecordsByAuthID(string) error
-	CheckRejectByCardNumber(string, string) (bool, error)
-	UpdateAvailableAmountByAuthID(string, float32, string) error
-}
-
-var (
-	Db databaseInterface = &database{}
-)
+//New opens the db described by the configuration and migrates the relevant tables
+func New(cfg config.DatabaseConfig, clk clock.Clock, log *logger.Logger, m *metrics.Metrics) (*Database, error) {
+	db := &Database{clock: clk, logger: log, metrics: m}
 
-//Setup opens the db and the relevant tables
-func (db *database) Setup(dbStoreFilePath string) error {
 	var err error
 	//establish connection
-	db.Db, err = gorm.Open("sqlite3", dbStoreFilePath)
+	db.Db, err = gorm.Open(cfg.Driver, cfg.DSN)
 	if err != nil {
-		log.Println(err.Error())
-		return err
+		log.Error("unable to open the database", logger.String("driver", cfg.Driver), logger.Err(err))
+		return nil, err
 	}
 
+	//gorm reports its errors through the gateway logger so that they are redacted as well
+	db.Db.SetLogger(gormLogger{logger: log})
+
 	//migrate struct definition into tables
-	db.Db = db.Db.AutoMigrate(&auth.Auth{}, &operation.Operation{}, &reject.Reject{})
+	db.Db = db.Db.AutoMigrate(&auth.Auth{}, &operation.Operation{}, &reject.Reject{},
+		&subscription.Subscription{}, &subscription.Charge{}, &fraud.Rule{}, &fraud.BinCountry{}, &decline.Decline{},
+		&review.Review{}, &challenge.Challenge{}, &settlement.Batch{}, &settlement.Total{},
+		&reconciliation.Reconciliation{}, &reconciliation.Item{}, &journal.Entry{}, &journal.Posting{},
+		&fee.Schedule{}, &fee.Rate{}, &dispute.Dispute{}, &dispute.Evidence{}, &dispute.Event{}, &migration.Migration{})
 	if db.Db.Error != nil {
-		return db.Db.Error
+		err = db.Db.Error
+		db.Db.Close()
+		return nil, err
 	}
-	return nil
+
+	//the operations and declines of a period are read by the exports and the reports, the id of the gorm model is the
+	//only field it indexes
+	if err := db.Db.Model(&operation.Operation{}).AddIndex("idx_operations_created_at", "created_at").Error; err != nil {
+		log.Error("unable to index the operations", logger.Err(err))
+		db.Db.Close()
+		return nil, err
+	}
+	if err := db.Db.Model(&decline.Decline{}).AddIndex("idx_declines_created_at", "created_at").Error; err != nil {
+		log.Error("unable to index the declines", logger.Err(err))
+		db.Db.Close()
+		return nil, err
+	}
+
+	if err := db.fillCardDigits(); err != nil {
+		db.Db.Close()
+		return nil, err
+	}
+
+	if err := db.eraseSubscriptionCards(); err != nil {
+		db.Db.Close()
+		return nil, err
+	}
+
+	if err := db.fillChargeStates(); err != nil {
+		db.Db.Close()
+		return nil, err
+	}
+
+	if err := db.openLedger(); err != nil {
+		db.Db.Close()
+		return nil, err
+	}
+
+	//record the schema version so that readiness can tell whether the db matches this build
+	applied := migration.Migration{Version: SchemaVersion, AppliedAt: clk.Now().UTC()}
+	if err := db.Db.Where(migration.Migration{Version: SchemaVersion}).FirstOrCreate(&applied).Error; err != nil {
+		log.Error("unable to record the schema version", logger.Err(err))
+		db.Db.Close()
+		return nil, err
+	}
+
+	//timestamps set by gorm are taken from the same clock as the rest of the gateway, in UTC as the range queries expect
+	db.Db.SetNowFuncOverride(func() time.Time {
+		return db.clock.Now().UTC()
+	})
+	return db, nil
+}
+
+//Ping checks that the db can still be reached
+func (db *Database) Ping(ctx context.Context) (err error) {
+	defer db.observe("Ping", time.Now(), &err)
+
+	return db.Db.DB().PingContext(ctx)
+}
+
+//AppliedSchemaVersion returns the latest schema version the db has been migrated to
+func (db *Database) AppliedSchemaVersion(ctx context.Context) (_ int, err error) {
+	defer db.observe("AppliedSchemaVersion", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "AppliedSchemaVersion"), logger.Err(err))
+		return 0, err
+	}
+
+	var latest migration.Migration
+	if err := tx.Order("version DESC").First(&latest).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "AppliedSchemaVersion"), logger.Err(err))
+		tx.Rollback()
+		return 0, err
+	}
+
+	return latest.Version, tx.Commit().Error
+}
+
+//observe records the latency and the outcome of a call, it is deferred with the address of the error the call returns
+func (db *Database) observe(call string, start time.Time, err *error) {
+	outcome := "ok"
+	if gorm.IsRecordNotFoundError(*err) {
+		outcome = "not_found"
+	} else if *err != nil {
+		outcome = metrics.OutcomeError
+	}
+	db.metrics.ObserveQuery(call, outcome, time.Since(start))
 }
 
 //InsertAuthRecord inserts an entry into the auths table
-func (db *database) InsertAuthRecord(data *auth.Auth) error {
-	tx := db.Db.Begin()
+func (db *Database) InsertAuthRecord(ctx context.Context, data *auth.Auth) (err error) {
+	defer db.observe("InsertAuthRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -60,18 +171,18 @@ func (db *database) InsertAuthRecord(data *auth.Auth) error {
 	}()
 
 	if err := tx.Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "InsertAuthRecord"), logger.Err(err))
 		return err
 	}
 
 	if err := tx.Create(data).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "InsertAuthRecord"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
 
-	if err := insertOperation("authorisation", data, tx); err != nil {
-		log.Println(err.Error())
+	if err := db.insertOperation("authorisation", data, data.AuthorisedAmount, 0, tx); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertAuthRecord"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
@@ -79,21 +190,31 @@ func (db *database) InsertAuthRecord(data *auth.Auth) error {
 	return tx.Commit().Error
 }
 
-func insertOperation(name string, data *auth.Auth, tx *gorm.DB) error {
+//insertOperation records the operation processing the amount on the authorisation, with the available amount it leaves
+//and the fee the merchant is charged for it
+func (db *Database) insertOperation(name string, data *auth.Auth, processed float32, fee float32, tx *gorm.DB) error {
 	if err := tx.Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "insertOperation"), logger.Err(err))
 		return err
 	}
 
 	op := &operation.Operation{
-		AuthID:   data.ID,
-		Name:     name,
-		Amount:   data.AvailableAmount,
-		Currency: data.Currency,
+		AuthID:          data.ID,
+		Name:            name,
+		Amount:          data.AvailableAmount,
+		ProcessedAmount: processed,
+		Currency:        data.Currency,
+		Fee:             fee,
 	}
 
 	if err := tx.Create(op).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "insertOperation"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := db.postEntry(name, data, op.ID, ledger.MinorUnits(processed), ledger.MinorUnits(fee), op.CreatedAt, tx); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "insertOperation"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
@@ -102,27 +223,37 @@ func insertOperation(name string, data *auth.Auth, tx *gorm.DB) error {
 }
 
 //Close closes the connection to the db
-func (db *database) Close() error {
+func (db *Database) Close() error {
 	return db.Db.Close()
 }
 
 //GetAuthRecordByID fetches an auth record given its id
-func (db *database) GetAuthRecordByID(id string) (bool, *auth.Auth, error) {
-	tx := db.Db.Begin()
+func (db *Database) GetAuthRecordByID(ctx context.Context, id string) (_ bool, _ *auth.Auth, err error) {
+	defer db.observe("GetAuthRecordByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
 
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetAuthRecordByID"), logger.Err(err))
+		return false, nil, err
+	}
+
 	var record auth.Auth
 	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "GetAuthRecordByID"), logger.Err(err))
 		tx.Rollback()
 		return false, nil, err
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetAuthRecordByID"), logger.Err(err))
+		return false, nil, err
+	}
 
 	//if the auth record has been soft deleted, return empty struct
 	var empty time.Time
@@ -134,25 +265,39 @@ func (db *database) GetAuthRecordByID(id string) (bool, *auth.Auth, error) {
 }
 
 //SoftDeleteAuthRecordByID initialises the deleteAt auth's variable
-func (db *database) SoftDeleteAuthRecordByID(id string) error {
-	tx := db.Db.Begin()
+func (db *Database) SoftDeleteAuthRecordByID(ctx context.Context, id string) (err error) {
+	defer db.observe("SoftDeleteAuthRecordByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
 
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SoftDeleteAuthRecordByID"), logger.Err(err))
+		return err
+	}
+
 	var record auth.Auth
 	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "SoftDeleteAuthRecordByID"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
 
-	record.DeletedAt = time.Now()
+	record.DeletedAt = db.clock.Now().UTC()
 
 	if err := tx.Save(&record).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "SoftDeleteAuthRecordByID"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	//the amount still held on the card is released
+	if err := db.postEntry("void", &record, 0, ledger.MinorUnits(record.AvailableAmount), 0, record.DeletedAt, tx); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SoftDeleteAuthRecordByID"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
@@ -160,23 +305,30 @@ func (db *database) SoftDeleteAuthRecordByID(id string) error {
 }
 
 //HardDeleteAuthRecordByID removes the auth record given its id
-func (db *database) HardDeleteAuthRecordByID(id string) error {
-	tx := db.Db.Begin()
+func (db *Database) HardDeleteAuthRecordByID(ctx context.Context, id string) (err error) {
+	defer db.observe("HardDeleteAuthRecordByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
 
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "HardDeleteAuthRecordByID"), logger.Err(err))
+		return err
+	}
+
 	var record auth.Auth
 	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "HardDeleteAuthRecordByID"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
 
 	if err := tx.Delete(&record).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "HardDeleteAuthRecordByID"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
@@ -184,17 +336,24 @@ func (db *database) HardDeleteAuthRecordByID(id string) error {
 }
 
 //DeleteOperationRecordsByAuthID removes all operations of a given authorisation id
-func (db *database) DeleteOperationRecordsByAuthID(id string) error {
-	tx := db.Db.Begin()
+func (db *Database) DeleteOperationRecordsByAuthID(ctx context.Context, id string) (err error) {
+	defer db.observe("DeleteOperationRecordsByAuthID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
 
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "DeleteOperationRecordsByAuthID"), logger.Err(err))
+		return err
+	}
+
 	var record operation.Operation
 	if err := tx.Where("auth_id = ?", id).Delete(&record).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "DeleteOperationRecordsByAuthID"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
@@ -203,19 +362,26 @@ func (db *database) DeleteOperationRecordsByAuthID(id string) error {
 }
 
 //GetOperationByAuthIDAndOperationName fetches the operation given the authorisation ID and the operation name to look for
-func (db *database) GetOperationByAuthIDAndOperationName(id string, opName string) (bool, operation.Operation, error) {
-	tx := db.Db.Begin()
+func (db *Database) GetOperationByAuthIDAndOperationName(ctx context.Context, id string, opName string) (_ bool, _ operation.Operation, err error) {
+	defer db.observe("GetOperationByAuthIDAndOperationName", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetOperationByAuthIDAndOperationName"), logger.Err(err))
+		return false, operation.Operation{}, err
+	}
 	var record operation.Operation
 
-	err := tx.Table("operations").Where("auth_id = ? AND name = ?", id, opName).Find(&record).Error
+	err = tx.Table("operations").Where("auth_id = ? AND name = ?", id, opName).Find(&record).Error
 
 	if err != nil && err.Error() != "record not found" {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "GetOperationByAuthIDAndOperationName"), logger.Err(err))
 		tx.Rollback()
 		return false, operation.Operation{}, err
 	}
@@ -230,19 +396,26 @@ func (db *database) GetOperationByAuthIDAndOperationName(id string, opName strin
 }
 
 //CheckRejectByCardNumber checks whether the operation with the passed card number is present in the rejects table
-func (db *database) CheckRejectByCardNumber(operation string, cardNumber string) (bool, error) {
-	tx := db.Db.Begin()
+func (db *Database) CheckRejectByCardNumber(ctx context.Context, operation string, cardNumber string) (_ bool, err error) {
+	defer db.observe("CheckRejectByCardNumber", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
 
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CheckRejectByCardNumber"), logger.Err(err))
+		return false, err
+	}
+
 	var record reject.Reject
 
-	err := tx.Where("card_number = ?", cardNumber).First(&record).Error
+	err = tx.Where("card_number = ?", cardNumber).First(&record).Error
 	if err != nil && err.Error() != "record not found" {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "CheckRejectByCardNumber"), logger.Err(err))
 		tx.Rollback()
 		return false, err
 	}
@@ -255,35 +428,327 @@ func (db *database) CheckRejectByCardNumber(operation string, cardNumber string)
 	return false, tx.Commit().Error
 }
 
-//UpdateAvailableAmountByAuthID updates the available amount of the given authorisation id record
-func (db *database) UpdateAvailableAmountByAuthID(id string, amount float32, opName string) error {
-	tx := db.Db.Begin()
+//UpdateAvailableAmountByAuthID moves the available amount of the authorisation from the one of the record as it has
+//been read to the new one, the operation is recorded with the amount it processed and the fee charged for it. The
+//update only applies while the available and charged back amounts are still the ones read and the authorisation has
+//not been voided, the operation and its fee having been computed from that state, a record changed in the meantime
+//fails with a record not found error and nothing is recorded
+func (db *Database) UpdateAvailableAmountByAuthID(ctx context.Context, read *auth.Auth, amount float32, opName string, processed, fee float32) (err error) {
+	defer db.observe("UpdateAvailableAmountByAuthID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateAvailableAmountByAuthID"), logger.Err(err))
+		return err
+	}
+
+	//the state is checked by the update itself so that two concurrent operations cannot both apply, a chargeback
+	//lowers what can be refunded without changing the available amount
+	result := tx.Model(&auth.Auth{}).
+		Where("id = ? AND available_amount = ? AND charged_back_amount = ? AND deleted_at = ?", read.ID, read.AvailableAmount, read.ChargedBackAmount, time.Time{}).
+		Update("available_amount", amount)
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateAvailableAmountByAuthID"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	var record auth.Auth
+	if err := tx.Where("id = ?", read.ID).First(&record).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateAvailableAmountByAuthID"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := db.insertOperation(opName, &record, processed, fee, tx); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateAvailableAmountByAuthID"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//GetStoredCardRecord fetches the auth record a subscription charges the card of, whether or not it has since been voided
+func (db *Database) GetStoredCardRecord(ctx context.Context, id string) (_ *auth.Auth, err error) {
+	defer db.observe("GetStoredCardRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
 
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetStoredCardRecord"), logger.Err(err))
+		return nil, err
+	}
+
 	var record auth.Auth
 	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
-		log.Println(err.Error())
+		db.logger.Error("database call failed", logger.String("call", "GetStoredCardRecord"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &record, tx.Commit().Error
+}
+
+//InsertSubscriptionRecord inserts an entry into the subscriptions table
+func (db *Database) InsertSubscriptionRecord(ctx context.Context, data *subscription.Subscription) (err error) {
+	defer db.observe("InsertSubscriptionRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertSubscriptionRecord"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertSubscriptionRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//GetSubscriptionRecordByID fetches a subscription record given its id
+func (db *Database) GetSubscriptionRecordByID(ctx context.Context, id string) (_ *subscription.Subscription, err error) {
+	defer db.observe("GetSubscriptionRecordByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetSubscriptionRecordByID"), logger.Err(err))
+		return nil, err
+	}
+
+	var record subscription.Subscription
+	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetSubscriptionRecordByID"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &record, tx.Commit().Error
+}
+
+//GetDueSubscriptionRecords fetches the active subscriptions whose next charge is due at the given time
+func (db *Database) GetDueSubscriptionRecords(ctx context.Context, dueAt time.Time) (_ []subscription.Subscription, err error) {
+	defer db.observe("GetDueSubscriptionRecords", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDueSubscriptionRecords"), logger.Err(err))
+		return nil, err
+	}
+
+	var records []subscription.Subscription
+	err = tx.Where("state = ? AND next_charge_at <= ?", "active", dueAt.UTC()).
+		Order("next_charge_at").Find(&records).Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDueSubscriptionRecords"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//InsertSubscriptionChargeRecord records a charge attempt before the card is charged, it fails if the same attempt of
+//the cycle has already been recorded
+func (db *Database) InsertSubscriptionChargeRecord(ctx context.Context, data *subscription.Charge) (err error) {
+	defer db.observe("InsertSubscriptionChargeRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertSubscriptionChargeRecord"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertSubscriptionChargeRecord"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
 
-	record.AvailableAmount = amount
+	return tx.Commit().Error
+}
+
+//GetSubscriptionChargeRecord fetches the recorded attempt of a subscription cycle
+func (db *Database) GetSubscriptionChargeRecord(ctx context.Context, id string, cycle int, attempt int) (_ *subscription.Charge, err error) {
+	defer db.observe("GetSubscriptionChargeRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetSubscriptionChargeRecord"), logger.Err(err))
+		return nil, err
+	}
+
+	var record subscription.Charge
+	err = tx.Where("subscription_id = ? AND cycle = ? AND attempt = ?", id, cycle, attempt).First(&record).Error
+	if err != nil {
+		//most attempts have not been recorded yet when they are looked up
+		if !gorm.IsRecordNotFoundError(err) {
+			db.logger.Error("database call failed", logger.String("call", "GetSubscriptionChargeRecord"), logger.Err(err))
+		}
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &record, tx.Commit().Error
+}
+
+//UpdateSubscriptionChargeRecord saves the outcome of a charge attempt as soon as the card has been charged, so that
+//the attempt can be reconciled with the subscription if the update of the subscription fails
+func (db *Database) UpdateSubscriptionChargeRecord(ctx context.Context, data *subscription.Charge) (err error) {
+	defer db.observe("UpdateSubscriptionChargeRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateSubscriptionChargeRecord"), logger.Err(err))
+		return err
+	}
 
-	if err := tx.Model(&record).Where("id = ?", id).Update("available_amount", amount).Error; err != nil {
-		log.Println(err.Error())
+	if err := tx.Save(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateSubscriptionChargeRecord"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
 
-	if err := insertOperation(opName, &record, tx); err != nil {
-		log.Println(err.Error())
+	return tx.Commit().Error
+}
+
+//UpdateSubscriptionRecord saves the subscription record and, if present, the charge attempt that changed it
+func (db *Database) UpdateSubscriptionRecord(ctx context.Context, data *subscription.Subscription, charge *subscription.Charge) (err error) {
+	defer db.observe("UpdateSubscriptionRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateSubscriptionRecord"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Save(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateSubscriptionRecord"), logger.Err(err))
 		tx.Rollback()
 		return err
 	}
 
+	if charge != nil {
+		if err := tx.Save(charge).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "UpdateSubscriptionRecord"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
 	return tx.Commit().Error
 }
+
+//eraseSubscriptionCards blanks the card details the subscriptions used to keep a copy of, the card is now read from
+//the authorisation of the subscription
+func (db *Database) eraseSubscriptionCards() error {
+	if !db.Db.Dialect().HasColumn("subscriptions", "number") {
+		return nil
+	}
+	err := db.Db.Exec("UPDATE subscriptions SET number = '', expiry_date = '' WHERE number <> '' OR expiry_date <> ''").Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "eraseSubscriptionCards"), logger.Err(err))
+	}
+	return err
+}
+
+//fillChargeStates sets the state of the charge attempts recorded before the states were kept from their outcome
+func (db *Database) fillChargeStates() error {
+	err := db.Db.Exec("UPDATE subscription_charges SET state = CASE WHEN is_success THEN 'succeeded'" +
+		" WHEN error <> '' THEN 'failed' ELSE 'claimed' END WHERE state IS NULL OR state = ''").Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "fillChargeStates"), logger.Err(err))
+	}
+	return err
+}
+
+//OpenAuthorisationTotals counts, per currency, the authorisations that have been neither voided nor refunded and the amount they still hold
+func (db *Database) OpenAuthorisationTotals(ctx context.Context) (_ []metrics.HeldAmount, err error) {
+	defer db.observe("OpenAuthorisationTotals", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "OpenAuthorisationTotals"), logger.Err(err))
+		return nil, err
+	}
+
+	var totals []metrics.HeldAmount
+	err = tx.Table("auths").
+		Select("currency, COUNT(*) AS count, SUM(available_amount) AS amount").
+		Where("deleted_at = ? AND available_amount > 0", time.Time{}).
+		Where("NOT EXISTS (SELECT 1 FROM operations WHERE operations.auth_id = auths.id AND operations.name = ? AND operations.deleted_at IS NULL)", "refund").
+		Group("currency").Order("currency").Scan(&totals).Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "OpenAuthorisationTotals"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return totals, tx.Commit().Error
+}
@@ -5,13 +5,52 @@ import "time"
 //Auth represents the table definition of the Auths table in the db
 type Auth struct {
 	ID string
+	//MerchantID is the merchant the authorisation has been made for, its captures and refunds are settled to it
+	MerchantID string `gorm:"index"`
 	//Sensitive information such as card details should be stored in compliance with PCI DSS requirement
-	Number           string
-	ExpiryDate       string
-	AuthorisedAmount float32
+	Number     string
+	ExpiryDate string
+	//CardBIN and CardLast4 are the first six and last four digits of the card number, the transactions are searched on them
+	CardBIN          string  `gorm:"column:card_bin;index"`
+	CardLast4        string  `gorm:"column:card_last4;index"`
+	AuthorisedAmount float32 `gorm:"index"`
 	AvailableAmount  float32
-	Currency         string
-	CreatedAt        time.Time
+	Currency         string    `gorm:"index"`
+	CreatedAt        time.Time `gorm:"index"`
 	UpdatedAt        time.Time
 	DeletedAt        time.Time
+	//ThreeDSStatus is authenticated when the cardholder completed a 3-D Secure challenge, the liability for
+	//fraudulent chargebacks is then shifted to the issuer
+	ThreeDSStatus  string `gorm:"column:three_ds_status"`
+	LiabilityShift bool
+	//ChargedBackAmount is the captured amount the merchant lost in disputes, it can no longer be refunded
+	ChargedBackAmount float32
+	Cardholder
+}
+
+//BeforeCreate keeps the BIN and last four digits of the card number before the authorisation is stored
+func (a *Auth) BeforeCreate() error {
+	a.CardBIN, a.CardLast4 = CardDigits(a.Number)
+	return nil
+}
+
+//CardDigits returns the first six and last four digits of a card number, the card numbers are validated beforehand
+func CardDigits(number string) (string, string) {
+	if len(number) < 10 {
+		return "", ""
+	}
+	return number[:6], number[len(number)-4:]
+}
+
+//Cardholder is the optional name and billing address given with the card, together with how the issuer matched the address
+type Cardholder struct {
+	CardholderName  string
+	BillingLine1    string
+	BillingLine2    string
+	BillingCity     string
+	BillingPostcode string
+	BillingCountry  string
+	//AVSAddress and AVSPostcode are the address verification results, not_checked when no billing address was given
+	AVSAddress  string `gorm:"column:avs_address"`
+	AVSPostcode string `gorm:"column:avs_postcode"`
 }
@@ -0,0 +1,27 @@
+package challenge
+
+import (
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"time"
+)
+
+//Challenge represents the table definition of the Challenges table in the db, there is one entry for every
+//authorisation waiting for the cardholder to authenticate before it is sent to the acquirer
+type Challenge struct {
+	AuthID     string `gorm:"column:auth_id;primary_key"`
+	MerchantID string
+	//Sensitive information such as card details should be stored in compliance with PCI DSS requirement
+	Number     string
+	ExpiryDate string
+	Amount     float32
+	Currency   string
+	State      string
+	//Rules is the comma separated list of the fraud rules holding the authorisation for review once it is authorised
+	Rules string
+	//ExpiresAt is when the cardholder can no longer complete the challenge
+	ExpiresAt   time.Time
+	CompletedAt time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	auth.Cardholder
+}
@@ -0,0 +1,17 @@
+package decline
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+//Decline represents the table definition of the Declines table in the db, there is one entry for every
+//authorisation refused by the acquirer or by the fraud rules
+type Decline struct {
+	gorm.Model
+	MerchantID string `gorm:"column:merchant_id;index"`
+	//Sensitive information such as card details should be stored in compliance with PCI DSS requirement
+	Number   string
+	Amount   float32
+	Currency string
+	Reason   string
+}
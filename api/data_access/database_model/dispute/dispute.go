@@ -0,0 +1,64 @@
+package dispute
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	//ErrInProgress is returned when a dispute is inserted for an authorisation that already has one in progress
+	ErrInProgress = errors.New("a dispute is already in progress for the authorisation")
+	//ErrTransactionChanged is returned when the authorisation of a lost dispute changes while it is charged back
+	ErrTransactionChanged = errors.New("the authorisation of the dispute has been changed meanwhile")
+)
+
+//Dispute represents the table definition of the Disputes table in the db, there is one entry for every chargeback
+//the acquirer notified against a captured authorisation
+type Dispute struct {
+	ID         string
+	AuthID     string `gorm:"column:auth_id;index"`
+	MerchantID string
+	State      string
+	Reason     string
+	Amount     float32
+	Currency   string
+	//DueAt is when the dispute is lost if the merchant has not submitted its evidence
+	DueAt      time.Time
+	ResolvedAt time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+//Evidence represents the table definition of the Dispute Evidence table in the db, the documents themselves are
+//stored on disk under their path
+type Evidence struct {
+	ID          uint   `gorm:"primary_key"`
+	DisputeID   string `gorm:"index"`
+	Name        string
+	ContentType string
+	Size        int64
+	Path        string
+	//SHA256 is the digest of the document, hex encoded
+	SHA256    string `gorm:"column:sha256"`
+	CreatedAt time.Time
+}
+
+//TableName overrides the default table name of the dispute evidence
+func (Evidence) TableName() string {
+	return "dispute_evidence"
+}
+
+//Event represents the table definition of the Dispute Events table in the db, there is one entry for every change
+//of state of a dispute and every evidence attached to it
+type Event struct {
+	ID        uint   `gorm:"primary_key"`
+	DisputeID string `gorm:"index"`
+	Type      string
+	Detail    string
+	CreatedAt time.Time
+}
+
+//TableName overrides the default table name of the dispute events
+func (Event) TableName() string {
+	return "dispute_events"
+}
@@ -0,0 +1,36 @@
+package fee
+
+import "time"
+
+//Schedule represents the table definition of the Fee Schedules table in the db, there is one entry for every
+//merchant charged for processing its payments
+type Schedule struct {
+	MerchantID string `gorm:"primary_key"`
+	//RefundPolicy is how the refunds are charged, retain, return or charge
+	RefundPolicy string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+//TableName overrides the default table name of the fee schedules
+func (Schedule) TableName() string {
+	return "fee_schedules"
+}
+
+//Rate represents the table definition of the Fee Rates table in the db, a rate applies to the payments of a card
+//brand and of a currency, an empty brand or currency standing for any
+type Rate struct {
+	ID         uint   `gorm:"primary_key"`
+	MerchantID string `gorm:"index"`
+	Brand      string
+	Currency   string
+	//Percentage of the amount captured and Fixed amount charged for every capture, RefundFixed for every refund
+	Percentage  float64
+	Fixed       float64
+	RefundFixed float64
+}
+
+//TableName overrides the default table name of the fee rates
+func (Rate) TableName() string {
+	return "fee_rates"
+}
@@ -0,0 +1,36 @@
+package fraud
+
+import (
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+//Rule represents the table definition of the Fraud Rules table in the db
+type Rule struct {
+	gorm.Model
+	Name      string
+	Type      string
+	Action    string
+	Threshold float32
+	//Currency restricts the amount rules to the authorisations of a currency
+	Currency string
+	//Window is how far back in the history of the card the velocity and decline rules look
+	Window time.Duration
+	//Countries is the comma separated list of the issuing countries blocked by a bin country rule
+	Countries string
+	Enabled   bool
+	//DryRun rules are evaluated and reported but never change the decision
+	DryRun bool
+}
+
+//TableName overrides the default table name of the fraud rules
+func (Rule) TableName() string {
+	return "fraud_rules"
+}
+
+//BinCountry represents the table definition of the Bin Countries table in the db,
+//it maps the leading digits of card numbers to the country of their issuer
+type BinCountry struct {
+	Bin     string `gorm:"primary_key"`
+	Country string
+}
@@ -0,0 +1,39 @@
+package journal
+
+import "time"
+
+//Entry represents the table definition of the Journal Entries table in the db, there is one entry for every
+//operation moving funds. Entries are never updated nor deleted, a correction is recorded as a new entry
+type Entry struct {
+	ID     uint   `gorm:"primary_key"`
+	AuthID string `gorm:"column:auth_id;index"`
+	//OperationID is the operation the entry records, 0 for the voids as they are not recorded as operations
+	OperationID uint
+	MerchantID  string
+	Name        string
+	Currency    string
+	CreatedAt   time.Time
+}
+
+//TableName overrides the default table name of the journal entries
+func (Entry) TableName() string {
+	return "journal_entries"
+}
+
+//Posting represents the table definition of the Journal Postings table in the db, the postings of an entry add
+//up to zero. The authorisation, merchant and currency of the entry are repeated so that balances are summed from this table alone
+type Posting struct {
+	ID      uint `gorm:"primary_key"`
+	EntryID uint `gorm:"index"`
+	Account string
+	//Amount is in hundredths of the currency, positive for a debit and negative for a credit
+	Amount     int64
+	AuthID     string `gorm:"column:auth_id;index"`
+	MerchantID string
+	Currency   string
+}
+
+//TableName overrides the default table name of the journal postings
+func (Posting) TableName() string {
+	return "journal_postings"
+}
@@ -0,0 +1,10 @@
+package migration
+
+import "time"
+
+//Migration represents the table definition of the Migrations table in the db, a row is stored for every
+//schema version the db has been migrated to
+type Migration struct {
+	Version   int `gorm:"primary_key;auto_increment:false"`
+	AppliedAt time.Time
+}
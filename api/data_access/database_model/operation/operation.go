@@ -7,8 +7,19 @@ import (
 //Operation represents the table definition of the Operations table in the db
 type Operation struct {
 	gorm.Model
-	AuthID   string `gorm:"column:auth_id"`
-	Name     string
-	Amount   float32
-	Currency string
+	//the operations of an authorisation are looked up by name when searching the transactions
+	AuthID string `gorm:"column:auth_id;index:idx_operations_auth_id_name"`
+	Name   string `gorm:"index:idx_operations_auth_id_name"`
+	//Amount is the available amount of the authorisation once the operation has been applied,
+	//ProcessedAmount is the amount authorised, captured or refunded by the operation itself
+	Amount          float32
+	ProcessedAmount float32
+	Currency        string
+	//Fee is what the merchant is charged for the capture or refund, negative when a refund returns part of the capture fee
+	Fee float32
+	//BatchID is the settlement batch the capture or refund has been settled in, empty until then
+	BatchID string `gorm:"column:batch_id"`
+	//Reviewer and Reason are set on the operations recording the decision taken on an authorisation held for review
+	Reviewer string
+	Reason   string
 }
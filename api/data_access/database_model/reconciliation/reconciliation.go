@@ -0,0 +1,38 @@
+package reconciliation
+
+import "time"
+
+//Reconciliation represents the table definition of the Reconciliations table in the db, there is one entry for every
+//acquirer settlement report imported against a settlement batch
+type Reconciliation struct {
+	ID         string
+	BatchID    string `gorm:"index"`
+	Matched    int
+	Missing    int
+	Unexpected int
+	Mismatched int
+	CreatedAt  time.Time
+}
+
+//Item represents the table definition of the Reconciliation Items table in the db, there is one entry for every
+//line of the report and for every operation of the batch the report is missing
+type Item struct {
+	ID               uint   `gorm:"primary_key"`
+	ReconciliationID string `gorm:"index"`
+	Status           string
+	//Line is the line of the report, 0 for the operations missing from it
+	Line int
+	//Reference is the authorisation the operation or the line refers to
+	Reference   string
+	Type        string
+	OperationID uint
+	//ExpectedAmount is the amount of the operation and ReportedAmount the amount of the line
+	ExpectedAmount float64
+	ReportedAmount float64
+	Currency       string
+}
+
+//TableName overrides the default table name of the reconciliation items
+func (Item) TableName() string {
+	return "reconciliation_items"
+}
@@ -0,0 +1,40 @@
+package report
+
+import "time"
+
+//Filter selects the activity a report is made of, the period includes From and excludes To. The zero value of the
+//merchant and the currency does not filter on them
+type Filter struct {
+	From       time.Time
+	To         time.Time
+	MerchantID string
+	//Scoped keeps the activity of MerchantID even when it is empty, a merchant only reports on its own activity
+	Scoped   bool
+	Currency string
+	//Bucket is day, week or month, the activity is summed by bucket
+	Bucket string
+}
+
+//Summary is the activity of the period of a report summed by bucket, the buckets are the dates they start on
+type Summary struct {
+	Operations    []Volume
+	Declines      []Volume
+	CaptureDelays []CaptureDelay
+}
+
+//Volume is the number and amount of the operations of a name, or of the declines of a reason, made in a currency
+//over a bucket
+type Volume struct {
+	Bucket   string
+	Name     string
+	Currency string
+	Count    int
+	Amount   float64
+}
+
+//CaptureDelay is the number of authorisations first captured over a bucket and the average time it took, in seconds
+type CaptureDelay struct {
+	Bucket  string
+	Count   int
+	Seconds float64
+}
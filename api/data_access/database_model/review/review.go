@@ -0,0 +1,21 @@
+package review
+
+import "time"
+
+//Review represents the table definition of the Reviews table in the db, there is one entry for every
+//authorisation the fraud rules have held for a decision by an analyst
+type Review struct {
+	AuthID string `gorm:"column:auth_id;primary_key"`
+	State  string
+	//Rules is the comma separated list of the fraud rules that held the authorisation
+	Rules    string
+	Amount   float32
+	Currency string
+	//DueAt is when the authorisation is declined if it has not been reviewed
+	DueAt      time.Time
+	Reviewer   string
+	Reason     string
+	ReviewedAt time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,40 @@
+package settlement
+
+import "time"
+
+//Batch represents the table definition of the Settlement Batches table in the db, a batch groups the captures
+//and refunds made before its cut-off that have not been settled yet
+type Batch struct {
+	ID     string
+	CutOff time.Time `gorm:"unique_index"`
+	//State is closed until the settlement file has been submitted to the acquirer
+	State       string
+	Operations  int
+	SubmittedAt time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+//TableName overrides the default table name of the settlement batches
+func (Batch) TableName() string {
+	return "settlement_batches"
+}
+
+//Total represents the table definition of the Settlement Totals table in the db, there is one entry for every
+//merchant and currency settled in a batch
+type Total struct {
+	ID             uint `gorm:"primary_key"`
+	BatchID        string
+	MerchantID     string
+	Currency       string
+	Captures       int
+	CapturedAmount float64
+	Refunds        int
+	RefundedAmount float64
+	Fees           float64
+}
+
+//TableName overrides the default table name of the settlement totals
+func (Total) TableName() string {
+	return "settlement_totals"
+}
@@ -0,0 +1,50 @@
+package subscription
+
+import (
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+//Subscription represents the table definition of the Subscriptions table in the db
+type Subscription struct {
+	ID string
+	//AuthID is the authorisation the stored card has been taken from
+	AuthID string `gorm:"column:auth_id"`
+	//MerchantID is the merchant of the authorisation, the charges are authorised for it
+	MerchantID string
+	//the card is read from the authorisation at every charge rather than copied here
+	Amount     float32
+	Currency   string
+	Interval   string
+	AnchorDate time.Time
+	//MaxCycles is the number of successful charges after which the subscription completes, 0 means no limit
+	MaxCycles       int
+	CompletedCycles int
+	//CurrentCycle is the position in the schedule of the next charge, the anchor date being cycle 0
+	CurrentCycle   int
+	FailedAttempts int
+	NextChargeAt   time.Time
+	State          string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+//Charge represents the table definition of the Subscription Charges table in the db,
+//there is one entry for every attempt of charging a subscription. The entry is inserted before the card is charged
+//and its outcome saved afterwards, the subscription, cycle and attempt are unique so that an attempt is made once
+type Charge struct {
+	gorm.Model
+	SubscriptionID string `gorm:"unique_index:idx_subscription_charges_attempt"`
+	Cycle          int    `gorm:"unique_index:idx_subscription_charges_attempt"`
+	Attempt        int    `gorm:"unique_index:idx_subscription_charges_attempt"`
+	AuthID         string `gorm:"column:auth_id"`
+	//State is claimed until the outcome of the attempt is saved, then succeeded, failed or pending_review
+	State     string
+	IsSuccess bool
+	Error     string
+}
+
+//TableName overrides the default table name of the subscription charges
+func (Charge) TableName() string {
+	return "subscription_charges"
+}
@@ -0,0 +1,64 @@
+package transaction
+
+import (
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"time"
+)
+
+//Transaction is an authorisation as returned by the transaction search, with its state and the amounts of its
+//operations. It is read from the auths and operations tables, there is no table of its own
+type Transaction struct {
+	auth.Auth
+	State          string
+	CapturedAmount float32
+	RefundedAmount float32
+	Fees           float32
+}
+
+//Filter selects the transactions to search for, the zero value of a field does not filter on it. The created at
+//range includes From and excludes To
+type Filter struct {
+	From       time.Time
+	To         time.Time
+	State      string
+	Currency   string
+	MinAmount  float32
+	MaxAmount  float32
+	CardBIN    string
+	CardLast4  string
+	MerchantID string
+	//Scoped keeps the transactions of MerchantID even when it is empty, a merchant only searches its own transactions
+	Scoped    bool
+	Operation string
+	//Sort is created_at or amount, ties are broken on the id so that the order is stable
+	Sort       string
+	Descending bool
+	//After is the last transaction of the previous page, the search starts right after it when its id is set
+	After Cursor
+	Limit int
+}
+
+//Cursor is the position of a transaction in the order of the search
+type Cursor struct {
+	ID        string
+	CreatedAt time.Time
+	Amount    float32
+}
+
+//Entry is an operation of a transaction as exported for reporting, the voids are read from the voided authorisations.
+//Only the first six and last four digits of the card number are read, along with its length
+type Entry struct {
+	AuthID           string
+	MerchantID       string
+	CardBIN          string `gorm:"column:card_bin"`
+	CardLast4        string `gorm:"column:card_last4"`
+	CardLength       int
+	AuthorisedAmount float32
+	Currency         string
+	AuthCreatedAt    time.Time
+	State            string
+	Name             string
+	ProcessedAmount  float32
+	Fee              float32
+	CreatedAt        time.Time
+}
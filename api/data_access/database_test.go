@@ -1,59 +1,82 @@
 package data_access
 
 import (
+	"context"
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/config"
 	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/migration"
+	"payment-gateway-api/api/data_access/database_model/subscription"
+	"payment-gateway-api/api/ledger"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
 	"testing"
 	"time"
 )
 
-func InitTestDb(t *testing.T) {
-	err := Db.Setup("./test_db_store/test_gateway.db")
+var (
+	now = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+)
+
+//newTestDb opens a private copy of the test db so that tests can run in parallel
+func newTestDb(t *testing.T) (*Database, func()) {
+	dir, err := ioutil.TempDir("", "gateway")
 	assert.Nil(t, err)
-}
 
-func cleanupDB(id string, t *testing.T) {
-	err := Db.HardDeleteAuthRecordByID(id)
+	seed, err := ioutil.ReadFile("./test_db_store/test_gateway.db")
 	assert.Nil(t, err)
+	dsn := filepath.Join(dir, "test_gateway.db")
+	assert.Nil(t, ioutil.WriteFile(dsn, seed, 0600))
 
-	err = Db.DeleteOperationRecordsByAuthID(id)
+	db, err := New(config.DatabaseConfig{Driver: "sqlite3", DSN: dsn}, clock.NewFake(now), logger.Discard(), metrics.New())
 	assert.Nil(t, err)
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
 }
 
 func TestDatabase_CreateAuthRecord_Integration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	expectedRecord := auth.Auth{
 		ID:               "NewCode",
 		Number:           "123456789123456",
-		ExpiryDate:       "12-2999",
+		ExpiryDate:       "12-2021",
 		AuthorisedAmount: 10,
 		AvailableAmount:  10,
 		Currency:         "LKR",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		DeletedAt:        time.Time{},
 	}
 
 	//check that there are no operations saved
-	isPresent, _, err := Db.GetOperationByAuthIDAndOperationName(expectedRecord.ID, "authorisation")
+	isPresent, _, err := db.GetOperationByAuthIDAndOperationName(context.Background(), expectedRecord.ID, "authorisation")
 	assert.Nil(t, err)
 	assert.EqualValues(t, false, isPresent)
 
-	err = Db.InsertAuthRecord(&expectedRecord)
+	err = db.InsertAuthRecord(context.Background(), &expectedRecord)
 
 	//check that the authorisation operation has been saved
-	isPresent, _, err = Db.GetOperationByAuthIDAndOperationName(expectedRecord.ID, "authorisation")
+	isPresent, _, err = db.GetOperationByAuthIDAndOperationName(context.Background(), expectedRecord.ID, "authorisation")
 	assert.Nil(t, err)
 	assert.EqualValues(t, true, isPresent)
 
 	assert.Nil(t, err)
 
-	_, actualRecord, err := Db.GetAuthRecordByID(expectedRecord.ID)
+	_, actualRecord, err := db.GetAuthRecordByID(context.Background(), expectedRecord.ID)
 
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedRecord.ID, actualRecord.ID)
@@ -63,56 +86,62 @@ func TestDatabase_CreateAuthRecord_Integration(t *testing.T) {
 	assert.EqualValues(t, expectedRecord.AuthorisedAmount, actualRecord.AuthorisedAmount)
 	assert.EqualValues(t, expectedRecord.AvailableAmount, actualRecord.AvailableAmount)
 
-	cleanupDB(expectedRecord.ID, t)
 }
 
 func TestDatabase_SoftDeleteAuthRecordByID_Integration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	expectedRecord := auth.Auth{
 		ID:               "NewCode",
 		Number:           "123456789123456",
-		ExpiryDate:       "12-2999",
+		ExpiryDate:       "12-2021",
 		AuthorisedAmount: 10,
 		AvailableAmount:  10,
 		Currency:         "LKR",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		DeletedAt:        time.Time{},
 	}
 
-	err := Db.InsertAuthRecord(&expectedRecord)
+	err := db.InsertAuthRecord(context.Background(), &expectedRecord)
 	assert.Nil(t, err)
 
-	err = Db.SoftDeleteAuthRecordByID(expectedRecord.ID)
+	err = db.SoftDeleteAuthRecordByID(context.Background(), expectedRecord.ID)
 	assert.Nil(t, err)
 
-	_, actualRecord, err := Db.GetAuthRecordByID(expectedRecord.ID)
+	_, actualRecord, err := db.GetAuthRecordByID(context.Background(), expectedRecord.ID)
 	assert.Nil(t, err)
 	assert.EqualValues(t, &auth.Auth{}, actualRecord)
 
-	cleanupDB(expectedRecord.ID, t)
+	//the deletion time is taken from the injected clock
+	var deletedRecord auth.Auth
+	err = db.Db.Where("id = ?", expectedRecord.ID).First(&deletedRecord).Error
+	assert.Nil(t, err)
+	assert.True(t, now.Equal(deletedRecord.DeletedAt))
+
 }
 
 func TestDatabase_CheckRejectByCardNumber(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	rejectedCardNumber := "4000000000000119"
 	nonRejectedCardNumber := "123"
 
-	isPresent, err := Db.CheckRejectByCardNumber("authorisation", nonRejectedCardNumber)
+	isPresent, err := db.CheckRejectByCardNumber(context.Background(), "authorisation", nonRejectedCardNumber)
 	assert.Nil(t, err)
 	assert.EqualValues(t, false, isPresent)
 
-	isPresent, err = Db.CheckRejectByCardNumber("authorisation", rejectedCardNumber)
+	isPresent, err = db.CheckRejectByCardNumber(context.Background(), "authorisation", rejectedCardNumber)
 	assert.Nil(t, err)
 	assert.EqualValues(t, true, isPresent)
 }
@@ -121,28 +150,29 @@ func TestDatabase_UpdateAvailableAmountByAuthID(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	expectedRecord := &auth.Auth{
 		ID:               "NewCode",
 		Number:           "123456789123456",
-		ExpiryDate:       "12-2999",
+		ExpiryDate:       "12-2021",
 		AuthorisedAmount: 10,
 		AvailableAmount:  5,
 		Currency:         "LKR",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		DeletedAt:        time.Time{},
 	}
 
-	err := Db.InsertAuthRecord(expectedRecord)
+	err := db.InsertAuthRecord(context.Background(), expectedRecord)
 	assert.Nil(t, err)
 
-	err = Db.UpdateAvailableAmountByAuthID(expectedRecord.ID, expectedRecord.AvailableAmount, "capture")
+	err = db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: expectedRecord.ID, AvailableAmount: expectedRecord.AvailableAmount}, expectedRecord.AvailableAmount, "capture", 0, 0)
 	assert.Nil(t, err)
 
-	_, actualRecord, err := Db.GetAuthRecordByID(expectedRecord.ID)
+	_, actualRecord, err := db.GetAuthRecordByID(context.Background(), expectedRecord.ID)
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedRecord.ID, actualRecord.ID)
 	assert.EqualValues(t, expectedRecord.Number, actualRecord.Number)
@@ -151,125 +181,472 @@ func TestDatabase_UpdateAvailableAmountByAuthID(t *testing.T) {
 	assert.EqualValues(t, expectedRecord.AuthorisedAmount, actualRecord.AuthorisedAmount)
 	assert.EqualValues(t, expectedRecord.AvailableAmount, actualRecord.AvailableAmount)
 
-	cleanupDB(expectedRecord.ID, t)
+}
+
+func TestDatabase_UpdateAvailableAmountByAuthID_CancelledMidway(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := &auth.Auth{
+		ID:               "NewCode",
+		Number:           "123456789123456",
+		ExpiryDate:       "12-2021",
+		AuthorisedAmount: 10,
+		AvailableAmount:  10,
+		Currency:         "LKR",
+	}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), record))
+
+	//the client goes away once the available amount has been updated but before the operation is recorded
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db.Db.Callback().Update().After("gorm:update").Register("test:cancel", func(*gorm.Scope) {
+		cancel()
+	})
+
+	err := db.UpdateAvailableAmountByAuthID(ctx, &auth.Auth{ID: record.ID, AvailableAmount: 10}, 4, "capture", 6, 0)
+	assert.NotNil(t, err)
+
+	_, actualRecord, err := db.GetAuthRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10, actualRecord.AvailableAmount)
+
+	isPresent, _, err := db.GetOperationByAuthIDAndOperationName(context.Background(), record.ID, "capture")
+	assert.Nil(t, err)
+	assert.False(t, isPresent)
+}
+
+func TestDatabase_UpdateAvailableAmountByAuthID_ChangedConcurrently(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := &auth.Auth{
+		ID:               "NewCode",
+		Number:           "123456789123456",
+		ExpiryDate:       "12-2021",
+		AuthorisedAmount: 10,
+		AvailableAmount:  10,
+		Currency:         "LKR",
+	}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), record))
+
+	//both captures read an available amount of 10, the second one is applied after the first
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 10}, 4, "capture", 6, 0))
+	err := db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 10}, 7, "capture", 3, 0)
+	assert.True(t, gorm.IsRecordNotFoundError(err))
+
+	_, actualRecord, err := db.GetAuthRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, actualRecord.AvailableAmount)
+
+	_, postings, err := db.ListJournalEntries(context.Background(), record.ID)
+	assert.Nil(t, err)
+	var captured int64
+	for _, posting := range postings {
+		if posting.Account == ledger.AccountMerchantReceivable {
+			captured += posting.Amount
+		}
+	}
+	assert.EqualValues(t, 600, captured)
+}
+
+func TestDatabase_UpdateAvailableAmountByAuthID_VoidedConcurrently(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := &auth.Auth{
+		ID:               "NewCode",
+		Number:           "123456789123456",
+		ExpiryDate:       "12-2021",
+		AuthorisedAmount: 10,
+		AvailableAmount:  10,
+		Currency:         "LKR",
+	}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), record))
+
+	//the capture and its fee are computed before the authorisation is voided
+	assert.Nil(t, db.SoftDeleteAuthRecordByID(context.Background(), record.ID))
+	err := db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 10}, 4, "capture", 6, 0.3)
+	assert.True(t, gorm.IsRecordNotFoundError(err))
+
+	isPresent, _, err := db.GetOperationByAuthIDAndOperationName(context.Background(), record.ID, "capture")
+	assert.Nil(t, err)
+	assert.False(t, isPresent)
+}
+
+func TestDatabase_CancelledContext(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := db.InsertAuthRecord(ctx, &auth.Auth{ID: "NewCode", Number: "123456789123456", AvailableAmount: 10, Currency: "LKR"})
+	assert.EqualValues(t, context.Canceled, err)
+
+	_, _, err = db.GetAuthRecordByID(context.Background(), "NewCode")
+	assert.True(t, gorm.IsRecordNotFoundError(err))
 }
 
 func TestDatabase_UpdateAvailableAmountByAuthID_GetAuthRecordError(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	record := &auth.Auth{
 		ID:               "NewCode",
 		Number:           "123456789123456",
-		ExpiryDate:       "12-2999",
+		ExpiryDate:       "12-2021",
 		AuthorisedAmount: 10,
 		AvailableAmount:  5,
 		Currency:         "LKR",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		DeletedAt:        time.Time{},
 	}
 
 	expectedError := "record not found"
 
-	err := Db.InsertAuthRecord(record)
+	err := db.InsertAuthRecord(context.Background(), record)
 	assert.Nil(t, err)
 
-	err = Db.UpdateAvailableAmountByAuthID("invalid_ID", 5, "capture")
+	err = db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: "invalid_ID", AvailableAmount: 5}, 5, "capture", 0, 0)
 	assert.EqualValues(t, expectedError, err.Error())
 
-	cleanupDB(record.ID, t)
 }
 
 func TestDatabase_HardDeleteAuthRecordByID_DeleteError(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	record := &auth.Auth{
 		ID:               "NewCode",
 		Number:           "123456789123456",
-		ExpiryDate:       "12-2999",
+		ExpiryDate:       "12-2021",
 		AuthorisedAmount: 10,
 		AvailableAmount:  5,
 		Currency:         "LKR",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		DeletedAt:        time.Time{},
 	}
 
 	expectedError := "record not found"
 
-	err := Db.InsertAuthRecord(record)
+	err := db.InsertAuthRecord(context.Background(), record)
 	assert.Nil(t, err)
 
-	err = Db.HardDeleteAuthRecordByID("invalid_ID")
+	err = db.HardDeleteAuthRecordByID(context.Background(), "invalid_ID")
 	assert.EqualValues(t, expectedError, err.Error())
 
-	cleanupDB(record.ID, t)
 }
 
 func TestDatabase_SoftDeleteAuthRecordByID_DeleteError(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	record := &auth.Auth{
 		ID:               "NewCode",
 		Number:           "123456789123456",
-		ExpiryDate:       "12-2999",
+		ExpiryDate:       "12-2021",
 		AuthorisedAmount: 10,
 		AvailableAmount:  5,
 		Currency:         "LKR",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		DeletedAt:        time.Time{},
 	}
 
 	expectedError := "record not found"
 
-	err := Db.InsertAuthRecord(record)
+	err := db.InsertAuthRecord(context.Background(), record)
 	assert.Nil(t, err)
 
-	err = Db.SoftDeleteAuthRecordByID("invalid_ID")
+	err = db.SoftDeleteAuthRecordByID(context.Background(), "invalid_ID")
 	assert.EqualValues(t, expectedError, err.Error())
 
-	cleanupDB(record.ID, t)
 }
 
 func TestDatabase_GetAuthRecordByID_DeleteError(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
-	InitTestDb(t)
-	defer Db.Close()
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
 
 	record := &auth.Auth{
 		ID:               "NewCode",
 		Number:           "123456789123456",
-		ExpiryDate:       "12-2999",
+		ExpiryDate:       "12-2021",
 		AuthorisedAmount: 10,
 		AvailableAmount:  5,
 		Currency:         "LKR",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		DeletedAt:        time.Time{},
 	}
 
 	expectedError := "record not found"
 
-	err := Db.InsertAuthRecord(record)
+	err := db.InsertAuthRecord(context.Background(), record)
 	assert.Nil(t, err)
 
-	_, _, err = Db.GetAuthRecordByID("invalid_ID")
+	_, _, err = db.GetAuthRecordByID(context.Background(), "invalid_ID")
 	assert.EqualValues(t, expectedError, err.Error())
 
-	cleanupDB(record.ID, t)
+}
+
+func TestDatabase_SubscriptionRecords_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	dueAt := time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC)
+	record := &subscription.Subscription{
+		ID:           "NewSubscription",
+		AuthID:       "NewCode",
+		MerchantID:   "acme",
+		Amount:       10,
+		Currency:     "LKR",
+		Interval:     "monthly",
+		AnchorDate:   dueAt,
+		NextChargeAt: dueAt,
+		State:        "active",
+	}
+
+	err := db.InsertSubscriptionRecord(context.Background(), record)
+	assert.Nil(t, err)
+
+	//not due yet the day before its next charge
+	dueRecords, err := db.GetDueSubscriptionRecords(context.Background(), dueAt.Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, countSubscription(dueRecords, record.ID))
+
+	dueRecords, err = db.GetDueSubscriptionRecords(context.Background(), dueAt)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, countSubscription(dueRecords, record.ID))
+
+	record.State = "paused"
+	err = db.UpdateSubscriptionRecord(context.Background(), record, &subscription.Charge{SubscriptionID: record.ID, Cycle: 0, Attempt: 1, IsSuccess: true})
+	assert.Nil(t, err)
+
+	actualRecord, err := db.GetSubscriptionRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "paused", actualRecord.State)
+	assert.EqualValues(t, record.AuthID, actualRecord.AuthID)
+
+	//paused subscriptions are never due
+	dueRecords, err = db.GetDueSubscriptionRecords(context.Background(), dueAt)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, countSubscription(dueRecords, record.ID))
+
+	_, err = db.GetSubscriptionRecordByID(context.Background(), "invalid_ID")
+	assert.EqualValues(t, "record not found", err.Error())
+
+}
+
+func TestDatabase_OpenAuthorisationTotals_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	records := []*auth.Auth{
+		{ID: "open-gbp-1", Number: "4929907390318794", ExpiryDate: "12-2021", AuthorisedAmount: 10, AvailableAmount: 10, Currency: "GBP"},
+		{ID: "open-gbp-2", Number: "4929907390318794", ExpiryDate: "12-2021", AuthorisedAmount: 20, AvailableAmount: 20, Currency: "GBP"},
+		{ID: "open-eur", Number: "4929907390318794", ExpiryDate: "12-2021", AuthorisedAmount: 7.5, AvailableAmount: 7.5, Currency: "EUR"},
+		{ID: "voided", Number: "4929907390318794", ExpiryDate: "12-2021", AuthorisedAmount: 50, AvailableAmount: 50, Currency: "GBP"},
+		{ID: "refunded", Number: "4929907390318794", ExpiryDate: "12-2021", AuthorisedAmount: 50, AvailableAmount: 50, Currency: "EUR"},
+		{ID: "captured", Number: "4929907390318794", ExpiryDate: "12-2021", AuthorisedAmount: 50, AvailableAmount: 50, Currency: "USD"},
+	}
+	for _, record := range records {
+		assert.Nil(t, db.InsertAuthRecord(context.Background(), record))
+	}
+
+	//partially captured authorisations still hold what is left
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: "open-gbp-2", AvailableAmount: 20}, 5, "capture", 15, 0))
+	assert.Nil(t, db.SoftDeleteAuthRecordByID(context.Background(), "voided"))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: "refunded", AvailableAmount: 50}, 0, "capture", 50, 0))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: "refunded", AvailableAmount: 0}, 50, "refund", 50, 0))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: "captured", AvailableAmount: 50}, 0, "capture", 50, 0))
+
+	totals, err := db.OpenAuthorisationTotals(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, []metrics.HeldAmount{
+		{Currency: "EUR", Count: 1, Amount: 7.5},
+		{Currency: "GBP", Count: 2, Amount: 15},
+	}, totals)
+}
+
+func TestDatabase_SubscriptionChargeRecords_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := &subscription.Subscription{ID: "ChargedSubscription", Interval: "monthly", State: "active"}
+	err := db.InsertSubscriptionRecord(context.Background(), record)
+	assert.Nil(t, err)
+
+	_, err = db.GetSubscriptionChargeRecord(context.Background(), record.ID, 2, 1)
+	assert.EqualValues(t, "record not found", err.Error())
+
+	charge := &subscription.Charge{SubscriptionID: record.ID, Cycle: 2, Attempt: 1, State: "claimed"}
+	err = db.InsertSubscriptionChargeRecord(context.Background(), charge)
+	assert.Nil(t, err)
+
+	//the same attempt of the cycle cannot be recorded twice
+	err = db.InsertSubscriptionChargeRecord(context.Background(), &subscription.Charge{SubscriptionID: record.ID, Cycle: 2, Attempt: 1})
+	assert.NotNil(t, err)
+
+	//the outcome is saved on the recorded attempt before the subscription is updated
+	charge.AuthID = "ChargeCode"
+	charge.State = "succeeded"
+	charge.IsSuccess = true
+	err = db.UpdateSubscriptionChargeRecord(context.Background(), charge)
+	assert.Nil(t, err)
+
+	recorded, err := db.GetSubscriptionChargeRecord(context.Background(), record.ID, 2, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "succeeded", recorded.State)
+	assert.EqualValues(t, "ChargeCode", recorded.AuthID)
+
+	record.CurrentCycle = 3
+	err = db.UpdateSubscriptionRecord(context.Background(), record, charge)
+	assert.Nil(t, err)
+
+	var charges []subscription.Charge
+	err = db.Db.Where("subscription_id = ?", record.ID).Find(&charges).Error
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(charges))
+	assert.EqualValues(t, "ChargeCode", charges[0].AuthID)
+	assert.EqualValues(t, true, charges[0].IsSuccess)
+}
+
+func TestDatabase_GetStoredCardRecord_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := &auth.Auth{ID: "StoredCard", Number: "4929907390318794", ExpiryDate: "12-2021", AuthorisedAmount: 10, AvailableAmount: 10, Currency: "GBP"}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), record))
+	//the card of a voided authorisation is still charged by its subscriptions
+	assert.Nil(t, db.SoftDeleteAuthRecordByID(context.Background(), record.ID))
+
+	card, err := db.GetStoredCardRecord(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "4929907390318794", card.Number)
+	assert.EqualValues(t, "12-2021", card.ExpiryDate)
+
+	_, err = db.GetStoredCardRecord(context.Background(), "invalid_ID")
+	assert.EqualValues(t, "record not found", err.Error())
+}
+
+func TestDatabase_SubscriptionMigrations_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	//the subscriptions created before the card was read from the authorisation kept a copy of it
+	if !db.Db.Dialect().HasColumn("subscriptions", "number") {
+		assert.Nil(t, db.Db.Exec("ALTER TABLE subscriptions ADD COLUMN number varchar(255)").Error)
+		assert.Nil(t, db.Db.Exec("ALTER TABLE subscriptions ADD COLUMN expiry_date varchar(255)").Error)
+	}
+	assert.Nil(t, db.InsertSubscriptionRecord(context.Background(), &subscription.Subscription{ID: "CopiedCard", State: "active"}))
+	assert.Nil(t, db.Db.Exec("UPDATE subscriptions SET number = '4929907390318794', expiry_date = '12-2021' WHERE id = 'CopiedCard'").Error)
+
+	//and their attempts had no state
+	charges := []*subscription.Charge{
+		{SubscriptionID: "CopiedCard", Cycle: 0, Attempt: 1, IsSuccess: true},
+		{SubscriptionID: "CopiedCard", Cycle: 1, Attempt: 1, Error: "card is expired"},
+		{SubscriptionID: "CopiedCard", Cycle: 1, Attempt: 2},
+	}
+	for _, charge := range charges {
+		assert.Nil(t, db.InsertSubscriptionChargeRecord(context.Background(), charge))
+	}
+
+	assert.Nil(t, db.eraseSubscriptionCards())
+	assert.Nil(t, db.fillChargeStates())
+
+	var count int
+	assert.Nil(t, db.Db.Table("subscriptions").Where("number <> '' OR expiry_date <> ''").Count(&count).Error)
+	assert.EqualValues(t, 0, count)
+
+	for i, expected := range []string{"succeeded", "failed", "claimed"} {
+		recorded, err := db.GetSubscriptionChargeRecord(context.Background(), "CopiedCard", charges[i].Cycle, charges[i].Attempt)
+		assert.Nil(t, err)
+		assert.EqualValues(t, expected, recorded.State)
+	}
+}
+
+func countSubscription(records []subscription.Subscription, id string) int {
+	count := 0
+	for _, record := range records {
+		if record.ID == id {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDatabase_AppliedSchemaVersion_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	assert.Nil(t, db.Ping(context.Background()))
+
+	version, err := db.AppliedSchemaVersion(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, SchemaVersion, version)
+
+	//a db migrated by a newer build is reported as such
+	assert.Nil(t, db.Db.Create(&migration.Migration{Version: SchemaVersion + 1, AppliedAt: now}).Error)
+	version, err = db.AppliedSchemaVersion(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, SchemaVersion+1, version)
 }
@@ -0,0 +1,273 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/dispute"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//InsertDisputeRecord inserts an entry into the disputes table together with the event opening it, ErrInProgress
+//is returned when the authorisation already has a dispute that has been neither won nor lost
+func (db *Database) InsertDisputeRecord(ctx context.Context, data *dispute.Dispute, event *dispute.Event) (err error) {
+	defer db.observe("InsertDisputeRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDisputeRecord"), logger.Err(err))
+		return err
+	}
+
+	//the check is made in the transaction of the insert so that two notifications cannot both open a dispute
+	var inProgress int
+	err = tx.Model(&dispute.Dispute{}).Where("auth_id = ? AND state NOT IN (?)", data.AuthID, []string{"won", "lost"}).Count(&inProgress).Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDisputeRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if inProgress > 0 {
+		tx.Rollback()
+		return dispute.ErrInProgress
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDisputeRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	event.DisputeID = data.ID
+	if err := tx.Create(event).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDisputeRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//GetDisputeByID fetches a dispute with its evidence and its events in the order they happened,
+//the record not found error is returned when there is none
+func (db *Database) GetDisputeByID(ctx context.Context, id string) (_ *dispute.Dispute, _ []dispute.Evidence, _ []dispute.Event, err error) {
+	defer db.observe("GetDisputeByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDisputeByID"), logger.Err(err))
+		return nil, nil, nil, err
+	}
+
+	var record dispute.Dispute
+	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, nil, err
+	}
+
+	var evidence []dispute.Evidence
+	if err := tx.Where("dispute_id = ?", id).Order("id").Find(&evidence).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDisputeByID"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, nil, err
+	}
+
+	var events []dispute.Event
+	if err := tx.Where("dispute_id = ?", id).Order("id").Find(&events).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDisputeByID"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, nil, err
+	}
+
+	return &record, evidence, events, tx.Commit().Error
+}
+
+//ListDisputes fetches the disputes, the latest first, of a state and of an authorisation when they are not empty
+func (db *Database) ListDisputes(ctx context.Context, state string, authID string) (_ []dispute.Dispute, err error) {
+	defer db.observe("ListDisputes", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListDisputes"), logger.Err(err))
+		return nil, err
+	}
+
+	query := tx.Order("created_at DESC")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	if authID != "" {
+		query = query.Where("auth_id = ?", authID)
+	}
+
+	var records []dispute.Dispute
+	if err := query.Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListDisputes"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//GetDueDisputes fetches the disputes in one of the states whose deadline has passed at the given time
+func (db *Database) GetDueDisputes(ctx context.Context, states []string, dueAt time.Time) (_ []dispute.Dispute, err error) {
+	defer db.observe("GetDueDisputes", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDueDisputes"), logger.Err(err))
+		return nil, err
+	}
+
+	var records []dispute.Dispute
+	if err := tx.Where("state IN (?) AND due_at <= ?", states, dueAt.UTC()).Order("due_at").Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDueDisputes"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//InsertDisputeEvidence inserts an entry into the dispute evidence table together with the event recording it
+func (db *Database) InsertDisputeEvidence(ctx context.Context, data *dispute.Evidence, event *dispute.Event) (err error) {
+	defer db.observe("InsertDisputeEvidence", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDisputeEvidence"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDisputeEvidence"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(event).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDisputeEvidence"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//UpdateDisputeState moves a dispute from a state to the state of the record and records the event, a lost dispute
+//is charged back to the merchant in the same transaction up to the captured amount that has been neither refunded nor
+//charged back yet. The record not found error is returned when the dispute is no longer in the state it is moved from
+//and ErrTransactionChanged when the authorisation has been changed while the chargeback was computed
+func (db *Database) UpdateDisputeState(ctx context.Context, data *dispute.Dispute, from string, event *dispute.Event, chargeBack bool) (err error) {
+	defer db.observe("UpdateDisputeState", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateDisputeState"), logger.Err(err))
+		return err
+	}
+
+	//the state is checked by the update itself so that the sweeper and a notification cannot both move the dispute
+	result := tx.Model(&dispute.Dispute{}).Where("id = ? AND state = ?", data.ID, from).Updates(map[string]interface{}{
+		"state":       data.State,
+		"due_at":      data.DueAt,
+		"resolved_at": data.ResolvedAt,
+		"updated_at":  data.UpdatedAt,
+	})
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateDisputeState"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	event.DisputeID = data.ID
+	if err := tx.Create(event).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateDisputeState"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if chargeBack {
+		var record auth.Auth
+		if err := tx.Where("id = ?", data.AuthID).First(&record).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "UpdateDisputeState"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+
+		//the amount refunded since the dispute was opened can no longer be charged back
+		amount := data.Amount
+		if captured := record.AuthorisedAmount - record.AvailableAmount - record.ChargedBackAmount; amount > captured {
+			amount = captured
+		}
+		if amount <= 0 {
+			return tx.Commit().Error
+		}
+
+		//the amounts the cap has been computed from are checked by the update itself so that a refund made meanwhile
+		//cannot take the refunded and charged back amounts above the captured one
+		result := tx.Model(&auth.Auth{}).
+			Where("id = ? AND available_amount = ? AND charged_back_amount = ?", data.AuthID, record.AvailableAmount, record.ChargedBackAmount).
+			Update("charged_back_amount", record.ChargedBackAmount+amount)
+		if err := result.Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "UpdateDisputeState"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			return dispute.ErrTransactionChanged
+		}
+		record.ChargedBackAmount += amount
+
+		if err := db.insertOperation("chargeback", &record, amount, 0, tx); err != nil {
+			db.logger.Error("database call failed", logger.String("call", "UpdateDisputeState"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,205 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/dispute"
+	"payment-gateway-api/api/ledger"
+	"testing"
+	"time"
+)
+
+func TestDatabase_Disputes_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := auth.Auth{
+		ID:               "a1c2e3f4-5b6d-4e7f-8a9b-0c1d2e3f4a01",
+		MerchantID:       "acme",
+		Number:           "4000056655665556",
+		ExpiryDate:       "12-2099",
+		AuthorisedAmount: 100,
+		AvailableAmount:  100,
+		Currency:         "GBP",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &record))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 100}, 40, "capture", 60, 0))
+
+	opened := dispute.Dispute{
+		ID:         "b2d3f4a5-6c7e-4f8a-9b0c-1d2e3f4a5b01",
+		AuthID:     record.ID,
+		MerchantID: "acme",
+		State:      "opened",
+		Reason:     "fraud",
+		Amount:     60,
+		Currency:   "GBP",
+		DueAt:      now.Add(time.Hour),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	assert.Nil(t, db.InsertDisputeRecord(context.Background(), &opened, &dispute.Event{Type: "opened", Detail: "fraud", CreatedAt: now}))
+	//an authorisation has one dispute in progress at a time
+	second := opened
+	second.ID = "b2d3f4a5-6c7e-4f8a-9b0c-1d2e3f4a5b02"
+	assert.EqualValues(t, dispute.ErrInProgress, db.InsertDisputeRecord(context.Background(), &second, &dispute.Event{Type: "opened", CreatedAt: now}))
+
+	evidence := dispute.Evidence{DisputeID: opened.ID, Name: "receipt.pdf", ContentType: "application/pdf", Size: 3, Path: opened.ID + "/1", SHA256: "digest", CreatedAt: now}
+	assert.Nil(t, db.InsertDisputeEvidence(context.Background(), &evidence, &dispute.Event{DisputeID: opened.ID, Type: "evidence_added", CreatedAt: now}))
+
+	stored, storedEvidence, events, err := db.GetDisputeByID(context.Background(), opened.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "fraud", stored.Reason)
+	assert.EqualValues(t, 1, len(storedEvidence))
+	assert.EqualValues(t, "digest", storedEvidence[0].SHA256)
+	assert.EqualValues(t, 2, len(events))
+	assert.EqualValues(t, "opened", events[0].Type)
+	assert.EqualValues(t, "evidence_added", events[1].Type)
+
+	listed, err := db.ListDisputes(context.Background(), "opened", record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(listed))
+	listed, err = db.ListDisputes(context.Background(), "won", "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, len(listed))
+
+	due, err := db.GetDueDisputes(context.Background(), []string{"opened", "evidence_required"}, now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(due))
+
+	//losing the dispute charges it back to the merchant
+	lost := *stored
+	lost.State = "lost"
+	lost.ResolvedAt = now
+	assert.Nil(t, db.UpdateDisputeState(context.Background(), &lost, "opened", &dispute.Event{Type: "lost", CreatedAt: now}, true))
+	//a dispute can only be moved from the state it is in
+	assert.EqualValues(t, "record not found", db.UpdateDisputeState(context.Background(), &lost, "opened", &dispute.Event{Type: "lost", CreatedAt: now}, true).Error())
+
+	_, charged, err := db.GetAuthRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 60, charged.ChargedBackAmount)
+	found, op, err := db.GetOperationByAuthIDAndOperationName(context.Background(), record.ID, "chargeback")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 60, op.ProcessedAmount)
+
+	balances, err := db.GetLedgerBalances(context.Background(), record.ID, "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, []ledger.Balance{
+		{Account: ledger.AccountCardholderFunds, Currency: "GBP", Amount: -10000},
+		{Account: ledger.AccountCardholderHold, Currency: "GBP", Amount: 4000},
+		{Account: ledger.AccountChargebacks, Currency: "GBP", Amount: 6000},
+		{Account: ledger.AccountMerchantReceivable, Currency: "GBP", Amount: 0},
+	}, balances)
+	_, _, events, err = db.GetDisputeByID(context.Background(), opened.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, len(events))
+}
+
+func TestDatabase_Disputes_ChargeBackAfterRefund_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := auth.Auth{
+		ID:               "a1c2e3f4-5b6d-4e7f-8a9b-0c1d2e3f4a02",
+		MerchantID:       "acme",
+		Number:           "4000056655665556",
+		ExpiryDate:       "12-2099",
+		AuthorisedAmount: 100,
+		AvailableAmount:  100,
+		Currency:         "GBP",
+	}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &record))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 100}, 40, "capture", 60, 0))
+
+	opened := dispute.Dispute{
+		ID:       "b2d3f4a5-6c7e-4f8a-9b0c-1d2e3f4a5b03",
+		AuthID:   record.ID,
+		State:    "opened",
+		Amount:   60,
+		Currency: "GBP",
+	}
+	assert.Nil(t, db.InsertDisputeRecord(context.Background(), &opened, &dispute.Event{Type: "opened", CreatedAt: now}))
+	//the merchant refunds most of the capture while the dispute is in progress
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 40}, 90, "refund", 50, 0))
+
+	lost := opened
+	lost.State = "lost"
+	assert.Nil(t, db.UpdateDisputeState(context.Background(), &lost, "opened", &dispute.Event{Type: "lost", CreatedAt: now}, true))
+
+	_, charged, err := db.GetAuthRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10, charged.ChargedBackAmount)
+	found, op, err := db.GetOperationByAuthIDAndOperationName(context.Background(), record.ID, "chargeback")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 10, op.ProcessedAmount)
+
+	//nothing is left to charge back once the capture has been refunded or charged back
+	reopened := opened
+	reopened.ID = "b2d3f4a5-6c7e-4f8a-9b0c-1d2e3f4a5b04"
+	assert.Nil(t, db.InsertDisputeRecord(context.Background(), &reopened, &dispute.Event{Type: "opened", CreatedAt: now}))
+	reopened.State = "lost"
+	assert.Nil(t, db.UpdateDisputeState(context.Background(), &reopened, "opened", &dispute.Event{Type: "lost", CreatedAt: now}, true))
+	_, charged, err = db.GetAuthRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10, charged.ChargedBackAmount)
+}
+
+func TestDatabase_Disputes_RefundDuringChargeBack_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := auth.Auth{
+		ID:               "a1c2e3f4-5b6d-4e7f-8a9b-0c1d2e3f4a03",
+		MerchantID:       "acme",
+		Number:           "4000056655665556",
+		ExpiryDate:       "12-2099",
+		AuthorisedAmount: 100,
+		AvailableAmount:  100,
+		Currency:         "GBP",
+	}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &record))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &record, 40, "capture", 60, 0))
+
+	opened := dispute.Dispute{
+		ID:       "b2d3f4a5-6c7e-4f8a-9b0c-1d2e3f4a5b05",
+		AuthID:   record.ID,
+		State:    "opened",
+		Amount:   60,
+		Currency: "GBP",
+	}
+	assert.Nil(t, db.InsertDisputeRecord(context.Background(), &opened, &dispute.Event{Type: "opened", CreatedAt: now}))
+
+	//the refund reads the authorisation before the dispute is lost and is applied after the chargeback
+	_, read, err := db.GetAuthRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	lost := opened
+	lost.State = "lost"
+	assert.Nil(t, db.UpdateDisputeState(context.Background(), &lost, "opened", &dispute.Event{Type: "lost", CreatedAt: now}, true))
+	err = db.UpdateAvailableAmountByAuthID(context.Background(), read, 100, "refund", 60, 0)
+	assert.True(t, gorm.IsRecordNotFoundError(err))
+
+	_, actual, err := db.GetAuthRecordByID(context.Background(), record.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 40, actual.AvailableAmount)
+	assert.EqualValues(t, 60, actual.ChargedBackAmount)
+	isPresent, _, err := db.GetOperationByAuthIDAndOperationName(context.Background(), record.ID, "refund")
+	assert.Nil(t, err)
+	assert.False(t, isPresent)
+}
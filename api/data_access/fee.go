@@ -0,0 +1,121 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"payment-gateway-api/api/data_access/database_model/fee"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//GetFeeSchedule fetches the fee schedule of a merchant and its rates, the record not found error is returned when there is none
+func (db *Database) GetFeeSchedule(ctx context.Context, merchantID string) (_ *fee.Schedule, _ []fee.Rate, err error) {
+	defer db.observe("GetFeeSchedule", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetFeeSchedule"), logger.Err(err))
+		return nil, nil, err
+	}
+
+	var record fee.Schedule
+	if err := tx.Where("merchant_id = ?", merchantID).First(&record).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	var rates []fee.Rate
+	if err := tx.Where("merchant_id = ?", merchantID).Order("id").Find(&rates).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetFeeSchedule"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return &record, rates, tx.Commit().Error
+}
+
+//SaveFeeSchedule creates or replaces the fee schedule of a merchant together with all its rates
+func (db *Database) SaveFeeSchedule(ctx context.Context, data *fee.Schedule, rates []fee.Rate) (err error) {
+	defer db.observe("SaveFeeSchedule", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SaveFeeSchedule"), logger.Err(err))
+		return err
+	}
+
+	var existing fee.Schedule
+	if err := tx.Where("merchant_id = ?", data.MerchantID).First(&existing).Error; err == nil {
+		data.CreatedAt = existing.CreatedAt
+	}
+	if err := tx.Save(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SaveFeeSchedule"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("merchant_id = ?", data.MerchantID).Delete(&fee.Rate{}).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SaveFeeSchedule"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	for i := range rates {
+		rates[i].ID = 0
+		rates[i].MerchantID = data.MerchantID
+		if err := tx.Create(&rates[i]).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "SaveFeeSchedule"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+//DeleteFeeSchedule removes the fee schedule of a merchant and its rates, the record not found error is returned when there is none
+func (db *Database) DeleteFeeSchedule(ctx context.Context, merchantID string) (err error) {
+	defer db.observe("DeleteFeeSchedule", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "DeleteFeeSchedule"), logger.Err(err))
+		return err
+	}
+
+	result := tx.Where("merchant_id = ?", merchantID).Delete(&fee.Schedule{})
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "DeleteFeeSchedule"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := tx.Where("merchant_id = ?", merchantID).Delete(&fee.Rate{}).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "DeleteFeeSchedule"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,43 @@
+package data_access
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/fee"
+	"testing"
+)
+
+func TestDatabase_FeeSchedules_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	_, _, err := db.GetFeeSchedule(context.Background(), "acme")
+	assert.EqualValues(t, "record not found", err.Error())
+
+	schedule := fee.Schedule{MerchantID: "acme", RefundPolicy: "retain"}
+	assert.Nil(t, db.SaveFeeSchedule(context.Background(), &schedule, []fee.Rate{
+		{Percentage: 2.9, Fixed: 0.3},
+		{Brand: "amex", Currency: "GBP", Percentage: 3.5},
+	}))
+
+	//saving the schedule again replaces all its rates
+	schedule = fee.Schedule{MerchantID: "acme", RefundPolicy: "charge"}
+	assert.Nil(t, db.SaveFeeSchedule(context.Background(), &schedule, []fee.Rate{
+		{Currency: "GBP", Percentage: 1.5, Fixed: 0.2, RefundFixed: 0.1},
+	}))
+	stored, rates, err := db.GetFeeSchedule(context.Background(), "acme")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "charge", stored.RefundPolicy)
+	assert.EqualValues(t, []fee.Rate{
+		{ID: rates[0].ID, MerchantID: "acme", Currency: "GBP", Percentage: 1.5, Fixed: 0.2, RefundFixed: 0.1},
+	}, rates)
+
+	assert.Nil(t, db.DeleteFeeSchedule(context.Background(), "acme"))
+	assert.EqualValues(t, "record not found", db.DeleteFeeSchedule(context.Background(), "acme").Error())
+	_, _, err = db.GetFeeSchedule(context.Background(), "acme")
+	assert.EqualValues(t, "record not found", err.Error())
+}
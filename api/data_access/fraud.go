@@ -0,0 +1,292 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"payment-gateway-api/api/data_access/database_model/decline"
+	"payment-gateway-api/api/data_access/database_model/fraud"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//ListFraudRules fetches all the fraud rules in the order they have been created
+func (db *Database) ListFraudRules(ctx context.Context) (_ []fraud.Rule, err error) {
+	defer db.observe("ListFraudRules", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListFraudRules"), logger.Err(err))
+		return nil, err
+	}
+
+	var records []fraud.Rule
+	if err := tx.Order("id").Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListFraudRules"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//InsertFraudRule inserts an entry into the fraud rules table
+func (db *Database) InsertFraudRule(ctx context.Context, data *fraud.Rule) (err error) {
+	defer db.observe("InsertFraudRule", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertFraudRule"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertFraudRule"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//UpdateFraudRule replaces the fields of an existing fraud rule, the record not found error is returned when there is none
+func (db *Database) UpdateFraudRule(ctx context.Context, data *fraud.Rule) (err error) {
+	defer db.observe("UpdateFraudRule", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateFraudRule"), logger.Err(err))
+		return err
+	}
+
+	var existing fraud.Rule
+	if err := tx.Where("id = ?", data.ID).First(&existing).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	data.CreatedAt = existing.CreatedAt
+	if err := tx.Save(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "UpdateFraudRule"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//DeleteFraudRule removes the fraud rule given its id, the record not found error is returned when there is none
+func (db *Database) DeleteFraudRule(ctx context.Context, id uint) (err error) {
+	defer db.observe("DeleteFraudRule", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "DeleteFraudRule"), logger.Err(err))
+		return err
+	}
+
+	result := tx.Where("id = ?", id).Delete(&fraud.Rule{})
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "DeleteFraudRule"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	return tx.Commit().Error
+}
+
+//SaveBinCountries inserts the bins into the bin countries table, replacing the country of the bins already present
+func (db *Database) SaveBinCountries(ctx context.Context, data []fraud.BinCountry) (err error) {
+	defer db.observe("SaveBinCountries", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SaveBinCountries"), logger.Err(err))
+		return err
+	}
+
+	for i := range data {
+		if err := tx.Save(&data[i]).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "SaveBinCountries"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+//GetBinCountry returns the issuing country of the longest bin the card number starts with, or an empty string if no bin matches
+func (db *Database) GetBinCountry(ctx context.Context, number string) (_ string, err error) {
+	defer db.observe("GetBinCountry", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetBinCountry"), logger.Err(err))
+		return "", err
+	}
+
+	var prefixes []string
+	for length := 6; length <= 8 && length <= len(number); length++ {
+		prefixes = append(prefixes, number[:length])
+	}
+
+	var records []fraud.BinCountry
+	if err := tx.Where("bin IN (?)", prefixes).Order("LENGTH(bin) DESC").Limit(1).Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetBinCountry"), logger.Err(err))
+		tx.Rollback()
+		return "", err
+	}
+
+	country := ""
+	if len(records) > 0 {
+		country = records[0].Country
+	}
+	return country, tx.Commit().Error
+}
+
+//CountAuthRecordsByNumberSince counts the authorisations of a card created at or after the given time
+func (db *Database) CountAuthRecordsByNumberSince(ctx context.Context, number string, since time.Time) (_ int, err error) {
+	defer db.observe("CountAuthRecordsByNumberSince", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CountAuthRecordsByNumberSince"), logger.Err(err))
+		return 0, err
+	}
+
+	var count int
+	if err := tx.Table("auths").Where("number = ? AND created_at >= ?", number, since.UTC()).Count(&count).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CountAuthRecordsByNumberSince"), logger.Err(err))
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, tx.Commit().Error
+}
+
+//SumAuthAmountByNumberSince sums the authorised amounts of a card in a currency created at or after the given time
+func (db *Database) SumAuthAmountByNumberSince(ctx context.Context, number string, currency string, since time.Time) (_ float64, err error) {
+	defer db.observe("SumAuthAmountByNumberSince", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SumAuthAmountByNumberSince"), logger.Err(err))
+		return 0, err
+	}
+
+	var total struct {
+		Amount float64
+	}
+	err = tx.Table("auths").Select("COALESCE(SUM(authorised_amount), 0) AS amount").
+		Where("number = ? AND currency = ? AND created_at >= ?", number, currency, since.UTC()).Scan(&total).Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SumAuthAmountByNumberSince"), logger.Err(err))
+		tx.Rollback()
+		return 0, err
+	}
+
+	return total.Amount, tx.Commit().Error
+}
+
+//InsertDecline inserts an entry into the declines table
+func (db *Database) InsertDecline(ctx context.Context, data *decline.Decline) (err error) {
+	defer db.observe("InsertDecline", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDecline"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertDecline"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//CountDeclinesByNumberSince counts the declined authorisations of a card created at or after the given time
+func (db *Database) CountDeclinesByNumberSince(ctx context.Context, number string, since time.Time) (_ int, err error) {
+	defer db.observe("CountDeclinesByNumberSince", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CountDeclinesByNumberSince"), logger.Err(err))
+		return 0, err
+	}
+
+	var count int
+	if err := tx.Model(&decline.Decline{}).Where("number = ? AND created_at >= ?", number, since.UTC()).Count(&count).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CountDeclinesByNumberSince"), logger.Err(err))
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, tx.Commit().Error
+}
@@ -0,0 +1,111 @@
+package data_access
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/decline"
+	"payment-gateway-api/api/data_access/database_model/fraud"
+	"testing"
+	"time"
+)
+
+func TestDatabase_FraudRules_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := fraud.Rule{Name: "card velocity", Type: "velocity_count", Action: "deny", Threshold: 5, Window: time.Hour, Enabled: true}
+	assert.Nil(t, db.InsertFraudRule(context.Background(), &record))
+
+	record.Enabled = false
+	record.Threshold = 10
+	assert.Nil(t, db.UpdateFraudRule(context.Background(), &record))
+
+	records, err := db.ListFraudRules(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(records))
+	assert.EqualValues(t, false, records[0].Enabled)
+	assert.EqualValues(t, 10, records[0].Threshold)
+	assert.EqualValues(t, time.Hour, records[0].Window)
+
+	missing := fraud.Rule{Name: "missing"}
+	missing.ID = record.ID + 1
+	assert.EqualValues(t, "record not found", db.UpdateFraudRule(context.Background(), &missing).Error())
+
+	assert.Nil(t, db.DeleteFraudRule(context.Background(), record.ID))
+	assert.EqualValues(t, "record not found", db.DeleteFraudRule(context.Background(), record.ID).Error())
+	records, err = db.ListFraudRules(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+}
+
+func TestDatabase_GetBinCountry_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	assert.Nil(t, db.SaveBinCountries(context.Background(), []fraud.BinCountry{{Bin: "492990", Country: "GB"}, {Bin: "49299073", Country: "IE"}}))
+	//saving a bin again replaces its country
+	assert.Nil(t, db.SaveBinCountries(context.Background(), []fraud.BinCountry{{Bin: "492990", Country: "FR"}}))
+
+	country, err := db.GetBinCountry(context.Background(), "4929907390318794")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "IE", country)
+
+	country, err = db.GetBinCountry(context.Background(), "4929901111111111")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "FR", country)
+
+	country, err = db.GetBinCountry(context.Background(), "5555555555554444")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "", country)
+}
+
+func TestDatabase_CardHistory_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	number := "4000056655665556"
+	for i, createdAt := range []time.Time{now.Add(-2 * time.Hour), now.Add(-30 * time.Minute), now} {
+		record := auth.Auth{
+			ID:               fmt.Sprintf("history-%d", i),
+			Number:           number,
+			ExpiryDate:       "12-2099",
+			AuthorisedAmount: 100,
+			AvailableAmount:  100,
+			Currency:         "GBP",
+			CreatedAt:        createdAt,
+			UpdatedAt:        createdAt,
+		}
+		assert.Nil(t, db.InsertAuthRecord(context.Background(), &record))
+	}
+	assert.Nil(t, db.InsertDecline(context.Background(), &decline.Decline{Number: number, Amount: 5, Currency: "GBP", Reason: "acquirer"}))
+
+	count, err := db.CountAuthRecordsByNumberSince(context.Background(), number, now.Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, count)
+
+	total, err := db.SumAuthAmountByNumberSince(context.Background(), number, "GBP", now.Add(-24*time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 300, total)
+
+	total, err = db.SumAuthAmountByNumberSince(context.Background(), number, "EUR", now.Add(-24*time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, total)
+
+	count, err = db.CountDeclinesByNumberSince(context.Background(), number, now.Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, count)
+}
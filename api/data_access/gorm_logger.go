@@ -0,0 +1,38 @@
+package data_access
+
+import (
+	"fmt"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//gormLogger writes the messages of gorm as structured lines, the queries are only
+//written at debug level and every value goes through the logger redaction
+type gormLogger struct {
+	logger *logger.Logger
+}
+
+//Print receives the values gorm logs, starting with the kind of message and its source
+func (g gormLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		g.logger.Debug(fmt.Sprint(values...))
+		return
+	}
+
+	source := logger.String("source", fmt.Sprint(values[1]))
+	switch values[0] {
+	case "sql":
+		fields := []logger.Field{source}
+		if len(values) > 4 {
+			if duration, ok := values[2].(time.Duration); ok {
+				fields = append(fields, logger.Duration("duration_ms", duration))
+			}
+			fields = append(fields, logger.String("query", fmt.Sprint(values[3])), logger.Any("vars", values[4]))
+		}
+		g.logger.Debug("gorm query", fields...)
+	case "log":
+		g.logger.Debug(fmt.Sprint(values[2:]...), source)
+	default:
+		g.logger.Error("gorm "+fmt.Sprint(values[0]), source, logger.String("error", fmt.Sprint(values[2:]...)))
+	}
+}
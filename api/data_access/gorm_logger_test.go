@@ -0,0 +1,23 @@
+package data_access
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/logger"
+	"testing"
+	"time"
+)
+
+func TestGormLogger_RedactsErrorsAndQueries(t *testing.T) {
+	t.Parallel()
+	buffer := &bytes.Buffer{}
+	gorm := gormLogger{logger: logger.New(buffer, logger.DebugLevel)}
+
+	gorm.Print("error", "database.go:42", errors.New("UNIQUE constraint failed for 4929907390318794"))
+	gorm.Print("sql", "database.go:42", 3*time.Millisecond, "INSERT INTO auths (number) VALUES (?)", []interface{}{"4929907390318794"}, int64(1))
+
+	assert.Contains(t, buffer.String(), `"error":"UNIQUE constraint failed for ****8794"`)
+	assert.Contains(t, buffer.String(), `"vars":["****8794"]`)
+	assert.NotContains(t, buffer.String(), "4929907390318794")
+}
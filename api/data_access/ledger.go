@@ -0,0 +1,297 @@
+package data_access
+
+import (
+	"context"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/journal"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/ledger"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//errUnbalancedEntry is returned when the postings of a journal entry do not add up to zero, nothing is written then
+var errUnbalancedEntry = errors.New("journal entry is not balanced")
+
+//postEntry records in the journal the postings of an operation processing the amount on the authorisation and charging
+//the fee to the merchant, it is called within the transaction writing the operation so that the journal never diverges
+//from the authorisations
+func (db *Database) postEntry(name string, data *auth.Auth, operationID uint, amount int64, fee int64, at time.Time, tx *gorm.DB) error {
+	postings := ledger.Postings(name, amount)
+	if postings == nil {
+		return nil
+	}
+	postings = append(postings, ledger.FeePostings(fee)...)
+	if !ledger.IsBalanced(postings) {
+		return errUnbalancedEntry
+	}
+
+	entry := &journal.Entry{
+		AuthID:      data.ID,
+		OperationID: operationID,
+		MerchantID:  data.MerchantID,
+		Name:        name,
+		Currency:    data.Currency,
+		CreatedAt:   at,
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+	for _, p := range postings {
+		posting := &journal.Posting{
+			EntryID:    entry.ID,
+			Account:    p.Account,
+			Amount:     p.Amount,
+			AuthID:     data.ID,
+			MerchantID: data.MerchantID,
+			Currency:   data.Currency,
+		}
+		if err := tx.Create(posting).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//openLedger records in the journal the operations of the authorisations made before the ledger existed,
+//they are replayed in the order they were made and the voided authorisations have their hold released
+func (db *Database) openLedger() (err error) {
+	tx := db.Db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "openLedger"), logger.Err(err))
+		return err
+	}
+
+	var records []auth.Auth
+	if err := tx.Where("id NOT IN (?)", tx.Table("journal_entries").Select("auth_id").QueryExpr()).Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "openLedger"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	for i := range records {
+		var operations []operation.Operation
+		if err := tx.Where("auth_id = ?", records[i].ID).Order("id").Find(&operations).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "openLedger"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+
+		//the operations record the available amount they left, what they processed is the difference with the previous one
+		var available int64
+		for _, op := range operations {
+			amount := ledger.MinorUnits(op.Amount) - available
+			if amount < 0 {
+				amount = -amount
+			}
+			available = ledger.MinorUnits(op.Amount)
+			if err := db.postEntry(op.Name, &records[i], op.ID, amount, ledger.MinorUnits(op.Fee), op.CreatedAt, tx); err != nil {
+				db.logger.Error("database call failed", logger.String("call", "openLedger"), logger.Err(err))
+				tx.Rollback()
+				return err
+			}
+		}
+		if !records[i].DeletedAt.IsZero() && available > 0 {
+			if err := db.postEntry("void", &records[i], 0, available, 0, records[i].DeletedAt, tx); err != nil {
+				db.logger.Error("database call failed", logger.String("call", "openLedger"), logger.Err(err))
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	if len(records) > 0 {
+		db.logger.Info("ledger opened for the existing authorisations", logger.Int("authorisations", len(records)))
+	}
+	return tx.Commit().Error
+}
+
+//GetLedgerBalances sums the postings per account and currency, of an authorisation and of a merchant when their ids are not empty
+func (db *Database) GetLedgerBalances(ctx context.Context, authID string, merchantID string) (_ []ledger.Balance, err error) {
+	defer db.observe("GetLedgerBalances", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetLedgerBalances"), logger.Err(err))
+		return nil, err
+	}
+
+	query := tx.Table("journal_postings").Select("account, currency, SUM(amount)").Group("account, currency").Order("account, currency")
+	if authID != "" {
+		query = query.Where("auth_id = ?", authID)
+	}
+	if merchantID != "" {
+		query = query.Where("merchant_id = ?", merchantID)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetLedgerBalances"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make([]ledger.Balance, 0)
+	for rows.Next() {
+		var balance ledger.Balance
+		if err := rows.Scan(&balance.Account, &balance.Currency, &balance.Amount); err != nil {
+			db.logger.Error("database call failed", logger.String("call", "GetLedgerBalances"), logger.Err(err))
+			tx.Rollback()
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetLedgerBalances"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return balances, tx.Commit().Error
+}
+
+//ListJournalEntries fetches the journal entries of an authorisation with their postings, in the order they were recorded
+func (db *Database) ListJournalEntries(ctx context.Context, authID string) (_ []journal.Entry, _ []journal.Posting, err error) {
+	defer db.observe("ListJournalEntries", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListJournalEntries"), logger.Err(err))
+		return nil, nil, err
+	}
+
+	var entries []journal.Entry
+	if err := tx.Where("auth_id = ?", authID).Order("id").Find(&entries).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListJournalEntries"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	var postings []journal.Posting
+	if err := tx.Where("auth_id = ?", authID).Order("entry_id, id").Find(&postings).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListJournalEntries"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return entries, postings, tx.Commit().Error
+}
+
+//ListLedgerHolds fetches every authorisation with the balance of its hold account
+func (db *Database) ListLedgerHolds(ctx context.Context) (_ []ledger.Hold, err error) {
+	defer db.observe("ListLedgerHolds", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListLedgerHolds"), logger.Err(err))
+		return nil, err
+	}
+
+	rows, err := tx.Table("auths").
+		Select("auths.id, auths.available_amount, auths.deleted_at, COALESCE(SUM(journal_postings.amount), 0)").
+		Joins("LEFT JOIN journal_postings ON journal_postings.auth_id = auths.id AND journal_postings.account = ?", ledger.AccountCardholderHold).
+		Group("auths.id, auths.available_amount, auths.deleted_at").
+		Order("auths.id").
+		Rows()
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListLedgerHolds"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make([]ledger.Hold, 0)
+	for rows.Next() {
+		var hold ledger.Hold
+		var deletedAt time.Time
+		if err := rows.Scan(&hold.AuthID, &hold.AvailableAmount, &deletedAt, &hold.Balance); err != nil {
+			db.logger.Error("database call failed", logger.String("call", "ListLedgerHolds"), logger.Err(err))
+			tx.Rollback()
+			return nil, err
+		}
+		hold.Voided = !deletedAt.IsZero()
+		holds = append(holds, hold)
+	}
+	if err := rows.Err(); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListLedgerHolds"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return holds, tx.Commit().Error
+}
+
+//ListUnbalancedJournalEntries fetches the journal entries whose postings do not add up to zero, or that have none
+func (db *Database) ListUnbalancedJournalEntries(ctx context.Context) (_ []ledger.Imbalance, err error) {
+	defer db.observe("ListUnbalancedJournalEntries", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListUnbalancedJournalEntries"), logger.Err(err))
+		return nil, err
+	}
+
+	rows, err := tx.Table("journal_entries").
+		Select("journal_entries.id, COALESCE(SUM(journal_postings.amount), 0)").
+		Joins("LEFT JOIN journal_postings ON journal_postings.entry_id = journal_entries.id").
+		Group("journal_entries.id").
+		Having("COALESCE(SUM(journal_postings.amount), 0) != 0 OR COUNT(journal_postings.id) = 0").
+		Order("journal_entries.id").
+		Rows()
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListUnbalancedJournalEntries"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	imbalances := make([]ledger.Imbalance, 0)
+	for rows.Next() {
+		var imbalance ledger.Imbalance
+		if err := rows.Scan(&imbalance.EntryID, &imbalance.Amount); err != nil {
+			db.logger.Error("database call failed", logger.String("call", "ListUnbalancedJournalEntries"), logger.Err(err))
+			tx.Rollback()
+			return nil, err
+		}
+		imbalances = append(imbalances, imbalance)
+	}
+	if err := rows.Err(); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListUnbalancedJournalEntries"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return imbalances, tx.Commit().Error
+}
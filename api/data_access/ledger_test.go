@@ -0,0 +1,134 @@
+package data_access
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/ledger"
+	"testing"
+	"time"
+)
+
+func TestDatabase_Ledger_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	captured := auth.Auth{ID: "a2d2e3f4-0a1b-4c2d-8e3f-000000000001", MerchantID: "acme", Number: "4000056655665556",
+		ExpiryDate: "12-2099", Currency: "GBP", AuthorisedAmount: 100, AvailableAmount: 100}
+	voided := auth.Auth{ID: "a2d2e3f4-0a1b-4c2d-8e3f-000000000002", MerchantID: "acme", Number: "4000056655665556",
+		ExpiryDate: "12-2099", Currency: "GBP", AuthorisedAmount: 30.5, AvailableAmount: 30.5}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &captured))
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &voided))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: captured.ID, AvailableAmount: 100}, 40, "capture", 60, 1.2))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: captured.ID, AvailableAmount: 40}, 50, "refund", 10, -0.2))
+	assert.Nil(t, db.SoftDeleteAuthRecordByID(context.Background(), voided.ID))
+
+	entries, postings, err := db.ListJournalEntries(context.Background(), captured.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, len(entries))
+	assert.EqualValues(t, []string{"authorisation", "capture", "refund"}, []string{entries[0].Name, entries[1].Name, entries[2].Name})
+	assert.EqualValues(t, 10, len(postings))
+	assert.EqualValues(t, entries[1].ID, postings[2].EntryID)
+	assert.EqualValues(t, ledger.AccountMerchantReceivable, postings[2].Account)
+	assert.EqualValues(t, 6000, postings[2].Amount)
+	//the fee of the capture is charged out of the receivable of the merchant
+	assert.EqualValues(t, entries[1].ID, postings[5].EntryID)
+	assert.EqualValues(t, ledger.AccountMerchantReceivable, postings[5].Account)
+	assert.EqualValues(t, -120, postings[5].Amount)
+
+	balances, err := db.GetLedgerBalances(context.Background(), captured.ID, "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, []ledger.Balance{
+		{Account: ledger.AccountCardholderFunds, Currency: "GBP", Amount: -10000},
+		{Account: ledger.AccountCardholderHold, Currency: "GBP", Amount: 5000},
+		{Account: ledger.AccountFees, Currency: "GBP", Amount: 100},
+		{Account: ledger.AccountMerchantReceivable, Currency: "GBP", Amount: 5900},
+		{Account: ledger.AccountRefundsPayable, Currency: "GBP", Amount: -1000},
+	}, balances)
+	balances, err = db.GetLedgerBalances(context.Background(), "", "acme")
+	assert.Nil(t, err)
+	assert.EqualValues(t, ledger.Balance{Account: ledger.AccountCardholderFunds, Currency: "GBP", Amount: -10000}, balances[0])
+
+	holds, err := db.ListLedgerHolds(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, []ledger.Hold{
+		{AuthID: captured.ID, AvailableAmount: 50, Balance: 5000},
+		{AuthID: voided.ID, AvailableAmount: 30.5, Voided: true, Balance: 0},
+	}, holds)
+	imbalances, err := db.ListUnbalancedJournalEntries(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, imbalances)
+}
+
+func TestDatabase_Ledger_DeclinedReview_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := auth.Auth{ID: "a2d2e3f4-0a1b-4c2d-8e3f-000000000003", Number: "4000056655665556", ExpiryDate: "12-2099",
+		Currency: "EUR", AuthorisedAmount: 20, AvailableAmount: 20}
+	pending := review.Review{AuthID: record.ID, State: "pending", DueAt: now.Add(time.Hour)}
+	assert.Nil(t, db.InsertPendingAuthRecord(context.Background(), &record, &pending))
+	declined := review.Review{AuthID: record.ID, State: "declined", Reviewer: "analyst", Reason: "fraud", ReviewedAt: now}
+	op := operation.Operation{AuthID: record.ID, Name: "review_declined", Amount: 20, Currency: "EUR"}
+	assert.Nil(t, db.CompleteReviewRecord(context.Background(), &declined, &op, true))
+
+	balances, err := db.GetLedgerBalances(context.Background(), record.ID, "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, []ledger.Balance{
+		{Account: ledger.AccountCardholderFunds, Currency: "EUR", Amount: 0},
+		{Account: ledger.AccountCardholderHold, Currency: "EUR", Amount: 0},
+	}, balances)
+}
+
+func TestDatabase_OpenLedger_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	//an authorisation captured and refunded, and another one voided, before the ledger existed
+	captured := auth.Auth{ID: "a2d2e3f4-0a1b-4c2d-8e3f-000000000004", Currency: "GBP", AuthorisedAmount: 100, AvailableAmount: 70}
+	voided := auth.Auth{ID: "a2d2e3f4-0a1b-4c2d-8e3f-000000000005", Currency: "GBP", AuthorisedAmount: 25, AvailableAmount: 25, DeletedAt: now}
+	assert.Nil(t, db.Db.Create(&captured).Error)
+	assert.Nil(t, db.Db.Create(&voided).Error)
+	for _, op := range []operation.Operation{
+		{AuthID: captured.ID, Name: "authorisation", Amount: 100},
+		{AuthID: captured.ID, Name: "capture", Amount: 40},
+		{AuthID: captured.ID, Name: "refund", Amount: 70},
+		{AuthID: voided.ID, Name: "authorisation", Amount: 25},
+	} {
+		op.Currency = "GBP"
+		assert.Nil(t, db.Db.Create(&op).Error)
+	}
+
+	assert.Nil(t, db.openLedger())
+	balances, err := db.GetLedgerBalances(context.Background(), captured.ID, "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, []ledger.Balance{
+		{Account: ledger.AccountCardholderFunds, Currency: "GBP", Amount: -10000},
+		{Account: ledger.AccountCardholderHold, Currency: "GBP", Amount: 7000},
+		{Account: ledger.AccountMerchantReceivable, Currency: "GBP", Amount: 6000},
+		{Account: ledger.AccountRefundsPayable, Currency: "GBP", Amount: -3000},
+	}, balances)
+	holds, err := db.ListLedgerHolds(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, ledger.Hold{AuthID: voided.ID, AvailableAmount: 25, Voided: true, Balance: 0}, holds[1])
+
+	//the ledger is opened once
+	assert.Nil(t, db.openLedger())
+	entries, _, err := db.ListJournalEntries(context.Background(), captured.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, len(entries))
+}
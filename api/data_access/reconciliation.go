@@ -0,0 +1,105 @@
+package data_access
+
+import (
+	"context"
+	"payment-gateway-api/api/data_access/database_model/reconciliation"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//CreateReconciliation stores the report of a reconciliation along with its items
+func (db *Database) CreateReconciliation(ctx context.Context, data *reconciliation.Reconciliation, items []reconciliation.Item) (err error) {
+	defer db.observe("CreateReconciliation", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CreateReconciliation"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CreateReconciliation"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	for i := range items {
+		items[i].ReconciliationID = data.ID
+		if err := tx.Create(&items[i]).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "CreateReconciliation"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+//ListReconciliations fetches the reconciliations without their items, the latest first. They are filtered by
+//settlement batch unless the batch id is empty
+func (db *Database) ListReconciliations(ctx context.Context, batchID string) (_ []reconciliation.Reconciliation, err error) {
+	defer db.observe("ListReconciliations", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListReconciliations"), logger.Err(err))
+		return nil, err
+	}
+
+	query := tx.Order("created_at DESC")
+	if batchID != "" {
+		query = query.Where("batch_id = ?", batchID)
+	}
+	var records []reconciliation.Reconciliation
+	if err := query.Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListReconciliations"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//GetReconciliationByID fetches a reconciliation with its items
+func (db *Database) GetReconciliationByID(ctx context.Context, id string) (_ *reconciliation.Reconciliation, _ []reconciliation.Item, err error) {
+	defer db.observe("GetReconciliationByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetReconciliationByID"), logger.Err(err))
+		return nil, nil, err
+	}
+
+	var record reconciliation.Reconciliation
+	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	var items []reconciliation.Item
+	if err := tx.Where("reconciliation_id = ?", id).Order("id").Find(&items).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetReconciliationByID"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return &record, items, tx.Commit().Error
+}
@@ -0,0 +1,64 @@
+package data_access
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/reconciliation"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"testing"
+	"time"
+)
+
+func TestDatabase_Reconciliations_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := auth.Auth{ID: "d1d2e3f4-0a1b-4c2d-8e3f-000000000001", MerchantID: "acme", Number: "4000056655665556",
+		ExpiryDate: "12-2099", Currency: "GBP", AuthorisedAmount: 100, AvailableAmount: 100}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &record))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 100}, 40, "capture", 60, 0))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: record.ID, AvailableAmount: 40}, 50, "refund", 10, 0))
+	batch := settlement.Batch{ID: "e1d2e3f4-0a1b-4c2d-8e3f-000000000001", CutOff: now.Add(time.Hour), State: "closed"}
+	_, err := db.CloseSettlementBatch(context.Background(), &batch)
+	assert.Nil(t, err)
+
+	//only the captures and refunds of the batch are returned, not the authorisation
+	operations, err := db.ListSettlementOperations(context.Background(), batch.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, len(operations))
+	assert.EqualValues(t, "capture", operations[0].Name)
+	assert.EqualValues(t, 60, operations[0].ProcessedAmount)
+	assert.EqualValues(t, "refund", operations[1].Name)
+	assert.EqualValues(t, 10, operations[1].ProcessedAmount)
+
+	first := reconciliation.Reconciliation{ID: "f1d2e3f4-0a1b-4c2d-8e3f-000000000001", BatchID: batch.ID, Matched: 1, Missing: 1, CreatedAt: now}
+	items := []reconciliation.Item{
+		{Status: "matched", Line: 2, Reference: record.ID, Type: "capture", OperationID: operations[0].ID, ExpectedAmount: 60, ReportedAmount: 60, Currency: "GBP"},
+		{Status: "missing", Reference: record.ID, Type: "refund", OperationID: operations[1].ID, ExpectedAmount: 10, Currency: "GBP"},
+	}
+	assert.Nil(t, db.CreateReconciliation(context.Background(), &first, items))
+	second := reconciliation.Reconciliation{ID: "f1d2e3f4-0a1b-4c2d-8e3f-000000000002", BatchID: "e1d2e3f4-0a1b-4c2d-8e3f-000000000002", CreatedAt: now.Add(time.Minute)}
+	assert.Nil(t, db.CreateReconciliation(context.Background(), &second, nil))
+
+	stored, storedItems, err := db.GetReconciliationByID(context.Background(), first.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, stored.Matched)
+	assert.EqualValues(t, 1, stored.Missing)
+	assert.EqualValues(t, items, storedItems)
+	_, _, err = db.GetReconciliationByID(context.Background(), "f1d2e3f4-0a1b-4c2d-8e3f-000000000003")
+	assert.EqualValues(t, "record not found", err.Error())
+
+	reconciliations, err := db.ListReconciliations(context.Background(), "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, len(reconciliations))
+	assert.EqualValues(t, second.ID, reconciliations[0].ID)
+	reconciliations, err = db.ListReconciliations(context.Background(), batch.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(reconciliations))
+	assert.EqualValues(t, first.ID, reconciliations[0].ID)
+}
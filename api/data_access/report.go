@@ -0,0 +1,100 @@
+package data_access
+
+import (
+	"context"
+	"fmt"
+	"payment-gateway-api/api/data_access/database_model/report"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//bucketStarts are the expressions of the date the bucket of a timestamp starts on, by bucket. The weeks start on Monday
+var bucketStarts = map[string]string{
+	"day":   "date(%s)",
+	"week":  "date(%s, 'weekday 0', '-6 days')",
+	"month": "strftime('%%Y-%%m-01', %s)",
+}
+
+//SummariseActivity sums the authorisations, captures, refunds and voids, the declines and the delays from
+//authorisation to capture of the period of the filter by bucket, the sums are made by the db. The authorisations held
+//for review are summed apart as pending_review or, once declined, review_declined, they are only approved once the
+//review is
+func (db *Database) SummariseActivity(ctx context.Context, filter report.Filter) (_ *report.Summary, err error) {
+	defer db.observe("SummariseActivity", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SummariseActivity"), logger.Err(err))
+		return nil, err
+	}
+
+	bucket := func(column string) string {
+		return fmt.Sprintf(bucketStarts[filter.Bucket], column)
+	}
+	summary := &report.Summary{}
+
+	operations, operationArgs := reportConditions(filter, "operations.created_at", "auths.merchant_id", "auths.currency")
+	voids, voidArgs := reportConditions(filter, "auths.deleted_at", "auths.merchant_id", "auths.currency")
+	query := "SELECT " + bucket("operations.created_at") + " AS bucket," +
+		" CASE WHEN operations.name <> 'authorisation' OR reviews.state IS NULL OR reviews.state = 'approved' THEN operations.name" +
+		" WHEN reviews.state = 'pending' THEN 'pending_review' ELSE 'review_declined' END AS name, auths.currency AS currency," +
+		" COUNT(*) AS count, SUM(operations.processed_amount) AS amount FROM operations JOIN auths ON auths.id = operations.auth_id" +
+		" LEFT JOIN reviews ON reviews.auth_id = operations.auth_id" +
+		" WHERE operations.deleted_at IS NULL AND operations.name IN ('authorisation', 'capture', 'refund') AND " + operations +
+		" GROUP BY 1, 2, 3" +
+		//a void releases what is left of the authorised amount, the authorisations declined by a review were never approved
+		" UNION ALL SELECT " + bucket("auths.deleted_at") + ", 'void', auths.currency, COUNT(*), SUM(auths.available_amount)" +
+		" FROM auths WHERE " + voids + " AND NOT EXISTS (SELECT 1 FROM reviews WHERE reviews.auth_id = auths.id AND reviews.state = 'declined')" +
+		" GROUP BY 1, 2, 3 ORDER BY 1, 2, 3"
+	if err := tx.Raw(query, append(operationArgs, voidArgs...)...).Scan(&summary.Operations).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SummariseActivity"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	declines, declineArgs := reportConditions(filter, "created_at", "merchant_id", "currency")
+	query = "SELECT " + bucket("created_at") + " AS bucket, reason AS name, currency, COUNT(*) AS count, SUM(amount) AS amount" +
+		" FROM declines WHERE deleted_at IS NULL AND " + declines + " GROUP BY 1, 2, 3 ORDER BY 1, 2, 3"
+	if err := tx.Raw(query, declineArgs...).Scan(&summary.Declines).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SummariseActivity"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	//the delay of an authorisation is counted in the bucket of its first capture, the julian days are only precise
+	//to the tens of microseconds once converted to seconds
+	captures, captureArgs := reportConditions(filter, "captures.captured_at", "auths.merchant_id", "auths.currency")
+	query = "SELECT " + bucket("captures.captured_at") + " AS bucket, COUNT(*) AS count," +
+		" AVG(ROUND((julianday(captures.captured_at) - julianday(auths.created_at)) * 86400, 3)) AS seconds" +
+		" FROM (SELECT auth_id, MIN(created_at) AS captured_at FROM operations WHERE name = 'capture' AND deleted_at IS NULL" +
+		" GROUP BY auth_id) AS captures JOIN auths ON auths.id = captures.auth_id WHERE " + captures + " GROUP BY 1 ORDER BY 1"
+	if err := tx.Raw(query, captureArgs...).Scan(&summary.CaptureDelays).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SummariseActivity"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return summary, tx.Commit().Error
+}
+
+//reportConditions returns the conditions of the rows of the report made over its period, of its merchant when it is
+//set or scoped and in its currency when it is set, with their arguments
+func reportConditions(filter report.Filter, timestamp string, merchantID string, currency string) (string, []interface{}) {
+	conditions := timestamp + " >= ? AND " + timestamp + " < ?"
+	args := []interface{}{filter.From.UTC(), filter.To.UTC()}
+	if filter.MerchantID != "" || filter.Scoped {
+		conditions += " AND " + merchantID + " = ?"
+		args = append(args, filter.MerchantID)
+	}
+	if filter.Currency != "" {
+		conditions += " AND " + currency + " = ?"
+		args = append(args, filter.Currency)
+	}
+	return conditions, args
+}
@@ -0,0 +1,108 @@
+package data_access
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/decline"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/report"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"testing"
+	"time"
+)
+
+func TestDatabase_SummariseActivity_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+	clk := db.clock.(*clock.FakeClock)
+	ctx := context.Background()
+
+	newAuth := func(id string, amount float32, currency string) *auth.Auth {
+		at := clk.Now()
+		record := &auth.Auth{ID: id, MerchantID: "report-co", Number: "4929907390318794", ExpiryDate: "12-2099",
+			AuthorisedAmount: amount, AvailableAmount: amount, Currency: currency, CreatedAt: at, UpdatedAt: at}
+		assert.Nil(t, db.InsertAuthRecord(ctx, record))
+		return record
+	}
+	//held authorisations are reviewed right away unless the state is pending
+	newHeld := func(id string, amount float32, currency string, state string) {
+		at := clk.Now()
+		record := &auth.Auth{ID: id, MerchantID: "report-co", Number: "4929907390318794", ExpiryDate: "12-2099",
+			AuthorisedAmount: amount, AvailableAmount: amount, Currency: currency, CreatedAt: at, UpdatedAt: at}
+		assert.Nil(t, db.InsertPendingAuthRecord(ctx, record, &review.Review{AuthID: id, State: "pending", DueAt: at.Add(time.Hour)}))
+		if state != "pending" {
+			decided := review.Review{AuthID: id, State: state, Reviewer: "analyst", ReviewedAt: at}
+			op := operation.Operation{AuthID: id, Name: "review_" + state, Amount: amount, Currency: currency}
+			assert.Nil(t, db.CompleteReviewRecord(ctx, &decided, &op, state == "declined"))
+		}
+	}
+
+	//a monday
+	captured := newAuth("d1000000-0000-4000-8000-000000000001", 100, "GBP")
+	voided := newAuth("d1000000-0000-4000-8000-000000000002", 50, "GBP")
+	assert.Nil(t, db.InsertDecline(ctx, &decline.Decline{MerchantID: "report-co", Number: "4000000000000002", Amount: 20, Currency: "GBP", Reason: "acquirer"}))
+	assert.Nil(t, db.InsertDecline(ctx, &decline.Decline{MerchantID: "other-co", Number: "4000000000000002", Amount: 20, Currency: "GBP", Reason: "acquirer"}))
+	clk.Advance(time.Hour)
+	assert.Nil(t, db.SoftDeleteAuthRecordByID(ctx, voided.ID))
+	clk.Advance(time.Hour)
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(ctx, &auth.Auth{ID: captured.ID, AvailableAmount: 100}, 40, "capture", 60, 1.2))
+	clk.Set(now.Add(48 * time.Hour))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(ctx, &auth.Auth{ID: captured.ID, AvailableAmount: 40}, 50, "refund", 10, 0))
+	//the monday after
+	clk.Set(now.Add(7 * 24 * time.Hour))
+	euro := newAuth("d1000000-0000-4000-8000-000000000003", 30, "EUR")
+	newHeld("d1000000-0000-4000-8000-000000000004", 40, "GBP", "pending")
+	newHeld("d1000000-0000-4000-8000-000000000005", 25, "GBP", "declined")
+	newHeld("d1000000-0000-4000-8000-000000000006", 20, "EUR", "approved")
+	assert.Nil(t, db.InsertDecline(ctx, &decline.Decline{MerchantID: "report-co", Number: "4000000000000002", Amount: 10, Currency: "EUR", Reason: "fraud"}))
+	clk.Advance(time.Hour)
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(ctx, &auth.Auth{ID: euro.ID, AvailableAmount: 30}, 0, "capture", 30, 0))
+
+	filter := report.Filter{From: now.Add(-time.Hour), To: now.Add(14 * 24 * time.Hour), MerchantID: "report-co", Bucket: "week"}
+	summary, err := db.SummariseActivity(ctx, filter)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []report.Volume{
+		{Bucket: "2020-06-15", Name: "authorisation", Currency: "GBP", Count: 2, Amount: 150},
+		{Bucket: "2020-06-15", Name: "capture", Currency: "GBP", Count: 1, Amount: 60},
+		{Bucket: "2020-06-15", Name: "refund", Currency: "GBP", Count: 1, Amount: 10},
+		{Bucket: "2020-06-15", Name: "void", Currency: "GBP", Count: 1, Amount: 50},
+		{Bucket: "2020-06-22", Name: "authorisation", Currency: "EUR", Count: 2, Amount: 50},
+		{Bucket: "2020-06-22", Name: "capture", Currency: "EUR", Count: 1, Amount: 30},
+		{Bucket: "2020-06-22", Name: "pending_review", Currency: "GBP", Count: 1, Amount: 40},
+		{Bucket: "2020-06-22", Name: "review_declined", Currency: "GBP", Count: 1, Amount: 25},
+	}, summary.Operations)
+	assert.EqualValues(t, []report.Volume{
+		{Bucket: "2020-06-15", Name: "acquirer", Currency: "GBP", Count: 1, Amount: 20},
+		{Bucket: "2020-06-22", Name: "fraud", Currency: "EUR", Count: 1, Amount: 10},
+	}, summary.Declines)
+	assert.EqualValues(t, []report.CaptureDelay{
+		{Bucket: "2020-06-15", Count: 1, Seconds: 7200},
+		{Bucket: "2020-06-22", Count: 1, Seconds: 3600},
+	}, summary.CaptureDelays)
+
+	filter.Bucket, filter.Currency = "day", "GBP"
+	summary, err = db.SummariseActivity(ctx, filter)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 6, len(summary.Operations))
+	assert.EqualValues(t, report.Volume{Bucket: "2020-06-17", Name: "refund", Currency: "GBP", Count: 1, Amount: 10}, summary.Operations[3])
+	assert.EqualValues(t, 1, len(summary.Declines))
+	assert.EqualValues(t, 1, len(summary.CaptureDelays))
+
+	filter.Bucket, filter.Currency = "month", ""
+	summary, err = db.SummariseActivity(ctx, filter)
+	assert.Nil(t, err)
+	assert.EqualValues(t, report.Volume{Bucket: "2020-06-01", Name: "authorisation", Currency: "EUR", Count: 2, Amount: 50}, summary.Operations[0])
+	assert.EqualValues(t, []report.CaptureDelay{{Bucket: "2020-06-01", Count: 2, Seconds: 5400}}, summary.CaptureDelays)
+
+	//the activity without a merchant is kept apart from the one of every merchant
+	summary, err = db.SummariseActivity(ctx, report.Filter{From: filter.From, To: filter.To, Scoped: true, Bucket: "month"})
+	assert.Nil(t, err)
+	assert.Empty(t, summary.Operations)
+	assert.Empty(t, summary.Declines)
+}
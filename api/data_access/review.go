@@ -0,0 +1,218 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/ledger"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//InsertPendingAuthRecord inserts an authorisation held for review together with its entry in the reviews table
+func (db *Database) InsertPendingAuthRecord(ctx context.Context, data *auth.Auth, pending *review.Review) (err error) {
+	defer db.observe("InsertPendingAuthRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertPendingAuthRecord"), logger.Err(err))
+		return err
+	}
+
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertPendingAuthRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := db.insertOperation("authorisation", data, data.AuthorisedAmount, 0, tx); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertPendingAuthRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := db.insertReview(pending, data, tx); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "InsertPendingAuthRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//insertReview adds the authorisation to the review queue and records the review operation with the rules that held it
+func (db *Database) insertReview(pending *review.Review, data *auth.Auth, tx *gorm.DB) error {
+	if err := tx.Create(pending).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "insertReview"), logger.Err(err))
+		return err
+	}
+
+	op := &operation.Operation{
+		AuthID:   data.ID,
+		Name:     "review",
+		Amount:   data.AvailableAmount,
+		Currency: data.Currency,
+		Reason:   pending.Rules,
+	}
+	if err := tx.Create(op).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "insertReview"), logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+//GetReviewRecordByAuthID fetches the review of an authorisation
+func (db *Database) GetReviewRecordByAuthID(ctx context.Context, id string) (_ *review.Review, err error) {
+	defer db.observe("GetReviewRecordByAuthID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetReviewRecordByAuthID"), logger.Err(err))
+		return nil, err
+	}
+
+	var record review.Review
+	if err := tx.Where("auth_id = ?", id).First(&record).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &record, tx.Commit().Error
+}
+
+//ListReviewRecords fetches the reviews in the given state, or all of them when the state is empty, the ones due first come first
+func (db *Database) ListReviewRecords(ctx context.Context, state string) (_ []review.Review, err error) {
+	defer db.observe("ListReviewRecords", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListReviewRecords"), logger.Err(err))
+		return nil, err
+	}
+
+	query := tx.Order("due_at")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+
+	var records []review.Review
+	if err := query.Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListReviewRecords"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//GetDueReviewRecords fetches the pending reviews whose deadline has passed at the given time
+func (db *Database) GetDueReviewRecords(ctx context.Context, dueAt time.Time) (_ []review.Review, err error) {
+	defer db.observe("GetDueReviewRecords", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDueReviewRecords"), logger.Err(err))
+		return nil, err
+	}
+
+	var records []review.Review
+	if err := tx.Where("state = ? AND due_at <= ?", "pending", dueAt.UTC()).Order("due_at").Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetDueReviewRecords"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//CompleteReviewRecord saves the decision taken on a pending review and the operation recording it, a declined
+//authorisation is voided in the same transaction. The record not found error is returned when the review is no longer pending
+func (db *Database) CompleteReviewRecord(ctx context.Context, data *review.Review, op *operation.Operation, voidAuth bool) (err error) {
+	defer db.observe("CompleteReviewRecord", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CompleteReviewRecord"), logger.Err(err))
+		return err
+	}
+
+	//the pending state is checked by the update itself so that two analysts cannot both complete the review
+	result := tx.Model(&review.Review{}).Where("auth_id = ? AND state = ?", data.AuthID, "pending").Updates(map[string]interface{}{
+		"state":       data.State,
+		"reviewer":    data.Reviewer,
+		"reason":      data.Reason,
+		"reviewed_at": data.ReviewedAt,
+		"updated_at":  data.UpdatedAt,
+	})
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CompleteReviewRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := tx.Create(op).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CompleteReviewRecord"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if voidAuth {
+		var record auth.Auth
+		if err := tx.Where("id = ?", data.AuthID).First(&record).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "CompleteReviewRecord"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Model(&auth.Auth{}).Where("id = ?", data.AuthID).Update("deleted_at", data.ReviewedAt).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "CompleteReviewRecord"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+
+		if err := db.postEntry("void", &record, 0, ledger.MinorUnits(record.AvailableAmount), 0, data.ReviewedAt, tx); err != nil {
+			db.logger.Error("database call failed", logger.String("call", "CompleteReviewRecord"), logger.Err(err))
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,85 @@
+package data_access
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"testing"
+	"time"
+)
+
+func TestDatabase_Reviews_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	ids := []string{"f6b2b1a8-0f2c-4d3e-9a51-1c1f7c1f0a01", "f6b2b1a8-0f2c-4d3e-9a51-1c1f7c1f0a02"}
+	for i, id := range ids {
+		record := auth.Auth{
+			ID:               id,
+			Number:           "4000056655665556",
+			ExpiryDate:       "12-2099",
+			AuthorisedAmount: 100,
+			AvailableAmount:  100,
+			Currency:         "GBP",
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+		pending := review.Review{
+			AuthID:    id,
+			State:     "pending",
+			Rules:     "daily amount",
+			Amount:    100,
+			Currency:  "GBP",
+			DueAt:     now.Add(time.Duration(i+1) * time.Hour),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		assert.Nil(t, db.InsertPendingAuthRecord(context.Background(), &record, &pending))
+	}
+
+	found, op, err := db.GetOperationByAuthIDAndOperationName(context.Background(), ids[0], "review")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "daily amount", op.Reason)
+
+	due, err := db.GetDueReviewRecords(context.Background(), now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(due))
+	assert.EqualValues(t, ids[0], due[0].AuthID)
+
+	record, err := db.GetReviewRecordByAuthID(context.Background(), ids[1])
+	assert.Nil(t, err)
+	record.State = "declined"
+	record.Reviewer = "analyst@example.com"
+	record.Reason = "stolen card"
+	record.ReviewedAt = now
+	decision := operation.Operation{AuthID: ids[1], Name: "review_declined", Amount: 100, Currency: "GBP", Reviewer: record.Reviewer, Reason: record.Reason}
+	assert.Nil(t, db.CompleteReviewRecord(context.Background(), record, &decision, true))
+
+	//a review can only be completed once
+	again := operation.Operation{AuthID: ids[1], Name: "review_approved"}
+	assert.EqualValues(t, "record not found", db.CompleteReviewRecord(context.Background(), record, &again, false).Error())
+
+	found, op, err = db.GetOperationByAuthIDAndOperationName(context.Background(), ids[1], "review_declined")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "analyst@example.com", op.Reviewer)
+
+	//the declined authorisation is voided
+	live, _, err := db.GetAuthRecordByID(context.Background(), ids[1])
+	assert.Nil(t, err)
+	assert.False(t, live)
+
+	pending, err := db.ListReviewRecords(context.Background(), "pending")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(pending))
+	all, err := db.ListReviewRecords(context.Background(), "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, len(all))
+}
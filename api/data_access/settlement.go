@@ -0,0 +1,293 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"math"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"payment-gateway-api/api/logger"
+	"time"
+)
+
+//settledOperations are the operations moving funds between the cardholders and the merchants
+var settledOperations = []string{"capture", "refund"}
+
+//CloseSettlementBatch stores the batch with the captures and refunds made before its cut-off that have not been settled yet,
+//they are marked as settled in it and their totals per merchant and currency are returned
+func (db *Database) CloseSettlementBatch(ctx context.Context, data *settlement.Batch) (_ []settlement.Total, err error) {
+	defer db.observe("CloseSettlementBatch", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CloseSettlementBatch"), logger.Err(err))
+		return nil, err
+	}
+
+	result := tx.Model(&operation.Operation{}).
+		Where("(batch_id = '' OR batch_id IS NULL) AND name IN (?) AND created_at < ?", settledOperations, data.CutOff.UTC()).
+		Update("batch_id", data.ID)
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CloseSettlementBatch"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+	data.Operations = int(result.RowsAffected)
+
+	//the cut-off is unique so that a batch closed concurrently fails here
+	if err := tx.Create(data).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CloseSettlementBatch"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	totals, err := db.aggregateSettlementBatch(data.ID, tx)
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "CloseSettlementBatch"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+	for i := range totals {
+		if err := tx.Create(&totals[i]).Error; err != nil {
+			db.logger.Error("database call failed", logger.String("call", "CloseSettlementBatch"), logger.Err(err))
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return totals, tx.Commit().Error
+}
+
+//aggregateSettlementBatch sums the captured and refunded amounts of the batch and the fees charged on them per merchant
+//of their authorisation and currency
+func (db *Database) aggregateSettlementBatch(id string, tx *gorm.DB) ([]settlement.Total, error) {
+	rows, err := tx.Table("operations").
+		Select("COALESCE(auths.merchant_id, ''), operations.currency, operations.name, COUNT(*), COALESCE(SUM(operations.processed_amount), 0), COALESCE(SUM(operations.fee), 0)").
+		Joins("JOIN auths ON auths.id = operations.auth_id").
+		Where("operations.batch_id = ? AND operations.deleted_at IS NULL", id).
+		Group("auths.merchant_id, operations.currency, operations.name").
+		Order("auths.merchant_id, operations.currency").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make([]settlement.Total, 0)
+	for rows.Next() {
+		var merchantID, currency, name string
+		var count int
+		var amount, fees float64
+		if err := rows.Scan(&merchantID, &currency, &name, &count, &amount, &fees); err != nil {
+			return nil, err
+		}
+		//the rows come ordered so that both operations of a merchant and currency follow each other
+		if last := len(totals) - 1; last < 0 || totals[last].MerchantID != merchantID || totals[last].Currency != currency {
+			totals = append(totals, settlement.Total{BatchID: id, MerchantID: merchantID, Currency: currency})
+		}
+		total := &totals[len(totals)-1]
+		//the amounts are summed from float32 values, they are rounded to the cent
+		amount = math.Round(amount*100) / 100
+		total.Fees = math.Round((total.Fees+fees)*100) / 100
+		if name == "capture" {
+			total.Captures, total.CapturedAmount = count, amount
+		} else {
+			total.Refunds, total.RefundedAmount = count, amount
+		}
+	}
+	return totals, rows.Err()
+}
+
+//ListSettlementBatches fetches all the settlement batches, the latest cut-off first
+func (db *Database) ListSettlementBatches(ctx context.Context) (_ []settlement.Batch, err error) {
+	defer db.observe("ListSettlementBatches", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListSettlementBatches"), logger.Err(err))
+		return nil, err
+	}
+
+	var records []settlement.Batch
+	if err := tx.Order("cut_off DESC").Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListSettlementBatches"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//GetSettlementBatchByID fetches a settlement batch with its totals
+func (db *Database) GetSettlementBatchByID(ctx context.Context, id string) (_ *settlement.Batch, _ []settlement.Total, err error) {
+	defer db.observe("GetSettlementBatchByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetSettlementBatchByID"), logger.Err(err))
+		return nil, nil, err
+	}
+
+	var record settlement.Batch
+	if err := tx.Where("id = ?", id).First(&record).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	var totals []settlement.Total
+	if err := tx.Where("batch_id = ?", id).Order("merchant_id, currency").Find(&totals).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetSettlementBatchByID"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return &record, totals, tx.Commit().Error
+}
+
+//GetSettlementBatchByCutOff fetches the settlement batch closed at the given cut-off
+func (db *Database) GetSettlementBatchByCutOff(ctx context.Context, cutOff time.Time) (_ *settlement.Batch, err error) {
+	defer db.observe("GetSettlementBatchByCutOff", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetSettlementBatchByCutOff"), logger.Err(err))
+		return nil, err
+	}
+
+	var record settlement.Batch
+	if err := tx.Where("cut_off = ?", cutOff.UTC()).First(&record).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &record, tx.Commit().Error
+}
+
+//SubmitSettlementBatch records the submission of a closed settlement batch to the acquirer.
+//The record not found error is returned when the batch is not closed
+func (db *Database) SubmitSettlementBatch(ctx context.Context, data *settlement.Batch) (err error) {
+	defer db.observe("SubmitSettlementBatch", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SubmitSettlementBatch"), logger.Err(err))
+		return err
+	}
+
+	result := tx.Model(&settlement.Batch{}).Where("id = ? AND state = ?", data.ID, "closed").Updates(map[string]interface{}{
+		"state":        data.State,
+		"submitted_at": data.SubmittedAt,
+		"updated_at":   data.UpdatedAt,
+	})
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SubmitSettlementBatch"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	return tx.Commit().Error
+}
+
+//ReopenSettlementBatch removes a closed settlement batch and its totals, its captures and refunds are released to be
+//settled in the next batch. The record not found error is returned when the batch is not closed, a submitted batch is never reopened
+func (db *Database) ReopenSettlementBatch(ctx context.Context, id string) (err error) {
+	defer db.observe("ReopenSettlementBatch", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ReopenSettlementBatch"), logger.Err(err))
+		return err
+	}
+
+	//the closed state is checked by the delete itself so that a batch being submitted cannot be reopened
+	result := tx.Where("id = ? AND state = ?", id, "closed").Delete(&settlement.Batch{})
+	if err := result.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ReopenSettlementBatch"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := tx.Where("batch_id = ?", id).Delete(&settlement.Total{}).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ReopenSettlementBatch"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&operation.Operation{}).Where("batch_id = ?", id).Update("batch_id", "").Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ReopenSettlementBatch"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//ListSettlementOperations fetches the captures and refunds settled in a batch, ordered as they were made
+func (db *Database) ListSettlementOperations(ctx context.Context, id string) (_ []operation.Operation, err error) {
+	defer db.observe("ListSettlementOperations", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListSettlementOperations"), logger.Err(err))
+		return nil, err
+	}
+
+	var records []operation.Operation
+	if err := tx.Where("batch_id = ?", id).Order("id").Find(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ListSettlementOperations"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
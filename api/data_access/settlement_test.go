@@ -0,0 +1,81 @@
+package data_access
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"testing"
+	"time"
+)
+
+func TestDatabase_SettlementBatches_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	authorisations := []auth.Auth{
+		{ID: "c1d2e3f4-0a1b-4c2d-8e3f-000000000001", MerchantID: "acme", Currency: "GBP"},
+		{ID: "c1d2e3f4-0a1b-4c2d-8e3f-000000000002", MerchantID: "acme", Currency: "EUR"},
+		{ID: "c1d2e3f4-0a1b-4c2d-8e3f-000000000003", MerchantID: "globex", Currency: "GBP"},
+	}
+	for i := range authorisations {
+		authorisations[i].Number = "4000056655665556"
+		authorisations[i].ExpiryDate = "12-2099"
+		authorisations[i].AuthorisedAmount = 100
+		authorisations[i].AvailableAmount = 100
+		assert.Nil(t, db.InsertAuthRecord(context.Background(), &authorisations[i]))
+	}
+	//acme captures 60 GBP and refunds 15.5 of them, captures 100 EUR, and globex captures 20.25 GBP
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: authorisations[0].ID, AvailableAmount: 100}, 40, "capture", 60, 1.2))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: authorisations[0].ID, AvailableAmount: 40}, 55.5, "refund", 15.5, -0.31))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: authorisations[1].ID, AvailableAmount: 100}, 0, "capture", 100, 0))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: authorisations[2].ID, AvailableAmount: 100}, 79.75, "capture", 20.25, 0))
+
+	batch := settlement.Batch{ID: "b1d2e3f4-0a1b-4c2d-8e3f-000000000001", CutOff: now.Add(time.Hour), State: "closed"}
+	totals, err := db.CloseSettlementBatch(context.Background(), &batch)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, batch.Operations)
+	assert.EqualValues(t, []settlement.Total{
+		{ID: totals[0].ID, BatchID: batch.ID, MerchantID: "acme", Currency: "EUR", Captures: 1, CapturedAmount: 100},
+		{ID: totals[1].ID, BatchID: batch.ID, MerchantID: "acme", Currency: "GBP", Captures: 1, CapturedAmount: 60, Refunds: 1, RefundedAmount: 15.5, Fees: 0.89},
+		{ID: totals[2].ID, BatchID: batch.ID, MerchantID: "globex", Currency: "GBP", Captures: 1, CapturedAmount: 20.25},
+	}, totals)
+
+	//a cut-off is closed once and the operations are settled once
+	duplicate := settlement.Batch{ID: "b1d2e3f4-0a1b-4c2d-8e3f-000000000002", CutOff: now.Add(time.Hour), State: "closed"}
+	_, err = db.CloseSettlementBatch(context.Background(), &duplicate)
+	assert.NotNil(t, err)
+	next := settlement.Batch{ID: "b1d2e3f4-0a1b-4c2d-8e3f-000000000003", CutOff: now.Add(25 * time.Hour), State: "closed"}
+	totals, err = db.CloseSettlementBatch(context.Background(), &next)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, next.Operations)
+	assert.Empty(t, totals)
+
+	found, err := db.GetSettlementBatchByCutOff(context.Background(), now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.EqualValues(t, batch.ID, found.ID)
+	batches, err := db.ListSettlementBatches(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, len(batches))
+	assert.EqualValues(t, next.ID, batches[0].ID)
+
+	//a submitted batch cannot be reopened
+	batch.State = "submitted"
+	batch.SubmittedAt = now
+	assert.Nil(t, db.SubmitSettlementBatch(context.Background(), &batch))
+	assert.EqualValues(t, "record not found", db.SubmitSettlementBatch(context.Background(), &batch).Error())
+	assert.EqualValues(t, "record not found", db.ReopenSettlementBatch(context.Background(), batch.ID).Error())
+
+	//a closed one is discarded, its operations are settled by the next batch
+	assert.Nil(t, db.ReopenSettlementBatch(context.Background(), next.ID))
+	_, _, err = db.GetSettlementBatchByID(context.Background(), next.ID)
+	assert.EqualValues(t, "record not found", err.Error())
+	stored, totals, err := db.GetSettlementBatchByID(context.Background(), batch.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "submitted", stored.State)
+	assert.EqualValues(t, 3, len(totals))
+}
@@ -0,0 +1,274 @@
+package data_access
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/transaction"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"time"
+)
+
+//transactionState is a state of the transactions and the condition the transactions in it meet, the states are
+//checked in order and a transaction is in the first one whose condition it meets
+type transactionState struct {
+	name      string
+	condition string
+}
+
+var (
+	transactionStates = []transactionState{
+		{"voided", "auths.deleted_at <> ?"},
+		{"pending_review", "EXISTS (SELECT 1 FROM reviews WHERE reviews.auth_id = auths.id AND reviews.state = 'pending')"},
+		{"charged_back", "auths.charged_back_amount > 0"},
+		{"refunded", operationExists("'refund'")},
+		{"captured", operationExists("'capture'")},
+	}
+	authorisedState = "authorised"
+
+	//sortColumns are the columns the transactions can be sorted on, by sort
+	sortColumns = map[string]string{
+		"created_at": "auths.created_at",
+		"amount":     "auths.authorised_amount",
+	}
+)
+
+//operationExists is the condition of the transactions having an operation of the given name
+func operationExists(name string) string {
+	return "EXISTS (SELECT 1 FROM operations WHERE operations.auth_id = auths.id AND operations.name = " + name +
+		" AND operations.deleted_at IS NULL)"
+}
+
+//operationSum is the sum of a column over the operations of the transaction with one of the given names
+func operationSum(column string, names string) string {
+	return "(SELECT COALESCE(SUM(operations." + column + "), 0) FROM operations WHERE operations.auth_id = auths.id" +
+		" AND operations.name IN (" + names + ") AND operations.deleted_at IS NULL)"
+}
+
+//stateQuery returns the expression of the state of the transactions, or the condition of the transactions in the
+//given state, with their arguments
+func stateQuery(state string) (string, []interface{}) {
+	var query strings.Builder
+	var args []interface{}
+	if state == "" {
+		query.WriteString("CASE")
+	}
+	for _, s := range transactionStates {
+		if strings.Contains(s.condition, "?") {
+			args = append(args, time.Time{})
+		}
+		switch {
+		case state == "":
+			query.WriteString(" WHEN " + s.condition + " THEN '" + s.name + "'")
+		case state == s.name:
+			query.WriteString(s.condition)
+			return query.String(), args
+		default:
+			query.WriteString("NOT " + s.condition + " AND ")
+		}
+	}
+	if state == "" {
+		query.WriteString(" ELSE '" + authorisedState + "' END")
+		return query.String(), args
+	}
+	//the authorised transactions are those in none of the other states
+	return strings.TrimSuffix(query.String(), " AND "), args
+}
+
+//searchTransactions builds the query of the transactions, with their state and the amounts of their operations
+func searchTransactions(tx *gorm.DB) *gorm.DB {
+	state, args := stateQuery("")
+	return tx.Table("auths").Select("auths.*, "+state+" AS state, "+
+		operationSum("processed_amount", "'capture'")+" AS captured_amount, "+
+		operationSum("processed_amount", "'refund'")+" AS refunded_amount, "+
+		operationSum("fee", "'capture', 'refund'")+" AS fees", args...)
+}
+
+//SearchTransactions fetches the transactions matching the filter in the order it sets, one page of at most
+//its limit at a time
+func (db *Database) SearchTransactions(ctx context.Context, filter transaction.Filter) (_ []transaction.Transaction, err error) {
+	defer db.observe("SearchTransactions", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SearchTransactions"), logger.Err(err))
+		return nil, err
+	}
+
+	query := searchTransactions(tx)
+	if !filter.From.IsZero() {
+		query = query.Where("auths.created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("auths.created_at < ?", filter.To.UTC())
+	}
+	if filter.State != "" {
+		condition, args := stateQuery(filter.State)
+		query = query.Where(condition, args...)
+	}
+	if filter.Currency != "" {
+		query = query.Where("auths.currency = ?", filter.Currency)
+	}
+	if filter.MinAmount > 0 {
+		query = query.Where("auths.authorised_amount >= ?", filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		query = query.Where("auths.authorised_amount <= ?", filter.MaxAmount)
+	}
+	if filter.CardBIN != "" {
+		//the bin column holds the first six digits, longer bins are checked on the card number
+		query = query.Where("auths.card_bin = ? AND auths.number LIKE ?", filter.CardBIN[:6], filter.CardBIN+"%")
+	}
+	if filter.CardLast4 != "" {
+		query = query.Where("auths.card_last4 = ?", filter.CardLast4)
+	}
+	if filter.MerchantID != "" || filter.Scoped {
+		query = query.Where("auths.merchant_id = ?", filter.MerchantID)
+	}
+	switch filter.Operation {
+	case "":
+	case "void":
+		query = query.Where("auths.deleted_at <> ?", time.Time{})
+	default:
+		query = query.Where(operationExists("?"), filter.Operation)
+	}
+
+	column, direction, comparison := sortColumns[filter.Sort], "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.After.ID != "" {
+		var after interface{} = filter.After.CreatedAt
+		if filter.Sort == "amount" {
+			after = filter.After.Amount
+		}
+		query = query.Where("("+column+" "+comparison+" ? OR ("+column+" = ? AND auths.id "+comparison+" ?))", after, after, filter.After.ID)
+	}
+
+	var records []transaction.Transaction
+	err = query.Order(column + " " + direction).Order("auths.id " + direction).Limit(filter.Limit).Scan(&records).Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "SearchTransactions"), logger.Err(err))
+		tx.Rollback()
+		return nil, err
+	}
+
+	return records, tx.Commit().Error
+}
+
+//GetTransactionByID fetches a transaction with its operations in the order they were made,
+//the record not found error is returned when there is none
+func (db *Database) GetTransactionByID(ctx context.Context, id string) (_ *transaction.Transaction, _ []operation.Operation, err error) {
+	defer db.observe("GetTransactionByID", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetTransactionByID"), logger.Err(err))
+		return nil, nil, err
+	}
+
+	var records []transaction.Transaction
+	if err := searchTransactions(tx).Where("auths.id = ?", id).Scan(&records).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetTransactionByID"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		tx.Rollback()
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+
+	var operations []operation.Operation
+	if err := tx.Where("auth_id = ?", id).Order("id").Find(&operations).Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "GetTransactionByID"), logger.Err(err))
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return &records[0], operations, tx.Commit().Error
+}
+
+//ExportEntries reads the operations made from the start to the end of the period, the start included, and the voids
+//of the period in the order they were made and hands them to fn one at a time. The rows are read as they are handed
+//so that the export of a long period is never held in memory, an error returned by fn stops the export
+func (db *Database) ExportEntries(ctx context.Context, from time.Time, to time.Time, fn func(*transaction.Entry) error) (err error) {
+	defer db.observe("ExportEntries", time.Now(), &err)
+
+	tx := db.Db.BeginTx(ctx, nil)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Error; err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ExportEntries"), logger.Err(err))
+		return err
+	}
+
+	state, stateArgs := stateQuery("")
+	columns := "auths.merchant_id, auths.card_bin, auths.card_last4, length(auths.number) AS card_length, " +
+		"auths.authorised_amount, auths.currency, auths.created_at AS auth_created_at, " + state + " AS state, "
+	query := "SELECT * FROM (SELECT operations.auth_id, " + columns +
+		"operations.name, operations.processed_amount, operations.fee, operations.created_at, operations.id AS sequence" +
+		" FROM operations JOIN auths ON auths.id = operations.auth_id" +
+		" WHERE operations.deleted_at IS NULL AND operations.created_at >= ? AND operations.created_at < ?" +
+		//a void releases what is left of the authorised amount
+		" UNION ALL SELECT auths.id, " + columns + "'void', auths.available_amount, 0, auths.deleted_at, 0 FROM auths" +
+		" WHERE auths.deleted_at >= ? AND auths.deleted_at < ?) ORDER BY created_at, auth_id, sequence"
+	args := append(append([]interface{}{}, stateArgs...), from.UTC(), to.UTC())
+	args = append(append(args, stateArgs...), from.UTC(), to.UTC())
+
+	rows, err := tx.Raw(query, args...).Rows()
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ExportEntries"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	//the rows hold the transaction until they are closed, they are closed before it ends
+	for rows.Next() {
+		var entry transaction.Entry
+		if err := tx.ScanRows(rows, &entry); err != nil {
+			db.logger.Error("database call failed", logger.String("call", "ExportEntries"), logger.Err(err))
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		db.logger.Error("database call failed", logger.String("call", "ExportEntries"), logger.Err(err))
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+//fillCardDigits stores the BIN and last four digits of the card numbers of the authorisations made before they were kept
+func (db *Database) fillCardDigits() error {
+	err := db.Db.Exec("UPDATE auths SET card_bin = substr(number, 1, 6), card_last4 = substr(number, -4)" +
+		" WHERE (card_last4 IS NULL OR card_last4 = '') AND length(number) >= 10").Error
+	if err != nil {
+		db.logger.Error("database call failed", logger.String("call", "fillCardDigits"), logger.Err(err))
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package data_access
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/data_access/database_model/transaction"
+	"testing"
+	"time"
+)
+
+func TestDatabase_SearchTransactions_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	newAuth := func(id string, number string, amount float32, currency string, merchantID string, at time.Time) auth.Auth {
+		return auth.Auth{
+			ID:               id,
+			MerchantID:       merchantID,
+			Number:           number,
+			ExpiryDate:       "12-2099",
+			AuthorisedAmount: amount,
+			AvailableAmount:  amount,
+			Currency:         currency,
+			CreatedAt:        at,
+			UpdatedAt:        at,
+		}
+	}
+	ids := []string{
+		"c1000000-0000-4000-8000-000000000001",
+		"c1000000-0000-4000-8000-000000000002",
+		"c1000000-0000-4000-8000-000000000003",
+		"c1000000-0000-4000-8000-000000000004",
+		"c1000000-0000-4000-8000-000000000005",
+	}
+	records := []auth.Auth{
+		newAuth(ids[0], "4929907390318794", 100, "GBP", "acme", now),
+		newAuth(ids[1], "4929907390318794", 50, "GBP", "acme", now.Add(time.Hour)),
+		newAuth(ids[2], "5555555555554444", 75, "EUR", "acme", now.Add(2*time.Hour)),
+		newAuth(ids[3], "5555555555554444", 75, "EUR", "globex", now.Add(2*time.Hour)),
+	}
+	for i := range records {
+		assert.Nil(t, db.InsertAuthRecord(context.Background(), &records[i]))
+	}
+	pending := newAuth(ids[4], "4929907390318794", 600, "GBP", "acme", now.Add(3*time.Hour))
+	assert.Nil(t, db.InsertPendingAuthRecord(context.Background(), &pending, &review.Review{AuthID: ids[4], State: "pending", Amount: 600, Currency: "GBP"}))
+
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: ids[0], AvailableAmount: 100}, 40, "capture", 60, 1.2))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: ids[0], AvailableAmount: 40}, 50, "refund", 10, 0))
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: ids[1], AvailableAmount: 50}, 20, "capture", 30, 0.5))
+	assert.Nil(t, db.SoftDeleteAuthRecordByID(context.Background(), ids[2]))
+
+	search := func(filter transaction.Filter) []string {
+		if filter.Sort == "" {
+			filter.Sort = "created_at"
+		}
+		if filter.Limit == 0 {
+			filter.Limit = 10
+		}
+		found, err := db.SearchTransactions(context.Background(), filter)
+		assert.Nil(t, err)
+		result := make([]string, 0, len(found))
+		for _, record := range found {
+			result = append(result, record.ID)
+		}
+		return result
+	}
+
+	assert.EqualValues(t, ids, search(transaction.Filter{}))
+	assert.EqualValues(t, []string{ids[4], ids[3], ids[2], ids[1], ids[0]}, search(transaction.Filter{Descending: true}))
+	assert.EqualValues(t, []string{ids[0]}, search(transaction.Filter{State: "refunded"}))
+	assert.EqualValues(t, []string{ids[1]}, search(transaction.Filter{State: "captured"}))
+	assert.EqualValues(t, []string{ids[2]}, search(transaction.Filter{State: "voided"}))
+	assert.EqualValues(t, []string{ids[3]}, search(transaction.Filter{State: "authorised"}))
+	assert.EqualValues(t, []string{ids[4]}, search(transaction.Filter{State: "pending_review"}))
+	assert.EqualValues(t, []string{ids[0], ids[1]}, search(transaction.Filter{Operation: "capture"}))
+	assert.EqualValues(t, []string{ids[2]}, search(transaction.Filter{Operation: "void"}))
+	assert.EqualValues(t, []string{ids[2], ids[3]}, search(transaction.Filter{Currency: "EUR"}))
+	assert.EqualValues(t, []string{ids[0], ids[2], ids[3]}, search(transaction.Filter{MinAmount: 75, MaxAmount: 100}))
+	assert.EqualValues(t, []string{ids[3]}, search(transaction.Filter{MerchantID: "globex"}))
+	assert.EqualValues(t, []string{ids[3]}, search(transaction.Filter{MerchantID: "globex", Scoped: true}))
+	assert.EqualValues(t, 0, len(search(transaction.Filter{Scoped: true})))
+	assert.EqualValues(t, []string{ids[2], ids[3]}, search(transaction.Filter{CardLast4: "4444"}))
+	assert.EqualValues(t, []string{ids[0], ids[1], ids[4]}, search(transaction.Filter{CardBIN: "49299073"}))
+	assert.EqualValues(t, 0, len(search(transaction.Filter{CardBIN: "49299074"})))
+	assert.EqualValues(t, []string{ids[1], ids[2], ids[3]}, search(transaction.Filter{From: now.Add(time.Hour), To: now.Add(3 * time.Hour)}))
+
+	//the transactions with the same amount are ordered by id and the pages follow each other without gaps
+	assert.EqualValues(t, []string{ids[4], ids[0], ids[3]}, search(transaction.Filter{Sort: "amount", Descending: true, Limit: 3}))
+	assert.EqualValues(t, []string{ids[2], ids[1]}, search(transaction.Filter{Sort: "amount", Descending: true,
+		After: transaction.Cursor{ID: ids[3], Amount: 75}}))
+	assert.EqualValues(t, []string{ids[2], ids[3], ids[4]}, search(transaction.Filter{After: transaction.Cursor{ID: ids[1], CreatedAt: now.Add(time.Hour)}}))
+
+	record, operations, err := db.GetTransactionByID(context.Background(), ids[0])
+	assert.Nil(t, err)
+	assert.EqualValues(t, "refunded", record.State)
+	assert.EqualValues(t, "492990", record.CardBIN)
+	assert.EqualValues(t, "8794", record.CardLast4)
+	assert.EqualValues(t, 60, record.CapturedAmount)
+	assert.EqualValues(t, 10, record.RefundedAmount)
+	assert.EqualValues(t, float32(1.2), record.Fees)
+	assert.EqualValues(t, 3, len(operations))
+	assert.EqualValues(t, "authorisation", operations[0].Name)
+
+	_, _, err = db.GetTransactionByID(context.Background(), "c1000000-0000-4000-8000-000000000009")
+	assert.EqualValues(t, "record not found", err.Error())
+}
+
+func TestDatabase_FillCardDigits_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+
+	record := auth.Auth{ID: "c2000000-0000-4000-8000-000000000001", Number: "4929907390318794", AuthorisedAmount: 10, AvailableAmount: 10, Currency: "GBP", CreatedAt: now}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &record))
+	//the authorisations stored before the digits were kept have none
+	assert.Nil(t, db.Db.Exec("UPDATE auths SET card_bin = NULL, card_last4 = NULL").Error)
+
+	assert.Nil(t, db.fillCardDigits())
+	found, err := db.SearchTransactions(context.Background(), transaction.Filter{CardLast4: "8794", Sort: "created_at", Limit: 10})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(found))
+	assert.EqualValues(t, "492990", found[0].CardBIN)
+}
+
+func TestDatabase_ExportEntries_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	t.Parallel()
+	db, teardown := newTestDb(t)
+	defer teardown()
+	clk := db.clock.(*clock.FakeClock)
+
+	captured := auth.Auth{ID: "e1000000-0000-4000-8000-000000000001", MerchantID: "acme", Number: "4929907390318794",
+		ExpiryDate: "12-2099", AuthorisedAmount: 100, AvailableAmount: 100, Currency: "GBP", CreatedAt: now, UpdatedAt: now}
+	voided := auth.Auth{ID: "e1000000-0000-4000-8000-000000000002", MerchantID: "acme", Number: "5555555555554444",
+		ExpiryDate: "12-2099", AuthorisedAmount: 30, AvailableAmount: 30, Currency: "EUR", CreatedAt: now, UpdatedAt: now}
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &captured))
+	assert.Nil(t, db.InsertAuthRecord(context.Background(), &voided))
+	clk.Advance(time.Hour)
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: captured.ID, AvailableAmount: 100}, 40, "capture", 60, 1.2))
+	clk.Advance(time.Hour)
+	assert.Nil(t, db.SoftDeleteAuthRecordByID(context.Background(), voided.ID))
+	//the refund is made after the end of the period
+	clk.Advance(24 * time.Hour)
+	assert.Nil(t, db.UpdateAvailableAmountByAuthID(context.Background(), &auth.Auth{ID: captured.ID, AvailableAmount: 40}, 50, "refund", 10, 0))
+
+	var entries []transaction.Entry
+	err := db.ExportEntries(context.Background(), now, now.Add(24*time.Hour), func(entry *transaction.Entry) error {
+		if entry.AuthID == captured.ID || entry.AuthID == voided.ID {
+			entries = append(entries, *entry)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, len(entries))
+	assert.EqualValues(t, []string{"authorisation", "authorisation", "capture", "void"},
+		[]string{entries[0].Name, entries[1].Name, entries[2].Name, entries[3].Name})
+
+	capture := entries[2]
+	assert.EqualValues(t, captured.ID, capture.AuthID)
+	assert.EqualValues(t, "acme", capture.MerchantID)
+	assert.EqualValues(t, "492990", capture.CardBIN)
+	assert.EqualValues(t, "8794", capture.CardLast4)
+	assert.EqualValues(t, 16, capture.CardLength)
+	assert.EqualValues(t, 100, capture.AuthorisedAmount)
+	assert.EqualValues(t, "refunded", capture.State)
+	assert.EqualValues(t, 60, capture.ProcessedAmount)
+	assert.EqualValues(t, 1.2, capture.Fee)
+	assert.True(t, now.Add(time.Hour).Equal(capture.CreatedAt))
+	assert.True(t, now.Equal(capture.AuthCreatedAt))
+
+	void := entries[3]
+	assert.EqualValues(t, voided.ID, void.AuthID)
+	assert.EqualValues(t, "voided", void.State)
+	assert.EqualValues(t, 30, void.ProcessedAmount)
+	assert.True(t, now.Add(2*time.Hour).Equal(void.CreatedAt))
+
+	//an error of the caller stops the export and leaves the db usable
+	calls := 0
+	err = db.ExportEntries(context.Background(), now, now.Add(24*time.Hour), func(entry *transaction.Entry) error {
+		calls++
+		return errors.New("broken pipe")
+	})
+	assert.EqualValues(t, "broken pipe", err.Error())
+	assert.EqualValues(t, 1, calls)
+	_, _, err = db.GetTransactionByID(context.Background(), captured.ID)
+	assert.Nil(t, err)
+}
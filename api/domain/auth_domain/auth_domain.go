@@ -3,13 +3,40 @@ package auth_domain
 import (
 	"errors"
 	"github.com/joeljunstrom/go-luhn"
-	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/clock"
 	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
 	"payment-gateway-api/api/domain/common_validation"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+const (
+	//StatusApproved is the status of the authorisations that can be captured straight away
+	StatusApproved = "approved"
+	//StatusPendingReview is the status of the authorisations held by the fraud rules until an analyst approves them
+	StatusPendingReview = "pending_review"
+	//StatusRequiresAction is the status of the authorisations waiting for the cardholder to complete a 3-D Secure challenge
+	StatusRequiresAction = "requires_action"
+
+	//AVSMatch is the result of the address verification when the issuer has the same address line or postcode on file
+	AVSMatch = "match"
+	//AVSNoMatch is the result of the address verification when the issuer has another address line or postcode on file
+	AVSNoMatch = "no_match"
+	//AVSNotChecked is the result of the address verification of the authorisations made without a billing address
+	AVSNotChecked = "not_checked"
+
+	BrandVisa       = "visa"
+	BrandMastercard = "mastercard"
+	BrandAmex       = "amex"
+	BrandDiscover   = "discover"
+	BrandUnknown    = "unknown"
+
+	//ResourcePath is the path of the authorisations resource of the versioned API
+	ResourcePath = "/v1/authorisations"
+)
+
 //AuthRequest is the format for the request by the authorisation endpoint
 type AuthRequest struct {
 	CardDetails CardDetails `json:"card_details" binding:"required"`
@@ -22,53 +49,162 @@ type CardDetails struct {
 	Number     string `json:"card_number"`
 	ExpiryDate string `json:"expiry_date"`
 	Cvv        string `json:"cvv"`
+	//CardholderName and BillingAddress are optional, the billing address is verified with the issuer when given
+	CardholderName string          `json:"cardholder_name"`
+	BillingAddress *BillingAddress `json:"billing_address"`
+}
+
+//BillingAddress is the address the issuer sends the statements of the card to
+type BillingAddress struct {
+	Line1    string `json:"line1"`
+	Line2    string `json:"line2"`
+	City     string `json:"city"`
+	Postcode string `json:"postcode"`
+	Country  string `json:"country"`
+}
+
+//StoredCardAuthRequest is the format for merchant initiated authorisations against a card on file,
+//the cvv is not part of it since it is never stored
+type StoredCardAuthRequest struct {
+	MerchantID string
+	Number     string
+	ExpiryDate string
+	Amount     float32
+	Currency   string
 }
 
 //AuthResponse is the format for the response by the authorisation endpoint
 type AuthResponse struct {
-	AuthID    string  `json:"id"`
-	IsSuccess bool    `json:"success"`
-	Amount    float32 `json:"amount"`
-	Currency  string  `json:"currency"`
+	AuthID         string     `json:"id"`
+	IsSuccess      bool       `json:"success"`
+	Status         string     `json:"status"`
+	Amount         float32    `json:"amount"`
+	Currency       string     `json:"currency"`
+	ChallengeURL   string     `json:"challenge_url,omitempty"`
+	LiabilityShift bool       `json:"liability_shift"`
+	AVS            *AVSResult `json:"avs,omitempty"`
+}
+
+//AVSResult is how the issuer matched the billing address line and postcode given with the card
+type AVSResult struct {
+	Address  string `json:"address"`
+	Postcode string `json:"postcode"`
 }
 
 //ValidateFields strips all spaces from strings and checks their validity
-func (r *AuthRequest) ValidateFields() []error {
+func (r *AuthRequest) ValidateFields(clk clock.Clock) []error {
 	var err = make([]error, 0)
 	r.CardDetails.Number = strings.Replace(r.CardDetails.Number, " ", "", -1)
 	if !isCardNumberValid(r.CardDetails.Number) {
 		err = append(err, errors.New(error_constant.InvalidCardNumber))
 	}
 	r.CardDetails.ExpiryDate = strings.Replace(r.CardDetails.ExpiryDate, " ", "", -1)
-	if !common_validation.IsExpiryDateValid(r.CardDetails.ExpiryDate) {
+	if !common_validation.IsExpiryDateValid(r.CardDetails.ExpiryDate, clk.Now()) {
 		err = append(err, errors.New(error_constant.InvalidCardExpiryDate))
 	}
 	r.CardDetails.Cvv = strings.Replace(r.CardDetails.Cvv, " ", "", -1)
 	if !isCvvValid(r.CardDetails.Cvv) {
 		err = append(err, errors.New(error_constant.InvalidCvv))
 	}
+	r.CardDetails.CardholderName = strings.Join(strings.Fields(r.CardDetails.CardholderName), " ")
+	if r.CardDetails.CardholderName != "" && !isCardholderNameValid(r.CardDetails.CardholderName) {
+		err = append(err, errors.New(error_constant.InvalidCardholderName))
+	}
+	if r.CardDetails.BillingAddress != nil {
+		err = append(err, r.CardDetails.BillingAddress.validateFields()...)
+	}
 	if !common_validation.IsAmountValid(r.Amount) {
 		err = append(err, errors.New(error_constant.InvalidAmount))
 	}
-	if !isCurrencyCodeValid(r.Currency) {
+	if !common_validation.IsCurrencyCodeValid(r.Currency) {
 		err = append(err, errors.New(error_constant.InvalidCurrencyCode))
 	}
 	return err
 }
 
+//validateFields trims the address and checks its postcode has the format of the postcodes of its country
+func (a *BillingAddress) validateFields() []error {
+	var err = make([]error, 0)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	if a.Line1 == "" {
+		err = append(err, errors.New(error_constant.InvalidBillingAddressLine))
+	}
+	a.Country = strings.ToUpper(strings.Replace(a.Country, " ", "", -1))
+	if !common_validation.IsCountryCodeValid(a.Country) {
+		err = append(err, errors.New(error_constant.InvalidBillingCountry))
+		return err
+	}
+	a.Postcode = strings.ToUpper(strings.TrimSpace(a.Postcode))
+	if !common_validation.IsPostcodeValid(a.Postcode, a.Country) {
+		err = append(err, errors.New(error_constant.InvalidPostcode))
+	}
+	return err
+}
+
+//isCardholderNameValid checks the name is made of letters, spaces and the punctuation found in names
+func isCardholderNameValid(name string) bool {
+	isValid, _ := regexp.MatchString(format_constant.CardholderNameLayout, name)
+	return isValid
+}
+
 //isCardNumberValid checks the card number validity using the Luhn algorithm
 func isCardNumberValid(cardNumber string) bool {
 	return luhn.Valid(cardNumber)
 }
 
+//CardBrand returns the network of the card from the leading digits of its number, unknown when none matches
+func CardBrand(cardNumber string) string {
+	prefix := func(length int) int {
+		if len(cardNumber) < length {
+			return -1
+		}
+		value, err := strconv.Atoi(cardNumber[:length])
+		if err != nil {
+			return -1
+		}
+		return value
+	}
+
+	switch {
+	case prefix(1) == 4:
+		return BrandVisa
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return BrandMastercard
+	case prefix(2) == 34, prefix(2) == 37:
+		return BrandAmex
+	case prefix(4) == 6011, prefix(2) == 65, prefix(3) >= 644 && prefix(3) <= 649:
+		return BrandDiscover
+	}
+	return BrandUnknown
+}
+
 //isCvvValid checks that the CVV is made of 3 or 4 integers
 func isCvvValid(cvv string) bool {
-	isValid, _ := regexp.MatchString(config.CvvFormatLayout, cvv)
+	isValid, _ := regexp.MatchString(format_constant.CvvFormatLayout, cvv)
 	return isValid
 }
 
-//isCurrencyCodeValid checks the currency is a 3 letter string
-func isCurrencyCodeValid(currency string) bool {
-	isValid, _ := regexp.MatchString(config.CurrencyCodeLayout, currency)
-	return isValid
+//ValidateFields checks the validity of the stored card authorisation fields
+func (r *StoredCardAuthRequest) ValidateFields(clk clock.Clock) []error {
+	var err = make([]error, 0)
+	if !isCardNumberValid(r.Number) {
+		err = append(err, errors.New(error_constant.InvalidCardNumber))
+	}
+	if !common_validation.IsExpiryDateValid(r.ExpiryDate, clk.Now()) {
+		err = append(err, errors.New(error_constant.InvalidCardExpiryDate))
+	}
+	if !common_validation.IsAmountValid(r.Amount) {
+		err = append(err, errors.New(error_constant.InvalidAmount))
+	}
+	if !common_validation.IsCurrencyCodeValid(r.Currency) {
+		err = append(err, errors.New(error_constant.InvalidCurrencyCode))
+	}
+	return err
+}
+
+//Location returns the URL of the authorisation in the versioned API, its captures, refunds and void are listed with it
+func Location(id string) string {
+	return ResourcePath + "/" + id
 }
@@ -4,11 +4,18 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/clock"
 	"payment-gateway-api/api/const/error_constant"
 	"testing"
+	"time"
+)
+
+var (
+	now = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
 )
 
 func TestAuthResponse(t *testing.T) {
+	t.Parallel()
 	expectedResponse := AuthResponse{
 		AuthID:    "123987-644ef1sdf-wf6d1fs1fr4w6f-df6ws54ef1",
 		IsSuccess: true,
@@ -29,6 +36,7 @@ func TestAuthResponse(t *testing.T) {
 }
 
 func TestAuthRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
 	cardDetails := CardDetails{
 		Number:     "4929907390318797",
 		ExpiryDate: "01-1900",
@@ -47,15 +55,16 @@ func TestAuthRequest_ValidateFields_Invalid(t *testing.T) {
 	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidAmount))
 	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidCurrencyCode))
 
-	actualErrors := request.ValidateFields()
+	actualErrors := request.ValidateFields(clock.NewFake(now))
 
 	assert.EqualValues(t, expectedErrors, actualErrors)
 }
 
 func TestAuthRequest_ValidateFields_Valid(t *testing.T) {
+	t.Parallel()
 	cardDetails := CardDetails{
 		Number:     "4929907390318794",
-		ExpiryDate: "12-3500",
+		ExpiryDate: "12-2021",
 		Cvv:        "123",
 	}
 	request := AuthRequest{
@@ -64,7 +73,112 @@ func TestAuthRequest_ValidateFields_Valid(t *testing.T) {
 		Currency:    "GBP",
 	}
 
-	actualErrors := request.ValidateFields()
+	actualErrors := request.ValidateFields(clock.NewFake(now))
+
+	assert.EqualValues(t, []error{}, actualErrors)
+}
+
+func TestAuthRequest_ValidateFields_Cardholder(t *testing.T) {
+	t.Parallel()
+	request := AuthRequest{
+		CardDetails: CardDetails{
+			Number:         "4929907390318794",
+			ExpiryDate:     "12-2021",
+			Cvv:            "123",
+			CardholderName: "  Zoë   O'Brien-Smith ",
+			BillingAddress: &BillingAddress{
+				Line1:    " 10 Downing Street ",
+				City:     "London",
+				Postcode: "sw1a 2aa",
+				Country:  "gb",
+			},
+		},
+		Amount:   10,
+		Currency: "GBP",
+	}
+
+	assert.EqualValues(t, []error{}, request.ValidateFields(clock.NewFake(now)))
+	assert.EqualValues(t, "Zoë O'Brien-Smith", request.CardDetails.CardholderName)
+	assert.EqualValues(t, "10 Downing Street", request.CardDetails.BillingAddress.Line1)
+	assert.EqualValues(t, "SW1A 2AA", request.CardDetails.BillingAddress.Postcode)
+	assert.EqualValues(t, "GB", request.CardDetails.BillingAddress.Country)
+}
+
+func TestAuthRequest_ValidateFields_Cardholder_Invalid(t *testing.T) {
+	t.Parallel()
+	request := AuthRequest{
+		CardDetails: CardDetails{
+			Number:         "4929907390318794",
+			ExpiryDate:     "12-2021",
+			Cvv:            "123",
+			CardholderName: "J0hn <Smith>",
+			BillingAddress: &BillingAddress{
+				Postcode: "ABC",
+				Country:  "US",
+			},
+		},
+		Amount:   10,
+		Currency: "GBP",
+	}
+
+	expectedErrors := []error{
+		errors.New(error_constant.InvalidCardholderName),
+		errors.New(error_constant.InvalidBillingAddressLine),
+		errors.New(error_constant.InvalidPostcode),
+	}
+	assert.EqualValues(t, expectedErrors, request.ValidateFields(clock.NewFake(now)))
+
+	request.CardDetails.CardholderName = ""
+	request.CardDetails.BillingAddress = &BillingAddress{Line1: "1 Main Street", Country: "USA"}
+	assert.EqualValues(t, []error{errors.New(error_constant.InvalidBillingCountry)}, request.ValidateFields(clock.NewFake(now)))
+}
+
+func TestStoredCardAuthRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
+	request := StoredCardAuthRequest{
+		Number:     "4929907390318797",
+		ExpiryDate: "01-1900",
+		Amount:     0,
+		Currency:   "gbp",
+	}
+
+	expectedErrors := []error{}
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidCardNumber))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidCardExpiryDate))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidAmount))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidCurrencyCode))
+
+	actualErrors := request.ValidateFields(clock.NewFake(now))
+
+	assert.EqualValues(t, expectedErrors, actualErrors)
+}
+
+func TestStoredCardAuthRequest_ValidateFields_Valid(t *testing.T) {
+	t.Parallel()
+	request := StoredCardAuthRequest{
+		Number:     "4929907390318794",
+		ExpiryDate: "12-2021",
+		Amount:     10,
+		Currency:   "GBP",
+	}
+
+	actualErrors := request.ValidateFields(clock.NewFake(now))
 
 	assert.EqualValues(t, []error{}, actualErrors)
 }
+
+func TestCardBrand(t *testing.T) {
+	t.Parallel()
+	for number, brand := range map[string]string{
+		"4929907390318794": BrandVisa,
+		"5555555555554444": BrandMastercard,
+		"2223003122003222": BrandMastercard,
+		"378282246310005":  BrandAmex,
+		"6011111111111117": BrandDiscover,
+		"6445644564456445": BrandDiscover,
+		"3530111333300000": BrandUnknown,
+		"":                 BrandUnknown,
+	} {
+		assert.EqualValues(t, brand, CardBrand(number), number)
+	}
+}
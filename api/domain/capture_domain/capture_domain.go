@@ -13,11 +13,20 @@ type CaptureRequest struct {
 	Amount float32 `json:"amount" binding:"required"`
 }
 
-//CaptureResponse is the format for the response by the capture endpoint
+//AmountRequest is the format for the request by the captures endpoint of an authorisation, the authorisation ID is
+//taken from the path
+type AmountRequest struct {
+	Amount float32 `json:"amount" binding:"required"`
+}
+
+//CaptureResponse is the format for the response by the capture endpoint, the net amount is what the merchant
+//is paid for the capture once its fee is deducted
 type CaptureResponse struct {
 	IsSuccess bool    `json:"success"`
 	Amount    float32 `json:"amount"`
 	Currency  string  `json:"currency"`
+	Fee       float32 `json:"fee"`
+	NetAmount float32 `json:"net_amount"`
 }
 
 //ValidateFields strips all spaces from strings and checks their validity
@@ -9,6 +9,7 @@ import (
 )
 
 func TestCaptureResponse(t *testing.T) {
+	t.Parallel()
 	expectedResponse := CaptureResponse{
 		IsSuccess: true,
 		Amount:    10,
@@ -28,6 +29,7 @@ func TestCaptureResponse(t *testing.T) {
 }
 
 func TestCaptureRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
 	request := CaptureRequest{
 		AuthId: "invalid_id",
 		Amount: 0,
@@ -43,6 +45,7 @@ func TestCaptureRequest_ValidateFields_Invalid(t *testing.T) {
 }
 
 func TestCaptureRequest_ValidateFields_Valid(t *testing.T) {
+	t.Parallel()
 	request := CaptureRequest{
 		AuthId: "970c8844-9238-4c31-95ca-6f079dd65729",
 		Amount: 10,
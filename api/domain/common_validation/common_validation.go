@@ -1,29 +1,28 @@
 package common_validation
 
 import (
-	"log"
-	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/const/format_constant"
 	"regexp"
 	"time"
 )
 
 //IsValidUUID checks whether the field is in the UUID format
 func IsValidUUID(uuid string) bool {
-	r := regexp.MustCompile(config.UUIDCodeLayout)
+	r := regexp.MustCompile(format_constant.UUIDCodeLayout)
 	return r.MatchString(uuid)
 }
 
-//IsExpiryDateValid checks that the card is not expired
-func IsExpiryDateValid(expiryDate string) bool {
-	expirationDate, err := time.Parse(config.ExpirationDateLayout, expiryDate)
+//IsExpiryDateValid checks that the card is not expired at the given time,
+//a card remains valid until the end of its expiry month
+func IsExpiryDateValid(expiryDate string, now time.Time) bool {
+	//a malformed date is reported to the client by the field validation, there is nothing to log
+	expirationDate, err := time.Parse(format_constant.ExpirationDateLayout, expiryDate)
 	if err != nil {
-		log.Println(err.Error())
 		return false
 	}
 
-	currentTime, err := time.Parse(config.ExpirationDateLayout, time.Now().Format(config.ExpirationDateLayout))
+	currentTime, err := time.Parse(format_constant.ExpirationDateLayout, now.Format(format_constant.ExpirationDateLayout))
 	if err != nil {
-		log.Println(err.Error())
 		return false
 	}
 
@@ -35,6 +34,53 @@ func IsExpiryDateValid(expiryDate string) bool {
 	return true
 }
 
+//IsCurrencyCodeValid checks the currency is a 3 letter string
+func IsCurrencyCodeValid(currency string) bool {
+	isValid, _ := regexp.MatchString(format_constant.CurrencyCodeLayout, currency)
+	return isValid
+}
+
+//IsCountryCodeValid checks the country is a 2 letter ISO 3166 code
+func IsCountryCodeValid(country string) bool {
+	isValid, _ := regexp.MatchString(format_constant.CountryCodeLayout, country)
+	return isValid
+}
+
+//postcodeLayouts are the formats of the postcodes of the countries they are known for
+var postcodeLayouts = map[string]string{
+	"GB": format_constant.GBPostcodeLayout,
+	"US": format_constant.USPostcodeLayout,
+	"CA": format_constant.CAPostcodeLayout,
+	"IE": format_constant.IEPostcodeLayout,
+	"NL": format_constant.NLPostcodeLayout,
+	"JP": format_constant.JPPostcodeLayout,
+	"AT": format_constant.FourDigitsPostcode,
+	"AU": format_constant.FourDigitsPostcode,
+	"BE": format_constant.FourDigitsPostcode,
+	"CH": format_constant.FourDigitsPostcode,
+	"DK": format_constant.FourDigitsPostcode,
+	"NO": format_constant.FourDigitsPostcode,
+	"DE": format_constant.FiveDigitsPostcode,
+	"ES": format_constant.FiveDigitsPostcode,
+	"FI": format_constant.FiveDigitsPostcode,
+	"FR": format_constant.FiveDigitsPostcode,
+	"IT": format_constant.FiveDigitsPostcode,
+}
+
+//IsPostcodeValid checks the postcode has the format of the postcodes of the country, it is required for those
+//countries and any postcode that looks like one is accepted for the others
+func IsPostcodeValid(postcode string, country string) bool {
+	layout, ok := postcodeLayouts[country]
+	if !ok {
+		if postcode == "" {
+			return true
+		}
+		layout = format_constant.OtherPostcodeLayout
+	}
+	isValid, _ := regexp.MatchString(layout, postcode)
+	return isValid
+}
+
 //isAmountValid checks in case amount is negative or zero
 func IsAmountValid(amount float32) bool {
 	return amount > 0
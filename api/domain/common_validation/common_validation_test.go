@@ -0,0 +1,50 @@
+package common_validation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestIsExpiryDateValid_LastDayOfExpiryMonth(t *testing.T) {
+	t.Parallel()
+	lastDayOfMonth := time.Date(2020, time.February, 29, 23, 59, 59, 0, time.UTC)
+	assert.EqualValues(t, true, IsExpiryDateValid("02-2020", lastDayOfMonth))
+}
+
+func TestIsExpiryDateValid_FirstDayAfterExpiryMonth(t *testing.T) {
+	t.Parallel()
+	firstDayOfNextMonth := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+	assert.EqualValues(t, false, IsExpiryDateValid("02-2020", firstDayOfNextMonth))
+}
+
+func TestIsExpiryDateValid_EndOfYear(t *testing.T) {
+	t.Parallel()
+	newYearsEve := time.Date(2020, time.December, 31, 23, 59, 59, 0, time.UTC)
+	assert.EqualValues(t, true, IsExpiryDateValid("12-2020", newYearsEve))
+	assert.EqualValues(t, true, IsExpiryDateValid("01-2021", newYearsEve))
+	assert.EqualValues(t, false, IsExpiryDateValid("12-2020", newYearsEve.Add(time.Second)))
+}
+
+func TestIsPostcodeValid(t *testing.T) {
+	t.Parallel()
+	assert.EqualValues(t, true, IsPostcodeValid("SW1A 1AA", "GB"))
+	assert.EqualValues(t, true, IsPostcodeValid("M1 1AE", "GB"))
+	assert.EqualValues(t, false, IsPostcodeValid("12345", "GB"))
+	assert.EqualValues(t, true, IsPostcodeValid("94105-1804", "US"))
+	assert.EqualValues(t, false, IsPostcodeValid("9410", "US"))
+	assert.EqualValues(t, true, IsPostcodeValid("75008", "FR"))
+	assert.EqualValues(t, true, IsPostcodeValid("1012 AB", "NL"))
+	//the postcode is required in the countries whose format is known
+	assert.EqualValues(t, false, IsPostcodeValid("", "DE"))
+	assert.EqualValues(t, true, IsPostcodeValid("", "AE"))
+	assert.EqualValues(t, true, IsPostcodeValid("110001", "IN"))
+	assert.EqualValues(t, false, IsPostcodeValid("#1", "IN"))
+}
+
+func TestIsExpiryDateValid_InvalidFormat(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	assert.EqualValues(t, false, IsExpiryDateValid("2020-06", now))
+	assert.EqualValues(t, false, IsExpiryDateValid("13-2020", now))
+}
@@ -0,0 +1,137 @@
+package dispute_domain
+
+import (
+	"errors"
+	"io"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/common_validation"
+	"strings"
+)
+
+const (
+	//StateOpened is the state of a dispute the acquirer has just notified
+	StateOpened = "opened"
+	//StateEvidenceRequired is the state of a dispute the acquirer asked the merchant to send evidence for
+	StateEvidenceRequired = "evidence_required"
+	//StateSubmitted is the state of a dispute whose evidence has been submitted to the acquirer
+	StateSubmitted = "submitted"
+	StateWon       = "won"
+	StateLost      = "lost"
+
+	//EventEvidenceAdded is the event of an evidence attached to a dispute, the other events are named after the states
+	EventEvidenceAdded = "evidence_added"
+)
+
+//DisputeRequest is the format for the request by the dispute creation endpoint, it stands for the notification
+//of a chargeback by the acquirer
+type DisputeRequest struct {
+	AuthId string  `json:"auth_id" binding:"required"`
+	Amount float32 `json:"amount" binding:"required"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+//StateRequest is the format for the request by the dispute update endpoint, it stands for the notifications of the
+//acquirer asking for evidence and deciding the dispute
+type StateRequest struct {
+	State  string `json:"state" binding:"required"`
+	Detail string `json:"detail"`
+}
+
+//DisputeResponse is the format for the disputes returned by the dispute endpoints, the evidence and events
+//are only returned with a single dispute
+type DisputeResponse struct {
+	ID         string             `json:"id"`
+	AuthID     string             `json:"auth_id"`
+	MerchantID string             `json:"merchant_id,omitempty"`
+	State      string             `json:"state"`
+	Reason     string             `json:"reason"`
+	Amount     float32            `json:"amount"`
+	Currency   string             `json:"currency"`
+	DueAt      string             `json:"due_at,omitempty"`
+	ResolvedAt string             `json:"resolved_at,omitempty"`
+	CreatedAt  string             `json:"created_at"`
+	Evidence   []EvidenceResponse `json:"evidence,omitempty"`
+	Events     []EventResponse    `json:"events,omitempty"`
+}
+
+//EvidenceResponse is the format for the evidence documents attached to a dispute
+type EvidenceResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	CreatedAt   string `json:"created_at"`
+}
+
+//EventResponse is the format for the events of a dispute
+type EventResponse struct {
+	Type      string `json:"type"`
+	Detail    string `json:"detail,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+//EvidenceFile is an evidence document ready to be downloaded, its content must be closed once read
+type EvidenceFile struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Content     io.ReadCloser
+}
+
+//ValidateFields strips all spaces from the id and checks the validity of the fields
+func (r *DisputeRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.AuthId = strings.Replace(r.AuthId, " ", "", -1)
+	if !common_validation.IsValidUUID(r.AuthId) {
+		err = append(err, errors.New(error_constant.InvalidAuthIdField))
+	}
+	if !common_validation.IsAmountValid(r.Amount) {
+		err = append(err, errors.New(error_constant.InvalidAmount))
+	}
+	r.Reason = strings.TrimSpace(r.Reason)
+	if r.Reason == "" {
+		err = append(err, errors.New(error_constant.InvalidDisputeReason))
+	}
+	return err
+}
+
+//ValidateFields strips all spaces from the state and checks it is one the acquirer can move a dispute to
+func (r *StateRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.State = strings.ToLower(strings.Replace(r.State, " ", "", -1))
+	if r.State != StateEvidenceRequired && r.State != StateWon && r.State != StateLost {
+		err = append(err, errors.New(error_constant.InvalidDisputeState))
+	}
+	r.Detail = strings.TrimSpace(r.Detail)
+	return err
+}
+
+//CanMove checks that a dispute can go from a state to another: an opened dispute can be decided straight away or
+//require evidence, which is then submitted before the dispute is decided. Disputes are lost when they are not
+//answered in time
+func CanMove(from string, to string) bool {
+	switch from {
+	case StateOpened:
+		return to == StateEvidenceRequired || to == StateWon || to == StateLost
+	case StateEvidenceRequired:
+		return to == StateSubmitted || to == StateLost
+	case StateSubmitted:
+		return to == StateWon || to == StateLost
+	}
+	return false
+}
+
+//IsOpen checks that a dispute has not been decided yet
+func IsOpen(state string) bool {
+	return state != StateWon && state != StateLost
+}
+
+//IsStateValid checks the state used to filter the disputes, an empty state lists all of them
+func IsStateValid(state string) bool {
+	switch state {
+	case "", StateOpened, StateEvidenceRequired, StateSubmitted, StateWon, StateLost:
+		return true
+	}
+	return false
+}
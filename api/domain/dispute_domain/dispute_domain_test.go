@@ -0,0 +1,57 @@
+package dispute_domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+)
+
+func TestDisputeRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := DisputeRequest{AuthId: " 5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", Amount: 60, Reason: " fraud "}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", request.AuthId)
+	assert.EqualValues(t, "fraud", request.Reason)
+
+	request = DisputeRequest{AuthId: "not-an-id", Amount: -1, Reason: " "}
+	expectedErrors := []error{
+		errors.New(error_constant.InvalidAuthIdField),
+		errors.New(error_constant.InvalidAmount),
+		errors.New(error_constant.InvalidDisputeReason),
+	}
+	assert.EqualValues(t, expectedErrors, request.ValidateFields())
+}
+
+func TestStateRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := StateRequest{State: " Evidence_Required ", Detail: " send the receipt "}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, StateEvidenceRequired, request.State)
+	assert.EqualValues(t, "send the receipt", request.Detail)
+
+	//disputes are submitted by the merchant, not by the acquirer
+	request = StateRequest{State: StateSubmitted}
+	assert.EqualValues(t, []error{errors.New(error_constant.InvalidDisputeState)}, request.ValidateFields())
+}
+
+func TestCanMove(t *testing.T) {
+	t.Parallel()
+	assert.True(t, CanMove(StateOpened, StateEvidenceRequired))
+	assert.True(t, CanMove(StateOpened, StateWon))
+	assert.True(t, CanMove(StateEvidenceRequired, StateSubmitted))
+	assert.True(t, CanMove(StateEvidenceRequired, StateLost))
+	assert.True(t, CanMove(StateSubmitted, StateWon))
+	assert.False(t, CanMove(StateOpened, StateSubmitted))
+	assert.False(t, CanMove(StateEvidenceRequired, StateWon))
+	assert.False(t, CanMove(StateWon, StateLost))
+	assert.False(t, CanMove(StateLost, StateOpened))
+}
+
+func TestIsStateValid(t *testing.T) {
+	t.Parallel()
+	for _, state := range []string{"", StateOpened, StateEvidenceRequired, StateSubmitted, StateWon, StateLost} {
+		assert.True(t, IsStateValid(state))
+	}
+	assert.False(t, IsStateValid("closed"))
+}
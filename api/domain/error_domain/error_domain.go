@@ -1,10 +1,17 @@
 package error_domain
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
 )
 
+//StatusClientClosedRequest is the status of the requests abandoned by the client before they completed
+const StatusClientClosedRequest = 499
+
 //GatewayErrorInterface is the used to interact with service errors
 type GatewayErrorInterface interface {
 	Status() int
@@ -43,3 +50,19 @@ func NewApiErrorFromBytes(body []byte) (GatewayErrorInterface, error) {
 	}
 	return &result, nil
 }
+
+//FromContext replaces an internal error caused by the request context being done with the reason the request stopped
+func FromContext(ctx context.Context, err GatewayErrorInterface) GatewayErrorInterface {
+	if err == nil || err.Status() < http.StatusInternalServerError {
+		return err
+	}
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return New(http.StatusGatewayTimeout, errors.New(error_constant.RequestTimedOut))
+	case context.Canceled:
+		return New(StatusClientClosedRequest, errors.New(error_constant.RequestCancelled))
+	default:
+		return err
+	}
+}
@@ -1,14 +1,18 @@
 package error_domain
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
+	t.Parallel()
 	err1 := errors.New("error1")
 	err2 := errors.New("error2")
 	errs := []error{err1, err2}
@@ -19,6 +23,7 @@ func TestNew(t *testing.T) {
 }
 
 func TestExchangeError(t *testing.T) {
+	t.Parallel()
 	expectedError := GatewayError{
 		Code:  400,
 		Error: "Bad Request Error",
@@ -31,3 +36,23 @@ func TestExchangeError(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedError.Error, actualError.ErrorMessage())
 }
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+	internal := New(http.StatusInternalServerError, errors.New("unable to update available amount"))
+	invalid := New(http.StatusUnprocessableEntity, errors.New("amount cannot be negative"))
+
+	assert.EqualValues(t, internal, FromContext(context.Background(), internal))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.EqualValues(t, StatusClientClosedRequest, FromContext(cancelled, internal).Status())
+	//errors that do not come from the context are kept
+	assert.EqualValues(t, invalid, FromContext(cancelled, invalid))
+	assert.Nil(t, FromContext(cancelled, nil))
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.EqualValues(t, http.StatusGatewayTimeout, FromContext(expired, internal).Status())
+	assert.EqualValues(t, "[the request did not complete in time]", FromContext(expired, internal).ErrorMessage())
+}
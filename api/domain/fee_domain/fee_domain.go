@@ -0,0 +1,105 @@
+package fee_domain
+
+import (
+	"errors"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/common_validation"
+	"regexp"
+	"strings"
+)
+
+const (
+	//RefundRetain keeps the fee charged on the capture when it is refunded, refunds are not charged
+	RefundRetain = "retain"
+	//RefundReturn gives back the percentage fee charged on the refunded amount
+	RefundReturn = "return"
+	//RefundCharge charges the refunds the fixed refund fee of their rate
+	RefundCharge = "charge"
+)
+
+//ScheduleRequest is the format for the request by the fee schedule update endpoint
+type ScheduleRequest struct {
+	//RefundPolicy defaults to retain when it is not part of the request
+	RefundPolicy string `json:"refund_policy"`
+	Rates        []Rate `json:"rates" binding:"required"`
+}
+
+//Rate is the fee charged on the payments of a card brand in a currency, an empty brand or currency applies to any.
+//The captures are charged the percentage of their amount plus the fixed amount
+type Rate struct {
+	Brand       string  `json:"brand,omitempty"`
+	Currency    string  `json:"currency,omitempty"`
+	Percentage  float64 `json:"percentage"`
+	Fixed       float64 `json:"fixed"`
+	RefundFixed float64 `json:"refund_fixed"`
+}
+
+//ScheduleResponse is the format for the fee schedules returned by the admin endpoints
+type ScheduleResponse struct {
+	MerchantID   string `json:"merchant_id"`
+	RefundPolicy string `json:"refund_policy"`
+	Rates        []Rate `json:"rates"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+//IsMerchantIDValid checks the merchant id a fee schedule is requested for
+func IsMerchantIDValid(merchantID string) bool {
+	isValid, _ := regexp.MatchString(format_constant.MerchantIdLayout, merchantID)
+	return isValid
+}
+
+//ValidateFields strips all spaces from strings and checks their validity
+func (r *ScheduleRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.RefundPolicy = strings.ToLower(strings.Replace(r.RefundPolicy, " ", "", -1))
+	if r.RefundPolicy == "" {
+		r.RefundPolicy = RefundRetain
+	}
+	if r.RefundPolicy != RefundRetain && r.RefundPolicy != RefundReturn && r.RefundPolicy != RefundCharge {
+		err = append(err, errors.New(error_constant.InvalidRefundPolicy))
+	}
+	if len(r.Rates) == 0 {
+		return append(err, errors.New(error_constant.InvalidFeeRates))
+	}
+
+	var invalidBrand, invalidCurrency, invalidPercentage, invalidAmount, duplicate bool
+	pairs := make(map[string]bool)
+	for i := range r.Rates {
+		rate := &r.Rates[i]
+		rate.Brand = strings.ToLower(strings.Replace(rate.Brand, " ", "", -1))
+		rate.Currency = strings.Replace(rate.Currency, " ", "", -1)
+		invalidBrand = invalidBrand || (rate.Brand != "" && !isBrandValid(rate.Brand))
+		invalidCurrency = invalidCurrency || (rate.Currency != "" && !common_validation.IsCurrencyCodeValid(rate.Currency))
+		invalidPercentage = invalidPercentage || rate.Percentage < 0 || rate.Percentage > 100
+		invalidAmount = invalidAmount || rate.Fixed < 0 || rate.RefundFixed < 0
+		pair := rate.Brand + "/" + rate.Currency
+		duplicate = duplicate || pairs[pair]
+		pairs[pair] = true
+	}
+	if invalidBrand {
+		err = append(err, errors.New(error_constant.InvalidFeeBrand))
+	}
+	if invalidCurrency {
+		err = append(err, errors.New(error_constant.InvalidCurrencyCode))
+	}
+	if invalidPercentage {
+		err = append(err, errors.New(error_constant.InvalidFeePercentage))
+	}
+	if invalidAmount {
+		err = append(err, errors.New(error_constant.InvalidFeeAmount))
+	}
+	if duplicate {
+		err = append(err, errors.New(error_constant.DuplicateFeeRate))
+	}
+	return err
+}
+
+func isBrandValid(brand string) bool {
+	switch brand {
+	case auth_domain.BrandVisa, auth_domain.BrandMastercard, auth_domain.BrandAmex, auth_domain.BrandDiscover, auth_domain.BrandUnknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package fee_domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+)
+
+func TestScheduleRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := ScheduleRequest{
+		Rates: []Rate{
+			{Brand: " Visa ", Currency: "GBP", Percentage: 1.4, Fixed: 0.2},
+			{Brand: "amex", Percentage: 2.5},
+			{Percentage: 2.9, Fixed: 0.3, RefundFixed: 0.1},
+		},
+	}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, RefundRetain, request.RefundPolicy)
+	assert.EqualValues(t, "visa", request.Rates[0].Brand)
+
+	request = ScheduleRequest{RefundPolicy: "Charge", Rates: []Rate{{Currency: "USD", Fixed: 0.25, RefundFixed: 0.5}}}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, RefundCharge, request.RefundPolicy)
+}
+
+func TestScheduleRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
+	request := ScheduleRequest{
+		RefundPolicy: "keep",
+		Rates: []Rate{
+			{Brand: "diners", Currency: "gbp", Percentage: 101},
+			{Brand: "diners", Currency: "gbp", Fixed: -1},
+		},
+	}
+	expectedErrors := []error{
+		errors.New(error_constant.InvalidRefundPolicy),
+		errors.New(error_constant.InvalidFeeBrand),
+		errors.New(error_constant.InvalidCurrencyCode),
+		errors.New(error_constant.InvalidFeePercentage),
+		errors.New(error_constant.InvalidFeeAmount),
+		errors.New(error_constant.DuplicateFeeRate),
+	}
+	assert.EqualValues(t, expectedErrors, request.ValidateFields())
+
+	request = ScheduleRequest{}
+	assert.EqualValues(t, []error{errors.New(error_constant.InvalidFeeRates)}, request.ValidateFields())
+}
+
+func TestIsMerchantIDValid(t *testing.T) {
+	t.Parallel()
+	assert.True(t, IsMerchantIDValid("acme"))
+	assert.True(t, IsMerchantIDValid("merchant-01.eu"))
+	assert.False(t, IsMerchantIDValid(""))
+	assert.False(t, IsMerchantIDValid("acme corp"))
+}
@@ -0,0 +1,217 @@
+package fraud_domain
+
+import (
+	"errors"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/domain/common_validation"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	DecisionAllow  = "allow"
+	DecisionReview = "review"
+	DecisionDeny   = "deny"
+
+	//RuleVelocityCount triggers when a card already has threshold authorisations within the window
+	RuleVelocityCount = "velocity_count"
+	//RuleVelocityAmount triggers when the amount authorised on a card in a currency within the window, this one included, is above threshold
+	RuleVelocityAmount = "velocity_amount"
+	//RuleAmountThreshold triggers when the amount of an authorisation in a currency is above threshold
+	RuleAmountThreshold = "amount_threshold"
+	//RuleRepeatedDeclines triggers when a card already has threshold declined authorisations within the window
+	RuleRepeatedDeclines = "repeated_declines"
+	//RuleBinCountry triggers when the card has been issued in one of the countries
+	RuleBinCountry = "bin_country"
+	//RuleAVSMismatch triggers when the issuer has another billing address line or postcode on file for the card
+	RuleAVSMismatch = "avs_mismatch"
+)
+
+//RuleRequest is the format for the request by the fraud rule creation and update endpoints
+type RuleRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Type      string   `json:"type" binding:"required"`
+	Action    string   `json:"action" binding:"required"`
+	Threshold float32  `json:"threshold"`
+	Currency  string   `json:"currency"`
+	Window    string   `json:"window"`
+	Countries []string `json:"countries"`
+	//Enabled defaults to true when it is not part of the request
+	Enabled *bool `json:"enabled"`
+	DryRun  bool  `json:"dry_run"`
+}
+
+//Rule is the format for the fraud rules returned by the admin endpoints
+type Rule struct {
+	ID        uint     `json:"id"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Action    string   `json:"action"`
+	Threshold float32  `json:"threshold,omitempty"`
+	Currency  string   `json:"currency,omitempty"`
+	Window    string   `json:"window,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	Enabled   bool     `json:"enabled"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+//BinCountriesRequest is the format for the request by the bin countries endpoint
+type BinCountriesRequest struct {
+	Bins []BinCountry `json:"bins" binding:"required"`
+}
+
+//BinCountry maps the leading digits of card numbers to the country of their issuer
+type BinCountry struct {
+	Bin     string `json:"bin"`
+	Country string `json:"country"`
+}
+
+//Transaction is the authorisation assessed by the fraud rules
+type Transaction struct {
+	Number   string  `json:"card_number" binding:"required"`
+	Amount   float32 `json:"amount" binding:"required"`
+	Currency string  `json:"currency" binding:"required"`
+	//AVSAddress and AVSPostcode are the address verification results of the billing address given with the card
+	AVSAddress  string `json:"avs_address"`
+	AVSPostcode string `json:"avs_postcode"`
+}
+
+//Assessment is the decision taken by the fraud rules and the rules that led to it,
+//dry run rules are reported without having any effect on the decision
+type Assessment struct {
+	Decision       string          `json:"decision"`
+	TriggeredRules []TriggeredRule `json:"triggered_rules"`
+}
+
+//TriggeredRule is a rule whose condition is met by the assessed transaction
+type TriggeredRule struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	DryRun bool   `json:"dry_run"`
+	Reason string `json:"reason"`
+}
+
+//ValidateFields strips all spaces from strings and checks their validity given the type of the rule
+func (r *RuleRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		err = append(err, errors.New(error_constant.InvalidRuleName))
+	}
+	r.Action = strings.ToLower(strings.Replace(r.Action, " ", "", -1))
+	if r.Action != DecisionReview && r.Action != DecisionDeny {
+		err = append(err, errors.New(error_constant.InvalidRuleAction))
+	}
+
+	r.Type = strings.ToLower(strings.Replace(r.Type, " ", "", -1))
+	r.Currency = strings.Replace(r.Currency, " ", "", -1)
+	r.Window = strings.Replace(r.Window, " ", "", -1)
+	switch r.Type {
+	case RuleVelocityCount, RuleRepeatedDeclines:
+		err = append(err, r.validateThreshold()...)
+		err = append(err, r.validateWindow()...)
+	case RuleVelocityAmount:
+		err = append(err, r.validateThreshold()...)
+		err = append(err, r.validateWindow()...)
+		err = append(err, r.validateCurrency()...)
+	case RuleAmountThreshold:
+		err = append(err, r.validateThreshold()...)
+		err = append(err, r.validateCurrency()...)
+	case RuleBinCountry:
+		for i := range r.Countries {
+			r.Countries[i] = strings.ToUpper(strings.Replace(r.Countries[i], " ", "", -1))
+		}
+		if len(r.Countries) == 0 || !areCountryCodesValid(r.Countries) {
+			err = append(err, errors.New(error_constant.InvalidRuleCountries))
+		}
+	case RuleAVSMismatch:
+	default:
+		err = append(err, errors.New(error_constant.InvalidRuleType))
+	}
+	return err
+}
+
+func (r *RuleRequest) validateThreshold() []error {
+	if r.Threshold <= 0 {
+		return []error{errors.New(error_constant.InvalidRuleThreshold)}
+	}
+	return nil
+}
+
+func (r *RuleRequest) validateWindow() []error {
+	if window, err := time.ParseDuration(r.Window); err != nil || window <= 0 {
+		return []error{errors.New(error_constant.InvalidRuleWindow)}
+	}
+	return nil
+}
+
+func (r *RuleRequest) validateCurrency() []error {
+	if !common_validation.IsCurrencyCodeValid(r.Currency) {
+		return []error{errors.New(error_constant.InvalidCurrencyCode)}
+	}
+	return nil
+}
+
+func areCountryCodesValid(countries []string) bool {
+	for _, country := range countries {
+		if !common_validation.IsCountryCodeValid(country) {
+			return false
+		}
+	}
+	return true
+}
+
+//ValidateFields strips all spaces from strings and checks their validity
+func (r *BinCountriesRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	for i := range r.Bins {
+		r.Bins[i].Bin = strings.Replace(r.Bins[i].Bin, " ", "", -1)
+		if isValid, _ := regexp.MatchString(format_constant.BinLayout, r.Bins[i].Bin); !isValid {
+			err = append(err, errors.New(error_constant.InvalidBin))
+			break
+		}
+	}
+	for i := range r.Bins {
+		r.Bins[i].Country = strings.ToUpper(strings.Replace(r.Bins[i].Country, " ", "", -1))
+		if !common_validation.IsCountryCodeValid(r.Bins[i].Country) {
+			err = append(err, errors.New(error_constant.InvalidCountryCode))
+			break
+		}
+	}
+	return err
+}
+
+//ValidateFields strips all spaces from strings and checks their validity
+func (t *Transaction) ValidateFields() []error {
+	var err = make([]error, 0)
+	t.Number = strings.Replace(t.Number, " ", "", -1)
+	if t.Number == "" {
+		err = append(err, errors.New(error_constant.InvalidCardNumber))
+	}
+	if !common_validation.IsAmountValid(t.Amount) {
+		err = append(err, errors.New(error_constant.InvalidAmount))
+	}
+	if !common_validation.IsCurrencyCodeValid(t.Currency) {
+		err = append(err, errors.New(error_constant.InvalidCurrencyCode))
+	}
+	return err
+}
+
+//Decide returns the most severe action among the triggered rules that are not dry runs
+func Decide(triggered []TriggeredRule) string {
+	decision := DecisionAllow
+	for _, rule := range triggered {
+		if rule.DryRun {
+			continue
+		}
+		if rule.Action == DecisionDeny {
+			return DecisionDeny
+		}
+		decision = DecisionReview
+	}
+	return decision
+}
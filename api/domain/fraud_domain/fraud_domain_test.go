@@ -0,0 +1,99 @@
+package fraud_domain
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+)
+
+func TestRuleRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := RuleRequest{
+		Name:      " card velocity ",
+		Type:      "Velocity_Count",
+		Action:    "deny",
+		Threshold: 5,
+		Window:    "1h",
+	}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, "card velocity", request.Name)
+	assert.EqualValues(t, RuleVelocityCount, request.Type)
+
+	request = RuleRequest{
+		Name:      "high amount",
+		Type:      RuleAmountThreshold,
+		Action:    "review",
+		Threshold: 5000,
+		Currency:  "GBP",
+	}
+	assert.Empty(t, request.ValidateFields())
+
+	request = RuleRequest{
+		Name:      "blocked issuers",
+		Type:      RuleBinCountry,
+		Action:    "deny",
+		Countries: []string{"kp", "IR"},
+	}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, []string{"KP", "IR"}, request.Countries)
+
+	request = RuleRequest{
+		Name:   "avs mismatch",
+		Type:   "AVS_Mismatch",
+		Action: "review",
+	}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, RuleAVSMismatch, request.Type)
+}
+
+func TestRuleRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
+	request := RuleRequest{
+		Name:   " ",
+		Type:   RuleVelocityAmount,
+		Action: "block",
+		Window: "-1h",
+	}
+
+	expectedErrors := []error{
+		errors.New(error_constant.InvalidRuleName),
+		errors.New(error_constant.InvalidRuleAction),
+		errors.New(error_constant.InvalidRuleThreshold),
+		errors.New(error_constant.InvalidRuleWindow),
+		errors.New(error_constant.InvalidCurrencyCode),
+	}
+	assert.EqualValues(t, fmt.Sprintf("%v", expectedErrors), fmt.Sprintf("%v", request.ValidateFields()))
+
+	request = RuleRequest{Name: "unknown", Type: "geo_velocity", Action: "deny"}
+	assert.EqualValues(t, []error{errors.New(error_constant.InvalidRuleType)}, request.ValidateFields())
+
+	request = RuleRequest{Name: "blocked issuers", Type: RuleBinCountry, Action: "deny", Countries: []string{"GBR"}}
+	assert.EqualValues(t, []error{errors.New(error_constant.InvalidRuleCountries)}, request.ValidateFields())
+}
+
+func TestBinCountriesRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := BinCountriesRequest{Bins: []BinCountry{{Bin: "492990", Country: "gb"}, {Bin: "4111 1111", Country: "US"}}}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, "GB", request.Bins[0].Country)
+	assert.EqualValues(t, "41111111", request.Bins[1].Bin)
+
+	request = BinCountriesRequest{Bins: []BinCountry{{Bin: "4929", Country: "GBR"}}}
+	assert.EqualValues(t, []error{errors.New(error_constant.InvalidBin), errors.New(error_constant.InvalidCountryCode)}, request.ValidateFields())
+}
+
+func TestDecide(t *testing.T) {
+	t.Parallel()
+	review := TriggeredRule{Name: "high amount", Action: DecisionReview}
+	deny := TriggeredRule{Name: "card velocity", Action: DecisionDeny}
+	dryRunDeny := TriggeredRule{Name: "new velocity", Action: DecisionDeny, DryRun: true}
+
+	assert.EqualValues(t, DecisionAllow, Decide(nil))
+	assert.EqualValues(t, DecisionReview, Decide([]TriggeredRule{review}))
+	assert.EqualValues(t, DecisionDeny, Decide([]TriggeredRule{review, deny}))
+	//dry run rules never change the decision
+	assert.EqualValues(t, DecisionAllow, Decide([]TriggeredRule{dryRunDeny}))
+	assert.EqualValues(t, DecisionReview, Decide([]TriggeredRule{dryRunDeny, review}))
+}
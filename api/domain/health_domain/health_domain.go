@@ -0,0 +1,31 @@
+package health_domain
+
+const (
+	StatusOK       = "ok"
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+//HealthResponse is the format for the response by the liveness endpoint
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+//ReadinessResponse is the format for the response by the readiness endpoint, every check is reported
+//with ok or the reason it failed
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+//IsReady tells whether every check has passed
+func (r *ReadinessResponse) IsReady() bool {
+	return r.Status == StatusReady
+}
+
+//VersionResponse is the format for the response by the version endpoint
+type VersionResponse struct {
+	GitSHA        string `json:"git_sha"`
+	BuildTime     string `json:"build_time"`
+	SchemaVersion int    `json:"schema_version"`
+}
@@ -0,0 +1,48 @@
+package ledger_domain
+
+const (
+	//ViolationHoldMismatch is an authorisation whose hold is not its available amount, or not zero once it has been voided
+	ViolationHoldMismatch = "hold_mismatch"
+	//ViolationUnbalancedEntry is a journal entry whose debits do not equal its credits
+	ViolationUnbalancedEntry = "unbalanced_entry"
+)
+
+//BalanceResponse is the format for the account balances returned by the ledger endpoints, debit balances are positive
+type BalanceResponse struct {
+	Account  string  `json:"account"`
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+}
+
+//EntryResponse is the format for the journal entries returned by the ledger endpoints
+type EntryResponse struct {
+	ID          uint              `json:"id"`
+	Name        string            `json:"name"`
+	OperationID uint              `json:"operation_id,omitempty"`
+	MerchantID  string            `json:"merchant_id,omitempty"`
+	Currency    string            `json:"currency"`
+	CreatedAt   string            `json:"created_at"`
+	Postings    []PostingResponse `json:"postings"`
+}
+
+//PostingResponse is an amount debited to an account when positive and credited when negative
+type PostingResponse struct {
+	Account string  `json:"account"`
+	Amount  float64 `json:"amount"`
+}
+
+//CheckResponse is the format for the result of the ledger invariant check, the ledger is sound when there is no violation
+type CheckResponse struct {
+	CheckedAt      string              `json:"checked_at"`
+	Authorisations int                 `json:"authorisations"`
+	Violations     []ViolationResponse `json:"violations"`
+}
+
+//ViolationResponse is an authorisation or a journal entry breaking an invariant of the ledger
+type ViolationResponse struct {
+	Type     string  `json:"type"`
+	AuthID   string  `json:"auth_id,omitempty"`
+	EntryID  uint    `json:"entry_id,omitempty"`
+	Expected float64 `json:"expected"`
+	Actual   float64 `json:"actual"`
+}
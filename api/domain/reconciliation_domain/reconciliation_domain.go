@@ -0,0 +1,41 @@
+package reconciliation_domain
+
+const (
+	StatusMatched    = "matched"
+	StatusMissing    = "missing"
+	StatusUnexpected = "unexpected"
+	StatusMismatched = "mismatched"
+)
+
+//Columns maps the values read from an acquirer settlement report to the headers of its columns,
+//the type and currency columns are optional
+type Columns struct {
+	Reference string
+	Type      string
+	Amount    string
+	Currency  string
+}
+
+//ReconciliationResponse is the format for the reconciliation reports returned by the reconciliation endpoints
+type ReconciliationResponse struct {
+	ID         string         `json:"id"`
+	BatchID    string         `json:"batch_id"`
+	CreatedAt  string         `json:"created_at"`
+	Matched    int            `json:"matched"`
+	Missing    int            `json:"missing"`
+	Unexpected int            `json:"unexpected"`
+	Mismatched int            `json:"mismatched"`
+	Items      []ItemResponse `json:"items,omitempty"`
+}
+
+//ItemResponse is a line of the acquirer report or an operation missing from it, the expected amount is the one
+//recorded by the gateway and the reported amount the one paid out by the acquirer
+type ItemResponse struct {
+	Status         string   `json:"status"`
+	Line           int      `json:"line,omitempty"`
+	Reference      string   `json:"reference"`
+	Type           string   `json:"type,omitempty"`
+	ExpectedAmount *float64 `json:"expected_amount,omitempty"`
+	ReportedAmount *float64 `json:"reported_amount,omitempty"`
+	Currency       string   `json:"currency,omitempty"`
+}
@@ -13,11 +13,20 @@ type RefundRequest struct {
 	Amount float32 `json:"amount" binding:"required"`
 }
 
-//RefundResponse is the format for the response by the refund endpoint
+//AmountRequest is the format for the request by the refunds endpoint of an authorisation, the authorisation ID is
+//taken from the path
+type AmountRequest struct {
+	Amount float32 `json:"amount" binding:"required"`
+}
+
+//RefundResponse is the format for the response by the refund endpoint, the net amount is what the refund costs
+//the merchant with its fee, less when the refund returns part of the capture fee
 type RefundResponse struct {
 	IsSuccess bool    `json:"success"`
 	Amount    float32 `json:"amount"`
 	Currency  string  `json:"currency"`
+	Fee       float32 `json:"fee"`
+	NetAmount float32 `json:"net_amount"`
 }
 
 //ValidateFields strips all spaces from strings and checks their validity
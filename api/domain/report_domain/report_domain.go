@@ -0,0 +1,172 @@
+package report_domain
+
+import (
+	"errors"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/domain/common_validation"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+
+	//MaxBuckets is the number of buckets a report can hold at most, a year of days
+	MaxBuckets = 366
+	//DateLayout is the layout of the dates the buckets start on
+	DateLayout = "2006-01-02"
+)
+
+//SummaryRequest is the format for the query parameters of the summary report, the period includes from and excludes to.
+//The merchant and the currency are optional
+type SummaryRequest struct {
+	From       string `form:"from"`
+	To         string `form:"to"`
+	MerchantID string `form:"merchant_id"`
+	Currency   string `form:"currency"`
+	Bucket     string `form:"bucket"`
+}
+
+//SummaryResponse is the format for the summary report, with the summary of the whole period and of every bucket of it
+type SummaryResponse struct {
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	MerchantID string          `json:"merchant_id,omitempty"`
+	Currency   string          `json:"currency,omitempty"`
+	Bucket     string          `json:"bucket"`
+	Totals     Summary         `json:"totals"`
+	Buckets    []BucketSummary `json:"buckets"`
+}
+
+//BucketSummary is the summary of a bucket of the report, starting on its start date
+type BucketSummary struct {
+	Start string `json:"start"`
+	Summary
+}
+
+//Summary is the activity over a period. The approval rate is the share of the authorisation attempts that were
+//approved and the decline rates the share declined for every reason, the attempts still held for review are neither.
+//The refund ratio is the number of refunds by capture and the average capture time is from authorisation to first capture
+type Summary struct {
+	Authorisations        Volume             `json:"authorisations"`
+	PendingReview         Volume             `json:"pending_review"`
+	Captures              Volume             `json:"captures"`
+	Refunds               Volume             `json:"refunds"`
+	Voids                 Volume             `json:"voids"`
+	Declines              Volume             `json:"declines"`
+	DeclinesByReason      map[string]Volume  `json:"declines_by_reason"`
+	ApprovalRate          float64            `json:"approval_rate"`
+	DeclineRate           float64            `json:"decline_rate"`
+	DeclineRates          map[string]float64 `json:"decline_rates"`
+	RefundRatio           float64            `json:"refund_ratio"`
+	AverageCaptureSeconds float64            `json:"average_capture_seconds"`
+}
+
+//Volume is a number of operations and their amounts by currency
+type Volume struct {
+	Count   int                `json:"count"`
+	Amounts map[string]float64 `json:"amounts"`
+}
+
+//NewSummary returns an empty summary
+func NewSummary() Summary {
+	return Summary{
+		Authorisations:   NewVolume(),
+		PendingReview:    NewVolume(),
+		Captures:         NewVolume(),
+		Refunds:          NewVolume(),
+		Voids:            NewVolume(),
+		Declines:         NewVolume(),
+		DeclinesByReason: make(map[string]Volume),
+		DeclineRates:     make(map[string]float64),
+	}
+}
+
+//NewVolume returns an empty volume
+func NewVolume() Volume {
+	return Volume{Amounts: make(map[string]float64)}
+}
+
+//ValidateFields strips all spaces from the fields, sets the default bucket and checks the validity of the fields
+func (r *SummaryRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.From, r.To = strings.TrimSpace(r.From), strings.TrimSpace(r.To)
+	from, fromErr := time.Parse(time.RFC3339, r.From)
+	to, toErr := time.Parse(time.RFC3339, r.To)
+	r.Bucket = strings.ToLower(strings.TrimSpace(r.Bucket))
+	if r.Bucket == "" {
+		r.Bucket = BucketDay
+	}
+	switch {
+	case r.From == "" || r.To == "":
+		err = append(err, errors.New(error_constant.ReportRangeRequired))
+	case fromErr != nil || toErr != nil:
+		err = append(err, errors.New(error_constant.InvalidTimestampFilter))
+	case !from.Before(to):
+		err = append(err, errors.New(error_constant.InvalidDateRange))
+	case IsBucketValid(r.Bucket) && len(Buckets(from, to, r.Bucket)) > MaxBuckets:
+		err = append(err, errors.New(error_constant.ReportRangeTooLong))
+	}
+	if !IsBucketValid(r.Bucket) {
+		err = append(err, errors.New(error_constant.InvalidReportBucket))
+	}
+	r.MerchantID = strings.TrimSpace(r.MerchantID)
+	if isValid, _ := regexp.MatchString(format_constant.MerchantIdLayout, r.MerchantID); r.MerchantID != "" && !isValid {
+		err = append(err, errors.New(error_constant.InvalidMerchantIdField))
+	}
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	if r.Currency != "" && !common_validation.IsCurrencyCodeValid(r.Currency) {
+		err = append(err, errors.New(error_constant.InvalidCurrencyCode))
+	}
+	return err
+}
+
+//Range returns the period of the report, it is only meaningful once the fields have been validated
+func (r *SummaryRequest) Range() (time.Time, time.Time) {
+	from, _ := time.Parse(time.RFC3339, r.From)
+	to, _ := time.Parse(time.RFC3339, r.To)
+	return from, to
+}
+
+//IsBucketValid checks the bucket is one of day, week or month
+func IsBucketValid(bucket string) bool {
+	return bucket == BucketDay || bucket == BucketWeek || bucket == BucketMonth
+}
+
+//BucketStart returns the start of the bucket the time falls in, in UTC. The weeks start on Monday
+func BucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case BucketWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case BucketMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+//Buckets returns the start of the buckets covering the period, stopping early once there are more than MaxBuckets
+func Buckets(from time.Time, to time.Time, bucket string) []time.Time {
+	var starts []time.Time
+	for start := BucketStart(from, bucket); start.Before(to) && len(starts) <= MaxBuckets; start = next(start, bucket) {
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+func next(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
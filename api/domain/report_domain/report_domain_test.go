@@ -0,0 +1,74 @@
+package report_domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+	"time"
+)
+
+func TestSummaryRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := SummaryRequest{
+		From:       " 2020-06-01T00:00:00Z",
+		To:         "2020-07-01T00:00:00Z ",
+		MerchantID: " merchant-1 ",
+		Currency:   "gbp",
+	}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, BucketDay, request.Bucket)
+	assert.EqualValues(t, "merchant-1", request.MerchantID)
+	assert.EqualValues(t, "GBP", request.Currency)
+
+	from, to := request.Range()
+	assert.EqualValues(t, time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.EqualValues(t, time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC), to)
+}
+
+func TestSummaryRequest_ValidateFields_Errors(t *testing.T) {
+	t.Parallel()
+	from, to := "2020-06-01T00:00:00Z", "2020-07-01T00:00:00Z"
+	tests := []struct {
+		name           string
+		request        SummaryRequest
+		expectedErrors []error
+	}{
+		{"range required", SummaryRequest{From: from}, []error{errors.New(error_constant.ReportRangeRequired)}},
+		{"timestamp", SummaryRequest{From: "2020-06-01", To: to}, []error{errors.New(error_constant.InvalidTimestampFilter)}},
+		{"date range", SummaryRequest{From: to, To: from}, []error{errors.New(error_constant.InvalidDateRange)}},
+		{"bucket", SummaryRequest{From: from, To: to, Bucket: "year"}, []error{errors.New(error_constant.InvalidReportBucket)}},
+		{"too long", SummaryRequest{From: "2019-01-01T00:00:00Z", To: "2020-06-01T00:00:00Z"}, []error{errors.New(error_constant.ReportRangeTooLong)}},
+		{"merchant", SummaryRequest{From: from, To: to, MerchantID: "merchant 1"}, []error{errors.New(error_constant.InvalidMerchantIdField)}},
+		{"currency", SummaryRequest{From: from, To: to, Currency: "pounds"}, []error{errors.New(error_constant.InvalidCurrencyCode)}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.EqualValues(t, tt.expectedErrors, tt.request.ValidateFields())
+		})
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	t.Parallel()
+	from := time.Date(2020, time.June, 3, 15, 0, 0, 0, time.UTC)
+	to := time.Date(2020, time.August, 2, 0, 0, 0, 0, time.UTC)
+
+	weeks := Buckets(from, to, BucketWeek)
+	assert.EqualValues(t, 9, len(weeks))
+	assert.EqualValues(t, time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC), weeks[0])
+	assert.EqualValues(t, time.Date(2020, time.July, 27, 0, 0, 0, 0, time.UTC), weeks[8])
+
+	months := Buckets(from, to, BucketMonth)
+	assert.EqualValues(t, []time.Time{
+		time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.August, 1, 0, 0, 0, 0, time.UTC),
+	}, months)
+
+	//a time in another zone falls in the bucket of its UTC day
+	assert.EqualValues(t, time.Date(2020, time.June, 2, 0, 0, 0, 0, time.UTC),
+		BucketStart(time.Date(2020, time.June, 3, 0, 30, 0, 0, time.FixedZone("BST", 3600)), BucketDay))
+}
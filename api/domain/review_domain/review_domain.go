@@ -0,0 +1,64 @@
+package review_domain
+
+import (
+	"errors"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/common_validation"
+	"strings"
+)
+
+const (
+	StatePending  = "pending"
+	StateApproved = "approved"
+	StateDeclined = "declined"
+
+	//SystemReviewer is the reviewer recorded on the authorisations declined because nobody reviewed them in time
+	SystemReviewer = "system"
+)
+
+//ReviewDecisionRequest is the format for the request by the review approval and decline endpoints
+type ReviewDecisionRequest struct {
+	AuthId   string `json:"id" binding:"required"`
+	Reviewer string `json:"reviewer" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+//ReviewResponse is the format for the reviews returned by the review endpoints
+type ReviewResponse struct {
+	AuthID         string   `json:"id"`
+	State          string   `json:"state"`
+	TriggeredRules []string `json:"triggered_rules"`
+	Amount         float32  `json:"amount"`
+	Currency       string   `json:"currency"`
+	DueAt          string   `json:"due_at"`
+	Reviewer       string   `json:"reviewer,omitempty"`
+	Reason         string   `json:"reason,omitempty"`
+	ReviewedAt     string   `json:"reviewed_at,omitempty"`
+}
+
+//ValidateFields strips all spaces from the id and checks the validity of the fields
+func (r *ReviewDecisionRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.AuthId = strings.Replace(r.AuthId, " ", "", -1)
+	if !common_validation.IsValidUUID(r.AuthId) {
+		err = append(err, errors.New(error_constant.InvalidAuthIdField))
+	}
+	r.Reviewer = strings.TrimSpace(r.Reviewer)
+	if r.Reviewer == "" {
+		err = append(err, errors.New(error_constant.InvalidReviewer))
+	}
+	r.Reason = strings.TrimSpace(r.Reason)
+	if r.Reason == "" {
+		err = append(err, errors.New(error_constant.InvalidReviewReason))
+	}
+	return err
+}
+
+//IsStateValid checks the state used to filter the reviews, an empty state lists all of them
+func IsStateValid(state string) bool {
+	switch state {
+	case "", StatePending, StateApproved, StateDeclined:
+		return true
+	}
+	return false
+}
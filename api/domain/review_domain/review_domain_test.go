@@ -0,0 +1,36 @@
+package review_domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+)
+
+func TestReviewDecisionRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := ReviewDecisionRequest{
+		AuthId:   " 5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01",
+		Reviewer: " analyst@example.com ",
+		Reason:   "customer confirmed the purchase",
+	}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01", request.AuthId)
+	assert.EqualValues(t, "analyst@example.com", request.Reviewer)
+
+	request = ReviewDecisionRequest{AuthId: "not-an-id", Reviewer: " ", Reason: ""}
+	expectedErrors := []error{
+		errors.New(error_constant.InvalidAuthIdField),
+		errors.New(error_constant.InvalidReviewer),
+		errors.New(error_constant.InvalidReviewReason),
+	}
+	assert.EqualValues(t, expectedErrors, request.ValidateFields())
+}
+
+func TestIsStateValid(t *testing.T) {
+	t.Parallel()
+	for _, state := range []string{"", StatePending, StateApproved, StateDeclined} {
+		assert.True(t, IsStateValid(state))
+	}
+	assert.False(t, IsStateValid("expired"))
+}
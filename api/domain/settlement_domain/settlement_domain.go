@@ -0,0 +1,48 @@
+package settlement_domain
+
+const (
+	StateClosed    = "closed"
+	StateSubmitted = "submitted"
+
+	FormatCSV        = "csv"
+	FormatFixedWidth = "fixed"
+)
+
+//BatchResponse is the format for the settlement batches returned by the settlement endpoints
+type BatchResponse struct {
+	ID          string          `json:"id"`
+	CutOff      string          `json:"cut_off"`
+	State       string          `json:"state"`
+	Operations  int             `json:"operations"`
+	SubmittedAt string          `json:"submitted_at,omitempty"`
+	Totals      []TotalResponse `json:"totals,omitempty"`
+}
+
+//TotalResponse is the amount settled to a merchant in a currency, the net amount is what the merchant is paid
+//once the fees charged on its captures and refunds are deducted
+type TotalResponse struct {
+	MerchantID     string  `json:"merchant_id"`
+	Currency       string  `json:"currency"`
+	Captures       int     `json:"captures"`
+	CapturedAmount float64 `json:"captured_amount"`
+	Refunds        int     `json:"refunds"`
+	RefundedAmount float64 `json:"refunded_amount"`
+	Fees           float64 `json:"fees"`
+	NetAmount      float64 `json:"net_amount"`
+}
+
+//File is a settlement file ready to be submitted to the acquirer
+type File struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+//IsFormatValid checks the format a settlement file is requested in, csv when it is empty
+func IsFormatValid(format string) bool {
+	switch format {
+	case "", FormatCSV, FormatFixedWidth:
+		return true
+	}
+	return false
+}
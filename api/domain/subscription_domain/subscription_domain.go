@@ -0,0 +1,130 @@
+package subscription_domain
+
+import (
+	"errors"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/domain/common_validation"
+	"strings"
+	"time"
+)
+
+const (
+	IntervalDaily   = "daily"
+	IntervalWeekly  = "weekly"
+	IntervalMonthly = "monthly"
+	IntervalYearly  = "yearly"
+
+	StateActive    = "active"
+	StatePaused    = "paused"
+	StateUnpaid    = "unpaid"
+	StateCancelled = "cancelled"
+	StateCompleted = "completed"
+
+	ChargeStateClaimed       = "claimed"
+	ChargeStateSucceeded     = "succeeded"
+	ChargeStateFailed        = "failed"
+	ChargeStatePendingReview = "pending_review"
+)
+
+//SubscriptionRequest is the format for the request by the subscription creation endpoint,
+//the stored card is the one used by the referenced authorisation
+type SubscriptionRequest struct {
+	AuthId     string  `json:"id" binding:"required"`
+	Amount     float32 `json:"amount" binding:"required"`
+	Currency   string  `json:"currency" binding:"required"`
+	Interval   string  `json:"interval" binding:"required"`
+	AnchorDate string  `json:"anchor_date" binding:"required"`
+	MaxCycles  int     `json:"max_cycles"`
+}
+
+//SubscriptionStateRequest is the format for the request by the pause, resume and cancel subscription endpoints
+type SubscriptionStateRequest struct {
+	SubscriptionId string `json:"id" binding:"required"`
+}
+
+//SubscriptionResponse is the format for the response by the subscription endpoints
+type SubscriptionResponse struct {
+	SubscriptionID  string  `json:"id"`
+	IsSuccess       bool    `json:"success"`
+	State           string  `json:"state"`
+	Amount          float32 `json:"amount"`
+	Currency        string  `json:"currency"`
+	Interval        string  `json:"interval"`
+	NextChargeDate  string  `json:"next_charge_date"`
+	CompletedCycles int     `json:"completed_cycles"`
+	MaxCycles       int     `json:"max_cycles"`
+}
+
+//ValidateFields strips all spaces from strings and checks their validity
+func (r *SubscriptionRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.AuthId = strings.Replace(r.AuthId, " ", "", -1)
+	if !common_validation.IsValidUUID(r.AuthId) {
+		err = append(err, errors.New(error_constant.InvalidAuthIdField))
+	}
+	if !common_validation.IsAmountValid(r.Amount) {
+		err = append(err, errors.New(error_constant.InvalidAmount))
+	}
+	if !common_validation.IsCurrencyCodeValid(r.Currency) {
+		err = append(err, errors.New(error_constant.InvalidCurrencyCode))
+	}
+	r.Interval = strings.ToLower(strings.Replace(r.Interval, " ", "", -1))
+	if !isIntervalValid(r.Interval) {
+		err = append(err, errors.New(error_constant.InvalidInterval))
+	}
+	r.AnchorDate = strings.Replace(r.AnchorDate, " ", "", -1)
+	if _, parseErr := time.Parse(format_constant.AnchorDateLayout, r.AnchorDate); parseErr != nil {
+		err = append(err, errors.New(error_constant.InvalidAnchorDate))
+	}
+	if r.MaxCycles < 0 {
+		err = append(err, errors.New(error_constant.InvalidMaxCycles))
+	}
+	return err
+}
+
+//ValidateFields strips all spaces from strings and checks their validity
+func (r *SubscriptionStateRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.SubscriptionId = strings.Replace(r.SubscriptionId, " ", "", -1)
+	if !common_validation.IsValidUUID(r.SubscriptionId) {
+		err = append(err, errors.New(error_constant.InvalidSubscriptionIdField))
+	}
+	return err
+}
+
+//isIntervalValid checks the interval is one of the supported billing periods
+func isIntervalValid(interval string) bool {
+	switch interval {
+	case IntervalDaily, IntervalWeekly, IntervalMonthly, IntervalYearly:
+		return true
+	}
+	return false
+}
+
+//ChargeDate returns the date of the given cycle of a schedule starting at the anchor date,
+//monthly and yearly schedules are clamped to the last day of shorter months instead of overflowing
+func ChargeDate(anchor time.Time, interval string, cycle int) time.Time {
+	switch interval {
+	case IntervalDaily:
+		return anchor.AddDate(0, 0, cycle)
+	case IntervalWeekly:
+		return anchor.AddDate(0, 0, 7*cycle)
+	case IntervalMonthly:
+		return addMonthsClamped(anchor, cycle)
+	case IntervalYearly:
+		return addMonthsClamped(anchor, 12*cycle)
+	}
+	return anchor
+}
+
+func addMonthsClamped(anchor time.Time, months int) time.Time {
+	firstOfMonth := time.Date(anchor.Year(), anchor.Month()+time.Month(months), 1,
+		anchor.Hour(), anchor.Minute(), anchor.Second(), anchor.Nanosecond(), anchor.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := anchor.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
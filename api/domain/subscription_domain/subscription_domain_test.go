@@ -0,0 +1,100 @@
+package subscription_domain
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionResponse(t *testing.T) {
+	t.Parallel()
+	expectedResponse := SubscriptionResponse{
+		SubscriptionID:  "970c8844-9238-4c31-95ca-6f079dd65729",
+		IsSuccess:       true,
+		State:           StateActive,
+		Amount:          10,
+		Currency:        "GBP",
+		Interval:        IntervalMonthly,
+		NextChargeDate:  "2020-02-29",
+		CompletedCycles: 1,
+		MaxCycles:       12,
+	}
+
+	bytes, err := json.Marshal(expectedResponse)
+	assert.Nil(t, err)
+	assert.NotNil(t, bytes)
+
+	var actualResponse SubscriptionResponse
+
+	err = json.Unmarshal(bytes, &actualResponse)
+	assert.Nil(t, err)
+	assert.EqualValues(t, expectedResponse, actualResponse)
+}
+
+func TestSubscriptionRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
+	request := SubscriptionRequest{
+		AuthId:     "invalid_id",
+		Amount:     -1,
+		Currency:   "gbp",
+		Interval:   "fortnightly",
+		AnchorDate: "31-01-2020",
+		MaxCycles:  -1,
+	}
+
+	expectedErrors := []error{}
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidAuthIdField))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidAmount))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidCurrencyCode))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidInterval))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidAnchorDate))
+	expectedErrors = append(expectedErrors, errors.New(error_constant.InvalidMaxCycles))
+
+	actualErrors := request.ValidateFields()
+
+	assert.EqualValues(t, expectedErrors, actualErrors)
+}
+
+func TestSubscriptionRequest_ValidateFields_Valid(t *testing.T) {
+	t.Parallel()
+	request := SubscriptionRequest{
+		AuthId:     "970c8844-9238-4c31-95ca-6f079dd65729",
+		Amount:     10,
+		Currency:   "GBP",
+		Interval:   "Monthly",
+		AnchorDate: "2020-01-31",
+		MaxCycles:  12,
+	}
+
+	actualErrors := request.ValidateFields()
+
+	assert.EqualValues(t, []error{}, actualErrors)
+	assert.EqualValues(t, IntervalMonthly, request.Interval)
+}
+
+func TestSubscriptionStateRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
+	request := SubscriptionStateRequest{SubscriptionId: "invalid_id"}
+
+	expectedErrors := []error{errors.New(error_constant.InvalidSubscriptionIdField)}
+
+	assert.EqualValues(t, expectedErrors, request.ValidateFields())
+}
+
+func TestChargeDate(t *testing.T) {
+	t.Parallel()
+	anchor := time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+	assert.EqualValues(t, anchor, ChargeDate(anchor, IntervalMonthly, 0))
+	assert.EqualValues(t, time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC), ChargeDate(anchor, IntervalDaily, 1))
+	assert.EqualValues(t, time.Date(2020, time.February, 14, 0, 0, 0, 0, time.UTC), ChargeDate(anchor, IntervalWeekly, 2))
+	assert.EqualValues(t, time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC), ChargeDate(anchor, IntervalMonthly, 1))
+	assert.EqualValues(t, time.Date(2020, time.March, 31, 0, 0, 0, 0, time.UTC), ChargeDate(anchor, IntervalMonthly, 2))
+	assert.EqualValues(t, time.Date(2021, time.January, 31, 0, 0, 0, 0, time.UTC), ChargeDate(anchor, IntervalYearly, 1))
+
+	leapDay := time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)
+	assert.EqualValues(t, time.Date(2021, time.February, 28, 0, 0, 0, 0, time.UTC), ChargeDate(leapDay, IntervalYearly, 1))
+}
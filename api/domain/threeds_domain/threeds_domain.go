@@ -0,0 +1,46 @@
+package threeds_domain
+
+import (
+	"errors"
+	"payment-gateway-api/api/const/error_constant"
+	"strings"
+)
+
+const (
+	//StatePending is the state of the challenges the cardholder has not answered yet
+	StatePending = "pending"
+	//StateAuthenticated is the state of the challenges the cardholder passed, the authorisation can be completed
+	StateAuthenticated = "authenticated"
+	//StateFailed is the state of the challenges the cardholder failed, the authorisation is declined when completed
+	StateFailed = "failed"
+	//StateCompleted is the state of the challenges whose authorisation has been completed, whatever its outcome
+	StateCompleted = "completed"
+
+	//SimulatorCode is the only code the simulated access control server accepts, any other fails the challenge
+	SimulatorCode = "123456"
+)
+
+//ChallengeRequest is the format for the answer of the cardholder to the challenge of the simulated access control server
+type ChallengeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+//ChallengeResponse is the format for the challenges returned by the simulated access control server
+type ChallengeResponse struct {
+	AuthID     string  `json:"id"`
+	State      string  `json:"state"`
+	CardNumber string  `json:"card_number"`
+	Amount     float32 `json:"amount"`
+	Currency   string  `json:"currency"`
+	ExpiresAt  string  `json:"expires_at"`
+}
+
+//ValidateFields strips the spaces around the code and checks it is not empty
+func (r *ChallengeRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.Code = strings.TrimSpace(r.Code)
+	if r.Code == "" {
+		err = append(err, errors.New(error_constant.InvalidChallengeCode))
+	}
+	return err
+}
@@ -0,0 +1,18 @@
+package threeds_domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+)
+
+func TestChallengeRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := ChallengeRequest{Code: " 123456 "}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, "123456", request.Code)
+
+	request = ChallengeRequest{Code: "  "}
+	assert.EqualValues(t, []error{errors.New(error_constant.InvalidChallengeCode)}, request.ValidateFields())
+}
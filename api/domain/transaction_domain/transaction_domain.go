@@ -0,0 +1,260 @@
+package transaction_domain
+
+import (
+	"errors"
+	"io"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/domain/common_validation"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	//StateAuthorised is the state of the transactions holding their whole amount, or part of it once partially captured
+	//and then released by a refund
+	StateAuthorised = "authorised"
+	//StatePendingReview is the state of the transactions held by the fraud rules until an analyst approves them
+	StatePendingReview = "pending_review"
+	StateCaptured      = "captured"
+	StateRefunded      = "refunded"
+	StateVoided        = "voided"
+	//StateChargedBack is the state of the transactions whose merchant lost a dispute
+	StateChargedBack = "charged_back"
+
+	SortCreatedAt = "created_at"
+	SortAmount    = "amount"
+
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+
+	//DefaultLimit and MaxLimit are the number of transactions returned by page when no limit is given and at most
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var (
+	states     = []string{StateAuthorised, StatePendingReview, StateCaptured, StateRefunded, StateVoided, StateChargedBack}
+	operations = []string{"authorisation", "capture", "refund", "void", "chargeback"}
+
+	//ExportColumns are the columns of the exports, in the order they are written when none are chosen
+	ExportColumns = []string{"transaction_id", "merchant_id", "state", "card_number", "card_brand", "authorised_amount",
+		"currency", "authorised_at", "operation", "amount", "fee", "created_at"}
+)
+
+//SearchRequest is the format for the query parameters of the transaction search endpoint, every filter is optional.
+//The created at range includes from and excludes to
+type SearchRequest struct {
+	From       string  `form:"from"`
+	To         string  `form:"to"`
+	State      string  `form:"state"`
+	Currency   string  `form:"currency"`
+	MinAmount  float32 `form:"min_amount"`
+	MaxAmount  float32 `form:"max_amount"`
+	Bin        string  `form:"bin"`
+	Last4      string  `form:"last4"`
+	MerchantID string  `form:"merchant_id"`
+	Operation  string  `form:"operation"`
+	Sort       string  `form:"sort"`
+	Order      string  `form:"order"`
+	Limit      int     `form:"limit"`
+	Cursor     string  `form:"cursor"`
+}
+
+//ExportRequest is the format for the query parameters of the transaction export, the operations made from the start
+//to the end of the period are exported, the start included. The columns are a comma separated list of export columns
+type ExportRequest struct {
+	From    string `form:"from"`
+	To      string `form:"to"`
+	Format  string `form:"format"`
+	Columns string `form:"columns"`
+}
+
+//ExportFile is an export ready to be written, the transactions are only read as it is written so that no more than
+//a row of it is ever held in memory
+type ExportFile struct {
+	Name        string
+	ContentType string
+	Write       func(io.Writer) error
+}
+
+//SearchResponse is the format for a page of the transaction search, the next cursor is only set when there are
+//more transactions to return
+type SearchResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	NextCursor   string                `json:"next_cursor,omitempty"`
+}
+
+//TransactionResponse is the format for the transactions returned by the transaction endpoints, the net amount is what
+//the merchant keeps: the captured amount less the refunded and charged back amounts and the fees. The operations are
+//only returned with a single transaction
+type TransactionResponse struct {
+	ID                string              `json:"id"`
+	MerchantID        string              `json:"merchant_id,omitempty"`
+	State             string              `json:"state"`
+	CardBin           string              `json:"card_bin"`
+	CardLast4         string              `json:"card_last4"`
+	CardBrand         string              `json:"card_brand"`
+	Amount            float32             `json:"amount"`
+	AvailableAmount   float32             `json:"available_amount"`
+	CapturedAmount    float32             `json:"captured_amount"`
+	RefundedAmount    float32             `json:"refunded_amount"`
+	ChargedBackAmount float32             `json:"charged_back_amount"`
+	Fee               float32             `json:"fee"`
+	NetAmount         float32             `json:"net_amount"`
+	Currency          string              `json:"currency"`
+	CreatedAt         string              `json:"created_at"`
+	Operations        []OperationResponse `json:"operations,omitempty"`
+}
+
+//OperationResponse is the format for the operations of a transaction
+type OperationResponse struct {
+	Name      string  `json:"name"`
+	Amount    float32 `json:"amount"`
+	Fee       float32 `json:"fee"`
+	CreatedAt string  `json:"created_at"`
+}
+
+//ValidateFields strips all spaces from the filters, sets the default sort, order and limit and checks the validity of the fields
+func (r *SearchRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	from, to, rangeErr := r.parseRange()
+	if rangeErr != nil {
+		err = append(err, rangeErr)
+	} else if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		err = append(err, errors.New(error_constant.InvalidDateRange))
+	}
+	r.State = strings.ToLower(strings.TrimSpace(r.State))
+	if r.State != "" && !contains(states, r.State) {
+		err = append(err, errors.New(error_constant.InvalidTransactionState))
+	}
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	if r.Currency != "" && !common_validation.IsCurrencyCodeValid(r.Currency) {
+		err = append(err, errors.New(error_constant.InvalidCurrencyCode))
+	}
+	if r.MinAmount < 0 || r.MaxAmount < 0 {
+		err = append(err, errors.New(error_constant.InvalidAmount))
+	} else if r.MaxAmount > 0 && r.MinAmount > r.MaxAmount {
+		err = append(err, errors.New(error_constant.InvalidAmountRange))
+	}
+	r.Bin = strings.Replace(r.Bin, " ", "", -1)
+	if isValid, _ := regexp.MatchString(format_constant.BinLayout, r.Bin); r.Bin != "" && !isValid {
+		err = append(err, errors.New(error_constant.InvalidBin))
+	}
+	r.Last4 = strings.Replace(r.Last4, " ", "", -1)
+	if isValid, _ := regexp.MatchString("^[0-9]{4}$", r.Last4); r.Last4 != "" && !isValid {
+		err = append(err, errors.New(error_constant.InvalidCardLast4))
+	}
+	r.MerchantID = strings.TrimSpace(r.MerchantID)
+	if isValid, _ := regexp.MatchString(format_constant.MerchantIdLayout, r.MerchantID); r.MerchantID != "" && !isValid {
+		err = append(err, errors.New(error_constant.InvalidMerchantIdField))
+	}
+	r.Operation = strings.ToLower(strings.TrimSpace(r.Operation))
+	if r.Operation != "" && !contains(operations, r.Operation) {
+		err = append(err, errors.New(error_constant.InvalidOperationType))
+	}
+	r.Sort = strings.ToLower(strings.TrimSpace(r.Sort))
+	if r.Sort == "" {
+		r.Sort = SortCreatedAt
+	}
+	if r.Sort != SortCreatedAt && r.Sort != SortAmount {
+		err = append(err, errors.New(error_constant.InvalidSortField))
+	}
+	r.Order = strings.ToLower(strings.TrimSpace(r.Order))
+	if r.Order == "" {
+		r.Order = "desc"
+	}
+	if r.Order != "asc" && r.Order != "desc" {
+		err = append(err, errors.New(error_constant.InvalidSortOrder))
+	}
+	if r.Limit == 0 {
+		r.Limit = DefaultLimit
+	}
+	if r.Limit < 1 || r.Limit > MaxLimit {
+		err = append(err, errors.New(error_constant.InvalidPageLimit))
+	}
+	r.Cursor = strings.TrimSpace(r.Cursor)
+	return err
+}
+
+//ValidateFields strips all spaces from the fields, sets the default format and checks the validity of the fields
+func (r *ExportRequest) ValidateFields() []error {
+	var err = make([]error, 0)
+	r.From, r.To = strings.TrimSpace(r.From), strings.TrimSpace(r.To)
+	from, to, rangeErr := r.Range()
+	switch {
+	case r.From == "" || r.To == "":
+		err = append(err, errors.New(error_constant.ExportRangeRequired))
+	case rangeErr != nil:
+		err = append(err, rangeErr)
+	case !from.Before(to):
+		err = append(err, errors.New(error_constant.InvalidDateRange))
+	}
+	r.Format = strings.ToLower(strings.TrimSpace(r.Format))
+	if r.Format == "" {
+		r.Format = ExportCSV
+	}
+	if r.Format != ExportCSV && r.Format != ExportJSONL {
+		err = append(err, errors.New(error_constant.InvalidExportFormat))
+	}
+	r.Columns = strings.Replace(r.Columns, " ", "", -1)
+	chosen := make(map[string]bool)
+	for _, column := range r.ColumnList() {
+		if !contains(ExportColumns, column) || chosen[column] {
+			err = append(err, errors.New(error_constant.InvalidExportColumns))
+			break
+		}
+		chosen[column] = true
+	}
+	return err
+}
+
+//ColumnList returns the columns to export in the order they were given, all of them when none were
+func (r *ExportRequest) ColumnList() []string {
+	if r.Columns == "" {
+		return ExportColumns
+	}
+	return strings.Split(r.Columns, ",")
+}
+
+//Range returns the period of the export
+func (r *ExportRequest) Range() (time.Time, time.Time, error) {
+	return parseRange(r.From, r.To)
+}
+
+//Range returns the created at range of the search, the zero time when a bound is not set. It is only meaningful
+//once the fields have been validated
+func (r *SearchRequest) Range() (time.Time, time.Time) {
+	from, to, _ := r.parseRange()
+	return from, to
+}
+
+func (r *SearchRequest) parseRange() (time.Time, time.Time, error) {
+	r.From, r.To = strings.TrimSpace(r.From), strings.TrimSpace(r.To)
+	return parseRange(r.From, r.To)
+}
+
+//parseRange parses the bounds of a range, the zero time stands for a bound that is not set
+func parseRange(fromValue string, toValue string) (from time.Time, to time.Time, err error) {
+	if fromValue != "" {
+		if from, err = time.Parse(time.RFC3339, fromValue); err != nil {
+			return time.Time{}, time.Time{}, errors.New(error_constant.InvalidTimestampFilter)
+		}
+	}
+	if toValue != "" {
+		if to, err = time.Parse(time.RFC3339, toValue); err != nil {
+			return time.Time{}, time.Time{}, errors.New(error_constant.InvalidTimestampFilter)
+		}
+	}
+	return from, to, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
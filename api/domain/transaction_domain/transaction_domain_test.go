@@ -0,0 +1,94 @@
+package transaction_domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/const/error_constant"
+	"testing"
+	"time"
+)
+
+func TestSearchRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := SearchRequest{
+		From:       " 2020-06-01T00:00:00Z",
+		To:         "2020-07-01T00:00:00+01:00 ",
+		State:      " Captured ",
+		Currency:   "gbp",
+		MinAmount:  10,
+		MaxAmount:  100,
+		Bin:        "4000 00",
+		Last4:      " 0002",
+		MerchantID: " merchant-1 ",
+		Operation:  "Refund",
+	}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, StateCaptured, request.State)
+	assert.EqualValues(t, "GBP", request.Currency)
+	assert.EqualValues(t, "400000", request.Bin)
+	assert.EqualValues(t, "0002", request.Last4)
+	assert.EqualValues(t, "merchant-1", request.MerchantID)
+	assert.EqualValues(t, "refund", request.Operation)
+	assert.EqualValues(t, SortCreatedAt, request.Sort)
+	assert.EqualValues(t, "desc", request.Order)
+	assert.EqualValues(t, DefaultLimit, request.Limit)
+
+	from, to := request.Range()
+	assert.EqualValues(t, time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC), from.UTC())
+	assert.EqualValues(t, time.Date(2020, time.June, 30, 23, 0, 0, 0, time.UTC), to.UTC())
+}
+
+func TestSearchRequest_ValidateFields_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		request        SearchRequest
+		expectedErrors []error
+	}{
+		{"timestamp", SearchRequest{From: "2020-06-01"}, []error{errors.New(error_constant.InvalidTimestampFilter)}},
+		{"date range", SearchRequest{From: "2020-06-01T00:00:00Z", To: "2020-06-01T00:00:00Z"}, []error{errors.New(error_constant.InvalidDateRange)}},
+		{"state", SearchRequest{State: "settled"}, []error{errors.New(error_constant.InvalidTransactionState)}},
+		{"currency", SearchRequest{Currency: "pounds"}, []error{errors.New(error_constant.InvalidCurrencyCode)}},
+		{"negative amount", SearchRequest{MinAmount: -1}, []error{errors.New(error_constant.InvalidAmount)}},
+		{"amount range", SearchRequest{MinAmount: 20, MaxAmount: 10}, []error{errors.New(error_constant.InvalidAmountRange)}},
+		{"bin", SearchRequest{Bin: "4000"}, []error{errors.New(error_constant.InvalidBin)}},
+		{"last4", SearchRequest{Last4: "12a4"}, []error{errors.New(error_constant.InvalidCardLast4)}},
+		{"merchant", SearchRequest{MerchantID: "merchant 1"}, []error{errors.New(error_constant.InvalidMerchantIdField)}},
+		{"operation", SearchRequest{Operation: "settlement"}, []error{errors.New(error_constant.InvalidOperationType)}},
+		{"sort", SearchRequest{Sort: "currency"}, []error{errors.New(error_constant.InvalidSortField)}},
+		{"order", SearchRequest{Order: "up"}, []error{errors.New(error_constant.InvalidSortOrder)}},
+		{"limit", SearchRequest{Limit: MaxLimit + 1}, []error{errors.New(error_constant.InvalidPageLimit)}},
+		{"every field", SearchRequest{State: "settled", Sort: "currency", Limit: -1}, []error{
+			errors.New(error_constant.InvalidTransactionState),
+			errors.New(error_constant.InvalidSortField),
+			errors.New(error_constant.InvalidPageLimit),
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.EqualValues(t, tt.expectedErrors, tt.request.ValidateFields())
+		})
+	}
+}
+
+func TestExportRequest_ValidateFields(t *testing.T) {
+	t.Parallel()
+	request := ExportRequest{From: " 2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z ", Format: " CSV"}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, ExportCSV, request.Format)
+	assert.EqualValues(t, ExportColumns, request.ColumnList())
+
+	request = ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z", Columns: "operation, amount"}
+	assert.Empty(t, request.ValidateFields())
+	assert.EqualValues(t, []string{"operation", "amount"}, request.ColumnList())
+
+	request = ExportRequest{From: "2020-06-01", To: "2020-07-01T00:00:00Z", Format: "xml", Columns: "pan"}
+	expectedErrors := []error{
+		errors.New(error_constant.InvalidTimestampFilter),
+		errors.New(error_constant.InvalidExportFormat),
+		errors.New(error_constant.InvalidExportColumns),
+	}
+	assert.EqualValues(t, expectedErrors, request.ValidateFields())
+}
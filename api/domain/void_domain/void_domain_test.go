@@ -9,6 +9,7 @@ import (
 )
 
 func TestVoidResponse(t *testing.T) {
+	t.Parallel()
 	expectedResponse := VoidResponse{
 		IsSuccess: true,
 		Amount:    10,
@@ -28,6 +29,7 @@ func TestVoidResponse(t *testing.T) {
 }
 
 func TestVoidRequest_ValidateFields_Invalid(t *testing.T) {
+	t.Parallel()
 	request := VoidRequest{
 		AuthId: "invalid_id",
 	}
@@ -41,6 +43,7 @@ func TestVoidRequest_ValidateFields_Invalid(t *testing.T) {
 }
 
 func TestVoidRequest_ValidateFields_Valid(t *testing.T) {
+	t.Parallel()
 	request := VoidRequest{
 		AuthId: "970c8844-9238-4c31-95ca-6f079dd65729",
 	}
@@ -0,0 +1,112 @@
+package grpc_server
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/domain/capture_domain"
+	"payment-gateway-api/api/domain/refund_domain"
+	"payment-gateway-api/api/domain/void_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/paymentpb"
+	"payment-gateway-api/api/services/authorisation_service"
+	"payment-gateway-api/api/services/capture_service"
+	"payment-gateway-api/api/services/refund_service"
+	"payment-gateway-api/api/services/transaction_service"
+	"payment-gateway-api/api/services/void_service"
+)
+
+//Dependencies are the services the payment calls are served with, the same the HTTP handlers use
+type Dependencies struct {
+	AuthorisationService authorisation_service.Service
+	CaptureService       capture_service.Service
+	RefundService        refund_service.Service
+	VoidService          void_service.Service
+	TransactionService   transaction_service.Service
+	Logger               *logger.Logger
+	Timeouts             config.TimeoutsConfig
+}
+
+type paymentsServer struct {
+	authorisationService authorisation_service.Service
+	captureService       capture_service.Service
+	refundService        refund_service.Service
+	voidService          void_service.Service
+	transactionService   transaction_service.Service
+}
+
+//New creates the gRPC server of the payment calls, every call is tagged with a request ID and the merchant sent in
+//its metadata, is logged once it has been handled and has a deadline taken from the timeouts by full method name
+func New(deps Dependencies, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(interceptor(deps.Logger, deps.Timeouts)))
+	server := grpc.NewServer(opts...)
+	paymentpb.RegisterPaymentsServer(server, &paymentsServer{
+		authorisationService: deps.AuthorisationService,
+		captureService:       deps.CaptureService,
+		refundService:        deps.RefundService,
+		voidService:          deps.VoidService,
+		transactionService:   deps.TransactionService,
+	})
+	return server
+}
+
+//Authorise authorises a payment with the card details of the request
+func (s *paymentsServer) Authorise(ctx context.Context, request *paymentpb.AuthoriseRequest) (*paymentpb.Authorisation, error) {
+	result, apiError := s.authorisationService.AuthoriseTransaction(ctx, authRequest(request))
+	if apiError != nil {
+		return nil, statusError(apiError)
+	}
+	return authorisation(result), nil
+}
+
+//Capture captures an amount of an authorisation
+func (s *paymentsServer) Capture(ctx context.Context, request *paymentpb.CaptureRequest) (*paymentpb.CaptureResponse, error) {
+	result, apiError := s.captureService.CaptureTransactionAmount(ctx, capture_domain.CaptureRequest{AuthId: request.GetId(), Amount: request.GetAmount()})
+	if apiError != nil {
+		return nil, statusError(apiError)
+	}
+	return &paymentpb.CaptureResponse{
+		Success:   result.IsSuccess,
+		Amount:    result.Amount,
+		Currency:  result.Currency,
+		Fee:       result.Fee,
+		NetAmount: result.NetAmount,
+	}, nil
+}
+
+//Refund refunds an amount of the captures of an authorisation
+func (s *paymentsServer) Refund(ctx context.Context, request *paymentpb.RefundRequest) (*paymentpb.RefundResponse, error) {
+	result, apiError := s.refundService.RefundTransactionAmount(ctx, refund_domain.RefundRequest{AuthId: request.GetId(), Amount: request.GetAmount()})
+	if apiError != nil {
+		return nil, statusError(apiError)
+	}
+	return &paymentpb.RefundResponse{
+		Success:   result.IsSuccess,
+		Amount:    result.Amount,
+		Currency:  result.Currency,
+		Fee:       result.Fee,
+		NetAmount: result.NetAmount,
+	}, nil
+}
+
+//Void cancels an authorisation
+func (s *paymentsServer) Void(ctx context.Context, request *paymentpb.VoidRequest) (*paymentpb.VoidResponse, error) {
+	result, apiError := s.voidService.VoidTransaction(ctx, void_domain.VoidRequest{AuthId: request.GetId()})
+	if apiError != nil {
+		return nil, statusError(apiError)
+	}
+	return &paymentpb.VoidResponse{
+		Success:  result.IsSuccess,
+		Amount:   result.Amount,
+		Currency: result.Currency,
+	}, nil
+}
+
+//GetTransaction returns an authorisation with its operations
+func (s *paymentsServer) GetTransaction(ctx context.Context, request *paymentpb.GetTransactionRequest) (*paymentpb.Transaction, error) {
+	result, apiError := s.transactionService.GetTransaction(ctx, request.GetId())
+	if apiError != nil {
+		return nil, statusError(apiError)
+	}
+	return transaction(result), nil
+}
@@ -0,0 +1,430 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/capture_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/refund_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/domain/void_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"payment-gateway-api/api/paymentpb"
+	"testing"
+	"time"
+)
+
+var authID = "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+
+type authoriseServiceMock struct {
+	authoriseTransactionFunc func(context.Context, auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
+}
+
+func (a *authoriseServiceMock) AuthoriseTransaction(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return a.authoriseTransactionFunc(ctx, request)
+}
+
+func (a *authoriseServiceMock) AuthoriseStoredCardTransaction(context.Context, auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (a *authoriseServiceMock) CompleteAuthorisation(context.Context, string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+type captureServiceMock struct {
+	captureTransactionAmount func(capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface)
+}
+
+func (c *captureServiceMock) CaptureTransactionAmount(ctx context.Context, request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+	return c.captureTransactionAmount(request)
+}
+
+type refundServiceMock struct {
+	refundTransactionAmount func(refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface)
+}
+
+func (r *refundServiceMock) RefundTransactionAmount(ctx context.Context, request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+	return r.refundTransactionAmount(request)
+}
+
+type voidServiceMock struct {
+	voidTransaction func(void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface)
+}
+
+func (v *voidServiceMock) VoidTransaction(ctx context.Context, request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+	return v.voidTransaction(request)
+}
+
+type transactionServiceMock struct {
+	getTransaction func(context.Context, string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface)
+}
+
+func (t *transactionServiceMock) SearchTransactions(context.Context, transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (t *transactionServiceMock) SearchAllTransactions(context.Context, transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (t *transactionServiceMock) GetTransaction(ctx context.Context, id string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface) {
+	return t.getTransaction(ctx, id)
+}
+
+func (t *transactionServiceMock) ExportTransactions(context.Context, transaction_domain.ExportRequest) (*transaction_domain.ExportFile, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+//newClient serves the services over an in-memory connection and returns a client of it, along with the function
+//stopping both
+func newClient(t *testing.T, deps Dependencies) (paymentpb.PaymentsClient, func()) {
+	deps.Logger = logger.Discard()
+	if deps.Timeouts.Default.Duration == 0 {
+		deps.Timeouts.Default = config.Duration{Duration: 10 * time.Second}
+	}
+	listener := bufconn.Listen(1 << 20)
+	server := New(deps)
+	go server.Serve(listener)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	assert.Nil(t, err)
+	return paymentpb.NewPaymentsClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestAuthorise(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	var actualRequest auth_domain.AuthRequest
+	service.authoriseTransactionFunc = func(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		actualRequest = request
+		return &auth_domain.AuthResponse{
+			AuthID:    authID,
+			IsSuccess: true,
+			Status:    auth_domain.StatusApproved,
+			Amount:    10,
+			Currency:  "GBP",
+		}, nil
+	}
+	client, stop := newClient(t, Dependencies{AuthorisationService: service})
+	defer stop()
+
+	response, err := client.Authorise(context.Background(), &paymentpb.AuthoriseRequest{
+		CardDetails: &paymentpb.CardDetails{
+			CardNumber: "4929907390318794",
+			ExpiryDate: "12-2099",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "GBP",
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2099",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "GBP",
+	}, actualRequest)
+	assert.EqualValues(t, authID, response.GetId())
+	assert.True(t, response.GetSuccess())
+	assert.EqualValues(t, auth_domain.StatusApproved, response.GetStatus())
+	assert.EqualValues(t, 10, response.GetAmount())
+	assert.EqualValues(t, "GBP", response.GetCurrency())
+	assert.Nil(t, response.GetAvs())
+}
+
+func TestAuthorise_BillingAddress(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	var actualRequest auth_domain.AuthRequest
+	service.authoriseTransactionFunc = func(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		actualRequest = request
+		return &auth_domain.AuthResponse{
+			AuthID:    authID,
+			IsSuccess: true,
+			Status:    auth_domain.StatusApproved,
+			AVS:       &auth_domain.AVSResult{Address: auth_domain.AVSMatch, Postcode: auth_domain.AVSNoMatch},
+		}, nil
+	}
+	client, stop := newClient(t, Dependencies{AuthorisationService: service})
+	defer stop()
+
+	response, err := client.Authorise(context.Background(), &paymentpb.AuthoriseRequest{
+		CardDetails: &paymentpb.CardDetails{
+			CardholderName: "Jane Doe",
+			BillingAddress: &paymentpb.BillingAddress{Line1: "1 High Street", City: "London", Postcode: "SW1A 1AA", Country: "GB"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Jane Doe", actualRequest.CardDetails.CardholderName)
+	assert.EqualValues(t, &auth_domain.BillingAddress{Line1: "1 High Street", City: "London", Postcode: "SW1A 1AA", Country: "GB"},
+		actualRequest.CardDetails.BillingAddress)
+	assert.EqualValues(t, auth_domain.AVSMatch, response.GetAvs().GetAddress())
+	assert.EqualValues(t, auth_domain.AVSNoMatch, response.GetAvs().GetPostcode())
+}
+
+func TestAuthorise_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	service.authoriseTransactionFunc = func(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.AuthorisationFailure))
+	}
+	client, stop := newClient(t, Dependencies{AuthorisationService: service})
+	defer stop()
+
+	_, err := client.Authorise(context.Background(), &paymentpb.AuthoriseRequest{})
+	assert.EqualValues(t, codes.PermissionDenied, status.Code(err))
+	assert.EqualValues(t, "["+error_constant.AuthorisationFailure+"]", status.Convert(err).Message())
+}
+
+func TestAuthorise_MissingCard(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	var actualRequest auth_domain.AuthRequest
+	service.authoriseTransactionFunc = func(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		actualRequest = request
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidCardNumber))
+	}
+	client, stop := newClient(t, Dependencies{AuthorisationService: service})
+	defer stop()
+
+	_, err := client.Authorise(context.Background(), &paymentpb.AuthoriseRequest{Amount: 10, Currency: "GBP"})
+	assert.EqualValues(t, codes.InvalidArgument, status.Code(err))
+	assert.EqualValues(t, auth_domain.CardDetails{}, actualRequest.CardDetails)
+}
+
+func TestAuthorise_Metadata(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	var actualMerchant string
+	var deadline time.Time
+	service.authoriseTransactionFunc = func(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		actualMerchant = merchant.FromContext(ctx)
+		deadline, _ = ctx.Deadline()
+		return &auth_domain.AuthResponse{AuthID: authID}, nil
+	}
+	timeouts := config.TimeoutsConfig{
+		Default:   config.Duration{Duration: 10 * time.Second},
+		Endpoints: map[string]config.Duration{"/payment.v1.Payments/Authorise": {Duration: time.Minute}},
+	}
+	client, stop := newClient(t, Dependencies{AuthorisationService: service, Timeouts: timeouts})
+	defer stop()
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MerchantIDKey, "acme", RequestIDKey, "request-1")
+	_, err := client.Authorise(ctx, &paymentpb.AuthoriseRequest{}, grpc.Header(&header))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "acme", actualMerchant)
+	assert.EqualValues(t, []string{"request-1"}, header.Get(RequestIDKey))
+	assert.True(t, time.Until(deadline) > 30*time.Second)
+
+	_, err = client.Authorise(context.Background(), &paymentpb.AuthoriseRequest{}, grpc.Header(&header))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "", actualMerchant)
+	assert.Len(t, header.Get(RequestIDKey), 1)
+	assert.NotEqual(t, "request-1", header.Get(RequestIDKey)[0])
+}
+
+func TestAuthorise_Panic(t *testing.T) {
+	t.Parallel()
+	service := &authoriseServiceMock{}
+	service.authoriseTransactionFunc = func(ctx context.Context, request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		panic("unexpected")
+	}
+	client, stop := newClient(t, Dependencies{AuthorisationService: service})
+	defer stop()
+
+	_, err := client.Authorise(context.Background(), &paymentpb.AuthoriseRequest{})
+	assert.EqualValues(t, codes.Internal, status.Code(err))
+}
+
+func TestCapture(t *testing.T) {
+	t.Parallel()
+	service := &captureServiceMock{}
+	var actualRequest capture_domain.CaptureRequest
+	service.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		actualRequest = request
+		return &capture_domain.CaptureResponse{IsSuccess: true, Amount: 5, Currency: "GBP", Fee: 0.5, NetAmount: 4.5}, nil
+	}
+	client, stop := newClient(t, Dependencies{CaptureService: service})
+	defer stop()
+
+	response, err := client.Capture(context.Background(), &paymentpb.CaptureRequest{Id: authID, Amount: 5})
+	assert.Nil(t, err)
+	assert.EqualValues(t, capture_domain.CaptureRequest{AuthId: authID, Amount: 5}, actualRequest)
+	assert.True(t, response.GetSuccess())
+	assert.EqualValues(t, 5, response.GetAmount())
+	assert.EqualValues(t, "GBP", response.GetCurrency())
+	assert.EqualValues(t, 0.5, response.GetFee())
+	assert.EqualValues(t, 4.5, response.GetNetAmount())
+}
+
+func TestCapture_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &captureServiceMock{}
+	service.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusOK, errors.New(error_constant.CancelledTransaction))
+	}
+	client, stop := newClient(t, Dependencies{CaptureService: service})
+	defer stop()
+
+	_, err := client.Capture(context.Background(), &paymentpb.CaptureRequest{Id: authID, Amount: 5})
+	assert.EqualValues(t, codes.FailedPrecondition, status.Code(err))
+	assert.EqualValues(t, "["+error_constant.CancelledTransaction+"]", status.Convert(err).Message())
+}
+
+func TestRefund(t *testing.T) {
+	t.Parallel()
+	service := &refundServiceMock{}
+	var actualRequest refund_domain.RefundRequest
+	service.refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+		actualRequest = request
+		return &refund_domain.RefundResponse{IsSuccess: true, Amount: 5, Currency: "GBP"}, nil
+	}
+	client, stop := newClient(t, Dependencies{RefundService: service})
+	defer stop()
+
+	response, err := client.Refund(context.Background(), &paymentpb.RefundRequest{Id: authID, Amount: 5})
+	assert.Nil(t, err)
+	assert.EqualValues(t, refund_domain.RefundRequest{AuthId: authID, Amount: 5}, actualRequest)
+	assert.True(t, response.GetSuccess())
+	assert.EqualValues(t, 5, response.GetAmount())
+	assert.EqualValues(t, "GBP", response.GetCurrency())
+}
+
+func TestRefund_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &refundServiceMock{}
+	service.refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidAmount))
+	}
+	client, stop := newClient(t, Dependencies{RefundService: service})
+	defer stop()
+
+	_, err := client.Refund(context.Background(), &paymentpb.RefundRequest{Id: authID})
+	assert.EqualValues(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRefund_StateConflict(t *testing.T) {
+	t.Parallel()
+	service := &refundServiceMock{}
+	service.refundTransactionAmount = func(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.TransactionStateInvalid))
+	}
+	client, stop := newClient(t, Dependencies{RefundService: service})
+	defer stop()
+
+	//the transaction cannot be refunded in its state, the fields of the request are valid
+	_, err := client.Refund(context.Background(), &paymentpb.RefundRequest{Id: authID, Amount: 5})
+	assert.EqualValues(t, codes.FailedPrecondition, status.Code(err))
+	assert.EqualValues(t, "["+error_constant.TransactionStateInvalid+"]", status.Convert(err).Message())
+}
+
+func TestVoid(t *testing.T) {
+	t.Parallel()
+	service := &voidServiceMock{}
+	var actualRequest void_domain.VoidRequest
+	service.voidTransaction = func(request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+		actualRequest = request
+		return &void_domain.VoidResponse{IsSuccess: true, Amount: 10, Currency: "GBP"}, nil
+	}
+	client, stop := newClient(t, Dependencies{VoidService: service})
+	defer stop()
+
+	response, err := client.Void(context.Background(), &paymentpb.VoidRequest{Id: authID})
+	assert.Nil(t, err)
+	assert.EqualValues(t, void_domain.VoidRequest{AuthId: authID}, actualRequest)
+	assert.True(t, response.GetSuccess())
+	assert.EqualValues(t, 10, response.GetAmount())
+	assert.EqualValues(t, "GBP", response.GetCurrency())
+}
+
+func TestVoid_ErrorFromService(t *testing.T) {
+	t.Parallel()
+	service := &voidServiceMock{}
+	service.voidTransaction = func(request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.UnableToVoidTransaction))
+	}
+	client, stop := newClient(t, Dependencies{VoidService: service})
+	defer stop()
+
+	_, err := client.Void(context.Background(), &paymentpb.VoidRequest{Id: authID})
+	assert.EqualValues(t, codes.Internal, status.Code(err))
+}
+
+func TestGetTransaction(t *testing.T) {
+	t.Parallel()
+	service := &transactionServiceMock{}
+	service.getTransaction = func(ctx context.Context, id string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface) {
+		return &transaction_domain.TransactionResponse{
+			ID:             id,
+			State:          "captured",
+			Amount:         10,
+			CapturedAmount: 10,
+			Currency:       "GBP",
+			Operations: []transaction_domain.OperationResponse{
+				{Name: "authorisation", Amount: 10},
+				{Name: "capture", Amount: 10, Fee: 0.5},
+			},
+		}, nil
+	}
+	client, stop := newClient(t, Dependencies{TransactionService: service})
+	defer stop()
+
+	response, err := client.GetTransaction(context.Background(), &paymentpb.GetTransactionRequest{Id: authID})
+	assert.Nil(t, err)
+	assert.EqualValues(t, authID, response.GetId())
+	assert.EqualValues(t, "captured", response.GetState())
+	assert.EqualValues(t, 10, response.GetCapturedAmount())
+	assert.Len(t, response.GetOperations(), 2)
+	assert.EqualValues(t, "capture", response.GetOperations()[1].GetName())
+	assert.EqualValues(t, 0.5, response.GetOperations()[1].GetFee())
+}
+
+func TestGetTransaction_Error(t *testing.T) {
+	t.Parallel()
+	service := &transactionServiceMock{}
+	service.getTransaction = func(ctx context.Context, id string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
+	}
+	client, stop := newClient(t, Dependencies{TransactionService: service})
+	defer stop()
+
+	_, err := client.GetTransaction(context.Background(), &paymentpb.GetTransactionRequest{Id: authID})
+	assert.EqualValues(t, codes.NotFound, status.Code(err))
+}
+
+func TestCode(t *testing.T) {
+	t.Parallel()
+	assert.EqualValues(t, codes.FailedPrecondition, Code(http.StatusOK))
+	assert.EqualValues(t, codes.InvalidArgument, Code(http.StatusBadRequest))
+	assert.EqualValues(t, codes.InvalidArgument, Code(http.StatusUnprocessableEntity))
+	assert.EqualValues(t, codes.PermissionDenied, Code(http.StatusUnauthorized))
+	assert.EqualValues(t, codes.NotFound, Code(http.StatusNotFound))
+	assert.EqualValues(t, codes.ResourceExhausted, Code(http.StatusTooManyRequests))
+	assert.EqualValues(t, codes.Canceled, Code(error_domain.StatusClientClosedRequest))
+	assert.EqualValues(t, codes.Internal, Code(http.StatusInternalServerError))
+	assert.EqualValues(t, codes.DeadlineExceeded, Code(http.StatusGatewayTimeout))
+	assert.EqualValues(t, codes.Unknown, Code(http.StatusTeapot))
+}
@@ -0,0 +1,68 @@
+package grpc_server
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"payment-gateway-api/api/middleware"
+	"strings"
+	"time"
+)
+
+var (
+	//RequestIDKey and MerchantIDKey are the metadata keys of the request and merchant IDs, the HTTP headers in lower case
+	RequestIDKey  = strings.ToLower(middleware.RequestIDHeader)
+	MerchantIDKey = strings.ToLower(middleware.MerchantIDHeader)
+)
+
+//interceptor does for the gRPC calls what the request logger, deadline and recovery middleware do for the HTTP
+//requests: the request ID sent by the client is kept if valid and sent back in the header, the context carries a
+//logger tagged with the request ID and the merchant, and the merchant itself, and expires after the timeout of the method
+func interceptor(base *logger.Logger, timeouts config.TimeoutsConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response interface{}, err error) {
+		start := time.Now()
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		requestID := first(md, RequestIDKey)
+		if !middleware.IsValidIdentifier(requestID) {
+			requestID = uuid.New().String()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID))
+
+		fields := []logger.Field{logger.String("request_id", requestID)}
+		if merchantID := first(md, MerchantIDKey); middleware.IsValidIdentifier(merchantID) {
+			fields = append(fields, logger.String("merchant", merchantID))
+			ctx = merchant.NewContext(ctx, merchantID)
+		}
+		log := base.With(fields...)
+
+		ctx, cancel := context.WithTimeout(logger.NewContext(ctx, log), timeouts.For(info.FullMethod))
+		defer cancel()
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Error("call panicked", logger.Any("panic", recovered))
+				err = status.Error(codes.Internal, "internal error")
+			}
+			log.Info("call handled",
+				logger.String("method", info.FullMethod),
+				logger.String("code", status.Code(err).String()),
+				logger.Duration("duration_ms", time.Since(start)))
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package grpc_server
+
+import (
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/paymentpb"
+)
+
+//authRequest turns the authorisation request into the one the authorisation service validates, a missing
+//card is left empty for the validation to reject it
+func authRequest(request *paymentpb.AuthoriseRequest) auth_domain.AuthRequest {
+	card := request.GetCardDetails()
+	result := auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:         card.GetCardNumber(),
+			ExpiryDate:     card.GetExpiryDate(),
+			Cvv:            card.GetCvv(),
+			CardholderName: card.GetCardholderName(),
+		},
+		Amount:   request.GetAmount(),
+		Currency: request.GetCurrency(),
+	}
+	if address := card.GetBillingAddress(); address != nil {
+		result.CardDetails.BillingAddress = &auth_domain.BillingAddress{
+			Line1:    address.GetLine1(),
+			Line2:    address.GetLine2(),
+			City:     address.GetCity(),
+			Postcode: address.GetPostcode(),
+			Country:  address.GetCountry(),
+		}
+	}
+	return result
+}
+
+func authorisation(response *auth_domain.AuthResponse) *paymentpb.Authorisation {
+	result := &paymentpb.Authorisation{
+		Id:             response.AuthID,
+		Success:        response.IsSuccess,
+		Status:         response.Status,
+		Amount:         response.Amount,
+		Currency:       response.Currency,
+		ChallengeUrl:   response.ChallengeURL,
+		LiabilityShift: response.LiabilityShift,
+	}
+	if response.AVS != nil {
+		result.Avs = &paymentpb.AVSResult{Address: response.AVS.Address, Postcode: response.AVS.Postcode}
+	}
+	return result
+}
+
+func transaction(response *transaction_domain.TransactionResponse) *paymentpb.Transaction {
+	result := &paymentpb.Transaction{
+		Id:                response.ID,
+		MerchantId:        response.MerchantID,
+		State:             response.State,
+		CardBin:           response.CardBin,
+		CardLast4:         response.CardLast4,
+		CardBrand:         response.CardBrand,
+		Amount:            response.Amount,
+		AvailableAmount:   response.AvailableAmount,
+		CapturedAmount:    response.CapturedAmount,
+		RefundedAmount:    response.RefundedAmount,
+		ChargedBackAmount: response.ChargedBackAmount,
+		Fee:               response.Fee,
+		NetAmount:         response.NetAmount,
+		Currency:          response.Currency,
+		CreatedAt:         response.CreatedAt,
+	}
+	for _, operation := range response.Operations {
+		result.Operations = append(result.Operations, &paymentpb.Operation{
+			Name:      operation.Name,
+			Amount:    operation.Amount,
+			Fee:       operation.Fee,
+			CreatedAt: operation.CreatedAt,
+		})
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package grpc_server
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+)
+
+//stateConflicts are the error bodies answered with 422 because of the state of the transaction rather than the fields
+//of the request, the calls failing with them can succeed once the transaction is in the right state
+var stateConflicts = map[string]bool{
+	"[" + error_constant.TransactionStateInvalid + "]": true,
+}
+
+//Code returns the gRPC code of a gateway error status. The gateway answers the declined payments with 401, they are
+//refused rather than unauthenticated, and the operations of a cancelled transaction with 200. A 422 is an invalid
+//field unless statusError finds a state conflict
+func Code(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.FailedPrecondition
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case error_domain.StatusClientClosedRequest:
+		return codes.Canceled
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Unknown
+	}
+}
+
+//statusError turns a gateway error into the status the call fails with, its message is the one of the error body
+func statusError(apiError error_domain.GatewayErrorInterface) error {
+	code := Code(apiError.Status())
+	if apiError.Status() == http.StatusUnprocessableEntity && stateConflicts[apiError.ErrorMessage()] {
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, apiError.ErrorMessage())
+}
@@ -0,0 +1,99 @@
+package ledger
+
+import "math"
+
+//The accounts of the ledger, debits are positive and credits negative so that the postings of an entry add up to zero.
+//The hold of an authorisation is what the merchant can still capture on the card, it must always equal its available amount
+const (
+	//AccountCardholderHold is the amount held on the cards by the authorisations
+	AccountCardholderHold = "cardholder_hold"
+	//AccountCardholderFunds is the counterpart of the holds, the funds of the cardholders the issuers set aside
+	AccountCardholderFunds = "cardholder_funds"
+	//AccountMerchantReceivable is the amount captured for the merchants, paid out to them at settlement
+	AccountMerchantReceivable = "merchant_receivable"
+	//AccountRefundsPayable is the amount the merchants refunded, owed back to the cardholders
+	AccountRefundsPayable = "refunds_payable"
+	//AccountFees is the amount charged to the merchants for processing their payments
+	AccountFees = "fees"
+	//AccountChargebacks is the captured amount the merchants lost in disputes, returned to the cardholders
+	AccountChargebacks = "chargebacks"
+)
+
+//Posting is an amount, in hundredths of the currency, debited to an account when positive and credited when negative
+type Posting struct {
+	Account string
+	Amount  int64
+}
+
+//Balance is the sum of the postings of an account in a currency
+type Balance struct {
+	Account  string
+	Currency string
+	Amount   int64
+}
+
+//Hold is the balance of the hold account of an authorisation next to the amounts recorded on the authorisation itself
+type Hold struct {
+	AuthID          string
+	AvailableAmount float32
+	Voided          bool
+	Balance         int64
+}
+
+//Imbalance is a journal entry whose postings do not add up to zero
+type Imbalance struct {
+	EntryID uint
+	Amount  int64
+}
+
+//Postings returns the balanced postings recording an operation processing the amount on an authorisation,
+//nil for the operations moving no funds
+func Postings(operationName string, amount int64) []Posting {
+	switch operationName {
+	case "authorisation":
+		return transfer(AccountCardholderHold, AccountCardholderFunds, amount)
+	case "capture":
+		return transfer(AccountMerchantReceivable, AccountCardholderHold, amount)
+	case "refund":
+		//the refunded amount can be captured again, it returns to the hold
+		return transfer(AccountCardholderHold, AccountRefundsPayable, amount)
+	case "void":
+		return transfer(AccountCardholderFunds, AccountCardholderHold, amount)
+	case "chargeback":
+		//the amount lost in a dispute is taken back from the merchant, the hold is left as it is
+		return transfer(AccountChargebacks, AccountMerchantReceivable, amount)
+	}
+	return nil
+}
+
+//FeePostings returns the balanced postings charging the fee of an operation to the merchant, out of the amount it is
+//paid at settlement, nil when there is no fee. The fees returned on refunds are negative and reverse the charge
+func FeePostings(fee int64) []Posting {
+	if fee == 0 {
+		return nil
+	}
+	return transfer(AccountFees, AccountMerchantReceivable, fee)
+}
+
+//IsBalanced checks that the debits of the postings equal their credits
+func IsBalanced(postings []Posting) bool {
+	var sum int64
+	for _, posting := range postings {
+		sum += posting.Amount
+	}
+	return sum == 0
+}
+
+//MinorUnits converts an amount of the gateway, stored as a float32, into hundredths of its currency
+func MinorUnits(amount float32) int64 {
+	return int64(math.Round(float64(amount) * 100))
+}
+
+//MajorUnits converts an amount in hundredths of its currency back into units of the currency
+func MajorUnits(amount int64) float64 {
+	return float64(amount) / 100
+}
+
+func transfer(debit string, credit string, amount int64) []Posting {
+	return []Posting{{Account: debit, Amount: amount}, {Account: credit, Amount: -amount}}
+}
@@ -0,0 +1,42 @@
+package ledger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPostings(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		operation string
+		debit     string
+		credit    string
+	}{
+		{"authorisation", AccountCardholderHold, AccountCardholderFunds},
+		{"capture", AccountMerchantReceivable, AccountCardholderHold},
+		{"refund", AccountCardholderHold, AccountRefundsPayable},
+		{"void", AccountCardholderFunds, AccountCardholderHold},
+		{"chargeback", AccountChargebacks, AccountMerchantReceivable},
+	} {
+		postings := Postings(tc.operation, 1050)
+		assert.EqualValues(t, []Posting{{Account: tc.debit, Amount: 1050}, {Account: tc.credit, Amount: -1050}}, postings, tc.operation)
+		assert.True(t, IsBalanced(postings), tc.operation)
+	}
+	assert.Nil(t, Postings("review_approved", 1050))
+	assert.False(t, IsBalanced([]Posting{{Account: AccountCardholderHold, Amount: 1050}, {Account: AccountCardholderFunds, Amount: -1000}}))
+}
+
+func TestFeePostings(t *testing.T) {
+	t.Parallel()
+	assert.EqualValues(t, []Posting{{Account: AccountFees, Amount: 120}, {Account: AccountMerchantReceivable, Amount: -120}}, FeePostings(120))
+	assert.EqualValues(t, []Posting{{Account: AccountFees, Amount: -15}, {Account: AccountMerchantReceivable, Amount: 15}}, FeePostings(-15))
+	assert.Nil(t, FeePostings(0))
+}
+
+func TestMinorUnits(t *testing.T) {
+	t.Parallel()
+	assert.EqualValues(t, 10, MinorUnits(0.1))
+	assert.EqualValues(t, 2025, MinorUnits(20.25))
+	assert.EqualValues(t, 9999999, MinorUnits(99999.99))
+	assert.EqualValues(t, 20.25, MajorUnits(2025))
+}
@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Level is the severity of a log line
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+//String returns the name of the level as written in the log lines
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+//ParseLevel returns the level with the given name among debug, info, warn and error
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("log level %q is not valid, use one of %v", name, levelNames)
+}
+
+//Field is a key value pair attached to a log line
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+//String creates a field holding a string value
+func String(key string, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+//Int creates a field holding an integer value
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+//Duration creates a field holding a duration written in milliseconds
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: float64(value) / float64(time.Millisecond)}
+}
+
+//Err creates the error field of a log line
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error", Value: nil}
+	}
+	return Field{Key: "error", Value: err.Error()}
+}
+
+//Any creates a field holding any value that can be encoded to json
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+//output serialises the writes of every logger derived from the same root
+type output struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+//Logger writes levelled log lines as json objects, every line carries the fields of the logger
+//and every value goes through the redaction of card numbers and security codes
+type Logger struct {
+	out    *output
+	level  Level
+	now    func() time.Time
+	fields []Field
+}
+
+type contextKey struct{}
+
+//New creates a logger writing the lines at or above the given level to out
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		out:   &output{writer: out},
+		level: level,
+		now:   time.Now,
+	}
+}
+
+//Discard creates a logger dropping every line, mostly useful in tests
+func Discard() *Logger {
+	return New(ioutil.Discard, ErrorLevel+1)
+}
+
+//With returns a logger adding the given fields to every line
+func (l *Logger) With(fields ...Field) *Logger {
+	child := *l
+	child.fields = make([]Field, 0, len(l.fields)+len(fields))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	return &child
+}
+
+//NewContext returns a copy of the context carrying the logger
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+//Ctx returns the request scoped logger carried by the context, or l when the context has none
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	if ctx != nil {
+		if scoped, ok := ctx.Value(contextKey{}).(*Logger); ok {
+			return scoped
+		}
+	}
+	return l
+}
+
+//Debug writes a line at debug level
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.log(DebugLevel, msg, fields)
+}
+
+//Info writes a line at info level
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.log(InfoLevel, msg, fields)
+}
+
+//Warn writes a line at warn level
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.log(WarnLevel, msg, fields)
+}
+
+//Error writes a line at error level
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fields)
+}
+
+func (l *Logger) log(level Level, msg string, fields []Field) {
+	if level < l.level {
+		return
+	}
+
+	line := make(map[string]interface{}, len(l.fields)+len(fields)+3)
+	for _, field := range l.fields {
+		line[field.Key] = redactField(field.Key, field.Value)
+	}
+	for _, field := range fields {
+		line[field.Key] = redactField(field.Key, field.Value)
+	}
+	line["time"] = l.now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level.String()
+	line["msg"] = Redact(msg)
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		b, _ = json.Marshal(map[string]interface{}{
+			"time":  line["time"],
+			"level": ErrorLevel.String(),
+			"msg":   "unable to encode log line",
+			"error": Redact(err.Error()),
+		})
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_, _ = l.out.writer.Write(append(b, '\n'))
+}
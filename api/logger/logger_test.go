@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogger(level Level) (*Logger, *bytes.Buffer) {
+	buffer := &bytes.Buffer{}
+	l := New(buffer, level)
+	l.now = func() time.Time {
+		return time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	}
+	return l, buffer
+}
+
+func lines(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var decoded map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &decoded))
+		result = append(result, decoded)
+	}
+	return result
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+	level, err := ParseLevel("WARN")
+	assert.Nil(t, err)
+	assert.EqualValues(t, WarnLevel, level)
+
+	_, err = ParseLevel("verbose")
+	assert.EqualValues(t, `log level "verbose" is not valid, use one of [debug info warn error]`, err.Error())
+}
+
+func TestLogger_WritesJsonLinesAtOrAboveLevel(t *testing.T) {
+	t.Parallel()
+	l, buffer := newTestLogger(InfoLevel)
+
+	l.Debug("not written")
+	l.Info("captured", String("auth_id", "valid_id"), Int("attempt", 2))
+	l.Error("failed", Err(errors.New("record not found")))
+
+	written := lines(t, buffer)
+	assert.EqualValues(t, 2, len(written))
+	assert.EqualValues(t, map[string]interface{}{
+		"time":    "2020-06-15T12:00:00Z",
+		"level":   "info",
+		"msg":     "captured",
+		"auth_id": "valid_id",
+		"attempt": float64(2),
+	}, written[0])
+	assert.EqualValues(t, "error", written[1]["level"])
+	assert.EqualValues(t, "record not found", written[1]["error"])
+}
+
+func TestLogger_WithAndContext(t *testing.T) {
+	t.Parallel()
+	base, buffer := newTestLogger(DebugLevel)
+	scoped := base.With(String("request_id", "req-1"), String("merchant", "acme"))
+
+	ctx := NewContext(context.Background(), scoped)
+	base.Ctx(ctx).With(String("auth_id", "valid_id")).Info("voided")
+	base.Ctx(context.Background()).Info("no request")
+
+	written := lines(t, buffer)
+	assert.EqualValues(t, "req-1", written[0]["request_id"])
+	assert.EqualValues(t, "acme", written[0]["merchant"])
+	assert.EqualValues(t, "valid_id", written[0]["auth_id"])
+	_, hasRequestID := written[1]["request_id"]
+	assert.EqualValues(t, false, hasRequestID)
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		text     string
+		expected string
+	}{
+		{"card 4929907390318794 declined", "card ****8794 declined"},
+		{"card 4929 9073 9031 8794 declined", "card ****8794 declined"},
+		{"card 4929-9073-9031-8794 declined", "card ****8794 declined"},
+		{`{"number":"4000000000000119","cvv":"123"}`, `{"number":"****0119","cvv":"****"}`},
+		{"UPDATE auths SET cvv = 1234", "UPDATE auths SET cvv = ****"},
+		{"amount 10000 on 2020-06-15", "amount 10000 on 2020-06-15"},
+		{"fc958d27-8e8e-4825-b3ec-e5236a8e7d28", "fc958d27-8e8e-4825-b3ec-e5236a8e7d28"},
+	}
+
+	for _, testCase := range testCases {
+		assert.EqualValues(t, testCase.expected, Redact(testCase.text))
+	}
+}
+
+func TestLogger_RedactsFields(t *testing.T) {
+	t.Parallel()
+	l, buffer := newTestLogger(DebugLevel)
+
+	type cardDetails struct {
+		Number string `json:"number"`
+		Cvv    string `json:"cvv"`
+	}
+
+	l.Error("insert of 4929907390318794 failed",
+		Err(errors.New("UNIQUE constraint failed: 4929907390318794")),
+		String("cvv", "123"),
+		Any("card", cardDetails{Number: "4929907390318794", Cvv: "123"}))
+
+	written := lines(t, buffer)
+	assert.EqualValues(t, "insert of ****8794 failed", written[0]["msg"])
+	assert.EqualValues(t, "UNIQUE constraint failed: ****8794", written[0]["error"])
+	assert.EqualValues(t, "****", written[0]["cvv"])
+	assert.EqualValues(t, map[string]interface{}{"number": "****8794", "cvv": "****"}, written[0]["card"])
+	assert.NotContains(t, buffer.String(), "4929907390318794")
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const mask = "****"
+
+var (
+	//card numbers are 12 to 19 digits, optionally grouped with spaces or dashes
+	panPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){11,18}\b`)
+	//security codes written as a key value pair, in json, query strings or sql
+	cvvPattern    = regexp.MustCompile(`(?i)("?\b(?:cvv|cvc|cvv2|security_code)\b"?\s*[:=]\s*"?)\d{3,4}`)
+	sensitiveKeys = []string{"cvv", "cvc", "cvv2", "security_code"}
+)
+
+//Redact masks the card numbers and security codes found in the text,
+//only the last four digits of a card number are kept
+func Redact(text string) string {
+	text = panPattern.ReplaceAllStringFunc(text, func(pan string) string {
+		digits := strings.NewReplacer(" ", "", "-", "").Replace(pan)
+		return mask + digits[len(digits)-4:]
+	})
+	return cvvPattern.ReplaceAllString(text, "${1}"+mask)
+}
+
+//redactField masks the value of a field, any value that is not a number or a boolean is
+//redacted through its json encoding so that nested card details are masked as well
+func redactField(key string, value interface{}) interface{} {
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.EqualFold(key, sensitiveKey) {
+			return mask
+		}
+	}
+
+	switch v := value.(type) {
+	case nil, bool, int, int64, float32, float64:
+		return v
+	case string:
+		return Redact(v)
+	case error:
+		return Redact(v.Error())
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return mask
+	}
+	var redacted interface{}
+	if err := json.Unmarshal([]byte(Redact(string(b))), &redacted); err != nil {
+		return mask
+	}
+	return redacted
+}
@@ -0,0 +1,20 @@
+package merchant
+
+import "context"
+
+type contextKey struct{}
+
+//NewContext returns a copy of the context carrying the id of the merchant the request has been sent by
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+//FromContext returns the id of the merchant carried by the context, or an empty string when the context has none
+func FromContext(ctx context.Context) string {
+	if ctx != nil {
+		if id, ok := ctx.Value(contextKey{}).(string); ok {
+			return id
+		}
+	}
+	return ""
+}
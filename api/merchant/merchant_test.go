@@ -0,0 +1,13 @@
+package merchant
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+	assert.EqualValues(t, "", FromContext(context.Background()))
+	assert.EqualValues(t, "acme", FromContext(NewContext(context.Background(), "acme")))
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+//heldAmountTimeout bounds the query run on every scrape, prometheus does not pass the deadline of the scrape to collectors
+const heldAmountTimeout = 5 * time.Second
+
+//heldAmountCollector reads the open authorisations on every scrape, so that the gauges are never out of step with the db
+type heldAmountCollector struct {
+	source HeldAmountSource
+	open   *prometheus.Desc
+	held   *prometheus.Desc
+}
+
+func newHeldAmountCollector(source HeldAmountSource) *heldAmountCollector {
+	return &heldAmountCollector{
+		source: source,
+		open: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "open_authorisations"),
+			"Authorisations that still hold an amount, by currency.", []string{"currency"}, nil),
+		held: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "held_amount"),
+			"Amount held by the open authorisations, by currency.", []string{"currency"}, nil),
+	}
+}
+
+//Describe sends the descriptors of the gauges
+func (c *heldAmountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.open
+	ch <- c.held
+}
+
+//Collect sends the current value of the gauges, or an invalid metric if they cannot be read
+func (c *heldAmountCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), heldAmountTimeout)
+	defer cancel()
+
+	totals, err := c.source.OpenAuthorisationTotals(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.open, err)
+		return
+	}
+
+	for _, total := range totals {
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(total.Count), total.Currency)
+		ch <- prometheus.MustNewConstMetric(c.held, prometheus.GaugeValue, total.Amount, total.Currency)
+	}
+}
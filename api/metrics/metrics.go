@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"payment-gateway-api/api/domain/error_domain"
+	"strconv"
+	"time"
+)
+
+const (
+	namespace = "gateway"
+
+	//OutcomeApproved is the outcome of an operation that completed successfully
+	OutcomeApproved = "approved"
+	//OutcomeDeclined is the outcome of an operation refused by the acquirer or by the state of the card
+	OutcomeDeclined = "declined"
+	//OutcomeRejected is the outcome of an operation refused because of the request itself
+	OutcomeRejected = "rejected"
+	//OutcomeError is the outcome of an operation that failed within the gateway
+	OutcomeError = "error"
+	//OutcomeCancelled is the outcome of an operation abandoned by the client
+	OutcomeCancelled = "cancelled"
+)
+
+//HeldAmount is the total of the open authorisations of a currency
+type HeldAmount struct {
+	Currency string
+	Count    int
+	Amount   float64
+}
+
+//HeldAmountSource reports the open authorisations at the time the metrics are scraped
+type HeldAmountSource interface {
+	OpenAuthorisationTotals(context.Context) ([]HeldAmount, error)
+}
+
+//Metrics holds the prometheus collectors of a gateway instance
+type Metrics struct {
+	registry     *prometheus.Registry
+	operations   *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+	throttled    *prometheus.CounterVec
+	fraud        *prometheus.CounterVec
+	fraudRules   *prometheus.CounterVec
+	reviews      *prometheus.CounterVec
+	challenges   *prometheus.CounterVec
+	disputes     *prometheus.CounterVec
+}
+
+//New creates the collectors on a registry of their own, so that several gateways can live in the same process
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_total",
+			Help:      "Payment operations processed, by operation, outcome and error code.",
+		}, []string{"operation", "outcome", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP handlers, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_call_duration_seconds",
+			Help:      "Latency of the data access calls, by call and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"call", "outcome"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "throttled_requests_total",
+			Help:      "Requests refused by the rate limits, by route and by the scope of the exhausted bucket.",
+		}, []string{"route", "scope"}),
+		fraud: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fraud_decisions_total",
+			Help:      "Authorisations assessed by the fraud rules, by decision.",
+		}, []string{"decision"}),
+		fraudRules: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fraud_rules_triggered_total",
+			Help:      "Fraud rules triggered by the authorisations, by rule, action and whether the rule is a dry run.",
+		}, []string{"rule", "action", "dry_run"}),
+		reviews: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reviews_total",
+			Help:      "Authorisations held for review, by outcome: opened, approved, declined or expired.",
+		}, []string{"outcome"}),
+		challenges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "challenges_total",
+			Help:      "3-D Secure challenges, by outcome: opened, authenticated or failed.",
+		}, []string{"outcome"}),
+		disputes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dispute_events_total",
+			Help:      "Events of the disputes raised by the cardholders, by event: opened, evidence_required, evidence_added, submitted, won or lost.",
+		}, []string{"event"}),
+	}
+
+	m.registry.MustRegister(m.operations, m.httpDuration, m.dbDuration, m.throttled, m.fraud, m.fraudRules, m.reviews, m.challenges,
+		m.disputes, prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	return m
+}
+
+//Handler serves the collected metrics in the prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+//WatchHeldAmounts exposes the open authorisations and the amount they hold, read from the source on every scrape
+func (m *Metrics) WatchHeldAmounts(source HeldAmountSource) {
+	m.registry.MustRegister(newHeldAmountCollector(source))
+}
+
+//ObserveOperation counts an operation given the error, if any, returned by the service
+func (m *Metrics) ObserveOperation(operation string, err error_domain.GatewayErrorInterface) {
+	outcome, code := Outcome(err)
+	m.operations.WithLabelValues(operation, outcome, code).Inc()
+}
+
+//ObserveRequest records the latency of an HTTP request
+func (m *Metrics) ObserveRequest(method string, route string, status int, elapsed time.Duration) {
+	m.httpDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+//ObserveQuery records the latency of a data access call and whether it succeeded, found nothing or failed
+func (m *Metrics) ObserveQuery(call string, outcome string, elapsed time.Duration) {
+	m.dbDuration.WithLabelValues(call, outcome).Observe(elapsed.Seconds())
+}
+
+//ObserveThrottled counts a request refused because the bucket of its merchant or of its client ip was empty
+func (m *Metrics) ObserveThrottled(route string, scope string) {
+	m.throttled.WithLabelValues(route, scope).Inc()
+}
+
+//ObserveFraudDecision counts an authorisation assessed by the fraud rules
+func (m *Metrics) ObserveFraudDecision(decision string) {
+	m.fraud.WithLabelValues(decision).Inc()
+}
+
+//ObserveFraudRule counts a fraud rule triggered by an authorisation
+func (m *Metrics) ObserveFraudRule(rule string, action string, dryRun bool) {
+	m.fraudRules.WithLabelValues(rule, action, strconv.FormatBool(dryRun)).Inc()
+}
+
+//ObserveReview counts an authorisation held for review, or the decision taken on it
+func (m *Metrics) ObserveReview(outcome string) {
+	m.reviews.WithLabelValues(outcome).Inc()
+}
+
+//ObserveChallenge counts a 3-D Secure challenge sent to a cardholder, or its result
+func (m *Metrics) ObserveChallenge(outcome string) {
+	m.challenges.WithLabelValues(outcome).Inc()
+}
+
+//ObserveDisputeEvent counts an event of a dispute
+func (m *Metrics) ObserveDisputeEvent(event string) {
+	m.disputes.WithLabelValues(event).Inc()
+}
+
+//Outcome classifies a service error into an outcome and the status code reported to the client
+func Outcome(err error_domain.GatewayErrorInterface) (string, string) {
+	if err == nil {
+		return OutcomeApproved, "none"
+	}
+
+	code := strconv.Itoa(err.Status())
+	switch {
+	case err.Status() >= http.StatusInternalServerError:
+		return OutcomeError, code
+	case err.Status() == error_domain.StatusClientClosedRequest:
+		return OutcomeCancelled, code
+	//declines by the acquirer, expired cards and amounts that cannot be processed are all reported as unauthorised
+	case err.Status() == http.StatusUnauthorized:
+		return OutcomeDeclined, code
+	default:
+		return OutcomeRejected, code
+	}
+}
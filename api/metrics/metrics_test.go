@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/domain/error_domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+type heldAmountSourceMock struct {
+	openAuthorisationTotals func() ([]HeldAmount, error)
+}
+
+func (h *heldAmountSourceMock) OpenAuthorisationTotals(ctx context.Context) ([]HeldAmount, error) {
+	return h.openAuthorisationTotals()
+}
+
+func scrape(t *testing.T, m *Metrics) (int, string) {
+	response := httptest.NewRecorder()
+	m.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := ioutil.ReadAll(response.Body)
+	assert.Nil(t, err)
+	return response.Code, string(body)
+}
+
+func TestOutcome(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		err     error_domain.GatewayErrorInterface
+		outcome string
+		code    string
+	}{
+		{nil, OutcomeApproved, "none"},
+		{error_domain.New(http.StatusUnauthorized, errors.New("capture failure")), OutcomeDeclined, "401"},
+		{error_domain.New(http.StatusUnprocessableEntity, errors.New("invalid")), OutcomeRejected, "422"},
+		{error_domain.New(http.StatusOK, errors.New("transaction has been cancelled")), OutcomeRejected, "200"},
+		{error_domain.New(http.StatusInternalServerError, errors.New("db down")), OutcomeError, "500"},
+		{error_domain.New(http.StatusGatewayTimeout, errors.New("timed out")), OutcomeError, "504"},
+		{error_domain.New(error_domain.StatusClientClosedRequest, errors.New("cancelled")), OutcomeCancelled, "499"},
+	}
+
+	for _, c := range cases {
+		outcome, code := Outcome(c.err)
+		assert.EqualValues(t, c.outcome, outcome)
+		assert.EqualValues(t, c.code, code)
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	t.Parallel()
+	m := New()
+	m.ObserveOperation("capture", nil)
+	m.ObserveOperation("capture", error_domain.New(http.StatusUnauthorized, errors.New("capture failure")))
+	m.ObserveOperation("capture", error_domain.New(http.StatusUnauthorized, errors.New("card is expired")))
+	m.ObserveRequest(http.MethodPatch, "/capture", http.StatusOK, 20*time.Millisecond)
+	m.ObserveQuery("GetAuthRecordByID", "not_found", time.Millisecond)
+
+	code, body := scrape(t, m)
+	assert.EqualValues(t, http.StatusOK, code)
+	assert.Contains(t, body, `gateway_operations_total{code="none",operation="capture",outcome="approved"} 1`)
+	assert.Contains(t, body, `gateway_operations_total{code="401",operation="capture",outcome="declined"} 2`)
+	assert.Contains(t, body, `gateway_http_request_duration_seconds_count{method="PATCH",route="/capture",status="200"} 1`)
+	assert.Contains(t, body, `gateway_db_call_duration_seconds_count{call="GetAuthRecordByID",outcome="not_found"} 1`)
+}
+
+func TestMetrics_IndependentRegistries(t *testing.T) {
+	t.Parallel()
+	first, second := New(), New()
+	first.ObserveOperation("void", nil)
+
+	_, body := scrape(t, second)
+	assert.False(t, strings.Contains(body, `operation="void"`))
+}
+
+func TestMetrics_WatchHeldAmounts(t *testing.T) {
+	t.Parallel()
+	m := New()
+	m.WatchHeldAmounts(&heldAmountSourceMock{openAuthorisationTotals: func() ([]HeldAmount, error) {
+		return []HeldAmount{
+			{Currency: "EUR", Count: 1, Amount: 7.5},
+			{Currency: "GBP", Count: 3, Amount: 120},
+		}, nil
+	}})
+
+	_, body := scrape(t, m)
+	assert.Contains(t, body, `gateway_open_authorisations{currency="EUR"} 1`)
+	assert.Contains(t, body, `gateway_open_authorisations{currency="GBP"} 3`)
+	assert.Contains(t, body, `gateway_held_amount{currency="EUR"} 7.5`)
+	assert.Contains(t, body, `gateway_held_amount{currency="GBP"} 120`)
+}
+
+func TestMetrics_WatchHeldAmounts_SourceError(t *testing.T) {
+	t.Parallel()
+	m := New()
+	m.WatchHeldAmounts(&heldAmountSourceMock{openAuthorisationTotals: func() ([]HeldAmount, error) {
+		return nil, errors.New("db down")
+	}})
+
+	code, _ := scrape(t, m)
+	assert.EqualValues(t, http.StatusInternalServerError, code)
+}
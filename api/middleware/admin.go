@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+	"strings"
+)
+
+//AdminAuth refuses with 401 the requests that do not carry the admin token as a bearer token
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			apiError := error_domain.New(http.StatusUnauthorized, errors.New(error_constant.AdminUnauthorised))
+			c.AbortWithStatusJSON(apiError.Status(), apiError)
+			return
+		}
+		c.Next()
+	}
+}
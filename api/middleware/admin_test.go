@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	t.Parallel()
+	router := gin.New()
+	router.Use(AdminAuth("s3cret"))
+	router.GET("/admin/fraud/rules", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for authorization, expectedStatus := range map[string]int{
+		"Bearer s3cret": http.StatusOK,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"s3cret ":       http.StatusUnauthorized,
+		"":              http.StatusUnauthorized,
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/admin/fraud/rules", nil)
+		request.Header.Set("Authorization", authorization)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		assert.EqualValues(t, expectedStatus, response.Code, authorization)
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+)
+
+//BodyLimit refuses with 413 the requests declaring a body larger than maxBytes and caps the bodies of the
+//others, so that a body sent without a length fails to bind once it goes over the limit instead of being read whole.
+//The routes receiving uploads have their own limits, keyed by route
+func BodyLimit(defaultBytes int64, routes map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maxBytes := defaultBytes
+		if routeBytes, ok := routes[c.FullPath()]; ok {
+			maxBytes = routeBytes
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			apiError := error_domain.New(http.StatusRequestEntityTooLarge, errors.New(error_constant.RequestBodyTooLarge))
+			c.AbortWithStatusJSON(apiError.Status(), apiError)
+			return
+		}
+
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
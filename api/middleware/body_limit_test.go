@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	t.Parallel()
+	router := gin.New()
+	router.Use(BodyLimit(16, map[string]int64{"/upload": 32}))
+	bind := func(c *gin.Context) {
+		var body map[string]string
+		if err := c.BindJSON(&body); err != nil {
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	router.POST("/authorize", bind)
+	router.POST("/upload", bind)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/authorize", strings.NewReader(`{"a":"b"}`)))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/authorize", strings.NewReader(`{"a":"bbbbbbbbbbbbbbbb"}`)))
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, response.Code)
+	assert.EqualValues(t, `{"error":"[the request body is too large]"}`, response.Body.String())
+
+	//a body of unknown length is cut at the limit and fails to bind
+	request := httptest.NewRequest(http.MethodPost, "/authorize", ioutil.NopCloser(strings.NewReader(`{"a":"bbbbbbbbbbbbbbbb"}`)))
+	request.ContentLength = -1
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	assert.EqualValues(t, http.StatusBadRequest, response.Code)
+
+	//the upload route has a larger limit of its own
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{"a":"bbbbbbbbbbbbbbbb"}`)))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"payment-gateway-api/api/config"
+)
+
+//Deadline bounds the context of every request by the timeout configured for its route, the context
+//is also cancelled when the client goes away so that the services and the db stop working on it
+func Deadline(timeouts config.TimeoutsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeouts.For(c.FullPath()))
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/config"
+	"testing"
+	"time"
+)
+
+func TestDeadline_UsesRouteTimeout(t *testing.T) {
+	t.Parallel()
+	timeouts := config.TimeoutsConfig{
+		Default:   config.Duration{Duration: time.Minute},
+		Endpoints: map[string]config.Duration{"/capture": {Duration: time.Second}},
+	}
+
+	remaining := make(map[string]time.Duration)
+	router := gin.New()
+	router.Use(Deadline(timeouts))
+	handler := func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		assert.True(t, ok)
+		remaining[c.FullPath()] = time.Until(deadline)
+	}
+	router.PATCH("/capture", handler)
+	router.PATCH("/refund", handler)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/capture", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/refund", nil))
+
+	assert.True(t, remaining["/capture"] <= time.Second)
+	assert.True(t, remaining["/refund"] > time.Second && remaining["/refund"] <= time.Minute)
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DeprecationHeader = "Deprecation"
+	LinkHeader        = "Link"
+)
+
+//Deprecated flags the responses of a legacy route with the Deprecation header and links to its successor in the
+//versioned API, the route keeps working as it did
+func Deprecated(successor string) gin.HandlerFunc {
+	link := "<" + successor + `>; rel="successor-version"`
+	return func(c *gin.Context) {
+		c.Header(DeprecationHeader, "true")
+		c.Header(LinkHeader, link)
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeprecated(t *testing.T) {
+	t.Parallel()
+	router := gin.New()
+	router.PATCH("/capture", Deprecated("/v1/authorisations/{id}/captures"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPatch, "/capture", nil))
+	assert.EqualValues(t, http.StatusOK, response.Code)
+	assert.EqualValues(t, "true", response.Header().Get(DeprecationHeader))
+	assert.EqualValues(t, `</v1/authorisations/{id}/captures>; rel="successor-version"`, response.Header().Get(LinkHeader))
+}
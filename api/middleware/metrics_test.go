@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/metrics"
+	"testing"
+)
+
+func TestMetrics_RecordsRoute(t *testing.T) {
+	t.Parallel()
+	m := metrics.New()
+	router := gin.New()
+	router.Use(Metrics(m))
+	router.PATCH("/capture", func(c *gin.Context) {
+		c.Status(http.StatusUnauthorized)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/capture", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/capture/4929907390318794", nil))
+
+	response := httptest.NewRecorder()
+	m.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := ioutil.ReadAll(response.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `gateway_http_request_duration_seconds_count{method="PATCH",route="/capture",status="401"} 1`)
+	//the raw path of unknown routes never becomes a label
+	assert.Contains(t, string(body), `gateway_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, string(body), "4929907390318794")
+}
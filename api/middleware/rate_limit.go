@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/metrics"
+	"payment-gateway-api/api/ratelimit"
+	"strconv"
+	"time"
+)
+
+const (
+	APIKeyHeader     = "X-API-Key"
+	RetryAfterHeader = "Retry-After"
+
+	//scopes of the buckets a request is taken from
+	ScopeMerchant = "merchant"
+	ScopeClientIP = "client_ip"
+)
+
+//RateLimit refuses with 429 the requests whose merchant or client ip have exhausted the bucket of the route,
+//the merchant is identified by its id or, failing that, by its api key. Retry-After tells the client when
+//the next token will be available
+func RateLimit(limiter *ratelimit.Limiter, limits config.RateLimitsConfig, m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limits.Enabled {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		limit := limits.For(route)
+
+		//the client ip is checked first so that a flood from one address does not drain the bucket of a merchant
+		if ok, wait := limiter.Allow(bucketKey(route, ScopeClientIP, c.ClientIP()), toLimit(limit.ClientIP)); !ok {
+			throttle(c, m, route, ScopeClientIP, wait)
+			return
+		}
+		if merchant := merchantKey(c); merchant != "" {
+			if ok, wait := limiter.Allow(bucketKey(route, ScopeMerchant, merchant), toLimit(limit.Merchant)); !ok {
+				throttle(c, m, route, ScopeMerchant, wait)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+//merchantKey returns the merchant id when valid, or a digest of the api key so that keys are never held in memory
+func merchantKey(c *gin.Context) string {
+	if merchantID := c.GetHeader(MerchantIDHeader); identifierPattern.MatchString(merchantID) {
+		return "id:" + merchantID
+	}
+	if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+		digest := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(digest[:])
+	}
+	return ""
+}
+
+func bucketKey(route string, scope string, id string) string {
+	return route + "|" + scope + "|" + id
+}
+
+func toLimit(limit config.RateLimit) ratelimit.Limit {
+	return ratelimit.Limit{PerSecond: limit.PerSecond, Burst: limit.Burst}
+}
+
+func throttle(c *gin.Context, m *metrics.Metrics, route string, scope string, wait time.Duration) {
+	m.ObserveThrottled(route, scope)
+	//Retry-After is in whole seconds, rounding down would send the client back before a token is available
+	c.Header(RetryAfterHeader, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	apiError := error_domain.New(http.StatusTooManyRequests, errors.New(error_constant.TooManyRequests))
+	c.AbortWithStatusJSON(apiError.Status(), apiError)
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/metrics"
+	"payment-gateway-api/api/ratelimit"
+	"testing"
+	"time"
+)
+
+func newRateLimitedRouter(clk clock.Clock, m *metrics.Metrics) *gin.Engine {
+	limits := config.RateLimitsConfig{
+		Enabled: true,
+		Default: config.EndpointRateLimit{
+			Merchant: config.RateLimit{PerSecond: 1, Burst: 2},
+			ClientIP: config.RateLimit{PerSecond: 10, Burst: 10},
+		},
+		Endpoints: map[string]config.EndpointRateLimit{
+			"/authorize": {
+				Merchant: config.RateLimit{PerSecond: 0.5, Burst: 1},
+				ClientIP: config.RateLimit{PerSecond: 10, Burst: 10},
+			},
+		},
+	}
+	router := gin.New()
+	router.Use(RateLimit(ratelimit.New(clk), limits, m))
+	handler := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	router.POST("/authorize", handler)
+	router.PATCH("/capture", handler)
+	return router
+}
+
+func send(router *gin.Engine, method string, path string, header string, value string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	if header != "" {
+		request.Header.Set(header, value)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func TestRateLimit_PerMerchantAndRoute(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC))
+	m := metrics.New()
+	router := newRateLimitedRouter(clk, m)
+
+	assert.EqualValues(t, http.StatusOK, send(router, http.MethodPost, "/authorize", MerchantIDHeader, "acme").Code)
+	response := send(router, http.MethodPost, "/authorize", MerchantIDHeader, "acme")
+	assert.EqualValues(t, http.StatusTooManyRequests, response.Code)
+	assert.EqualValues(t, "2", response.Header().Get(RetryAfterHeader))
+	assert.EqualValues(t, `{"error":"[too many requests, retry later]"}`, response.Body.String())
+
+	//other merchants and other routes have their own buckets
+	assert.EqualValues(t, http.StatusOK, send(router, http.MethodPost, "/authorize", MerchantIDHeader, "globex").Code)
+	assert.EqualValues(t, http.StatusOK, send(router, http.MethodPatch, "/capture", MerchantIDHeader, "acme").Code)
+
+	clk.Advance(2 * time.Second)
+	assert.EqualValues(t, http.StatusOK, send(router, http.MethodPost, "/authorize", MerchantIDHeader, "acme").Code)
+
+	scrape := httptest.NewRecorder()
+	m.Handler().ServeHTTP(scrape, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := ioutil.ReadAll(scrape.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `gateway_throttled_requests_total{route="/authorize",scope="merchant"} 1`)
+}
+
+func TestRateLimit_ApiKeyAndClientIP(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC))
+	m := metrics.New()
+	router := newRateLimitedRouter(clk, m)
+
+	//merchants without an id are told apart by their api key
+	assert.EqualValues(t, http.StatusOK, send(router, http.MethodPost, "/authorize", APIKeyHeader, "sk_test_1").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, send(router, http.MethodPost, "/authorize", APIKeyHeader, "sk_test_1").Code)
+	assert.EqualValues(t, http.StatusOK, send(router, http.MethodPost, "/authorize", APIKeyHeader, "sk_test_2").Code)
+
+	//requests without a merchant are only limited by their client ip, of which 3 tokens are already used
+	for i := 0; i < 7; i++ {
+		assert.EqualValues(t, http.StatusOK, send(router, http.MethodPost, "/authorize", "", "").Code)
+	}
+	response := send(router, http.MethodPost, "/authorize", "", "")
+	assert.EqualValues(t, http.StatusTooManyRequests, response.Code)
+	assert.EqualValues(t, "1", response.Header().Get(RetryAfterHeader))
+
+	scrape := httptest.NewRecorder()
+	m.Handler().ServeHTTP(scrape, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := ioutil.ReadAll(scrape.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `gateway_throttled_requests_total{route="/authorize",scope="client_ip"} 1`)
+	assert.NotContains(t, string(body), "sk_test_1")
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	t.Parallel()
+	router := gin.New()
+	router.Use(RateLimit(ratelimit.New(clock.New()), config.RateLimitsConfig{Enabled: false}, metrics.New()))
+	router.POST("/authorize", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for i := 0; i < 5; i++ {
+		assert.EqualValues(t, http.StatusOK, send(router, http.MethodPost, "/authorize", MerchantIDHeader, "acme").Code)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"regexp"
+	"time"
+)
+
+const (
+	RequestIDHeader  = "X-Request-ID"
+	MerchantIDHeader = "X-Merchant-ID"
+)
+
+//identifiers sent by clients are only trusted when they cannot break the log lines
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+//RequestLogger assigns a request ID to every request, keeping the one sent by the client if valid,
+//and carries a logger tagged with the request ID and the merchant, and the merchant itself, in the request context.
+//A line is written for every request once it has been handled
+func RequestLogger(base *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !IsValidIdentifier(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := c.Request.Context()
+		fields := []logger.Field{logger.String("request_id", requestID)}
+		if merchantID := c.GetHeader(MerchantIDHeader); IsValidIdentifier(merchantID) {
+			fields = append(fields, logger.String("merchant", merchantID))
+			ctx = merchant.NewContext(ctx, merchantID)
+		}
+		log := base.With(fields...)
+		c.Request = c.Request.WithContext(logger.NewContext(ctx, log))
+
+		c.Next()
+
+		log.Info("request handled",
+			logger.String("method", c.Request.Method),
+			logger.String("path", c.Request.URL.Path),
+			logger.Int("status", c.Writer.Status()),
+			logger.Duration("duration_ms", time.Since(start)),
+			logger.String("client_ip", c.ClientIP()))
+	}
+}
+
+//IsValidIdentifier reports whether a request or merchant ID sent by a client can be written in the log lines
+func IsValidIdentifier(id string) bool {
+	return identifierPattern.MatchString(id)
+}
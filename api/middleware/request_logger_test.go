@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+)
+
+func newTestRouter() (*gin.Engine, *bytes.Buffer) {
+	buffer := &bytes.Buffer{}
+	base := logger.New(buffer, logger.DebugLevel)
+
+	router := gin.New()
+	router.Use(RequestLogger(base))
+	router.POST("/capture", func(c *gin.Context) {
+		base.Ctx(c.Request.Context()).With(logger.String("auth_id", "valid_id")).Info("transaction captured")
+		c.Status(http.StatusOK)
+	})
+	return router, buffer
+}
+
+func logLines(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var decoded map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &decoded))
+		result = append(result, decoded)
+	}
+	return result
+}
+
+func TestRequestLogger_PropagatesRequestID(t *testing.T) {
+	t.Parallel()
+	router, buffer := newTestRouter()
+
+	request := httptest.NewRequest(http.MethodPost, "/capture", nil)
+	request.Header.Set(RequestIDHeader, "client-request-1")
+	request.Header.Set(MerchantIDHeader, "acme")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	assert.EqualValues(t, "client-request-1", response.Header().Get(RequestIDHeader))
+
+	lines := logLines(t, buffer)
+	assert.EqualValues(t, 2, len(lines))
+	assert.EqualValues(t, "transaction captured", lines[0]["msg"])
+	assert.EqualValues(t, "client-request-1", lines[0]["request_id"])
+	assert.EqualValues(t, "acme", lines[0]["merchant"])
+	assert.EqualValues(t, "valid_id", lines[0]["auth_id"])
+
+	assert.EqualValues(t, "request handled", lines[1]["msg"])
+	assert.EqualValues(t, "client-request-1", lines[1]["request_id"])
+	assert.EqualValues(t, float64(http.StatusOK), lines[1]["status"])
+	assert.EqualValues(t, "/capture", lines[1]["path"])
+}
+
+func TestRequestLogger_AssignsRequestID(t *testing.T) {
+	t.Parallel()
+	router, buffer := newTestRouter()
+
+	//an identifier that could forge log content is replaced
+	request := httptest.NewRequest(http.MethodPost, "/capture", nil)
+	request.Header.Set(RequestIDHeader, "bad id\n{\"level\":\"error\"}")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	requestID := response.Header().Get(RequestIDHeader)
+	assert.EqualValues(t, 36, len(requestID))
+
+	lines := logLines(t, buffer)
+	assert.EqualValues(t, requestID, lines[0]["request_id"])
+	_, hasMerchant := lines[0]["merchant"]
+	assert.EqualValues(t, false, hasMerchant)
+}
@@ -0,0 +1,358 @@
+package openapi
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//Version is the version of the OpenAPI specification the documents follow
+	Version = "3.0.3"
+	//ContentJSON is the content type of the JSON bodies, the default one of the endpoints
+	ContentJSON = "application/json"
+	//AdminScheme is the security scheme of the admin endpoints, a bearer token
+	AdminScheme = "adminToken"
+	//ErrorSchema is the name of the component describing the error bodies
+	ErrorSchema = "error_domain.GatewayError"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+//Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+//Info describes the API of the document
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+//PathItem holds the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+//Operation describes a method of a path
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+//Parameter describes a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+//RequestBody describes the body of a request by content type
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+//Response describes the body of a response by content type and its headers
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+//Header describes a header of a response
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+//MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+//Schema describes a value, the structs are described once in the components and referenced
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+//Components holds the schemas and the security schemes referenced by the operations
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+//SecurityScheme describes how the requests are authenticated
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+//Endpoint is an operation of the gateway as it is routed: its path is in the gin syntax, its query and bodies are the
+//domain structs the handlers bind and write. A body is either a value of the struct or a *Schema for the bodies that
+//are not JSON
+type Endpoint struct {
+	Method     string
+	Path       string
+	ID         string
+	Tag        string
+	Summary    string
+	Admin      bool
+	Deprecated bool
+	Headers    []Parameter
+	Query      interface{}
+	Params     []Parameter
+	Body       interface{}
+	BodyType   string
+	Replies    []Reply
+	Errors     []int
+}
+
+//Reply is a response of an endpoint with the status it is written with, its headers are described by name
+type Reply struct {
+	Status      int
+	Description string
+	Body        interface{}
+	ContentType string
+	Headers     map[string]string
+}
+
+//New creates an empty document
+func New(title string, description string, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Description: description, Version: version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				ErrorSchema: {Type: "object", Properties: map[string]*Schema{"error": {Type: "string"}}},
+			},
+			SecuritySchemes: map[string]SecurityScheme{AdminScheme: {Type: "http", Scheme: "bearer"}},
+		},
+	}
+}
+
+//Add describes the endpoint in the document, its errors are answered with the error body
+func (d *Document) Add(endpoint Endpoint) {
+	operation := &Operation{
+		Tags:        []string{endpoint.Tag},
+		Summary:     endpoint.Summary,
+		OperationID: endpoint.ID,
+		Parameters:  d.parameters(endpoint),
+		Responses:   make(map[string]*Response),
+		Deprecated:  endpoint.Deprecated,
+	}
+	if endpoint.Body != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  d.content(endpoint.Body, endpoint.BodyType),
+		}
+	}
+	//the replies with the same status are the content types the response comes in
+	for _, reply := range endpoint.Replies {
+		response, ok := operation.Responses[strconv.Itoa(reply.Status)]
+		if !ok {
+			response = &Response{Description: reply.Description}
+			if reply.Description == "" {
+				response.Description = http.StatusText(reply.Status)
+			}
+			operation.Responses[strconv.Itoa(reply.Status)] = response
+		}
+		for name, description := range reply.Headers {
+			if response.Headers == nil {
+				response.Headers = make(map[string]Header)
+			}
+			response.Headers[name] = Header{Description: description, Schema: &Schema{Type: "string"}}
+		}
+		if reply.Body == nil {
+			continue
+		}
+		if response.Content == nil {
+			response.Content = make(map[string]MediaType)
+		}
+		for contentType, media := range d.content(reply.Body, reply.ContentType) {
+			response.Content[contentType] = media
+		}
+	}
+	for _, status := range endpoint.Errors {
+		operation.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{ContentJSON: {Schema: &Schema{Ref: ref(ErrorSchema)}}},
+		}
+	}
+	if endpoint.Admin {
+		operation.Security = []map[string][]string{{AdminScheme: {}}}
+	}
+
+	item, ok := d.Paths[Path(endpoint.Path)]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[Path(endpoint.Path)] = item
+	}
+	item[strings.ToLower(endpoint.Method)] = operation
+}
+
+//Operations returns the method and path, in the gin syntax, of every operation of the document sorted
+func (d *Document) Operations() []string {
+	var operations []string
+	for p, item := range d.Paths {
+		for method := range item {
+			operations = append(operations, strings.ToUpper(method)+" "+ginPath(p))
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+//Path turns a gin path into an OpenAPI one, /disputes/:id becomes /disputes/{id}
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func ginPath(openAPIPath string) string {
+	segments := strings.Split(openAPIPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+//parameters returns the path parameters of the endpoint followed by its headers and its query parameters, the query
+//struct fields are read from their form tags
+func (d *Document) parameters(endpoint Endpoint) []Parameter {
+	var parameters []Parameter
+	for _, segment := range strings.Split(endpoint.Path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			parameters = append(parameters, Parameter{Name: segment[1:], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	parameters = append(parameters, endpoint.Headers...)
+	if endpoint.Query != nil {
+		t := reflect.TypeOf(endpoint.Query)
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("form"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			parameters = append(parameters, Parameter{Name: name, In: "query", Schema: d.schema(t.Field(i).Type)})
+		}
+	}
+	return append(parameters, endpoint.Params...)
+}
+
+func (d *Document) content(body interface{}, contentType string) map[string]MediaType {
+	if contentType == "" {
+		contentType = ContentJSON
+	}
+	schema, ok := body.(*Schema)
+	if !ok {
+		schema = d.schema(reflect.TypeOf(body))
+	}
+	return map[string]MediaType{contentType: {Schema: schema}}
+}
+
+//schema describes the type as encoding/json writes it, the structs are added to the components under their package
+//and name the first time they are met
+func (d *Document) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		schema := d.schema(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.object(t)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			//the name is taken before the fields are described so that recursive structs end
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.object(t)
+		}
+		return &Schema{Ref: ref(name)}
+	default:
+		return &Schema{}
+	}
+}
+
+//object describes the exported fields of the struct by JSON name, the embedded structs without a name are inlined
+func (d *Document) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for property, value := range d.object(field.Type).Properties {
+				schema.Properties[property] = value
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = d.schema(field.Type)
+	}
+	return schema
+}
+
+func ref(name string) string {
+	return "#/components/schemas/" + name
+}
@@ -0,0 +1,100 @@
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type address struct {
+	Line1 string `json:"line1"`
+}
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type request struct {
+	base
+	Amount   float32            `json:"amount"`
+	Count    int64              `json:"count,omitempty"`
+	Approved *bool              `json:"approved"`
+	Address  *address           `json:"address"`
+	Tags     []string           `json:"tags"`
+	Totals   map[string]float64 `json:"totals"`
+	Content  []byte             `json:"content"`
+	At       time.Time          `json:"at"`
+	Code     int                `json:"-"`
+	internal string
+	Untagged bool
+}
+
+type filter struct {
+	State string `form:"state"`
+	Limit int    `form:"limit"`
+	Other string
+}
+
+func TestDocument_Add(t *testing.T) {
+	t.Parallel()
+	d := New("test", "", "1")
+	d.Add(Endpoint{
+		Method:     http.MethodPost,
+		Path:       "/things/:id",
+		ID:         "createThing",
+		Tag:        "things",
+		Summary:    "Creates a thing",
+		Admin:      true,
+		Deprecated: true,
+		Query:      filter{},
+		Body:       request{},
+		Replies: []Reply{
+			{Status: http.StatusCreated, Body: request{}, Headers: map[string]string{"Location": "the URL of the thing"}},
+			{Status: http.StatusOK, Body: &Schema{Type: "string"}, ContentType: "text/csv"},
+			{Status: http.StatusOK, Body: &Schema{Type: "string"}, ContentType: "text/plain"},
+		},
+		Errors: []int{http.StatusNotFound},
+	})
+
+	operation := d.Paths["/things/{id}"]["post"]
+	assert.EqualValues(t, []Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "state", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}, operation.Parameters)
+	assert.EqualValues(t, "#/components/schemas/openapi.request", operation.RequestBody.Content[ContentJSON].Schema.Ref)
+	assert.EqualValues(t, "Created", operation.Responses["201"].Description)
+	assert.EqualValues(t, map[string]Header{"Location": {Description: "the URL of the thing", Schema: &Schema{Type: "string"}}}, operation.Responses["201"].Headers)
+	assert.True(t, operation.Deprecated)
+	assert.EqualValues(t, 2, len(operation.Responses["200"].Content))
+	assert.EqualValues(t, ref(ErrorSchema), operation.Responses["404"].Content[ContentJSON].Schema.Ref)
+	assert.EqualValues(t, []map[string][]string{{AdminScheme: {}}}, operation.Security)
+
+	schema := d.Components.Schemas["openapi.request"]
+	assert.EqualValues(t, map[string]*Schema{
+		"id":       {Type: "string"},
+		"amount":   {Type: "number", Format: "float"},
+		"count":    {Type: "integer", Format: "int64"},
+		"approved": {Type: "boolean", Nullable: true},
+		"address":  {Ref: "#/components/schemas/openapi.address"},
+		"tags":     {Type: "array", Items: &Schema{Type: "string"}},
+		"totals":   {Type: "object", AdditionalProperties: &Schema{Type: "number", Format: "double"}},
+		"content":  {Type: "string", Format: "byte"},
+		"at":       {Type: "string", Format: "date-time"},
+		"Untagged": {Type: "boolean"},
+	}, schema.Properties)
+	assert.Contains(t, d.Components.Schemas, "openapi.address")
+
+	assert.EqualValues(t, []string{"POST /things/:id"}, d.Operations())
+	_, err := json.Marshal(d)
+	assert.Nil(t, err)
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+	assert.EqualValues(t, "/disputes/{id}/evidence/{evidence_id}", Path("/disputes/:id/evidence/:evidence_id"))
+	assert.EqualValues(t, "/disputes/:id/evidence/:evidence_id", ginPath("/disputes/{id}/evidence/{evidence_id}"))
+	assert.EqualValues(t, "/healthz", Path("/healthz"))
+}
@@ -0,0 +1,5 @@
+//Package paymentpb holds the protobuf messages and the gRPC client and server of the payment calls, generated from
+//payment.proto with protoc-gen-go v1.4.2 of github.com/golang/protobuf, which also generates the gRPC service
+package paymentpb
+
+//go:generate protoc -I.. --go_out=plugins=grpc,paths=source_relative:.. ../paymentpb/payment.proto
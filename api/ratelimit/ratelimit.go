@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"payment-gateway-api/api/clock"
+	"sync"
+	"time"
+)
+
+//idleTimeout is how long a full bucket is kept once its key stops sending requests
+const idleTimeout = 10 * time.Minute
+
+//Limit is the rate a bucket is refilled at and the number of tokens it holds when full
+type Limit struct {
+	PerSecond float64
+	Burst     int
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+//Limiter keeps a token bucket for every key it has seen, buckets are created full
+type Limiter struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+//New creates an empty limiter whose buckets are refilled according to the clock
+func New(clk clock.Clock) *Limiter {
+	return &Limiter{
+		clock:     clk,
+		buckets:   make(map[string]*bucket),
+		lastSweep: clk.Now(),
+	}
+}
+
+//Allow takes a token from the bucket of the key, when the bucket is empty the request is refused and
+//the time until the next token is returned
+func (l *Limiter) Allow(key string, limit Limit) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	//refill the tokens earned since the bucket was last used
+	b.tokens += now.Sub(b.lastSeen).Seconds() * limit.PerSecond
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.PerSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+//sweep forgets the buckets of the keys that have been idle long enough for their bucket to be full again,
+//so that the memory used by the limiter does not grow with every client ever seen
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/clock"
+	"testing"
+	"time"
+)
+
+var (
+	now = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(now)
+	limiter := New(clk)
+	limit := Limit{PerSecond: 2, Burst: 3}
+
+	//the burst is available straight away
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("merchant:acme", limit)
+		assert.True(t, allowed)
+	}
+
+	allowed, wait := limiter.Allow("merchant:acme", limit)
+	assert.False(t, allowed)
+	assert.EqualValues(t, 500*time.Millisecond, wait)
+
+	//other keys have their own bucket
+	allowed, _ = limiter.Allow("merchant:globex", limit)
+	assert.True(t, allowed)
+
+	//a token is earned every half a second
+	clk.Advance(250 * time.Millisecond)
+	allowed, wait = limiter.Allow("merchant:acme", limit)
+	assert.False(t, allowed)
+	assert.EqualValues(t, 250*time.Millisecond, wait)
+
+	clk.Advance(250 * time.Millisecond)
+	allowed, _ = limiter.Allow("merchant:acme", limit)
+	assert.True(t, allowed)
+
+	//the bucket never holds more than the burst
+	clk.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("merchant:acme", limit)
+		assert.True(t, allowed)
+	}
+	allowed, _ = limiter.Allow("merchant:acme", limit)
+	assert.False(t, allowed)
+}
+
+func TestLimiter_ForgetsIdleBuckets(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(now)
+	limiter := New(clk)
+	limit := Limit{PerSecond: 1, Burst: 1}
+
+	limiter.Allow("client_ip:10.0.0.1", limit)
+	clk.Advance(5 * time.Minute)
+	limiter.Allow("client_ip:10.0.0.2", limit)
+	assert.Len(t, limiter.buckets, 2)
+
+	clk.Advance(6 * time.Minute)
+	limiter.Allow("client_ip:10.0.0.3", limit)
+	assert.Len(t, limiter.buckets, 2)
+	assert.NotContains(t, limiter.buckets, "client_ip:10.0.0.1")
+}
@@ -0,0 +1,143 @@
+package acs_service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/challenge"
+	"payment-gateway-api/api/domain/common_validation"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/threeds_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"strings"
+)
+
+//Store is the persistence the simulated access control server reads the challenges from and records their answers in
+type Store interface {
+	GetChallengeRecordByAuthID(context.Context, string) (*challenge.Challenge, error)
+	UpdateChallengeRecordState(context.Context, *challenge.Challenge, string) error
+}
+
+//Dependencies are the collaborators of the simulated access control server
+type Dependencies struct {
+	Store   Store
+	Clock   clock.Clock
+	Logger  *logger.Logger
+	Metrics *metrics.Metrics
+}
+
+type acsService struct {
+	store   Store
+	clock   clock.Clock
+	logger  *logger.Logger
+	metrics *metrics.Metrics
+}
+
+//Service simulates the access control server of the issuers, it shows the challenges to the cardholders and
+//authenticates those answering with the simulator code
+type Service interface {
+	GetChallenge(context.Context, string) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface)
+	AnswerChallenge(context.Context, string, threeds_domain.ChallengeRequest) (*threeds_domain.ChallengeResponse, error_domain.GatewayErrorInterface)
+}
+
+//New creates the simulated access control server from its dependencies
+func New(deps Dependencies) Service {
+	return &acsService{
+		store:   deps.Store,
+		clock:   deps.Clock,
+		logger:  deps.Logger,
+		metrics: deps.Metrics,
+	}
+}
+
+//GetChallenge returns the challenge shown to the cardholder
+func (a *acsService) GetChallenge(ctx context.Context, id string) (_ *threeds_domain.ChallengeResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	record, errInf := a.getChallenge(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	return toResponse(record), nil
+}
+
+//AnswerChallenge authenticates the cardholder when the code is the simulator code and fails the challenge otherwise,
+//a challenge can only be answered once and before it expires
+func (a *acsService) AnswerChallenge(ctx context.Context, id string, request threeds_domain.ChallengeRequest) (_ *threeds_domain.ChallengeResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	record, errInf := a.getChallenge(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	if record.State != threeds_domain.StatePending {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ChallengeStateInvalid))
+	}
+	now := a.clock.Now()
+	if now.After(record.ExpiresAt) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ChallengeExpired))
+	}
+
+	record.State = threeds_domain.StateFailed
+	if request.Code == threeds_domain.SimulatorCode {
+		record.State = threeds_domain.StateAuthenticated
+	}
+	record.UpdatedAt = now.UTC()
+
+	log := a.logger.Ctx(ctx).With(logger.String("auth_id", record.AuthID))
+	err := a.store.UpdateChallengeRecordState(ctx, record, threeds_domain.StatePending)
+	if err != nil {
+		//the challenge has been answered by another call since it was read
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ChallengeStateInvalid))
+		}
+		log.Error(error_constant.ChallengeUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ChallengeUpdateFailure))
+	}
+
+	a.metrics.ObserveChallenge(record.State)
+	log.Info("challenge answered", logger.String("state", record.State))
+	return toResponse(record), nil
+}
+
+func (a *acsService) getChallenge(ctx context.Context, id string) (*challenge.Challenge, error_domain.GatewayErrorInterface) {
+	id = strings.Replace(id, " ", "", -1)
+	if !common_validation.IsValidUUID(id) {
+		return nil, error_domain.New(http.StatusBadRequest, errors.New(error_constant.InvalidAuthIdField))
+	}
+
+	record, err := a.store.GetChallengeRecordByAuthID(ctx, id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.ChallengeNotFound))
+		}
+		a.logger.Ctx(ctx).Error(error_constant.ChallengeRetrievalFailure, logger.String("auth_id", id), logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ChallengeRetrievalFailure))
+	}
+	return record, nil
+}
+
+//toResponse converts a challenge record into the format shown to the cardholder, only the last digits of the card are shown
+func toResponse(record *challenge.Challenge) *threeds_domain.ChallengeResponse {
+	number := record.Number
+	if len(number) > 4 {
+		number = number[len(number)-4:]
+	}
+	return &threeds_domain.ChallengeResponse{
+		AuthID:     record.AuthID,
+		State:      record.State,
+		CardNumber: "****" + number,
+		Amount:     record.Amount,
+		Currency:   record.Currency,
+		ExpiresAt:  record.ExpiresAt.UTC().Format(format_constant.TimestampLayout),
+	}
+}
@@ -0,0 +1,138 @@
+package acs_service
+
+import (
+	"context"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/challenge"
+	"payment-gateway-api/api/domain/threeds_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"testing"
+	"time"
+)
+
+var (
+	now    = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	authID = "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+)
+
+type storeMock struct {
+	record    *challenge.Challenge
+	getErr    error
+	updateErr error
+	updated   *challenge.Challenge
+	from      string
+}
+
+func (s *storeMock) GetChallengeRecordByAuthID(ctx context.Context, id string) (*challenge.Challenge, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	if s.record == nil || s.record.AuthID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	record := *s.record
+	return &record, nil
+}
+
+func (s *storeMock) UpdateChallengeRecordState(ctx context.Context, data *challenge.Challenge, from string) error {
+	if s.updateErr != nil {
+		return s.updateErr
+	}
+	s.updated = data
+	s.from = from
+	return nil
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{
+		Store:   store,
+		Clock:   clock.NewFake(now),
+		Logger:  logger.Discard(),
+		Metrics: metrics.New(),
+	})
+}
+
+func pendingChallenge() *challenge.Challenge {
+	return &challenge.Challenge{
+		AuthID:    authID,
+		Number:    "4929907390318794",
+		Amount:    10,
+		Currency:  "EUR",
+		State:     threeds_domain.StatePending,
+		ExpiresAt: now.Add(time.Minute),
+	}
+}
+
+func TestAcsService_GetChallenge(t *testing.T) {
+	t.Parallel()
+	response, errInf := newService(&storeMock{record: pendingChallenge()}).GetChallenge(context.Background(), authID)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, threeds_domain.ChallengeResponse{
+		AuthID:     authID,
+		State:      threeds_domain.StatePending,
+		CardNumber: "****8794",
+		Amount:     10,
+		Currency:   "EUR",
+		ExpiresAt:  "2020-06-15T12:01:00Z",
+	}, *response)
+
+	_, errInf = newService(&storeMock{}).GetChallenge(context.Background(), authID)
+	assert.EqualValues(t, http.StatusNotFound, errInf.Status())
+}
+
+func TestAcsService_AnswerChallenge(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{record: pendingChallenge()}
+	response, errInf := newService(store).AnswerChallenge(context.Background(), authID, threeds_domain.ChallengeRequest{Code: " 123456 "})
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, threeds_domain.StateAuthenticated, response.State)
+	assert.EqualValues(t, threeds_domain.StatePending, store.from)
+	assert.EqualValues(t, threeds_domain.StateAuthenticated, store.updated.State)
+
+	store = &storeMock{record: pendingChallenge()}
+	response, errInf = newService(store).AnswerChallenge(context.Background(), authID, threeds_domain.ChallengeRequest{Code: "000000"})
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, threeds_domain.StateFailed, response.State)
+	assert.EqualValues(t, threeds_domain.StateFailed, store.updated.State)
+}
+
+func TestAcsService_AnswerChallenge_Errors(t *testing.T) {
+	t.Parallel()
+	answered := pendingChallenge()
+	answered.State = threeds_domain.StateFailed
+	expired := pendingChallenge()
+	expired.ExpiresAt = now.Add(-time.Second)
+
+	tests := []struct {
+		name           string
+		store          *storeMock
+		id             string
+		code           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"empty code", &storeMock{record: pendingChallenge()}, authID, " ", http.StatusUnprocessableEntity, error_constant.InvalidChallengeCode},
+		{"invalid id", &storeMock{record: pendingChallenge()}, "not-an-id", "123456", http.StatusBadRequest, error_constant.InvalidAuthIdField},
+		{"retrieval failure", &storeMock{getErr: errors.New("disk I/O error")}, authID, "123456", http.StatusInternalServerError, error_constant.ChallengeRetrievalFailure},
+		{"already answered", &storeMock{record: answered}, authID, "123456", http.StatusUnprocessableEntity, error_constant.ChallengeStateInvalid},
+		{"expired", &storeMock{record: expired}, authID, "123456", http.StatusUnprocessableEntity, error_constant.ChallengeExpired},
+		{"answered concurrently", &storeMock{record: pendingChallenge(), updateErr: gorm.ErrRecordNotFound}, authID, "123456", http.StatusUnprocessableEntity, error_constant.ChallengeStateInvalid},
+		{"update failure", &storeMock{record: pendingChallenge(), updateErr: errors.New("disk I/O error")}, authID, "123456", http.StatusInternalServerError, error_constant.ChallengeUpdateFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(tt.store).AnswerChallenge(context.Background(), tt.id, threeds_domain.ChallengeRequest{Code: tt.code})
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
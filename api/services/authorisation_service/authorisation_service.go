@@ -1,78 +1,501 @@
 package authorisation_service
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
-	"log"
 	"net/http"
+	"payment-gateway-api/api/acquirer"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/config"
 	"payment-gateway-api/api/const/error_constant"
-	dal "payment-gateway-api/api/data_access"
 	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/challenge"
+	"payment-gateway-api/api/data_access/database_model/decline"
+	"payment-gateway-api/api/data_access/database_model/review"
 	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/common_validation"
 	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fraud_domain"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/domain/threeds_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"payment-gateway-api/api/metrics"
+	"payment-gateway-api/api/services/fraud_service"
+	"strings"
 	"time"
 )
 
-type authorisationService struct{}
+//Store is the persistence the authorisation service saves the authorisations to
+type Store interface {
+	InsertAuthRecord(context.Context, *auth.Auth) error
+	InsertPendingAuthRecord(context.Context, *auth.Auth, *review.Review) error
+	InsertDecline(context.Context, *decline.Decline) error
+	GetBinCountry(context.Context, string) (string, error)
+	InsertChallengeRecord(context.Context, *challenge.Challenge) error
+	GetChallengeRecordByAuthID(context.Context, string) (*challenge.Challenge, error)
+	FinaliseChallengeRecord(context.Context, *challenge.Challenge, string, *auth.Auth, *review.Review) error
+}
+
+//Dependencies are the collaborators of the authorisation service, the authorisations held for review
+//are declined if nobody reviews them within the review SLA and the cards issued in the 3-D Secure
+//countries are authenticated by their cardholder before they are sent to the acquirer
+type Dependencies struct {
+	Store        Store
+	Acquirer     acquirer.Acquirer
+	FraudService fraud_service.Service
+	Clock        clock.Clock
+	Logger       *logger.Logger
+	Metrics      *metrics.Metrics
+	Limits       config.LimitsConfig
+	ReviewSLA    time.Duration
+	ThreeDS      config.ThreeDSConfig
+}
+
+type authorisationService struct {
+	store        Store
+	acquirer     acquirer.Acquirer
+	fraudService fraud_service.Service
+	clock        clock.Clock
+	logger       *logger.Logger
+	metrics      *metrics.Metrics
+	limits       config.LimitsConfig
+	reviewSLA    time.Duration
+	threeDS      config.ThreeDSConfig
+}
 
-type authorisationServiceInterface interface {
-	AuthoriseTransaction(auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
+//Service authorises the transactions of cardholders
+type Service interface {
+	AuthoriseTransaction(context.Context, auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
+	AuthoriseStoredCardTransaction(context.Context, auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
+	CompleteAuthorisation(context.Context, string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
 }
 
 var (
-	AuthorisationService authorisationServiceInterface = &authorisationService{}
-	operationName                                      = "authorisation"
+	operationName = "authorisation"
+
+	//reasons the declined authorisations are recorded with
+	declinedByAcquirer  = "acquirer"
+	declinedByFraud     = "fraud"
+	declinedByChallenge = "authentication"
 )
 
+//New creates the authorisation service from its dependencies
+func New(deps Dependencies) Service {
+	return &authorisationService{
+		store:        deps.Store,
+		acquirer:     deps.Acquirer,
+		fraudService: deps.FraudService,
+		clock:        deps.Clock,
+		logger:       deps.Logger,
+		metrics:      deps.Metrics,
+		limits:       deps.Limits,
+		reviewSLA:    deps.ReviewSLA,
+		threeDS:      deps.ThreeDS,
+	}
+}
+
 //AuthoriseTransaction authorises a transaction by making sure the request has valid fields
-func (a *authorisationService) AuthoriseTransaction(request auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
-	errs := request.ValidateFields()
+func (a *authorisationService) AuthoriseTransaction(ctx context.Context, request auth_domain.AuthRequest) (_ *auth_domain.AuthResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() {
+		errInf = error_domain.FromContext(ctx, errInf)
+		a.metrics.ObserveOperation(operationName, errInf)
+	}()
+
+	errs := request.ValidateFields(a.clock)
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusBadRequest, errs...)
+	}
+
+	return a.authorise(ctx, merchant.FromContext(ctx), request.CardDetails.Number, request.CardDetails.ExpiryDate, request.Amount, request.Currency,
+		newCardholder(request.CardDetails), true)
+}
+
+//AuthoriseStoredCardTransaction authorises a merchant initiated transaction against a card on file,
+//the cardholder is not there to authenticate so no challenge is ever sent
+func (a *authorisationService) AuthoriseStoredCardTransaction(ctx context.Context, request auth_domain.StoredCardAuthRequest) (_ *auth_domain.AuthResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() {
+		errInf = error_domain.FromContext(ctx, errInf)
+		a.metrics.ObserveOperation(operationName, errInf)
+	}()
+
+	errs := request.ValidateFields(a.clock)
 	if len(errs) > 0 {
 		return nil, error_domain.New(http.StatusBadRequest, errs...)
 	}
 
-	isReject, err := dal.Db.CheckRejectByCardNumber(operationName, request.CardDetails.Number)
+	return a.authorise(ctx, request.MerchantID, request.Number, request.ExpiryDate, request.Amount, request.Currency, auth.Cardholder{}, false)
+}
+
+//authorise assesses the transaction with the fraud rules, checks the card with the acquirer and stores the authorisation of the validated fields,
+//the authorisations the fraud rules flag for review are stored as pending until an analyst approves them and, when authenticate is set,
+//the cards issued in the 3-D Secure countries are challenged before anything is sent to the acquirer
+func (a *authorisationService) authorise(ctx context.Context, merchantID string, number string, expiryDate string, amount float32, currency string, holder auth.Cardholder, authenticate bool) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	if amount > a.limits.MaxAuthorisationAmount {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.AmountAboveLimit))
+	}
+
+	log := a.logger.Ctx(ctx)
+	if errInf := a.verifyAddress(ctx, number, &holder); errInf != nil {
+		return nil, errInf
+	}
+	assessment, errInf := a.fraudService.Evaluate(ctx, fraud_domain.Transaction{
+		Number:      number,
+		Amount:      amount,
+		Currency:    currency,
+		AVSAddress:  holder.AVSAddress,
+		AVSPostcode: holder.AVSPostcode,
+	})
+	if errInf != nil {
+		return nil, errInf
+	}
+	if assessment.Decision == fraud_domain.DecisionDeny {
+		log.Info("authorisation denied by the fraud rules", logger.Any("triggered_rules", assessment.TriggeredRules))
+		a.recordDecline(ctx, merchantID, number, amount, currency, declinedByFraud)
+		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.AuthorisationFailure))
+	}
+	var rules []string
+	if assessment.Decision == fraud_domain.DecisionReview {
+		rules = reviewRules(assessment)
+	}
+
+	if authenticate {
+		isRequired, errInf := a.isChallengeRequired(ctx, number)
+		if errInf != nil {
+			return nil, errInf
+		}
+		if isRequired {
+			return a.challenge(ctx, merchantID, number, expiryDate, amount, currency, holder, rules)
+		}
+	}
+
+	if errInf := a.checkWithAcquirer(ctx, merchantID, number, amount, currency); errInf != nil {
+		return nil, errInf
+	}
+
+	record := a.newAuthRecord(uuid.New().String(), number, expiryDate, amount, currency)
+	record.MerchantID = merchantID
+	record.Cardholder = holder
+	log = log.With(logger.String("auth_id", record.ID))
+
+	if len(rules) > 0 {
+		return a.holdForReview(ctx, &record, rules)
+	}
+
+	err := a.store.InsertAuthRecord(ctx, &record)
+	if err != nil {
+		log.Error("unable to store the authorisation", logger.Err(err))
+		return nil, &error_domain.GatewayError{
+			Code:  http.StatusInternalServerError,
+			Error: err.Error(),
+		}
+	}
+
+	log.Info("transaction authorised", logger.Any("amount", amount), logger.String("currency", currency))
+	return toResponse(&record, auth_domain.StatusApproved), nil
+}
+
+//CompleteAuthorisation sends the authorisation to the acquirer once its cardholder has passed the 3-D Secure challenge,
+//the liability for fraudulent chargebacks is then shifted to the issuer, it is declined when the cardholder failed it
+func (a *authorisationService) CompleteAuthorisation(ctx context.Context, id string) (_ *auth_domain.AuthResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() {
+		errInf = error_domain.FromContext(ctx, errInf)
+		a.metrics.ObserveOperation(operationName, errInf)
+	}()
+
+	id = strings.Replace(id, " ", "", -1)
+	if !common_validation.IsValidUUID(id) {
+		return nil, error_domain.New(http.StatusBadRequest, errors.New(error_constant.InvalidAuthIdField))
+	}
+
+	log := a.logger.Ctx(ctx).With(logger.String("auth_id", id))
+	record, err := a.store.GetChallengeRecordByAuthID(ctx, id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.ChallengeNotFound))
+		}
+		log.Error(error_constant.ChallengeRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ChallengeRetrievalFailure))
+	}
+
+	switch record.State {
+	case threeds_domain.StatePending:
+		if a.clock.Now().After(record.ExpiresAt) {
+			return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ChallengeExpired))
+		}
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ChallengeNotCompleted))
+	case threeds_domain.StateFailed:
+		if errInf := a.finalise(ctx, record, nil, nil); errInf != nil {
+			return nil, errInf
+		}
+		log.Info("authorisation declined, the cardholder failed the challenge")
+		a.recordDecline(ctx, record.MerchantID, record.Number, record.Amount, record.Currency, declinedByChallenge)
+		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.AuthenticationFailure))
+	case threeds_domain.StateAuthenticated:
+	default:
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ChallengeStateInvalid))
+	}
+
+	if errInf := a.checkWithAcquirer(ctx, record.MerchantID, record.Number, record.Amount, record.Currency); errInf != nil {
+		//the challenge is closed so that the declined authorisation cannot be completed again
+		if errInf.Status() == http.StatusUnauthorized {
+			if finaliseErr := a.finalise(ctx, record, nil, nil); finaliseErr != nil {
+				return nil, finaliseErr
+			}
+		}
+		return nil, errInf
+	}
+
+	authorised := a.newAuthRecord(record.AuthID, record.Number, record.ExpiryDate, record.Amount, record.Currency)
+	authorised.ThreeDSStatus = threeds_domain.StateAuthenticated
+	authorised.LiabilityShift = true
+	authorised.MerchantID = record.MerchantID
+	authorised.Cardholder = record.Cardholder
+
+	var pending *review.Review
+	if record.Rules != "" {
+		pending = a.newReview(&authorised, record.Rules)
+	}
+	if errInf := a.finalise(ctx, record, &authorised, pending); errInf != nil {
+		return nil, errInf
+	}
+
+	if pending != nil {
+		a.metrics.ObserveReview("opened")
+		log.Warn("authenticated authorisation held for review by the fraud rules", logger.String("triggered_rules", record.Rules))
+		return toResponse(&authorised, auth_domain.StatusPendingReview), nil
+	}
+	log.Info("authenticated transaction authorised", logger.Any("amount", record.Amount), logger.String("currency", record.Currency))
+	return toResponse(&authorised, auth_domain.StatusApproved), nil
+}
+
+//isChallengeRequired checks whether the card has been issued in one of the countries where its cardholder must be authenticated
+func (a *authorisationService) isChallengeRequired(ctx context.Context, number string) (bool, error_domain.GatewayErrorInterface) {
+	if len(a.threeDS.Countries) == 0 {
+		return false, nil
+	}
+	country, err := a.store.GetBinCountry(ctx, number)
 	if err != nil {
-		log.Println(err.Error())
+		a.logger.Ctx(ctx).Error(error_constant.AuthenticationCheckFailure, logger.Err(err))
+		return false, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.AuthenticationCheckFailure))
+	}
+	for _, c := range a.threeDS.Countries {
+		if c == country {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//challenge stores the authorisation as waiting for its cardholder to answer the challenge of the access control server,
+//the rules the fraud rules flagged it with are kept so that it is held for review once completed
+func (a *authorisationService) challenge(ctx context.Context, merchantID string, number string, expiryDate string, amount float32, currency string, holder auth.Cardholder, rules []string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	now := a.clock.Now().UTC()
+	record := challenge.Challenge{
+		AuthID:     uuid.New().String(),
+		MerchantID: merchantID,
+		Number:     number,
+		ExpiryDate: expiryDate,
+		Amount:     amount,
+		Currency:   currency,
+		State:      threeds_domain.StatePending,
+		Rules:      strings.Join(rules, ","),
+		ExpiresAt:  now.Add(a.threeDS.ChallengeTTL.Duration),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Cardholder: holder,
+	}
+	log := a.logger.Ctx(ctx).With(logger.String("auth_id", record.AuthID))
+
+	if err := a.store.InsertChallengeRecord(ctx, &record); err != nil {
+		log.Error("unable to store the challenge", logger.Err(err))
 		return nil, &error_domain.GatewayError{
+			Code:  http.StatusInternalServerError,
+			Error: err.Error(),
+		}
+	}
+
+	a.metrics.ObserveChallenge("opened")
+	log.Info("cardholder challenged")
+	return &auth_domain.AuthResponse{
+		AuthID:       record.AuthID,
+		IsSuccess:    false,
+		Status:       auth_domain.StatusRequiresAction,
+		Amount:       amount,
+		Currency:     currency,
+		ChallengeURL: strings.TrimRight(a.threeDS.ACSURL, "/") + "/" + record.AuthID,
+		AVS:          toAVSResult(&holder),
+	}, nil
+}
+
+//finalise closes the challenge together with the authorisation it led to, if any
+func (a *authorisationService) finalise(ctx context.Context, record *challenge.Challenge, authorised *auth.Auth, pending *review.Review) error_domain.GatewayErrorInterface {
+	from := record.State
+	now := a.clock.Now().UTC()
+	record.State = threeds_domain.StateCompleted
+	record.CompletedAt = now
+	record.UpdatedAt = now
+
+	err := a.store.FinaliseChallengeRecord(ctx, record, from, authorised, pending)
+	if err != nil {
+		//the authorisation has been completed by another call since the challenge was read
+		if err.Error() == "record not found" {
+			return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ChallengeStateInvalid))
+		}
+		a.logger.Ctx(ctx).Error(error_constant.ChallengeUpdateFailure, logger.String("auth_id", record.AuthID), logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ChallengeUpdateFailure))
+	}
+	return nil
+}
+
+//checkWithAcquirer asks the acquirer whether it declines the card, the declines are recorded for the repeated declines rules
+func (a *authorisationService) checkWithAcquirer(ctx context.Context, merchantID string, number string, amount float32, currency string) error_domain.GatewayErrorInterface {
+	log := a.logger.Ctx(ctx)
+	isReject, err := a.acquirer.IsDeclined(ctx, operationName, number)
+	if err != nil {
+		log.Error(error_constant.RejectRetrievalFailure, logger.Err(err))
+		return &error_domain.GatewayError{
 			Code:  http.StatusInternalServerError,
 			Error: error_constant.RejectRetrievalFailure,
 		}
 	}
 	if isReject {
-		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.AuthorisationFailure))
+		log.Info("authorisation declined by the acquirer")
+		a.recordDecline(ctx, merchantID, number, amount, currency, declinedByAcquirer)
+		return error_domain.New(http.StatusUnauthorized, errors.New(error_constant.AuthorisationFailure))
 	}
+	return nil
+}
+
+//verifyAddress asks the issuer through the acquirer whether the billing address given with the card matches the one it has on file,
+//the results are not_checked when no billing address was given
+func (a *authorisationService) verifyAddress(ctx context.Context, number string, holder *auth.Cardholder) error_domain.GatewayErrorInterface {
+	holder.AVSAddress = auth_domain.AVSNotChecked
+	holder.AVSPostcode = auth_domain.AVSNotChecked
+	if holder.BillingLine1 == "" {
+		return nil
+	}
+	addressMatch, postcodeMatch, err := a.acquirer.VerifyAddress(ctx, number)
+	if err != nil {
+		a.logger.Ctx(ctx).Error(error_constant.AddressVerificationFailure, logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.AddressVerificationFailure))
+	}
+	holder.AVSAddress = avsResult(addressMatch)
+	holder.AVSPostcode = avsResult(postcodeMatch)
+	return nil
+}
 
-	//generate uniqueID
-	authId := uuid.New().String()
+func avsResult(isMatch bool) string {
+	if isMatch {
+		return auth_domain.AVSMatch
+	}
+	return auth_domain.AVSNoMatch
+}
 
-	record := auth.Auth{
-		ID:               authId,
-		Number:           request.CardDetails.Number,
-		ExpiryDate:       request.CardDetails.ExpiryDate,
-		AuthorisedAmount: request.Amount,
-		AvailableAmount:  request.Amount,
-		Currency:         request.Currency,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+//newCardholder keeps the name and billing address given with the card
+func newCardholder(details auth_domain.CardDetails) auth.Cardholder {
+	holder := auth.Cardholder{CardholderName: details.CardholderName}
+	if address := details.BillingAddress; address != nil {
+		holder.BillingLine1 = address.Line1
+		holder.BillingLine2 = address.Line2
+		holder.BillingCity = address.City
+		holder.BillingPostcode = address.Postcode
+		holder.BillingCountry = address.Country
+	}
+	return holder
+}
+
+//newAuthRecord creates the authorisation of the whole amount
+func (a *authorisationService) newAuthRecord(id string, number string, expiryDate string, amount float32, currency string) auth.Auth {
+	return auth.Auth{
+		ID:               id,
+		Number:           number,
+		ExpiryDate:       expiryDate,
+		AuthorisedAmount: amount,
+		AvailableAmount:  amount,
+		Currency:         currency,
+		CreatedAt:        a.clock.Now().UTC(),
+		UpdatedAt:        a.clock.Now().UTC(),
 		DeletedAt:        time.Time{},
 	}
+}
 
-	err = dal.Db.InsertAuthRecord(&record)
-	if err != nil {
-		log.Println(err.Error())
+//holdForReview stores the authorisation with its entry in the review queue, it cannot be captured until it is approved
+func (a *authorisationService) holdForReview(ctx context.Context, record *auth.Auth, rules []string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	log := a.logger.Ctx(ctx).With(logger.String("auth_id", record.ID))
+
+	if err := a.store.InsertPendingAuthRecord(ctx, record, a.newReview(record, strings.Join(rules, ","))); err != nil {
+		log.Error("unable to store the authorisation", logger.Err(err))
 		return nil, &error_domain.GatewayError{
 			Code:  http.StatusInternalServerError,
 			Error: err.Error(),
 		}
 	}
 
-	response := auth_domain.AuthResponse{
-		AuthID:    authId,
-		IsSuccess: true,
-		Amount:    request.Amount,
-		Currency:  request.Currency,
+	a.metrics.ObserveReview("opened")
+	log.Warn("authorisation held for review by the fraud rules", logger.Any("triggered_rules", rules))
+	return toResponse(record, auth_domain.StatusPendingReview), nil
+}
+
+//newReview creates the entry of the authorisation in the review queue, due once the review SLA has passed
+func (a *authorisationService) newReview(record *auth.Auth, rules string) *review.Review {
+	now := a.clock.Now().UTC()
+	return &review.Review{
+		AuthID:    record.ID,
+		State:     review_domain.StatePending,
+		Rules:     rules,
+		Amount:    record.AuthorisedAmount,
+		Currency:  record.Currency,
+		DueAt:     now.Add(a.reviewSLA),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+//reviewRules returns the names of the rules holding the authorisation for review, the dry run ones are only reported
+func reviewRules(assessment *fraud_domain.Assessment) []string {
+	rules := make([]string, 0, len(assessment.TriggeredRules))
+	for _, rule := range assessment.TriggeredRules {
+		if !rule.DryRun {
+			rules = append(rules, rule.Name)
+		}
+	}
+	return rules
+}
+
+//toResponse converts the stored authorisation into the response of the authorisation endpoints, only the approved
+//ones are successful
+func toResponse(record *auth.Auth, status string) *auth_domain.AuthResponse {
+	return &auth_domain.AuthResponse{
+		AuthID:         record.ID,
+		IsSuccess:      status == auth_domain.StatusApproved,
+		Status:         status,
+		Amount:         record.AuthorisedAmount,
+		Currency:       record.Currency,
+		LiabilityShift: record.LiabilityShift,
+		AVS:            toAVSResult(&record.Cardholder),
 	}
+}
 
-	return &response, nil
+//toAVSResult returns the address verification results, there are none for the authorisations made without a billing address
+func toAVSResult(holder *auth.Cardholder) *auth_domain.AVSResult {
+	if holder.AVSAddress == "" || holder.AVSAddress == auth_domain.AVSNotChecked {
+		return nil
+	}
+	return &auth_domain.AVSResult{Address: holder.AVSAddress, Postcode: holder.AVSPostcode}
+}
+
+//recordDecline keeps the declined authorisation for the repeated declines rules and the reports, the decline stands even if it cannot be recorded
+func (a *authorisationService) recordDecline(ctx context.Context, merchantID string, number string, amount float32, currency string, reason string) {
+	record := decline.Decline{
+		MerchantID: merchantID,
+		Number:     number,
+		Amount:     amount,
+		Currency:   currency,
+		Reason:     reason,
+	}
+	if err := a.store.InsertDecline(ctx, &record); err != nil {
+		a.logger.Ctx(ctx).Error("unable to record the declined authorisation", logger.Err(err))
+	}
 }
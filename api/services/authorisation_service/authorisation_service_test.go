@@ -1,69 +1,149 @@
 package authorisation_service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/config"
 	"payment-gateway-api/api/const/error_constant"
-	"payment-gateway-api/api/data_access"
 	"payment-gateway-api/api/data_access/database_model/auth"
-	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/challenge"
+	"payment-gateway-api/api/data_access/database_model/decline"
+	"payment-gateway-api/api/data_access/database_model/review"
 	"payment-gateway-api/api/domain/auth_domain"
 	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fraud_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"payment-gateway-api/api/metrics"
 	"testing"
+	"time"
 )
 
 var (
-	insertAuthRecord        func(*auth.Auth) error
-	checkRejectByCardNumber func(string, string) (bool, error)
+	now = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
 )
 
-type databaseMock struct{}
+type storeMock struct {
+	insertAuthRecord           func(*auth.Auth) error
+	insertPendingAuthRecord    func(*auth.Auth, *review.Review) error
+	insertDecline              func(*decline.Decline) error
+	binCountry                 string
+	insertChallengeRecord      func(*challenge.Challenge) error
+	getChallengeRecordByAuthID func(string) (*challenge.Challenge, error)
+	finaliseChallengeRecord    func(*challenge.Challenge, string, *auth.Auth, *review.Review) error
+}
 
-func (db *databaseMock) GetOperationByAuthIDAndOperationName(string, string) (bool, operation.Operation, error) {
-	return true, operation.Operation{}, nil
+func (s *storeMock) InsertAuthRecord(ctx context.Context, data *auth.Auth) error {
+	return s.insertAuthRecord(data)
 }
 
-func (db *databaseMock) CheckRejectByCardNumber(opName string, cardNumber string) (bool, error) {
-	return checkRejectByCardNumber(opName, cardNumber)
+func (s *storeMock) InsertPendingAuthRecord(ctx context.Context, data *auth.Auth, pending *review.Review) error {
+	return s.insertPendingAuthRecord(data, pending)
 }
 
-func (db *databaseMock) UpdateAvailableAmountByAuthID(string, float32, string) error {
-	return nil
+func (s *storeMock) InsertDecline(ctx context.Context, data *decline.Decline) error {
+	if s.insertDecline == nil {
+		return nil
+	}
+	return s.insertDecline(data)
 }
 
-func (db *databaseMock) SoftDeleteAuthRecordByID(string) error {
-	return nil
+func (s *storeMock) GetBinCountry(ctx context.Context, number string) (string, error) {
+	return s.binCountry, nil
 }
 
-func (db *databaseMock) HardDeleteAuthRecordByID(string) error {
-	return nil
+func (s *storeMock) InsertChallengeRecord(ctx context.Context, data *challenge.Challenge) error {
+	return s.insertChallengeRecord(data)
+}
+
+func (s *storeMock) GetChallengeRecordByAuthID(ctx context.Context, id string) (*challenge.Challenge, error) {
+	return s.getChallengeRecordByAuthID(id)
+}
+
+func (s *storeMock) FinaliseChallengeRecord(ctx context.Context, data *challenge.Challenge, from string, authorised *auth.Auth, pending *review.Review) error {
+	return s.finaliseChallengeRecord(data, from, authorised, pending)
+}
+
+//fraudMock allows every transaction unless evaluate is set
+type fraudMock struct {
+	evaluate func(fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface)
+}
+
+func (f *fraudMock) Evaluate(ctx context.Context, transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+	if f.evaluate == nil {
+		return &fraud_domain.Assessment{Decision: fraud_domain.DecisionAllow}, nil
+	}
+	return f.evaluate(transaction)
+}
+
+func (f *fraudMock) DryRun(ctx context.Context, transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+	return f.Evaluate(ctx, transaction)
 }
 
-func (db *databaseMock) DeleteOperationRecordsByAuthID(string) error {
+func (f *fraudMock) ListRules(ctx context.Context) ([]fraud_domain.Rule, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (f *fraudMock) CreateRule(ctx context.Context, request fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (f *fraudMock) UpdateRule(ctx context.Context, id string, request fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (f *fraudMock) DeleteRule(ctx context.Context, id string) error_domain.GatewayErrorInterface {
 	return nil
 }
 
-func (db *databaseMock) Setup(string) error {
+func (f *fraudMock) SaveBinCountries(ctx context.Context, request fraud_domain.BinCountriesRequest) error_domain.GatewayErrorInterface {
 	return nil
 }
 
-func (db *databaseMock) GetAuthRecordByID(string) (bool, *auth.Auth, error) {
-	return false, nil, nil
+type acquirerMock struct {
+	isDeclined    func(string, string) (bool, error)
+	verifyAddress func(string) (bool, bool, error)
+}
+
+func (a *acquirerMock) IsDeclined(ctx context.Context, opName string, cardNumber string) (bool, error) {
+	return a.isDeclined(opName, cardNumber)
 }
 
-func (db *databaseMock) Close() error {
+func (a *acquirerMock) VerifyAddress(ctx context.Context, cardNumber string) (bool, bool, error) {
+	return a.verifyAddress(cardNumber)
+}
+
+func (a *acquirerMock) Check(ctx context.Context) error {
 	return nil
 }
 
-func (db *databaseMock) InsertAuthRecord(data *auth.Auth) error {
-	return insertAuthRecord(data)
+func newService(store *storeMock, acquirer *acquirerMock, limits config.LimitsConfig) Service {
+	return newServiceWithFraud(store, acquirer, &fraudMock{}, limits)
+}
+
+func newServiceWithFraud(store *storeMock, acquirer *acquirerMock, fraud *fraudMock, limits config.LimitsConfig) Service {
+	return New(Dependencies{
+		Store:        store,
+		Acquirer:     acquirer,
+		FraudService: fraud,
+		Clock:        clock.NewFake(now),
+		Logger:       logger.Discard(),
+		Metrics:      metrics.New(),
+		Limits:       limits,
+		ReviewSLA:    24 * time.Hour,
+		ThreeDS:      config.Default().ThreeDS,
+	})
 }
 
 func TestAuthorisationService_AuthorisePayment(t *testing.T) {
+	t.Parallel()
 	cardDetails := auth_domain.CardDetails{
 		Number:     "4929907390318794",
-		ExpiryDate: "12-3500",
+		ExpiryDate: "12-2021",
 		Cvv:        "123",
 	}
 	request := auth_domain.AuthRequest{
@@ -79,17 +159,16 @@ func TestAuthorisationService_AuthorisePayment(t *testing.T) {
 		Currency:  request.Currency,
 	}
 
-	insertAuthRecord = func(auth *auth.Auth) error {
-		return nil
-	}
-
-	checkRejectByCardNumber = func(opName string, cardNumber string) (b bool, err error) {
-		return false, nil
-	}
-
-	data_access.Db = &databaseMock{}
+	service := newService(
+		&storeMock{insertAuthRecord: func(auth *auth.Auth) error {
+			return nil
+		}},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (b bool, err error) {
+			return false, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
 
-	actualResponse, err := AuthorisationService.AuthoriseTransaction(request)
+	actualResponse, err := service.AuthoriseTransaction(context.Background(), request)
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedResponse.IsSuccess, actualResponse.IsSuccess)
 	assert.EqualValues(t, expectedResponse.Amount, actualResponse.Amount)
@@ -97,9 +176,10 @@ func TestAuthorisationService_AuthorisePayment(t *testing.T) {
 }
 
 func TestAuthorisationService_AuthorisePayment_Error(t *testing.T) {
+	t.Parallel()
 	cardDetails := auth_domain.CardDetails{
 		Number:     "4929907390318794",
-		ExpiryDate: "12-3500",
+		ExpiryDate: "12-2021",
 		Cvv:        "123",
 	}
 	request := auth_domain.AuthRequest{
@@ -115,37 +195,461 @@ func TestAuthorisationService_AuthorisePayment_Error(t *testing.T) {
 		Error: errorMessage,
 	}
 
-	insertAuthRecord = func(auth *auth.Auth) error {
-		return errors.New(errorMessage)
-	}
-
-	data_access.Db = &databaseMock{}
+	service := newService(
+		&storeMock{insertAuthRecord: func(auth *auth.Auth) error {
+			return errors.New(errorMessage)
+		}},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+			return false, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
 
-	resp, actualError := AuthorisationService.AuthoriseTransaction(request)
+	resp, actualError := service.AuthoriseTransaction(context.Background(), request)
 	assert.Nil(t, resp)
 	assert.EqualValues(t, expectedError.Status(), actualError.Status())
 	assert.EqualValues(t, expectedError.ErrorMessage(), actualError.ErrorMessage())
 }
 
 func TestAuthorisationService_AuthorisePayment_RejectedCardError(t *testing.T) {
-
+	t.Parallel()
 	request := auth_domain.AuthRequest{
 		CardDetails: auth_domain.CardDetails{
 			Number:     "4929907390318794",
-			ExpiryDate: "12-2999",
+			ExpiryDate: "12-2021",
 			Cvv:        "123",
 		},
 		Amount:   10,
 		Currency: "LKR",
 	}
 
-	checkRejectByCardNumber = func(opName string, cardNumber string) (bool, error) {
-		return false, errors.New("")
+	service := newService(
+		&storeMock{},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+			return false, errors.New("")
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+
+	actualResponse, err := service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, error_constant.RejectRetrievalFailure, err.ErrorMessage())
+}
+
+func TestAuthorisationService_AuthoriseStoredCardTransaction(t *testing.T) {
+	t.Parallel()
+	request := auth_domain.StoredCardAuthRequest{
+		Number:     "4929907390318794",
+		ExpiryDate: "12-2021",
+		Amount:     10,
+		Currency:   "GBP",
+	}
+
+	var insertedRecord *auth.Auth
+	service := newService(
+		&storeMock{insertAuthRecord: func(auth *auth.Auth) error {
+			insertedRecord = auth
+			return nil
+		}},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (b bool, err error) {
+			return false, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+
+	actualResponse, err := service.AuthoriseStoredCardTransaction(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, true, actualResponse.IsSuccess)
+	assert.EqualValues(t, request.Number, insertedRecord.Number)
+	assert.EqualValues(t, request.Amount, insertedRecord.AvailableAmount)
+}
+
+func TestAuthorisationService_AuthorisePayment_AmountAboveLimit(t *testing.T) {
+	t.Parallel()
+	request := auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2021",
+			Cvv:        "123",
+		},
+		Amount:   501,
+		Currency: "GBP",
 	}
 
-	data_access.Db = &databaseMock{}
+	expectedErrors := []error{errors.New(error_constant.AmountAboveLimit)}
+
+	service := newService(
+		&storeMock{},
+		&acquirerMock{},
+		config.LimitsConfig{MaxAuthorisationAmount: 500})
 
-	actualResponse, err := AuthorisationService.AuthoriseTransaction(request)
+	actualResponse, err := service.AuthoriseTransaction(context.Background(), request)
 	assert.Nil(t, actualResponse)
-	assert.EqualValues(t, error_constant.RejectRetrievalFailure, err.ErrorMessage())
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", expectedErrors), err.ErrorMessage())
+}
+
+func TestAuthorisationService_AuthorisePayment_DeclinedByAcquirer(t *testing.T) {
+	t.Parallel()
+	request := auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2021",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "GBP",
+	}
+
+	var recorded *decline.Decline
+	service := newService(
+		&storeMock{insertDecline: func(data *decline.Decline) error {
+			recorded = data
+			return nil
+		}},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+			return true, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+
+	actualResponse, err := service.AuthoriseTransaction(merchant.NewContext(context.Background(), "acme"), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusUnauthorized, err.Status())
+	assert.EqualValues(t, "4929907390318794", recorded.Number)
+	assert.EqualValues(t, "acquirer", recorded.Reason)
+	assert.EqualValues(t, "acme", recorded.MerchantID)
+}
+
+func TestAuthorisationService_AuthorisePayment_FraudDecisions(t *testing.T) {
+	t.Parallel()
+	request := auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2021",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "GBP",
+	}
+	assess := func(decision string) *fraudMock {
+		return &fraudMock{evaluate: func(transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+			assert.EqualValues(t, fraud_domain.Transaction{
+				Number:      "4929907390318794",
+				Amount:      10,
+				Currency:    "GBP",
+				AVSAddress:  auth_domain.AVSNotChecked,
+				AVSPostcode: auth_domain.AVSNotChecked,
+			}, transaction)
+			return &fraud_domain.Assessment{Decision: decision}, nil
+		}}
+	}
+
+	//a denied transaction never reaches the acquirer and looks like any other decline to the merchant
+	var recorded *decline.Decline
+	service := newServiceWithFraud(
+		&storeMock{insertDecline: func(data *decline.Decline) error {
+			recorded = data
+			return nil
+		}},
+		&acquirerMock{},
+		assess(fraud_domain.DecisionDeny),
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err := service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusUnauthorized, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.AuthorisationFailure)}), err.ErrorMessage())
+	assert.EqualValues(t, "fraud", recorded.Reason)
+
+	//transactions to review are held until an analyst approves them
+	var pending *review.Review
+	service = newServiceWithFraud(
+		&storeMock{insertPendingAuthRecord: func(auth *auth.Auth, data *review.Review) error {
+			assert.EqualValues(t, auth.ID, data.AuthID)
+			pending = data
+			return nil
+		}},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+			return false, nil
+		}},
+		&fraudMock{evaluate: func(transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+			return &fraud_domain.Assessment{Decision: fraud_domain.DecisionReview, TriggeredRules: []fraud_domain.TriggeredRule{
+				{Name: "high amount", Action: fraud_domain.DecisionReview},
+				{Name: "new velocity", Action: fraud_domain.DecisionDeny, DryRun: true},
+				{Name: "daily amount", Action: fraud_domain.DecisionReview},
+			}}, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err = service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, actualResponse.IsSuccess)
+	assert.EqualValues(t, auth_domain.StatusPendingReview, actualResponse.Status)
+	assert.EqualValues(t, "pending", pending.State)
+	assert.EqualValues(t, "high amount,daily amount", pending.Rules)
+	assert.EqualValues(t, now.Add(24*time.Hour), pending.DueAt)
+
+	//transactions whose risk cannot be assessed are not authorised
+	service = newServiceWithFraud(
+		&storeMock{},
+		&acquirerMock{},
+		&fraudMock{evaluate: func(transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+			return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FraudCheckFailure))
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err = service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+}
+
+func TestAuthorisationService_AuthorisePayment_Challenge(t *testing.T) {
+	t.Parallel()
+	request := auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2021",
+			Cvv:        "123",
+		},
+		Amount:   10,
+		Currency: "EUR",
+	}
+
+	//cards issued in the 3-D Secure countries are challenged before they reach the acquirer
+	var stored *challenge.Challenge
+	service := newService(
+		&storeMock{binCountry: "FR", insertChallengeRecord: func(data *challenge.Challenge) error {
+			stored = data
+			return nil
+		}},
+		&acquirerMock{},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err := service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, auth_domain.AuthResponse{
+		AuthID:       stored.AuthID,
+		Status:       auth_domain.StatusRequiresAction,
+		Amount:       10,
+		Currency:     "EUR",
+		ChallengeURL: "/acs/challenges/" + stored.AuthID,
+	}, *actualResponse)
+	assert.EqualValues(t, "pending", stored.State)
+	assert.EqualValues(t, now.Add(10*time.Minute), stored.ExpiresAt)
+
+	//the others are authorised straight away
+	service = newService(
+		&storeMock{binCountry: "GB", insertAuthRecord: func(data *auth.Auth) error {
+			return nil
+		}},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+			return false, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err = service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, auth_domain.StatusApproved, actualResponse.Status)
+	assert.EqualValues(t, false, actualResponse.LiabilityShift)
+}
+
+func TestAuthorisationService_AuthorisePayment_AddressVerification(t *testing.T) {
+	t.Parallel()
+	request := auth_domain.AuthRequest{
+		CardDetails: auth_domain.CardDetails{
+			Number:         "4929907390318794",
+			ExpiryDate:     "12-2021",
+			Cvv:            "123",
+			CardholderName: "Jane Doe",
+			BillingAddress: &auth_domain.BillingAddress{
+				Line1:    "10 Downing Street",
+				City:     "London",
+				Postcode: "SW1A 2AA",
+				Country:  "GB",
+			},
+		},
+		Amount:   10,
+		Currency: "GBP",
+	}
+
+	//the results of the address verification are stored with the authorisation and assessed by the fraud rules
+	var stored *auth.Auth
+	var assessed fraud_domain.Transaction
+	service := newServiceWithFraud(
+		&storeMock{insertAuthRecord: func(data *auth.Auth) error {
+			stored = data
+			return nil
+		}},
+		&acquirerMock{
+			isDeclined: func(opName string, cardNumber string) (bool, error) {
+				return false, nil
+			},
+			verifyAddress: func(cardNumber string) (bool, bool, error) {
+				return true, false, nil
+			},
+		},
+		&fraudMock{evaluate: func(transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+			assessed = transaction
+			return &fraud_domain.Assessment{Decision: fraud_domain.DecisionAllow}, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err := service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, &auth_domain.AVSResult{Address: auth_domain.AVSMatch, Postcode: auth_domain.AVSNoMatch}, actualResponse.AVS)
+	assert.EqualValues(t, auth_domain.AVSMatch, assessed.AVSAddress)
+	assert.EqualValues(t, auth_domain.AVSNoMatch, assessed.AVSPostcode)
+	assert.EqualValues(t, auth.Cardholder{
+		CardholderName:  "Jane Doe",
+		BillingLine1:    "10 Downing Street",
+		BillingCity:     "London",
+		BillingPostcode: "SW1A 2AA",
+		BillingCountry:  "GB",
+		AVSAddress:      auth_domain.AVSMatch,
+		AVSPostcode:     auth_domain.AVSNoMatch,
+	}, stored.Cardholder)
+
+	//the authorisation is not made when the address cannot be verified
+	service = newService(
+		&storeMock{},
+		&acquirerMock{verifyAddress: func(cardNumber string) (bool, bool, error) {
+			return false, false, errors.New("acquirer unavailable")
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err = service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.AddressVerificationFailure)}), err.ErrorMessage())
+
+	//without a billing address nothing is verified
+	request.CardDetails.BillingAddress = nil
+	service = newService(
+		&storeMock{insertAuthRecord: func(data *auth.Auth) error {
+			stored = data
+			return nil
+		}},
+		&acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+			return false, nil
+		}},
+		config.LimitsConfig{MaxAuthorisationAmount: 100000})
+	actualResponse, err = service.AuthoriseTransaction(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Nil(t, actualResponse.AVS)
+	assert.EqualValues(t, auth_domain.AVSNotChecked, stored.AVSAddress)
+	assert.EqualValues(t, "Jane Doe", stored.CardholderName)
+}
+
+func TestAuthorisationService_CompleteAuthorisation(t *testing.T) {
+	t.Parallel()
+	id := "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+	challenged := func(state string, rules string) *challenge.Challenge {
+		return &challenge.Challenge{
+			AuthID:     id,
+			Number:     "4929907390318794",
+			ExpiryDate: "12-2021",
+			Amount:     10,
+			Currency:   "EUR",
+			State:      state,
+			Rules:      rules,
+			ExpiresAt:  now.Add(time.Minute),
+		}
+	}
+	type finalised struct {
+		from       string
+		authorised *auth.Auth
+		pending    *review.Review
+	}
+	newStore := func(record *challenge.Challenge, result *finalised) *storeMock {
+		return &storeMock{
+			getChallengeRecordByAuthID: func(string) (*challenge.Challenge, error) {
+				return record, nil
+			},
+			finaliseChallengeRecord: func(data *challenge.Challenge, from string, authorised *auth.Auth, pending *review.Review) error {
+				assert.EqualValues(t, "completed", data.State)
+				*result = finalised{from: from, authorised: authorised, pending: pending}
+				return nil
+			},
+		}
+	}
+	approving := &acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+		return false, nil
+	}}
+	limits := config.LimitsConfig{MaxAuthorisationAmount: 100000}
+
+	//an authenticated authorisation shifts the liability to the issuer
+	var result finalised
+	actualResponse, err := newService(newStore(challenged("authenticated", ""), &result), approving, limits).CompleteAuthorisation(context.Background(), id)
+	assert.Nil(t, err)
+	assert.EqualValues(t, auth_domain.AuthResponse{
+		AuthID:         id,
+		IsSuccess:      true,
+		Status:         auth_domain.StatusApproved,
+		Amount:         10,
+		Currency:       "EUR",
+		LiabilityShift: true,
+	}, *actualResponse)
+	assert.EqualValues(t, "authenticated", result.from)
+	assert.EqualValues(t, "authenticated", result.authorised.ThreeDSStatus)
+	assert.EqualValues(t, true, result.authorised.LiabilityShift)
+	assert.Nil(t, result.pending)
+
+	//the fraud rules flagging it at the first call still hold it for review
+	result = finalised{}
+	actualResponse, err = newService(newStore(challenged("authenticated", "high amount"), &result), approving, limits).CompleteAuthorisation(context.Background(), id)
+	assert.Nil(t, err)
+	assert.EqualValues(t, auth_domain.StatusPendingReview, actualResponse.Status)
+	assert.EqualValues(t, "high amount", result.pending.Rules)
+
+	//a failed challenge declines the authorisation
+	var recorded *decline.Decline
+	result = finalised{}
+	store := newStore(challenged("failed", ""), &result)
+	store.insertDecline = func(data *decline.Decline) error {
+		recorded = data
+		return nil
+	}
+	actualResponse, err = newService(store, &acquirerMock{}, limits).CompleteAuthorisation(context.Background(), id)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusUnauthorized, err.Status())
+	assert.EqualValues(t, "authentication", recorded.Reason)
+	assert.EqualValues(t, "failed", result.from)
+	assert.Nil(t, result.authorised)
+
+	//so does the acquirer, the challenge is closed all the same
+	result = finalised{}
+	declining := &acquirerMock{isDeclined: func(opName string, cardNumber string) (bool, error) {
+		return true, nil
+	}}
+	actualResponse, err = newService(newStore(challenged("authenticated", ""), &result), declining, limits).CompleteAuthorisation(context.Background(), id)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusUnauthorized, err.Status())
+	assert.EqualValues(t, "authenticated", result.from)
+	assert.Nil(t, result.authorised)
+}
+
+func TestAuthorisationService_CompleteAuthorisation_Errors(t *testing.T) {
+	t.Parallel()
+	id := "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+	challenged := func(state string, expiresAt time.Time) func(string) (*challenge.Challenge, error) {
+		return func(string) (*challenge.Challenge, error) {
+			return &challenge.Challenge{AuthID: id, State: state, ExpiresAt: expiresAt}, nil
+		}
+	}
+
+	tests := []struct {
+		name           string
+		id             string
+		getChallenge   func(string) (*challenge.Challenge, error)
+		expectedStatus int
+		expectedError  string
+	}{
+		{"invalid id", "not-an-id", nil, http.StatusBadRequest, error_constant.InvalidAuthIdField},
+		{"not found", id, func(string) (*challenge.Challenge, error) { return nil, errors.New("record not found") }, http.StatusNotFound, error_constant.ChallengeNotFound},
+		{"retrieval failure", id, func(string) (*challenge.Challenge, error) { return nil, errors.New("disk I/O error") }, http.StatusInternalServerError, error_constant.ChallengeRetrievalFailure},
+		{"not answered", id, challenged("pending", now.Add(time.Minute)), http.StatusUnprocessableEntity, error_constant.ChallengeNotCompleted},
+		{"expired", id, challenged("pending", now.Add(-time.Minute)), http.StatusUnprocessableEntity, error_constant.ChallengeExpired},
+		{"already completed", id, challenged("completed", now.Add(time.Minute)), http.StatusUnprocessableEntity, error_constant.ChallengeStateInvalid},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			service := newService(&storeMock{getChallengeRecordByAuthID: tt.getChallenge}, &acquirerMock{}, config.LimitsConfig{MaxAuthorisationAmount: 100000})
+			actualResponse, err := service.CompleteAuthorisation(context.Background(), tt.id)
+			assert.Nil(t, actualResponse)
+			assert.EqualValues(t, tt.expectedStatus, err.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", err.ErrorMessage())
+		})
+	}
 }
@@ -1,33 +1,84 @@
 package capture_service
 
 import (
+	"context"
 	"errors"
-	"log"
+	"math"
 	"net/http"
+	"payment-gateway-api/api/acquirer"
+	"payment-gateway-api/api/clock"
 	"payment-gateway-api/api/const/error_constant"
-	"payment-gateway-api/api/data_access"
 	"payment-gateway-api/api/data_access/database_model/auth"
 	"payment-gateway-api/api/domain/capture_domain"
 	"payment-gateway-api/api/domain/common_validation"
 	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
 	"payment-gateway-api/api/services/common_service"
+	"payment-gateway-api/api/services/fee_service"
 )
 
-type captureService struct{}
+//Store is the persistence the capture service reads and updates the authorisations from
+type Store interface {
+	GetAuthRecordByID(context.Context, string) (bool, *auth.Auth, error)
+	UpdateAvailableAmountByAuthID(context.Context, *auth.Auth, float32, string, float32, float32) error
+}
+
+//Dependencies are the collaborators of the capture service
+type Dependencies struct {
+	Store         Store
+	CommonService common_service.Service
+	FeeService    fee_service.Service
+	Acquirer      acquirer.Acquirer
+	Clock         clock.Clock
+	Logger        *logger.Logger
+	Metrics       *metrics.Metrics
+}
+
+type captureService struct {
+	store         Store
+	commonService common_service.Service
+	feeService    fee_service.Service
+	acquirer      acquirer.Acquirer
+	clock         clock.Clock
+	logger        *logger.Logger
+	metrics       *metrics.Metrics
+}
 
-type captureServiceInterface interface {
-	CaptureTransactionAmount(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface)
+//Service captures amounts of authorised transactions
+type Service interface {
+	CaptureTransactionAmount(context.Context, capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface)
 }
 
 var (
-	CaptureService captureServiceInterface = &captureService{}
-	operationName                          = "capture"
+	operationName = "capture"
 )
 
+//New creates the capture service from its dependencies
+func New(deps Dependencies) Service {
+	return &captureService{
+		store:         deps.Store,
+		commonService: deps.CommonService,
+		feeService:    deps.FeeService,
+		acquirer:      deps.Acquirer,
+		clock:         deps.Clock,
+		logger:        deps.Logger,
+		metrics:       deps.Metrics,
+	}
+}
+
 //CaptureTransactionAmount captures transaction amount of an already authorised transaction by making sure the request and operations are valid
-func (c *captureService) CaptureTransactionAmount(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+func (c *captureService) CaptureTransactionAmount(ctx context.Context, request capture_domain.CaptureRequest) (_ *capture_domain.CaptureResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() {
+		errInf = error_domain.FromContext(ctx, errInf)
+		c.metrics.ObserveOperation(operationName, errInf)
+	}()
+
+	log := c.logger.Ctx(ctx).With(logger.String("auth_id", request.AuthId))
+	ctx = logger.NewContext(ctx, log)
+
 	//validate the capture operation
-	authRecord, response, errInf := validateOperation(request)
+	authRecord, response, errInf := c.validateOperation(ctx, request)
 	if errInf != nil {
 		return response, errInf
 	}
@@ -38,51 +89,64 @@ func (c *captureService) CaptureTransactionAmount(request capture_domain.Capture
 		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.RequestedAmountNotValid))
 	}
 
-	//update available amount in db
-	authRecord.AvailableAmount = newAvailableAmount
-	err := data_access.Db.UpdateAvailableAmountByAuthID(authRecord.ID, newAvailableAmount, operationName)
+	//the fee is charged to the merchant according to its fee schedule, it is recorded with the operation only if the
+	//authorisation is still in the state it has been computed from
+	fee, errInf := c.feeService.CalculateFee(ctx, authRecord, operationName, request.Amount)
+	if errInf != nil {
+		return nil, errInf
+	}
+
+	//update available amount in db, it is rejected if another operation changed it since it was read
+	err := c.store.UpdateAvailableAmountByAuthID(ctx, authRecord, newAvailableAmount, operationName, request.Amount, fee)
 	if err != nil {
-		log.Println(err.Error())
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusConflict, errors.New(error_constant.ConcurrentTransactionUpdate))
+		}
+		log.Error(error_constant.UpdateAvailableAmountFailure, logger.Err(err))
 		return nil, &error_domain.GatewayError{
 			Code:  http.StatusInternalServerError,
 			Error: error_constant.UpdateAvailableAmountFailure,
 		}
 	}
 
+	log.Info("transaction captured", logger.Any("amount", request.Amount), logger.String("currency", authRecord.Currency), logger.Any("fee", fee))
 	return &capture_domain.CaptureResponse{
 		IsSuccess: true,
 		Amount:    newAvailableAmount,
 		Currency:  authRecord.Currency,
+		Fee:       fee,
+		NetAmount: float32(math.Round(float64(request.Amount-fee)*100) / 100),
 	}, nil
 }
 
-func validateOperation(request capture_domain.CaptureRequest) (*auth.Auth, *capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+func (c *captureService) validateOperation(ctx context.Context, request capture_domain.CaptureRequest) (*auth.Auth, *capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+	log := c.logger.Ctx(ctx)
 	errs := request.ValidateFields()
 	if len(errs) > 0 {
 		return nil, nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
 	}
 
-	isValid, err := common_service.CommonService.IsAuthorisedState(operationName, request.AuthId)
+	isValid, err := c.commonService.IsAuthorisedState(ctx, operationName, request.AuthId)
 	if err != nil {
-		log.Println(err.Error())
+		log.Error(error_constant.UnableToCheckForInvalidState, logger.Err(err))
 		return nil, nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.UnableToCheckForInvalidState))
 	}
 	if !isValid {
 		return nil, nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.TransactionStateInvalid))
 	}
 
-	isSoftDeleted, authRecord, err := data_access.Db.GetAuthRecordByID(request.AuthId)
+	isSoftDeleted, authRecord, err := c.store.GetAuthRecordByID(ctx, request.AuthId)
 	if err != nil {
 		if err.Error() == "record not found" {
 			return nil, nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
 		}
-		log.Println(err.Error())
+		log.Error(error_constant.TransactionRetrievalFailure, logger.Err(err))
 		return nil, nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.TransactionRetrievalFailure))
 	}
 	//check card number for capture failure reject
-	isReject, err := data_access.Db.CheckRejectByCardNumber(operationName, authRecord.Number)
+	isReject, err := c.acquirer.IsDeclined(ctx, operationName, authRecord.Number)
 	if err != nil {
-		log.Println(err.Error())
+		log.Error(error_constant.RejectRetrievalFailure, logger.Err(err))
 		return nil, nil, &error_domain.GatewayError{
 			Code:  http.StatusInternalServerError,
 			Error: error_constant.RejectRetrievalFailure,
@@ -97,7 +161,7 @@ func validateOperation(request capture_domain.CaptureRequest) (*auth.Auth, *capt
 		return nil, nil, error_domain.New(http.StatusOK, errors.New(error_constant.CancelledTransaction))
 	}
 	//check expiration date, in case it was done at the end of the valid month
-	if isValid := common_validation.IsExpiryDateValid(authRecord.ExpiryDate); !isValid {
+	if isValid := common_validation.IsExpiryDateValid(authRecord.ExpiryDate, c.clock.Now()); !isValid {
 		return nil, nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.ExpiredCard))
 	}
 	return authRecord, nil, nil
@@ -1,74 +1,104 @@
 package capture_service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
 	"payment-gateway-api/api/const/error_constant"
-	"payment-gateway-api/api/data_access"
 	"payment-gateway-api/api/data_access/database_model/auth"
-	"payment-gateway-api/api/data_access/database_model/operation"
 	"payment-gateway-api/api/domain/capture_domain"
-	"payment-gateway-api/api/services/common_service"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fee_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
 	"testing"
+	"time"
 )
 
 var (
+	now = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+)
+
+type storeMock struct {
 	getAuthRecordByID             func(string) (bool, *auth.Auth, error)
-	softDeleteAuthRecordByID      func(string) error
-	isAuthorisedState             func(string, string) (bool, error)
-	checkRejectByCardNumber       func(string, string) (bool, error)
 	updateAvailableAmountByAuthID func(string, float32, string) error
-)
+	previous                      float32
+	processed                     float32
+	fee                           float32
+}
 
-type databaseMock struct{}
-type commonServiceMock struct{}
+func (s *storeMock) GetAuthRecordByID(ctx context.Context, id string) (bool, *auth.Auth, error) {
+	return s.getAuthRecordByID(id)
+}
 
-func (c commonServiceMock) IsAuthorisedState(operationName string, id string) (bool, error) {
-	return isAuthorisedState(operationName, id)
+func (s *storeMock) UpdateAvailableAmountByAuthID(ctx context.Context, read *auth.Auth, newAmount float32, opName string, processed, fee float32) error {
+	s.previous, s.processed, s.fee = read.AvailableAmount, processed, fee
+	return s.updateAvailableAmountByAuthID(read.ID, newAmount, opName)
 }
 
-func (d databaseMock) CheckRejectByCardNumber(opName string, cardNumber string) (bool, error) {
-	return checkRejectByCardNumber(opName, cardNumber)
+type commonServiceMock struct {
+	isAuthorisedState func(string, string) (bool, error)
 }
 
-func (d databaseMock) UpdateAvailableAmountByAuthID(id string, newAmount float32, opName string) error {
-	return updateAvailableAmountByAuthID(id, newAmount, opName)
+func (c *commonServiceMock) IsAuthorisedState(ctx context.Context, operationName string, id string) (bool, error) {
+	return c.isAuthorisedState(operationName, id)
 }
 
-func (d databaseMock) Setup(string) error {
-	return nil
+type feeServiceMock struct {
+	fee    float32
+	errInf error_domain.GatewayErrorInterface
 }
 
-func (d databaseMock) InsertAuthRecord(*auth.Auth) error {
-	return nil
+func (f *feeServiceMock) CalculateFee(ctx context.Context, record *auth.Auth, operationName string, amount float32) (float32, error_domain.GatewayErrorInterface) {
+	return f.fee, f.errInf
+}
+
+func (f *feeServiceMock) GetSchedule(ctx context.Context, merchantID string) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
 }
 
-func (d databaseMock) GetAuthRecordByID(id string) (bool, *auth.Auth, error) {
-	return getAuthRecordByID(id)
+func (f *feeServiceMock) SaveSchedule(ctx context.Context, merchantID string, request fee_domain.ScheduleRequest) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
 }
 
-func (d databaseMock) Close() error {
+func (f *feeServiceMock) DeleteSchedule(ctx context.Context, merchantID string) error_domain.GatewayErrorInterface {
 	return nil
 }
 
-func (d databaseMock) SoftDeleteAuthRecordByID(id string) error {
-	return softDeleteAuthRecordByID(id)
+type acquirerMock struct {
+	isDeclined func(string, string) (bool, error)
 }
 
-func (d databaseMock) HardDeleteAuthRecordByID(string) error {
-	return nil
+func (a *acquirerMock) IsDeclined(ctx context.Context, opName string, cardNumber string) (bool, error) {
+	return a.isDeclined(opName, cardNumber)
 }
 
-func (d databaseMock) GetOperationByAuthIDAndOperationName(string, string) (bool, operation.Operation, error) {
-	return false, operation.Operation{}, nil
+func (a *acquirerMock) VerifyAddress(ctx context.Context, cardNumber string) (bool, bool, error) {
+	return true, true, nil
 }
 
-func (d databaseMock) DeleteOperationRecordsByAuthID(string) error {
+func (a *acquirerMock) Check(ctx context.Context) error {
 	return nil
 }
 
+func newService(store *storeMock, commonService *commonServiceMock, acquirer *acquirerMock, clk clock.Clock) Service {
+	return New(Dependencies{
+		Store:         store,
+		CommonService: commonService,
+		FeeService:    &feeServiceMock{},
+		Acquirer:      acquirer,
+		Clock:         clk,
+		Logger:        logger.Discard(),
+		Metrics:       metrics.New(),
+	})
+}
+
 func TestCaptureService_CaptureTransactionAmount_InvalidState(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
 
 	request := capture_domain.CaptureRequest{
 		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
@@ -79,18 +109,21 @@ func TestCaptureService_CaptureTransactionAmount_InvalidState(t *testing.T) {
 	expectedErrors := make([]error, 0)
 	expectedErrors = append(expectedErrors, err1)
 
-	isAuthorisedState = func(operationName string, id string) (bool, error) {
+	commonService.isAuthorisedState = func(operationName string, id string) (bool, error) {
 		return false, nil
 	}
 
-	common_service.CommonService = &commonServiceMock{}
+	service := newService(store, commonService, acquirer, clock.NewFake(now))
 
-	actualResponse, err := CaptureService.CaptureTransactionAmount(request)
+	actualResponse, err := service.CaptureTransactionAmount(context.Background(), request)
 	assert.Nil(t, actualResponse)
 	assert.EqualValues(t, fmt.Sprintf("%v", expectedErrors), err.ErrorMessage())
 }
 
 func TestCaptureService_CaptureTransactionAmount(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
+
 	request := capture_domain.CaptureRequest{
 		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
 		Amount: 5,
@@ -102,65 +135,116 @@ func TestCaptureService_CaptureTransactionAmount(t *testing.T) {
 		Currency:  "GBP",
 	}
 
-	getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+	store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
 		return true, &auth.Auth{
-			ExpiryDate:       "12-3999",
+			ExpiryDate:       "12-2021",
 			AvailableAmount:  request.Amount + expectedResponse.Amount,
 			AuthorisedAmount: request.Amount + expectedResponse.Amount,
 			Currency:         expectedResponse.Currency,
 		}, nil
 	}
 
-	checkRejectByCardNumber = func(opName string, cardNumber string) (bool, error) {
+	acquirer.isDeclined = func(opName string, cardNumber string) (bool, error) {
 		return false, nil
 	}
 
-	updateAvailableAmountByAuthID = func(id string, newAmount float32, opName string) error {
+	store.updateAvailableAmountByAuthID = func(id string, newAmount float32, opName string) error {
 		return nil
 	}
 
-	isAuthorisedState = func(opName, id string) (b bool, err error) {
+	commonService.isAuthorisedState = func(opName, id string) (b bool, err error) {
 		return true, nil
 	}
 
-	common_service.CommonService = &commonServiceMock{}
-	data_access.Db = &databaseMock{}
+	service := newService(store, commonService, acquirer, clock.NewFake(now))
 
-	actualResponse, err := CaptureService.CaptureTransactionAmount(request)
+	actualResponse, err := service.CaptureTransactionAmount(context.Background(), request)
 	assert.Nil(t, err)
 	assert.EqualValues(t, expectedResponse.IsSuccess, actualResponse.IsSuccess)
 	assert.EqualValues(t, expectedResponse.Amount, actualResponse.Amount)
 	assert.EqualValues(t, expectedResponse.Currency, actualResponse.Currency)
+	//the update is made against the available amount that has been read and records the requested amount
+	assert.EqualValues(t, 10, store.previous)
+	assert.EqualValues(t, request.Amount, store.processed)
+}
+
+func TestCaptureService_CaptureTransactionAmount_Fee(t *testing.T) {
+	t.Parallel()
+	request := capture_domain.CaptureRequest{
+		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
+		Amount: 5,
+	}
+	store := &storeMock{
+		getAuthRecordByID: func(id string) (bool, *auth.Auth, error) {
+			return true, &auth.Auth{
+				ExpiryDate:       "12-2021",
+				AvailableAmount:  request.Amount + 5,
+				AuthorisedAmount: request.Amount + 5,
+				Currency:         "GBP",
+			}, nil
+		},
+		updateAvailableAmountByAuthID: func(id string, newAmount float32, opName string) error {
+			return nil
+		},
+	}
+	deps := Dependencies{
+		Store:         store,
+		CommonService: &commonServiceMock{isAuthorisedState: func(string, string) (bool, error) { return true, nil }},
+		FeeService:    &feeServiceMock{fee: 0.35},
+		Acquirer:      &acquirerMock{isDeclined: func(string, string) (bool, error) { return false, nil }},
+		Clock:         clock.NewFake(now),
+		Logger:        logger.Discard(),
+		Metrics:       metrics.New(),
+	}
+
+	//the fee is stored with the operation and deducted from the net amount
+	actualResponse, err := New(deps).CaptureTransactionAmount(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, float32(0.35), actualResponse.Fee)
+	assert.EqualValues(t, float32(4.65), actualResponse.NetAmount)
+	assert.EqualValues(t, float32(0.35), store.fee)
+
+	//nothing is captured when the fee cannot be calculated
+	store.fee = 0
+	deps.FeeService = &feeServiceMock{errInf: error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FeeCalculationFailure))}
+	actualResponse, err = New(deps).CaptureTransactionAmount(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+	assert.EqualValues(t, float32(0), store.fee)
 }
 
 func TestCaptureService_CaptureTransactionAmount_RejectedCardError(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
 
 	request := capture_domain.CaptureRequest{
 		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
 		Amount: 5,
 	}
 
-	getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+	store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
 		return true, &auth.Auth{}, nil
 	}
 
-	isAuthorisedState = func(opName, id string) (b bool, err error) {
+	commonService.isAuthorisedState = func(opName, id string) (b bool, err error) {
 		return true, nil
 	}
 
-	checkRejectByCardNumber = func(opName string, cardNumber string) (bool, error) {
+	acquirer.isDeclined = func(opName string, cardNumber string) (bool, error) {
 		return false, errors.New("expectedError")
 	}
 
-	common_service.CommonService = &commonServiceMock{}
-	data_access.Db = &databaseMock{}
+	service := newService(store, commonService, acquirer, clock.NewFake(now))
 
-	actualResponse, err := CaptureService.CaptureTransactionAmount(request)
+	actualResponse, err := service.CaptureTransactionAmount(context.Background(), request)
 	assert.Nil(t, actualResponse)
 	assert.EqualValues(t, error_constant.RejectRetrievalFailure, err.ErrorMessage())
 }
 
 func TestCaptureService_CaptureTransactionAmount_UpdateAvailableAmountError(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
+
 	request := capture_domain.CaptureRequest{
 		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
 		Amount: 5,
@@ -172,36 +256,81 @@ func TestCaptureService_CaptureTransactionAmount_UpdateAvailableAmountError(t *t
 		Currency:  "GBP",
 	}
 
-	getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+	store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
 		return true, &auth.Auth{
-			ExpiryDate:       "12-3999",
+			ExpiryDate:       "12-2021",
 			AvailableAmount:  request.Amount + expectedResponse.Amount,
 			AuthorisedAmount: request.Amount + expectedResponse.Amount,
 			Currency:         expectedResponse.Currency,
 		}, nil
 	}
 
-	isAuthorisedState = func(opName, id string) (b bool, err error) {
+	commonService.isAuthorisedState = func(opName, id string) (b bool, err error) {
 		return true, nil
 	}
 
-	checkRejectByCardNumber = func(opName string, cardNumber string) (bool, error) {
+	acquirer.isDeclined = func(opName string, cardNumber string) (bool, error) {
 		return false, nil
 	}
 
-	updateAvailableAmountByAuthID = func(id string, newAmount float32, opName string) error {
+	store.updateAvailableAmountByAuthID = func(id string, newAmount float32, opName string) error {
 		return errors.New("")
 	}
 
-	common_service.CommonService = &commonServiceMock{}
-	data_access.Db = &databaseMock{}
+	service := newService(store, commonService, acquirer, clock.NewFake(now))
 
-	actualResponse, err := CaptureService.CaptureTransactionAmount(request)
+	actualResponse, err := service.CaptureTransactionAmount(context.Background(), request)
 	assert.Nil(t, actualResponse)
 	assert.EqualValues(t, error_constant.UpdateAvailableAmountFailure, err.ErrorMessage())
 }
 
+func TestCaptureService_CaptureTransactionAmount_ChangedConcurrently(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
+
+	request := capture_domain.CaptureRequest{
+		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
+		Amount: 5,
+	}
+
+	expectedResponse := capture_domain.CaptureResponse{
+		IsSuccess: true,
+		Amount:    5,
+		Currency:  "GBP",
+	}
+
+	store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+		return true, &auth.Auth{
+			ExpiryDate:       "12-2021",
+			AvailableAmount:  request.Amount + expectedResponse.Amount,
+			AuthorisedAmount: request.Amount + expectedResponse.Amount,
+			Currency:         expectedResponse.Currency,
+		}, nil
+	}
+
+	commonService.isAuthorisedState = func(opName, id string) (b bool, err error) {
+		return true, nil
+	}
+
+	acquirer.isDeclined = func(opName string, cardNumber string) (bool, error) {
+		return false, nil
+	}
+
+	store.updateAvailableAmountByAuthID = func(id string, newAmount float32, opName string) error {
+		return errors.New("record not found")
+	}
+
+	service := newService(store, commonService, acquirer, clock.NewFake(now))
+
+	actualResponse, err := service.CaptureTransactionAmount(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusConflict, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.ConcurrentTransactionUpdate)}), err.ErrorMessage())
+}
+
 func TestCaptureService_CaptureTransactionAmount_GetAuthRecordError(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
 
 	request := capture_domain.CaptureRequest{
 		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
@@ -212,18 +341,91 @@ func TestCaptureService_CaptureTransactionAmount_GetAuthRecordError(t *testing.T
 	expectedErrors := make([]error, 0)
 	expectedErrors = append(expectedErrors, err1)
 
-	getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+	store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
 		return true, &auth.Auth{}, errors.New("record not found")
 	}
 
-	isAuthorisedState = func(opName, id string) (b bool, err error) {
+	commonService.isAuthorisedState = func(opName, id string) (b bool, err error) {
 		return true, nil
 	}
 
-	common_service.CommonService = &commonServiceMock{}
-	data_access.Db = &databaseMock{}
+	service := newService(store, commonService, acquirer, clock.NewFake(now))
 
-	actualResponse, err := CaptureService.CaptureTransactionAmount(request)
+	actualResponse, err := service.CaptureTransactionAmount(context.Background(), request)
 	assert.Nil(t, actualResponse)
 	assert.EqualValues(t, fmt.Sprintf("%v", expectedErrors), err.ErrorMessage())
 }
+
+func TestCaptureService_CaptureTransactionAmount_ExpiredCard(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
+
+	request := capture_domain.CaptureRequest{
+		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
+		Amount: 5,
+	}
+
+	expectedErrors := []error{errors.New(error_constant.ExpiredCard)}
+
+	store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+		return true, &auth.Auth{
+			ExpiryDate:       "06-2020",
+			AvailableAmount:  10,
+			AuthorisedAmount: 10,
+			Currency:         "GBP",
+		}, nil
+	}
+
+	commonService.isAuthorisedState = func(opName, id string) (b bool, err error) {
+		return true, nil
+	}
+
+	acquirer.isDeclined = func(opName string, cardNumber string) (bool, error) {
+		return false, nil
+	}
+
+	//the card can still be used on the last day of its expiry month
+	service := newService(store, commonService, acquirer, clock.NewFake(time.Date(2020, time.June, 30, 23, 59, 0, 0, time.UTC)))
+	store.updateAvailableAmountByAuthID = func(id string, newAmount float32, opName string) error {
+		return nil
+	}
+	actualResponse, err := service.CaptureTransactionAmount(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 5, actualResponse.Amount)
+
+	service = newService(store, commonService, acquirer, clock.NewFake(time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC)))
+	actualResponse, err = service.CaptureTransactionAmount(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, fmt.Sprintf("%v", expectedErrors), err.ErrorMessage())
+}
+
+func TestCaptureService_CaptureTransactionAmount_Cancelled(t *testing.T) {
+	t.Parallel()
+	store, commonService, acquirer := &storeMock{}, &commonServiceMock{}, &acquirerMock{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+		return true, &auth.Auth{ExpiryDate: "12-2021", AvailableAmount: 10, AuthorisedAmount: 10, Currency: "GBP"}, nil
+	}
+	commonService.isAuthorisedState = func(opName, id string) (bool, error) {
+		return true, nil
+	}
+	acquirer.isDeclined = func(opName string, cardNumber string) (bool, error) {
+		return false, nil
+	}
+	//the client goes away while the db is being updated
+	store.updateAvailableAmountByAuthID = func(id string, newAmount float32, opName string) error {
+		cancel()
+		return context.Canceled
+	}
+
+	service := newService(store, commonService, acquirer, clock.NewFake(now))
+
+	actualResponse, err := service.CaptureTransactionAmount(ctx, capture_domain.CaptureRequest{
+		AuthId: "fc958d27-8e8e-4825-b3ec-e5236a8e7d28",
+		Amount: 5,
+	})
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, error_domain.StatusClientClosedRequest, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.RequestCancelled)}), err.ErrorMessage())
+}
@@ -1,25 +1,45 @@
 package common_service
 
 import (
+	"context"
 	"errors"
-	"log"
 	"payment-gateway-api/api/const/error_constant"
-	"payment-gateway-api/api/data_access"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/logger"
 )
 
-type commonService struct{}
+//Store is the persistence the common service reads the operations from
+type Store interface {
+	GetOperationByAuthIDAndOperationName(context.Context, string, string) (bool, operation.Operation, error)
+}
 
-type commonServiceInterface interface {
-	IsAuthorisedState(string, string) (bool, error)
+//Dependencies are the collaborators of the common service
+type Dependencies struct {
+	Store  Store
+	Logger *logger.Logger
 }
 
-var (
-	CommonService commonServiceInterface = &commonService{}
-)
+type commonService struct {
+	store  Store
+	logger *logger.Logger
+}
+
+//Service checks the operations requested against the auth transaction lifecycle
+type Service interface {
+	IsAuthorisedState(context.Context, string, string) (bool, error)
+}
+
+//New creates the common service from its dependencies
+func New(deps Dependencies) Service {
+	return &commonService{
+		store:  deps.Store,
+		logger: deps.Logger,
+	}
+}
 
 //IsAuthorisedState will check whether the operation required by the client are
 //authorised in relation to the auth transaction lifecycle diagram
-func (c *commonService) IsAuthorisedState(operationName, id string) (bool, error) {
+func (c *commonService) IsAuthorisedState(ctx context.Context, operationName, id string) (bool, error) {
 	var invalidPreviousState string
 
 	switch operationName {
@@ -28,15 +48,27 @@ func (c *commonService) IsAuthorisedState(operationName, id string) (bool, error
 	case "capture":
 		invalidPreviousState = "refund"
 	case "refund":
-		return true, nil
 	default:
 		return false, errors.New(error_constant.OperationNameInvalid)
 	}
 
+	//authorisations held for review allow no operation until they are approved
+	isPending, err := c.isPendingReview(ctx, id)
+	if err != nil {
+		c.logger.Ctx(ctx).Error(error_constant.UnableToCheckForInvalidState, logger.String("operation", operationName), logger.Err(err))
+		return false, err
+	}
+	if isPending {
+		return false, nil
+	}
+	if invalidPreviousState == "" {
+		return true, nil
+	}
+
 	//check whether previous state that are invalid for the current operation are present in db
-	isPresent, _, err := data_access.Db.GetOperationByAuthIDAndOperationName(id, invalidPreviousState)
+	isPresent, _, err := c.store.GetOperationByAuthIDAndOperationName(ctx, id, invalidPreviousState)
 	if err != nil {
-		log.Println(err.Error())
+		c.logger.Ctx(ctx).Error(error_constant.UnableToCheckForInvalidState, logger.String("operation", operationName), logger.Err(err))
 		return false, err
 	}
 
@@ -47,3 +79,17 @@ func (c *commonService) IsAuthorisedState(operationName, id string) (bool, error
 
 	return true, nil
 }
+
+//isPendingReview checks whether the authorisation has been held for review and not approved yet,
+//declined ones are voided and therefore refused by the services anyway
+func (c *commonService) isPendingReview(ctx context.Context, id string) (bool, error) {
+	isHeld, _, err := c.store.GetOperationByAuthIDAndOperationName(ctx, id, "review")
+	if err != nil || !isHeld {
+		return false, err
+	}
+	isApproved, _, err := c.store.GetOperationByAuthIDAndOperationName(ctx, id, "review_approved")
+	if err != nil {
+		return false, err
+	}
+	return !isApproved, nil
+}
@@ -0,0 +1,461 @@
+package dispute_service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"path/filepath"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/dispute"
+	"payment-gateway-api/api/domain/common_validation"
+	"payment-gateway-api/api/domain/dispute_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Store is the persistence the dispute service records the disputes, their evidence and their events in
+type Store interface {
+	GetAuthRecordByID(context.Context, string) (bool, *auth.Auth, error)
+	InsertDisputeRecord(context.Context, *dispute.Dispute, *dispute.Event) error
+	GetDisputeByID(context.Context, string) (*dispute.Dispute, []dispute.Evidence, []dispute.Event, error)
+	ListDisputes(context.Context, string, string) ([]dispute.Dispute, error)
+	GetDueDisputes(context.Context, []string, time.Time) ([]dispute.Dispute, error)
+	InsertDisputeEvidence(context.Context, *dispute.Evidence, *dispute.Event) error
+	UpdateDisputeState(context.Context, *dispute.Dispute, string, *dispute.Event, bool) error
+}
+
+//Dependencies are the collaborators of the dispute service, the response window is the time the merchant
+//has to answer a dispute and the maximum evidence size is in bytes
+type Dependencies struct {
+	Store           Store
+	Evidence        EvidenceStore
+	Clock           clock.Clock
+	Logger          *logger.Logger
+	Metrics         *metrics.Metrics
+	ResponseWindow  time.Duration
+	MaxEvidenceSize int64
+}
+
+type disputeService struct {
+	store           Store
+	evidence        EvidenceStore
+	clock           clock.Clock
+	logger          *logger.Logger
+	metrics         *metrics.Metrics
+	responseWindow  time.Duration
+	maxEvidenceSize int64
+}
+
+//Service follows the disputes raised by the cardholders against captured transactions from the notification of the
+//acquirer to its decision, charging the lost ones back to the merchant
+type Service interface {
+	OpenDispute(context.Context, dispute_domain.DisputeRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	ListDisputes(context.Context, string, string) ([]dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	GetDispute(context.Context, string) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	UpdateState(context.Context, string, dispute_domain.StateRequest) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	AddEvidence(context.Context, string, string, io.Reader) (*dispute_domain.EvidenceResponse, error_domain.GatewayErrorInterface)
+	GetEvidence(context.Context, string, string) (*dispute_domain.EvidenceFile, error_domain.GatewayErrorInterface)
+	SubmitDispute(context.Context, string) (*dispute_domain.DisputeResponse, error_domain.GatewayErrorInterface)
+	ExpireOverdueDisputes(context.Context) error
+}
+
+var (
+	//evidenceTypes are the content types of the documents accepted as evidence
+	evidenceTypes = map[string]bool{
+		"application/pdf": true,
+		"image/png":       true,
+		"image/jpeg":      true,
+		"text/plain":      true,
+	}
+	expiredDetail = "no response before the deadline"
+)
+
+//New creates the dispute service from its dependencies
+func New(deps Dependencies) Service {
+	return &disputeService{
+		store:           deps.Store,
+		evidence:        deps.Evidence,
+		clock:           deps.Clock,
+		logger:          deps.Logger,
+		metrics:         deps.Metrics,
+		responseWindow:  deps.ResponseWindow,
+		maxEvidenceSize: deps.MaxEvidenceSize,
+	}
+}
+
+//OpenDispute records a dispute the acquirer notified against a captured authorisation, the merchant then has
+//the response window to answer it. An authorisation can only have one dispute in progress at a time
+func (d *disputeService) OpenDispute(ctx context.Context, request dispute_domain.DisputeRequest) (_ *dispute_domain.DisputeResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	log := d.logger.Ctx(ctx).With(logger.String("auth_id", request.AuthId))
+	found, authRecord, err := d.store.GetAuthRecordByID(ctx, request.AuthId)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
+		}
+		log.Error(error_constant.TransactionRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.TransactionRetrievalFailure))
+	}
+	//only the captured amount that has not been refunded or charged back yet can be disputed
+	disputable := authRecord.AuthorisedAmount - authRecord.AvailableAmount - authRecord.ChargedBackAmount
+	if !found || disputable <= 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.TransactionStateInvalid))
+	}
+	if request.Amount > disputable {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.RequestedAmountNotValid))
+	}
+
+	now := d.clock.Now().UTC()
+	record := &dispute.Dispute{
+		ID:         uuid.New().String(),
+		AuthID:     authRecord.ID,
+		MerchantID: authRecord.MerchantID,
+		State:      dispute_domain.StateOpened,
+		Reason:     request.Reason,
+		Amount:     request.Amount,
+		Currency:   authRecord.Currency,
+		DueAt:      now.Add(d.responseWindow),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	event := &dispute.Event{Type: dispute_domain.StateOpened, Detail: request.Reason, CreatedAt: now}
+	if err := d.store.InsertDisputeRecord(ctx, record, event); err != nil {
+		if err == dispute.ErrInProgress {
+			return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeAlreadyOpen))
+		}
+		log.Error(error_constant.DisputeUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.DisputeUpdateFailure))
+	}
+
+	d.emit(ctx, record, event.Type)
+	return toResponse(record, nil, nil), nil
+}
+
+//ListDisputes returns the disputes, the latest first, filtered by state and authorisation when they are not empty
+func (d *disputeService) ListDisputes(ctx context.Context, state string, authID string) (_ []dispute_domain.DisputeResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	state = strings.ToLower(strings.TrimSpace(state))
+	if !dispute_domain.IsStateValid(state) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidDisputeFilter))
+	}
+	authID = strings.TrimSpace(authID)
+	if authID != "" && !common_validation.IsValidUUID(authID) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidAuthIdField))
+	}
+
+	records, err := d.store.ListDisputes(ctx, state, authID)
+	if err != nil {
+		d.logger.Ctx(ctx).Error(error_constant.DisputeRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.DisputeRetrievalFailure))
+	}
+
+	disputes := make([]dispute_domain.DisputeResponse, 0, len(records))
+	for i := range records {
+		disputes = append(disputes, *toResponse(&records[i], nil, nil))
+	}
+	return disputes, nil
+}
+
+//GetDispute returns a dispute with its evidence and its history
+func (d *disputeService) GetDispute(ctx context.Context, id string) (_ *dispute_domain.DisputeResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	record, evidence, events, errInf := d.get(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	return toResponse(record, evidence, events), nil
+}
+
+//UpdateState applies the notifications of the acquirer to a dispute: it asks for evidence, which restarts the
+//response window, or decides the dispute. A lost dispute is charged back to the merchant
+func (d *disputeService) UpdateState(ctx context.Context, id string, request dispute_domain.StateRequest) (_ *dispute_domain.DisputeResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	record, _, _, errInf := d.get(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	if errInf := d.move(ctx, record, request.State, request.Detail); errInf != nil {
+		return nil, errInf
+	}
+	return toResponse(record, nil, nil), nil
+}
+
+//AddEvidence stores a document supporting the merchant's case, evidence can only be attached until the dispute is
+//submitted and before its deadline. The content type is detected from the document itself rather than trusted
+//from the upload
+func (d *disputeService) AddEvidence(ctx context.Context, id string, name string, content io.Reader) (_ *dispute_domain.EvidenceResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	record, _, _, errInf := d.get(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	if record.State != dispute_domain.StateOpened && record.State != dispute_domain.StateEvidenceRequired {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeStateInvalid))
+	}
+	now := d.clock.Now().UTC()
+	if !now.Before(record.DueAt) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeDeadlinePassed))
+	}
+
+	reader := bufio.NewReaderSize(content, 512)
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, error_domain.New(http.StatusBadRequest, errors.New(error_constant.InvalidEvidenceFile))
+	}
+	contentType := strings.Split(http.DetectContentType(head), ";")[0]
+	if len(head) == 0 || !evidenceTypes[contentType] {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidEvidenceFile))
+	}
+
+	log := d.logger.Ctx(ctx).With(logger.String("dispute_id", record.ID))
+	//one more byte than allowed is read to tell a document of the maximum size from a larger one
+	path, size, digest, err := d.evidence.Save(record.ID, io.LimitReader(reader, d.maxEvidenceSize+1))
+	if err != nil {
+		log.Error(error_constant.DisputeUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.DisputeUpdateFailure))
+	}
+	if size > d.maxEvidenceSize {
+		d.remove(ctx, path)
+		return nil, error_domain.New(http.StatusRequestEntityTooLarge, errors.New(error_constant.EvidenceTooLarge))
+	}
+
+	evidence := &dispute.Evidence{
+		DisputeID:   record.ID,
+		Name:        evidenceName(name),
+		ContentType: contentType,
+		Size:        size,
+		Path:        path,
+		SHA256:      digest,
+		CreatedAt:   now,
+	}
+	event := &dispute.Event{DisputeID: record.ID, Type: dispute_domain.EventEvidenceAdded, Detail: evidence.Name, CreatedAt: now}
+	if err := d.store.InsertDisputeEvidence(ctx, evidence, event); err != nil {
+		log.Error(error_constant.DisputeUpdateFailure, logger.Err(err))
+		//the document is not referenced by any evidence, it would never be read
+		d.remove(ctx, path)
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.DisputeUpdateFailure))
+	}
+
+	d.emit(ctx, record, event.Type)
+	return toEvidenceResponse(evidence), nil
+}
+
+//GetEvidence opens an evidence document of a dispute for download
+func (d *disputeService) GetEvidence(ctx context.Context, id string, evidenceID string) (_ *dispute_domain.EvidenceFile, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	number, err := strconv.ParseUint(strings.TrimSpace(evidenceID), 10, 64)
+	if err != nil || number == 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidEvidenceIdField))
+	}
+
+	_, evidence, _, errInf := d.get(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	for i := range evidence {
+		if uint64(evidence[i].ID) != number {
+			continue
+		}
+		content, err := d.evidence.Open(evidence[i].Path)
+		if err != nil {
+			d.logger.Ctx(ctx).Error(error_constant.DisputeRetrievalFailure, logger.String("dispute_id", id), logger.Err(err))
+			return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.DisputeRetrievalFailure))
+		}
+		return &dispute_domain.EvidenceFile{
+			Name:        evidence[i].Name,
+			ContentType: evidence[i].ContentType,
+			Size:        evidence[i].Size,
+			Content:     content,
+		}, nil
+	}
+	return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.EvidenceNotFound))
+}
+
+//SubmitDispute sends the evidence of a dispute to the acquirer, at least one document must have been attached
+//and the deadline must not have passed
+func (d *disputeService) SubmitDispute(ctx context.Context, id string) (_ *dispute_domain.DisputeResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	record, evidence, _, errInf := d.get(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	if !dispute_domain.CanMove(record.State, dispute_domain.StateSubmitted) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeStateInvalid))
+	}
+	if !d.clock.Now().Before(record.DueAt) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeDeadlinePassed))
+	}
+	if len(evidence) == 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeEvidenceMissing))
+	}
+
+	if errInf := d.move(ctx, record, dispute_domain.StateSubmitted, ""); errInf != nil {
+		return nil, errInf
+	}
+	return toResponse(record, nil, nil), nil
+}
+
+//ExpireOverdueDisputes loses the disputes the merchant has not answered before their deadline,
+//a failure on one of them does not prevent the others from being expired
+func (d *disputeService) ExpireOverdueDisputes(ctx context.Context) error {
+	states := []string{dispute_domain.StateOpened, dispute_domain.StateEvidenceRequired}
+	records, err := d.store.GetDueDisputes(ctx, states, d.clock.Now().UTC())
+	if err != nil {
+		d.logger.Ctx(ctx).Error(error_constant.DisputeRetrievalFailure, logger.Err(err))
+		return err
+	}
+
+	for i := range records {
+		d.move(ctx, &records[i], dispute_domain.StateLost, expiredDetail)
+	}
+	return nil
+}
+
+//get fetches a dispute, mapping the failures to the errors returned by the endpoints
+func (d *disputeService) get(ctx context.Context, id string) (*dispute.Dispute, []dispute.Evidence, []dispute.Event, error_domain.GatewayErrorInterface) {
+	id = strings.TrimSpace(id)
+	if !common_validation.IsValidUUID(id) {
+		return nil, nil, nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidDisputeIdField))
+	}
+
+	record, evidence, events, err := d.store.GetDisputeByID(ctx, id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, nil, nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.DisputeNotFound))
+		}
+		d.logger.Ctx(ctx).Error(error_constant.DisputeRetrievalFailure, logger.String("dispute_id", id), logger.Err(err))
+		return nil, nil, nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.DisputeRetrievalFailure))
+	}
+	return record, evidence, events, nil
+}
+
+//move changes the state of a dispute and records the event, evidence required restarts the response window
+//and a lost dispute is charged back to the merchant
+func (d *disputeService) move(ctx context.Context, record *dispute.Dispute, state string, detail string) error_domain.GatewayErrorInterface {
+	if !dispute_domain.CanMove(record.State, state) {
+		return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeStateInvalid))
+	}
+
+	from := record.State
+	now := d.clock.Now().UTC()
+	record.State = state
+	record.UpdatedAt = now
+	if state == dispute_domain.StateEvidenceRequired {
+		record.DueAt = now.Add(d.responseWindow)
+	}
+	if !dispute_domain.IsOpen(state) {
+		record.ResolvedAt = now
+	}
+
+	event := &dispute.Event{Type: state, Detail: detail, CreatedAt: now}
+	err := d.store.UpdateDisputeState(ctx, record, from, event, state == dispute_domain.StateLost)
+	if err != nil {
+		record.State = from
+		//the dispute has been moved by someone else since it was read
+		if err.Error() == "record not found" {
+			return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.DisputeStateInvalid))
+		}
+		//a capture or refund of the authorisation has been made while the chargeback was computed
+		if err == dispute.ErrTransactionChanged {
+			return error_domain.New(http.StatusConflict, errors.New(error_constant.ConcurrentTransactionUpdate))
+		}
+		d.logger.Ctx(ctx).Error(error_constant.DisputeUpdateFailure, logger.String("dispute_id", record.ID), logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.DisputeUpdateFailure))
+	}
+
+	d.emit(ctx, record, state)
+	return nil
+}
+
+//remove deletes a document that has been saved but is not kept as evidence
+func (d *disputeService) remove(ctx context.Context, path string) {
+	if err := d.evidence.Remove(path); err != nil {
+		d.logger.Ctx(ctx).Error("unable to remove the evidence document", logger.String("path", path), logger.Err(err))
+	}
+}
+
+//emit reports an event of a dispute to the logs and the metrics
+func (d *disputeService) emit(ctx context.Context, record *dispute.Dispute, event string) {
+	d.metrics.ObserveDisputeEvent(event)
+	d.logger.Ctx(ctx).Info("dispute event", logger.String("dispute_id", record.ID), logger.String("auth_id", record.AuthID),
+		logger.String("event", event), logger.String("state", record.State))
+}
+
+//evidenceName keeps the base name of the uploaded file, it is only used as the name of the download
+func evidenceName(name string) string {
+	name = filepath.Base(strings.Replace(strings.TrimSpace(name), "\\", "/", -1))
+	if name == "." || name == "/" || name == "" {
+		return "evidence"
+	}
+	return name
+}
+
+//toResponse converts a dispute record into the format returned by the dispute endpoints
+func toResponse(record *dispute.Dispute, evidence []dispute.Evidence, events []dispute.Event) *dispute_domain.DisputeResponse {
+	response := &dispute_domain.DisputeResponse{
+		ID:         record.ID,
+		AuthID:     record.AuthID,
+		MerchantID: record.MerchantID,
+		State:      record.State,
+		Reason:     record.Reason,
+		Amount:     record.Amount,
+		Currency:   record.Currency,
+		CreatedAt:  record.CreatedAt.UTC().Format(format_constant.TimestampLayout),
+	}
+	if dispute_domain.IsOpen(record.State) {
+		response.DueAt = record.DueAt.UTC().Format(format_constant.TimestampLayout)
+	}
+	if !record.ResolvedAt.IsZero() {
+		response.ResolvedAt = record.ResolvedAt.UTC().Format(format_constant.TimestampLayout)
+	}
+	for i := range evidence {
+		response.Evidence = append(response.Evidence, *toEvidenceResponse(&evidence[i]))
+	}
+	for i := range events {
+		response.Events = append(response.Events, dispute_domain.EventResponse{
+			Type:      events[i].Type,
+			Detail:    events[i].Detail,
+			CreatedAt: events[i].CreatedAt.UTC().Format(format_constant.TimestampLayout),
+		})
+	}
+	return response
+}
+
+//toEvidenceResponse converts an evidence record into the format returned by the dispute endpoints
+func toEvidenceResponse(record *dispute.Evidence) *dispute_domain.EvidenceResponse {
+	return &dispute_domain.EvidenceResponse{
+		ID:          record.ID,
+		Name:        record.Name,
+		ContentType: record.ContentType,
+		Size:        record.Size,
+		SHA256:      record.SHA256,
+		CreatedAt:   record.CreatedAt.UTC().Format(format_constant.TimestampLayout),
+	}
+}
@@ -0,0 +1,464 @@
+package dispute_service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/dispute"
+	"payment-gateway-api/api/domain/dispute_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	now       = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	authID    = "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+	disputeID = "7d5a3f4c-0e6b-4a4d-9c3f-5b8e9d0a1f23"
+	pdf       = []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+)
+
+type stateUpdate struct {
+	dispute    dispute.Dispute
+	from       string
+	event      dispute.Event
+	chargeBack bool
+}
+
+type storeMock struct {
+	auth      *auth.Auth
+	found     bool
+	authErr   error
+	disputes  []dispute.Dispute
+	evidence  []dispute.Evidence
+	getErr    error
+	insertErr error
+	updateErr error
+	inserted  []dispute.Dispute
+	events    []dispute.Event
+	added     []dispute.Evidence
+	updates   []stateUpdate
+	dueStates []string
+}
+
+func (s *storeMock) GetAuthRecordByID(ctx context.Context, id string) (bool, *auth.Auth, error) {
+	if s.authErr != nil {
+		return false, nil, s.authErr
+	}
+	if s.auth == nil {
+		return false, nil, gorm.ErrRecordNotFound
+	}
+	return s.found, s.auth, nil
+}
+
+func (s *storeMock) InsertDisputeRecord(ctx context.Context, data *dispute.Dispute, event *dispute.Event) error {
+	if s.insertErr != nil {
+		return s.insertErr
+	}
+	for i := range s.disputes {
+		if s.disputes[i].AuthID == data.AuthID && dispute_domain.IsOpen(s.disputes[i].State) {
+			return dispute.ErrInProgress
+		}
+	}
+	s.inserted = append(s.inserted, *data)
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *storeMock) GetDisputeByID(ctx context.Context, id string) (*dispute.Dispute, []dispute.Evidence, []dispute.Event, error) {
+	if s.getErr != nil {
+		return nil, nil, nil, s.getErr
+	}
+	for i := range s.disputes {
+		if s.disputes[i].ID == id {
+			record := s.disputes[i]
+			return &record, append(s.evidence, s.added...), s.events, nil
+		}
+	}
+	return nil, nil, nil, gorm.ErrRecordNotFound
+}
+
+func (s *storeMock) ListDisputes(ctx context.Context, state string, authID string) ([]dispute.Dispute, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	return s.disputes, nil
+}
+
+func (s *storeMock) GetDueDisputes(ctx context.Context, states []string, dueAt time.Time) ([]dispute.Dispute, error) {
+	s.dueStates = states
+	return s.disputes, nil
+}
+
+func (s *storeMock) InsertDisputeEvidence(ctx context.Context, data *dispute.Evidence, event *dispute.Event) error {
+	if s.insertErr != nil {
+		return s.insertErr
+	}
+	data.ID = uint(len(s.added) + 1)
+	s.added = append(s.added, *data)
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *storeMock) UpdateDisputeState(ctx context.Context, data *dispute.Dispute, from string, event *dispute.Event, chargeBack bool) error {
+	if s.updateErr != nil {
+		return s.updateErr
+	}
+	s.updates = append(s.updates, stateUpdate{dispute: *data, from: from, event: *event, chargeBack: chargeBack})
+	return nil
+}
+
+type evidenceMock struct {
+	saved   map[string][]byte
+	saveErr error
+	removed []string
+}
+
+func (e *evidenceMock) Save(disputeID string, content io.Reader) (string, int64, string, error) {
+	if e.saveErr != nil {
+		return "", 0, "", e.saveErr
+	}
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return "", 0, "", err
+	}
+	path := disputeID + "/document"
+	e.saved[path] = data
+	return path, int64(len(data)), "digest", nil
+}
+
+func (e *evidenceMock) Open(path string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(e.saved[path])), nil
+}
+
+func (e *evidenceMock) Remove(path string) error {
+	e.removed = append(e.removed, path)
+	return nil
+}
+
+func newService(store *storeMock, evidence *evidenceMock) Service {
+	return New(Dependencies{
+		Store:           store,
+		Evidence:        evidence,
+		Clock:           clock.NewFake(now),
+		Logger:          logger.Discard(),
+		Metrics:         metrics.New(),
+		ResponseWindow:  7 * 24 * time.Hour,
+		MaxEvidenceSize: 64,
+	})
+}
+
+func capturedAuth() *auth.Auth {
+	return &auth.Auth{
+		ID:               authID,
+		MerchantID:       "merchant-1",
+		AuthorisedAmount: 100,
+		AvailableAmount:  40,
+		Currency:         "GBP",
+	}
+}
+
+func openDispute(state string) dispute.Dispute {
+	return dispute.Dispute{
+		ID:        disputeID,
+		AuthID:    authID,
+		State:     state,
+		Reason:    "fraud",
+		Amount:    60,
+		Currency:  "GBP",
+		DueAt:     now.Add(time.Hour),
+		CreatedAt: now.Add(-time.Hour),
+	}
+}
+
+func TestDisputeService_OpenDispute(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{auth: capturedAuth(), found: true}
+	request := dispute_domain.DisputeRequest{AuthId: authID, Amount: 60, Reason: " fraud "}
+
+	response, errInf := newService(store, &evidenceMock{}).OpenDispute(context.Background(), request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, dispute_domain.StateOpened, response.State)
+	assert.EqualValues(t, "merchant-1", response.MerchantID)
+	assert.EqualValues(t, "fraud", response.Reason)
+	assert.EqualValues(t, "GBP", response.Currency)
+	assert.EqualValues(t, "2020-06-22T12:00:00Z", response.DueAt)
+
+	assert.EqualValues(t, 1, len(store.inserted))
+	assert.EqualValues(t, response.ID, store.inserted[0].ID)
+	assert.EqualValues(t, dispute_domain.StateOpened, store.events[0].Type)
+}
+
+func TestDisputeService_OpenDispute_Errors(t *testing.T) {
+	t.Parallel()
+	request := dispute_domain.DisputeRequest{AuthId: authID, Amount: 60, Reason: "fraud"}
+	refunded := capturedAuth()
+	refunded.AvailableAmount = 100
+	chargedBack := capturedAuth()
+	chargedBack.ChargedBackAmount = 30
+	lost := openDispute(dispute_domain.StateLost)
+
+	tests := []struct {
+		name           string
+		store          *storeMock
+		request        dispute_domain.DisputeRequest
+		expectedStatus int
+		expectedError  string
+	}{
+		{"invalid request", &storeMock{}, dispute_domain.DisputeRequest{AuthId: authID, Amount: 60}, http.StatusUnprocessableEntity, error_constant.InvalidDisputeReason},
+		{"authorisation not found", &storeMock{}, request, http.StatusNotFound, error_constant.TransactionNotFound},
+		{"retrieval failure", &storeMock{authErr: errors.New("disk I/O error")}, request, http.StatusInternalServerError, error_constant.TransactionRetrievalFailure},
+		{"voided authorisation", &storeMock{auth: &auth.Auth{}}, request, http.StatusUnprocessableEntity, error_constant.TransactionStateInvalid},
+		{"nothing captured", &storeMock{auth: refunded, found: true}, request, http.StatusUnprocessableEntity, error_constant.TransactionStateInvalid},
+		{"amount charged back", &storeMock{auth: chargedBack, found: true}, request, http.StatusUnprocessableEntity, error_constant.RequestedAmountNotValid},
+		{"dispute in progress", &storeMock{auth: capturedAuth(), found: true, disputes: []dispute.Dispute{lost, openDispute(dispute_domain.StateSubmitted)}}, request, http.StatusUnprocessableEntity, error_constant.DisputeAlreadyOpen},
+		{"insert failure", &storeMock{auth: capturedAuth(), found: true, insertErr: errors.New("disk I/O error")}, request, http.StatusInternalServerError, error_constant.DisputeUpdateFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(tt.store, &evidenceMock{}).OpenDispute(context.Background(), tt.request)
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
+
+func TestDisputeService_ListDisputes(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}}
+
+	disputes, errInf := newService(store, &evidenceMock{}).ListDisputes(context.Background(), " Opened ", authID)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, 1, len(disputes))
+	assert.EqualValues(t, disputeID, disputes[0].ID)
+	assert.Nil(t, disputes[0].Events)
+
+	_, errInf = newService(store, &evidenceMock{}).ListDisputes(context.Background(), "closed", "")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.InvalidDisputeFilter+"]", errInf.ErrorMessage())
+
+	_, errInf = newService(store, &evidenceMock{}).ListDisputes(context.Background(), "", "123")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.InvalidAuthIdField+"]", errInf.ErrorMessage())
+}
+
+func TestDisputeService_GetDispute(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{
+		disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)},
+		evidence: []dispute.Evidence{{ID: 1, Name: "receipt.pdf", ContentType: "application/pdf", Size: 10, SHA256: "digest", CreatedAt: now}},
+		events:   []dispute.Event{{Type: dispute_domain.StateOpened, Detail: "fraud", CreatedAt: now.Add(-time.Hour)}},
+	}
+
+	response, errInf := newService(store, &evidenceMock{}).GetDispute(context.Background(), disputeID)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, []dispute_domain.EvidenceResponse{{ID: 1, Name: "receipt.pdf", ContentType: "application/pdf", Size: 10, SHA256: "digest", CreatedAt: "2020-06-15T12:00:00Z"}}, response.Evidence)
+	assert.EqualValues(t, []dispute_domain.EventResponse{{Type: dispute_domain.StateOpened, Detail: "fraud", CreatedAt: "2020-06-15T11:00:00Z"}}, response.Events)
+
+	_, errInf = newService(store, &evidenceMock{}).GetDispute(context.Background(), "123")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.InvalidDisputeIdField+"]", errInf.ErrorMessage())
+
+	_, errInf = newService(store, &evidenceMock{}).GetDispute(context.Background(), authID)
+	assert.EqualValues(t, http.StatusNotFound, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.DisputeNotFound+"]", errInf.ErrorMessage())
+}
+
+func TestDisputeService_UpdateState(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}}
+
+	response, errInf := newService(store, &evidenceMock{}).UpdateState(context.Background(), disputeID, dispute_domain.StateRequest{State: "Evidence_Required"})
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, dispute_domain.StateEvidenceRequired, response.State)
+	//the response window starts again when evidence is required
+	assert.EqualValues(t, "2020-06-22T12:00:00Z", response.DueAt)
+	assert.EqualValues(t, 1, len(store.updates))
+	assert.EqualValues(t, dispute_domain.StateOpened, store.updates[0].from)
+	assert.False(t, store.updates[0].chargeBack)
+
+	store = &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateSubmitted)}}
+	response, errInf = newService(store, &evidenceMock{}).UpdateState(context.Background(), disputeID, dispute_domain.StateRequest{State: "lost", Detail: "evidence rejected"})
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, dispute_domain.StateLost, response.State)
+	assert.EqualValues(t, "", response.DueAt)
+	assert.EqualValues(t, "2020-06-15T12:00:00Z", response.ResolvedAt)
+	assert.True(t, store.updates[0].chargeBack)
+	assert.EqualValues(t, dispute.Event{Type: dispute_domain.StateLost, Detail: "evidence rejected", CreatedAt: now}, store.updates[0].event)
+}
+
+func TestDisputeService_UpdateState_Errors(t *testing.T) {
+	t.Parallel()
+	request := dispute_domain.StateRequest{State: dispute_domain.StateWon}
+
+	tests := []struct {
+		name           string
+		store          *storeMock
+		request        dispute_domain.StateRequest
+		expectedStatus int
+		expectedError  string
+	}{
+		{"invalid state", &storeMock{}, dispute_domain.StateRequest{State: dispute_domain.StateSubmitted}, http.StatusUnprocessableEntity, error_constant.InvalidDisputeState},
+		{"dispute not found", &storeMock{}, request, http.StatusNotFound, error_constant.DisputeNotFound},
+		{"retrieval failure", &storeMock{getErr: errors.New("disk I/O error")}, request, http.StatusInternalServerError, error_constant.DisputeRetrievalFailure},
+		{"already decided", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateLost)}}, request, http.StatusUnprocessableEntity, error_constant.DisputeStateInvalid},
+		{"evidence not submitted", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateEvidenceRequired)}}, request, http.StatusUnprocessableEntity, error_constant.DisputeStateInvalid},
+		{"moved concurrently", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}, updateErr: gorm.ErrRecordNotFound}, request, http.StatusUnprocessableEntity, error_constant.DisputeStateInvalid},
+		{"charged back concurrently", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateSubmitted)}, updateErr: dispute.ErrTransactionChanged}, dispute_domain.StateRequest{State: dispute_domain.StateLost}, http.StatusConflict, error_constant.ConcurrentTransactionUpdate},
+		{"update failure", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}, updateErr: errors.New("disk I/O error")}, request, http.StatusInternalServerError, error_constant.DisputeUpdateFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(tt.store, &evidenceMock{}).UpdateState(context.Background(), disputeID, tt.request)
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
+
+func TestDisputeService_AddEvidence(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateEvidenceRequired)}}
+	evidence := &evidenceMock{saved: map[string][]byte{}}
+	service := newService(store, evidence)
+
+	response, errInf := service.AddEvidence(context.Background(), disputeID, "../../etc/receipt.pdf", bytes.NewReader(pdf))
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, dispute_domain.EvidenceResponse{
+		ID:          1,
+		Name:        "receipt.pdf",
+		ContentType: "application/pdf",
+		Size:        int64(len(pdf)),
+		SHA256:      "digest",
+		CreatedAt:   "2020-06-15T12:00:00Z",
+	}, *response)
+	assert.EqualValues(t, pdf, evidence.saved[disputeID+"/document"])
+	assert.EqualValues(t, dispute.Event{DisputeID: disputeID, Type: dispute_domain.EventEvidenceAdded, Detail: "receipt.pdf", CreatedAt: now}, store.events[0])
+
+	file, errInf := service.GetEvidence(context.Background(), disputeID, "1")
+	assert.Nil(t, errInf)
+	content, _ := ioutil.ReadAll(file.Content)
+	assert.EqualValues(t, pdf, content)
+	assert.EqualValues(t, "receipt.pdf", file.Name)
+
+	_, errInf = service.GetEvidence(context.Background(), disputeID, "2")
+	assert.EqualValues(t, http.StatusNotFound, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.EvidenceNotFound+"]", errInf.ErrorMessage())
+
+	_, errInf = service.GetEvidence(context.Background(), disputeID, "first")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.InvalidEvidenceIdField+"]", errInf.ErrorMessage())
+}
+
+func TestDisputeService_AddEvidence_Errors(t *testing.T) {
+	t.Parallel()
+	overdue := openDispute(dispute_domain.StateOpened)
+	overdue.DueAt = now
+
+	tests := []struct {
+		name           string
+		store          *storeMock
+		evidence       *evidenceMock
+		content        []byte
+		expectedStatus int
+		expectedError  string
+	}{
+		{"dispute not found", &storeMock{}, &evidenceMock{}, pdf, http.StatusNotFound, error_constant.DisputeNotFound},
+		{"dispute submitted", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateSubmitted)}}, &evidenceMock{}, pdf, http.StatusUnprocessableEntity, error_constant.DisputeStateInvalid},
+		{"deadline passed", &storeMock{disputes: []dispute.Dispute{overdue}}, &evidenceMock{}, pdf, http.StatusUnprocessableEntity, error_constant.DisputeDeadlinePassed},
+		{"empty file", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}}, &evidenceMock{}, nil, http.StatusUnprocessableEntity, error_constant.InvalidEvidenceFile},
+		{"unsupported file", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}}, &evidenceMock{}, []byte("PK\x03\x04archive"), http.StatusUnprocessableEntity, error_constant.InvalidEvidenceFile},
+		{"save failure", &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}}, &evidenceMock{saveErr: errors.New("no space left on device")}, pdf, http.StatusInternalServerError, error_constant.DisputeUpdateFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(tt.store, tt.evidence).AddEvidence(context.Background(), disputeID, "file", bytes.NewReader(tt.content))
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
+
+func TestDisputeService_AddEvidence_InsertFailure(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}, insertErr: errors.New("disk I/O error")}
+	evidence := &evidenceMock{saved: map[string][]byte{}}
+
+	_, errInf := newService(store, evidence).AddEvidence(context.Background(), disputeID, "notes.txt", strings.NewReader("the goods were delivered"))
+	assert.EqualValues(t, http.StatusInternalServerError, errInf.Status())
+	//the document saved before the failure is removed
+	assert.EqualValues(t, []string{disputeID + "/document"}, evidence.removed)
+}
+
+func TestDisputeService_AddEvidence_TooLarge(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}}
+	evidence := &evidenceMock{saved: map[string][]byte{}}
+
+	_, errInf := newService(store, evidence).AddEvidence(context.Background(), disputeID, "notes.txt", strings.NewReader(strings.Repeat("a", 65)))
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.EvidenceTooLarge+"]", errInf.ErrorMessage())
+	assert.EqualValues(t, 65, len(evidence.saved[disputeID+"/document"]))
+	assert.EqualValues(t, []string{disputeID + "/document"}, evidence.removed)
+	assert.EqualValues(t, 0, len(store.added))
+
+	_, errInf = newService(store, evidence).AddEvidence(context.Background(), disputeID, "notes.txt", strings.NewReader(strings.Repeat("a", 64)))
+	assert.Nil(t, errInf)
+}
+
+func TestDisputeService_SubmitDispute(t *testing.T) {
+	t.Parallel()
+	withEvidence := &storeMock{
+		disputes: []dispute.Dispute{openDispute(dispute_domain.StateEvidenceRequired)},
+		evidence: []dispute.Evidence{{ID: 1, Name: "receipt.pdf"}},
+	}
+
+	response, errInf := newService(withEvidence, &evidenceMock{}).SubmitDispute(context.Background(), disputeID)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, dispute_domain.StateSubmitted, response.State)
+	assert.EqualValues(t, dispute_domain.StateEvidenceRequired, withEvidence.updates[0].from)
+	assert.False(t, withEvidence.updates[0].chargeBack)
+
+	withoutEvidence := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateEvidenceRequired)}}
+	_, errInf = newService(withoutEvidence, &evidenceMock{}).SubmitDispute(context.Background(), disputeID)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.DisputeEvidenceMissing+"]", errInf.ErrorMessage())
+
+	opened := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened)}, evidence: withEvidence.evidence}
+	_, errInf = newService(opened, &evidenceMock{}).SubmitDispute(context.Background(), disputeID)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.DisputeStateInvalid+"]", errInf.ErrorMessage())
+}
+
+func TestDisputeService_ExpireOverdueDisputes(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{disputes: []dispute.Dispute{openDispute(dispute_domain.StateOpened), openDispute(dispute_domain.StateEvidenceRequired)}}
+
+	assert.Nil(t, newService(store, &evidenceMock{}).ExpireOverdueDisputes(context.Background()))
+	assert.EqualValues(t, []string{dispute_domain.StateOpened, dispute_domain.StateEvidenceRequired}, store.dueStates)
+	assert.EqualValues(t, 2, len(store.updates))
+	for _, update := range store.updates {
+		assert.True(t, update.chargeBack)
+		assert.EqualValues(t, dispute_domain.StateLost, update.dispute.State)
+		assert.EqualValues(t, expiredDetail, update.event.Detail)
+	}
+}
@@ -0,0 +1,65 @@
+package dispute_service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+//EvidenceStore keeps the documents attached to the disputes, they are addressed by the path it returns when saving them
+type EvidenceStore interface {
+	Save(disputeID string, content io.Reader) (path string, size int64, digest string, err error)
+	Open(path string) (io.ReadCloser, error)
+	Remove(path string) error
+}
+
+type diskStore struct {
+	dir string
+}
+
+//NewDiskStore creates the evidence store keeping the documents of every dispute in a directory of its own under dir
+func NewDiskStore(dir string) EvidenceStore {
+	return &diskStore{dir: dir}
+}
+
+//Save writes the content to a new file of the dispute, its path is relative to the directory of the store and its
+//digest is the hex encoded SHA-256 of the content
+func (d *diskStore) Save(disputeID string, content io.Reader) (_ string, _ int64, _ string, err error) {
+	if err := os.MkdirAll(filepath.Join(d.dir, disputeID), 0700); err != nil {
+		return "", 0, "", err
+	}
+
+	path := filepath.Join(disputeID, uuid.New().String())
+	file, err := os.OpenFile(filepath.Join(d.dir, path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(filepath.Join(d.dir, path))
+		}
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), content)
+	if err != nil {
+		return "", 0, "", err
+	}
+	return path, size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+//Open opens the document saved under the path
+func (d *diskStore) Open(path string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(d.dir, path))
+}
+
+//Remove deletes the document saved under the path
+func (d *diskStore) Remove(path string) error {
+	return os.Remove(filepath.Join(d.dir, path))
+}
@@ -0,0 +1,33 @@
+package dispute_service
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "evidence")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store := NewDiskStore(dir)
+
+	path, size, digest, err := store.Save(disputeID, bytes.NewReader([]byte("abc")))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, size)
+	assert.EqualValues(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", digest)
+	assert.EqualValues(t, disputeID, filepath.Dir(path))
+
+	content, err := store.Open(path)
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadAll(content)
+	content.Close()
+	assert.EqualValues(t, "abc", string(data))
+
+	assert.Nil(t, store.Remove(path))
+	_, err = store.Open(path)
+	assert.True(t, os.IsNotExist(err))
+}
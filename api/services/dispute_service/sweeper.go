@@ -0,0 +1,54 @@
+package dispute_service
+
+import (
+	"context"
+	"payment-gateway-api/api/logger"
+	"sync"
+	"time"
+)
+
+//Sweeper periodically loses the disputes that have not been answered before their deadline
+type Sweeper struct {
+	service  Service
+	logger   *logger.Logger
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+//NewSweeper creates a sweeper looking for overdue disputes at every interval
+func NewSweeper(service Service, interval time.Duration, logger *logger.Logger) *Sweeper {
+	return &Sweeper{
+		service:  service,
+		logger:   logger,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+//Start runs the sweeper in its own goroutine until Stop is called
+func (s *Sweeper) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.service.ExpireOverdueDisputes(context.Background()); err != nil {
+					s.logger.Error("unable to expire the overdue disputes", logger.Err(err))
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+//Stop signals the sweeper to stop and waits for the expiries in progress to complete
+func (s *Sweeper) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
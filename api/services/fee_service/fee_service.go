@@ -0,0 +1,187 @@
+package fee_service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/fee"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fee_domain"
+	"payment-gateway-api/api/logger"
+)
+
+//Store is the persistence the fee service keeps the fee schedules of the merchants in
+type Store interface {
+	GetFeeSchedule(context.Context, string) (*fee.Schedule, []fee.Rate, error)
+	SaveFeeSchedule(context.Context, *fee.Schedule, []fee.Rate) error
+	DeleteFeeSchedule(context.Context, string) error
+}
+
+//Dependencies are the collaborators of the fee service
+type Dependencies struct {
+	Store  Store
+	Logger *logger.Logger
+}
+
+type feeService struct {
+	store  Store
+	logger *logger.Logger
+}
+
+//Service calculates the fees charged to the merchants on their captures and refunds and manages their fee schedules
+type Service interface {
+	CalculateFee(context.Context, *auth.Auth, string, float32) (float32, error_domain.GatewayErrorInterface)
+	GetSchedule(context.Context, string) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface)
+	SaveSchedule(context.Context, string, fee_domain.ScheduleRequest) (*fee_domain.ScheduleResponse, error_domain.GatewayErrorInterface)
+	DeleteSchedule(context.Context, string) error_domain.GatewayErrorInterface
+}
+
+//New creates the fee service from its dependencies
+func New(deps Dependencies) Service {
+	return &feeService{
+		store:  deps.Store,
+		logger: deps.Logger,
+	}
+}
+
+//CalculateFee returns the fee the merchant of the authorisation is charged for capturing or refunding the amount,
+//rounded to the cent. The merchants without a fee schedule, or without a rate for the brand and currency of the
+//card, are not charged. The fees returned on refunds are negative
+func (f *feeService) CalculateFee(ctx context.Context, record *auth.Auth, operationName string, amount float32) (_ float32, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	schedule, rates, err := f.store.GetFeeSchedule(ctx, record.MerchantID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return 0, nil
+		}
+		f.logger.Ctx(ctx).Error(error_constant.FeeCalculationFailure, logger.Err(err))
+		return 0, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FeeCalculationFailure))
+	}
+
+	rate := matchRate(rates, auth_domain.CardBrand(record.Number), record.Currency)
+	if rate == nil {
+		return 0, nil
+	}
+	var charged float64
+	switch {
+	case operationName == "capture":
+		charged = float64(amount)*rate.Percentage/100 + rate.Fixed
+	case operationName == "refund" && schedule.RefundPolicy == fee_domain.RefundReturn:
+		charged = -float64(amount) * rate.Percentage / 100
+	case operationName == "refund" && schedule.RefundPolicy == fee_domain.RefundCharge:
+		charged = rate.RefundFixed
+	}
+	return float32(math.Round(charged*100) / 100), nil
+}
+
+//matchRate returns the rate of the card brand and currency, falling back on the rate of the brand for any currency,
+//then of the currency for any brand and lastly on the rate applying to any payment
+func matchRate(rates []fee.Rate, brand string, currency string) *fee.Rate {
+	candidates := [][2]string{{brand, currency}, {brand, ""}, {"", currency}, {"", ""}}
+	for _, candidate := range candidates {
+		for i := range rates {
+			if rates[i].Brand == candidate[0] && rates[i].Currency == candidate[1] {
+				return &rates[i]
+			}
+		}
+	}
+	return nil
+}
+
+//GetSchedule returns the fee schedule of a merchant
+func (f *feeService) GetSchedule(ctx context.Context, merchantID string) (_ *fee_domain.ScheduleResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	if !fee_domain.IsMerchantIDValid(merchantID) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidMerchantIdField))
+	}
+
+	schedule, rates, err := f.store.GetFeeSchedule(ctx, merchantID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.FeeScheduleNotFound))
+		}
+		f.logger.Ctx(ctx).Error(error_constant.FeeRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FeeRetrievalFailure))
+	}
+	return toResponse(schedule, rates), nil
+}
+
+//SaveSchedule creates the fee schedule of a merchant or replaces all its fields, the operations already made keep the
+//fees they have been charged
+func (f *feeService) SaveSchedule(ctx context.Context, merchantID string, request fee_domain.ScheduleRequest) (_ *fee_domain.ScheduleResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if !fee_domain.IsMerchantIDValid(merchantID) {
+		errs = append([]error{errors.New(error_constant.InvalidMerchantIdField)}, errs...)
+	}
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	schedule := &fee.Schedule{MerchantID: merchantID, RefundPolicy: request.RefundPolicy}
+	rates := make([]fee.Rate, 0, len(request.Rates))
+	for _, rate := range request.Rates {
+		rates = append(rates, fee.Rate{
+			MerchantID:  merchantID,
+			Brand:       rate.Brand,
+			Currency:    rate.Currency,
+			Percentage:  rate.Percentage,
+			Fixed:       rate.Fixed,
+			RefundFixed: rate.RefundFixed,
+		})
+	}
+	if err := f.store.SaveFeeSchedule(ctx, schedule, rates); err != nil {
+		f.logger.Ctx(ctx).Error(error_constant.FeeUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FeeUpdateFailure))
+	}
+
+	f.logger.Ctx(ctx).Info("fee schedule saved", logger.String("merchant_id", merchantID), logger.Int("rates", len(rates)))
+	return toResponse(schedule, rates), nil
+}
+
+//DeleteSchedule removes the fee schedule of a merchant, its next operations are no longer charged
+func (f *feeService) DeleteSchedule(ctx context.Context, merchantID string) (errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	if !fee_domain.IsMerchantIDValid(merchantID) {
+		return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidMerchantIdField))
+	}
+
+	if err := f.store.DeleteFeeSchedule(ctx, merchantID); err != nil {
+		if err.Error() == "record not found" {
+			return error_domain.New(http.StatusNotFound, errors.New(error_constant.FeeScheduleNotFound))
+		}
+		f.logger.Ctx(ctx).Error(error_constant.FeeUpdateFailure, logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FeeUpdateFailure))
+	}
+
+	f.logger.Ctx(ctx).Info("fee schedule deleted", logger.String("merchant_id", merchantID))
+	return nil
+}
+
+func toResponse(schedule *fee.Schedule, rates []fee.Rate) *fee_domain.ScheduleResponse {
+	response := &fee_domain.ScheduleResponse{
+		MerchantID:   schedule.MerchantID,
+		RefundPolicy: schedule.RefundPolicy,
+		Rates:        make([]fee_domain.Rate, 0, len(rates)),
+		UpdatedAt:    schedule.UpdatedAt.UTC().Format(format_constant.TimestampLayout),
+	}
+	for _, rate := range rates {
+		response.Rates = append(response.Rates, fee_domain.Rate{
+			Brand:       rate.Brand,
+			Currency:    rate.Currency,
+			Percentage:  rate.Percentage,
+			Fixed:       rate.Fixed,
+			RefundFixed: rate.RefundFixed,
+		})
+	}
+	return response
+}
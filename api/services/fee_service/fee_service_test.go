@@ -0,0 +1,147 @@
+package fee_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/fee"
+	"payment-gateway-api/api/domain/fee_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+type storeMock struct {
+	schedule *fee.Schedule
+	rates    []fee.Rate
+	getErr   error
+	saveErr  error
+	saved    *fee.Schedule
+	deleted  string
+}
+
+func (s *storeMock) GetFeeSchedule(ctx context.Context, merchantID string) (*fee.Schedule, []fee.Rate, error) {
+	if s.getErr != nil {
+		return nil, nil, s.getErr
+	}
+	if s.schedule == nil {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return s.schedule, s.rates, nil
+}
+
+func (s *storeMock) SaveFeeSchedule(ctx context.Context, data *fee.Schedule, rates []fee.Rate) error {
+	s.saved = data
+	s.rates = rates
+	return s.saveErr
+}
+
+func (s *storeMock) DeleteFeeSchedule(ctx context.Context, merchantID string) error {
+	if s.schedule == nil {
+		return gorm.ErrRecordNotFound
+	}
+	s.deleted = merchantID
+	return nil
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{
+		Store:  store,
+		Logger: logger.Discard(),
+	})
+}
+
+var rates = []fee.Rate{
+	{MerchantID: "acme", Percentage: 2.9, Fixed: 0.3, RefundFixed: 0.15},
+	{MerchantID: "acme", Currency: "GBP", Percentage: 1.5, Fixed: 0.2, RefundFixed: 0.1},
+	{MerchantID: "acme", Brand: "amex", Percentage: 3.5},
+	{MerchantID: "acme", Brand: "amex", Currency: "GBP", Percentage: 3, Fixed: 0.1},
+}
+
+func TestFeeService_CalculateFee(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{schedule: &fee.Schedule{MerchantID: "acme", RefundPolicy: fee_domain.RefundRetain}, rates: rates}
+	service := newService(store)
+	visa := &auth.Auth{MerchantID: "acme", Number: "4929907390318794", Currency: "GBP"}
+	amex := &auth.Auth{MerchantID: "acme", Number: "378282246310005", Currency: "GBP"}
+
+	//the most specific rate applies
+	charged, err := service.CalculateFee(context.Background(), visa, "capture", 100)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1.7, charged)
+	charged, _ = service.CalculateFee(context.Background(), amex, "capture", 100)
+	assert.EqualValues(t, 3.1, charged)
+	amex.Currency = "USD"
+	charged, _ = service.CalculateFee(context.Background(), amex, "capture", 100)
+	assert.EqualValues(t, 3.5, charged)
+	visa.Currency = "USD"
+	charged, _ = service.CalculateFee(context.Background(), visa, "capture", 33.33)
+	assert.EqualValues(t, 1.27, charged)
+
+	//refunds are charged according to the refund policy
+	charged, _ = service.CalculateFee(context.Background(), visa, "refund", 50)
+	assert.EqualValues(t, 0, charged)
+	store.schedule.RefundPolicy = fee_domain.RefundReturn
+	charged, _ = service.CalculateFee(context.Background(), visa, "refund", 50)
+	assert.EqualValues(t, -1.45, charged)
+	store.schedule.RefundPolicy = fee_domain.RefundCharge
+	charged, _ = service.CalculateFee(context.Background(), visa, "refund", 50)
+	assert.EqualValues(t, 0.15, charged)
+
+	//the merchants without a fee schedule are not charged
+	charged, err = newService(&storeMock{}).CalculateFee(context.Background(), visa, "capture", 100)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, charged)
+
+	_, err = newService(&storeMock{getErr: errors.New("database is locked")}).CalculateFee(context.Background(), visa, "capture", 100)
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.FeeCalculationFailure)}), err.ErrorMessage())
+}
+
+func TestFeeService_SaveSchedule(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{}
+	response, err := newService(store).SaveSchedule(context.Background(), "acme", fee_domain.ScheduleRequest{
+		Rates: []fee_domain.Rate{{Brand: "Visa", Percentage: 1.4, Fixed: 0.2}},
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "acme", store.saved.MerchantID)
+	assert.EqualValues(t, fee_domain.RefundRetain, store.saved.RefundPolicy)
+	assert.EqualValues(t, []fee.Rate{{MerchantID: "acme", Brand: "visa", Percentage: 1.4, Fixed: 0.2}}, store.rates)
+	assert.EqualValues(t, []fee_domain.Rate{{Brand: "visa", Percentage: 1.4, Fixed: 0.2}}, response.Rates)
+
+	_, err = newService(store).SaveSchedule(context.Background(), "acme corp", fee_domain.ScheduleRequest{})
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.InvalidMerchantIdField),
+		errors.New(error_constant.InvalidFeeRates)}), err.ErrorMessage())
+
+	store = &storeMock{saveErr: errors.New("database is locked")}
+	_, err = newService(store).SaveSchedule(context.Background(), "acme", fee_domain.ScheduleRequest{Rates: []fee_domain.Rate{{Fixed: 1}}})
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+}
+
+func TestFeeService_GetAndDeleteSchedule(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{schedule: &fee.Schedule{MerchantID: "acme", RefundPolicy: fee_domain.RefundCharge}, rates: rates[:1]}
+	service := newService(store)
+
+	response, err := service.GetSchedule(context.Background(), "acme")
+	assert.Nil(t, err)
+	assert.EqualValues(t, fee_domain.RefundCharge, response.RefundPolicy)
+	assert.EqualValues(t, []fee_domain.Rate{{Percentage: 2.9, Fixed: 0.3, RefundFixed: 0.15}}, response.Rates)
+
+	assert.Nil(t, service.DeleteSchedule(context.Background(), "acme"))
+	assert.EqualValues(t, "acme", store.deleted)
+
+	_, err = newService(&storeMock{}).GetSchedule(context.Background(), "globex")
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.FeeScheduleNotFound)}), err.ErrorMessage())
+	err = newService(&storeMock{}).DeleteSchedule(context.Background(), "globex")
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+	err = newService(&storeMock{}).DeleteSchedule(context.Background(), "")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+}
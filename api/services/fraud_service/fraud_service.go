@@ -0,0 +1,342 @@
+package fraud_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/fraud"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/fraud_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Store is the persistence the fraud service keeps its rules in and reads the history of the cards from
+type Store interface {
+	ListFraudRules(context.Context) ([]fraud.Rule, error)
+	InsertFraudRule(context.Context, *fraud.Rule) error
+	UpdateFraudRule(context.Context, *fraud.Rule) error
+	DeleteFraudRule(context.Context, uint) error
+	SaveBinCountries(context.Context, []fraud.BinCountry) error
+	GetBinCountry(context.Context, string) (string, error)
+	CountAuthRecordsByNumberSince(context.Context, string, time.Time) (int, error)
+	SumAuthAmountByNumberSince(context.Context, string, string, time.Time) (float64, error)
+	CountDeclinesByNumberSince(context.Context, string, time.Time) (int, error)
+}
+
+//Dependencies are the collaborators of the fraud service
+type Dependencies struct {
+	Store   Store
+	Clock   clock.Clock
+	Logger  *logger.Logger
+	Metrics *metrics.Metrics
+}
+
+type fraudService struct {
+	store   Store
+	clock   clock.Clock
+	logger  *logger.Logger
+	metrics *metrics.Metrics
+}
+
+//Service evaluates the fraud rules against the authorisations and manages the rules
+type Service interface {
+	Evaluate(context.Context, fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface)
+	DryRun(context.Context, fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface)
+	ListRules(context.Context) ([]fraud_domain.Rule, error_domain.GatewayErrorInterface)
+	CreateRule(context.Context, fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface)
+	UpdateRule(context.Context, string, fraud_domain.RuleRequest) (*fraud_domain.Rule, error_domain.GatewayErrorInterface)
+	DeleteRule(context.Context, string) error_domain.GatewayErrorInterface
+	SaveBinCountries(context.Context, fraud_domain.BinCountriesRequest) error_domain.GatewayErrorInterface
+}
+
+//New creates the fraud service from its dependencies
+func New(deps Dependencies) Service {
+	return &fraudService{
+		store:   deps.Store,
+		clock:   deps.Clock,
+		logger:  deps.Logger,
+		metrics: deps.Metrics,
+	}
+}
+
+//Evaluate assesses a transaction about to be authorised against the enabled rules, the card history
+//is read only for the rule types in use. The decision and the triggered rules are recorded in the metrics
+func (f *fraudService) Evaluate(ctx context.Context, transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+	assessment, errInf := f.assess(ctx, transaction)
+	if errInf != nil {
+		return nil, errInf
+	}
+
+	f.metrics.ObserveFraudDecision(assessment.Decision)
+	for _, rule := range assessment.TriggeredRules {
+		f.metrics.ObserveFraudRule(rule.Name, rule.Action, rule.DryRun)
+	}
+	return assessment, nil
+}
+
+//DryRun assesses a transaction the way Evaluate does, without it being recorded anywhere, so that rules can be tried out
+func (f *fraudService) DryRun(ctx context.Context, transaction fraud_domain.Transaction) (*fraud_domain.Assessment, error_domain.GatewayErrorInterface) {
+	errs := transaction.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+	return f.assess(ctx, transaction)
+}
+
+func (f *fraudService) assess(ctx context.Context, transaction fraud_domain.Transaction) (_ *fraud_domain.Assessment, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	log := f.logger.Ctx(ctx)
+	rules, err := f.store.ListFraudRules(ctx)
+	if err != nil {
+		log.Error(error_constant.RuleRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FraudCheckFailure))
+	}
+
+	triggered := make([]fraud_domain.TriggeredRule, 0)
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		reason, err := f.check(ctx, rule, transaction)
+		if err != nil {
+			log.Error(error_constant.FraudCheckFailure, logger.String("rule", rule.Name), logger.Err(err))
+			return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.FraudCheckFailure))
+		}
+		if reason == "" {
+			continue
+		}
+		triggered = append(triggered, fraud_domain.TriggeredRule{
+			ID:     rule.ID,
+			Name:   rule.Name,
+			Type:   rule.Type,
+			Action: rule.Action,
+			DryRun: rule.DryRun,
+			Reason: reason,
+		})
+	}
+
+	return &fraud_domain.Assessment{
+		Decision:       fraud_domain.Decide(triggered),
+		TriggeredRules: triggered,
+	}, nil
+}
+
+//check returns why the rule is triggered by the transaction, or an empty string when it is not
+func (f *fraudService) check(ctx context.Context, rule fraud.Rule, transaction fraud_domain.Transaction) (string, error) {
+	since := f.clock.Now().UTC().Add(-rule.Window)
+
+	switch rule.Type {
+	case fraud_domain.RuleVelocityCount:
+		count, err := f.store.CountAuthRecordsByNumberSince(ctx, transaction.Number, since)
+		if err != nil || float32(count) < rule.Threshold {
+			return "", err
+		}
+		return fmt.Sprintf("%d authorisations of the card within %s", count, rule.Window), nil
+	case fraud_domain.RuleVelocityAmount:
+		if transaction.Currency != rule.Currency {
+			return "", nil
+		}
+		total, err := f.store.SumAuthAmountByNumberSince(ctx, transaction.Number, transaction.Currency, since)
+		total += float64(transaction.Amount)
+		if err != nil || total <= float64(rule.Threshold) {
+			return "", err
+		}
+		return fmt.Sprintf("%.2f %s authorised on the card within %s", total, rule.Currency, rule.Window), nil
+	case fraud_domain.RuleAmountThreshold:
+		if transaction.Currency != rule.Currency || transaction.Amount <= rule.Threshold {
+			return "", nil
+		}
+		return fmt.Sprintf("amount above %.2f %s", rule.Threshold, rule.Currency), nil
+	case fraud_domain.RuleRepeatedDeclines:
+		count, err := f.store.CountDeclinesByNumberSince(ctx, transaction.Number, since)
+		if err != nil || float32(count) < rule.Threshold {
+			return "", err
+		}
+		return fmt.Sprintf("%d declines of the card within %s", count, rule.Window), nil
+	case fraud_domain.RuleBinCountry:
+		country, err := f.store.GetBinCountry(ctx, transaction.Number)
+		if err != nil || country == "" || !contains(strings.Split(rule.Countries, ","), country) {
+			return "", err
+		}
+		return fmt.Sprintf("card issued in %s", country), nil
+	case fraud_domain.RuleAVSMismatch:
+		var mismatches []string
+		if transaction.AVSAddress == auth_domain.AVSNoMatch {
+			mismatches = append(mismatches, "address line")
+		}
+		if transaction.AVSPostcode == auth_domain.AVSNoMatch {
+			mismatches = append(mismatches, "postcode")
+		}
+		switch len(mismatches) {
+		case 0:
+			return "", nil
+		case 1:
+			return fmt.Sprintf("billing %s does not match", mismatches[0]), nil
+		}
+		return fmt.Sprintf("billing %s do not match", strings.Join(mismatches, " and ")), nil
+	}
+	return "", nil
+}
+
+//ListRules returns all the fraud rules, disabled ones included
+func (f *fraudService) ListRules(ctx context.Context) (_ []fraud_domain.Rule, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	records, err := f.store.ListFraudRules(ctx)
+	if err != nil {
+		f.logger.Ctx(ctx).Error(error_constant.RuleRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.RuleRetrievalFailure))
+	}
+
+	rules := make([]fraud_domain.Rule, 0, len(records))
+	for i := range records {
+		rules = append(rules, *toRule(&records[i]))
+	}
+	return rules, nil
+}
+
+//CreateRule adds a fraud rule, it applies to the authorisations that follow straight away
+func (f *fraudService) CreateRule(ctx context.Context, request fraud_domain.RuleRequest) (_ *fraud_domain.Rule, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	record := toRecord(request)
+	if err := f.store.InsertFraudRule(ctx, record); err != nil {
+		f.logger.Ctx(ctx).Error(error_constant.RuleUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.RuleUpdateFailure))
+	}
+
+	f.logger.Ctx(ctx).Info("fraud rule created", logger.Any("rule_id", record.ID), logger.String("rule", record.Name))
+	return toRule(record), nil
+}
+
+//UpdateRule replaces all the fields of a fraud rule
+func (f *fraudService) UpdateRule(ctx context.Context, id string, request fraud_domain.RuleRequest) (_ *fraud_domain.Rule, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	ruleID, err := strconv.ParseUint(id, 10, 32)
+	errs := request.ValidateFields()
+	if err != nil || ruleID == 0 {
+		errs = append([]error{errors.New(error_constant.InvalidRuleIdField)}, errs...)
+	}
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	record := toRecord(request)
+	record.ID = uint(ruleID)
+	if err := f.store.UpdateFraudRule(ctx, record); err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.RuleNotFound))
+		}
+		f.logger.Ctx(ctx).Error(error_constant.RuleUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.RuleUpdateFailure))
+	}
+
+	f.logger.Ctx(ctx).Info("fraud rule updated", logger.Any("rule_id", record.ID), logger.String("rule", record.Name))
+	return toRule(record), nil
+}
+
+//DeleteRule removes a fraud rule
+func (f *fraudService) DeleteRule(ctx context.Context, id string) (errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	ruleID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil || ruleID == 0 {
+		return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidRuleIdField))
+	}
+
+	if err := f.store.DeleteFraudRule(ctx, uint(ruleID)); err != nil {
+		if err.Error() == "record not found" {
+			return error_domain.New(http.StatusNotFound, errors.New(error_constant.RuleNotFound))
+		}
+		f.logger.Ctx(ctx).Error(error_constant.RuleUpdateFailure, logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.RuleUpdateFailure))
+	}
+
+	f.logger.Ctx(ctx).Info("fraud rule deleted", logger.Any("rule_id", ruleID))
+	return nil
+}
+
+//SaveBinCountries records the issuing country of the given bins, used by the bin country rules
+func (f *fraudService) SaveBinCountries(ctx context.Context, request fraud_domain.BinCountriesRequest) (errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	records := make([]fraud.BinCountry, 0, len(request.Bins))
+	for _, bin := range request.Bins {
+		records = append(records, fraud.BinCountry{Bin: bin.Bin, Country: bin.Country})
+	}
+	if err := f.store.SaveBinCountries(ctx, records); err != nil {
+		f.logger.Ctx(ctx).Error(error_constant.RuleUpdateFailure, logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.RuleUpdateFailure))
+	}
+	return nil
+}
+
+//toRecord converts a validated rule request into a fraud rule record
+func toRecord(request fraud_domain.RuleRequest) *fraud.Rule {
+	window, _ := time.ParseDuration(request.Window)
+	enabled := true
+	if request.Enabled != nil {
+		enabled = *request.Enabled
+	}
+	return &fraud.Rule{
+		Name:      request.Name,
+		Type:      request.Type,
+		Action:    request.Action,
+		Threshold: request.Threshold,
+		Currency:  request.Currency,
+		Window:    window,
+		Countries: strings.Join(request.Countries, ","),
+		Enabled:   enabled,
+		DryRun:    request.DryRun,
+	}
+}
+
+//toRule converts a fraud rule record into the format returned by the admin endpoints
+func toRule(record *fraud.Rule) *fraud_domain.Rule {
+	rule := &fraud_domain.Rule{
+		ID:        record.ID,
+		Name:      record.Name,
+		Type:      record.Type,
+		Action:    record.Action,
+		Threshold: record.Threshold,
+		Currency:  record.Currency,
+		Enabled:   record.Enabled,
+		DryRun:    record.DryRun,
+	}
+	if record.Window > 0 {
+		rule.Window = record.Window.String()
+	}
+	if record.Countries != "" {
+		rule.Countries = strings.Split(record.Countries, ",")
+	}
+	return rule
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package fraud_service
+
+import (
+	"context"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/fraud"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/fraud_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"testing"
+	"time"
+)
+
+var (
+	now = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+)
+
+type storeMock struct {
+	rules          []fraud.Rule
+	listErr        error
+	authCount      int
+	authAmount     float64
+	declineCount   int
+	binCountry     string
+	since          []time.Time
+	insertedRule   *fraud.Rule
+	updateErr      error
+	deleteErr      error
+	savedBins      []fraud.BinCountry
+	historyQueries int
+}
+
+func (s *storeMock) ListFraudRules(ctx context.Context) ([]fraud.Rule, error) {
+	return s.rules, s.listErr
+}
+
+func (s *storeMock) InsertFraudRule(ctx context.Context, data *fraud.Rule) error {
+	data.ID = 7
+	s.insertedRule = data
+	return nil
+}
+
+func (s *storeMock) UpdateFraudRule(ctx context.Context, data *fraud.Rule) error {
+	return s.updateErr
+}
+
+func (s *storeMock) DeleteFraudRule(ctx context.Context, id uint) error {
+	return s.deleteErr
+}
+
+func (s *storeMock) SaveBinCountries(ctx context.Context, data []fraud.BinCountry) error {
+	s.savedBins = data
+	return nil
+}
+
+func (s *storeMock) GetBinCountry(ctx context.Context, number string) (string, error) {
+	s.historyQueries++
+	return s.binCountry, nil
+}
+
+func (s *storeMock) CountAuthRecordsByNumberSince(ctx context.Context, number string, since time.Time) (int, error) {
+	s.historyQueries++
+	s.since = append(s.since, since)
+	return s.authCount, nil
+}
+
+func (s *storeMock) SumAuthAmountByNumberSince(ctx context.Context, number string, currency string, since time.Time) (float64, error) {
+	s.historyQueries++
+	s.since = append(s.since, since)
+	return s.authAmount, nil
+}
+
+func (s *storeMock) CountDeclinesByNumberSince(ctx context.Context, number string, since time.Time) (int, error) {
+	s.historyQueries++
+	s.since = append(s.since, since)
+	return s.declineCount, nil
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{
+		Store:   store,
+		Clock:   clock.NewFake(now),
+		Logger:  logger.Discard(),
+		Metrics: metrics.New(),
+	})
+}
+
+func rule(id uint, name string, ruleType string, action string) fraud.Rule {
+	record := fraud.Rule{Name: name, Type: ruleType, Action: action, Enabled: true}
+	record.ID = id
+	return record
+}
+
+var transaction = fraud_domain.Transaction{Number: "4929907390318794", Amount: 600, Currency: "GBP"}
+
+func TestFraudService_Evaluate(t *testing.T) {
+	t.Parallel()
+	velocity := rule(1, "card velocity", fraud_domain.RuleVelocityCount, fraud_domain.DecisionDeny)
+	velocity.Threshold = 5
+	velocity.Window = time.Hour
+	dailyAmount := rule(2, "daily amount", fraud_domain.RuleVelocityAmount, fraud_domain.DecisionReview)
+	dailyAmount.Threshold = 1000
+	dailyAmount.Currency = "GBP"
+	dailyAmount.Window = 24 * time.Hour
+	highAmount := rule(3, "high amount", fraud_domain.RuleAmountThreshold, fraud_domain.DecisionDeny)
+	highAmount.Threshold = 500
+	highAmount.Currency = "GBP"
+	highAmount.DryRun = true
+	declines := rule(4, "repeated declines", fraud_domain.RuleRepeatedDeclines, fraud_domain.DecisionDeny)
+	declines.Threshold = 3
+	declines.Window = time.Hour
+	blocked := rule(5, "blocked issuers", fraud_domain.RuleBinCountry, fraud_domain.DecisionDeny)
+	blocked.Countries = "KP,IR"
+	disabled := rule(6, "disabled", fraud_domain.RuleAmountThreshold, fraud_domain.DecisionDeny)
+	disabled.Threshold = 1
+	disabled.Currency = "GBP"
+	disabled.Enabled = false
+
+	store := &storeMock{
+		rules:        []fraud.Rule{velocity, dailyAmount, highAmount, declines, blocked, disabled},
+		authCount:    4,
+		authAmount:   500,
+		declineCount: 1,
+		binCountry:   "GB",
+	}
+	assessment, err := newService(store).Evaluate(context.Background(), transaction)
+	assert.Nil(t, err)
+	assert.EqualValues(t, fraud_domain.DecisionReview, assessment.Decision)
+	assert.EqualValues(t, []fraud_domain.TriggeredRule{
+		{ID: 2, Name: "daily amount", Type: fraud_domain.RuleVelocityAmount, Action: fraud_domain.DecisionReview, Reason: "1100.00 GBP authorised on the card within 24h0m0s"},
+		{ID: 3, Name: "high amount", Type: fraud_domain.RuleAmountThreshold, Action: fraud_domain.DecisionDeny, DryRun: true, Reason: "amount above 500.00 GBP"},
+	}, assessment.TriggeredRules)
+	//the windows are counted back from the time of the transaction
+	assert.EqualValues(t, []time.Time{now.Add(-time.Hour), now.Add(-24 * time.Hour), now.Add(-time.Hour)}, store.since)
+
+	store.authCount = 5
+	store.declineCount = 3
+	store.binCountry = "KP"
+	assessment, err = newService(store).Evaluate(context.Background(), transaction)
+	assert.Nil(t, err)
+	assert.EqualValues(t, fraud_domain.DecisionDeny, assessment.Decision)
+	assert.EqualValues(t, 5, len(assessment.TriggeredRules))
+	assert.EqualValues(t, "5 authorisations of the card within 1h0m0s", assessment.TriggeredRules[0].Reason)
+	assert.EqualValues(t, "3 declines of the card within 1h0m0s", assessment.TriggeredRules[3].Reason)
+	assert.EqualValues(t, "card issued in KP", assessment.TriggeredRules[4].Reason)
+}
+
+func TestFraudService_Evaluate_OtherCurrency(t *testing.T) {
+	t.Parallel()
+	dailyAmount := rule(2, "daily amount", fraud_domain.RuleVelocityAmount, fraud_domain.DecisionDeny)
+	dailyAmount.Threshold = 10
+	dailyAmount.Currency = "EUR"
+	dailyAmount.Window = 24 * time.Hour
+
+	store := &storeMock{rules: []fraud.Rule{dailyAmount}, authAmount: 5000}
+	assessment, err := newService(store).Evaluate(context.Background(), transaction)
+	assert.Nil(t, err)
+	assert.EqualValues(t, fraud_domain.DecisionAllow, assessment.Decision)
+	assert.Empty(t, assessment.TriggeredRules)
+	//rules of other currencies do not read the history of the card
+	assert.EqualValues(t, 0, store.historyQueries)
+}
+
+func TestFraudService_Evaluate_AVSMismatch(t *testing.T) {
+	t.Parallel()
+	mismatch := rule(7, "avs mismatch", fraud_domain.RuleAVSMismatch, fraud_domain.DecisionReview)
+	store := &storeMock{rules: []fraud.Rule{mismatch}}
+
+	matched := transaction
+	matched.AVSAddress = auth_domain.AVSMatch
+	matched.AVSPostcode = auth_domain.AVSMatch
+	assessment, err := newService(store).Evaluate(context.Background(), matched)
+	assert.Nil(t, err)
+	assert.EqualValues(t, fraud_domain.DecisionAllow, assessment.Decision)
+
+	//authorisations made without a billing address are not checked
+	assessment, err = newService(store).Evaluate(context.Background(), transaction)
+	assert.Nil(t, err)
+	assert.EqualValues(t, fraud_domain.DecisionAllow, assessment.Decision)
+
+	mismatched := matched
+	mismatched.AVSPostcode = auth_domain.AVSNoMatch
+	assessment, err = newService(store).Evaluate(context.Background(), mismatched)
+	assert.Nil(t, err)
+	assert.EqualValues(t, fraud_domain.DecisionReview, assessment.Decision)
+	assert.EqualValues(t, "billing postcode does not match", assessment.TriggeredRules[0].Reason)
+
+	mismatched.AVSAddress = auth_domain.AVSNoMatch
+	assessment, err = newService(store).Evaluate(context.Background(), mismatched)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "billing address line and postcode do not match", assessment.TriggeredRules[0].Reason)
+	assert.EqualValues(t, 0, store.historyQueries)
+}
+
+func TestFraudService_Evaluate_StoreError(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{listErr: errors.New("cannot connect to db")}
+	assessment, err := newService(store).Evaluate(context.Background(), transaction)
+	assert.Nil(t, assessment)
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+	assert.EqualValues(t, "[unable to assess the risk of the transaction]", err.ErrorMessage())
+}
+
+func TestFraudService_CreateRule(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{}
+	result, err := newService(store).CreateRule(context.Background(), fraud_domain.RuleRequest{
+		Name:      "card velocity",
+		Type:      fraud_domain.RuleVelocityCount,
+		Action:    fraud_domain.DecisionDeny,
+		Threshold: 5,
+		Window:    "1h",
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, &fraud_domain.Rule{
+		ID:        7,
+		Name:      "card velocity",
+		Type:      fraud_domain.RuleVelocityCount,
+		Action:    fraud_domain.DecisionDeny,
+		Threshold: 5,
+		Window:    "1h0m0s",
+		Enabled:   true,
+	}, result)
+	assert.EqualValues(t, time.Hour, store.insertedRule.Window)
+
+	_, err = newService(store).CreateRule(context.Background(), fraud_domain.RuleRequest{Name: "x", Type: "x", Action: "deny"})
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+}
+
+func TestFraudService_UpdateAndDeleteRule(t *testing.T) {
+	t.Parallel()
+	request := fraud_domain.RuleRequest{
+		Name:      "blocked issuers",
+		Type:      fraud_domain.RuleBinCountry,
+		Action:    fraud_domain.DecisionDeny,
+		Countries: []string{"KP"},
+	}
+	disabled := false
+	request.Enabled = &disabled
+
+	store := &storeMock{}
+	result, err := newService(store).UpdateRule(context.Background(), "3", request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, result.ID)
+	assert.EqualValues(t, false, result.Enabled)
+	assert.EqualValues(t, []string{"KP"}, result.Countries)
+
+	_, err = newService(store).UpdateRule(context.Background(), "three", request)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, "["+error_constant.InvalidRuleIdField+"]", err.ErrorMessage())
+
+	store.updateErr = gorm.ErrRecordNotFound
+	_, err = newService(store).UpdateRule(context.Background(), "3", request)
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+
+	assert.Nil(t, newService(store).DeleteRule(context.Background(), "3"))
+	store.deleteErr = gorm.ErrRecordNotFound
+	assert.EqualValues(t, http.StatusNotFound, newService(store).DeleteRule(context.Background(), "3").Status())
+	assert.EqualValues(t, http.StatusUnprocessableEntity, newService(store).DeleteRule(context.Background(), "0").Status())
+}
+
+func TestFraudService_DryRun(t *testing.T) {
+	t.Parallel()
+	highAmount := rule(3, "high amount", fraud_domain.RuleAmountThreshold, fraud_domain.DecisionDeny)
+	highAmount.Threshold = 500
+	highAmount.Currency = "GBP"
+	service := newService(&storeMock{rules: []fraud.Rule{highAmount}})
+
+	assessment, err := service.DryRun(context.Background(), transaction)
+	assert.Nil(t, err)
+	assert.EqualValues(t, fraud_domain.DecisionDeny, assessment.Decision)
+
+	_, err = service.DryRun(context.Background(), fraud_domain.Transaction{Number: "4929907390318794", Amount: -1, Currency: "GBP"})
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+}
+
+func TestFraudService_SaveBinCountries(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{}
+	err := newService(store).SaveBinCountries(context.Background(), fraud_domain.BinCountriesRequest{
+		Bins: []fraud_domain.BinCountry{{Bin: "492990", Country: "gb"}},
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []fraud.BinCountry{{Bin: "492990", Country: "GB"}}, store.savedBins)
+}
@@ -0,0 +1,115 @@
+package health_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"payment-gateway-api/api/acquirer"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/domain/health_domain"
+	"payment-gateway-api/api/logger"
+	"sync/atomic"
+)
+
+//Store is the persistence whose reachability and schema decide whether the gateway is ready
+type Store interface {
+	Ping(context.Context) error
+	AppliedSchemaVersion(context.Context) (int, error)
+}
+
+//Dependencies are the collaborators of the health service, the schema version is the one the build expects
+type Dependencies struct {
+	Store         Store
+	Acquirer      acquirer.Acquirer
+	Logger        *logger.Logger
+	SchemaVersion int
+	GitSHA        string
+	BuildTime     string
+}
+
+type healthService struct {
+	store         Store
+	acquirer      acquirer.Acquirer
+	logger        *logger.Logger
+	schemaVersion int
+	gitSHA        string
+	buildTime     string
+	draining      int32
+}
+
+//Service reports whether the gateway is alive, whether it can take traffic and what it has been built from
+type Service interface {
+	Readiness(context.Context) *health_domain.ReadinessResponse
+	Version() *health_domain.VersionResponse
+	Drain()
+}
+
+//New creates the health service from its dependencies
+func New(deps Dependencies) Service {
+	return &healthService{
+		store:         deps.Store,
+		acquirer:      deps.Acquirer,
+		logger:        deps.Logger,
+		schemaVersion: deps.SchemaVersion,
+		gitSHA:        deps.GitSHA,
+		buildTime:     deps.BuildTime,
+	}
+}
+
+//Readiness runs every check, the gateway is ready when the db can be reached, its schema is the one
+//of this build, the acquirer answers and the gateway is not shutting down
+func (h *healthService) Readiness(ctx context.Context) *health_domain.ReadinessResponse {
+	checks := map[string]error{
+		"shutdown":   h.checkShutdown(),
+		"database":   h.store.Ping(ctx),
+		"migrations": h.checkMigrations(ctx),
+		"acquirer":   h.acquirer.Check(ctx),
+	}
+
+	response := &health_domain.ReadinessResponse{
+		Status: health_domain.StatusReady,
+		Checks: make(map[string]string, len(checks)),
+	}
+	for name, err := range checks {
+		if err != nil {
+			h.logger.Ctx(ctx).Warn("readiness check failed", logger.String("check", name), logger.Err(err))
+			response.Status = health_domain.StatusNotReady
+			response.Checks[name] = err.Error()
+			continue
+		}
+		response.Checks[name] = health_domain.StatusOK
+	}
+	return response
+}
+
+//Version returns the build information of the gateway
+func (h *healthService) Version() *health_domain.VersionResponse {
+	return &health_domain.VersionResponse{
+		GitSHA:        h.gitSHA,
+		BuildTime:     h.buildTime,
+		SchemaVersion: h.schemaVersion,
+	}
+}
+
+//Drain makes the gateway report that it is not ready, it is called once the shutdown has started
+func (h *healthService) Drain() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+func (h *healthService) checkShutdown() error {
+	if atomic.LoadInt32(&h.draining) == 1 {
+		return errors.New(error_constant.GatewayShuttingDown)
+	}
+	return nil
+}
+
+func (h *healthService) checkMigrations(ctx context.Context) error {
+	applied, err := h.store.AppliedSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if applied != h.schemaVersion {
+		return fmt.Errorf("db schema version is %d, this build expects %d", applied, h.schemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package health_service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"payment-gateway-api/api/domain/health_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+)
+
+type storeMock struct {
+	ping                 func() error
+	appliedSchemaVersion func() (int, error)
+}
+
+func (s *storeMock) Ping(ctx context.Context) error {
+	return s.ping()
+}
+
+func (s *storeMock) AppliedSchemaVersion(ctx context.Context) (int, error) {
+	return s.appliedSchemaVersion()
+}
+
+type acquirerMock struct {
+	check func() error
+}
+
+func (a *acquirerMock) IsDeclined(ctx context.Context, opName string, cardNumber string) (bool, error) {
+	return false, nil
+}
+
+func (a *acquirerMock) VerifyAddress(ctx context.Context, cardNumber string) (bool, bool, error) {
+	return true, true, nil
+}
+
+func (a *acquirerMock) Check(ctx context.Context) error {
+	return a.check()
+}
+
+func newHealthyMocks() (*storeMock, *acquirerMock) {
+	store := &storeMock{
+		ping:                 func() error { return nil },
+		appliedSchemaVersion: func() (int, error) { return 3, nil },
+	}
+	acquirer := &acquirerMock{
+		check: func() error { return nil },
+	}
+	return store, acquirer
+}
+
+func newService(store *storeMock, acquirer *acquirerMock) Service {
+	return New(Dependencies{
+		Store:         store,
+		Acquirer:      acquirer,
+		Logger:        logger.Discard(),
+		SchemaVersion: 3,
+		GitSHA:        "4f1c2d9",
+		BuildTime:     "2020-07-01T10:00:00Z",
+	})
+}
+
+func TestHealthService_Readiness(t *testing.T) {
+	t.Parallel()
+	service := newService(newHealthyMocks())
+
+	response := service.Readiness(context.Background())
+	assert.True(t, response.IsReady())
+	assert.EqualValues(t, map[string]string{
+		"shutdown":   health_domain.StatusOK,
+		"database":   health_domain.StatusOK,
+		"migrations": health_domain.StatusOK,
+		"acquirer":   health_domain.StatusOK,
+	}, response.Checks)
+}
+
+func TestHealthService_Readiness_FailingChecks(t *testing.T) {
+	t.Parallel()
+	store, acquirer := newHealthyMocks()
+	store.appliedSchemaVersion = func() (int, error) {
+		return 2, nil
+	}
+	acquirer.check = func() error {
+		return errors.New("database is locked")
+	}
+	service := newService(store, acquirer)
+
+	response := service.Readiness(context.Background())
+	assert.False(t, response.IsReady())
+	assert.EqualValues(t, health_domain.StatusNotReady, response.Status)
+	assert.EqualValues(t, health_domain.StatusOK, response.Checks["database"])
+	assert.EqualValues(t, "db schema version is 2, this build expects 3", response.Checks["migrations"])
+	assert.EqualValues(t, "database is locked", response.Checks["acquirer"])
+}
+
+func TestHealthService_Readiness_Draining(t *testing.T) {
+	t.Parallel()
+	service := newService(newHealthyMocks())
+	assert.True(t, service.Readiness(context.Background()).IsReady())
+
+	service.Drain()
+	response := service.Readiness(context.Background())
+	assert.False(t, response.IsReady())
+	assert.EqualValues(t, "the gateway is shutting down", response.Checks["shutdown"])
+}
+
+func TestHealthService_Version(t *testing.T) {
+	t.Parallel()
+	service := newService(newHealthyMocks())
+
+	assert.EqualValues(t, &health_domain.VersionResponse{
+		GitSHA:        "4f1c2d9",
+		BuildTime:     "2020-07-01T10:00:00Z",
+		SchemaVersion: 3,
+	}, service.Version())
+}
@@ -0,0 +1,169 @@
+package ledger_service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/journal"
+	"payment-gateway-api/api/domain/common_validation"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/ledger_domain"
+	"payment-gateway-api/api/ledger"
+	"payment-gateway-api/api/logger"
+	"strings"
+)
+
+//Store is the persistence the ledger service reads the journal from
+type Store interface {
+	GetLedgerBalances(context.Context, string, string) ([]ledger.Balance, error)
+	ListJournalEntries(context.Context, string) ([]journal.Entry, []journal.Posting, error)
+	ListLedgerHolds(context.Context) ([]ledger.Hold, error)
+	ListUnbalancedJournalEntries(context.Context) ([]ledger.Imbalance, error)
+}
+
+//Dependencies are the collaborators of the ledger service
+type Dependencies struct {
+	Store  Store
+	Clock  clock.Clock
+	Logger *logger.Logger
+}
+
+type ledgerService struct {
+	store  Store
+	clock  clock.Clock
+	logger *logger.Logger
+}
+
+//Service reports the balances of the ledger and checks that it agrees with the authorisations
+type Service interface {
+	GetBalances(context.Context, string, string) ([]ledger_domain.BalanceResponse, error_domain.GatewayErrorInterface)
+	ListEntries(context.Context, string) ([]ledger_domain.EntryResponse, error_domain.GatewayErrorInterface)
+	CheckInvariants(context.Context) (*ledger_domain.CheckResponse, error_domain.GatewayErrorInterface)
+}
+
+//New creates the ledger service from its dependencies
+func New(deps Dependencies) Service {
+	return &ledgerService{
+		store:  deps.Store,
+		clock:  deps.Clock,
+		logger: deps.Logger,
+	}
+}
+
+//GetBalances returns the balance of every account per currency, of an authorisation and of a merchant when their ids are given
+func (s *ledgerService) GetBalances(ctx context.Context, authID string, merchantID string) (_ []ledger_domain.BalanceResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	authID = strings.Replace(authID, " ", "", -1)
+	if authID != "" && !common_validation.IsValidUUID(authID) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidAuthIdField))
+	}
+
+	balances, err := s.store.GetLedgerBalances(ctx, authID, strings.TrimSpace(merchantID))
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.LedgerRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.LedgerRetrievalFailure))
+	}
+
+	response := make([]ledger_domain.BalanceResponse, 0, len(balances))
+	for _, balance := range balances {
+		response = append(response, ledger_domain.BalanceResponse{
+			Account:  balance.Account,
+			Currency: balance.Currency,
+			Balance:  ledger.MajorUnits(balance.Amount),
+		})
+	}
+	return response, nil
+}
+
+//ListEntries returns the journal entries of an authorisation with their postings, in the order they were recorded
+func (s *ledgerService) ListEntries(ctx context.Context, authID string) (_ []ledger_domain.EntryResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	authID = strings.Replace(authID, " ", "", -1)
+	if !common_validation.IsValidUUID(authID) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidAuthIdField))
+	}
+
+	entries, postings, err := s.store.ListJournalEntries(ctx, authID)
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.LedgerRetrievalFailure, logger.String("auth_id", authID), logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.LedgerRetrievalFailure))
+	}
+
+	response := make([]ledger_domain.EntryResponse, 0, len(entries))
+	index := make(map[uint]int, len(entries))
+	for _, entry := range entries {
+		index[entry.ID] = len(response)
+		response = append(response, ledger_domain.EntryResponse{
+			ID:          entry.ID,
+			Name:        entry.Name,
+			OperationID: entry.OperationID,
+			MerchantID:  entry.MerchantID,
+			Currency:    entry.Currency,
+			CreatedAt:   entry.CreatedAt.UTC().Format(format_constant.TimestampLayout),
+			Postings:    make([]ledger_domain.PostingResponse, 0, 2),
+		})
+	}
+	for _, posting := range postings {
+		if i, ok := index[posting.EntryID]; ok {
+			response[i].Postings = append(response[i].Postings, ledger_domain.PostingResponse{
+				Account: posting.Account,
+				Amount:  ledger.MajorUnits(posting.Amount),
+			})
+		}
+	}
+	return response, nil
+}
+
+//CheckInvariants checks that every journal entry is balanced and that the hold of every authorisation is its
+//available amount, or zero once it has been voided
+func (s *ledgerService) CheckInvariants(ctx context.Context) (_ *ledger_domain.CheckResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	imbalances, err := s.store.ListUnbalancedJournalEntries(ctx)
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.LedgerRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.LedgerRetrievalFailure))
+	}
+	holds, err := s.store.ListLedgerHolds(ctx)
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.LedgerRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.LedgerRetrievalFailure))
+	}
+
+	response := &ledger_domain.CheckResponse{
+		CheckedAt:      s.clock.Now().UTC().Format(format_constant.TimestampLayout),
+		Authorisations: len(holds),
+		Violations:     make([]ledger_domain.ViolationResponse, 0),
+	}
+	for _, imbalance := range imbalances {
+		response.Violations = append(response.Violations, ledger_domain.ViolationResponse{
+			Type:    ledger_domain.ViolationUnbalancedEntry,
+			EntryID: imbalance.EntryID,
+			Actual:  ledger.MajorUnits(imbalance.Amount),
+		})
+	}
+	for _, hold := range holds {
+		expected := ledger.MinorUnits(hold.AvailableAmount)
+		if hold.Voided {
+			expected = 0
+		}
+		if hold.Balance != expected {
+			response.Violations = append(response.Violations, ledger_domain.ViolationResponse{
+				Type:     ledger_domain.ViolationHoldMismatch,
+				AuthID:   hold.AuthID,
+				Expected: ledger.MajorUnits(expected),
+				Actual:   ledger.MajorUnits(hold.Balance),
+			})
+		}
+	}
+
+	if len(response.Violations) > 0 {
+		s.logger.Ctx(ctx).Error("ledger invariants violated", logger.Int("violations", len(response.Violations)))
+	}
+	return response, nil
+}
@@ -0,0 +1,130 @@
+package ledger_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/journal"
+	"payment-gateway-api/api/domain/ledger_domain"
+	"payment-gateway-api/api/ledger"
+	"payment-gateway-api/api/logger"
+	"testing"
+	"time"
+)
+
+var (
+	now    = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	authID = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+)
+
+type storeMock struct {
+	balances   []ledger.Balance
+	entries    []journal.Entry
+	postings   []journal.Posting
+	holds      []ledger.Hold
+	imbalances []ledger.Imbalance
+	err        error
+	filters    []string
+}
+
+func (s *storeMock) GetLedgerBalances(ctx context.Context, authID string, merchantID string) ([]ledger.Balance, error) {
+	s.filters = []string{authID, merchantID}
+	return s.balances, s.err
+}
+
+func (s *storeMock) ListJournalEntries(ctx context.Context, authID string) ([]journal.Entry, []journal.Posting, error) {
+	return s.entries, s.postings, s.err
+}
+
+func (s *storeMock) ListLedgerHolds(ctx context.Context) ([]ledger.Hold, error) {
+	return s.holds, s.err
+}
+
+func (s *storeMock) ListUnbalancedJournalEntries(ctx context.Context) ([]ledger.Imbalance, error) {
+	return s.imbalances, s.err
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{
+		Store:  store,
+		Clock:  clock.NewFake(now),
+		Logger: logger.Discard(),
+	})
+}
+
+func TestLedgerService_GetBalances(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{balances: []ledger.Balance{{Account: ledger.AccountMerchantReceivable, Currency: "GBP", Amount: 2025}}}
+	balances, err := newService(store).GetBalances(context.Background(), "", " acme ")
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"", "acme"}, store.filters)
+	assert.EqualValues(t, []ledger_domain.BalanceResponse{{Account: ledger.AccountMerchantReceivable, Currency: "GBP", Balance: 20.25}}, balances)
+
+	_, err = newService(store).GetBalances(context.Background(), "not-a-uuid", "")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.InvalidAuthIdField)}), err.ErrorMessage())
+
+	store.err = errors.New("database is locked")
+	_, err = newService(store).GetBalances(context.Background(), authID, "")
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+}
+
+func TestLedgerService_ListEntries(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{
+		entries: []journal.Entry{
+			{ID: 1, AuthID: authID, OperationID: 7, Name: "authorisation", Currency: "GBP", CreatedAt: now},
+			{ID: 2, AuthID: authID, Name: "void", Currency: "GBP", CreatedAt: now.Add(time.Minute)},
+		},
+		postings: []journal.Posting{
+			{EntryID: 1, Account: ledger.AccountCardholderHold, Amount: 1050},
+			{EntryID: 1, Account: ledger.AccountCardholderFunds, Amount: -1050},
+			{EntryID: 2, Account: ledger.AccountCardholderFunds, Amount: 1050},
+			{EntryID: 2, Account: ledger.AccountCardholderHold, Amount: -1050},
+		},
+	}
+	entries, err := newService(store).ListEntries(context.Background(), authID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []ledger_domain.EntryResponse{
+		{ID: 1, Name: "authorisation", OperationID: 7, Currency: "GBP", CreatedAt: "2020-06-15T12:00:00Z", Postings: []ledger_domain.PostingResponse{
+			{Account: ledger.AccountCardholderHold, Amount: 10.5}, {Account: ledger.AccountCardholderFunds, Amount: -10.5},
+		}},
+		{ID: 2, Name: "void", Currency: "GBP", CreatedAt: "2020-06-15T12:01:00Z", Postings: []ledger_domain.PostingResponse{
+			{Account: ledger.AccountCardholderFunds, Amount: 10.5}, {Account: ledger.AccountCardholderHold, Amount: -10.5},
+		}},
+	}, entries)
+
+	_, err = newService(store).ListEntries(context.Background(), "")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+}
+
+func TestLedgerService_CheckInvariants(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{holds: []ledger.Hold{
+		{AuthID: "1a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", AvailableAmount: 40.1, Balance: 4010},
+		{AuthID: "2a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", AvailableAmount: 25, Voided: true},
+	}}
+	check, err := newService(store).CheckInvariants(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, &ledger_domain.CheckResponse{CheckedAt: "2020-06-15T12:00:00Z", Authorisations: 2, Violations: []ledger_domain.ViolationResponse{}}, check)
+
+	//a capture missing from the ledger, a void whose hold has not been released and an entry crediting more than it debits
+	store = &storeMock{
+		holds: []ledger.Hold{
+			{AuthID: "1a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", AvailableAmount: 40, Balance: 10000},
+			{AuthID: "2a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", AvailableAmount: 25, Voided: true, Balance: 2500},
+		},
+		imbalances: []ledger.Imbalance{{EntryID: 3, Amount: -100}},
+	}
+	check, err = newService(store).CheckInvariants(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, []ledger_domain.ViolationResponse{
+		{Type: ledger_domain.ViolationUnbalancedEntry, EntryID: 3, Actual: -1},
+		{Type: ledger_domain.ViolationHoldMismatch, AuthID: "1a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", Expected: 40, Actual: 100},
+		{Type: ledger_domain.ViolationHoldMismatch, AuthID: "2a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", Expected: 0, Actual: 25},
+	}, check.Violations)
+}
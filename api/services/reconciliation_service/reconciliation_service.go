@@ -0,0 +1,211 @@
+package reconciliation_service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/reconciliation"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"payment-gateway-api/api/domain/common_validation"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+)
+
+//Store is the persistence the reconciliation service reads the settled operations from and stores its reports in
+type Store interface {
+	GetSettlementBatchByID(context.Context, string) (*settlement.Batch, []settlement.Total, error)
+	ListSettlementOperations(context.Context, string) ([]operation.Operation, error)
+	CreateReconciliation(context.Context, *reconciliation.Reconciliation, []reconciliation.Item) error
+	ListReconciliations(context.Context, string) ([]reconciliation.Reconciliation, error)
+	GetReconciliationByID(context.Context, string) (*reconciliation.Reconciliation, []reconciliation.Item, error)
+}
+
+//Dependencies are the collaborators of the reconciliation service, the columns are those of the reports of the acquirer
+type Dependencies struct {
+	Store   Store
+	Clock   clock.Clock
+	Logger  *logger.Logger
+	Columns reconciliation_domain.Columns
+}
+
+type reconciliationService struct {
+	store   Store
+	clock   clock.Clock
+	logger  *logger.Logger
+	columns reconciliation_domain.Columns
+}
+
+//Service reconciles the settlement batches with the settlement reports of the acquirer
+type Service interface {
+	ImportReport(context.Context, string, io.Reader, reconciliation_domain.Columns) (*reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface)
+	ListReconciliations(context.Context, string) ([]reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface)
+	GetReconciliation(context.Context, string) (*reconciliation_domain.ReconciliationResponse, error_domain.GatewayErrorInterface)
+}
+
+//New creates the reconciliation service from its dependencies
+func New(deps Dependencies) Service {
+	return &reconciliationService{
+		store:   deps.Store,
+		clock:   deps.Clock,
+		logger:  deps.Logger,
+		columns: deps.Columns,
+	}
+}
+
+//ImportReport reconciles a settlement batch with the report the acquirer paid it out with and stores the result,
+//the columns set override the configured ones for this report
+func (s *reconciliationService) ImportReport(ctx context.Context, batchID string, report io.Reader, columns reconciliation_domain.Columns) (_ *reconciliation_domain.ReconciliationResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	batchID = strings.Replace(batchID, " ", "", -1)
+	if !common_validation.IsValidUUID(batchID) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidBatchIdField))
+	}
+	log := s.logger.Ctx(ctx).With(logger.String("batch_id", batchID))
+	if _, _, err := s.store.GetSettlementBatchByID(ctx, batchID); err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.BatchNotFound))
+		}
+		log.Error(error_constant.BatchRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchRetrievalFailure))
+	}
+
+	lines, errInf := readReport(report, s.withDefaults(columns))
+	if errInf != nil {
+		return nil, errInf
+	}
+	operations, err := s.store.ListSettlementOperations(ctx, batchID)
+	if err != nil {
+		log.Error(error_constant.BatchRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchRetrievalFailure))
+	}
+
+	items := reconcile(lines, operations)
+	record := reconciliation.Reconciliation{
+		ID:        uuid.New().String(),
+		BatchID:   batchID,
+		CreatedAt: s.clock.Now().UTC(),
+	}
+	for _, item := range items {
+		switch item.Status {
+		case reconciliation_domain.StatusMatched:
+			record.Matched++
+		case reconciliation_domain.StatusMissing:
+			record.Missing++
+		case reconciliation_domain.StatusUnexpected:
+			record.Unexpected++
+		case reconciliation_domain.StatusMismatched:
+			record.Mismatched++
+		}
+	}
+	if err := s.store.CreateReconciliation(ctx, &record, items); err != nil {
+		log.Error(error_constant.ReportCreationFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ReportCreationFailure))
+	}
+
+	log.Info("settlement report reconciled", logger.String("reconciliation_id", record.ID), logger.Int("matched", record.Matched),
+		logger.Int("missing", record.Missing), logger.Int("unexpected", record.Unexpected), logger.Int("mismatched", record.Mismatched))
+	return toResponse(&record, items), nil
+}
+
+//ListReconciliations returns the reconciliations without their items, the latest first, of a settlement batch or of all
+//of them when the batch id is empty
+func (s *reconciliationService) ListReconciliations(ctx context.Context, batchID string) (_ []reconciliation_domain.ReconciliationResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	batchID = strings.Replace(batchID, " ", "", -1)
+	if batchID != "" && !common_validation.IsValidUUID(batchID) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidBatchIdField))
+	}
+
+	records, err := s.store.ListReconciliations(ctx, batchID)
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.ReportRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ReportRetrievalFailure))
+	}
+
+	reconciliations := make([]reconciliation_domain.ReconciliationResponse, 0, len(records))
+	for i := range records {
+		reconciliations = append(reconciliations, *toResponse(&records[i], nil))
+	}
+	return reconciliations, nil
+}
+
+//GetReconciliation returns a reconciliation with all its items
+func (s *reconciliationService) GetReconciliation(ctx context.Context, id string) (_ *reconciliation_domain.ReconciliationResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	id = strings.Replace(id, " ", "", -1)
+	if !common_validation.IsValidUUID(id) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidReconciliationIdField))
+	}
+
+	record, items, err := s.store.GetReconciliationByID(ctx, id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.ReconciliationNotFound))
+		}
+		s.logger.Ctx(ctx).Error(error_constant.ReportRetrievalFailure, logger.String("reconciliation_id", id), logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ReportRetrievalFailure))
+	}
+	return toResponse(record, items), nil
+}
+
+//withDefaults completes the columns of a report with the configured ones
+func (s *reconciliationService) withDefaults(columns reconciliation_domain.Columns) reconciliation_domain.Columns {
+	if columns.Reference == "" {
+		columns.Reference = s.columns.Reference
+	}
+	if columns.Type == "" {
+		columns.Type = s.columns.Type
+	}
+	if columns.Amount == "" {
+		columns.Amount = s.columns.Amount
+	}
+	if columns.Currency == "" {
+		columns.Currency = s.columns.Currency
+	}
+	return columns
+}
+
+//toResponse converts a reconciliation into the format returned by the reconciliation endpoints
+func toResponse(record *reconciliation.Reconciliation, items []reconciliation.Item) *reconciliation_domain.ReconciliationResponse {
+	response := &reconciliation_domain.ReconciliationResponse{
+		ID:         record.ID,
+		BatchID:    record.BatchID,
+		CreatedAt:  record.CreatedAt.UTC().Format(format_constant.TimestampLayout),
+		Matched:    record.Matched,
+		Missing:    record.Missing,
+		Unexpected: record.Unexpected,
+		Mismatched: record.Mismatched,
+	}
+	if items != nil {
+		response.Items = make([]reconciliation_domain.ItemResponse, 0, len(items))
+	}
+	for i := range items {
+		item := reconciliation_domain.ItemResponse{
+			Status:    items[i].Status,
+			Line:      items[i].Line,
+			Reference: items[i].Reference,
+			Type:      items[i].Type,
+			Currency:  items[i].Currency,
+		}
+		//the lines of the report have no operation when they are unexpected and the missing operations have no line
+		if items[i].Status != reconciliation_domain.StatusUnexpected {
+			item.ExpectedAmount = &items[i].ExpectedAmount
+		}
+		if items[i].Status != reconciliation_domain.StatusMissing {
+			item.ReportedAmount = &items[i].ReportedAmount
+		}
+		response.Items = append(response.Items, item)
+	}
+	return response
+}
@@ -0,0 +1,190 @@
+package reconciliation_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/reconciliation"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	now              = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	batchID          = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+	reconciliationID = "1a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+	firstAuthID      = "2a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+	secondAuthID     = "3a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+	columns          = reconciliation_domain.Columns{Reference: "reference", Type: "type", Amount: "amount", Currency: "currency"}
+)
+
+type storeMock struct {
+	operations []operation.Operation
+	created    *reconciliation.Reconciliation
+	items      []reconciliation.Item
+	createErr  error
+}
+
+func (s *storeMock) GetSettlementBatchByID(ctx context.Context, id string) (*settlement.Batch, []settlement.Total, error) {
+	if id != batchID {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return &settlement.Batch{ID: id}, nil, nil
+}
+
+func (s *storeMock) ListSettlementOperations(ctx context.Context, id string) ([]operation.Operation, error) {
+	return s.operations, nil
+}
+
+func (s *storeMock) CreateReconciliation(ctx context.Context, data *reconciliation.Reconciliation, items []reconciliation.Item) error {
+	if s.createErr != nil {
+		return s.createErr
+	}
+	s.created, s.items = data, items
+	return nil
+}
+
+func (s *storeMock) ListReconciliations(ctx context.Context, batchID string) ([]reconciliation.Reconciliation, error) {
+	if s.created == nil {
+		return nil, nil
+	}
+	return []reconciliation.Reconciliation{*s.created}, nil
+}
+
+func (s *storeMock) GetReconciliationByID(ctx context.Context, id string) (*reconciliation.Reconciliation, []reconciliation.Item, error) {
+	if s.created == nil || s.created.ID != id {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return s.created, s.items, nil
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{
+		Store:   store,
+		Clock:   clock.NewFake(now),
+		Logger:  logger.Discard(),
+		Columns: columns,
+	})
+}
+
+func newOperation(id uint, authID string, name string, amount float32) operation.Operation {
+	op := operation.Operation{AuthID: authID, Name: name, ProcessedAmount: amount, Currency: "GBP", BatchID: batchID}
+	op.ID = id
+	return op
+}
+
+func amount(value float64) *float64 {
+	return &value
+}
+
+func TestReconciliationService_ImportReport(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{operations: []operation.Operation{
+		newOperation(1, firstAuthID, "capture", 60),
+		newOperation(2, firstAuthID, "capture", 25.5),
+		newOperation(3, firstAuthID, "refund", 10),
+		newOperation(4, secondAuthID, "capture", 40.1),
+	}}
+	//the line paying 60.00 is matched with the first capture although the line paying 59.90 comes before it
+	report := "Reference,Amount,Type,Currency\n" +
+		firstAuthID + ",59.90,capture,GBP\n" +
+		firstAuthID + ",25.50,capture,gbp\n" +
+		firstAuthID + ",60.00,capture,GBP\n" +
+		firstAuthID + ",-10,refund,GBP\n" +
+		"4a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d,15,capture,EUR\n"
+	response, err := newService(store).ImportReport(context.Background(), batchID, strings.NewReader(report), reconciliation_domain.Columns{})
+	assert.Nil(t, err)
+	assert.EqualValues(t, batchID, store.created.BatchID)
+	assert.EqualValues(t, "2020-06-15T12:00:00Z", response.CreatedAt)
+	assert.EqualValues(t, 3, response.Matched)
+	assert.EqualValues(t, 1, response.Missing)
+	assert.EqualValues(t, 2, response.Unexpected)
+	assert.EqualValues(t, 0, response.Mismatched)
+	assert.EqualValues(t, []reconciliation_domain.ItemResponse{
+		{Status: "unexpected", Line: 2, Reference: firstAuthID, Type: "capture", ReportedAmount: amount(59.9), Currency: "GBP"},
+		{Status: "matched", Line: 3, Reference: firstAuthID, Type: "capture", ExpectedAmount: amount(25.5), ReportedAmount: amount(25.5), Currency: "GBP"},
+		{Status: "matched", Line: 4, Reference: firstAuthID, Type: "capture", ExpectedAmount: amount(60), ReportedAmount: amount(60), Currency: "GBP"},
+		{Status: "matched", Line: 5, Reference: firstAuthID, Type: "refund", ExpectedAmount: amount(10), ReportedAmount: amount(-10), Currency: "GBP"},
+		{Status: "unexpected", Line: 6, Reference: "4a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", Type: "capture", ReportedAmount: amount(15), Currency: "EUR"},
+		{Status: "missing", Reference: secondAuthID, Type: "capture", ExpectedAmount: amount(40.1), Currency: "GBP"},
+	}, response.Items)
+	assert.EqualValues(t, 2, store.items[1].OperationID)
+}
+
+func TestReconciliationService_ImportReport_Mismatched(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{operations: []operation.Operation{
+		newOperation(1, firstAuthID, "capture", 60),
+		newOperation(2, secondAuthID, "capture", 40),
+	}}
+	//the columns of the report are mapped for this import, it has no type nor currency
+	report := "txn_ref,paid\n" + firstAuthID + ",59.99\n" + secondAuthID + ",40\n"
+	response, err := newService(store).ImportReport(context.Background(), batchID, strings.NewReader(report),
+		reconciliation_domain.Columns{Reference: "txn_ref", Amount: "paid"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, response.Matched)
+	assert.EqualValues(t, 1, response.Mismatched)
+	assert.EqualValues(t, reconciliation_domain.ItemResponse{
+		Status: "mismatched", Line: 2, Reference: firstAuthID, Type: "capture", ExpectedAmount: amount(60), ReportedAmount: amount(59.99), Currency: "GBP",
+	}, response.Items[0])
+
+	stored, err := newService(store).GetReconciliation(context.Background(), store.created.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, response, stored)
+	list, err := newService(store).ListReconciliations(context.Background(), batchID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(list))
+	assert.Nil(t, list[0].Items)
+}
+
+func TestReconciliationService_ImportReport_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		batchID string
+		report  string
+		status  int
+		message string
+	}{
+		{"invalid batch id", "not-a-uuid", "reference,amount\n", http.StatusUnprocessableEntity, error_constant.InvalidBatchIdField},
+		{"unknown batch", reconciliationID, "reference,amount\n", http.StatusNotFound, error_constant.BatchNotFound},
+		{"empty report", batchID, "", http.StatusUnprocessableEntity, error_constant.InvalidReportFile},
+		{"missing column", batchID, "reference,value\n", http.StatusUnprocessableEntity, error_constant.ReportColumnMissing},
+		{"invalid amount", batchID, "reference,amount\n" + firstAuthID + ",ten\n", http.StatusUnprocessableEntity, error_constant.InvalidReportLine + ": line 2"},
+		{"invalid type", batchID, "reference,amount,type\n" + firstAuthID + ",10,void\n", http.StatusUnprocessableEntity, error_constant.InvalidReportLine + ": line 2"},
+		{"invalid csv", batchID, "reference,amount\n\"" + firstAuthID + ",10\n", http.StatusUnprocessableEntity, error_constant.InvalidReportLine + ": line 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &storeMock{}
+			_, err := newService(store).ImportReport(context.Background(), tc.batchID, strings.NewReader(tc.report), reconciliation_domain.Columns{})
+			assert.EqualValues(t, tc.status, err.Status())
+			assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(tc.message)}), err.ErrorMessage())
+			assert.Nil(t, store.created)
+		})
+	}
+
+	store := &storeMock{createErr: errors.New("database is locked")}
+	_, err := newService(store).ImportReport(context.Background(), batchID, strings.NewReader("reference,amount\n"), reconciliation_domain.Columns{})
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+}
+
+func TestReconciliationService_GetReconciliation(t *testing.T) {
+	t.Parallel()
+	_, err := newService(&storeMock{}).GetReconciliation(context.Background(), "not-a-uuid")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	_, err = newService(&storeMock{}).GetReconciliation(context.Background(), reconciliationID)
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.ReconciliationNotFound)}), err.ErrorMessage())
+	_, err = newService(&storeMock{}).ListReconciliations(context.Background(), "not-a-uuid")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+}
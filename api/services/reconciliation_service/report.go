@@ -0,0 +1,192 @@
+package reconciliation_service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/reconciliation"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/reconciliation_domain"
+	"strconv"
+	"strings"
+)
+
+//reportLine is a payout read from an acquirer settlement report, number is its line in the report counting the header
+type reportLine struct {
+	number    int
+	reference string
+	opType    string
+	amount    float64
+	currency  string
+}
+
+//readReport parses an acquirer settlement report, a csv file whose header line names the columns. The reference and
+//amount columns must be present, the type and currency ones are only read when they are
+func readReport(report io.Reader, columns reconciliation_domain.Columns) ([]reportLine, error_domain.GatewayErrorInterface) {
+	reader := csv.NewReader(report)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, readError(err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		//spreadsheets often start the file with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(name string) int {
+		if i, ok := index[strings.ToLower(strings.TrimSpace(name))]; ok && name != "" {
+			return i
+		}
+		return -1
+	}
+	reference, opType, amount, currency := column(columns.Reference), column(columns.Type), column(columns.Amount), column(columns.Currency)
+	if reference < 0 || amount < 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ReportColumnMissing))
+	}
+
+	var lines []reportLine
+	for number := 2; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, readError(err)
+		}
+		value := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		line := reportLine{
+			number:    number,
+			reference: value(reference),
+			opType:    strings.ToLower(value(opType)),
+			currency:  strings.ToUpper(value(currency)),
+		}
+		parsed, err := strconv.ParseFloat(value(amount), 64)
+		if err != nil || line.reference == "" || !isTypeValid(line.opType) {
+			return nil, lineError(number)
+		}
+		line.amount = parsed
+		lines = append(lines, line)
+	}
+}
+
+//readError reports a report that could not be read, either because it is not a valid csv file or because it is larger
+//than the request bodies accepted by the gateway
+func readError(err error) error_domain.GatewayErrorInterface {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return lineError(parseErr.StartLine)
+	}
+	if err.Error() == "http: request body too large" {
+		return error_domain.New(http.StatusRequestEntityTooLarge, errors.New(error_constant.RequestBodyTooLarge))
+	}
+	return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidReportFile))
+}
+
+func lineError(number int) error_domain.GatewayErrorInterface {
+	return error_domain.New(http.StatusUnprocessableEntity, fmt.Errorf("%s: line %d", error_constant.InvalidReportLine, number))
+}
+
+func isTypeValid(opType string) bool {
+	switch opType {
+	case "", "capture", "refund":
+		return true
+	}
+	return false
+}
+
+//reconcile matches the lines of the report to the captures and refunds of the batch. A line matches an operation of
+//the authorisation it references, of its type when it has one, for the same amount and currency. The lines left are
+//paired with the operations of their authorisation left, and reported as mismatched, or reported as unexpected when
+//there is none. The operations left are missing from the report
+func reconcile(lines []reportLine, operations []operation.Operation) []reconciliation.Item {
+	used := make([]bool, len(operations))
+	paired := make([]int, len(lines))
+	for i := range lines {
+		paired[i] = -1
+		for j := range operations {
+			if !used[j] && isCandidate(&lines[i], &operations[j]) && isSameAmount(&lines[i], &operations[j]) {
+				paired[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i := range lines {
+		for j := 0; j < len(operations) && paired[i] < 0; j++ {
+			if !used[j] && isCandidate(&lines[i], &operations[j]) {
+				paired[i], used[j] = j, true
+			}
+		}
+	}
+
+	items := make([]reconciliation.Item, 0, len(lines)+len(operations))
+	for i, line := range lines {
+		item := reconciliation.Item{
+			Status:         reconciliation_domain.StatusUnexpected,
+			Line:           line.number,
+			Reference:      line.reference,
+			Type:           line.opType,
+			ReportedAmount: line.amount,
+			Currency:       line.currency,
+		}
+		if j := paired[i]; j >= 0 {
+			item.Status = reconciliation_domain.StatusMismatched
+			if isSameAmount(&line, &operations[j]) {
+				item.Status = reconciliation_domain.StatusMatched
+			}
+			item.Type = operations[j].Name
+			item.OperationID = operations[j].ID
+			item.ExpectedAmount = processedAmount(&operations[j])
+			if item.Currency == "" {
+				item.Currency = operations[j].Currency
+			}
+		}
+		items = append(items, item)
+	}
+	for j := range operations {
+		if !used[j] {
+			items = append(items, reconciliation.Item{
+				Status:         reconciliation_domain.StatusMissing,
+				Reference:      operations[j].AuthID,
+				Type:           operations[j].Name,
+				OperationID:    operations[j].ID,
+				ExpectedAmount: processedAmount(&operations[j]),
+				Currency:       operations[j].Currency,
+			})
+		}
+	}
+	return items
+}
+
+func isCandidate(line *reportLine, op *operation.Operation) bool {
+	return line.reference == op.AuthID && (line.opType == "" || line.opType == op.Name)
+}
+
+//isSameAmount compares the amounts in hundredths of their currency, the acquirers may report the refunds as negative amounts
+func isSameAmount(line *reportLine, op *operation.Operation) bool {
+	return minorUnits(math.Abs(line.amount)) == minorUnits(processedAmount(op)) &&
+		(line.currency == "" || line.currency == op.Currency)
+}
+
+//processedAmount returns the amount of the operation, stored as a float32, rounded to the cent
+func processedAmount(op *operation.Operation) float64 {
+	return math.Round(float64(op.ProcessedAmount)*100) / 100
+}
+
+func minorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
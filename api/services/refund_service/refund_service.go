@@ -1,89 +1,153 @@
 package refund_service
 
 import (
+	"context"
 	"errors"
-	"log"
+	"math"
 	"net/http"
+	"payment-gateway-api/api/acquirer"
+	"payment-gateway-api/api/clock"
 	"payment-gateway-api/api/const/error_constant"
-	"payment-gateway-api/api/data_access"
 	"payment-gateway-api/api/data_access/database_model/auth"
 	"payment-gateway-api/api/domain/common_validation"
 	"payment-gateway-api/api/domain/error_domain"
 	"payment-gateway-api/api/domain/refund_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
 	"payment-gateway-api/api/services/common_service"
+	"payment-gateway-api/api/services/fee_service"
 )
 
-type refundService struct{}
+//Store is the persistence the refund service reads and updates the authorisations from
+type Store interface {
+	GetAuthRecordByID(context.Context, string) (bool, *auth.Auth, error)
+	UpdateAvailableAmountByAuthID(context.Context, *auth.Auth, float32, string, float32, float32) error
+}
+
+//Dependencies are the collaborators of the refund service
+type Dependencies struct {
+	Store         Store
+	CommonService common_service.Service
+	FeeService    fee_service.Service
+	Acquirer      acquirer.Acquirer
+	Clock         clock.Clock
+	Logger        *logger.Logger
+	Metrics       *metrics.Metrics
+}
+
+type refundService struct {
+	store         Store
+	commonService common_service.Service
+	feeService    fee_service.Service
+	acquirer      acquirer.Acquirer
+	clock         clock.Clock
+	logger        *logger.Logger
+	metrics       *metrics.Metrics
+}
 
-type refundServiceInterface interface {
-	RefundTransactionAmount(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface)
+//Service refunds amounts of authorised transactions
+type Service interface {
+	RefundTransactionAmount(context.Context, refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface)
 }
 
 var (
-	RefundService refundServiceInterface = &refundService{}
-	operationName                        = "refund"
+	operationName = "refund"
 )
 
+//New creates the refund service from its dependencies
+func New(deps Dependencies) Service {
+	return &refundService{
+		store:         deps.Store,
+		commonService: deps.CommonService,
+		feeService:    deps.FeeService,
+		acquirer:      deps.Acquirer,
+		clock:         deps.Clock,
+		logger:        deps.Logger,
+		metrics:       deps.Metrics,
+	}
+}
+
 //RefundTransactionAmount refunds transaction amount of an already authorised and captured transaction by making sure the request and operations are valid
-func (c *refundService) RefundTransactionAmount(request refund_domain.RefundRequest) (*refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+func (c *refundService) RefundTransactionAmount(ctx context.Context, request refund_domain.RefundRequest) (_ *refund_domain.RefundResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() {
+		errInf = error_domain.FromContext(ctx, errInf)
+		c.metrics.ObserveOperation(operationName, errInf)
+	}()
+
+	log := c.logger.Ctx(ctx).With(logger.String("auth_id", request.AuthId))
+	ctx = logger.NewContext(ctx, log)
+
 	//validate the refund operation
-	authRecord, response, errInf := validateOperation(request)
+	authRecord, response, errInf := c.validateOperation(ctx, request)
 	if errInf != nil {
 		return response, errInf
 	}
 
-	//check that the amount is not greater than the amount that has been previously captured
-	capturedAmount := authRecord.AuthorisedAmount - authRecord.AvailableAmount
+	//check that the amount is not greater than the amount that has been previously captured, less what has been charged back
+	capturedAmount := authRecord.AuthorisedAmount - authRecord.AvailableAmount - authRecord.ChargedBackAmount
 	if request.Amount > capturedAmount {
 		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.RequestedAmountNotValid))
 	}
 	newAvailableAmount := authRecord.AvailableAmount + request.Amount
 
-	//update available amount in db
-	authRecord.AvailableAmount = newAvailableAmount
-	err := data_access.Db.UpdateAvailableAmountByAuthID(authRecord.ID, newAvailableAmount, operationName)
+	//the fee is charged to the merchant according to its fee schedule, it is recorded with the operation only if the
+	//authorisation is still in the state it has been computed from
+	fee, errInf := c.feeService.CalculateFee(ctx, authRecord, operationName, request.Amount)
+	if errInf != nil {
+		return nil, errInf
+	}
+
+	//update available amount in db, it is rejected if another operation changed it since it was read
+	err := c.store.UpdateAvailableAmountByAuthID(ctx, authRecord, newAvailableAmount, operationName, request.Amount, fee)
 	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusConflict, errors.New(error_constant.ConcurrentTransactionUpdate))
+		}
 		return nil, &error_domain.GatewayError{
 			Code:  http.StatusInternalServerError,
 			Error: error_constant.UpdateAvailableAmountFailure,
 		}
 	}
 
+	log.Info("transaction refunded", logger.Any("amount", request.Amount), logger.String("currency", authRecord.Currency), logger.Any("fee", fee))
 	return &refund_domain.RefundResponse{
 		IsSuccess: true,
 		Amount:    newAvailableAmount,
 		Currency:  authRecord.Currency,
+		Fee:       fee,
+		NetAmount: float32(math.Round(float64(request.Amount+fee)*100) / 100),
 	}, nil
 }
 
-func validateOperation(request refund_domain.RefundRequest) (*auth.Auth, *refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+func (c *refundService) validateOperation(ctx context.Context, request refund_domain.RefundRequest) (*auth.Auth, *refund_domain.RefundResponse, error_domain.GatewayErrorInterface) {
+	log := c.logger.Ctx(ctx)
 	errs := request.ValidateFields()
 	if len(errs) > 0 {
 		return nil, nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
 	}
 
 	//check the requested operation is in execution during correct state
-	isValid, err := common_service.CommonService.IsAuthorisedState(operationName, request.AuthId)
+	isValid, err := c.commonService.IsAuthorisedState(ctx, operationName, request.AuthId)
 	if err != nil {
-		log.Println(err.Error())
+		log.Error(error_constant.UnableToCheckForInvalidState, logger.Err(err))
 		return nil, nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.UnableToCheckForInvalidState))
 	}
 	if !isValid {
 		return nil, nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.TransactionStateInvalid))
 	}
 
-	isSoftDeleted, authRecord, err := data_access.Db.GetAuthRecordByID(request.AuthId)
+	isSoftDeleted, authRecord, err := c.store.GetAuthRecordByID(ctx, request.AuthId)
 	if err != nil {
-		log.Println(err.Error())
+		log.Error(error_constant.TransactionRetrievalFailure, logger.Err(err))
 		if err.Error() == "record not found" {
 			return nil, nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
 		}
 		return nil, nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.TransactionRetrievalFailure))
 	}
 	//check card number for refund failure reject
-	isReject, err := data_access.Db.CheckRejectByCardNumber(operationName, authRecord.Number)
+	isReject, err := c.acquirer.IsDeclined(ctx, operationName, authRecord.Number)
 	if err != nil {
-		log.Println(err.Error())
+		log.Error(error_constant.RejectRetrievalFailure, logger.Err(err))
 		return nil, nil, &error_domain.GatewayError{
 			Code:  http.StatusInternalServerError,
 			Error: error_constant.RejectRetrievalFailure,
@@ -98,7 +162,7 @@ func validateOperation(request refund_domain.RefundRequest) (*auth.Auth, *refund
 		return nil, nil, error_domain.New(http.StatusOK, errors.New(error_constant.CancelledTransaction))
 	}
 	//check expiration date, in case it was done at the end of the valid month
-	if isValid := common_validation.IsExpiryDateValid(authRecord.ExpiryDate); !isValid {
+	if isValid := common_validation.IsExpiryDateValid(authRecord.ExpiryDate, c.clock.Now()); !isValid {
 		return nil, nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.ExpiredCard))
 	}
 	return authRecord, nil, nil
@@ -0,0 +1,198 @@
+package report_service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/report"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/report_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+)
+
+//Store is the persistence the activity of the gateway is summed in
+type Store interface {
+	SummariseActivity(context.Context, report.Filter) (*report.Summary, error)
+}
+
+//Dependencies are the collaborators of the report service
+type Dependencies struct {
+	Store  Store
+	Logger *logger.Logger
+}
+
+type reportService struct {
+	store  Store
+	logger *logger.Logger
+}
+
+//Service reports on the activity of the gateway over a period
+type Service interface {
+	GetSummary(context.Context, report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface)
+	GetOverallSummary(context.Context, report_domain.SummaryRequest) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface)
+}
+
+//tally is the summary of a bucket or of the whole period as it is being added up, along with the delays from
+//authorisation to capture its average is made of
+type tally struct {
+	summary        report_domain.Summary
+	captured       int
+	captureSeconds float64
+}
+
+//New creates the report service from its dependencies
+func New(deps Dependencies) Service {
+	return &reportService{
+		store:  deps.Store,
+		logger: deps.Logger,
+	}
+}
+
+//GetSummary returns the summary of the activity of the calling merchant, the merchant filter is replaced by the
+//calling merchant
+func (r *reportService) GetSummary(ctx context.Context, request report_domain.SummaryRequest) (_ *report_domain.SummaryResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	request.MerchantID = merchant.FromContext(ctx)
+	return r.summarise(ctx, request, true)
+}
+
+//GetOverallSummary returns the summary of the activity of every merchant, or of the merchant of the request when set
+func (r *reportService) GetOverallSummary(ctx context.Context, request report_domain.SummaryRequest) (_ *report_domain.SummaryResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	return r.summarise(ctx, request, false)
+}
+
+//summarise returns the volumes, the approval and decline rates, the refund ratio and the average capture time over
+//the period of the request and every bucket of it, the buckets without any activity included
+func (r *reportService) summarise(ctx context.Context, request report_domain.SummaryRequest, scoped bool) (*report_domain.SummaryResponse, error_domain.GatewayErrorInterface) {
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	from, to := request.Range()
+	activity, err := r.store.SummariseActivity(ctx, report.Filter{
+		From:       from,
+		To:         to,
+		MerchantID: request.MerchantID,
+		Scoped:     scoped,
+		Currency:   request.Currency,
+		Bucket:     request.Bucket,
+	})
+	if err != nil {
+		r.logger.Ctx(ctx).Error(error_constant.ReportFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ReportFailure))
+	}
+
+	starts := report_domain.Buckets(from, to, request.Bucket)
+	tallies := make(map[string]*tally, len(starts))
+	for _, start := range starts {
+		tallies[start.Format(report_domain.DateLayout)] = &tally{summary: report_domain.NewSummary()}
+	}
+	totals := &tally{summary: report_domain.NewSummary()}
+	//every row is added to its bucket and to the totals
+	each := func(bucket string, fn func(*tally)) {
+		if t, ok := tallies[bucket]; ok {
+			fn(t)
+		}
+		fn(totals)
+	}
+
+	for i := range activity.Operations {
+		volume := activity.Operations[i]
+		each(volume.Bucket, func(t *tally) {
+			switch volume.Name {
+			case "authorisation":
+				add(&t.summary.Authorisations, &volume)
+			case "pending_review":
+				add(&t.summary.PendingReview, &volume)
+			case "review_declined":
+				//an authorisation declined by a review was never approved
+				decline(t, "review", &volume)
+			case "capture":
+				add(&t.summary.Captures, &volume)
+			case "refund":
+				add(&t.summary.Refunds, &volume)
+			case "void":
+				add(&t.summary.Voids, &volume)
+			}
+		})
+	}
+	for i := range activity.Declines {
+		volume := activity.Declines[i]
+		each(volume.Bucket, func(t *tally) {
+			decline(t, volume.Name, &volume)
+		})
+	}
+	for _, delay := range activity.CaptureDelays {
+		delay := delay
+		each(delay.Bucket, func(t *tally) {
+			t.captured += delay.Count
+			t.captureSeconds += delay.Seconds * float64(delay.Count)
+		})
+	}
+
+	response := &report_domain.SummaryResponse{
+		From:       from.UTC().Format(format_constant.TimestampLayout),
+		To:         to.UTC().Format(format_constant.TimestampLayout),
+		MerchantID: request.MerchantID,
+		Currency:   request.Currency,
+		Bucket:     request.Bucket,
+		Totals:     totals.finish(),
+		Buckets:    make([]report_domain.BucketSummary, 0, len(starts)),
+	}
+	for _, start := range starts {
+		key := start.Format(report_domain.DateLayout)
+		response.Buckets = append(response.Buckets, report_domain.BucketSummary{Start: key, Summary: tallies[key].finish()})
+	}
+	return response, nil
+}
+
+//add adds the rows of a volume of the report to a volume of the summary
+func add(volume *report_domain.Volume, row *report.Volume) {
+	volume.Count += row.Count
+	volume.Amounts[row.Currency] = round(volume.Amounts[row.Currency]+row.Amount, 2)
+}
+
+//decline adds the rows of a volume of the report to the declines of the summary and to those of the reason
+func decline(t *tally, reason string, row *report.Volume) {
+	add(&t.summary.Declines, row)
+	volume, ok := t.summary.DeclinesByReason[reason]
+	if !ok {
+		volume = report_domain.NewVolume()
+	}
+	add(&volume, row)
+	t.summary.DeclinesByReason[reason] = volume
+}
+
+//finish works out the rates of the summary once all its volumes have been added up
+func (t *tally) finish() report_domain.Summary {
+	summary := t.summary
+	attempts := float64(summary.Authorisations.Count + summary.PendingReview.Count + summary.Declines.Count)
+	if attempts > 0 {
+		summary.ApprovalRate = round(float64(summary.Authorisations.Count)/attempts, 4)
+		summary.DeclineRate = round(float64(summary.Declines.Count)/attempts, 4)
+		for reason, volume := range summary.DeclinesByReason {
+			summary.DeclineRates[reason] = round(float64(volume.Count)/attempts, 4)
+		}
+	}
+	if summary.Captures.Count > 0 {
+		summary.RefundRatio = round(float64(summary.Refunds.Count)/float64(summary.Captures.Count), 4)
+	}
+	if t.captured > 0 {
+		summary.AverageCaptureSeconds = round(t.captureSeconds/float64(t.captured), 2)
+	}
+	return summary
+}
+
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,145 @@
+package report_service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/report"
+	"payment-gateway-api/api/domain/report_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"testing"
+	"time"
+)
+
+type storeMock struct {
+	summary *report.Summary
+	err     error
+	filters []report.Filter
+}
+
+func (s *storeMock) SummariseActivity(ctx context.Context, filter report.Filter) (*report.Summary, error) {
+	s.filters = append(s.filters, filter)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.summary, nil
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{Store: store, Logger: logger.Discard()})
+}
+
+func TestReportService_GetSummary(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{summary: &report.Summary{
+		Operations: []report.Volume{
+			{Bucket: "2020-06-01", Name: "authorisation", Currency: "GBP", Count: 3, Amount: 300},
+			{Bucket: "2020-06-01", Name: "authorisation", Currency: "EUR", Count: 1, Amount: 50.5},
+			{Bucket: "2020-06-01", Name: "capture", Currency: "GBP", Count: 2, Amount: 150},
+			{Bucket: "2020-06-01", Name: "refund", Currency: "GBP", Count: 1, Amount: 20},
+			{Bucket: "2020-06-15", Name: "authorisation", Currency: "GBP", Count: 2, Amount: 40},
+			{Bucket: "2020-06-15", Name: "capture", Currency: "GBP", Count: 2, Amount: 40},
+			{Bucket: "2020-06-15", Name: "void", Currency: "GBP", Count: 1, Amount: 10},
+			{Bucket: "2020-06-15", Name: "pending_review", Currency: "GBP", Count: 1, Amount: 25},
+			{Bucket: "2020-06-15", Name: "review_declined", Currency: "GBP", Count: 1, Amount: 15},
+		},
+		Declines: []report.Volume{
+			{Bucket: "2020-06-01", Name: "acquirer", Currency: "GBP", Count: 1, Amount: 500},
+			{Bucket: "2020-06-15", Name: "acquirer", Currency: "GBP", Count: 1, Amount: 60},
+			{Bucket: "2020-06-15", Name: "fraud", Currency: "GBP", Count: 2, Amount: 30},
+		},
+		CaptureDelays: []report.CaptureDelay{
+			{Bucket: "2020-06-01", Count: 2, Seconds: 60},
+			{Bucket: "2020-06-15", Count: 1, Seconds: 3600},
+		},
+	}}
+	request := report_domain.SummaryRequest{From: "2020-06-01T00:00:00Z", To: "2020-06-22T00:00:00Z", Bucket: "week", Currency: "gbp", MerchantID: "globex"}
+
+	response, errInf := newService(store).GetSummary(merchant.NewContext(context.Background(), "acme"), request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, report.Filter{
+		From:       time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC),
+		To:         time.Date(2020, time.June, 22, 0, 0, 0, 0, time.UTC),
+		MerchantID: "acme",
+		Scoped:     true,
+		Currency:   "GBP",
+		Bucket:     report_domain.BucketWeek,
+	}, store.filters[0])
+	assert.EqualValues(t, "2020-06-01T00:00:00Z", response.From)
+	assert.EqualValues(t, "acme", response.MerchantID)
+	assert.EqualValues(t, "GBP", response.Currency)
+
+	totals := response.Totals
+	assert.EqualValues(t, 6, totals.Authorisations.Count)
+	assert.EqualValues(t, map[string]float64{"GBP": 340, "EUR": 50.5}, totals.Authorisations.Amounts)
+	assert.EqualValues(t, 4, totals.Captures.Count)
+	assert.EqualValues(t, 1, totals.Voids.Count)
+	//the authorisations held for review are only approved once reviewed
+	assert.EqualValues(t, 1, totals.PendingReview.Count)
+	assert.EqualValues(t, 5, totals.Declines.Count)
+	assert.EqualValues(t, 2, totals.DeclinesByReason["acquirer"].Count)
+	assert.EqualValues(t, map[string]float64{"GBP": 15}, totals.DeclinesByReason["review"].Amounts)
+	assert.EqualValues(t, 0.5, totals.ApprovalRate)
+	assert.EqualValues(t, 0.4167, totals.DeclineRate)
+	assert.EqualValues(t, 0.1667, totals.DeclineRates["fraud"])
+	assert.EqualValues(t, 0.0833, totals.DeclineRates["review"])
+	assert.EqualValues(t, 0.25, totals.RefundRatio)
+	assert.EqualValues(t, 1240, totals.AverageCaptureSeconds)
+
+	//every week of the period is reported, the one without activity included
+	assert.EqualValues(t, 3, len(response.Buckets))
+	first, second, third := response.Buckets[0], response.Buckets[1], response.Buckets[2]
+	assert.EqualValues(t, "2020-06-01", first.Start)
+	assert.EqualValues(t, 0.8, first.ApprovalRate)
+	assert.EqualValues(t, 0.5, first.RefundRatio)
+	assert.EqualValues(t, 60, first.AverageCaptureSeconds)
+	assert.EqualValues(t, "2020-06-08", second.Start)
+	assert.EqualValues(t, 0, second.Authorisations.Count)
+	assert.EqualValues(t, 0, second.ApprovalRate)
+	assert.NotNil(t, second.Authorisations.Amounts)
+	assert.EqualValues(t, "2020-06-15", third.Start)
+	assert.EqualValues(t, 0.2857, third.ApprovalRate)
+	assert.EqualValues(t, 0, third.RefundRatio)
+	assert.EqualValues(t, 3600, third.AverageCaptureSeconds)
+}
+
+func TestReportService_GetOverallSummary(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{summary: &report.Summary{}}
+	request := report_domain.SummaryRequest{From: "2020-06-01T00:00:00Z", To: "2020-06-08T00:00:00Z", MerchantID: "globex"}
+
+	response, errInf := newService(store).GetOverallSummary(merchant.NewContext(context.Background(), "acme"), request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, "globex", store.filters[0].MerchantID)
+	assert.False(t, store.filters[0].Scoped)
+	assert.EqualValues(t, "globex", response.MerchantID)
+	assert.EqualValues(t, 7, len(response.Buckets))
+}
+
+func TestReportService_GetSummary_Errors(t *testing.T) {
+	t.Parallel()
+	valid := report_domain.SummaryRequest{From: "2020-06-01T00:00:00Z", To: "2020-06-22T00:00:00Z"}
+	tests := []struct {
+		name            string
+		request         report_domain.SummaryRequest
+		storeErr        error
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{"invalid request", report_domain.SummaryRequest{From: valid.From, To: valid.To, Bucket: "year"}, nil, http.StatusUnprocessableEntity, error_constant.InvalidReportBucket},
+		{"store failure", valid, errors.New("disk I/O error"), http.StatusInternalServerError, error_constant.ReportFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(&storeMock{err: tt.storeErr}).GetSummary(context.Background(), tt.request)
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedMessage+"]", errInf.ErrorMessage())
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package review_service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"strings"
+	"time"
+)
+
+//Store is the persistence the review service reads the review queue from and records the decisions in
+type Store interface {
+	GetReviewRecordByAuthID(context.Context, string) (*review.Review, error)
+	ListReviewRecords(context.Context, string) ([]review.Review, error)
+	GetDueReviewRecords(context.Context, time.Time) ([]review.Review, error)
+	CompleteReviewRecord(context.Context, *review.Review, *operation.Operation, bool) error
+}
+
+//Dependencies are the collaborators of the review service
+type Dependencies struct {
+	Store   Store
+	Clock   clock.Clock
+	Logger  *logger.Logger
+	Metrics *metrics.Metrics
+}
+
+type reviewService struct {
+	store   Store
+	clock   clock.Clock
+	logger  *logger.Logger
+	metrics *metrics.Metrics
+}
+
+//Service lets analysts work through the authorisations held for review and declines the ones left too long
+type Service interface {
+	ListReviews(context.Context, string) ([]review_domain.ReviewResponse, error_domain.GatewayErrorInterface)
+	ApproveReview(context.Context, review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface)
+	DeclineReview(context.Context, review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface)
+	DeclineExpiredReviews(context.Context) error
+}
+
+var (
+	expiredReason = "not reviewed within the review SLA"
+)
+
+//New creates the review service from its dependencies
+func New(deps Dependencies) Service {
+	return &reviewService{
+		store:   deps.Store,
+		clock:   deps.Clock,
+		logger:  deps.Logger,
+		metrics: deps.Metrics,
+	}
+}
+
+//ListReviews returns the reviews in the given state, all of them when the state is empty
+func (r *reviewService) ListReviews(ctx context.Context, state string) (_ []review_domain.ReviewResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	state = strings.ToLower(strings.TrimSpace(state))
+	if !review_domain.IsStateValid(state) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidReviewState))
+	}
+
+	records, err := r.store.ListReviewRecords(ctx, state)
+	if err != nil {
+		r.logger.Ctx(ctx).Error(error_constant.ReviewRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ReviewRetrievalFailure))
+	}
+
+	reviews := make([]review_domain.ReviewResponse, 0, len(records))
+	for i := range records {
+		reviews = append(reviews, *toResponse(&records[i]))
+	}
+	return reviews, nil
+}
+
+//ApproveReview releases an authorisation held for review, it can then be captured, voided or refunded as any other
+func (r *reviewService) ApproveReview(ctx context.Context, request review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+	return r.decide(ctx, request, review_domain.StateApproved)
+}
+
+//DeclineReview voids an authorisation held for review
+func (r *reviewService) DeclineReview(ctx context.Context, request review_domain.ReviewDecisionRequest) (*review_domain.ReviewResponse, error_domain.GatewayErrorInterface) {
+	return r.decide(ctx, request, review_domain.StateDeclined)
+}
+
+func (r *reviewService) decide(ctx context.Context, request review_domain.ReviewDecisionRequest, state string) (_ *review_domain.ReviewResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	log := r.logger.Ctx(ctx).With(logger.String("auth_id", request.AuthId))
+	record, err := r.store.GetReviewRecordByAuthID(ctx, request.AuthId)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.ReviewNotFound))
+		}
+		log.Error(error_constant.ReviewRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ReviewRetrievalFailure))
+	}
+	if record.State != review_domain.StatePending {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ReviewStateInvalid))
+	}
+
+	if errInf := r.complete(ctx, record, state, request.Reviewer, request.Reason); errInf != nil {
+		return nil, errInf
+	}
+
+	r.metrics.ObserveReview(state)
+	log.Info("review completed", logger.String("state", state), logger.String("reviewer", request.Reviewer))
+	return toResponse(record), nil
+}
+
+//DeclineExpiredReviews declines the authorisations that have not been reviewed within the SLA,
+//a failure on one of them does not prevent the others from being declined
+func (r *reviewService) DeclineExpiredReviews(ctx context.Context) error {
+	records, err := r.store.GetDueReviewRecords(ctx, r.clock.Now().UTC())
+	if err != nil {
+		r.logger.Ctx(ctx).Error(error_constant.ReviewRetrievalFailure, logger.Err(err))
+		return err
+	}
+
+	for i := range records {
+		errInf := r.complete(ctx, &records[i], review_domain.StateDeclined, review_domain.SystemReviewer, expiredReason)
+		if errInf != nil {
+			continue
+		}
+		r.metrics.ObserveReview("expired")
+		r.logger.Ctx(ctx).Info("review expired", logger.String("auth_id", records[i].AuthID))
+	}
+	return nil
+}
+
+//complete records the decision on the review and the operation recording it, a declined authorisation is voided
+func (r *reviewService) complete(ctx context.Context, record *review.Review, state string, reviewer string, reason string) error_domain.GatewayErrorInterface {
+	now := r.clock.Now().UTC()
+	record.State = state
+	record.Reviewer = reviewer
+	record.Reason = reason
+	record.ReviewedAt = now
+	record.UpdatedAt = now
+
+	op := &operation.Operation{
+		AuthID:   record.AuthID,
+		Name:     "review_" + state,
+		Amount:   record.Amount,
+		Currency: record.Currency,
+		Reviewer: reviewer,
+		Reason:   reason,
+	}
+
+	err := r.store.CompleteReviewRecord(ctx, record, op, state == review_domain.StateDeclined)
+	if err != nil {
+		//the review has been completed by someone else since it was read
+		if err.Error() == "record not found" {
+			return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.ReviewStateInvalid))
+		}
+		r.logger.Ctx(ctx).Error(error_constant.ReviewUpdateFailure, logger.String("auth_id", record.AuthID), logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.ReviewUpdateFailure))
+	}
+	return nil
+}
+
+//toResponse converts a review record into the format returned by the review endpoints
+func toResponse(record *review.Review) *review_domain.ReviewResponse {
+	response := &review_domain.ReviewResponse{
+		AuthID:         record.AuthID,
+		State:          record.State,
+		TriggeredRules: []string{},
+		Amount:         record.Amount,
+		Currency:       record.Currency,
+		DueAt:          record.DueAt.UTC().Format(format_constant.TimestampLayout),
+		Reviewer:       record.Reviewer,
+		Reason:         record.Reason,
+	}
+	if record.Rules != "" {
+		response.TriggeredRules = strings.Split(record.Rules, ",")
+	}
+	if !record.ReviewedAt.IsZero() {
+		response.ReviewedAt = record.ReviewedAt.UTC().Format(format_constant.TimestampLayout)
+	}
+	return response
+}
@@ -0,0 +1,193 @@
+package review_service
+
+import (
+	"context"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
+	"testing"
+	"time"
+)
+
+var (
+	now    = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	authID = "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+)
+
+type completion struct {
+	review   review.Review
+	op       operation.Operation
+	voidAuth bool
+}
+
+type storeMock struct {
+	records     []review.Review
+	listedState string
+	dueAt       time.Time
+	getErr      error
+	completeErr error
+	completed   []completion
+}
+
+func (s *storeMock) GetReviewRecordByAuthID(ctx context.Context, id string) (*review.Review, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	for i := range s.records {
+		if s.records[i].AuthID == id {
+			record := s.records[i]
+			return &record, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *storeMock) ListReviewRecords(ctx context.Context, state string) ([]review.Review, error) {
+	s.listedState = state
+	return s.records, nil
+}
+
+func (s *storeMock) GetDueReviewRecords(ctx context.Context, dueAt time.Time) ([]review.Review, error) {
+	s.dueAt = dueAt
+	return s.records, nil
+}
+
+func (s *storeMock) CompleteReviewRecord(ctx context.Context, data *review.Review, op *operation.Operation, voidAuth bool) error {
+	if s.completeErr != nil {
+		return s.completeErr
+	}
+	s.completed = append(s.completed, completion{review: *data, op: *op, voidAuth: voidAuth})
+	return nil
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{
+		Store:   store,
+		Clock:   clock.NewFake(now),
+		Logger:  logger.Discard(),
+		Metrics: metrics.New(),
+	})
+}
+
+func pendingReview(id string) review.Review {
+	return review.Review{
+		AuthID:   id,
+		State:    review_domain.StatePending,
+		Rules:    "daily amount,new device",
+		Amount:   600,
+		Currency: "GBP",
+		DueAt:    now.Add(time.Hour),
+	}
+}
+
+func TestReviewService_ListReviews(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{records: []review.Review{pendingReview(authID)}}
+
+	reviews, errInf := newService(store).ListReviews(context.Background(), " Pending ")
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, review_domain.StatePending, store.listedState)
+	assert.EqualValues(t, []review_domain.ReviewResponse{{
+		AuthID:         authID,
+		State:          review_domain.StatePending,
+		TriggeredRules: []string{"daily amount", "new device"},
+		Amount:         600,
+		Currency:       "GBP",
+		DueAt:          "2020-06-15T13:00:00Z",
+	}}, reviews)
+
+	_, errInf = newService(store).ListReviews(context.Background(), "expired")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+	assert.EqualValues(t, "["+error_constant.InvalidReviewState+"]", errInf.ErrorMessage())
+}
+
+func TestReviewService_ApproveReview(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{records: []review.Review{pendingReview(authID)}}
+	request := review_domain.ReviewDecisionRequest{AuthId: authID, Reviewer: "analyst@example.com", Reason: "customer confirmed the purchase"}
+
+	response, errInf := newService(store).ApproveReview(context.Background(), request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, review_domain.StateApproved, response.State)
+	assert.EqualValues(t, "analyst@example.com", response.Reviewer)
+	assert.EqualValues(t, "2020-06-15T12:00:00Z", response.ReviewedAt)
+
+	assert.EqualValues(t, 1, len(store.completed))
+	assert.False(t, store.completed[0].voidAuth)
+	assert.EqualValues(t, operation.Operation{
+		AuthID:   authID,
+		Name:     "review_approved",
+		Amount:   600,
+		Currency: "GBP",
+		Reviewer: "analyst@example.com",
+		Reason:   "customer confirmed the purchase",
+	}, store.completed[0].op)
+}
+
+func TestReviewService_DeclineReview(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{records: []review.Review{pendingReview(authID)}}
+	request := review_domain.ReviewDecisionRequest{AuthId: authID, Reviewer: "analyst@example.com", Reason: "stolen card"}
+
+	response, errInf := newService(store).DeclineReview(context.Background(), request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, review_domain.StateDeclined, response.State)
+	assert.EqualValues(t, 1, len(store.completed))
+	assert.True(t, store.completed[0].voidAuth)
+	assert.EqualValues(t, "review_declined", store.completed[0].op.Name)
+}
+
+func TestReviewService_Decide_Errors(t *testing.T) {
+	t.Parallel()
+	request := review_domain.ReviewDecisionRequest{AuthId: authID, Reviewer: "analyst@example.com", Reason: "stolen card"}
+	approved := pendingReview(authID)
+	approved.State = review_domain.StateApproved
+
+	tests := []struct {
+		name           string
+		store          *storeMock
+		request        review_domain.ReviewDecisionRequest
+		expectedStatus int
+		expectedError  string
+	}{
+		{"invalid request", &storeMock{}, review_domain.ReviewDecisionRequest{AuthId: authID, Reason: "stolen card"}, http.StatusUnprocessableEntity, error_constant.InvalidReviewer},
+		{"review not found", &storeMock{}, request, http.StatusNotFound, error_constant.ReviewNotFound},
+		{"retrieval failure", &storeMock{getErr: errors.New("disk I/O error")}, request, http.StatusInternalServerError, error_constant.ReviewRetrievalFailure},
+		{"already reviewed", &storeMock{records: []review.Review{approved}}, request, http.StatusUnprocessableEntity, error_constant.ReviewStateInvalid},
+		{"reviewed concurrently", &storeMock{records: []review.Review{pendingReview(authID)}, completeErr: gorm.ErrRecordNotFound}, request, http.StatusUnprocessableEntity, error_constant.ReviewStateInvalid},
+		{"update failure", &storeMock{records: []review.Review{pendingReview(authID)}, completeErr: errors.New("disk I/O error")}, request, http.StatusInternalServerError, error_constant.ReviewUpdateFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(tt.store).DeclineReview(context.Background(), tt.request)
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
+
+func TestReviewService_DeclineExpiredReviews(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{records: []review.Review{pendingReview(authID), pendingReview("6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12")}}
+
+	assert.Nil(t, newService(store).DeclineExpiredReviews(context.Background()))
+	assert.EqualValues(t, now, store.dueAt)
+	assert.EqualValues(t, 2, len(store.completed))
+	for _, completed := range store.completed {
+		assert.True(t, completed.voidAuth)
+		assert.EqualValues(t, review_domain.StateDeclined, completed.review.State)
+		assert.EqualValues(t, review_domain.SystemReviewer, completed.op.Reviewer)
+		assert.EqualValues(t, "review_declined", completed.op.Name)
+	}
+}
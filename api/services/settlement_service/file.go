@@ -0,0 +1,94 @@
+package settlement_service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"strconv"
+	"strings"
+)
+
+const (
+	//fixedWidthRecordLength is the length of every record of the fixed-width files, padded with spaces
+	fixedWidthRecordLength = 146
+	//fixedWidthMerchantLength is the width of the merchant ids in the fixed-width files, longer ids are truncated
+	fixedWidthMerchantLength = 64
+)
+
+var csvHeader = []string{"batch_id", "cut_off", "merchant_id", "currency", "captures", "captured_amount", "refunds",
+	"refunded_amount", "fees", "net_amount"}
+
+//writeCSV writes the settlement file with a header line and a line for every merchant and currency of the batch
+func writeCSV(record *settlement.Batch, totals []settlement.Total) []byte {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	//writing to a buffer never fails
+	_ = writer.Write(csvHeader)
+	cutOff := record.CutOff.UTC().Format(format_constant.TimestampLayout)
+	for i := range totals {
+		_ = writer.Write([]string{
+			record.ID,
+			cutOff,
+			totals[i].MerchantID,
+			totals[i].Currency,
+			strconv.Itoa(totals[i].Captures),
+			formatAmount(totals[i].CapturedAmount),
+			strconv.Itoa(totals[i].Refunds),
+			formatAmount(totals[i].RefundedAmount),
+			formatAmount(totals[i].Fees),
+			formatAmount(netAmount(&totals[i])),
+		})
+	}
+	writer.Flush()
+	return buffer.Bytes()
+}
+
+//writeFixedWidth writes the settlement file with a header record, a detail record for every merchant and currency of
+//the batch and a trailer record. The amounts are written in hundredths of their currency, zero padded, and the
+//fees and net amount are preceded by their sign
+func writeFixedWidth(record *settlement.Batch, totals []settlement.Total) []byte {
+	var buffer bytes.Buffer
+	writeRecord(&buffer, fmt.Sprintf("H%-36s%s%08d", record.ID, record.CutOff.UTC().Format("20060102150405"), len(totals)))
+	for i := range totals {
+		merchantID := totals[i].MerchantID
+		if len(merchantID) > fixedWidthMerchantLength {
+			merchantID = merchantID[:fixedWidthMerchantLength]
+		}
+		writeRecord(&buffer, fmt.Sprintf("D%-*s%-3s%08d%015d%08d%015d%s%s", fixedWidthMerchantLength, merchantID,
+			totals[i].Currency, totals[i].Captures, minorUnits(totals[i].CapturedAmount), totals[i].Refunds,
+			minorUnits(totals[i].RefundedAmount), signedAmount(totals[i].Fees), signedAmount(netAmount(&totals[i]))))
+	}
+	writeRecord(&buffer, fmt.Sprintf("T%08d%08d", len(totals)+2, record.Operations))
+	return buffer.Bytes()
+}
+
+func writeRecord(buffer *bytes.Buffer, line string) {
+	buffer.WriteString(line)
+	buffer.WriteString(strings.Repeat(" ", fixedWidthRecordLength-len(line)))
+	buffer.WriteString("\n")
+}
+
+//netAmount is the amount paid to the merchant, the captured amount less the refunded amount and the fees
+func netAmount(total *settlement.Total) float64 {
+	return math.Round((total.CapturedAmount-total.RefundedAmount-total.Fees)*100) / 100
+}
+
+//signedAmount writes an amount of the fixed-width files in hundredths of its currency preceded by its sign
+func signedAmount(amount float64) string {
+	units := minorUnits(amount)
+	if units < 0 {
+		return fmt.Sprintf("-%015d", -units)
+	}
+	return fmt.Sprintf("+%015d", units)
+}
+
+func minorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
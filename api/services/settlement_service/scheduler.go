@@ -0,0 +1,54 @@
+package settlement_service
+
+import (
+	"context"
+	"payment-gateway-api/api/logger"
+	"sync"
+	"time"
+)
+
+//Scheduler periodically closes the settlement batch of the cut-off once it has passed
+type Scheduler struct {
+	service  Service
+	logger   *logger.Logger
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+//NewScheduler creates a scheduler looking for a batch to close at every interval
+func NewScheduler(service Service, interval time.Duration, logger *logger.Logger) *Scheduler {
+	return &Scheduler{
+		service:  service,
+		logger:   logger,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+//Start runs the scheduler in its own goroutine until Stop is called
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.service.CloseDueBatch(context.Background()); err != nil {
+					s.logger.Error("unable to close the settlement batch", logger.Err(err))
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+//Stop signals the scheduler to stop and waits for the batch being closed to complete
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
@@ -0,0 +1,268 @@
+package settlement_service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"payment-gateway-api/api/domain/common_validation"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/settlement_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"time"
+)
+
+//Store is the persistence the settlement service groups the captures and refunds into batches with
+type Store interface {
+	CloseSettlementBatch(context.Context, *settlement.Batch) ([]settlement.Total, error)
+	ListSettlementBatches(context.Context) ([]settlement.Batch, error)
+	GetSettlementBatchByID(context.Context, string) (*settlement.Batch, []settlement.Total, error)
+	GetSettlementBatchByCutOff(context.Context, time.Time) (*settlement.Batch, error)
+	SubmitSettlementBatch(context.Context, *settlement.Batch) error
+	ReopenSettlementBatch(context.Context, string) error
+}
+
+//Dependencies are the collaborators of the settlement service, a batch is closed every day at the cut-off,
+//given as the time elapsed since midnight UTC
+type Dependencies struct {
+	Store  Store
+	Clock  clock.Clock
+	Logger *logger.Logger
+	CutOff time.Duration
+}
+
+type settlementService struct {
+	store  Store
+	clock  clock.Clock
+	logger *logger.Logger
+	cutOff time.Duration
+}
+
+//Service closes the daily settlement batches and produces the settlement files submitted to the acquirer
+type Service interface {
+	CloseBatch(context.Context) (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface)
+	ListBatches(context.Context) ([]settlement_domain.BatchResponse, error_domain.GatewayErrorInterface)
+	GetBatch(context.Context, string) (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface)
+	GetSettlementFile(context.Context, string, string) (*settlement_domain.File, error_domain.GatewayErrorInterface)
+	SubmitBatch(context.Context, string) (*settlement_domain.BatchResponse, error_domain.GatewayErrorInterface)
+	ReopenBatch(context.Context, string) error_domain.GatewayErrorInterface
+	CloseDueBatch(context.Context) error
+}
+
+//New creates the settlement service from its dependencies
+func New(deps Dependencies) Service {
+	return &settlementService{
+		store:  deps.Store,
+		clock:  deps.Clock,
+		logger: deps.Logger,
+		cutOff: deps.CutOff,
+	}
+}
+
+//CloseBatch closes the batch of the latest cut-off, it settles the captures and refunds made before it that are not
+//part of a batch yet
+func (s *settlementService) CloseBatch(ctx context.Context) (_ *settlement_domain.BatchResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	cutOff := s.latestCutOff()
+	log := s.logger.Ctx(ctx).With(logger.String("cut_off", cutOff.Format(format_constant.TimestampLayout)))
+	_, err := s.store.GetSettlementBatchByCutOff(ctx, cutOff)
+	if err == nil {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.BatchAlreadyClosed))
+	}
+	if err.Error() != "record not found" {
+		log.Error(error_constant.BatchRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchRetrievalFailure))
+	}
+
+	now := s.clock.Now().UTC()
+	record := settlement.Batch{
+		ID:        uuid.New().String(),
+		CutOff:    cutOff,
+		State:     settlement_domain.StateClosed,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	totals, err := s.store.CloseSettlementBatch(ctx, &record)
+	if err != nil {
+		log.Error(error_constant.BatchUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchUpdateFailure))
+	}
+
+	log.Info("settlement batch closed", logger.String("batch_id", record.ID), logger.Int("operations", record.Operations))
+	return toResponse(&record, totals), nil
+}
+
+//CloseDueBatch closes the batch of the latest cut-off unless it has already been closed
+func (s *settlementService) CloseDueBatch(ctx context.Context) error {
+	_, errInf := s.CloseBatch(ctx)
+	if errInf == nil || errInf.Status() == http.StatusUnprocessableEntity {
+		return nil
+	}
+	return errors.New(errInf.ErrorMessage())
+}
+
+//ListBatches returns all the settlement batches without their totals, the latest first
+func (s *settlementService) ListBatches(ctx context.Context) (_ []settlement_domain.BatchResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	records, err := s.store.ListSettlementBatches(ctx)
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.BatchRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchRetrievalFailure))
+	}
+
+	batches := make([]settlement_domain.BatchResponse, 0, len(records))
+	for i := range records {
+		batches = append(batches, *toResponse(&records[i], nil))
+	}
+	return batches, nil
+}
+
+//GetBatch returns a settlement batch with its totals per merchant and currency
+func (s *settlementService) GetBatch(ctx context.Context, id string) (_ *settlement_domain.BatchResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	record, totals, errInf := s.getBatch(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	return toResponse(record, totals), nil
+}
+
+//GetSettlementFile writes the settlement file of a batch in the requested format, csv by default
+func (s *settlementService) GetSettlementFile(ctx context.Context, id string, format string) (_ *settlement_domain.File, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	if !settlement_domain.IsFormatValid(format) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidSettlementFormat))
+	}
+
+	record, totals, errInf := s.getBatch(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+
+	name := "settlement-" + record.CutOff.UTC().Format("20060102") + "-" + record.ID
+	if format == settlement_domain.FormatFixedWidth {
+		return &settlement_domain.File{Name: name + ".txt", ContentType: "text/plain", Content: writeFixedWidth(record, totals)}, nil
+	}
+	return &settlement_domain.File{Name: name + ".csv", ContentType: "text/csv", Content: writeCSV(record, totals)}, nil
+}
+
+//SubmitBatch records that the settlement file of a closed batch has been submitted to the acquirer, the batch can
+//no longer be reopened
+func (s *settlementService) SubmitBatch(ctx context.Context, id string) (_ *settlement_domain.BatchResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	record, totals, errInf := s.getBatch(ctx, id)
+	if errInf != nil {
+		return nil, errInf
+	}
+	if record.State != settlement_domain.StateClosed {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.BatchStateInvalid))
+	}
+
+	now := s.clock.Now().UTC()
+	record.State = settlement_domain.StateSubmitted
+	record.SubmittedAt = now
+	record.UpdatedAt = now
+	if err := s.store.SubmitSettlementBatch(ctx, record); err != nil {
+		//the batch has been submitted or reopened by another call since it was read
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.BatchStateInvalid))
+		}
+		s.logger.Ctx(ctx).Error(error_constant.BatchUpdateFailure, logger.String("batch_id", id), logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchUpdateFailure))
+	}
+
+	s.logger.Ctx(ctx).Info("settlement batch submitted", logger.String("batch_id", id))
+	return toResponse(record, totals), nil
+}
+
+//ReopenBatch discards a closed batch, its captures and refunds are settled again by the next batch closed at or after
+//its cut-off. A submitted batch cannot be reopened
+func (s *settlementService) ReopenBatch(ctx context.Context, id string) (errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	record, _, errInf := s.getBatch(ctx, id)
+	if errInf != nil {
+		return errInf
+	}
+	if record.State != settlement_domain.StateClosed {
+		return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.BatchStateInvalid))
+	}
+
+	if err := s.store.ReopenSettlementBatch(ctx, record.ID); err != nil {
+		if err.Error() == "record not found" {
+			return error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.BatchStateInvalid))
+		}
+		s.logger.Ctx(ctx).Error(error_constant.BatchUpdateFailure, logger.String("batch_id", id), logger.Err(err))
+		return error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchUpdateFailure))
+	}
+
+	s.logger.Ctx(ctx).Info("settlement batch reopened", logger.String("batch_id", id))
+	return nil
+}
+
+func (s *settlementService) getBatch(ctx context.Context, id string) (*settlement.Batch, []settlement.Total, error_domain.GatewayErrorInterface) {
+	id = strings.Replace(id, " ", "", -1)
+	if !common_validation.IsValidUUID(id) {
+		return nil, nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidBatchIdField))
+	}
+
+	record, totals, err := s.store.GetSettlementBatchByID(ctx, id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.BatchNotFound))
+		}
+		s.logger.Ctx(ctx).Error(error_constant.BatchRetrievalFailure, logger.String("batch_id", id), logger.Err(err))
+		return nil, nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.BatchRetrievalFailure))
+	}
+	return record, totals, nil
+}
+
+//latestCutOff returns the last cut-off that has passed, today's one once it is due and yesterday's one until then
+func (s *settlementService) latestCutOff() time.Time {
+	now := s.clock.Now().UTC()
+	cutOff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(s.cutOff)
+	if cutOff.After(now) {
+		cutOff = cutOff.AddDate(0, 0, -1)
+	}
+	return cutOff
+}
+
+//toResponse converts a settlement batch into the format returned by the settlement endpoints
+func toResponse(record *settlement.Batch, totals []settlement.Total) *settlement_domain.BatchResponse {
+	response := &settlement_domain.BatchResponse{
+		ID:         record.ID,
+		CutOff:     record.CutOff.UTC().Format(format_constant.TimestampLayout),
+		State:      record.State,
+		Operations: record.Operations,
+	}
+	if !record.SubmittedAt.IsZero() {
+		response.SubmittedAt = record.SubmittedAt.UTC().Format(format_constant.TimestampLayout)
+	}
+	if totals != nil {
+		response.Totals = make([]settlement_domain.TotalResponse, 0, len(totals))
+	}
+	for _, total := range totals {
+		response.Totals = append(response.Totals, settlement_domain.TotalResponse{
+			MerchantID:     total.MerchantID,
+			Currency:       total.Currency,
+			Captures:       total.Captures,
+			CapturedAmount: total.CapturedAmount,
+			Refunds:        total.Refunds,
+			RefundedAmount: total.RefundedAmount,
+			Fees:           total.Fees,
+			NetAmount:      netAmount(&total),
+		})
+	}
+	return response
+}
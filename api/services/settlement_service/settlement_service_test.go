@@ -0,0 +1,180 @@
+package settlement_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/settlement"
+	"payment-gateway-api/api/domain/settlement_domain"
+	"payment-gateway-api/api/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	now     = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	batchID = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+)
+
+type storeMock struct {
+	batches  []settlement.Batch
+	totals   []settlement.Total
+	closeErr error
+	closed   *settlement.Batch
+	reopened string
+}
+
+func (s *storeMock) CloseSettlementBatch(ctx context.Context, data *settlement.Batch) ([]settlement.Total, error) {
+	if s.closeErr != nil {
+		return nil, s.closeErr
+	}
+	data.Operations = 3
+	s.closed = data
+	return s.totals, nil
+}
+
+func (s *storeMock) ListSettlementBatches(ctx context.Context) ([]settlement.Batch, error) {
+	return s.batches, nil
+}
+
+func (s *storeMock) GetSettlementBatchByID(ctx context.Context, id string) (*settlement.Batch, []settlement.Total, error) {
+	for i := range s.batches {
+		if s.batches[i].ID == id {
+			record := s.batches[i]
+			return &record, s.totals, nil
+		}
+	}
+	return nil, nil, gorm.ErrRecordNotFound
+}
+
+func (s *storeMock) GetSettlementBatchByCutOff(ctx context.Context, cutOff time.Time) (*settlement.Batch, error) {
+	for i := range s.batches {
+		if s.batches[i].CutOff.Equal(cutOff) {
+			record := s.batches[i]
+			return &record, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *storeMock) SubmitSettlementBatch(ctx context.Context, data *settlement.Batch) error {
+	return nil
+}
+
+func (s *storeMock) ReopenSettlementBatch(ctx context.Context, id string) error {
+	s.reopened = id
+	return nil
+}
+
+func newService(store *storeMock, cutOff time.Duration) Service {
+	return New(Dependencies{
+		Store:  store,
+		Clock:  clock.NewFake(now),
+		Logger: logger.Discard(),
+		CutOff: cutOff,
+	})
+}
+
+var totals = []settlement.Total{
+	{BatchID: batchID, MerchantID: "acme", Currency: "GBP", Captures: 2, CapturedAmount: 60, Refunds: 1, RefundedAmount: 75.5, Fees: 1.8},
+	{BatchID: batchID, MerchantID: "globex", Currency: "USD", Captures: 1, CapturedAmount: 20.25},
+}
+
+func TestSettlementService_CloseBatch(t *testing.T) {
+	t.Parallel()
+	//the cut-off of the day has passed
+	store := &storeMock{totals: totals}
+	response, err := newService(store, 10*time.Hour).CloseBatch(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, now.Add(-2*time.Hour), store.closed.CutOff)
+	assert.EqualValues(t, settlement_domain.StateClosed, store.closed.State)
+	assert.EqualValues(t, "2020-06-15T10:00:00Z", response.CutOff)
+	assert.EqualValues(t, 3, response.Operations)
+	assert.EqualValues(t, settlement_domain.TotalResponse{
+		MerchantID: "acme", Currency: "GBP", Captures: 2, CapturedAmount: 60, Refunds: 1, RefundedAmount: 75.5, Fees: 1.8, NetAmount: -17.3,
+	}, response.Totals[0])
+
+	//until it has, the batch of the day before is closed
+	store = &storeMock{totals: totals}
+	_, err = newService(store, 22*time.Hour).CloseBatch(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, now.Add(-14*time.Hour), store.closed.CutOff)
+
+	//and it is closed once
+	store = &storeMock{batches: []settlement.Batch{{ID: batchID, CutOff: now.Add(-14 * time.Hour)}}}
+	_, err = newService(store, 22*time.Hour).CloseBatch(context.Background())
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.BatchAlreadyClosed)}), err.ErrorMessage())
+	assert.Nil(t, newService(store, 22*time.Hour).CloseDueBatch(context.Background()))
+	assert.Nil(t, store.closed)
+
+	store = &storeMock{closeErr: errors.New("database is locked")}
+	_, err = newService(store, 22*time.Hour).CloseBatch(context.Background())
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+	assert.NotNil(t, newService(store, 22*time.Hour).CloseDueBatch(context.Background()))
+}
+
+func TestSettlementService_SubmitAndReopenBatch(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{batches: []settlement.Batch{{ID: batchID, CutOff: now, State: settlement_domain.StateClosed}}, totals: totals}
+	service := newService(store, 22*time.Hour)
+
+	assert.Nil(t, service.ReopenBatch(context.Background(), batchID))
+	assert.EqualValues(t, batchID, store.reopened)
+
+	response, err := service.SubmitBatch(context.Background(), batchID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, settlement_domain.StateSubmitted, response.State)
+	assert.EqualValues(t, "2020-06-15T12:00:00Z", response.SubmittedAt)
+
+	//once submitted a batch is never reopened nor submitted again
+	store.batches[0].State = settlement_domain.StateSubmitted
+	store.reopened = ""
+	err = service.ReopenBatch(context.Background(), batchID)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.BatchStateInvalid)}), err.ErrorMessage())
+	assert.EqualValues(t, "", store.reopened)
+	_, err = service.SubmitBatch(context.Background(), batchID)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+
+	_, err = service.GetBatch(context.Background(), "9a8b7c6d-5e4f-4a3b-8c2d-000000000000")
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+	_, err = service.GetBatch(context.Background(), "batch")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+}
+
+func TestSettlementService_GetSettlementFile(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{batches: []settlement.Batch{{ID: batchID, CutOff: now, State: settlement_domain.StateClosed, Operations: 4}}, totals: totals}
+	service := newService(store, 22*time.Hour)
+
+	file, err := service.GetSettlementFile(context.Background(), batchID, "")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "settlement-20200615-"+batchID+".csv", file.Name)
+	assert.EqualValues(t, "text/csv", file.ContentType)
+	assert.EqualValues(t, "batch_id,cut_off,merchant_id,currency,captures,captured_amount,refunds,refunded_amount,fees,net_amount\n"+
+		batchID+",2020-06-15T12:00:00Z,acme,GBP,2,60.00,1,75.50,1.80,-17.30\n"+
+		batchID+",2020-06-15T12:00:00Z,globex,USD,1,20.25,0,0.00,0.00,20.25\n", string(file.Content))
+
+	file, err = service.GetSettlementFile(context.Background(), batchID, "FIXED")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "settlement-20200615-"+batchID+".txt", file.Name)
+	lines := strings.Split(strings.TrimSuffix(string(file.Content), "\n"), "\n")
+	assert.EqualValues(t, 4, len(lines))
+	for _, line := range lines {
+		assert.EqualValues(t, fixedWidthRecordLength, len(line))
+	}
+	assert.EqualValues(t, "H"+batchID+"2020061512000000000002", strings.TrimRight(lines[0], " "))
+	assert.EqualValues(t, "D"+"acme"+strings.Repeat(" ", 60)+"GBP"+"00000002"+"000000000006000"+"00000001"+"000000000007550"+"+000000000000180"+"-000000000001730", lines[1])
+	assert.EqualValues(t, "T0000000400000004", strings.TrimRight(lines[3], " "))
+
+	_, err = service.GetSettlementFile(context.Background(), batchID, "xml")
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", []error{errors.New(error_constant.InvalidSettlementFormat)}), err.ErrorMessage())
+}
@@ -0,0 +1,63 @@
+package subscription_service
+
+import (
+	"context"
+	"payment-gateway-api/api/logger"
+	"sync"
+	"time"
+)
+
+//Scheduler periodically charges the subscriptions that are due
+type Scheduler struct {
+	service  Service
+	logger   *logger.Logger
+	interval time.Duration
+	//ctx is cancelled by Stop so that a run in progress does not charge the remaining subscriptions
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+//NewScheduler creates a scheduler checking for due subscriptions at every interval
+func NewScheduler(service Service, interval time.Duration, logger *logger.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		service:  service,
+		logger:   logger,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+	}
+}
+
+//Start runs the scheduler in its own goroutine until Stop is called
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				//a run cancelled by Stop is not an error
+				if err := s.service.ChargeDueSubscriptions(s.ctx); err != nil && s.ctx.Err() == nil {
+					s.logger.Error("unable to charge the due subscriptions", logger.Err(err))
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+//Stop cancels the run in progress and waits for the scheduler to return, the subscription being charged
+//is completed before the run stops
+func (s *Scheduler) Stop() {
+	s.cancel()
+	close(s.stop)
+	s.wg.Wait()
+}
@@ -0,0 +1,434 @@
+package subscription_service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/data_access/database_model/subscription"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/capture_domain"
+	"payment-gateway-api/api/domain/common_validation"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/domain/subscription_domain"
+	"payment-gateway-api/api/domain/void_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/services/authorisation_service"
+	"payment-gateway-api/api/services/capture_service"
+	"payment-gateway-api/api/services/void_service"
+	"time"
+)
+
+//Store is the persistence the subscription service keeps the subscriptions in
+type Store interface {
+	GetAuthRecordByID(context.Context, string) (bool, *auth.Auth, error)
+	GetStoredCardRecord(context.Context, string) (*auth.Auth, error)
+	GetReviewRecordByAuthID(context.Context, string) (*review.Review, error)
+	InsertSubscriptionRecord(context.Context, *subscription.Subscription) error
+	GetSubscriptionRecordByID(context.Context, string) (*subscription.Subscription, error)
+	GetDueSubscriptionRecords(context.Context, time.Time) ([]subscription.Subscription, error)
+	InsertSubscriptionChargeRecord(context.Context, *subscription.Charge) error
+	GetSubscriptionChargeRecord(context.Context, string, int, int) (*subscription.Charge, error)
+	UpdateSubscriptionChargeRecord(context.Context, *subscription.Charge) error
+	UpdateSubscriptionRecord(context.Context, *subscription.Subscription, *subscription.Charge) error
+}
+
+//Dependencies are the collaborators of the subscription service, a declined charge is retried
+//after each of the retry intervals before the subscription is marked as unpaid
+type Dependencies struct {
+	Store                Store
+	AuthorisationService authorisation_service.Service
+	CaptureService       capture_service.Service
+	VoidService          void_service.Service
+	Clock                clock.Clock
+	Logger               *logger.Logger
+	RetryIntervals       []time.Duration
+}
+
+type subscriptionService struct {
+	store                Store
+	authorisationService authorisation_service.Service
+	captureService       capture_service.Service
+	voidService          void_service.Service
+	clock                clock.Clock
+	logger               *logger.Logger
+	retryIntervals       []time.Duration
+}
+
+//Service manages the subscriptions and charges the ones that are due
+type Service interface {
+	CreateSubscription(context.Context, subscription_domain.SubscriptionRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface)
+	PauseSubscription(context.Context, subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface)
+	ResumeSubscription(context.Context, subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface)
+	CancelSubscription(context.Context, subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface)
+	ChargeDueSubscriptions(context.Context) error
+}
+
+//New creates the subscription service from its dependencies
+func New(deps Dependencies) Service {
+	return &subscriptionService{
+		store:                deps.Store,
+		authorisationService: deps.AuthorisationService,
+		captureService:       deps.CaptureService,
+		voidService:          deps.VoidService,
+		clock:                deps.Clock,
+		logger:               deps.Logger,
+		retryIntervals:       deps.RetryIntervals,
+	}
+}
+
+//CreateSubscription schedules recurring charges against the card stored with an existing authorisation
+func (s *subscriptionService) CreateSubscription(ctx context.Context, request subscription_domain.SubscriptionRequest) (_ *subscription_domain.SubscriptionResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	anchorDate, _ := time.Parse(format_constant.AnchorDateLayout, request.AnchorDate)
+	if anchorDate.Before(today(s.clock.Now())) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidAnchorDate))
+	}
+
+	log := s.logger.Ctx(ctx).With(logger.String("auth_id", request.AuthId))
+	isValid, authRecord, err := s.store.GetAuthRecordByID(ctx, request.AuthId)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
+		}
+		log.Error(error_constant.TransactionRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.TransactionRetrievalFailure))
+	}
+	if !isValid {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.CancelledTransaction))
+	}
+	if !common_validation.IsExpiryDateValid(authRecord.ExpiryDate, s.clock.Now()) {
+		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.ExpiredCard))
+	}
+
+	now := s.clock.Now().UTC()
+	record := subscription.Subscription{
+		ID:           uuid.New().String(),
+		AuthID:       authRecord.ID,
+		MerchantID:   authRecord.MerchantID,
+		Amount:       request.Amount,
+		Currency:     request.Currency,
+		Interval:     request.Interval,
+		AnchorDate:   anchorDate,
+		MaxCycles:    request.MaxCycles,
+		NextChargeAt: anchorDate,
+		State:        subscription_domain.StateActive,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.store.InsertSubscriptionRecord(ctx, &record); err != nil {
+		log.Error(error_constant.SubscriptionCreationFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.SubscriptionCreationFailure))
+	}
+
+	return toResponse(&record), nil
+}
+
+//PauseSubscription stops the charges of an active subscription until it is resumed
+func (s *subscriptionService) PauseSubscription(ctx context.Context, request subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+	return s.changeState(ctx, request, func(record *subscription.Subscription) bool {
+		if record.State != subscription_domain.StateActive {
+			return false
+		}
+		record.State = subscription_domain.StatePaused
+		return true
+	})
+}
+
+//ResumeSubscription restarts the charges of a paused or unpaid subscription,
+//the cycles that fell due in the meantime are skipped rather than charged all at once
+func (s *subscriptionService) ResumeSubscription(ctx context.Context, request subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+	return s.changeState(ctx, request, func(record *subscription.Subscription) bool {
+		if record.State != subscription_domain.StatePaused && record.State != subscription_domain.StateUnpaid {
+			return false
+		}
+		startOfToday := today(s.clock.Now())
+		for subscription_domain.ChargeDate(record.AnchorDate, record.Interval, record.CurrentCycle).Before(startOfToday) {
+			record.CurrentCycle++
+		}
+		record.NextChargeAt = subscription_domain.ChargeDate(record.AnchorDate, record.Interval, record.CurrentCycle)
+		record.FailedAttempts = 0
+		record.State = subscription_domain.StateActive
+		return true
+	})
+}
+
+//CancelSubscription definitively stops the charges of a subscription
+func (s *subscriptionService) CancelSubscription(ctx context.Context, request subscription_domain.SubscriptionStateRequest) (*subscription_domain.SubscriptionResponse, error_domain.GatewayErrorInterface) {
+	return s.changeState(ctx, request, func(record *subscription.Subscription) bool {
+		if record.State == subscription_domain.StateCancelled || record.State == subscription_domain.StateCompleted {
+			return false
+		}
+		record.State = subscription_domain.StateCancelled
+		return true
+	})
+}
+
+//changeState applies the transition to the requested subscription, the transition returns false when
+//the subscription is not in a state that allows it
+func (s *subscriptionService) changeState(ctx context.Context, request subscription_domain.SubscriptionStateRequest, transition func(*subscription.Subscription) bool) (_ *subscription_domain.SubscriptionResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	log := s.logger.Ctx(ctx).With(logger.String("subscription_id", request.SubscriptionId))
+	record, err := s.store.GetSubscriptionRecordByID(ctx, request.SubscriptionId)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.SubscriptionNotFound))
+		}
+		log.Error(error_constant.SubscriptionRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.SubscriptionRetrievalFailure))
+	}
+
+	if !transition(record) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.SubscriptionStateInvalid))
+	}
+	record.UpdatedAt = s.clock.Now().UTC()
+
+	if err := s.store.UpdateSubscriptionRecord(ctx, record, nil); err != nil {
+		log.Error(error_constant.SubscriptionUpdateFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.SubscriptionUpdateFailure))
+	}
+
+	return toResponse(record), nil
+}
+
+//ChargeDueSubscriptions authorises and captures every active subscription whose next charge is due. Every attempt
+//is recorded before the card is charged and its outcome saved right after, a subscription whose update fails afterwards
+//stays due and the recorded outcome is applied to it by the next run instead of charging the card again. A charge held
+//for review leaves the subscription due until the review is completed. A cancelled run stops before the next
+//subscription, the one being charged is completed so that its outcome is saved
+func (s *subscriptionService) ChargeDueSubscriptions(ctx context.Context) error {
+	now := s.clock.Now().UTC()
+	records, err := s.store.GetDueSubscriptionRecords(ctx, now)
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.SubscriptionRetrievalFailure, logger.Err(err))
+		return err
+	}
+
+	for i := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record := &records[i]
+		log := s.logger.Ctx(ctx).With(logger.String("subscription_id", record.ID))
+		chargeCtx := withoutCancel{logger.NewContext(ctx, log)}
+
+		charge, ok := s.settleCharge(chargeCtx, record)
+		if !ok {
+			continue
+		}
+		if charge.State == subscription_domain.ChargeStatePendingReview {
+			log.Info("subscription charge pending review", logger.String("auth_id", charge.AuthID),
+				logger.Int("cycle", charge.Cycle), logger.Int("attempt", charge.Attempt))
+			continue
+		}
+
+		s.applyChargeOutcome(record, charge, now)
+		log.Info("subscription charged", logger.String("auth_id", charge.AuthID), logger.Any("success", charge.IsSuccess),
+			logger.Int("cycle", charge.Cycle), logger.Int("attempt", charge.Attempt), logger.String("state", record.State))
+
+		if err := s.store.UpdateSubscriptionRecord(chargeCtx, record, charge); err != nil {
+			log.Error(error_constant.SubscriptionUpdateFailure, logger.String("auth_id", charge.AuthID), logger.Err(err))
+		}
+	}
+
+	return nil
+}
+
+//settleCharge returns the attempt due for the subscription together with its outcome, the card is only charged when
+//the attempt has not been recorded yet. An attempt recorded by an earlier run returns its saved outcome, or the outcome
+//of its review once completed. False is returned when there is no outcome to apply to the subscription yet
+func (s *subscriptionService) settleCharge(ctx context.Context, record *subscription.Subscription) (*subscription.Charge, bool) {
+	log := s.logger.Ctx(ctx)
+	charge, err := s.store.GetSubscriptionChargeRecord(ctx, record.ID, record.CurrentCycle, record.FailedAttempts+1)
+	if err != nil && err.Error() != "record not found" {
+		log.Error(error_constant.SubscriptionRetrievalFailure, logger.Err(err))
+		return nil, false
+	}
+
+	if err != nil {
+		card, err := s.store.GetStoredCardRecord(ctx, record.AuthID)
+		if err != nil {
+			log.Error(error_constant.TransactionRetrievalFailure, logger.String("auth_id", record.AuthID), logger.Err(err))
+			return nil, false
+		}
+
+		charge = &subscription.Charge{
+			SubscriptionID: record.ID,
+			Cycle:          record.CurrentCycle,
+			Attempt:        record.FailedAttempts + 1,
+			State:          subscription_domain.ChargeStateClaimed,
+		}
+		if err := s.store.InsertSubscriptionChargeRecord(ctx, charge); err != nil {
+			log.Error(error_constant.SubscriptionChargeNotClaimed, logger.Int("cycle", charge.Cycle),
+				logger.Int("attempt", charge.Attempt), logger.Err(err))
+			return nil, false
+		}
+		s.chargeSubscription(ctx, record, card, charge)
+	} else {
+		switch charge.State {
+		case subscription_domain.ChargeStateSucceeded, subscription_domain.ChargeStateFailed:
+			//the outcome has been saved but the subscription could not be updated with it
+			return charge, true
+		case subscription_domain.ChargeStatePendingReview:
+			if !s.completeReviewedCharge(ctx, record, charge) {
+				return nil, false
+			}
+		default:
+			//the card may be being charged by another run, or the run stopped before the outcome could be saved
+			log.Warn(error_constant.SubscriptionChargeUnresolved, logger.Int("cycle", charge.Cycle),
+				logger.Int("attempt", charge.Attempt))
+			return nil, false
+		}
+	}
+
+	//the subscription update saves the outcome again, so that it is not lost if only this call fails
+	if err := s.store.UpdateSubscriptionChargeRecord(ctx, charge); err != nil {
+		log.Error(error_constant.SubscriptionChargeNotSaved, logger.String("auth_id", charge.AuthID), logger.Err(err))
+	}
+	return charge, true
+}
+
+//chargeSubscription authorises the subscription amount on the stored card through the existing services and captures
+//it, the outcome is recorded in the charge. An authorisation held for review is left uncaptured until it is reviewed
+func (s *subscriptionService) chargeSubscription(ctx context.Context, record *subscription.Subscription, card *auth.Auth, charge *subscription.Charge) {
+	authResponse, errInf := s.authorisationService.AuthoriseStoredCardTransaction(ctx, auth_domain.StoredCardAuthRequest{
+		MerchantID: record.MerchantID,
+		Number:     card.Number,
+		ExpiryDate: card.ExpiryDate,
+		Amount:     record.Amount,
+		Currency:   record.Currency,
+	})
+	if errInf != nil {
+		charge.State = subscription_domain.ChargeStateFailed
+		charge.Error = errInf.ErrorMessage()
+		return
+	}
+	charge.AuthID = authResponse.AuthID
+	if authResponse.Status == auth_domain.StatusPendingReview {
+		charge.State = subscription_domain.ChargeStatePendingReview
+		charge.Error = error_constant.AuthorisationPendingReview
+		return
+	}
+
+	s.captureCharge(ctx, record, charge)
+}
+
+//completeReviewedCharge captures a charge whose authorisation has been approved in review, or fails it once declined,
+//false is returned while the review is still pending
+func (s *subscriptionService) completeReviewedCharge(ctx context.Context, record *subscription.Subscription, charge *subscription.Charge) bool {
+	reviewRecord, err := s.store.GetReviewRecordByAuthID(ctx, charge.AuthID)
+	if err != nil {
+		s.logger.Ctx(ctx).Error(error_constant.ReviewRetrievalFailure, logger.String("auth_id", charge.AuthID), logger.Err(err))
+		return false
+	}
+
+	switch reviewRecord.State {
+	case review_domain.StateApproved:
+		s.captureCharge(ctx, record, charge)
+	case review_domain.StateDeclined:
+		//the authorisation has been voided with the review
+		charge.State = subscription_domain.ChargeStateFailed
+		charge.Error = error_constant.AuthorisationReviewDeclined
+	default:
+		return false
+	}
+	return true
+}
+
+//captureCharge captures the authorised subscription amount, an authorisation whose capture fails is voided so that
+//no amount stays held on the card
+func (s *subscriptionService) captureCharge(ctx context.Context, record *subscription.Subscription, charge *subscription.Charge) {
+	_, errInf := s.captureService.CaptureTransactionAmount(ctx, capture_domain.CaptureRequest{
+		AuthId: charge.AuthID,
+		Amount: record.Amount,
+	})
+	if errInf != nil {
+		charge.State = subscription_domain.ChargeStateFailed
+		charge.Error = errInf.ErrorMessage()
+		if _, voidErr := s.voidService.VoidTransaction(ctx, void_domain.VoidRequest{AuthId: charge.AuthID}); voidErr != nil {
+			s.logger.Ctx(ctx).Error("unable to void the authorisation of a failed charge", logger.String("auth_id", charge.AuthID),
+				logger.String("error", voidErr.ErrorMessage()))
+		}
+		return
+	}
+
+	charge.State = subscription_domain.ChargeStateSucceeded
+	charge.Error = ""
+	charge.IsSuccess = true
+}
+
+//applyChargeOutcome moves the subscription schedule forward after a successful charge, or schedules
+//the next retry after a decline until the configured retries are exhausted
+func (s *subscriptionService) applyChargeOutcome(record *subscription.Subscription, charge *subscription.Charge, now time.Time) {
+	record.UpdatedAt = now
+
+	if !charge.IsSuccess {
+		record.FailedAttempts++
+		if record.FailedAttempts > len(s.retryIntervals) {
+			record.State = subscription_domain.StateUnpaid
+			return
+		}
+		record.NextChargeAt = now.Add(s.retryIntervals[record.FailedAttempts-1])
+		return
+	}
+
+	record.FailedAttempts = 0
+	record.CompletedCycles++
+	record.CurrentCycle++
+	if record.MaxCycles > 0 && record.CompletedCycles >= record.MaxCycles {
+		record.State = subscription_domain.StateCompleted
+		return
+	}
+	record.NextChargeAt = subscription_domain.ChargeDate(record.AnchorDate, record.Interval, record.CurrentCycle)
+}
+
+//today returns the start of the day of the given time in UTC
+func today(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+//withoutCancel keeps the values of its parent context but is never done, a claimed charge attempt runs with it so
+//that it is not interrupted between charging the card and saving the outcome
+type withoutCancel struct {
+	context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (withoutCancel) Done() <-chan struct{} { return nil }
+
+func (withoutCancel) Err() error { return nil }
+
+func toResponse(record *subscription.Subscription) *subscription_domain.SubscriptionResponse {
+	return &subscription_domain.SubscriptionResponse{
+		SubscriptionID:  record.ID,
+		IsSuccess:       true,
+		State:           record.State,
+		Amount:          record.Amount,
+		Currency:        record.Currency,
+		Interval:        record.Interval,
+		NextChargeDate:  record.NextChargeAt.Format(format_constant.AnchorDateLayout),
+		CompletedCycles: record.CompletedCycles,
+		MaxCycles:       record.MaxCycles,
+	}
+}
@@ -0,0 +1,713 @@
+package subscription_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/clock"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/review"
+	"payment-gateway-api/api/data_access/database_model/subscription"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/capture_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/review_domain"
+	"payment-gateway-api/api/domain/subscription_domain"
+	"payment-gateway-api/api/domain/void_domain"
+	"payment-gateway-api/api/logger"
+	"testing"
+	"time"
+)
+
+var (
+	now            = time.Date(2020, time.January, 31, 9, 30, 0, 0, time.UTC)
+	subscriptionId = "fc958d27-8e8e-4825-b3ec-e5236a8e7d28"
+	storedAuthId   = "970c8844-9238-4c31-95ca-6f079dd65729"
+)
+
+type storeMock struct {
+	getAuthRecordByID              func(string) (bool, *auth.Auth, error)
+	getStoredCardRecord            func(string) (*auth.Auth, error)
+	getReviewRecordByAuthID        func(string) (*review.Review, error)
+	insertSubscriptionRecord       func(*subscription.Subscription) error
+	getSubscriptionRecordByID      func(string) (*subscription.Subscription, error)
+	getDueSubscriptionRecords      func(time.Time) ([]subscription.Subscription, error)
+	insertSubscriptionChargeRecord func(*subscription.Charge) error
+	getSubscriptionChargeRecord    func(string, int, int) (*subscription.Charge, error)
+	updateSubscriptionChargeRecord func(*subscription.Charge) error
+	updateSubscriptionRecord       func(*subscription.Subscription, *subscription.Charge) error
+}
+
+type authorisationServiceMock struct {
+	authoriseStoredCardTransaction func(auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface)
+}
+
+type captureServiceMock struct {
+	captureTransactionAmount func(capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface)
+}
+
+type voidServiceMock struct {
+	voidTransaction func(void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface)
+}
+
+func (s *storeMock) GetAuthRecordByID(ctx context.Context, id string) (bool, *auth.Auth, error) {
+	return s.getAuthRecordByID(id)
+}
+
+func (s *storeMock) GetStoredCardRecord(ctx context.Context, id string) (*auth.Auth, error) {
+	return s.getStoredCardRecord(id)
+}
+
+func (s *storeMock) GetReviewRecordByAuthID(ctx context.Context, id string) (*review.Review, error) {
+	return s.getReviewRecordByAuthID(id)
+}
+
+func (s *storeMock) InsertSubscriptionRecord(ctx context.Context, record *subscription.Subscription) error {
+	return s.insertSubscriptionRecord(record)
+}
+
+func (s *storeMock) GetSubscriptionRecordByID(ctx context.Context, id string) (*subscription.Subscription, error) {
+	return s.getSubscriptionRecordByID(id)
+}
+
+func (s *storeMock) GetDueSubscriptionRecords(ctx context.Context, dueAt time.Time) ([]subscription.Subscription, error) {
+	return s.getDueSubscriptionRecords(dueAt)
+}
+
+func (s *storeMock) InsertSubscriptionChargeRecord(ctx context.Context, charge *subscription.Charge) error {
+	return s.insertSubscriptionChargeRecord(charge)
+}
+
+func (s *storeMock) GetSubscriptionChargeRecord(ctx context.Context, id string, cycle int, attempt int) (*subscription.Charge, error) {
+	return s.getSubscriptionChargeRecord(id, cycle, attempt)
+}
+
+func (s *storeMock) UpdateSubscriptionChargeRecord(ctx context.Context, charge *subscription.Charge) error {
+	return s.updateSubscriptionChargeRecord(charge)
+}
+
+func (s *storeMock) UpdateSubscriptionRecord(ctx context.Context, record *subscription.Subscription, charge *subscription.Charge) error {
+	return s.updateSubscriptionRecord(record, charge)
+}
+
+func (a *authorisationServiceMock) AuthoriseTransaction(context.Context, auth_domain.AuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (a *authorisationServiceMock) CompleteAuthorisation(context.Context, string) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return nil, nil
+}
+
+func (a *authorisationServiceMock) AuthoriseStoredCardTransaction(ctx context.Context, request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+	return a.authoriseStoredCardTransaction(request)
+}
+
+func (c *captureServiceMock) CaptureTransactionAmount(ctx context.Context, request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+	return c.captureTransactionAmount(request)
+}
+
+func (v *voidServiceMock) VoidTransaction(ctx context.Context, request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+	return v.voidTransaction(request)
+}
+
+//mocks are the dependencies of a subscription service created for a single test
+type mocks struct {
+	store                *storeMock
+	authorisationService *authorisationServiceMock
+	captureService       *captureServiceMock
+	voidService          *voidServiceMock
+}
+
+func newService() (Service, *mocks) {
+	m := &mocks{
+		store:                &storeMock{},
+		authorisationService: &authorisationServiceMock{},
+		captureService:       &captureServiceMock{},
+		voidService:          &voidServiceMock{},
+	}
+	service := New(Dependencies{
+		Store:                m.store,
+		AuthorisationService: m.authorisationService,
+		CaptureService:       m.captureService,
+		VoidService:          m.voidService,
+		Clock:                clock.NewFake(now),
+		Logger:               logger.Discard(),
+		RetryIntervals:       []time.Duration{24 * time.Hour, 72 * time.Hour, 168 * time.Hour},
+	})
+	//the attempts have not been made yet and are recorded unless a test says otherwise
+	m.store.getStoredCardRecord = func(id string) (*auth.Auth, error) {
+		return &auth.Auth{ID: id, Number: "4929907390318794", ExpiryDate: "12-2020"}, nil
+	}
+	m.store.getSubscriptionChargeRecord = func(string, int, int) (*subscription.Charge, error) {
+		return nil, errors.New("record not found")
+	}
+	m.store.insertSubscriptionChargeRecord = func(*subscription.Charge) error { return nil }
+	m.store.updateSubscriptionChargeRecord = func(*subscription.Charge) error { return nil }
+	return service, m
+}
+
+func activeSubscription() subscription.Subscription {
+	anchor := time.Date(2019, time.December, 31, 0, 0, 0, 0, time.UTC)
+	return subscription.Subscription{
+		ID:           subscriptionId,
+		AuthID:       storedAuthId,
+		MerchantID:   "acme",
+		Amount:       10,
+		Currency:     "GBP",
+		Interval:     subscription_domain.IntervalMonthly,
+		AnchorDate:   anchor,
+		MaxCycles:    3,
+		CurrentCycle: 1,
+		NextChargeAt: time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC),
+		State:        subscription_domain.StateActive,
+	}
+}
+
+func TestSubscriptionService_CreateSubscription(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	request := subscription_domain.SubscriptionRequest{
+		AuthId:     "970c8844-9238-4c31-95ca-6f079dd65729",
+		Amount:     10,
+		Currency:   "GBP",
+		Interval:   subscription_domain.IntervalMonthly,
+		AnchorDate: "2020-01-31",
+		MaxCycles:  12,
+	}
+
+	mocks.store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+		return true, &auth.Auth{ID: id, MerchantID: "acme", Number: "4929907390318794", ExpiryDate: "12-2020"}, nil
+	}
+
+	var insertedRecord *subscription.Subscription
+	mocks.store.insertSubscriptionRecord = func(record *subscription.Subscription) error {
+		insertedRecord = record
+		return nil
+	}
+
+	actualResponse, err := service.CreateSubscription(context.Background(), request)
+	assert.Nil(t, err)
+	assert.EqualValues(t, true, actualResponse.IsSuccess)
+	assert.EqualValues(t, subscription_domain.StateActive, actualResponse.State)
+	assert.EqualValues(t, "2020-01-31", actualResponse.NextChargeDate)
+	assert.EqualValues(t, request.AuthId, insertedRecord.AuthID)
+	assert.EqualValues(t, "acme", insertedRecord.MerchantID)
+	assert.EqualValues(t, now, insertedRecord.CreatedAt)
+}
+
+func TestSubscriptionService_CreateSubscription_AnchorDateInThePast(t *testing.T) {
+	t.Parallel()
+	service, _ := newService()
+
+	request := subscription_domain.SubscriptionRequest{
+		AuthId:     "970c8844-9238-4c31-95ca-6f079dd65729",
+		Amount:     10,
+		Currency:   "GBP",
+		Interval:   subscription_domain.IntervalMonthly,
+		AnchorDate: "2020-01-30",
+	}
+
+	expectedErrors := []error{errors.New(error_constant.InvalidAnchorDate)}
+
+	actualResponse, err := service.CreateSubscription(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Status())
+	assert.EqualValues(t, fmt.Sprintf("%v", expectedErrors), err.ErrorMessage())
+}
+
+func TestSubscriptionService_CreateSubscription_AuthNotFound(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	request := subscription_domain.SubscriptionRequest{
+		AuthId:     "970c8844-9238-4c31-95ca-6f079dd65729",
+		Amount:     10,
+		Currency:   "GBP",
+		Interval:   subscription_domain.IntervalWeekly,
+		AnchorDate: "2020-02-01",
+	}
+
+	mocks.store.getAuthRecordByID = func(id string) (bool, *auth.Auth, error) {
+		return false, nil, errors.New("record not found")
+	}
+
+	actualResponse, err := service.CreateSubscription(context.Background(), request)
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+}
+
+func TestSubscriptionService_PauseSubscription_InvalidState(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getSubscriptionRecordByID = func(id string) (*subscription.Subscription, error) {
+		record := activeSubscription()
+		record.State = subscription_domain.StateCancelled
+		return &record, nil
+	}
+
+	expectedErrors := []error{errors.New(error_constant.SubscriptionStateInvalid)}
+
+	actualResponse, err := service.PauseSubscription(context.Background(), subscription_domain.SubscriptionStateRequest{SubscriptionId: subscriptionId})
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, fmt.Sprintf("%v", expectedErrors), err.ErrorMessage())
+}
+
+func TestSubscriptionService_ResumeSubscription_SkipsMissedCycles(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getSubscriptionRecordByID = func(id string) (*subscription.Subscription, error) {
+		record := activeSubscription()
+		record.State = subscription_domain.StatePaused
+		record.CurrentCycle = 0
+		record.NextChargeAt = record.AnchorDate
+		return &record, nil
+	}
+
+	var updatedRecord *subscription.Subscription
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedRecord = record
+		assert.Nil(t, charge)
+		return nil
+	}
+
+	actualResponse, err := service.ResumeSubscription(context.Background(), subscription_domain.SubscriptionStateRequest{SubscriptionId: subscriptionId})
+	assert.Nil(t, err)
+	assert.EqualValues(t, subscription_domain.StateActive, actualResponse.State)
+	assert.EqualValues(t, "2020-01-31", actualResponse.NextChargeDate)
+	assert.EqualValues(t, 1, updatedRecord.CurrentCycle)
+}
+
+func TestSubscriptionService_CancelSubscription_NotFound(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getSubscriptionRecordByID = func(id string) (*subscription.Subscription, error) {
+		return nil, errors.New("record not found")
+	}
+
+	actualResponse, err := service.CancelSubscription(context.Background(), subscription_domain.SubscriptionStateRequest{SubscriptionId: subscriptionId})
+	assert.Nil(t, actualResponse)
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		assert.EqualValues(t, now, dueAt)
+		return []subscription.Subscription{activeSubscription()}, nil
+	}
+	mocks.store.getStoredCardRecord = func(id string) (*auth.Auth, error) {
+		assert.EqualValues(t, storedAuthId, id)
+		return &auth.Auth{ID: id, Number: "4929907390318794", ExpiryDate: "12-2020"}, nil
+	}
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "4929907390318794", request.Number)
+		assert.EqualValues(t, "acme", request.MerchantID)
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+	mocks.captureService.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		assert.EqualValues(t, "new_auth_id", request.AuthId)
+		return &capture_domain.CaptureResponse{IsSuccess: true}, nil
+	}
+	var claimedState, savedState string
+	mocks.store.insertSubscriptionChargeRecord = func(charge *subscription.Charge) error {
+		claimedState = charge.State
+		return nil
+	}
+	mocks.store.updateSubscriptionChargeRecord = func(charge *subscription.Charge) error {
+		savedState = charge.State
+		return nil
+	}
+
+	var updatedRecord *subscription.Subscription
+	var recordedCharge *subscription.Charge
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedRecord = record
+		recordedCharge = charge
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, subscription_domain.ChargeStateClaimed, claimedState)
+	assert.EqualValues(t, subscription_domain.ChargeStateSucceeded, savedState)
+	assert.EqualValues(t, true, recordedCharge.IsSuccess)
+	assert.EqualValues(t, "new_auth_id", recordedCharge.AuthID)
+	assert.EqualValues(t, 1, recordedCharge.Cycle)
+	assert.EqualValues(t, 1, updatedRecord.CompletedCycles)
+	assert.EqualValues(t, 2, updatedRecord.CurrentCycle)
+	assert.EqualValues(t, time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC), updatedRecord.NextChargeAt)
+	assert.EqualValues(t, subscription_domain.StateActive, updatedRecord.State)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_LastCycleCompletes(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		record := activeSubscription()
+		record.CompletedCycles = 2
+		return []subscription.Subscription{record}, nil
+	}
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+	mocks.captureService.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		return &capture_domain.CaptureResponse{IsSuccess: true}, nil
+	}
+
+	var updatedRecord *subscription.Subscription
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedRecord = record
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, updatedRecord.CompletedCycles)
+	assert.EqualValues(t, subscription_domain.StateCompleted, updatedRecord.State)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_DeclineIsRetried(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription()}, nil
+	}
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.AuthorisationFailure))
+	}
+
+	var updatedRecord *subscription.Subscription
+	var recordedCharge *subscription.Charge
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedRecord = record
+		recordedCharge = charge
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, recordedCharge.IsSuccess)
+	assert.EqualValues(t, 1, recordedCharge.Attempt)
+	assert.EqualValues(t, 1, updatedRecord.FailedAttempts)
+	assert.EqualValues(t, 1, updatedRecord.CurrentCycle)
+	assert.EqualValues(t, now.Add(24*time.Hour), updatedRecord.NextChargeAt)
+	assert.EqualValues(t, subscription_domain.StateActive, updatedRecord.State)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_RetriesExhausted(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		record := activeSubscription()
+		record.FailedAttempts = 3
+		return []subscription.Subscription{record}, nil
+	}
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+	mocks.captureService.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		return nil, error_domain.New(http.StatusUnauthorized, errors.New(error_constant.CaptureFailure))
+	}
+	isVoided := false
+	mocks.voidService.voidTransaction = func(request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+		isVoided = request.AuthId == "new_auth_id"
+		return &void_domain.VoidResponse{IsSuccess: true}, nil
+	}
+
+	var updatedRecord *subscription.Subscription
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedRecord = record
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, true, isVoided)
+	assert.EqualValues(t, 4, updatedRecord.FailedAttempts)
+	assert.EqualValues(t, subscription_domain.StateUnpaid, updatedRecord.State)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_AttemptAlreadyRecorded(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription()}, nil
+	}
+	mocks.store.insertSubscriptionChargeRecord = func(charge *subscription.Charge) error {
+		assert.EqualValues(t, 1, charge.Cycle)
+		assert.EqualValues(t, 1, charge.Attempt)
+		return errors.New("UNIQUE constraint failed: subscription_charges.subscription_id, subscription_charges.cycle, subscription_charges.attempt")
+	}
+	isAuthorised := false
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		isAuthorised = true
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+	isUpdated := false
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		isUpdated = true
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, isAuthorised)
+	assert.EqualValues(t, false, isUpdated)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_UpdateFailureDoesNotStopTheRun(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	second := activeSubscription()
+	second.ID = "0b7e1d52-3f8a-4c6e-9a41-2d5f7c8e9b10"
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription(), second}, nil
+	}
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+	mocks.captureService.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		return &capture_domain.CaptureResponse{IsSuccess: true}, nil
+	}
+
+	var updatedIDs []string
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedIDs = append(updatedIDs, record.ID)
+		if record.ID == subscriptionId {
+			return errors.New("database is locked")
+		}
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{subscriptionId, second.ID}, updatedIDs)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_RecordedOutcomeIsApplied(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription()}, nil
+	}
+	//an earlier run charged the card but could not update the subscription
+	mocks.store.getSubscriptionChargeRecord = func(id string, cycle int, attempt int) (*subscription.Charge, error) {
+		assert.EqualValues(t, subscriptionId, id)
+		assert.EqualValues(t, 1, cycle)
+		assert.EqualValues(t, 1, attempt)
+		return &subscription.Charge{SubscriptionID: id, Cycle: cycle, Attempt: attempt, AuthID: "new_auth_id",
+			State: subscription_domain.ChargeStateSucceeded, IsSuccess: true}, nil
+	}
+	isAuthorised := false
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		isAuthorised = true
+		return &auth_domain.AuthResponse{AuthID: "other_auth_id", IsSuccess: true}, nil
+	}
+
+	var updatedRecord *subscription.Subscription
+	var recordedCharge *subscription.Charge
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedRecord = record
+		recordedCharge = charge
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, isAuthorised)
+	assert.EqualValues(t, "new_auth_id", recordedCharge.AuthID)
+	assert.EqualValues(t, 1, updatedRecord.CompletedCycles)
+	assert.EqualValues(t, 2, updatedRecord.CurrentCycle)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_ClaimWithoutOutcomeIsLeft(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription()}, nil
+	}
+	mocks.store.getSubscriptionChargeRecord = func(id string, cycle int, attempt int) (*subscription.Charge, error) {
+		return &subscription.Charge{SubscriptionID: id, Cycle: cycle, Attempt: attempt, State: subscription_domain.ChargeStateClaimed}, nil
+	}
+	isAuthorised := false
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		isAuthorised = true
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+	isUpdated := false
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		isUpdated = true
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, isAuthorised)
+	assert.EqualValues(t, false, isUpdated)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_PendingReviewWaits(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription()}, nil
+	}
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		return &auth_domain.AuthResponse{AuthID: "held_auth_id", IsSuccess: true, Status: auth_domain.StatusPendingReview}, nil
+	}
+	isCaptured := false
+	mocks.captureService.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		isCaptured = true
+		return &capture_domain.CaptureResponse{IsSuccess: true}, nil
+	}
+	var savedCharge *subscription.Charge
+	mocks.store.updateSubscriptionChargeRecord = func(charge *subscription.Charge) error {
+		savedCharge = charge
+		return nil
+	}
+	isUpdated := false
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		isUpdated = true
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, isCaptured)
+	assert.EqualValues(t, subscription_domain.ChargeStatePendingReview, savedCharge.State)
+	assert.EqualValues(t, "held_auth_id", savedCharge.AuthID)
+	//the subscription stays due with the same attempt until the review is completed
+	assert.EqualValues(t, false, isUpdated)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_ReviewedCharges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		reviewState    string
+		isCaptured     bool
+		isUpdated      bool
+		completed      int
+		failedAttempts int
+	}{
+		{name: "still pending", reviewState: review_domain.StatePending},
+		{name: "approved", reviewState: review_domain.StateApproved, isCaptured: true, isUpdated: true, completed: 1},
+		{name: "declined", reviewState: review_domain.StateDeclined, isUpdated: true, failedAttempts: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service, mocks := newService()
+
+			mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+				return []subscription.Subscription{activeSubscription()}, nil
+			}
+			mocks.store.getSubscriptionChargeRecord = func(id string, cycle int, attempt int) (*subscription.Charge, error) {
+				return &subscription.Charge{SubscriptionID: id, Cycle: cycle, Attempt: attempt, AuthID: "held_auth_id",
+					State: subscription_domain.ChargeStatePendingReview, Error: error_constant.AuthorisationPendingReview}, nil
+			}
+			mocks.store.getReviewRecordByAuthID = func(id string) (*review.Review, error) {
+				assert.EqualValues(t, "held_auth_id", id)
+				return &review.Review{AuthID: id, State: test.reviewState}, nil
+			}
+			isAuthorised := false
+			mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+				isAuthorised = true
+				return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+			}
+			isCaptured := false
+			mocks.captureService.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+				isCaptured = request.AuthId == "held_auth_id"
+				return &capture_domain.CaptureResponse{IsSuccess: true}, nil
+			}
+			var updatedRecord *subscription.Subscription
+			mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+				updatedRecord = record
+				return nil
+			}
+
+			err := service.ChargeDueSubscriptions(context.Background())
+			assert.Nil(t, err)
+			assert.EqualValues(t, false, isAuthorised)
+			assert.EqualValues(t, test.isCaptured, isCaptured)
+			assert.EqualValues(t, test.isUpdated, updatedRecord != nil)
+			if updatedRecord != nil {
+				assert.EqualValues(t, test.completed, updatedRecord.CompletedCycles)
+				assert.EqualValues(t, test.failedAttempts, updatedRecord.FailedAttempts)
+			}
+		})
+	}
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_CancelledRunStops(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription()}, nil
+	}
+	isAuthorised := false
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		isAuthorised = true
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := service.ChargeDueSubscriptions(ctx)
+	assert.EqualValues(t, context.Canceled, err)
+	assert.EqualValues(t, false, isAuthorised)
+}
+
+func TestSubscriptionService_ChargeDueSubscriptions_CancelledRunCompletesTheCharge(t *testing.T) {
+	t.Parallel()
+	service, mocks := newService()
+
+	second := activeSubscription()
+	second.ID = "0b7e1d52-3f8a-4c6e-9a41-2d5f7c8e9b10"
+	mocks.store.getDueSubscriptionRecords = func(dueAt time.Time) ([]subscription.Subscription, error) {
+		return []subscription.Subscription{activeSubscription(), second}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	//the run is cancelled while the first subscription is being charged
+	mocks.authorisationService.authoriseStoredCardTransaction = func(request auth_domain.StoredCardAuthRequest) (*auth_domain.AuthResponse, error_domain.GatewayErrorInterface) {
+		cancel()
+		return &auth_domain.AuthResponse{AuthID: "new_auth_id", IsSuccess: true}, nil
+	}
+	mocks.captureService.captureTransactionAmount = func(request capture_domain.CaptureRequest) (*capture_domain.CaptureResponse, error_domain.GatewayErrorInterface) {
+		return &capture_domain.CaptureResponse{IsSuccess: true}, nil
+	}
+
+	var updatedIDs []string
+	mocks.store.updateSubscriptionRecord = func(record *subscription.Subscription, charge *subscription.Charge) error {
+		updatedIDs = append(updatedIDs, record.ID)
+		assert.EqualValues(t, true, charge.IsSuccess)
+		return nil
+	}
+
+	err := service.ChargeDueSubscriptions(ctx)
+	assert.EqualValues(t, context.Canceled, err)
+	assert.EqualValues(t, []string{subscriptionId}, updatedIDs)
+}
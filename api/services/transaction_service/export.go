@@ -0,0 +1,273 @@
+package transaction_service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/transaction"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var totalsHeader = []string{"currency", "operations", "authorised_amount", "captured_amount", "refunded_amount",
+	"charged_back_amount", "voided_amount", "fees", "net_amount"}
+
+//exportTotal is what the operations of an export add up to in a currency, the net amount is what the merchants keep
+type exportTotal struct {
+	Currency          string  `json:"currency"`
+	Operations        int     `json:"operations"`
+	AuthorisedAmount  float64 `json:"authorised_amount"`
+	CapturedAmount    float64 `json:"captured_amount"`
+	RefundedAmount    float64 `json:"refunded_amount"`
+	ChargedBackAmount float64 `json:"charged_back_amount"`
+	VoidedAmount      float64 `json:"voided_amount"`
+	Fees              float64 `json:"fees"`
+	NetAmount         float64 `json:"net_amount"`
+}
+
+//exportEncoder writes the rows of an export and then its totals in one of the export formats
+type exportEncoder interface {
+	header(columns []string) error
+	row(columns []string, values []interface{}) error
+	totals(totals []exportTotal) error
+	flush() error
+}
+
+//ExportTransactions checks the export request and returns the export of the operations of its period, with the
+//card numbers masked. Nothing is read until the export is written
+func (t *transactionService) ExportTransactions(ctx context.Context, request transaction_domain.ExportRequest) (_ *transaction_domain.ExportFile, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	from, to, _ := request.Range()
+	columns := request.ColumnList()
+	file := &transaction_domain.ExportFile{
+		Name:        "transactions-" + from.UTC().Format("20060102") + "-" + to.UTC().Format("20060102") + "." + request.Format,
+		ContentType: "text/csv",
+	}
+	if request.Format == transaction_domain.ExportJSONL {
+		file.ContentType = "application/x-ndjson"
+	}
+	file.Write = func(w io.Writer) error {
+		var encoder exportEncoder = &csvEncoder{writer: csv.NewWriter(w)}
+		if request.Format == transaction_domain.ExportJSONL {
+			buffered := bufio.NewWriter(w)
+			encoder = &jsonlEncoder{writer: buffered, encoder: json.NewEncoder(buffered)}
+		}
+		if err := t.writeExport(ctx, from, to, columns, encoder); err != nil {
+			t.logger.Ctx(ctx).Error(error_constant.ExportFailure, logger.Err(err))
+			return errors.New(error_constant.ExportFailure)
+		}
+		return nil
+	}
+	return file, nil
+}
+
+//writeExport writes the operations of the period one at a time as they are read and then their totals by currency
+func (t *transactionService) writeExport(ctx context.Context, from time.Time, to time.Time, columns []string, encoder exportEncoder) error {
+	if err := encoder.header(columns); err != nil {
+		return err
+	}
+	totals := make(map[string]*exportTotal)
+	err := t.store.ExportEntries(ctx, from, to, func(entry *transaction.Entry) error {
+		total, ok := totals[entry.Currency]
+		if !ok {
+			total = &exportTotal{Currency: entry.Currency}
+			totals[entry.Currency] = total
+		}
+		total.add(entry)
+
+		values := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, exportValue(entry, column))
+		}
+		return encoder.row(columns, values)
+	})
+	if err != nil {
+		return err
+	}
+
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	sorted := make([]exportTotal, 0, len(currencies))
+	for _, currency := range currencies {
+		total := totals[currency]
+		total.NetAmount = total.CapturedAmount - total.RefundedAmount - total.ChargedBackAmount - total.Fees
+		sorted = append(sorted, total.rounded())
+	}
+	if err := encoder.totals(sorted); err != nil {
+		return err
+	}
+	return encoder.flush()
+}
+
+func (e *exportTotal) add(entry *transaction.Entry) {
+	e.Operations++
+	amount := float64(entry.ProcessedAmount)
+	switch entry.Name {
+	case "authorisation":
+		e.AuthorisedAmount += amount
+	case "capture":
+		e.CapturedAmount += amount
+	case "refund":
+		e.RefundedAmount += amount
+	case "chargeback":
+		e.ChargedBackAmount += amount
+	case "void":
+		e.VoidedAmount += amount
+	}
+	e.Fees += float64(entry.Fee)
+}
+
+//rounded returns the total with its amounts rounded to hundredths, the sums of float amounts drift otherwise
+func (e exportTotal) rounded() exportTotal {
+	for _, amount := range []*float64{&e.AuthorisedAmount, &e.CapturedAmount, &e.RefundedAmount, &e.ChargedBackAmount,
+		&e.VoidedAmount, &e.Fees, &e.NetAmount} {
+		*amount = roundAmount(*amount)
+	}
+	return e
+}
+
+//exportValue returns the value of a column of an export, the amounts are numbers and everything else is text
+func exportValue(entry *transaction.Entry, column string) interface{} {
+	switch column {
+	case "transaction_id":
+		return entry.AuthID
+	case "merchant_id":
+		return entry.MerchantID
+	case "state":
+		return entry.State
+	case "card_number":
+		return maskCardNumber(entry)
+	case "card_brand":
+		return auth_domain.CardBrand(entry.CardBIN)
+	case "authorised_amount":
+		return roundAmount(float64(entry.AuthorisedAmount))
+	case "currency":
+		return entry.Currency
+	case "authorised_at":
+		return entry.AuthCreatedAt.UTC().Format(format_constant.TimestampLayout)
+	case "operation":
+		return entry.Name
+	case "amount":
+		return roundAmount(float64(entry.ProcessedAmount))
+	case "fee":
+		return roundAmount(float64(entry.Fee))
+	default:
+		return entry.CreatedAt.UTC().Format(format_constant.TimestampLayout)
+	}
+}
+
+//maskCardNumber hides every digit of the card number but its first six and last four
+func maskCardNumber(entry *transaction.Entry) string {
+	hidden := entry.CardLength - len(entry.CardBIN) - len(entry.CardLast4)
+	if hidden < 0 {
+		hidden = 0
+	}
+	return entry.CardBIN + strings.Repeat("*", hidden) + entry.CardLast4
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+//csvEncoder writes the exports as a header line and a line for every operation, followed by an empty line and the
+//totals with their own header line
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (c *csvEncoder) header(columns []string) error {
+	return c.writer.Write(columns)
+}
+
+func (c *csvEncoder) row(columns []string, values []interface{}) error {
+	record := make([]string, 0, len(values))
+	for _, value := range values {
+		if amount, ok := value.(float64); ok {
+			record = append(record, formatAmount(amount))
+			continue
+		}
+		record = append(record, value.(string))
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvEncoder) totals(totals []exportTotal) error {
+	if err := c.writer.Write(nil); err != nil {
+		return err
+	}
+	if err := c.writer.Write(totalsHeader); err != nil {
+		return err
+	}
+	for _, total := range totals {
+		err := c.writer.Write([]string{total.Currency, strconv.Itoa(total.Operations), formatAmount(total.AuthorisedAmount),
+			formatAmount(total.CapturedAmount), formatAmount(total.RefundedAmount), formatAmount(total.ChargedBackAmount),
+			formatAmount(total.VoidedAmount), formatAmount(total.Fees), formatAmount(total.NetAmount)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvEncoder) flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+//jsonlEncoder writes the exports as a json object for every operation, keyed by column, followed by an object for
+//every currency holding its totals under the total key
+type jsonlEncoder struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (j *jsonlEncoder) header(columns []string) error {
+	return nil
+}
+
+func (j *jsonlEncoder) row(columns []string, values []interface{}) error {
+	record := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		record[column] = values[i]
+	}
+	return j.encoder.Encode(record)
+}
+
+func (j *jsonlEncoder) totals(totals []exportTotal) error {
+	for i := range totals {
+		if err := j.encoder.Encode(map[string]exportTotal{"total": totals[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *jsonlEncoder) flush() error {
+	return j.writer.Flush()
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
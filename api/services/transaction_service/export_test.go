@@ -0,0 +1,143 @@
+package transaction_service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/transaction"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+//failingWriter fails every write, as when the client of an export goes away
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func exportEntries() []transaction.Entry {
+	entry := func(id string, currency string, name string, amount float32, fee float32, at time.Time) transaction.Entry {
+		return transaction.Entry{
+			AuthID:           id,
+			MerchantID:       "acme",
+			CardBIN:          "492990",
+			CardLast4:        "8794",
+			CardLength:       16,
+			AuthorisedAmount: 100,
+			Currency:         currency,
+			AuthCreatedAt:    now,
+			State:            transaction_domain.StateRefunded,
+			Name:             name,
+			ProcessedAmount:  amount,
+			Fee:              fee,
+			CreatedAt:        at,
+		}
+	}
+	return []transaction.Entry{
+		entry(authID, "GBP", "authorisation", 100, 0, now),
+		entry("6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12", "EUR", "authorisation", 30, 0, now),
+		entry(authID, "GBP", "capture", 60.1, 1.2, now.Add(time.Hour)),
+		entry(authID, "GBP", "refund", 10.2, -0.2, now.Add(2*time.Hour)),
+		entry("6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12", "EUR", "void", 30, 0, now.Add(3*time.Hour)),
+	}
+}
+
+func TestTransactionService_ExportTransactions_CSV(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{entries: exportEntries()}
+	request := transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z",
+		Columns: "transaction_id, card_number,card_brand,operation,amount,fee,created_at"}
+
+	file, errInf := newService(store).ExportTransactions(context.Background(), request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, "transactions-20200601-20200701.csv", file.Name)
+	assert.EqualValues(t, "text/csv", file.ContentType)
+	//nothing is read until the export is written
+	assert.Empty(t, store.periods)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, file.Write(&buffer))
+	expected := "transaction_id,card_number,card_brand,operation,amount,fee,created_at\n" +
+		authID + ",492990******8794,visa,authorisation,100.00,0.00,2020-06-15T12:00:00Z\n" +
+		"6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12,492990******8794,visa,authorisation,30.00,0.00,2020-06-15T12:00:00Z\n" +
+		authID + ",492990******8794,visa,capture,60.10,1.20,2020-06-15T13:00:00Z\n" +
+		authID + ",492990******8794,visa,refund,10.20,-0.20,2020-06-15T14:00:00Z\n" +
+		"6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12,492990******8794,visa,void,30.00,0.00,2020-06-15T15:00:00Z\n" +
+		"\n" +
+		"currency,operations,authorised_amount,captured_amount,refunded_amount,charged_back_amount,voided_amount,fees,net_amount\n" +
+		"EUR,2,30.00,0.00,0.00,0.00,30.00,0.00,0.00\n" +
+		"GBP,3,100.00,60.10,10.20,0.00,0.00,1.00,48.90\n"
+	assert.EqualValues(t, expected, buffer.String())
+
+	from, to := store.periods[0][0], store.periods[0][1]
+	assert.EqualValues(t, time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.EqualValues(t, time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC), to)
+}
+
+func TestTransactionService_ExportTransactions_JSONL(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{entries: exportEntries()[:1]}
+	request := transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z", Format: "JSONL"}
+
+	file, errInf := newService(store).ExportTransactions(context.Background(), request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, "transactions-20200601-20200701.jsonl", file.Name)
+	assert.EqualValues(t, "application/x-ndjson", file.ContentType)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, file.Write(&buffer))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.EqualValues(t, 2, len(lines))
+	assert.EqualValues(t, `{"amount":100,"authorised_amount":100,"authorised_at":"2020-06-15T12:00:00Z","card_brand":"visa",`+
+		`"card_number":"492990******8794","created_at":"2020-06-15T12:00:00Z","currency":"GBP","fee":0,"merchant_id":"acme",`+
+		`"operation":"authorisation","state":"refunded","transaction_id":"`+authID+`"}`, lines[0])
+	assert.EqualValues(t, `{"total":{"currency":"GBP","operations":1,"authorised_amount":100,"captured_amount":0,`+
+		`"refunded_amount":0,"charged_back_amount":0,"voided_amount":0,"fees":0,"net_amount":0}}`, lines[1])
+	assert.NotContains(t, buffer.String(), "4929907390318794")
+}
+
+func TestTransactionService_ExportTransactions_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		request       transaction_domain.ExportRequest
+		expectedError string
+	}{
+		{"missing period", transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z"}, error_constant.ExportRangeRequired},
+		{"invalid period", transaction_domain.ExportRequest{From: "2020-07-01T00:00:00Z", To: "2020-06-01T00:00:00Z"}, error_constant.InvalidDateRange},
+		{"invalid format", transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z", Format: "xlsx"}, error_constant.InvalidExportFormat},
+		{"unknown column", transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z", Columns: "transaction_id,cvv"}, error_constant.InvalidExportColumns},
+		{"repeated column", transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z", Columns: "fee,fee"}, error_constant.InvalidExportColumns},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			file, errInf := newService(&storeMock{}).ExportTransactions(context.Background(), tt.request)
+			assert.Nil(t, file)
+			assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
+
+func TestTransactionService_ExportTransactions_WriteFailure(t *testing.T) {
+	t.Parallel()
+	request := transaction_domain.ExportRequest{From: "2020-06-01T00:00:00Z", To: "2020-07-01T00:00:00Z", Format: "jsonl"}
+
+	file, errInf := newService(&storeMock{entries: exportEntries(), err: errors.New("disk I/O error")}).ExportTransactions(context.Background(), request)
+	assert.Nil(t, errInf)
+	err := file.Write(&bytes.Buffer{})
+	assert.EqualValues(t, error_constant.ExportFailure, err.Error())
+
+	file, errInf = newService(&storeMock{entries: exportEntries()}).ExportTransactions(context.Background(), request)
+	assert.Nil(t, errInf)
+	err = file.Write(failingWriter{})
+	assert.EqualValues(t, error_constant.ExportFailure, err.Error())
+}
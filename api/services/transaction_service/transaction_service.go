@@ -0,0 +1,215 @@
+package transaction_service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/const/format_constant"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/transaction"
+	"payment-gateway-api/api/domain/auth_domain"
+	"payment-gateway-api/api/domain/common_validation"
+	"payment-gateway-api/api/domain/error_domain"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"time"
+)
+
+//Store is the persistence the transactions are searched in
+type Store interface {
+	SearchTransactions(context.Context, transaction.Filter) ([]transaction.Transaction, error)
+	GetTransactionByID(context.Context, string) (*transaction.Transaction, []operation.Operation, error)
+	ExportEntries(context.Context, time.Time, time.Time, func(*transaction.Entry) error) error
+}
+
+//Dependencies are the collaborators of the transaction service
+type Dependencies struct {
+	Store  Store
+	Logger *logger.Logger
+}
+
+type transactionService struct {
+	store  Store
+	logger *logger.Logger
+}
+
+//Service lets the merchants and the support teams look up the transactions processed by the gateway
+type Service interface {
+	SearchTransactions(context.Context, transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface)
+	SearchAllTransactions(context.Context, transaction_domain.SearchRequest) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface)
+	GetTransaction(context.Context, string) (*transaction_domain.TransactionResponse, error_domain.GatewayErrorInterface)
+	ExportTransactions(context.Context, transaction_domain.ExportRequest) (*transaction_domain.ExportFile, error_domain.GatewayErrorInterface)
+}
+
+//cursor is the position the next page of a search starts after, along with the order it was taken in so that it
+//cannot be used with another one
+type cursor struct {
+	Sort      string    `json:"s"`
+	Order     string    `json:"o"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"t"`
+	Amount    float32   `json:"a"`
+}
+
+//New creates the transaction service from its dependencies
+func New(deps Dependencies) Service {
+	return &transactionService{
+		store:  deps.Store,
+		logger: deps.Logger,
+	}
+}
+
+//SearchTransactions returns a page of the transactions of the calling merchant matching the filters of the request,
+//the merchant filter is replaced by the calling merchant
+func (t *transactionService) SearchTransactions(ctx context.Context, request transaction_domain.SearchRequest) (_ *transaction_domain.SearchResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	request.MerchantID = merchant.FromContext(ctx)
+	return t.search(ctx, request, true)
+}
+
+//SearchAllTransactions returns a page of the transactions of every merchant matching the filters of the request,
+//for the support teams
+func (t *transactionService) SearchAllTransactions(ctx context.Context, request transaction_domain.SearchRequest) (_ *transaction_domain.SearchResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	return t.search(ctx, request, false)
+}
+
+//search returns a page of the transactions matching the filters of the request. The page after it is fetched by
+//sending the same request with the returned cursor, the order stays stable as new transactions come in
+func (t *transactionService) search(ctx context.Context, request transaction_domain.SearchRequest, scoped bool) (*transaction_domain.SearchResponse, error_domain.GatewayErrorInterface) {
+
+	errs := request.ValidateFields()
+	if len(errs) > 0 {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
+	}
+
+	from, to := request.Range()
+	filter := transaction.Filter{
+		From:       from,
+		To:         to,
+		State:      request.State,
+		Currency:   request.Currency,
+		MinAmount:  request.MinAmount,
+		MaxAmount:  request.MaxAmount,
+		CardBIN:    request.Bin,
+		CardLast4:  request.Last4,
+		MerchantID: request.MerchantID,
+		Scoped:     scoped,
+		Operation:  request.Operation,
+		Sort:       request.Sort,
+		Descending: request.Order == "desc",
+		//one more transaction than the limit is fetched to know whether there is a next page
+		Limit: request.Limit + 1,
+	}
+	if request.Cursor != "" {
+		after, err := decodeCursor(request.Cursor)
+		if err != nil || after.Sort != request.Sort || after.Order != request.Order {
+			return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidCursor))
+		}
+		filter.After = transaction.Cursor{ID: after.ID, CreatedAt: after.CreatedAt, Amount: after.Amount}
+	}
+
+	records, err := t.store.SearchTransactions(ctx, filter)
+	if err != nil {
+		t.logger.Ctx(ctx).Error(error_constant.TransactionRetrievalFailure, logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.TransactionRetrievalFailure))
+	}
+
+	response := &transaction_domain.SearchResponse{Transactions: make([]transaction_domain.TransactionResponse, 0, len(records))}
+	if len(records) > request.Limit {
+		records = records[:request.Limit]
+		last := records[len(records)-1]
+		response.NextCursor = encodeCursor(cursor{
+			Sort:      request.Sort,
+			Order:     request.Order,
+			ID:        last.ID,
+			CreatedAt: last.CreatedAt,
+			Amount:    last.AuthorisedAmount,
+		})
+	}
+	for i := range records {
+		response.Transactions = append(response.Transactions, *toResponse(&records[i], nil))
+	}
+	return response, nil
+}
+
+//GetTransaction returns a transaction of the calling merchant with the operations made on it, the transactions of the
+//other merchants are not found
+func (t *transactionService) GetTransaction(ctx context.Context, id string) (_ *transaction_domain.TransactionResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() { errInf = error_domain.FromContext(ctx, errInf) }()
+
+	if !common_validation.IsValidUUID(id) {
+		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.InvalidAuthIdField))
+	}
+
+	record, operations, err := t.store.GetTransactionByID(ctx, id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
+		}
+		t.logger.Ctx(ctx).Error(error_constant.TransactionRetrievalFailure, logger.String("auth_id", id), logger.Err(err))
+		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.TransactionRetrievalFailure))
+	}
+	if record.MerchantID != merchant.FromContext(ctx) {
+		return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
+	}
+	return toResponse(record, operations), nil
+}
+
+//toResponse converts a transaction and its operations into the format returned by the transaction endpoints,
+//only the last digits of the card number are returned
+func toResponse(record *transaction.Transaction, operations []operation.Operation) *transaction_domain.TransactionResponse {
+	response := &transaction_domain.TransactionResponse{
+		ID:                record.ID,
+		MerchantID:        record.MerchantID,
+		State:             record.State,
+		CardBin:           record.CardBIN,
+		CardLast4:         record.CardLast4,
+		CardBrand:         auth_domain.CardBrand(record.Number),
+		Amount:            record.AuthorisedAmount,
+		AvailableAmount:   record.AvailableAmount,
+		CapturedAmount:    record.CapturedAmount,
+		RefundedAmount:    record.RefundedAmount,
+		ChargedBackAmount: record.ChargedBackAmount,
+		Fee:               record.Fees,
+		NetAmount:         record.CapturedAmount - record.RefundedAmount - record.ChargedBackAmount - record.Fees,
+		Currency:          record.Currency,
+		CreatedAt:         record.CreatedAt.UTC().Format(format_constant.TimestampLayout),
+	}
+	for i := range operations {
+		response.Operations = append(response.Operations, transaction_domain.OperationResponse{
+			Name:      operations[i].Name,
+			Amount:    operations[i].ProcessedAmount,
+			Fee:       operations[i].Fee,
+			CreatedAt: operations[i].CreatedAt.UTC().Format(format_constant.TimestampLayout),
+		})
+	}
+	return response
+}
+
+//encodeCursor and decodeCursor turn the position of a search into the opaque string handed to the clients and back
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	if c.ID == "" {
+		return nil, errors.New("cursor has no id")
+	}
+	return &c, nil
+}
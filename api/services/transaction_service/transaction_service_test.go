@@ -0,0 +1,224 @@
+package transaction_service
+
+import (
+	"context"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"payment-gateway-api/api/const/error_constant"
+	"payment-gateway-api/api/data_access/database_model/auth"
+	"payment-gateway-api/api/data_access/database_model/operation"
+	"payment-gateway-api/api/data_access/database_model/transaction"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/merchant"
+	"testing"
+	"time"
+)
+
+var (
+	now    = time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+	authID = "5b3e1d2a-8c4f-4e2b-9a1d-3f6c7b8e9d01"
+)
+
+type storeMock struct {
+	records    []transaction.Transaction
+	operations []operation.Operation
+	entries    []transaction.Entry
+	err        error
+	filters    []transaction.Filter
+	periods    [][]time.Time
+}
+
+func (s *storeMock) SearchTransactions(ctx context.Context, filter transaction.Filter) ([]transaction.Transaction, error) {
+	s.filters = append(s.filters, filter)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.records) > filter.Limit {
+		return s.records[:filter.Limit], nil
+	}
+	return s.records, nil
+}
+
+func (s *storeMock) GetTransactionByID(ctx context.Context, id string) (*transaction.Transaction, []operation.Operation, error) {
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	for i := range s.records {
+		if s.records[i].ID == id {
+			return &s.records[i], s.operations, nil
+		}
+	}
+	return nil, nil, gorm.ErrRecordNotFound
+}
+
+func (s *storeMock) ExportEntries(ctx context.Context, from time.Time, to time.Time, fn func(*transaction.Entry) error) error {
+	s.periods = append(s.periods, []time.Time{from, to})
+	for i := range s.entries {
+		if err := fn(&s.entries[i]); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func newService(store *storeMock) Service {
+	return New(Dependencies{Store: store, Logger: logger.Discard()})
+}
+
+func capturedTransaction(id string, createdAt time.Time) transaction.Transaction {
+	return transaction.Transaction{
+		Auth: auth.Auth{
+			ID:               id,
+			MerchantID:       "merchant-1",
+			Number:           "4000000000000002",
+			CardBIN:          "400000",
+			CardLast4:        "0002",
+			AuthorisedAmount: 100,
+			AvailableAmount:  20,
+			Currency:         "GBP",
+			CreatedAt:        createdAt,
+		},
+		State:          transaction_domain.StateCaptured,
+		CapturedAmount: 80,
+		Fees:           1.5,
+	}
+}
+
+func TestTransactionService_SearchTransactions(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{records: []transaction.Transaction{
+		capturedTransaction(authID, now),
+		capturedTransaction("6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12", now.Add(-time.Minute)),
+		capturedTransaction("7d5a3f4c-0e6b-4a4d-9c3f-5b8e9d0a1f23", now.Add(-2*time.Minute)),
+	}}
+	request := transaction_domain.SearchRequest{State: "captured", Currency: "gbp", Bin: "400000", MerchantID: "merchant-2", Limit: 2}
+	ctx := merchant.NewContext(context.Background(), "merchant-1")
+
+	page, errInf := newService(store).SearchTransactions(ctx, request)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, 2, len(page.Transactions))
+	assert.NotEmpty(t, page.NextCursor)
+
+	first := page.Transactions[0]
+	assert.EqualValues(t, authID, first.ID)
+	assert.EqualValues(t, "visa", first.CardBrand)
+	assert.EqualValues(t, "0002", first.CardLast4)
+	assert.EqualValues(t, 78.5, first.NetAmount)
+	assert.EqualValues(t, "2020-06-15T12:00:00Z", first.CreatedAt)
+	assert.Nil(t, first.Operations)
+
+	filter := store.filters[0]
+	assert.EqualValues(t, "GBP", filter.Currency)
+	assert.EqualValues(t, "400000", filter.CardBIN)
+	assert.EqualValues(t, "merchant-1", filter.MerchantID)
+	assert.True(t, filter.Scoped)
+	assert.EqualValues(t, transaction_domain.SortCreatedAt, filter.Sort)
+	assert.True(t, filter.Descending)
+	assert.EqualValues(t, 3, filter.Limit)
+	assert.Empty(t, filter.After.ID)
+
+	//the cursor resumes the search after the last transaction of the page
+	request.Cursor = page.NextCursor
+	page, errInf = newService(store).SearchTransactions(ctx, request)
+	assert.Nil(t, errInf)
+	after := store.filters[1].After
+	assert.EqualValues(t, "6c4f2e3b-9d5a-4f3c-8b2e-4a7d8c9f0e12", after.ID)
+	assert.True(t, now.Add(-time.Minute).Equal(after.CreatedAt))
+	assert.EqualValues(t, 100, after.Amount)
+
+	//a page that is not full is the last one
+	store.records = store.records[:1]
+	page, errInf = newService(store).SearchTransactions(ctx, transaction_domain.SearchRequest{})
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, 1, len(page.Transactions))
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestTransactionService_SearchAllTransactions(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{records: []transaction.Transaction{capturedTransaction(authID, now)}}
+
+	page, errInf := newService(store).SearchAllTransactions(merchant.NewContext(context.Background(), "merchant-1"), transaction_domain.SearchRequest{MerchantID: "merchant-2"})
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, 1, len(page.Transactions))
+	assert.EqualValues(t, "merchant-2", store.filters[0].MerchantID)
+	assert.False(t, store.filters[0].Scoped)
+
+	_, errInf = newService(store).SearchAllTransactions(context.Background(), transaction_domain.SearchRequest{State: "settled"})
+	assert.EqualValues(t, http.StatusUnprocessableEntity, errInf.Status())
+}
+
+func TestTransactionService_SearchTransactions_Errors(t *testing.T) {
+	t.Parallel()
+	cursor := encodeCursor(cursor{Sort: transaction_domain.SortCreatedAt, Order: "desc", ID: authID, CreatedAt: now})
+
+	tests := []struct {
+		name           string
+		store          *storeMock
+		request        transaction_domain.SearchRequest
+		expectedStatus int
+		expectedError  string
+	}{
+		{"invalid request", &storeMock{}, transaction_domain.SearchRequest{State: "settled"}, http.StatusUnprocessableEntity, error_constant.InvalidTransactionState},
+		{"malformed cursor", &storeMock{}, transaction_domain.SearchRequest{Cursor: "not a cursor"}, http.StatusUnprocessableEntity, error_constant.InvalidCursor},
+		{"cursor of another sort", &storeMock{}, transaction_domain.SearchRequest{Sort: "amount", Cursor: cursor}, http.StatusUnprocessableEntity, error_constant.InvalidCursor},
+		{"cursor of another order", &storeMock{}, transaction_domain.SearchRequest{Order: "asc", Cursor: cursor}, http.StatusUnprocessableEntity, error_constant.InvalidCursor},
+		{"retrieval failure", &storeMock{err: errors.New("disk I/O error")}, transaction_domain.SearchRequest{}, http.StatusInternalServerError, error_constant.TransactionRetrievalFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(tt.store).SearchTransactions(context.Background(), tt.request)
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
+
+func TestTransactionService_GetTransaction(t *testing.T) {
+	t.Parallel()
+	store := &storeMock{
+		records: []transaction.Transaction{capturedTransaction(authID, now)},
+		operations: []operation.Operation{
+			{Name: "authorisation", ProcessedAmount: 100, Model: gorm.Model{CreatedAt: now}},
+			{Name: "capture", ProcessedAmount: 80, Fee: 1.5, Model: gorm.Model{CreatedAt: now.Add(time.Hour)}},
+		},
+	}
+
+	response, errInf := newService(store).GetTransaction(merchant.NewContext(context.Background(), "merchant-1"), authID)
+	assert.Nil(t, errInf)
+	assert.EqualValues(t, transaction_domain.StateCaptured, response.State)
+	assert.EqualValues(t, 2, len(response.Operations))
+	assert.EqualValues(t, transaction_domain.OperationResponse{Name: "capture", Amount: 80, Fee: 1.5, CreatedAt: "2020-06-15T13:00:00Z"}, response.Operations[1])
+}
+
+func TestTransactionService_GetTransaction_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		store          *storeMock
+		id             string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"invalid id", &storeMock{}, "123", http.StatusUnprocessableEntity, error_constant.InvalidAuthIdField},
+		{"not found", &storeMock{}, authID, http.StatusNotFound, error_constant.TransactionNotFound},
+		{"of another merchant", &storeMock{records: []transaction.Transaction{capturedTransaction(authID, now)}}, authID, http.StatusNotFound, error_constant.TransactionNotFound},
+		{"retrieval failure", &storeMock{err: errors.New("disk I/O error")}, authID, http.StatusInternalServerError, error_constant.TransactionRetrievalFailure},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			response, errInf := newService(tt.store).GetTransaction(context.Background(), tt.id)
+			assert.Nil(t, response)
+			assert.EqualValues(t, tt.expectedStatus, errInf.Status())
+			assert.EqualValues(t, "["+tt.expectedError+"]", errInf.ErrorMessage())
+		})
+	}
+}
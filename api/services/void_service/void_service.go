@@ -1,47 +1,89 @@
 package void_service
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"payment-gateway-api/api/const/error_constant"
-	"payment-gateway-api/api/data_access"
+	"payment-gateway-api/api/data_access/database_model/auth"
 	"payment-gateway-api/api/domain/error_domain"
 	"payment-gateway-api/api/domain/void_domain"
+	"payment-gateway-api/api/logger"
+	"payment-gateway-api/api/metrics"
 	"payment-gateway-api/api/services/common_service"
 )
 
-type voidService struct{}
+//Store is the persistence the void service reads and cancels the authorisations from
+type Store interface {
+	GetAuthRecordByID(context.Context, string) (bool, *auth.Auth, error)
+	SoftDeleteAuthRecordByID(context.Context, string) error
+}
+
+//Dependencies are the collaborators of the void service
+type Dependencies struct {
+	Store         Store
+	CommonService common_service.Service
+	Logger        *logger.Logger
+	Metrics       *metrics.Metrics
+}
+
+type voidService struct {
+	store         Store
+	commonService common_service.Service
+	logger        *logger.Logger
+	metrics       *metrics.Metrics
+}
 
-type voidServiceInterface interface {
-	VoidTransaction(request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface)
+//Service cancels authorised transactions
+type Service interface {
+	VoidTransaction(context.Context, void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface)
 }
 
 var (
-	VoidService   voidServiceInterface = &voidService{}
-	operationName                      = "void"
+	operationName = "void"
 )
 
+//New creates the void service from its dependencies
+func New(deps Dependencies) Service {
+	return &voidService{
+		store:         deps.Store,
+		commonService: deps.CommonService,
+		logger:        deps.Logger,
+		metrics:       deps.Metrics,
+	}
+}
+
 //VoidTransaction cancels a transaction after being authorised by making sure the request and operations are valid
-func (v *voidService) VoidTransaction(request void_domain.VoidRequest) (*void_domain.VoidResponse, error_domain.GatewayErrorInterface) {
+func (v *voidService) VoidTransaction(ctx context.Context, request void_domain.VoidRequest) (_ *void_domain.VoidResponse, errInf error_domain.GatewayErrorInterface) {
+	defer func() {
+		errInf = error_domain.FromContext(ctx, errInf)
+		v.metrics.ObserveOperation(operationName, errInf)
+	}()
+
+	log := v.logger.Ctx(ctx).With(logger.String("auth_id", request.AuthId))
+	ctx = logger.NewContext(ctx, log)
+
 	errs := request.ValidateFields()
 	if len(errs) > 0 {
 		return nil, error_domain.New(http.StatusUnprocessableEntity, errs...)
 	}
 
 	//check operation can be executed according to state
-	isValid, err := common_service.CommonService.IsAuthorisedState(operationName, request.AuthId)
+	isValid, err := v.commonService.IsAuthorisedState(ctx, operationName, request.AuthId)
 	if err != nil {
+		log.Error(error_constant.UnableToCheckForInvalidState, logger.Err(err))
 		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.UnableToCheckForInvalidState))
 	}
 	if !isValid {
 		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.TransactionStateInvalid))
 	}
 
-	isSoftDeleted, authRecord, err := data_access.Db.GetAuthRecordByID(request.AuthId)
+	isSoftDeleted, authRecord, err := v.store.GetAuthRecordByID(ctx, request.AuthId)
 	if err != nil {
 		if err.Error() == "record not found" {
 			return nil, error_domain.New(http.StatusNotFound, errors.New(error_constant.TransactionNotFound))
 		}
+		log.Error(error_constant.TransactionRetrievalFailure, logger.Err(err))
 		return nil, error_domain.New(http.StatusInternalServerError, errors.New(error_constant.TransactionRetrievalFailure))
 	}
 	if !isSoftDeleted {
@@ -49,11 +91,13 @@ func (v *voidService) VoidTransaction(request void_domain.VoidRequest) (*void_do
 	}
 
 	//otherwise we can soft delete the transaction by initialising the deletedAt field
-	err = data_access.Db.SoftDeleteAuthRecordByID(request.AuthId)
+	err = v.store.SoftDeleteAuthRecordByID(ctx, request.AuthId)
 	if err != nil {
+		log.Error(error_constant.UnableToVoidTransaction, logger.Err(err))
 		return nil, error_domain.New(http.StatusUnprocessableEntity, errors.New(error_constant.UnableToVoidTransaction))
 	}
 
+	log.Info("transaction voided")
 	response := void_domain.VoidResponse{
 		IsSuccess: true,
 		Amount:    authRecord.AuthorisedAmount,
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"payment-gateway-api/api/app"
+	"payment-gateway-api/api/config"
+	"payment-gateway-api/api/domain/transaction_domain"
+	"payment-gateway-api/api/logger"
+)
+
+//export runs the export subcommand and returns its exit code. Its own flags come first, the arguments after them
+//configure the gateway as they do for the server:
+//	payment-gateway-api export -from 2020-06-01T00:00:00Z -to 2020-07-01T00:00:00Z -- -db-dsn gateway.db
+func export(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	request := transaction_domain.ExportRequest{}
+	fs.StringVar(&request.From, "from", "", "start of the period to export, included, as an RFC 3339 timestamp")
+	fs.StringVar(&request.To, "to", "", "end of the period to export, excluded, as an RFC 3339 timestamp")
+	fs.StringVar(&request.Format, "format", transaction_domain.ExportCSV, "format of the export, csv or jsonl")
+	fs.StringVar(&request.Columns, "columns", "", "comma separated columns of the export, all of them by default")
+	output := fs.String("output", "", "file to write the export to, the standard output by default")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	level, err := logger.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	//the logs never mix with an export written to the standard output
+	log := logger.New(os.Stderr, level)
+
+	gateway, err := app.New(cfg, log)
+	if err != nil {
+		log.Error("failed to connect to db", logger.Err(err))
+		return 1
+	}
+	defer gateway.Close()
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		file, err = os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		out = file
+	}
+
+	err = gateway.Export(context.Background(), request, out)
+	//the file is only complete once it has been closed, the error of the export comes first
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		//an incomplete export is not left behind
+		if file != nil {
+			os.Remove(*output)
+		}
+		return 1
+	}
+	return 0
+}
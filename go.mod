@@ -4,9 +4,14 @@ go 1.13
 
 require (
 	github.com/gin-gonic/gin v1.6.3
+	github.com/golang/protobuf v1.4.2
 	github.com/google/uuid v1.1.1
 	github.com/jinzhu/gorm v1.9.14
 	github.com/joeljunstrom/go-luhn v0.0.0-20190413165225-1e071b33b576
 	github.com/mattn/go-sqlite3 v2.0.3+incompatible
+	github.com/prometheus/client_golang v1.7.1
 	github.com/stretchr/testify v1.4.0
+	google.golang.org/grpc v1.31.1
+	google.golang.org/protobuf v1.23.0
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -1,16 +1,49 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"payment-gateway-api/api/app"
 	"payment-gateway-api/api/config"
-	"payment-gateway-api/api/data_access"
+	"payment-gateway-api/api/logger"
 )
 
 func main() {
-	err := data_access.Db.Setup(config.DbStoreFilePath)
+	//the export subcommand writes an export of the transactions instead of serving the api
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(export(os.Args[2:]))
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	level, err := logger.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	log := logger.New(os.Stdout, level)
+
+	gateway, err := app.New(cfg, log)
 	if err != nil {
-		panic("failed to connect to db: " + err.Error())
+		log.Error("failed to connect to db", logger.Err(err))
+		os.Exit(1)
+	}
+
+	runErr := gateway.Run()
+	if runErr != nil {
+		log.Error("gateway stopped unexpectedly", logger.Err(runErr))
+	}
+
+	//the database is only closed once the in-flight requests have been drained
+	if err := gateway.Close(); err != nil {
+		log.Error("unable to close the db", logger.Err(err))
+	}
+
+	if runErr != nil {
+		os.Exit(1)
 	}
-	defer data_access.Db.Close()
-	app.RunApp()
 }